@@ -43,6 +43,12 @@ type BatchSummary_t struct {
 // Attempting to register a second processor for the same combination will result in an error.
 // The 'op' parameter is case-insensitive and will be converted to lowercase before registration.
 func (jm *JobManager) RegisterProcessorBatch(app string, op string, p BatchProcessor) error {
+	return jm.RegisterProcessorBatchV2(app, op, batchProcessorAdapter{p})
+}
+
+// RegisterProcessorBatchV2 registers a context-aware processor for a batch operation type,
+// like RegisterProcessorBatch.
+func (jm *JobManager) RegisterProcessorBatchV2(app string, op string, p BatchProcessorV2) error {
 	// Convert op to lowercase before inserting into the database
 	op = strings.ToLower(op)
 
@@ -64,47 +70,87 @@ func (jm *JobManager) RegisterProcessorBatch(app string, op string, p BatchProce
 // status will be set to 'wait', indicating that the batch should be held back from immediate processing. If
 // 'waitabit' is false, the batch status will be set to 'queued', making it available for processing.
 func (jm *JobManager) BatchSubmit(app, op string, batchctx JSONstr, batchInput []BatchInput_t, waitabit bool) (batchID string, err error) {
+	return jm.BatchSubmitWithOptions(app, op, batchctx, batchInput, SubmitOptions_t{WaitABit: waitabit})
+}
+
+// BatchSubmitAt submits a new batch which is not processed before runAt.
+// The batch is inserted right away with status 'queued', so BatchDone reports it as queued
+// until it has been processed, and BatchAbort can cancel it before runAt.
+// A zero runAt, or one in the past, makes the batch available for processing immediately.
+func (jm *JobManager) BatchSubmitAt(app, op string, batchctx JSONstr, batchInput []BatchInput_t, runAt time.Time) (batchID string, err error) {
+	return jm.BatchSubmitWithOptions(app, op, batchctx, batchInput, SubmitOptions_t{RunAt: runAt})
+}
+
+// BatchSubmitWithOptions submits a new batch like BatchSubmit, with the optional settings in opts.
+// Rows of batches with a higher opts.Priority are picked up before those of lower priority ones.
+// A batch with opts.DependsOn stays in 'wait' until all its parents are done (see "Batch
+// Dependencies and Pipelines" in the README).
+func (jm *JobManager) BatchSubmitWithOptions(app, op string, batchctx JSONstr, batchInput []BatchInput_t, opts SubmitOptions_t) (batchID string, err error) {
 	// Generate a unique batch ID
 	batchUUID, err := uuid.NewUUID()
 
 	// Start a transaction
-	tx, err := jm.db.Begin(context.Background())
+	tx, err := jm.store.Begin(context.Background())
 	if err != nil {
 		return "", err
 	}
 	defer tx.Rollback(context.Background())
 
-	// Set the batch status based on waitabit
-	status := batchsqlc.StatusEnumQueued
-	if waitabit {
-		status = batchsqlc.StatusEnumWait
+	// Create transaction-bound queries
+	txQueries := tx.Queries()
+
+	// A repeated submission returns the batch submitted first
+	if opts.IdempotencyKey != "" {
+		existing, err := jm.claimIdempotencyKey(context.Background(), txQueries, app, opts.IdempotencyKey, batchUUID)
+		if err != nil {
+			return "", err
+		}
+		if existing != uuid.Nil {
+			return existing.String(), nil
+		}
 	}
 
-	// Convert op to lowercase before inserting into the database
-	op = strings.ToLower(op)
+	err = insertBatch(context.Background(), txQueries, batchUUID, app, op, batchctx, batchInput, opts)
+	if err != nil {
+		return "", err
+	}
 
-	// Create transaction-bound queries
-	txQueries := batchsqlc.New(tx)
+	// Wake up idle workers once the batch is committed, unless it is not ready to run yet
+	if len(opts.DependsOn) > 0 {
+		// Its parents may all be done already
+		if err := jm.resolveDependentBatch(context.Background(), txQueries, batchUUID); err != nil {
+			return "", err
+		}
+	} else if !opts.WaitABit && !opts.RunAt.After(time.Now()) {
+		if err := jm.notifyJobsQueued(context.Background(), txQueries, app); err != nil {
+			return "", err
+		}
+	}
 
-	// Insert a record into the batches table
-	_, err = txQueries.InsertIntoBatches(context.Background(), batchsqlc.InsertIntoBatchesParams{
-		ID:      batchUUID,
-		App:     app,
-		Op:      op,
-		Context: []byte(batchctx.String()),
-		Status:  status,
-		Reqat:   pgtype.Timestamp{Time: time.Now(), Valid: true},
-	})
+	// Commit the transaction
+	err = tx.Commit(context.Background())
 	if err != nil {
 		return "", err
 	}
 
+	return batchUUID.String(), nil
+}
+
+// insertBatch inserts a record into the batches table and one record per input row into the
+// batchrows table, using the given (normally transaction-bound) queries.
+func insertBatch(ctx context.Context, q batchsqlc.Querier, batchUUID uuid.UUID, app, op string, batchctx JSONstr, batchInput []BatchInput_t, opts SubmitOptions_t) error {
+	err := insertBatchRecord(ctx, q, batchUUID, app, op, batchctx, opts)
+	if err != nil {
+		return err
+	}
+
 	// Insert records into the batchrows table
 	batchRowsParam := batchsqlc.BulkInsertIntoBatchRowsParams{
-		Batch: make([]uuid.UUID, len(batchInput)),
-		Line:  make([]int32, len(batchInput)),
-		Input: make([][]byte, len(batchInput)),
-		Reqat: make([]pgtype.Timestamp, len(batchInput)),
+		Batch:    make([]uuid.UUID, len(batchInput)),
+		Line:     make([]int32, len(batchInput)),
+		Input:    make([][]byte, len(batchInput)),
+		Reqat:    make([]pgtype.Timestamp, len(batchInput)),
+		Priority: int32(opts.Priority),
 	}
 	for i, input := range batchInput {
 		batchRowsParam.Batch[i] = batchUUID
@@ -112,26 +158,52 @@ func (jm *JobManager) BatchSubmit(app, op string, batchctx JSONstr, batchInput [
 		batchRowsParam.Input[i] = []byte(input.Input.String())
 		batchRowsParam.Reqat[i] = pgtype.Timestamp{Time: time.Now(), Valid: true}
 	}
-	_, err = txQueries.BulkInsertIntoBatchRows(context.Background(), batchRowsParam)
-	if err != nil {
-		return "", err
+	_, err = q.BulkInsertIntoBatchRows(ctx, batchRowsParam)
+	return err
+}
+
+// insertBatchRecord inserts the record of a new batch into the batches table, with its
+// dependencies if it has any. The batch status is 'wait' if opts.WaitABit or
+// opts.DependsOn is set and 'queued' otherwise.
+func insertBatchRecord(ctx context.Context, q batchsqlc.Querier, batchUUID uuid.UUID, app, op string, batchctx JSONstr, opts SubmitOptions_t) error {
+	// Convert op to lowercase before inserting into the database
+	op = strings.ToLower(op)
+
+	// Set the batch status based on waitabit
+	status := batchsqlc.StatusEnumQueued
+	if opts.WaitABit || len(opts.DependsOn) > 0 {
+		status = batchsqlc.StatusEnumWait
 	}
 
-	// Commit the transaction
-	err = tx.Commit(context.Background())
+	callbackURL, err := callbackURLParam(opts.CallbackURL)
 	if err != nil {
-		return "", err
+		return err
 	}
 
-	return batchUUID.String(), nil
+	// Insert a record into the batches table
+	_, err = q.InsertIntoBatches(ctx, batchsqlc.InsertIntoBatchesParams{
+		ID:          batchUUID,
+		App:         app,
+		Op:          op,
+		Context:     []byte(batchctx.String()),
+		Status:      status,
+		Reqat:       pgtype.Timestamp{Time: time.Now(), Valid: true},
+		Runat:       pgtype.Timestamp{Time: opts.RunAt.In(time.Local), Valid: !opts.RunAt.IsZero()},
+		Priority:    int32(opts.Priority),
+		CallbackUrl: callbackURL,
+	})
+	if err != nil || len(opts.DependsOn) == 0 {
+		return err
+	}
+	return insertBatchDependencies(ctx, q, batchUUID, opts)
 }
 
 func (jm *JobManager) BatchDone(batchID string) (status batchsqlc.StatusEnum, batchOutput []BatchOutput_t, outputFiles map[string]string, nsuccess, nfailed, naborted int, err error) {
 	var batch batchsqlc.Batch
 	// Check REDIS for the batch status
 	redisKey := BatchStatusKey(batchID)
-	statusVal, err := jm.redisClient.Get(context.Background(), redisKey).Result()
-	if err == redis.Nil {
+	statusVal, err := jm.getFromRedis(context.Background(), redisKey)
+	if err == ErrStatusCacheMiss {
 		// Key does not exist in REDIS, check the database
 		batch, err = jm.queries.GetBatchByID(context.Background(), uuid.MustParse(batchID))
 		if err != nil {
@@ -145,7 +217,7 @@ func (jm *JobManager) BatchDone(batchID string) (status batchsqlc.StatusEnum, ba
 			// final status, set expiry to 100 times the cache duration
 			expirySec = 100 * jm.config.BatchStatusCacheDurSec
 		}
-		err = updateStatusInRedis(jm.redisClient, uuid.MustParse(batchID), status, expirySec)
+		err = updateStatusInRedis(jm.statusCache, uuid.MustParse(batchID), status, expirySec)
 		if err != nil {
 			jm.logger.Warn().LogActivity("Failed to update status in Redis cache", map[string]any{
 				"batchId": batchID,
@@ -201,7 +273,7 @@ func (jm *JobManager) BatchDone(batchID string) (status batchsqlc.StatusEnum, ba
 		nfailed = int(batch.Nfailed.Int32)
 		naborted = int(batch.Naborted.Int32)
 
-	case batchsqlc.StatusEnumQueued, batchsqlc.StatusEnumInprog, batchsqlc.StatusEnumWait:
+	case batchsqlc.StatusEnumQueued, batchsqlc.StatusEnumInprog, batchsqlc.StatusEnumWait, batchsqlc.StatusEnumPaused:
 		// Return with status indicating to try later
 		return status, nil, nil, 0, 0, 0, nil
 	}
@@ -226,7 +298,7 @@ func (jm *JobManager) BatchStatus(batchID string) (
 
 	// Check Redis for cached summary
 	redisKey := BatchSummaryKey(batchID)
-	summaryJSON, err := jm.redisClient.Get(ctx, redisKey).Result()
+	summaryJSON, err := jm.getFromRedis(ctx, redisKey)
 	if err == nil {
 		// Cache hit - parse and return
 		var summary BatchSummary_t
@@ -236,7 +308,7 @@ func (jm *JobManager) BatchStatus(batchID string) (
 		return batchsqlc.StatusEnum(summary.Status), summary.OutputFiles,
 			summary.NSuccess, summary.NFailed, summary.NAborted, nil
 	}
-	if err != redis.Nil {
+	if err != ErrStatusCacheMiss {
 		// Redis error (not just cache miss)
 		jm.logger.Warn().LogActivity("Redis error checking batch summary cache", map[string]any{
 			"batchId": batchID,
@@ -271,7 +343,7 @@ func (jm *JobManager) BatchStatus(batchID string) (
 		// Final status - cache for longer
 		expirySec = 100 * jm.config.BatchStatusCacheDurSec
 	}
-	if err := updateBatchSummaryInRedis(jm.redisClient, batchUUID, status, outputFiles,
+	if err := updateBatchSummaryInRedis(jm.statusCache, batchUUID, status, outputFiles,
 		nsuccess, nfailed, naborted, expirySec); err != nil {
 		jm.logger.Warn().LogActivity("Failed to cache batch summary in Redis", map[string]any{
 			"batchId": batchID,
@@ -292,13 +364,13 @@ func (jm *JobManager) BatchAbort(batchID string) (status batchsqlc.StatusEnum, n
 	}
 
 	// Start a transaction
-	tx, err := jm.db.Begin(context.Background())
+	tx, err := jm.store.Begin(context.Background())
 	if err != nil {
 		return "", 0, 0, 0, fmt.Errorf("failed to start transaction: %v", err)
 	}
 	defer tx.Rollback(context.Background())
 
-	queries := batchsqlc.New(tx)
+	queries := tx.Queries()
 
 	// Perform SELECT FOR UPDATE on batches and batchrows for the given batch ID
 	fmt.Printf("jobs.abort before getbatchbyid\n")
@@ -380,6 +452,30 @@ func (jm *JobManager) BatchAbort(batchID string) (status batchsqlc.StatusEnum, n
 		return "", 0, 0, 0, fmt.Errorf("failed to update batch summary: %v", err)
 	}
 
+	// Queue the completion webhook, if the batch has a callback URL
+	if batch.CallbackUrl.Valid {
+		err = enqueueCompletionWebhook(context.Background(), queries, batchUUID, CompletionEvent_t{
+			Event:    WebhookEventBatchDone,
+			ID:       batchID,
+			App:      batch.App,
+			Op:       batch.Op,
+			Status:   batchsqlc.StatusEnumAborted,
+			NSuccess: successCount,
+			NFailed:  failedCount,
+			NAborted: abortedCount,
+			DoneAt:   time.Now(),
+		})
+		if err != nil {
+			return "", 0, 0, 0, err
+		}
+	}
+
+	// Abort or queue the batches waiting on this one
+	err = jm.releaseDependents(context.Background(), queries, batchUUID)
+	if err != nil {
+		return "", 0, 0, 0, err
+	}
+
 	// Commit the transaction
 	fmt.Printf("jobs.abort before tx.commit")
 	err = tx.Commit(context.Background())
@@ -388,7 +484,7 @@ func (jm *JobManager) BatchAbort(batchID string) (status batchsqlc.StatusEnum, n
 	}
 
 	// Update status in Redis
-	err = updateStatusInRedis(jm.redisClient, batchUUID, batchsqlc.StatusEnumAborted, 100*jm.config.BatchStatusCacheDurSec)
+	err = updateStatusInRedis(jm.statusCache, batchUUID, batchsqlc.StatusEnumAborted, 100*jm.config.BatchStatusCacheDurSec)
 	if err != nil {
 		jm.logger.Warn().LogActivity("Failed to update status in Redis cache for aborted batch", map[string]any{
 			"batchId": batchUUID.String(),
@@ -396,6 +492,10 @@ func (jm *JobManager) BatchAbort(batchID string) (status batchsqlc.StatusEnum, n
 		})
 		// Continue despite Redis failure - Redis is just a cache
 	}
+	jm.clearBatchProgress(batchUUID)
+
+	// Interrupt the rows of the batch that are being processed
+	jm.signalAbort(batchUUID)
 	return batchsqlc.StatusEnumAborted, successCount, failedCount, abortedCount, nil
 }
 
@@ -421,14 +521,14 @@ func (jm *JobManager) BatchAppend(batchID string, batchinput []BatchInput_t, wai
 	}
 
 	// Start a transaction
-	tx, err := jm.db.Begin(context.Background())
+	tx, err := jm.store.Begin(context.Background())
 	if err != nil {
 		return 0, fmt.Errorf("failed to start transaction: %v", err)
 	}
 	defer tx.Rollback(context.Background())
 
 	// Create transaction-bound queries
-	txQueries := batchsqlc.New(tx)
+	txQueries := tx.Queries()
 
 	// Insert records into the batchrows table
 	for _, input := range batchinput {
@@ -438,10 +538,11 @@ func (jm *JobManager) BatchAppend(batchID string, batchinput []BatchInput_t, wai
 
 		// Convert BatchInput_t to InsertIntoBatchRowsParams
 		dbParams := batchsqlc.InsertIntoBatchRowsParams{
-			Batch: batchUUID,
-			Line:  int32(input.Line),
-			Input: []byte(input.Input.String()),
-			Reqat: pgtype.Timestamp{Time: time.Now(), Valid: true},
+			Batch:    batchUUID,
+			Line:     int32(input.Line),
+			Input:    []byte(input.Input.String()),
+			Reqat:    pgtype.Timestamp{Time: time.Now(), Valid: true},
+			Priority: batch.Priority,
 		}
 
 		err := txQueries.InsertIntoBatchRows(context.Background(), dbParams)
@@ -450,14 +551,24 @@ func (jm *JobManager) BatchAppend(batchID string, batchinput []BatchInput_t, wai
 		}
 	}
 
-	// Update the batch status to "queued" if waitabit is false
+	// Update the batch status to "queued" if waitabit is false, unless the batch has
+	// parents: it is queued once they are done
 	if !waitabit {
-		err = txQueries.UpdateBatchStatus(context.Background(), batchsqlc.UpdateBatchStatusParams{
-			ID:     uuid.MustParse(batchID),
-			Status: batchsqlc.StatusEnumQueued,
-		})
+		nparents, err := txQueries.CountBatchDependencies(context.Background(), batchUUID)
 		if err != nil {
-			return 0, fmt.Errorf("failed to update batch status: %v", err)
+			return 0, fmt.Errorf("failed to count batch dependencies: %v", err)
+		}
+		if nparents == 0 {
+			err = txQueries.UpdateBatchStatus(context.Background(), batchsqlc.UpdateBatchStatusParams{
+				ID:     uuid.MustParse(batchID),
+				Status: batchsqlc.StatusEnumQueued,
+			})
+			if err != nil {
+				return 0, fmt.Errorf("failed to update batch status: %v", err)
+			}
+			if err := jm.notifyJobsQueued(context.Background(), txQueries, batch.App); err != nil {
+				return 0, err
+			}
 		}
 	}
 
@@ -485,14 +596,17 @@ func (jm *JobManager) WaitOff(batchID string) (string, int, error) {
 	}
 
 	// Start a transaction
-	tx, err := jm.db.Begin(context.Background())
+	tx, err := jm.store.Begin(context.Background())
 	if err != nil {
 		return "", 0, fmt.Errorf("failed to start transaction: %v", err)
 	}
 	defer tx.Rollback(context.Background())
 
+	// Create transaction-bound queries
+	txQueries := tx.Queries()
+
 	// Perform SELECT FOR UPDATE on the batches table
-	batch, err := jm.queries.GetBatchByID(context.Background(), batchUUID)
+	batch, err := txQueries.GetBatchByID(context.Background(), batchUUID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return "", 0, fmt.Errorf("batch not found: %v", err)
@@ -503,7 +617,7 @@ func (jm *JobManager) WaitOff(batchID string) (string, int, error) {
 	// Check if the batch status is already "queued"
 	if batch.Status == batchsqlc.StatusEnumQueued {
 		// Get the total count of rows in batchrows for the batch
-		batchRows, err := jm.queries.GetBatchRowsCount(context.Background(), batchUUID)
+		batchRows, err := txQueries.GetBatchRowsCount(context.Background(), batchUUID)
 		if err != nil {
 			return "", 0, fmt.Errorf("failed to get batch rows: %v", err)
 		}
@@ -517,17 +631,29 @@ func (jm *JobManager) WaitOff(batchID string) (string, int, error) {
 		return "", 0, fmt.Errorf("batch status must be 'wait' to change to 'queued'")
 	}
 
+	// A batch with parents is queued once they are done, not by WaitOff
+	nparents, err := txQueries.CountBatchDependencies(context.Background(), batchUUID)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to count batch dependencies: %v", err)
+	}
+	if nparents > 0 {
+		return "", 0, fmt.Errorf("%w: %s", ErrBatchHasParents, batchID)
+	}
+
 	// Update the batch status to "queued"
-	err = jm.queries.UpdateBatchStatus(context.Background(), batchsqlc.UpdateBatchStatusParams{
+	err = txQueries.UpdateBatchStatus(context.Background(), batchsqlc.UpdateBatchStatusParams{
 		ID:     batchUUID,
 		Status: batchsqlc.StatusEnumQueued,
 	})
 	if err != nil {
 		return "", 0, fmt.Errorf("failed to update batch status: %v", err)
 	}
+	if err := jm.notifyJobsQueued(context.Background(), txQueries, batch.App); err != nil {
+		return "", 0, err
+	}
 
 	// Get the total count of rows in batchrows for the batch
-	nrows, err := jm.queries.GetBatchRowsCount(context.Background(), batchUUID)
+	nrows, err := txQueries.GetBatchRowsCount(context.Background(), batchUUID)
 	if err != nil {
 		return "", 0, fmt.Errorf("failed to get batch rows count: %v", err)
 	}
@@ -559,6 +685,8 @@ func mapStatusEnum(status batchsqlc.StatusEnum) BatchStatus_t {
 		return BatchFailed
 	case batchsqlc.StatusEnumAborted:
 		return BatchAborted
+	case batchsqlc.StatusEnumPaused:
+		return BatchPaused
 	default:
 		return BatchTryLater
 	}
@@ -264,3 +264,152 @@ func TestMultiWorkerBatchProcessing(t *testing.T) {
 		assert.True(t, doneatNotNull, "batch %d (%s): doneat should not be NULL", i+1, id)
 	}
 }
+
+// panickingBatchProcessor behaves like trackingBatchProcessor but panics on
+// panicLine, to check that a panic in one worker goroutine only fails its row.
+type panickingBatchProcessor struct {
+	trackingBatchProcessor
+	panicLine int
+}
+
+func (p *panickingBatchProcessor) DoBatchJob(
+	initBlock InitBlock,
+	batchctx JSONstr,
+	line int,
+	input JSONstr,
+) (batchsqlc.StatusEnum, JSONstr, []wscutils.ErrorMessage, map[string]string, error) {
+	if line == p.panicLine {
+		panic("bad row")
+	}
+	return p.trackingBatchProcessor.DoBatchJob(initBlock, batchctx, line, input)
+}
+
+// TestWorkerPoolBatchProcessing verifies that a single JobManager instance
+// processes the rows of a fetched block in parallel when Workers > 1, with a
+// panic in one row isolated to that row and the batch summarised once.
+func TestWorkerPoolBatchProcessing(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test requiring Docker")
+	}
+
+	const (
+		rowsPerBatch   = 100
+		panicLine      = 13
+		appName        = "testapp"
+		opName         = "testop"
+		overallTimeout = 30 * time.Second
+	)
+
+	ctx := context.Background()
+
+	pgContainer, err := postgres.Run(ctx,
+		"postgres:16-alpine",
+		postgres.WithDatabase("testdb"),
+		postgres.WithUsername("test"),
+		postgres.WithPassword("test"),
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("database system is ready to accept connections").
+				WithOccurrence(2).
+				WithStartupTimeout(30*time.Second),
+		),
+	)
+	require.NoError(t, err)
+	defer pgContainer.Terminate(ctx)
+
+	connStr, err := pgContainer.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(t, err)
+
+	conn, err := pgx.Connect(ctx, connStr)
+	require.NoError(t, err)
+	err = MigrateDatabase(conn)
+	require.NoError(t, err)
+	conn.Close(ctx)
+
+	pool, err := pgxpool.New(ctx, connStr)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+
+	redisClient := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer redisClient.Close()
+
+	loggerCtx := &logharbour.LoggerContext{}
+	logger := logharbour.NewLogger(loggerCtx, "test", log.Writer())
+
+	// One instance with a pool of 4 workers and blocks of 20 rows
+	jm := NewJobManager(pool, redisClient, nil, logger, &JobManagerConfig{
+		BatchChunkNRows:    20,
+		Workers:            4,
+		PollingIntervalSec: 1,
+	})
+	jm.objStore = &objstore.ObjectStoreMock{
+		PutFunc: func(ctx context.Context, bucket, obj string, reader io.Reader, size int64, contentType string) error {
+			return nil
+		},
+	}
+
+	require.NoError(t, jm.RegisterInitializer(appName, &noopInitializer{}))
+	processor := &panickingBatchProcessor{panicLine: panicLine}
+	require.NoError(t, jm.RegisterProcessorBatch(appName, opName, processor))
+
+	batchContext, err := NewJSONstr("{}")
+	require.NoError(t, err)
+	inputs := make([]BatchInput_t, rowsPerBatch)
+	for r := 0; r < rowsPerBatch; r++ {
+		input, jsonErr := NewJSONstr(fmt.Sprintf(`{"row": %d}`, r+1))
+		require.NoError(t, jsonErr)
+		inputs[r] = BatchInput_t{Line: r + 1, Input: input}
+	}
+	batchID, err := jm.BatchSubmit(appName, opName, batchContext, inputs, false)
+	require.NoError(t, err)
+
+	workerCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		jm.RunWithContext(workerCtx)
+	}()
+
+	deadline := time.After(overallTimeout)
+	for {
+		status, _, _, _, _, _, pollErr := jm.BatchDone(batchID)
+		require.NoError(t, pollErr)
+		if status != batchsqlc.StatusEnumQueued && status != batchsqlc.StatusEnumInprog {
+			break
+		}
+		select {
+		case <-deadline:
+			cancel()
+			<-done
+			t.Fatal("timed out waiting for batch to complete")
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+	cancel()
+	<-done
+
+	_, _, _, nsuccess, nfailed, naborted, err := jm.BatchDone(batchID)
+	require.NoError(t, err)
+	assert.Equal(t, rowsPerBatch-1, nsuccess)
+	assert.Equal(t, 1, nfailed, "only the panicking row should fail")
+	assert.Equal(t, 0, naborted)
+
+	var failedLine int
+	err = pool.QueryRow(ctx,
+		`SELECT line FROM batchrows WHERE batch = $1 AND status = 'failed'`, batchID).Scan(&failedLine)
+	require.NoError(t, err)
+	assert.Equal(t, panicLine, failedLine)
+
+	// Every row was untracked once processed
+	tracked, err := redisClient.SCard(ctx, workerRowsKey(jm.instanceID)).Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), tracked)
+
+	peak := processor.peakHit.Load()
+	t.Logf("peak concurrent DoBatchJob invocations: %d", peak)
+	assert.Greater(t, peak, int64(1),
+		"expected the worker pool to process rows concurrently, got peak=%d", peak)
+}
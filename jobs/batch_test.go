@@ -12,9 +12,11 @@ import (
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/remiges-tech/alya/jobs/pg/batchsqlc"
+	"github.com/remiges-tech/alya/jobs/pg/batchsqlc/mocks"
 	"github.com/remiges-tech/alya/wscutils"
 	"github.com/remiges-tech/logharbour/logharbour"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type mockBatchProcessor struct {
@@ -91,7 +93,7 @@ func TestBatchDone(t *testing.T) {
 	// Create a JobManager instance with the database and Redis dependencies
 	jm := &JobManager{
 		queries:     batchsqlc.New(db),
-		redisClient: redisClient,
+		statusCache: NewRedisStatusCache(redisClient),
 		config:      JobManagerConfig{},
 	}
 
@@ -240,3 +242,62 @@ type MockInitBlock struct{}
 func (ib *MockInitBlock) Close() error {
 	return nil
 }
+
+func TestInsertBatch_Options(t *testing.T) {
+	mockQuerier := &mocks.QuerierMock{
+		InsertIntoBatchesFunc: func(ctx context.Context, arg batchsqlc.InsertIntoBatchesParams) (uuid.UUID, error) {
+			return arg.ID, nil
+		},
+		BulkInsertIntoBatchRowsFunc: func(ctx context.Context, arg batchsqlc.BulkInsertIntoBatchRowsParams) (int64, error) {
+			return int64(len(arg.Line)), nil
+		},
+	}
+	batchctx, _ := NewJSONstr("")
+	input, _ := NewJSONstr(`{"amount": 100}`)
+	batchInput := []BatchInput_t{{Line: 1, Input: input}, {Line: 2, Input: input}}
+
+	// defaults
+	err := insertBatch(context.Background(), mockQuerier, uuid.New(), "banking", "Transfer", batchctx, batchInput, SubmitOptions_t{})
+	assert.NoError(t, err)
+	arg := mockQuerier.InsertIntoBatchesCalls()[0].Arg
+	assert.Equal(t, "transfer", arg.Op)
+	assert.Equal(t, batchsqlc.StatusEnumQueued, arg.Status)
+	assert.False(t, arg.Runat.Valid)
+	assert.Equal(t, int32(0), arg.Priority)
+	assert.Equal(t, []int32{1, 2}, mockQuerier.BulkInsertIntoBatchRowsCalls()[0].Arg.Line)
+	assert.Equal(t, int32(0), mockQuerier.BulkInsertIntoBatchRowsCalls()[0].Arg.Priority)
+
+	// all options set
+	runAt := time.Now().Add(time.Hour)
+	err = insertBatch(context.Background(), mockQuerier, uuid.New(), "banking", "transfer", batchctx, batchInput, SubmitOptions_t{WaitABit: true, RunAt: runAt, Priority: 5})
+	assert.NoError(t, err)
+	arg = mockQuerier.InsertIntoBatchesCalls()[1].Arg
+	assert.Equal(t, batchsqlc.StatusEnumWait, arg.Status)
+	assert.True(t, arg.Runat.Valid)
+	assert.True(t, arg.Runat.Time.Equal(runAt))
+	assert.Equal(t, int32(5), arg.Priority)
+	assert.Equal(t, int32(5), mockQuerier.BulkInsertIntoBatchRowsCalls()[1].Arg.Priority)
+
+	// runat is stored as local wall-clock time, whatever the zone of RunAt
+	_, offset := time.Now().Zone()
+	runAt = time.Now().Add(time.Hour).In(time.FixedZone("elsewhere", offset+5*3600))
+	err = insertBatch(context.Background(), mockQuerier, uuid.New(), "banking", "transfer", batchctx, batchInput, SubmitOptions_t{RunAt: runAt})
+	assert.NoError(t, err)
+	arg = mockQuerier.InsertIntoBatchesCalls()[2].Arg
+	assert.True(t, arg.Runat.Time.Equal(runAt))
+	assert.Equal(t, time.Local, arg.Runat.Time.Location())
+	assert.Equal(t, runAt.In(time.Local).Hour(), arg.Runat.Time.Hour())
+}
+
+func TestSlowQuerySubmit_RunAt(t *testing.T) {
+	jm, store, _ := newMemoryTestJobManager(t)
+	_, offset := time.Now().Zone()
+	runAt := time.Now().Add(time.Hour).In(time.FixedZone("elsewhere", offset-7*3600))
+
+	reqID, err := jm.SlowQuerySubmitWithOptions("memapp", "memop", mustJSONstr(t, `{}`), mustJSONstr(t, `{}`), SubmitOptions_t{RunAt: runAt})
+	require.NoError(t, err)
+	batch, err := store.Queries().GetBatchByID(context.Background(), uuid.MustParse(reqID))
+	require.NoError(t, err)
+	assert.True(t, batch.Runat.Time.Equal(runAt))
+	assert.Equal(t, time.Local, batch.Runat.Time.Location())
+}
@@ -0,0 +1,525 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/remiges-tech/alya/jobs/objstore"
+	"github.com/remiges-tech/alya/jobs/pg/batchsqlc"
+)
+
+// The output files of a batch are assembled when it is summarised, from the blobrows
+// of its processed rows (see "Output Files" in the README). The rows are read a page
+// at a time, in the order of their lines, and the records of each logical file are
+// encoded in its output format and streamed into its own object: a file that fits in
+// one part is stored with a single put, a larger one is uploaded part by part as a
+// multipart upload. At most one part of each file is held in memory.
+//
+// The output pipeline of the batch, if any, compresses and encrypts each file on its
+// way to the object store, and once all the files are stored, signs them and stores
+// their checksum manifest.
+//
+// After each part uploaded, a checkpoint recording the uploads in progress is stored
+// next to the output files. If the worker dies before the batch is summarised, the
+// next summarisation of the batch resumes the uploads from the checkpoint instead of
+// starting again.
+
+// outputCheckpointPrefix is the prefix of the names of the checkpoints of output
+// files in the batch output bucket.
+const outputCheckpointPrefix = "alya-checkpoints/"
+
+// rowPos_t is the position of a processed row in the output files, which follow the
+// order of lines and then of rowids.
+type rowPos_t struct {
+	Line  int32 `json:"line"`
+	Rowid int64 `json:"rowid"`
+}
+
+// outputStart comes before the first row of any batch.
+var outputStart = rowPos_t{Line: math.MinInt32}
+
+func (p rowPos_t) after(q rowPos_t) bool {
+	return p.Line > q.Line || (p.Line == q.Line && p.Rowid > q.Rowid)
+}
+
+// outputCheckpoint_t is the checkpoint of the output files of a batch.
+type outputCheckpoint_t struct {
+	// Scanned is the last row whose lines are in the uploaded parts of every file, or
+	// in files already stored; summarisation resumes after it
+	Scanned rowPos_t                 `json:"scanned"`
+	Files   map[string]*outputFile_t `json:"files"`
+}
+
+// outputFile_t is the state of one output file. Its records from rows up to Uploaded
+// are in Parts, and those from later rows in buf. The Uploaded of a file in a format
+// that is not resumable stays at the row before its first record, as the file cannot
+// be continued from its parts.
+type outputFile_t struct {
+	ObjectID string          `json:"objectid"`
+	UploadID string          `json:"uploadid,omitempty"`
+	Parts    []objstore.Part `json:"parts,omitempty"`
+	Uploaded rowPos_t        `json:"uploaded"`
+	Records  int             `json:"records"` // records in Parts
+	Done     bool            `json:"done"`    // the object is complete
+
+	buf     bytes.Buffer
+	format  OutputFormat
+	enc     OutputEncoder
+	sink    io.WriteCloser // the output pipeline into buf, if it transforms the file
+	records int            // records in Parts and buf
+}
+
+// outputWriter writes the output files of a batch.
+type outputWriter struct {
+	jm       *JobManager
+	ctx      context.Context
+	batch    batchsqlc.Batch
+	cp       outputCheckpoint_t
+	saved    bool     // the checkpoint is in the object store
+	last     rowPos_t // the last row written
+	partSize int
+	summary  OutputSummary_t // batch counters for header and trailer records
+	pipeline OutputPipeline
+}
+
+// writeOutputFiles streams the processed rows of batch, with the given counters, into
+// its output files, and returns the object IDs and the content types of the files by
+// logical name.
+func (jm *JobManager) writeOutputFiles(ctx context.Context, q batchsqlc.Querier, batch batchsqlc.Batch, nsuccess, nfailed, naborted int64) (outputFiles, outputTypes map[string]string, err error) {
+	w, err := jm.newOutputWriter(ctx, batch)
+	if err != nil {
+		return nil, nil, err
+	}
+	w.summary.NSuccess, w.summary.NFailed, w.summary.NAborted = int(nsuccess), int(nfailed), int(naborted)
+
+	after := w.last
+	for {
+		rows, err := q.GetProcessedBatchRowsPage(ctx, batchsqlc.GetProcessedBatchRowsPageParams{
+			Batch:      batch.ID,
+			AfterLine:  after.Line,
+			AfterRowid: after.Rowid,
+			PageSize:   int32(jm.config.SummaryChunkNRows),
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get processed batch rows: %w", err)
+		}
+		for _, row := range rows {
+			if err := w.write(row); err != nil {
+				return nil, nil, w.fail(err)
+			}
+		}
+		if len(rows) < jm.config.SummaryChunkNRows {
+			break
+		}
+		after = rowPos_t{Line: rows[len(rows)-1].Line, Rowid: rows[len(rows)-1].Rowid}
+	}
+
+	outputFiles, outputTypes, err = w.finish()
+	if err != nil {
+		return nil, nil, w.fail(err)
+	}
+	if w.saved {
+		jm.deleteOutputCheckpoint(ctx, batch.ID)
+	}
+	return outputFiles, outputTypes, nil
+}
+
+// newOutputWriter returns a writer for the output files of batch, resuming from its
+// checkpoint if there is one.
+func (jm *JobManager) newOutputWriter(ctx context.Context, batch batchsqlc.Batch) (*outputWriter, error) {
+	w := &outputWriter{
+		jm:       jm,
+		ctx:      ctx,
+		batch:    batch,
+		cp:       outputCheckpoint_t{Scanned: outputStart, Files: map[string]*outputFile_t{}},
+		last:     outputStart,
+		partSize: jm.config.OutputPartSize,
+		summary:  OutputSummary_t{BatchID: batch.ID.String(), App: batch.App, Op: batch.Op},
+		pipeline: jm.outputPipeline(batch.App, batch.Op),
+	}
+
+	r, err := jm.objStore.Get(ctx, jm.config.BatchOutputBucket, outputCheckpointName(batch.ID))
+	if errors.Is(err, objstore.ErrObjectNotFound) {
+		return w, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get output checkpoint: %w", err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read output checkpoint: %w", err)
+	}
+	if err := json.Unmarshal(data, &w.cp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal output checkpoint: %w", err)
+	}
+	if w.cp.Files == nil {
+		w.cp.Files = map[string]*outputFile_t{}
+	}
+	w.saved = true
+	w.last = w.cp.Scanned
+
+	// Files that are not resumable are written again from the start
+	for logicalFile, f := range w.cp.Files {
+		if f.Done || f.UploadID == "" || w.resumable(logicalFile, f) {
+			continue
+		}
+		jm.abortOutputUpload(ctx, batch.ID, f)
+		f.UploadID, f.Parts, f.Records = "", nil, 0
+	}
+
+	jm.logger.Info().LogActivity("Resuming output files of batch from checkpoint", map[string]any{
+		"batchId":     batch.ID.String(),
+		"fileCount":   len(w.cp.Files),
+		"resumeLine":  w.cp.Scanned.Line,
+		"resumeRowid": w.cp.Scanned.Rowid,
+	})
+	return w, nil
+}
+
+// write adds the records of a processed row to the output files, and uploads the files
+// that have a part's worth of records. A file is created by the first non-empty
+// content for it, and each content is trimmed and encoded as a record.
+func (w *outputWriter) write(row batchsqlc.GetProcessedBatchRowsPageRow) error {
+	pos := rowPos_t{Line: row.Line, Rowid: row.Rowid}
+	defer func() { w.last = pos }()
+	if len(row.Blobrows) == 0 {
+		return nil
+	}
+
+	var blobRows map[string]string
+	if err := json.Unmarshal(row.Blobrows, &blobRows); err != nil {
+		return fmt.Errorf("failed to unmarshal blobrows: %v", err)
+	}
+
+	var full []string
+	for logicalFile, content := range blobRows {
+		if content == "" {
+			continue
+		}
+		f, ok := w.cp.Files[logicalFile]
+		if !ok {
+			f = &outputFile_t{ObjectID: uuid.New().String(), Uploaded: w.last}
+			w.cp.Files[logicalFile] = f
+		}
+		// Skip what a previous summarisation has already uploaded
+		if f.Done || !pos.after(f.Uploaded) {
+			continue
+		}
+
+		content = strings.TrimSpace(content)
+		if content == "" {
+			continue
+		}
+		if f.buf.Len() == 0 && w.last.after(f.Uploaded) && w.resumable(logicalFile, f) {
+			f.Uploaded = w.last
+		}
+		enc, err := w.encoder(logicalFile, f)
+		if err != nil {
+			return err
+		}
+		if err := enc.Encode(content); err != nil {
+			return fmt.Errorf("failed to write line %d to %s: %w", row.Line, logicalFile, err)
+		}
+		f.records++
+		if f.buf.Len() >= w.partSize {
+			full = append(full, logicalFile)
+		}
+	}
+
+	// Parts hold whole rows, so the files are uploaded once the row is written
+	if len(full) == 0 {
+		return nil
+	}
+	sort.Strings(full)
+	for _, logicalFile := range full {
+		if err := w.putPart(logicalFile, pos); err != nil {
+			return err
+		}
+	}
+	return w.save(pos)
+}
+
+// putPart uploads the buffered records of a file, up to the row at pos, as its next part.
+func (w *outputWriter) putPart(logicalFile string, pos rowPos_t) error {
+	f := w.cp.Files[logicalFile]
+	store, bucket := w.jm.objStore, w.jm.config.BatchOutputBucket
+	if f.UploadID == "" {
+		uploadID, err := objstore.ObjectNewUpload(w.ctx, store, bucket, f.ObjectID, w.info(logicalFile, f))
+		if err != nil {
+			return fmt.Errorf("failed to start upload of %s: %w", logicalFile, err)
+		}
+		f.UploadID = uploadID
+	}
+
+	part, err := store.PutPart(w.ctx, bucket, f.ObjectID, f.UploadID, len(f.Parts)+1, bytes.NewReader(f.buf.Bytes()), int64(f.buf.Len()))
+	if err != nil {
+		return fmt.Errorf("failed to upload part %d of %s: %w", len(f.Parts)+1, logicalFile, err)
+	}
+	f.Parts = append(f.Parts, part)
+	f.buf.Reset()
+	f.Records = f.records
+	if w.resumable(logicalFile, f) {
+		f.Uploaded = pos
+	}
+
+	w.jm.logger.Debug0().LogActivity("Uploaded part of batch output file", map[string]any{
+		"batchId":  w.batch.ID.String(),
+		"file":     logicalFile,
+		"objectId": f.ObjectID,
+		"part":     part.Number,
+		"size":     part.Size,
+	})
+	return nil
+}
+
+// finish ends and stores the files that are not complete yet, and returns the object
+// IDs and the content types of all the files by logical name.
+func (w *outputWriter) finish() (outputFiles, outputTypes map[string]string, err error) {
+	store, bucket := w.jm.objStore, w.jm.config.BatchOutputBucket
+	logicalFiles := make([]string, 0, len(w.cp.Files))
+	for logicalFile := range w.cp.Files {
+		logicalFiles = append(logicalFiles, logicalFile)
+	}
+	sort.Strings(logicalFiles)
+
+	outputFiles = make(map[string]string)
+	outputTypes = make(map[string]string)
+	for _, logicalFile := range logicalFiles {
+		f := w.cp.Files[logicalFile]
+		outputFiles[logicalFile] = f.ObjectID
+		outputTypes[logicalFile] = w.pipeline.contentType(w.fileFormat(logicalFile, f).ContentType())
+		if f.Done {
+			continue
+		}
+
+		enc, err := w.encoder(logicalFile, f)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := enc.End(w.fileSummary(logicalFile, f)); err != nil {
+			return nil, nil, fmt.Errorf("failed to end %s: %w", logicalFile, err)
+		}
+		if f.sink != nil {
+			if err := f.sink.Close(); err != nil {
+				return nil, nil, fmt.Errorf("failed to end %s: %w", logicalFile, err)
+			}
+		}
+		if f.UploadID == "" {
+			err := objstore.ObjectPut(w.ctx, store, bucket, f.ObjectID, bytes.NewReader(f.buf.Bytes()), int64(f.buf.Len()), w.info(logicalFile, f))
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to put %s in object store: %w", logicalFile, err)
+			}
+		} else {
+			if f.buf.Len() > 0 {
+				err = w.putPart(logicalFile, w.last)
+			}
+			if err == nil {
+				err = store.CompleteMultipartUpload(w.ctx, bucket, f.ObjectID, f.UploadID, f.Parts)
+			}
+			if errors.Is(err, objstore.ErrUploadNotFound) {
+				// The upload may have been completed by a summarisation that died before
+				// saving the checkpoint
+				if _, statErr := store.Stat(w.ctx, bucket, f.ObjectID); statErr == nil {
+					err = nil
+				}
+			}
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to complete upload of %s: %w", logicalFile, err)
+			}
+		}
+		f.Done = true
+		f.buf = bytes.Buffer{}
+
+		if w.saved {
+			if err := w.save(w.last); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	if w.pipeline.Signer != nil || w.pipeline.Manifest {
+		if err := w.seal(logicalFiles, outputFiles, outputTypes); err != nil {
+			return nil, nil, err
+		}
+	}
+	return outputFiles, outputTypes, nil
+}
+
+// fileFormat returns the output format of a file.
+func (w *outputWriter) fileFormat(logicalFile string, f *outputFile_t) OutputFormat {
+	if f.format == nil {
+		f.format = w.jm.outputFormat(w.batch.App, w.batch.Op, logicalFile)
+	}
+	return f.format
+}
+
+// encoder returns the encoder of a file, creating it on first use in this
+// summarisation. The file is begun if none of it has been uploaded yet.
+func (w *outputWriter) encoder(logicalFile string, f *outputFile_t) (OutputEncoder, error) {
+	if f.enc != nil {
+		return f.enc, nil
+	}
+	var sink io.Writer = &f.buf
+	if w.pipeline.transforms() {
+		var err error
+		if f.sink, err = w.pipeline.writer(&f.buf); err != nil {
+			return nil, fmt.Errorf("failed to start output pipeline of %s: %w", logicalFile, err)
+		}
+		sink = f.sink
+	}
+	f.enc = w.fileFormat(logicalFile, f).NewEncoder(sink)
+	f.records = f.Records
+	if len(f.Parts) == 0 {
+		if err := f.enc.Begin(w.fileSummary(logicalFile, f)); err != nil {
+			return nil, fmt.Errorf("failed to begin %s: %w", logicalFile, err)
+		}
+	}
+	return f.enc, nil
+}
+
+// resumable reports whether a file can be continued from its uploaded parts, which is
+// the case if neither its format nor the output pipeline keep state between records.
+func (w *outputWriter) resumable(logicalFile string, f *outputFile_t) bool {
+	return w.fileFormat(logicalFile, f).Resumable() && !w.pipeline.transforms()
+}
+
+// fileSummary returns the summary passed to the header and trailer functions of the
+// format of a file.
+func (w *outputWriter) fileSummary(logicalFile string, f *outputFile_t) OutputSummary_t {
+	summary := w.summary
+	summary.LogicalFile = logicalFile
+	summary.NRecords = f.records
+	return summary
+}
+
+// save stores the checkpoint, with all rows up to pos written.
+func (w *outputWriter) save(pos rowPos_t) error {
+	w.cp.Scanned = pos
+	for logicalFile, f := range w.cp.Files {
+		pending := f.buf.Len() > 0 || (!f.Done && !w.resumable(logicalFile, f))
+		if pending && w.cp.Scanned.after(f.Uploaded) {
+			w.cp.Scanned = f.Uploaded
+		}
+	}
+	data, err := json.Marshal(w.cp)
+	if err != nil {
+		return fmt.Errorf("failed to marshal output checkpoint: %w", err)
+	}
+	err = w.jm.objStore.Put(w.ctx, w.jm.config.BatchOutputBucket, outputCheckpointName(w.batch.ID), bytes.NewReader(data), int64(len(data)), "application/json")
+	if err != nil {
+		return fmt.Errorf("failed to put output checkpoint: %w", err)
+	}
+	w.saved = true
+	return nil
+}
+
+// fail returns err, after giving up on the checkpoint if an upload in it no longer
+// exists, e.g. because the object store expired it: the next summarisation then
+// starts again.
+func (w *outputWriter) fail(err error) error {
+	if errors.Is(err, objstore.ErrUploadNotFound) {
+		w.jm.logger.Warn().LogActivity("Upload of batch output file lost, discarding checkpoint", map[string]any{
+			"batchId": w.batch.ID.String(),
+			"error":   err.Error(),
+		})
+		w.jm.abortOutputUploads(w.ctx, w.batch.ID, w.cp)
+		w.jm.deleteOutputCheckpoint(w.ctx, w.batch.ID)
+	}
+	return err
+}
+
+// info returns the info block of an output file.
+func (w *outputWriter) info(logicalFile string, f *outputFile_t) string {
+	contentType := w.pipeline.contentType(w.fileFormat(logicalFile, f).ContentType())
+	return w.objectInfo(logicalFile+w.pipeline.extension(), contentType)
+}
+
+// objectInfo returns the info block of an object stored with the output files, naming
+// the batch as its owner (see "Object Metadata" in the README).
+func (w *outputWriter) objectInfo(filename, contentType string) string {
+	return objstore.Info{
+		MimeType:   contentType,
+		OwnerClass: "batch",
+		OwnerID:    w.batch.ID.String(),
+		Filename:   filename,
+		From:       w.batch.App + "/" + w.batch.Op,
+	}.String()
+}
+
+// discardOutputCheckpoint aborts the uploads in the checkpoint of the output files of
+// a batch, if it has one, and deletes the checkpoint, so that the next summarisation
+// of the batch starts again. Errors are only logged.
+func (jm *JobManager) discardOutputCheckpoint(ctx context.Context, batchID uuid.UUID) {
+	r, err := jm.objStore.Get(ctx, jm.config.BatchOutputBucket, outputCheckpointName(batchID))
+	if errors.Is(err, objstore.ErrObjectNotFound) {
+		return
+	}
+	if err == nil {
+		var cp outputCheckpoint_t
+		err = json.NewDecoder(r).Decode(&cp)
+		r.Close()
+		if err == nil {
+			jm.abortOutputUploads(ctx, batchID, cp)
+		}
+	}
+	if err != nil {
+		jm.logger.Warn().LogActivity("Failed to read output checkpoint", map[string]any{
+			"batchId": batchID.String(),
+			"error":   err.Error(),
+		})
+	}
+	jm.deleteOutputCheckpoint(ctx, batchID)
+}
+
+// abortOutputUploads aborts the uploads of the output files of a batch that are in
+// progress in cp. Errors are only logged.
+func (jm *JobManager) abortOutputUploads(ctx context.Context, batchID uuid.UUID, cp outputCheckpoint_t) {
+	for _, f := range cp.Files {
+		if !f.Done {
+			jm.abortOutputUpload(ctx, batchID, f)
+		}
+	}
+}
+
+// abortOutputUpload aborts the upload of an output file of a batch, if it has one.
+// Errors are only logged.
+func (jm *JobManager) abortOutputUpload(ctx context.Context, batchID uuid.UUID, f *outputFile_t) {
+	if f.UploadID == "" {
+		return
+	}
+	err := jm.objStore.AbortMultipartUpload(ctx, jm.config.BatchOutputBucket, f.ObjectID, f.UploadID)
+	if err != nil && !errors.Is(err, objstore.ErrUploadNotFound) {
+		jm.logger.Warn().LogActivity("Failed to abort upload of batch output file", map[string]any{
+			"batchId":  batchID.String(),
+			"objectId": f.ObjectID,
+			"error":    err.Error(),
+		})
+	}
+}
+
+// deleteOutputCheckpoint deletes the checkpoint of the output files of a batch.
+// Errors are only logged.
+func (jm *JobManager) deleteOutputCheckpoint(ctx context.Context, batchID uuid.UUID) {
+	err := jm.objStore.Delete(ctx, jm.config.BatchOutputBucket, outputCheckpointName(batchID))
+	if err != nil {
+		jm.logger.Warn().LogActivity("Failed to delete output checkpoint", map[string]any{
+			"batchId": batchID.String(),
+			"error":   err.Error(),
+		})
+	}
+}
+
+// outputCheckpointName returns the name of the checkpoint of the output files of a
+// batch in the batch output bucket.
+func outputCheckpointName(batchID uuid.UUID) string {
+	return outputCheckpointPrefix + batchID.String() + ".json"
+}
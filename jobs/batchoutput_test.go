@@ -0,0 +1,333 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/remiges-tech/alya/jobs/objstore"
+	"github.com/remiges-tech/alya/jobs/pg/batchsqlc"
+	"github.com/remiges-tech/alya/jobs/pg/batchsqlc/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBatchProcessingLogicalFiles tests the batch processing functionality
+// with multiple logical files generated from the blobrows data of individual
+// batch rows. It simulates a scenario where a batch job processes financial
+// transactions and generates two logical files:
+//
+//  1. transaction_summary.txt: Contains a summary line for each processed
+//     transaction, with details like transaction ID, type, amount, and balance.
+//
+//  2. error_log.txt: Contains error messages or logs generated during the
+//     processing of individual transactions.
+func TestBatchProcessingLogicalFiles(t *testing.T) {
+	// Prepare test data
+	batchRows := []batchsqlc.GetProcessedBatchRowsPageRow{
+		{
+			Rowid:    1,
+			Line:     1,
+			Blobrows: []byte(`{"transaction_summary.txt": "TX001,DEPOSIT,1000.00,5000.00", "error_log.txt": ""}`),
+		},
+		{
+			Rowid:    2,
+			Line:     2,
+			Blobrows: []byte(`{"transaction_summary.txt": "TX002,WITHDRAWAL,500.00,4500.00"}`),
+		},
+		{
+			Rowid:    3,
+			Line:     3,
+			Blobrows: []byte(`{"transaction_summary.txt": "TX003,WITHDRAWAL,20000.00,4500.00", "error_log.txt": "Invalid transaction amount"}`),
+		},
+	}
+	jm, _, objStore := newMemoryTestJobManager(t)
+	batch := batchsqlc.Batch{ID: uuid.New(), App: "bankapp", Op: "txns"}
+
+	// Call the function under test
+	outputFiles, _, err := jm.writeOutputFiles(context.Background(), pagedRowsQuerier(batchRows), batch, 3, 0, 0)
+	require.NoError(t, err)
+
+	// Verify that the correct files were created
+	require.Len(t, outputFiles, 2)
+
+	// Check transaction_summary.txt contents
+	expectedSummary := "TX001,DEPOSIT,1000.00,5000.00\nTX002,WITHDRAWAL,500.00,4500.00\nTX003,WITHDRAWAL,20000.00,4500.00\n"
+	require.Equal(t, expectedSummary, readOutputFile(t, jm, objStore, outputFiles, "transaction_summary.txt"))
+
+	// Check error_log.txt contents
+	expectedErrorLog := "Invalid transaction amount\n"
+	require.Equal(t, expectedErrorLog, readOutputFile(t, jm, objStore, outputFiles, "error_log.txt"))
+}
+
+// failingPartStore fails the PutPart calls after the first okParts, as if the
+// worker had died.
+type failingPartStore struct {
+	objstore.ObjectStore
+	okParts int
+	nparts  int
+}
+
+func (s *failingPartStore) PutPart(ctx context.Context, bucket, obj, uploadID string, number int, reader io.Reader, size int64) (objstore.Part, error) {
+	s.nparts++
+	if s.nparts > s.okParts {
+		return objstore.Part{}, errors.New("worker died")
+	}
+	return s.ObjectStore.PutPart(ctx, bucket, obj, uploadID, number, reader, size)
+}
+
+func TestWriteOutputFiles_Resume(t *testing.T) {
+	ctx := context.Background()
+	jm, _, memStore := newMemoryTestJobManager(t)
+	jm.config.SummaryChunkNRows = 4
+	jm.config.OutputPartSize = objstore.MinPartSize
+	batch := batchsqlc.Batch{ID: uuid.New(), App: "bankapp", Op: "txns"}
+
+	// Each row adds 1 MiB to big.txt, which is uploaded in parts of 5 rows: after
+	// lines 5 and 10, and the rest at the end
+	var rows []batchsqlc.GetProcessedBatchRowsPageRow
+	var big, small strings.Builder
+	for line := 1; line <= 12; line++ {
+		content := strings.Repeat(fmt.Sprintf("%x", line%16), 1<<20)
+		rows = append(rows, batchsqlc.GetProcessedBatchRowsPageRow{
+			Rowid:    int64(100 + line),
+			Line:     int32(line),
+			Blobrows: []byte(fmt.Sprintf(`{"big.txt": %q, "small.txt": "line %d"}`, content, line)),
+		})
+		big.WriteString(content + "\n")
+		small.WriteString(fmt.Sprintf("line %d\n", line))
+	}
+	q := pagedRowsQuerier(rows)
+
+	// The worker dies uploading the second part of big.txt
+	store := &failingPartStore{ObjectStore: memStore, okParts: 1}
+	jm.objStore = store
+	_, _, err := jm.writeOutputFiles(ctx, q, batch, 0, 0, 0)
+	require.Error(t, err)
+	_, err = memStore.Stat(ctx, jm.config.BatchOutputBucket, outputCheckpointName(batch.ID))
+	require.NoError(t, err, "no checkpoint after the first part")
+
+	// The next summarisation uploads only the parts that are missing
+	store.okParts, store.nparts = 2, 0
+	outputFiles, _, err := jm.writeOutputFiles(ctx, q, batch, 0, 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 2, store.nparts)
+	require.Len(t, outputFiles, 2)
+	assert.True(t, big.String() == readOutputFile(t, jm, memStore, outputFiles, "big.txt"), "big.txt differs")
+	assert.Equal(t, small.String(), readOutputFile(t, jm, memStore, outputFiles, "small.txt"))
+
+	info, size, _, err := objstore.ObjectInfo(ctx, memStore, outputFiles["big.txt"], jm.config.BatchOutputBucket)
+	require.NoError(t, err)
+	assert.JSONEq(t, fmt.Sprintf(`{"mimetype": "text/plain", "ownerclass": "batch", "ownerid": %q, "filename": "big.txt", "from": "bankapp/txns"}`, batch.ID), info)
+	assert.Equal(t, big.Len(), size)
+
+	_, err = memStore.Stat(ctx, jm.config.BatchOutputBucket, outputCheckpointName(batch.ID))
+	assert.ErrorIs(t, err, objstore.ErrObjectNotFound, "checkpoint left after summarisation")
+}
+
+func TestWriteOutputFiles_LostUpload(t *testing.T) {
+	ctx := context.Background()
+	jm, _, memStore := newMemoryTestJobManager(t)
+	jm.config.OutputPartSize = objstore.MinPartSize
+	batch := batchsqlc.Batch{ID: uuid.New(), App: "bankapp", Op: "txns"}
+	rows := []batchsqlc.GetProcessedBatchRowsPageRow{
+		{Rowid: 1, Line: 1, Blobrows: []byte(fmt.Sprintf(`{"big.txt": %q}`, strings.Repeat("a", objstore.MinPartSize)))},
+		{Rowid: 2, Line: 2, Blobrows: []byte(`{"big.txt": "b"}`)},
+	}
+	q := pagedRowsQuerier(rows)
+
+	// The worker dies after the first part
+	jm.objStore = &failingPartStore{ObjectStore: memStore, okParts: 1}
+	_, _, err := jm.writeOutputFiles(ctx, q, batch, 0, 0, 0)
+	require.Error(t, err)
+
+	// The upload expires before the next summarisation, which gives up on the
+	// checkpoint, so that the one after starts again
+	r, err := memStore.Get(ctx, jm.config.BatchOutputBucket, outputCheckpointName(batch.ID))
+	require.NoError(t, err)
+	var cp outputCheckpoint_t
+	require.NoError(t, json.NewDecoder(r).Decode(&cp))
+	r.Close()
+	require.Contains(t, cp.Files, "big.txt")
+	require.NoError(t, memStore.AbortMultipartUpload(ctx, jm.config.BatchOutputBucket, cp.Files["big.txt"].ObjectID, cp.Files["big.txt"].UploadID))
+
+	jm.objStore = memStore
+	_, _, err = jm.writeOutputFiles(ctx, q, batch, 0, 0, 0)
+	assert.ErrorIs(t, err, objstore.ErrUploadNotFound)
+	_, err = memStore.Stat(ctx, jm.config.BatchOutputBucket, outputCheckpointName(batch.ID))
+	assert.ErrorIs(t, err, objstore.ErrObjectNotFound, "checkpoint kept after losing its upload")
+
+	outputFiles, _, err := jm.writeOutputFiles(ctx, q, batch, 0, 0, 0)
+	require.NoError(t, err)
+	assert.True(t, strings.Repeat("a", objstore.MinPartSize)+"\nb\n" == readOutputFile(t, jm, memStore, outputFiles, "big.txt"), "big.txt differs")
+}
+
+// pagedRowsQuerier returns a querier that serves rows, sorted by line and rowid,
+// as the processed rows of any batch.
+func pagedRowsQuerier(rows []batchsqlc.GetProcessedBatchRowsPageRow) *mocks.QuerierMock {
+	return &mocks.QuerierMock{
+		GetProcessedBatchRowsPageFunc: func(ctx context.Context, arg batchsqlc.GetProcessedBatchRowsPageParams) ([]batchsqlc.GetProcessedBatchRowsPageRow, error) {
+			var page []batchsqlc.GetProcessedBatchRowsPageRow
+			after := rowPos_t{Line: arg.AfterLine, Rowid: arg.AfterRowid}
+			for _, row := range rows {
+				if (rowPos_t{Line: row.Line, Rowid: row.Rowid}).after(after) && len(page) < int(arg.PageSize) {
+					page = append(page, row)
+				}
+			}
+			return page, nil
+		},
+	}
+}
+
+func readOutputFile(t *testing.T, jm *JobManager, store objstore.ObjectStore, outputFiles map[string]string, logicalFile string) string {
+	t.Helper()
+	require.Contains(t, outputFiles, logicalFile)
+	data, err := objstore.ObjectGet(context.Background(), store, outputFiles[logicalFile], jm.config.BatchOutputBucket)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestWriteOutputFiles_Formats(t *testing.T) {
+	ctx := context.Background()
+	jm, _, memStore := newMemoryTestJobManager(t)
+	require.NoError(t, jm.RegisterOutputFormat("bankapp", "txns", "txns.csv", CSVFormat{
+		Columns: []string{"id", "amount"},
+		Trailer: func(s OutputSummary_t) [][]any {
+			return [][]any{{"records", s.NRecords}, {"failed rows", s.NFailed}}
+		},
+	}))
+	batch := batchsqlc.Batch{ID: uuid.New(), App: "bankapp", Op: "txns"}
+	rows := []batchsqlc.GetProcessedBatchRowsPageRow{
+		{Rowid: 1, Line: 1, Blobrows: []byte(`{"txns.csv": "{\"id\": \"TX001\", \"amount\": 10}", "notes.txt": "checked"}`)},
+		{Rowid: 2, Line: 2, Blobrows: []byte(`{"txns.csv": "[\"TX002\", \"1,000\"]"}`)},
+		{Rowid: 3, Line: 3, Blobrows: []byte(`{"notes.txt": "TX003 failed"}`)},
+	}
+
+	outputFiles, outputTypes, err := jm.writeOutputFiles(ctx, pagedRowsQuerier(rows), batch, 2, 1, 0)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"txns.csv": "text/csv", "notes.txt": "text/plain"}, outputTypes)
+	assert.Equal(t, "id,amount\nTX001,10\nTX002,\"1,000\"\nrecords,2\nfailed rows,1\n", readOutputFile(t, jm, memStore, outputFiles, "txns.csv"))
+	assert.Equal(t, "checked\nTX003 failed\n", readOutputFile(t, jm, memStore, outputFiles, "notes.txt"))
+
+	info, _, _, err := objstore.ObjectInfo(ctx, memStore, outputFiles["txns.csv"], jm.config.BatchOutputBucket)
+	require.NoError(t, err)
+	assert.Contains(t, info, `"mimetype":"text/csv"`)
+	attrs, err := memStore.Stat(ctx, jm.config.BatchOutputBucket, outputFiles["txns.csv"])
+	require.NoError(t, err)
+	assert.Equal(t, "text/csv", attrs.ContentType)
+
+	// A content that does not fit the format fails the summarisation
+	rows = append(rows, batchsqlc.GetProcessedBatchRowsPageRow{Rowid: 4, Line: 4, Blobrows: []byte(`{"txns.csv": "TX004,5"}`)})
+	batch.ID = uuid.New()
+	_, _, err = jm.writeOutputFiles(ctx, pagedRowsQuerier(rows), batch, 2, 1, 0)
+	assert.ErrorContains(t, err, "failed to write line 4 to txns.csv")
+}
+
+// restartFormat is the text format, except that it is not resumable.
+type restartFormat struct {
+	TextFormat
+}
+
+func (restartFormat) Resumable() bool { return false }
+
+func TestWriteOutputFiles_ResumeNotResumable(t *testing.T) {
+	ctx := context.Background()
+	jm, _, memStore := newMemoryTestJobManager(t)
+	jm.config.OutputPartSize = objstore.MinPartSize
+	require.NoError(t, jm.RegisterOutputFormat("bankapp", "txns", "big.txt", restartFormat{}))
+	batch := batchsqlc.Batch{ID: uuid.New(), App: "bankapp", Op: "txns"}
+
+	var rows []batchsqlc.GetProcessedBatchRowsPageRow
+	var big strings.Builder
+	for line := 1; line <= 12; line++ {
+		content := strings.Repeat(fmt.Sprintf("%x", line%16), 1<<20)
+		rows = append(rows, batchsqlc.GetProcessedBatchRowsPageRow{
+			Rowid:    int64(line),
+			Line:     int32(line),
+			Blobrows: []byte(fmt.Sprintf(`{"big.txt": %q}`, content)),
+		})
+		big.WriteString(content + "\n")
+	}
+	q := pagedRowsQuerier(rows)
+
+	// The worker dies uploading the second part
+	store := &failingPartStore{ObjectStore: memStore, okParts: 1}
+	jm.objStore = store
+	_, _, err := jm.writeOutputFiles(ctx, q, batch, 12, 0, 0)
+	require.Error(t, err)
+
+	// The file cannot be continued from its first part, so all its parts are
+	// uploaded again
+	store.okParts, store.nparts = 3, 0
+	outputFiles, _, err := jm.writeOutputFiles(ctx, q, batch, 12, 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 3, store.nparts)
+	assert.True(t, big.String() == readOutputFile(t, jm, memStore, outputFiles, "big.txt"), "big.txt differs")
+}
+
+func TestWriteOutputFiles_Pipeline(t *testing.T) {
+	ctx := context.Background()
+	jm, _, memStore := newMemoryTestJobManager(t)
+	key := make([]byte, 32)
+	rand.Read(key)
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	require.NoError(t, jm.RegisterOutputFormat("bankapp", "txns", "txns.csv", CSVFormat{Columns: []string{"id", "amount"}}))
+	require.NoError(t, jm.RegisterOutputPipeline("bankapp", "TXNS", OutputPipeline{
+		Compress:   true,
+		Encryption: AESGCMEncryption{Key: key},
+		Signer:     Ed25519Signer{Key: priv},
+		Manifest:   true,
+	}))
+	batch := batchsqlc.Batch{ID: uuid.New(), App: "bankapp", Op: "txns"}
+	rows := []batchsqlc.GetProcessedBatchRowsPageRow{
+		{Rowid: 1, Line: 1, Blobrows: []byte(`{"txns.csv": "[\"TX001\", 10]", "notes.txt": "checked"}`)},
+		{Rowid: 2, Line: 2, Blobrows: []byte(`{"txns.csv": "[\"TX002\", 20]"}`)},
+	}
+
+	outputFiles, outputTypes, err := jm.writeOutputFiles(ctx, pagedRowsQuerier(rows), batch, 2, 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"txns.csv":                  "application/octet-stream",
+		"txns.csv.sig":              "application/octet-stream",
+		"notes.txt":                 "application/octet-stream",
+		"notes.txt.sig":             "application/octet-stream",
+		OutputManifestName:          "text/plain",
+		OutputManifestName + ".sig": "application/octet-stream",
+	}, outputTypes)
+	assert.Len(t, outputFiles, len(outputTypes))
+
+	info, _, _, err := objstore.ObjectInfo(ctx, memStore, outputFiles["txns.csv"], jm.config.BatchOutputBucket)
+	require.NoError(t, err)
+	assert.Contains(t, info, `"filename":"txns.csv.gz.enc"`)
+
+	manifest := []byte(readOutputFile(t, jm, memStore, outputFiles, OutputManifestName))
+	manifestSig := readOutputFile(t, jm, memStore, outputFiles, OutputManifestName+".sig")
+	require.NoError(t, VerifyEd25519Signature(pub, bytes.NewReader(manifest), []byte(manifestSig)))
+	for file, want := range map[string]string{"txns.csv": "id,amount\nTX001,10\nTX002,20\n", "notes.txt": "checked\n"} {
+		sealed := readOutputFile(t, jm, memStore, outputFiles, file)
+		sig := readOutputFile(t, jm, memStore, outputFiles, file+".sig")
+		assert.NoError(t, VerifyEd25519Signature(pub, strings.NewReader(sealed), []byte(sig)))
+		assert.NoError(t, VerifyOutputManifest(manifest, file+".gz.enc", strings.NewReader(sealed)))
+
+		r, err := DecodeOutputFile(strings.NewReader(sealed), file+".gz.enc", OutputKeys{AESKey: key})
+		require.NoError(t, err)
+		plain, err := io.ReadAll(r)
+		require.NoError(t, err)
+		assert.Equal(t, want, string(plain))
+	}
+
+	// A logical file cannot be named as a signature
+	rows = append(rows, batchsqlc.GetProcessedBatchRowsPageRow{Rowid: 3, Line: 3, Blobrows: []byte(`{"notes.txt.sig": "x"}`)})
+	batch.ID = uuid.New()
+	_, _, err = jm.writeOutputFiles(ctx, pagedRowsQuerier(rows), batch, 3, 0, 0)
+	assert.ErrorContains(t, err, "clashes with a signature")
+}
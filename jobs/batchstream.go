@@ -0,0 +1,190 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/remiges-tech/alya/jobs/pg/batchsqlc"
+)
+
+var (
+	ErrEmptyBatch   = errors.New("batch has no input rows")
+	ErrInvalidInput = errors.New("invalid batch input row")
+)
+
+// BatchInputIterator yields the input rows of a batch one at a time, so that a batch
+// can be submitted without holding all of its rows in memory.
+// Next returns io.EOF once there are no more rows.
+type BatchInputIterator interface {
+	Next() (BatchInput_t, error)
+}
+
+// BatchInputFunc adapts an ordinary function to the BatchInputIterator interface.
+type BatchInputFunc func() (BatchInput_t, error)
+
+// Next calls f.
+func (f BatchInputFunc) Next() (BatchInput_t, error) {
+	return f()
+}
+
+// jsonLinesBatchInput reads batch input rows from a stream of JSON values.
+type jsonLinesBatchInput struct {
+	dec  *json.Decoder
+	line int
+}
+
+// NewJSONLinesBatchInput returns a BatchInputIterator that reads one JSON value per input
+// row from r, as found in JSON Lines files. Rows are numbered from 1 in the order read.
+func NewJSONLinesBatchInput(r io.Reader) BatchInputIterator {
+	return &jsonLinesBatchInput{dec: json.NewDecoder(r)}
+}
+
+func (it *jsonLinesBatchInput) Next() (BatchInput_t, error) {
+	var raw json.RawMessage
+	if err := it.dec.Decode(&raw); err != nil {
+		if err == io.EOF {
+			return BatchInput_t{}, io.EOF
+		}
+		return BatchInput_t{}, fmt.Errorf("%w: row %d: %v", ErrInvalidInput, it.line+1, err)
+	}
+	it.line++
+	return BatchInput_t{Line: it.line, Input: JSONstr{value: string(raw), valid: true}}, nil
+}
+
+// BatchSubmitStream submits a new batch like BatchSubmitWithOptions, reading its input rows
+// from rows instead of a slice. The rows are loaded with COPY in chunks of
+// JobManagerConfig.SubmitChunkNRows rows, so memory use does not grow with the size of the
+// batch. The batch record and all its rows are written in a single transaction: if rows
+// returns an error other than io.EOF, or loading fails, nothing is submitted.
+// It returns the number of rows submitted.
+func (jm *JobManager) BatchSubmitStream(app, op string, batchctx JSONstr, rows BatchInputIterator, opts SubmitOptions_t) (batchID string, nrows int, err error) {
+	ctx := context.Background()
+
+	// Generate a unique batch ID
+	batchUUID, err := uuid.NewUUID()
+	if err != nil {
+		return "", 0, err
+	}
+
+	// Start a transaction
+	tx, err := jm.store.Begin(ctx)
+	if err != nil {
+		return "", 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Create transaction-bound queries
+	txQueries := tx.Queries()
+
+	// A repeated submission returns the batch submitted first, without reading rows
+	if opts.IdempotencyKey != "" {
+		existing, err := jm.claimIdempotencyKey(ctx, txQueries, app, opts.IdempotencyKey, batchUUID)
+		if err != nil {
+			return "", 0, err
+		}
+		if existing != uuid.Nil {
+			nrows, err := txQueries.GetBatchRowsCount(ctx, existing)
+			if err != nil {
+				return "", 0, fmt.Errorf("failed to count batch rows: %w", err)
+			}
+			return existing.String(), int(nrows), nil
+		}
+	}
+
+	err = insertBatchRecord(ctx, txQueries, batchUUID, app, op, batchctx, opts)
+	if err != nil {
+		return "", 0, err
+	}
+
+	nrows, err = copyBatchRows(ctx, txQueries, batchUUID, int32(opts.Priority), rows, jm.config.SubmitChunkNRows)
+	if err != nil {
+		return "", 0, err
+	}
+	if nrows == 0 {
+		return "", 0, ErrEmptyBatch
+	}
+
+	// Wake up idle workers once the batch is committed, unless it is not ready to run yet
+	if len(opts.DependsOn) > 0 {
+		// Its parents may all be done already
+		if err := jm.resolveDependentBatch(ctx, txQueries, batchUUID); err != nil {
+			return "", 0, err
+		}
+	} else if !opts.WaitABit && !opts.RunAt.After(time.Now()) {
+		if err := jm.notifyJobsQueued(ctx, txQueries, app); err != nil {
+			return "", 0, err
+		}
+	}
+
+	// Commit the transaction
+	err = tx.Commit(ctx)
+	if err != nil {
+		return "", 0, err
+	}
+
+	jm.logger.Info().LogActivity("Batch submitted from stream", map[string]any{
+		"batchId": batchUUID.String(),
+		"app":     app,
+		"op":      op,
+		"nrows":   nrows,
+	})
+	return batchUUID.String(), nrows, nil
+}
+
+// copyBatchRows reads all rows and loads them into the batchrows table of the given batch,
+// with the priority of the batch, chunkSize rows at a time. It returns the number of rows loaded.
+func copyBatchRows(ctx context.Context, q batchsqlc.Querier, batchUUID uuid.UUID, priority int32, rows BatchInputIterator, chunkSize int) (int, error) {
+	chunkSize = max(chunkSize, 1)
+	nrows := 0
+	chunk := make([]batchsqlc.CopyBatchRowsParams, 0, chunkSize)
+	flush := func() error {
+		if len(chunk) == 0 {
+			return nil
+		}
+		if _, err := q.CopyBatchRows(ctx, chunk); err != nil {
+			return fmt.Errorf("failed to copy batch rows: %w", err)
+		}
+		nrows += len(chunk)
+		chunk = make([]batchsqlc.CopyBatchRowsParams, 0, chunkSize)
+		return nil
+	}
+
+	for {
+		input, err := rows.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to read batch input: %w", err)
+		}
+		if input.Line <= 0 {
+			return 0, fmt.Errorf("%w: invalid line number: %d", ErrInvalidInput, input.Line)
+		}
+		if !input.Input.IsValid() {
+			return 0, fmt.Errorf("%w: line %d: input is not valid JSON", ErrInvalidInput, input.Line)
+		}
+
+		chunk = append(chunk, batchsqlc.CopyBatchRowsParams{
+			Batch:    batchUUID,
+			Line:     int32(input.Line),
+			Input:    []byte(input.Input.String()),
+			Reqat:    pgtype.Timestamp{Time: time.Now(), Valid: true},
+			Priority: priority,
+		})
+		if len(chunk) == chunkSize {
+			if err := flush(); err != nil {
+				return 0, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return 0, err
+	}
+	return nrows, nil
+}
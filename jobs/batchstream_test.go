@@ -0,0 +1,142 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/remiges-tech/alya/jobs/pg/batchsqlc"
+	"github.com/remiges-tech/alya/jobs/pg/batchsqlc/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sliceBatchInput returns an iterator over n rows numbered from 1.
+func sliceBatchInput(n int) BatchInputIterator {
+	i := 0
+	return BatchInputFunc(func() (BatchInput_t, error) {
+		if i == n {
+			return BatchInput_t{}, io.EOF
+		}
+		i++
+		input, _ := NewJSONstr(`{"n":1}`)
+		return BatchInput_t{Line: i, Input: input}, nil
+	})
+}
+
+func newCopyQuerier() *mocks.QuerierMock {
+	return &mocks.QuerierMock{
+		CopyBatchRowsFunc: func(ctx context.Context, arg []batchsqlc.CopyBatchRowsParams) (int64, error) {
+			return int64(len(arg)), nil
+		},
+	}
+}
+
+func TestNewJSONLinesBatchInput(t *testing.T) {
+	r := strings.NewReader("{\"acct\":\"A1\"}\n\n{\"acct\":\"A2\",\n \"amt\":5}\n[1,2]\n")
+	rows := NewJSONLinesBatchInput(r)
+
+	var got []BatchInput_t
+	for {
+		row, err := rows.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		got = append(got, row)
+	}
+	require.Len(t, got, 3)
+	assert.Equal(t, []int{1, 2, 3}, []int{got[0].Line, got[1].Line, got[2].Line})
+	assert.JSONEq(t, `{"acct":"A2","amt":5}`, got[1].Input.String())
+	assert.True(t, got[2].Input.IsValid())
+
+	rows = NewJSONLinesBatchInput(strings.NewReader("{\"acct\":\"A1\"}\n{\"acct\":\n"))
+	_, err := rows.Next()
+	require.NoError(t, err)
+	_, err = rows.Next()
+	assert.ErrorIs(t, err, ErrInvalidInput)
+}
+
+func TestCopyBatchRows(t *testing.T) {
+	ctx := context.Background()
+	batchUUID := uuid.New()
+
+	t.Run("chunks", func(t *testing.T) {
+		q := newCopyQuerier()
+
+		nrows, err := copyBatchRows(ctx, q, batchUUID, 0, sliceBatchInput(7), 3)
+		require.NoError(t, err)
+		assert.Equal(t, 7, nrows)
+
+		calls := q.CopyBatchRowsCalls()
+		require.Len(t, calls, 3)
+		assert.Len(t, calls[0].Arg, 3)
+		assert.Len(t, calls[1].Arg, 3)
+		assert.Len(t, calls[2].Arg, 1)
+		assert.Equal(t, batchUUID, calls[0].Arg[0].Batch)
+		assert.Equal(t, int32(1), calls[0].Arg[0].Line)
+		assert.Equal(t, int32(7), calls[2].Arg[0].Line)
+		assert.JSONEq(t, `{"n":1}`, string(calls[2].Arg[0].Input))
+		assert.True(t, calls[2].Arg[0].Reqat.Valid)
+	})
+
+	t.Run("exact multiple", func(t *testing.T) {
+		q := newCopyQuerier()
+
+		nrows, err := copyBatchRows(ctx, q, batchUUID, 0, sliceBatchInput(6), 3)
+		require.NoError(t, err)
+		assert.Equal(t, 6, nrows)
+		assert.Len(t, q.CopyBatchRowsCalls(), 2)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		q := newCopyQuerier()
+
+		nrows, err := copyBatchRows(ctx, q, batchUUID, 0, sliceBatchInput(0), 3)
+		require.NoError(t, err)
+		assert.Zero(t, nrows)
+		assert.Empty(t, q.CopyBatchRowsCalls())
+	})
+
+	t.Run("reader error", func(t *testing.T) {
+		q := newCopyQuerier()
+		src := sliceBatchInput(4)
+		n := 0
+		rows := BatchInputFunc(func() (BatchInput_t, error) {
+			if n++; n == 4 {
+				return BatchInput_t{}, errors.New("disk read failed")
+			}
+			return src.Next()
+		})
+
+		_, err := copyBatchRows(ctx, q, batchUUID, 0, rows, 2)
+		assert.ErrorContains(t, err, "disk read failed")
+	})
+
+	t.Run("invalid line", func(t *testing.T) {
+		q := newCopyQuerier()
+		rows := BatchInputFunc(func() (BatchInput_t, error) {
+			input, _ := NewJSONstr(`{}`)
+			return BatchInput_t{Line: 0, Input: input}, nil
+		})
+
+		_, err := copyBatchRows(ctx, q, batchUUID, 0, rows, 2)
+		assert.ErrorIs(t, err, ErrInvalidInput)
+		assert.Empty(t, q.CopyBatchRowsCalls())
+	})
+
+	t.Run("copy error", func(t *testing.T) {
+		q := &mocks.QuerierMock{
+			CopyBatchRowsFunc: func(ctx context.Context, arg []batchsqlc.CopyBatchRowsParams) (int64, error) {
+				return 0, errors.New("connection reset")
+			},
+		}
+
+		_, err := copyBatchRows(ctx, q, batchUUID, 0, sliceBatchInput(5), 2)
+		assert.ErrorContains(t, err, "connection reset")
+		assert.Len(t, q.CopyBatchRowsCalls(), 1)
+	})
+}
@@ -4,15 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
-	"os"
-	"strings"
 	"time"
 
-	"github.com/go-redis/redis/v8"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
-	"github.com/remiges-tech/alya/jobs/objstore"
 	"github.com/remiges-tech/alya/jobs/pg/batchsqlc"
 )
 
@@ -152,29 +147,13 @@ func (jm *JobManager) summarizeBatch(q batchsqlc.Querier, batchID uuid.UUID) err
 		"status": batchStatus,
 	})
 
-	// Fetch processed batchrows records for the batch to create temporary files
-	processedBatchRows, err := q.GetProcessedBatchRowsByBatchIDSorted(ctx, batchID)
+	// Stream the processed rows into the output files in the object store
+	objStoreFiles, outputTypes, err := jm.writeOutputFiles(ctx, q, batch, nsuccess, nfailed, naborted)
 	if err != nil {
-		return fmt.Errorf("failed to get processed batch rows sorted: %v", err)
-	}
-
-	// Create temporary files for each unique logical file in blobrows
-	tmpFiles, err := createTemporaryFiles(processedBatchRows)
-	if err != nil {
-		return fmt.Errorf("failed to create temporary files: %v", err)
-	}
-	defer cleanupTemporaryFiles(tmpFiles)
-
-	// Append blobrows strings to the appropriate temporary files
-	err = appendBlobRowsToFiles(processedBatchRows, tmpFiles)
-	if err != nil {
-		return fmt.Errorf("failed to append blobrows to files: %v", err)
-	}
-
-	// Move temporary files to the object store and update outputfiles
-	objStoreFiles, err := moveFilesToObjectStore(tmpFiles, jm.objStore, jm.config.BatchOutputBucket)
-	if err != nil {
-		return fmt.Errorf("failed to move files to object store: %v", err)
+		jm.logger.Error(err).LogActivity("Failed to write output files", map[string]any{
+			"batchId": batchID.String(),
+		})
+		return fmt.Errorf("failed to write output files: %w", err)
 	}
 
 	// Update the batches record with summarized information
@@ -186,7 +165,7 @@ func (jm *JobManager) summarizeBatch(q batchsqlc.Querier, batchID uuid.UUID) err
 		"nFailed": nfailed,
 		"nAborted": naborted,
 	})
-	err = updateBatchSummary(q, ctx, batchID, batchStatus, objStoreFiles, nsuccess, nfailed, naborted)
+	err = updateBatchSummary(q, ctx, batchID, batchStatus, objStoreFiles, outputTypes, nsuccess, nfailed, naborted)
 	if err != nil {
 		jm.logger.Error(err).LogActivity("Failed to update batch summary", map[string]any{
 			"batchId": batchID.String(),
@@ -194,6 +173,38 @@ func (jm *JobManager) summarizeBatch(q batchsqlc.Querier, batchID uuid.UUID) err
 		return fmt.Errorf("failed to update batch summary: %v", err)
 	}
 
+	// Queue the completion webhook in the same transaction as the summary
+	if batch.CallbackUrl.Valid {
+		err = enqueueCompletionWebhook(ctx, q, batchID, CompletionEvent_t{
+			Event:           WebhookEventBatchDone,
+			ID:              batchID.String(),
+			App:             batch.App,
+			Op:              batch.Op,
+			Status:          batchStatus,
+			NSuccess:        int(nsuccess),
+			NFailed:         int(nfailed),
+			NAborted:        int(naborted),
+			OutputFiles:     objStoreFiles,
+			OutputFileTypes: outputTypes,
+			DoneAt:          time.Now(),
+		})
+		if err != nil {
+			jm.logger.Error(err).LogActivity("Failed to queue completion webhook", map[string]any{
+				"batchId": batchID.String(),
+			})
+			return err
+		}
+	}
+
+	// Abort or queue the batches waiting on this one, in the same transaction
+	err = jm.releaseDependents(ctx, q, batchID)
+	if err != nil {
+		jm.logger.Error(err).LogActivity("Failed to release dependent batches", map[string]any{
+			"batchId": batchID.String(),
+		})
+		return err
+	}
+
 	jm.logger.Info().LogActivity("Batch summary database update completed successfully", map[string]any{
 		"batchId": batchID.String(),
 		"status": batchStatus,
@@ -205,7 +216,7 @@ func (jm *JobManager) summarizeBatch(q batchsqlc.Querier, batchID uuid.UUID) err
 		"status":        batchStatus,
 		"cacheDuration": 100 * jm.config.BatchStatusCacheDurSec,
 	})
-	err = updateStatusInRedis(jm.redisClient, batchID, batchStatus, 100*jm.config.BatchStatusCacheDurSec)
+	err = updateStatusInRedis(jm.statusCache, batchID, batchStatus, 100*jm.config.BatchStatusCacheDurSec)
 	if err != nil {
 		jm.logger.Warn().LogActivity("Failed to update status in Redis cache for batch", map[string]any{
 			"batchId": batchID.String(),
@@ -215,7 +226,7 @@ func (jm *JobManager) summarizeBatch(q batchsqlc.Querier, batchID uuid.UUID) err
 	}
 
 	// Cache the batch summary for BatchStatus API
-	err = updateBatchSummaryInRedis(jm.redisClient, batchID, batchStatus, objStoreFiles,
+	err = updateBatchSummaryInRedis(jm.statusCache, batchID, batchStatus, objStoreFiles,
 		int(nsuccess), int(nfailed), int(naborted), 100*jm.config.BatchStatusCacheDurSec)
 	if err != nil {
 		jm.logger.Warn().LogActivity("Failed to cache batch summary in Redis", map[string]any{
@@ -224,6 +235,7 @@ func (jm *JobManager) summarizeBatch(q batchsqlc.Querier, batchID uuid.UUID) err
 		})
 		// Continue despite Redis failure - Redis is just a cache
 	}
+	jm.clearBatchProgress(batchID)
 
 	context, err := NewJSONstr(string(batch.Context))
 	if err != nil {
@@ -238,15 +250,16 @@ func (jm *JobManager) summarizeBatch(q batchsqlc.Querier, batchID uuid.UUID) err
 	}
 
 	details := BatchDetails_t{
-		ID:          batchID.String(),
-		App:         batch.App,
-		Op:          batch.Op,
-		Context:     context,
-		Status:      batchStatus,
-		OutputFiles: objStoreFiles,
-		NSuccess:    int(nsuccess),
-		NFailed:     int(nfailed),
-		NAborted:    int(naborted),
+		ID:              batchID.String(),
+		App:             batch.App,
+		Op:              batch.Op,
+		Context:         context,
+		Status:          batchStatus,
+		OutputFiles:     objStoreFiles,
+		OutputFileTypes: outputTypes,
+		NSuccess:        int(nsuccess),
+		NFailed:         int(nfailed),
+		NAborted:        int(naborted),
 	}
 
 	// Get or create InitBlock
@@ -274,7 +287,7 @@ func (jm *JobManager) summarizeBatch(q batchsqlc.Querier, batchID uuid.UUID) err
 			"processorType": "batch",
 		})
 		markDoneStart := time.Now()
-		if err := processor.MarkDone(initBlock, context, details); err != nil {
+		if err := processor.MarkDone(ctx, initBlock, context, details); err != nil {
 			jm.logger.Error(err).LogActivity("MarkDone failed for batch", map[string]any{
 				"batchId": batchID.String(),
 				"app": batch.App,
@@ -298,7 +311,7 @@ func (jm *JobManager) summarizeBatch(q batchsqlc.Querier, batchID uuid.UUID) err
 				"processorType": "slowquery",
 			})
 			markDoneStart := time.Now()
-			if err := slowqueryprocessor.MarkDone(initBlock, context, details); err != nil {
+			if err := slowqueryprocessor.MarkDone(ctx, initBlock, context, details); err != nil {
 				jm.logger.Error(err).LogActivity("MarkDone failed for slow query", map[string]any{
 					"batchId": batchID.String(),
 					"app": batch.App,
@@ -348,92 +361,15 @@ func determineBatchStatus(nsuccess, nfailed, naborted int64) batchsqlc.StatusEnu
 	}
 }
 
-func createTemporaryFiles(batchRows []batchsqlc.GetProcessedBatchRowsByBatchIDSortedRow) (map[string]*os.File, error) {
-	tmpFiles := make(map[string]*os.File)
-	for _, row := range batchRows {
-		if len(row.Blobrows) > 0 {
-			var blobRows map[string]any
-			if err := json.Unmarshal(row.Blobrows, &blobRows); err != nil {
-				return nil, fmt.Errorf("failed to unmarshal blobrows: %v", err)
-			}
-
-			for logicalFile, content := range blobRows {
-				// Check if the content is not empty before creating the temporary file
-				if content != "" {
-					if _, exists := tmpFiles[logicalFile]; !exists {
-						file, err := os.CreateTemp("", logicalFile)
-						if err != nil {
-							return nil, fmt.Errorf("failed to create temporary file: %v", err)
-						}
-						tmpFiles[logicalFile] = file
-					}
-				}
-			}
-		}
-	}
-	return tmpFiles, nil
-}
-
-func cleanupTemporaryFiles(tmpFiles map[string]*os.File) {
-	for _, file := range tmpFiles {
-		if err := file.Close(); err != nil {
-			// Use standard log for cleanup errors as we don't have access to JobManager here
-			log.Printf("failed to close temporary file: %v", err)
-		}
-		if err := os.Remove(file.Name()); err != nil {
-			log.Printf("failed to remove temporary file: %v", err)
-		}
-	}
-}
-
-func appendBlobRowsToFiles(batchRows []batchsqlc.GetProcessedBatchRowsByBatchIDSortedRow, tmpFiles map[string]*os.File) error {
-	for _, row := range batchRows {
-		if len(row.Blobrows) == 0 {
-			continue
-		}
-
-		var blobRows map[string]string
-		if err := json.Unmarshal(row.Blobrows, &blobRows); err != nil {
-			return fmt.Errorf("failed to unmarshal blobrows: %v", err)
-		}
-
-		for logicalFile, content := range blobRows {
-			content = strings.TrimSpace(content)
-			if content == "" {
-				continue
-			}
-
-			if file, ok := tmpFiles[logicalFile]; ok {
-				// Only write if there's content and a new line if content was successfully written
-				if _, err := file.WriteString(content); err != nil {
-					return fmt.Errorf("failed to write content to file: %v", err)
-				}
-				if _, err := file.WriteString("\n"); err != nil {
-					return fmt.Errorf("failed to write newline to file: %v", err)
-				}
-			}
-		}
-	}
-	return nil
-}
-
-func moveFilesToObjectStore(tmpFiles map[string]*os.File, store objstore.ObjectStore, bucket string) (map[string]string, error) {
-	outputFiles := make(map[string]string)
-	for logicalFile, file := range tmpFiles {
-		objectID, err := moveToObjectStore(file.Name(), store, bucket)
-		if err != nil {
-			return nil, fmt.Errorf("failed to move file to object store: %v", err)
-		}
-		outputFiles[logicalFile] = objectID
-	}
-	return outputFiles, nil
-}
-
-func updateBatchSummary(q batchsqlc.Querier, ctx context.Context, batchID uuid.UUID, status batchsqlc.StatusEnum, outputFiles map[string]string, nsuccess, nfailed, naborted int64) error {
+func updateBatchSummary(q batchsqlc.Querier, ctx context.Context, batchID uuid.UUID, status batchsqlc.StatusEnum, outputFiles, outputTypes map[string]string, nsuccess, nfailed, naborted int64) error {
 	outputFilesJSON, err := json.Marshal(outputFiles)
 	if err != nil {
 		return fmt.Errorf("failed to marshal output files: %v", err)
 	}
+	outputTypesJSON, err := json.Marshal(outputTypes)
+	if err != nil {
+		return fmt.Errorf("failed to marshal output file types: %v", err)
+	}
 
 	err = q.UpdateBatchSummary(ctx, batchsqlc.UpdateBatchSummaryParams{
 		ID:          batchID,
@@ -443,6 +379,7 @@ func updateBatchSummary(q batchsqlc.Querier, ctx context.Context, batchID uuid.U
 		Nsuccess:    pgtype.Int4{Int32: int32(nsuccess), Valid: true},
 		Nfailed:     pgtype.Int4{Int32: int32(nfailed), Valid: true},
 		Naborted:    pgtype.Int4{Int32: int32(naborted), Valid: true},
+		Outputtypes: outputTypesJSON,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to update batch summary: %v", err)
@@ -450,24 +387,40 @@ func updateBatchSummary(q batchsqlc.Querier, ctx context.Context, batchID uuid.U
 	return nil
 }
 
-// updateStatusInRedis updates the batch status in Redis.
-// Redis SET is atomic, so no transaction is needed for single-key updates.
-func updateStatusInRedis(redisClient *redis.Client, batchID uuid.UUID, status batchsqlc.StatusEnum, expirySec int) error {
+// getFromRedis reads a key of the status cache. Without a status cache every key
+// is missing, so that status reads go to the database.
+func (jm *JobManager) getFromRedis(ctx context.Context, key string) (string, error) {
+	if jm.statusCache == nil {
+		return "", ErrStatusCacheMiss
+	}
+	return jm.statusCache.Get(ctx, key)
+}
+
+// updateStatusInRedis updates the batch status in the status cache.
+// A single SET is atomic, so no transaction is needed for single-key updates.
+// Without a status cache it does nothing, as do the other cache updates.
+func updateStatusInRedis(cache StatusCache, batchID uuid.UUID, status batchsqlc.StatusEnum, expirySec int) error {
+	if cache == nil {
+		return nil
+	}
 	redisKey := BatchStatusKey(batchID.String())
 	expiry := time.Duration(expirySec) * time.Second
 
-	err := redisClient.Set(context.Background(), redisKey, string(status), expiry).Err()
+	err := cache.Set(context.Background(), redisKey, string(status), expiry)
 	if err != nil {
 		return fmt.Errorf("failed to update status in Redis: %w", err)
 	}
 	return nil
 }
 
-// updateBatchSummaryInRedis caches the batch summary (status, outputfiles, counters) in Redis.
-// Uses a single key with JSON blob for atomic read/write.
-func updateBatchSummaryInRedis(redisClient *redis.Client, batchID uuid.UUID,
+// updateBatchSummaryInRedis caches the batch summary (status, outputfiles, counters) in the
+// status cache. Uses a single key with JSON blob for atomic read/write.
+func updateBatchSummaryInRedis(cache StatusCache, batchID uuid.UUID,
 	status batchsqlc.StatusEnum, outputFiles map[string]string,
 	nsuccess, nfailed, naborted int, expirySec int) error {
+	if cache == nil {
+		return nil
+	}
 
 	summary := BatchSummary_t{
 		Status:      string(status),
@@ -485,17 +438,20 @@ func updateBatchSummaryInRedis(redisClient *redis.Client, batchID uuid.UUID,
 	redisKey := BatchSummaryKey(batchID.String())
 	expiry := time.Duration(expirySec) * time.Second
 
-	err = redisClient.Set(context.Background(), redisKey, summaryJSON, expiry).Err()
+	err = cache.Set(context.Background(), redisKey, string(summaryJSON), expiry)
 	if err != nil {
 		return fmt.Errorf("failed to update batch summary in Redis: %w", err)
 	}
 	return nil
 }
 
-// updateStatusAndOutputFilesDataInRedis updates batch status, result, and output files in Redis.
-// Uses TxPipeline (MULTI/EXEC) for atomic multi-key update. All keys use hash tags for
-// Redis Cluster compatibility (same slot).
-func updateStatusAndOutputFilesDataInRedis(redisClient *redis.Client, batchID uuid.UUID, status batchsqlc.StatusEnum, outputFiles map[string]string, result string, expirySec int) error {
+// updateStatusAndOutputFilesDataInRedis updates batch status, result, and output files in the
+// status cache, all at once with SetMulti. All keys use hash tags for Redis Cluster
+// compatibility (same slot).
+func updateStatusAndOutputFilesDataInRedis(cache StatusCache, batchID uuid.UUID, status batchsqlc.StatusEnum, outputFiles map[string]string, result string, expirySec int) error {
+	if cache == nil {
+		return nil
+	}
 	redisKey := BatchStatusKey(batchID.String())
 	redisResultKey := BatchResultKey(batchID.String())
 	redisOutputFilesKey := BatchOutputFilesKey(batchID.String())
@@ -506,39 +462,13 @@ func updateStatusAndOutputFilesDataInRedis(redisClient *redis.Client, batchID uu
 		return fmt.Errorf("failed to marshal output files: %w", err)
 	}
 
-	pipe := redisClient.TxPipeline()
-	pipe.Set(context.Background(), redisKey, string(status), expiry)
-	pipe.Set(context.Background(), redisResultKey, result, expiry)
-	pipe.Set(context.Background(), redisOutputFilesKey, outputFilesJSON, expiry)
-	_, err = pipe.Exec(context.Background())
+	err = cache.SetMulti(context.Background(), map[string]string{
+		redisKey:            string(status),
+		redisResultKey:      result,
+		redisOutputFilesKey: string(outputFilesJSON),
+	}, expiry)
 	if err != nil {
 		return fmt.Errorf("failed to update status, outputfiles and result in Redis: %w", err)
 	}
 	return nil
 }
-
-func moveToObjectStore(filePath string, store objstore.ObjectStore, bucket string) (string, error) {
-	// Open the file
-	file, err := os.Open(filePath)
-	if err != nil {
-		return "", fmt.Errorf("failed to open file: %v", err)
-	}
-	defer file.Close()
-
-	// Get the file info
-	fileInfo, err := file.Stat()
-	if err != nil {
-		return "", fmt.Errorf("failed to get file info: %v", err)
-	}
-
-	// Generate a unique object name
-	objectName := uuid.New().String()
-
-	// Put the object in the object store
-	err = store.Put(context.Background(), bucket, objectName, file, fileInfo.Size(), "application/octet-stream")
-	if err != nil {
-		return "", fmt.Errorf("failed to put object in store: %v", err)
-	}
-
-	return objectName, nil
-}
@@ -0,0 +1,215 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/remiges-tech/alya/jobs/pg/batchsqlc"
+	"github.com/remiges-tech/alya/wscutils"
+)
+
+var (
+	// ErrJobAborted is the cause of the cancellation of a row's context when its batch
+	// or slow query is aborted. The row is left aborted.
+	ErrJobAborted = errors.New("job aborted")
+
+	// ErrShuttingDown is the cause of the cancellation of a row's context when the
+	// JobManager shuts down. The row is put back in the queue.
+	ErrShuttingDown = errors.New("job manager shutting down")
+
+	// ErrRowTimeout is the cause of the cancellation of a row's context when it runs
+	// past the timeout registered for its (app, op). The row fails, unless the processor
+	// returns a transient error and its retry policy allows another attempt.
+	ErrRowTimeout = errors.New("row timed out")
+
+	ErrInvalidRowTimeout = errors.New("invalid row timeout")
+)
+
+// batchProcessorAdapter runs a BatchProcessor as a BatchProcessorV2. The context is
+// not passed on, so its rows run to completion even once they are cancelled.
+type batchProcessorAdapter struct {
+	p BatchProcessor
+}
+
+func (a batchProcessorAdapter) DoBatchJob(ctx context.Context, initBlock InitBlock, jobctx JSONstr, line int, input JSONstr) (batchsqlc.StatusEnum, JSONstr, []wscutils.ErrorMessage, map[string]string, error) {
+	return a.p.DoBatchJob(initBlock, jobctx, line, input)
+}
+
+func (a batchProcessorAdapter) MarkDone(ctx context.Context, initBlock InitBlock, jobctx JSONstr, details BatchDetails_t) error {
+	return a.p.MarkDone(initBlock, jobctx, details)
+}
+
+// slowQueryProcessorAdapter runs a SlowQueryProcessor as a SlowQueryProcessorV2, like
+// batchProcessorAdapter.
+type slowQueryProcessorAdapter struct {
+	p SlowQueryProcessor
+}
+
+func (a slowQueryProcessorAdapter) DoSlowQuery(ctx context.Context, initBlock InitBlock, jobctx JSONstr, input JSONstr) (batchsqlc.StatusEnum, JSONstr, []wscutils.ErrorMessage, map[string]string, error) {
+	return a.p.DoSlowQuery(initBlock, jobctx, input)
+}
+
+func (a slowQueryProcessorAdapter) MarkDone(ctx context.Context, initBlock InitBlock, jobctx JSONstr, details BatchDetails_t) error {
+	return a.p.MarkDone(initBlock, jobctx, details)
+}
+
+// RegisterRowTimeout sets the time a row of the given (app, op) may be processed for.
+// When it has passed, the context given to the processor is cancelled with cause
+// ErrRowTimeout. Only processors registered with RegisterProcessorBatchV2 or
+// RegisterProcessorSlowQueryV2 see the context; the others are not interrupted.
+// The 'op' parameter is case-insensitive.
+func (jm *JobManager) RegisterRowTimeout(app string, op string, timeout time.Duration) error {
+	if timeout <= 0 {
+		return fmt.Errorf("%w: timeout must be positive, got %v", ErrInvalidRowTimeout, timeout)
+	}
+
+	op = strings.ToLower(op)
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+	jm.rowtimeouts[app+op] = timeout
+	return nil
+}
+
+// runningRows holds the cancel functions of the contexts of the rows being processed
+// by this instance, by batch.
+type runningRows struct {
+	mu   sync.Mutex
+	rows map[uuid.UUID]map[int64]context.CancelCauseFunc
+}
+
+func (r *runningRows) add(batchID uuid.UUID, rowID int64, cancel context.CancelCauseFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.rows == nil {
+		r.rows = make(map[uuid.UUID]map[int64]context.CancelCauseFunc)
+	}
+	if r.rows[batchID] == nil {
+		r.rows[batchID] = make(map[int64]context.CancelCauseFunc)
+	}
+	r.rows[batchID][rowID] = cancel
+}
+
+func (r *runningRows) remove(batchID uuid.UUID, rowID int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.rows[batchID], rowID)
+	if len(r.rows[batchID]) == 0 {
+		delete(r.rows, batchID)
+	}
+}
+
+// cancel cancels the contexts of the running rows of a batch and returns their number.
+func (r *runningRows) cancel(batchID uuid.UUID, cause error) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, cancel := range r.rows[batchID] {
+		cancel(cause)
+	}
+	return len(r.rows[batchID])
+}
+
+// cancelAll cancels the contexts of all running rows.
+func (r *runningRows) cancelAll(cause error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, rows := range r.rows {
+		for _, cancel := range rows {
+			cancel(cause)
+		}
+	}
+}
+
+// rowContext returns the context a row is processed with, and a function to call once
+// it is done. The context is cancelled when the row's batch is aborted, when ctx (the
+// processing loop's context) is cancelled or Shutdown is called, and when the row
+// timeout of its (app, op) expires.
+func (jm *JobManager) rowContext(ctx context.Context, row batchsqlc.FetchBlockOfRowsRow) (context.Context, func()) {
+	// The row is not cancelled with ctx directly, so that the cause is ErrShuttingDown
+	rowCtx, cancel := context.WithCancelCause(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(ctx, func() { cancel(ErrShuttingDown) })
+	jm.running.add(row.Batch, row.Rowid, cancel)
+
+	jm.mu.RLock()
+	timeout, ok := jm.rowtimeouts[row.App+row.Op]
+	jm.mu.RUnlock()
+	cancelTimeout := context.CancelFunc(func() {})
+	if ok {
+		rowCtx, cancelTimeout = context.WithTimeoutCause(rowCtx, timeout, ErrRowTimeout)
+	}
+
+	return rowCtx, func() {
+		jm.running.remove(row.Batch, row.Rowid)
+		stop()
+		cancelTimeout()
+		cancel(nil)
+	}
+}
+
+// rowCancelledError returns the error to handle a row with if its processor returned
+// err after the row's context was cancelled: err wrapped with the cause of the
+// cancellation. It returns nil if the row was not cancelled or the processor succeeded.
+func rowCancelledError(ctx context.Context, err error) error {
+	if err == nil || ctx.Err() == nil {
+		return nil
+	}
+	return fmt.Errorf("%w: %w", context.Cause(ctx), err)
+}
+
+// signalAbort interrupts the rows of an aborted batch or slow query that are being
+// processed: directly on this instance, and through Redis on the others.
+func (jm *JobManager) signalAbort(batchID uuid.UUID) {
+	jm.running.cancel(batchID, ErrJobAborted)
+
+	if jm.redisClient == nil {
+		return
+	}
+	if err := jm.redisClient.Publish(context.Background(), abortChannel(), batchID.String()).Err(); err != nil {
+		jm.logger.Warn().LogActivity("Failed to publish abort to other instances", map[string]any{
+			"batchId": batchID.String(),
+			"error":   err.Error(),
+		})
+	}
+}
+
+// runAbortListener interrupts the rows of batches and slow queries aborted on other
+// instances until ctx is cancelled. Without a Redis client, aborts only interrupt the
+// rows being processed by the instance they were made on.
+func (jm *JobManager) runAbortListener(ctx context.Context) {
+	if jm.redisClient == nil {
+		return
+	}
+	pubsub := jm.redisClient.Subscribe(ctx, abortChannel())
+	defer pubsub.Close()
+
+	// The channel is kept open across reconnections; aborts published while
+	// disconnected are missed, which only lets their running rows finish
+	ch := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			batchID, err := uuid.Parse(msg.Payload)
+			if err != nil {
+				jm.logger.Warn().LogActivity("Invalid batch ID on abort channel", map[string]any{
+					"payload": msg.Payload,
+				})
+				continue
+			}
+			if n := jm.running.cancel(batchID, ErrJobAborted); n > 0 {
+				jm.logger.Info().LogActivity("Interrupting rows of aborted job", map[string]any{
+					"batchId": batchID.String(),
+					"nrows":   n,
+				})
+			}
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/remiges-tech/alya/jobs/pg/batchsqlc"
+	"github.com/remiges-tech/alya/jobs/pg/batchsqlc/mocks"
+	"github.com/remiges-tech/alya/wscutils"
+	"github.com/remiges-tech/logharbour/logharbour"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingBatchProcessor waits for its context to be cancelled, unless done is closed first.
+type blockingBatchProcessor struct {
+	done chan struct{}
+}
+
+func (p *blockingBatchProcessor) DoBatchJob(ctx context.Context, initBlock InitBlock, jobctx JSONstr, line int, input JSONstr) (batchsqlc.StatusEnum, JSONstr, []wscutils.ErrorMessage, map[string]string, error) {
+	select {
+	case <-ctx.Done():
+		return batchsqlc.StatusEnumFailed, JSONstr{}, nil, nil, ctx.Err()
+	case <-p.done:
+		return batchsqlc.StatusEnumSuccess, JSONstr{}, nil, nil, nil
+	}
+}
+
+func (p *blockingBatchProcessor) MarkDone(ctx context.Context, initBlock InitBlock, jobctx JSONstr, details BatchDetails_t) error {
+	return nil
+}
+
+func newCancelTestRow(batchID uuid.UUID, rowID int64) batchsqlc.FetchBlockOfRowsRow {
+	return batchsqlc.FetchBlockOfRowsRow{App: "app1", Op: "op1", Batch: batchID, Rowid: rowID, Line: int32(rowID), Context: []byte(`{}`), Input: []byte(`{}`)}
+}
+
+func TestRegisterRowTimeout(t *testing.T) {
+	jm := newTestJobManager(&mocks.QuerierMock{}, nil)
+
+	assert.ErrorIs(t, jm.RegisterRowTimeout("app1", "op1", 0), ErrInvalidRowTimeout)
+	assert.ErrorIs(t, jm.RegisterRowTimeout("app1", "op1", -time.Second), ErrInvalidRowTimeout)
+	require.NoError(t, jm.RegisterRowTimeout("app1", "OP1", time.Minute))
+	assert.Equal(t, time.Minute, jm.rowtimeouts["app1op1"])
+}
+
+func TestRowContext(t *testing.T) {
+	t.Run("abort", func(t *testing.T) {
+		jm := newTestJobManager(&mocks.QuerierMock{}, nil)
+		batchID := uuid.New()
+		ctx1, done1 := jm.rowContext(context.Background(), newCancelTestRow(batchID, 1))
+		defer done1()
+		ctx2, done2 := jm.rowContext(context.Background(), newCancelTestRow(uuid.New(), 2))
+		defer done2()
+
+		jm.signalAbort(batchID)
+		assert.ErrorIs(t, context.Cause(ctx1), ErrJobAborted)
+		assert.NoError(t, ctx2.Err())
+	})
+
+	t.Run("done", func(t *testing.T) {
+		jm := newTestJobManager(&mocks.QuerierMock{}, nil)
+		batchID := uuid.New()
+		ctx, done := jm.rowContext(context.Background(), newCancelTestRow(batchID, 1))
+
+		done()
+		assert.ErrorIs(t, ctx.Err(), context.Canceled)
+		assert.Zero(t, jm.running.cancel(batchID, ErrJobAborted))
+		assert.ErrorIs(t, context.Cause(ctx), context.Canceled)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		jm := newTestJobManager(&mocks.QuerierMock{}, nil)
+		require.NoError(t, jm.RegisterRowTimeout("app1", "op1", 10*time.Millisecond))
+		ctx, done := jm.rowContext(context.Background(), newCancelTestRow(uuid.New(), 1))
+		defer done()
+
+		<-ctx.Done()
+		assert.ErrorIs(t, context.Cause(ctx), ErrRowTimeout)
+	})
+
+	t.Run("processing loop stopped", func(t *testing.T) {
+		jm := newTestJobManager(&mocks.QuerierMock{}, nil)
+		loopCtx, stop := context.WithCancel(context.Background())
+		ctx, done := jm.rowContext(loopCtx, newCancelTestRow(uuid.New(), 1))
+		defer done()
+
+		stop()
+		<-ctx.Done()
+		assert.ErrorIs(t, context.Cause(ctx), ErrShuttingDown)
+	})
+
+	t.Run("shutdown", func(t *testing.T) {
+		jm := newTestJobManager(&mocks.QuerierMock{}, nil)
+		ctx, done := jm.rowContext(context.Background(), newCancelTestRow(uuid.New(), 1))
+		defer done()
+
+		require.NoError(t, jm.Shutdown(context.Background()))
+		assert.ErrorIs(t, context.Cause(ctx), ErrShuttingDown)
+	})
+}
+
+func TestAbortListener(t *testing.T) {
+	jm, redisClient := newLimitsTestJobManager(t)
+	logger := logharbour.NewLogger(&logharbour.LoggerContext{}, "test", log.Writer())
+	other := NewJobManager(nil, redisClient, nil, logger, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go other.runAbortListener(ctx)
+
+	batchID := uuid.New()
+	rowCtx, done := other.rowContext(context.Background(), newCancelTestRow(batchID, 1))
+	defer done()
+
+	// Publish until the listener has subscribed
+	require.Eventually(t, func() bool {
+		jm.signalAbort(batchID)
+		return rowCtx.Err() != nil
+	}, 5*time.Second, 20*time.Millisecond)
+	assert.ErrorIs(t, context.Cause(rowCtx), ErrJobAborted)
+}
+
+func TestProcessBatchJob_Cancelled(t *testing.T) {
+	newJobManager := func(q *mocks.QuerierMock, p BatchProcessorV2) *JobManager {
+		jm := newTestJobManager(q, nil)
+		require.NoError(t, jm.RegisterInitializer("app1", &stubInitializer{}))
+		require.NoError(t, jm.RegisterProcessorBatchV2("app1", "op1", p))
+		return jm
+	}
+	newQuerier := func() *mocks.QuerierMock {
+		return &mocks.QuerierMock{
+			UpdateBatchRowsBatchJobFunc: func(ctx context.Context, arg batchsqlc.UpdateBatchRowsBatchJobParams) error {
+				return nil
+			},
+		}
+	}
+
+	t.Run("timeout fails the row", func(t *testing.T) {
+		q := newQuerier()
+		jm := newJobManager(q, &blockingBatchProcessor{done: make(chan struct{})})
+		require.NoError(t, jm.RegisterRowTimeout("app1", "op1", 10*time.Millisecond))
+		row := newCancelTestRow(uuid.New(), 1)
+
+		rowCtx, done := jm.rowContext(context.Background(), row)
+		status, err := jm.processRow(rowCtx, q, row)
+		done()
+		require.NoError(t, err)
+		assert.Equal(t, batchsqlc.StatusEnumFailed, status)
+
+		calls := q.UpdateBatchRowsBatchJobCalls()
+		require.Len(t, calls, 1)
+		assert.Equal(t, batchsqlc.StatusEnumFailed, calls[0].Arg.Status)
+		var messages []wscutils.ErrorMessage
+		require.NoError(t, json.Unmarshal(calls[0].Arg.Messages, &messages))
+		require.Len(t, messages, 1)
+		assert.Equal(t, ErrCodeTimeout, messages[0].ErrCode)
+	})
+
+	t.Run("abort leaves the row alone", func(t *testing.T) {
+		q := newQuerier()
+		jm := newJobManager(q, &blockingBatchProcessor{done: make(chan struct{})})
+		row := newCancelTestRow(uuid.New(), 1)
+
+		rowCtx, done := jm.rowContext(context.Background(), row)
+		defer done()
+		go jm.signalAbort(row.Batch)
+		_, err := jm.processRow(rowCtx, q, row)
+		assert.ErrorIs(t, err, ErrJobAborted)
+		assert.Empty(t, q.UpdateBatchRowsBatchJobCalls())
+	})
+
+	t.Run("finished row is recorded", func(t *testing.T) {
+		q := newQuerier()
+		p := &blockingBatchProcessor{done: make(chan struct{})}
+		close(p.done)
+		jm := newJobManager(q, p)
+		row := newCancelTestRow(uuid.New(), 1)
+
+		rowCtx, done := jm.rowContext(context.Background(), row)
+		defer done()
+		status, err := jm.processRow(rowCtx, q, row)
+		require.NoError(t, err)
+		assert.Equal(t, batchsqlc.StatusEnumSuccess, status)
+		assert.Len(t, q.UpdateBatchRowsBatchJobCalls(), 1)
+	})
+}
@@ -0,0 +1,80 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCron_Invalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"abc * * * *",
+		"@often",
+	} {
+		t.Run(expr, func(t *testing.T) {
+			_, err := parseCron(expr)
+			assert.ErrorIs(t, err, ErrInvalidCronExpr)
+		})
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	loc := time.UTC
+	// 2024-03-01 is a Friday
+	from := time.Date(2024, 3, 1, 10, 17, 30, 0, loc)
+
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2024, 3, 1, 10, 18, 0, 0, loc)},
+		{"*/15 * * * *", time.Date(2024, 3, 1, 10, 30, 0, 0, loc)},
+		{"0 18 * * *", time.Date(2024, 3, 1, 18, 0, 0, 0, loc)},
+		{"30 9 * * *", time.Date(2024, 3, 2, 9, 30, 0, 0, loc)},
+		{"0 18 * * mon-fri", time.Date(2024, 3, 1, 18, 0, 0, 0, loc)},
+		{"0 9 * * 1", time.Date(2024, 3, 4, 9, 0, 0, 0, loc)},
+		{"0 9 * * 7", time.Date(2024, 3, 3, 9, 0, 0, 0, loc)},
+		{"0 0 1 * *", time.Date(2024, 4, 1, 0, 0, 0, 0, loc)},
+		{"0 0 29 feb *", time.Date(2024, 2, 29, 0, 0, 0, 0, loc).AddDate(4, 0, 0)},
+		{"0 12 15,20 * *", time.Date(2024, 3, 15, 12, 0, 0, 0, loc)},
+		// day of month and day of week both restricted: either matches
+		{"0 12 15 * sat", time.Date(2024, 3, 2, 12, 0, 0, 0, loc)},
+		{"5/20 10 * * *", time.Date(2024, 3, 1, 10, 25, 0, 0, loc)},
+		{"@daily", time.Date(2024, 3, 2, 0, 0, 0, 0, loc)},
+		{"@hourly", time.Date(2024, 3, 1, 11, 0, 0, 0, loc)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			c, err := parseCron(tt.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, c.next(from))
+		})
+	}
+}
+
+func TestCronNext_NoMatch(t *testing.T) {
+	c, err := parseCron("0 0 30 2 *")
+	require.NoError(t, err)
+	assert.True(t, c.next(time.Now()).IsZero())
+}
+
+func TestCronNext_Location(t *testing.T) {
+	loc := time.FixedZone("IST", 5*3600+1800)
+	c, err := parseCron("0 18 * * *")
+	require.NoError(t, err)
+
+	next := c.next(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC).In(loc))
+	assert.Equal(t, time.Date(2024, 3, 1, 18, 0, 0, 0, loc), next)
+	assert.Equal(t, time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC), next.UTC())
+}
@@ -0,0 +1,245 @@
+package jobs
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/remiges-tech/alya/jobs/pg/batchsqlc"
+)
+
+var (
+	ErrInvalidDependency = errors.New("invalid batch dependency")
+	ErrParentNotFound    = errors.New("parent batch not found")
+	ErrBatchHasParents   = errors.New("batch is queued once its parent batches are done")
+)
+
+// ParentsContextKey is the key under which the context of a batch that depends on
+// other batches holds a ParentOutput_t for each of them, by name, once it is queued.
+// Only batches whose context is a JSON object get it.
+const ParentsContextKey = "alya_parents"
+
+// ParentFailurePolicy_t says what happens to a batch when one of the batches it
+// depends on is done without succeeding.
+type ParentFailurePolicy_t string
+
+const (
+	// ParentFailureAbort aborts the batch without running any of its rows, and in
+	// turn the batches that depend on it with the same policy.
+	ParentFailureAbort ParentFailurePolicy_t = "abort"
+	// ParentFailureRun runs the batch all the same once all its parents are done.
+	ParentFailureRun ParentFailurePolicy_t = "run"
+)
+
+// Dependency_t is a batch that a batch submitted with SubmitOptions_t.DependsOn
+// depends on.
+type Dependency_t struct {
+	BatchID string
+	// Name is the key of the parent under ParentsContextKey in the context of the
+	// dependent batch (default: BatchID).
+	Name string
+}
+
+// ParentOutput_t is what the context of a dependent batch holds about each of its
+// parents once it is queued, so that its rows can read the parents' output files.
+type ParentOutput_t struct {
+	ID          string               `json:"id"`
+	Status      batchsqlc.StatusEnum `json:"status"`
+	OutputFiles map[string]string    `json:"outputfiles,omitempty"`
+}
+
+// insertBatchDependencies records the parents of a new batch, from opts.DependsOn.
+// The parents are locked first, so that none of them can be summarised before the
+// calling transaction ends without seeing the new batch.
+func insertBatchDependencies(ctx context.Context, q batchsqlc.Querier, batchUUID uuid.UUID, opts SubmitOptions_t) error {
+	policy := cmp.Or(opts.OnParentFailure, ParentFailureAbort)
+	if policy != ParentFailureAbort && policy != ParentFailureRun {
+		return fmt.Errorf("%w: unknown parent failure policy %q", ErrInvalidDependency, policy)
+	}
+	if opts.WaitABit {
+		return fmt.Errorf("%w: a batch with parents cannot also wait a bit", ErrInvalidDependency)
+	}
+
+	parents := make([]uuid.UUID, len(opts.DependsOn))
+	names := make([]string, len(opts.DependsOn))
+	for i, dep := range opts.DependsOn {
+		parent, err := uuid.Parse(dep.BatchID)
+		if err != nil {
+			return fmt.Errorf("%w: invalid parent batch ID %q", ErrInvalidDependency, dep.BatchID)
+		}
+		if slices.Contains(parents[:i], parent) {
+			return fmt.Errorf("%w: parent batch %s is listed twice", ErrInvalidDependency, dep.BatchID)
+		}
+		name := cmp.Or(dep.Name, dep.BatchID)
+		if slices.Contains(names[:i], name) {
+			return fmt.Errorf("%w: parent name %q is used twice", ErrInvalidDependency, name)
+		}
+		parents[i], names[i] = parent, name
+	}
+
+	locked, err := q.LockParentBatches(ctx, parents)
+	if err != nil {
+		return fmt.Errorf("failed to lock parent batches: %w", err)
+	}
+	for _, parent := range parents {
+		if !slices.Contains(locked, parent) {
+			return fmt.Errorf("%w: %s", ErrParentNotFound, parent)
+		}
+	}
+
+	for i, parent := range parents {
+		err := q.InsertBatchDependency(ctx, batchsqlc.InsertBatchDependencyParams{
+			Batch:           batchUUID,
+			Parent:          parent,
+			Name:            names[i],
+			OnParentFailure: string(policy),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to insert batch dependency: %w", err)
+		}
+	}
+	return nil
+}
+
+// resolveDependentBatch decides what happens to a batch in 'wait' that depends on
+// other batches, using the given (normally transaction-bound) queries. It is aborted
+// if one of its parents is done without succeeding and its policy is
+// ParentFailureAbort, queued with its parents' outputs in its context once they are
+// all done, and left waiting otherwise.
+func (jm *JobManager) resolveDependentBatch(ctx context.Context, q batchsqlc.Querier, batchUUID uuid.UUID) error {
+	// Lock the batch, so that parents finishing at the same time resolve it one after the other
+	batch, err := q.GetBatchByID(ctx, batchUUID)
+	if err != nil {
+		return fmt.Errorf("failed to get dependent batch: %w", err)
+	}
+	if batch.Status != batchsqlc.StatusEnumWait {
+		return nil
+	}
+
+	parents, err := q.GetParentBatches(ctx, batchUUID)
+	if err != nil {
+		return fmt.Errorf("failed to get parent batches: %w", err)
+	}
+
+	done := true
+	outputs := make(map[string]ParentOutput_t, len(parents))
+	for _, parent := range parents {
+		if !isFinalStatus(parent.Status) {
+			done = false
+			continue
+		}
+		if parent.Status != batchsqlc.StatusEnumSuccess && ParentFailurePolicy_t(parent.OnParentFailure) == ParentFailureAbort {
+			return jm.abortDependentBatch(ctx, q, batch, parent.Parent)
+		}
+		output := ParentOutput_t{ID: parent.Parent.String(), Status: parent.Status}
+		if len(parent.Outputfiles) > 0 {
+			if err := json.Unmarshal(parent.Outputfiles, &output.OutputFiles); err != nil {
+				return fmt.Errorf("failed to parse output files of parent batch %s: %w", parent.Parent, err)
+			}
+		}
+		outputs[parent.Name] = output
+	}
+	if !done {
+		return nil
+	}
+
+	outputsJSON, err := json.Marshal(outputs)
+	if err != nil {
+		return fmt.Errorf("failed to marshal parent outputs: %w", err)
+	}
+	err = q.ReleaseDependentBatch(ctx, batchsqlc.ReleaseDependentBatchParams{
+		Parents: outputsJSON,
+		ID:      batchUUID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to queue dependent batch: %w", err)
+	}
+	if err := jm.notifyJobsQueued(ctx, q, batch.App); err != nil {
+		return err
+	}
+
+	jm.logger.Info().LogActivity("Dependent batch queued as its parent batches are done", map[string]any{
+		"batchId":  batchUUID.String(),
+		"nparents": len(parents),
+	})
+	return nil
+}
+
+// abortDependentBatch aborts all the rows of a waiting batch because parent did not
+// succeed, and then resolves the batches that depend on it in turn.
+func (jm *JobManager) abortDependentBatch(ctx context.Context, q batchsqlc.Querier, batch batchsqlc.Batch, parent uuid.UUID) error {
+	pendingRows, err := q.GetPendingBatchRows(ctx, batch.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get pending batchrows: %w", err)
+	}
+	if len(pendingRows) > 0 {
+		rowids := make([]int64, len(pendingRows))
+		for i, row := range pendingRows {
+			rowids[i] = row.Rowid
+		}
+		err = q.UpdateBatchRowsStatus(ctx, batchsqlc.UpdateBatchRowsStatusParams{
+			Status:  batchsqlc.StatusEnumAborted,
+			Column2: rowids,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to update batchrows status: %w", err)
+		}
+	}
+
+	err = q.UpdateBatchSummary(ctx, batchsqlc.UpdateBatchSummaryParams{
+		ID:       batch.ID,
+		Status:   batchsqlc.StatusEnumAborted,
+		Doneat:   pgtype.Timestamp{Time: time.Now(), Valid: true},
+		Nsuccess: pgtype.Int4{Int32: 0, Valid: true},
+		Nfailed:  pgtype.Int4{Int32: 0, Valid: true},
+		Naborted: pgtype.Int4{Int32: int32(len(pendingRows)), Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update batch summary: %w", err)
+	}
+
+	// Queue the completion webhook, if the batch has a callback URL
+	if batch.CallbackUrl.Valid {
+		err = enqueueCompletionWebhook(ctx, q, batch.ID, CompletionEvent_t{
+			Event:    WebhookEventBatchDone,
+			ID:       batch.ID.String(),
+			App:      batch.App,
+			Op:       batch.Op,
+			Status:   batchsqlc.StatusEnumAborted,
+			NAborted: len(pendingRows),
+			DoneAt:   time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	jm.logger.Info().LogActivity("Dependent batch aborted as a parent batch did not succeed", map[string]any{
+		"batchId":  batch.ID.String(),
+		"parentId": parent.String(),
+		"naborted": len(pendingRows),
+	})
+	return jm.releaseDependents(ctx, q, batch.ID)
+}
+
+// releaseDependents resolves the batches waiting on a batch that has just been done,
+// using the given (normally transaction-bound) queries. It is called in the
+// transaction in which the batch is summarised or aborted.
+func (jm *JobManager) releaseDependents(ctx context.Context, q batchsqlc.Querier, batchUUID uuid.UUID) error {
+	dependents, err := q.GetWaitingDependentBatches(ctx, batchUUID)
+	if err != nil {
+		return fmt.Errorf("failed to get dependent batches: %w", err)
+	}
+	for _, dependent := range dependents {
+		if err := jm.resolveDependentBatch(ctx, q, dependent); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/remiges-tech/alya/jobs/pg/batchsqlc"
+	"github.com/remiges-tech/alya/jobs/pg/batchsqlc/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newDependencyQuerier returns a mock holding a waiting batch with the given parents,
+// which accepts every update.
+func newDependencyQuerier(batch batchsqlc.Batch, parents ...batchsqlc.GetParentBatchesRow) *mocks.QuerierMock {
+	return &mocks.QuerierMock{
+		InsertIntoBatchesFunc: func(ctx context.Context, arg batchsqlc.InsertIntoBatchesParams) (uuid.UUID, error) {
+			return arg.ID, nil
+		},
+		LockParentBatchesFunc: func(ctx context.Context, ids []uuid.UUID) ([]uuid.UUID, error) {
+			return ids, nil
+		},
+		InsertBatchDependencyFunc: func(ctx context.Context, arg batchsqlc.InsertBatchDependencyParams) error {
+			return nil
+		},
+		GetBatchByIDFunc: func(ctx context.Context, id uuid.UUID) (batchsqlc.Batch, error) {
+			return batch, nil
+		},
+		GetParentBatchesFunc: func(ctx context.Context, id uuid.UUID) ([]batchsqlc.GetParentBatchesRow, error) {
+			return parents, nil
+		},
+		ReleaseDependentBatchFunc: func(ctx context.Context, arg batchsqlc.ReleaseDependentBatchParams) error {
+			return nil
+		},
+		NotifyJobsQueuedFunc: func(ctx context.Context, app string) (string, error) {
+			return "", nil
+		},
+		GetPendingBatchRowsFunc: func(ctx context.Context, id uuid.UUID) ([]batchsqlc.GetPendingBatchRowsRow, error) {
+			return []batchsqlc.GetPendingBatchRowsRow{{Rowid: 21}, {Rowid: 22}}, nil
+		},
+		UpdateBatchRowsStatusFunc: func(ctx context.Context, arg batchsqlc.UpdateBatchRowsStatusParams) error {
+			return nil
+		},
+		UpdateBatchSummaryFunc: func(ctx context.Context, arg batchsqlc.UpdateBatchSummaryParams) error {
+			return nil
+		},
+		GetWaitingDependentBatchesFunc: func(ctx context.Context, parent uuid.UUID) ([]uuid.UUID, error) {
+			return nil, nil
+		},
+	}
+}
+
+func parentRow(name string, status batchsqlc.StatusEnum, policy ParentFailurePolicy_t, outputFiles string) batchsqlc.GetParentBatchesRow {
+	return batchsqlc.GetParentBatchesRow{
+		Parent:          uuid.New(),
+		Name:            name,
+		OnParentFailure: string(policy),
+		Status:          status,
+		Outputfiles:     []byte(outputFiles),
+	}
+}
+
+func TestInsertBatchRecord_Dependencies(t *testing.T) {
+	q := newDependencyQuerier(batchsqlc.Batch{})
+	batchUUID := uuid.New()
+	extract, load := uuid.NewString(), uuid.NewString()
+
+	err := insertBatchRecord(context.Background(), q, batchUUID, "bank", "report", mustJSONstr(t, `{}`), SubmitOptions_t{
+		DependsOn: []Dependency_t{{BatchID: extract, Name: "extract"}, {BatchID: load}},
+	})
+	require.NoError(t, err)
+
+	require.Len(t, q.InsertIntoBatchesCalls(), 1)
+	assert.Equal(t, batchsqlc.StatusEnumWait, q.InsertIntoBatchesCalls()[0].Arg.Status)
+	require.Len(t, q.LockParentBatchesCalls(), 1)
+	assert.Equal(t, []uuid.UUID{uuid.MustParse(extract), uuid.MustParse(load)}, q.LockParentBatchesCalls()[0].Ids)
+
+	deps := q.InsertBatchDependencyCalls()
+	require.Len(t, deps, 2)
+	assert.Equal(t, batchsqlc.InsertBatchDependencyParams{
+		Batch:           batchUUID,
+		Parent:          uuid.MustParse(extract),
+		Name:            "extract",
+		OnParentFailure: "abort",
+	}, deps[0].Arg)
+	assert.Equal(t, load, deps[1].Arg.Name)
+}
+
+func TestInsertBatchDependencies_Rejected(t *testing.T) {
+	parent := uuid.NewString()
+
+	tests := []struct {
+		name string
+		opts SubmitOptions_t
+		want error
+	}{
+		{"wait a bit", SubmitOptions_t{WaitABit: true, DependsOn: []Dependency_t{{BatchID: parent}}}, ErrInvalidDependency},
+		{"unknown policy", SubmitOptions_t{OnParentFailure: "retry", DependsOn: []Dependency_t{{BatchID: parent}}}, ErrInvalidDependency},
+		{"invalid ID", SubmitOptions_t{DependsOn: []Dependency_t{{BatchID: "not-a-uuid"}}}, ErrInvalidDependency},
+		{"parent twice", SubmitOptions_t{DependsOn: []Dependency_t{{BatchID: parent, Name: "a"}, {BatchID: parent, Name: "b"}}}, ErrInvalidDependency},
+		{"name twice", SubmitOptions_t{DependsOn: []Dependency_t{{BatchID: parent, Name: "a"}, {BatchID: uuid.NewString(), Name: "a"}}}, ErrInvalidDependency},
+		{"parent not found", SubmitOptions_t{DependsOn: []Dependency_t{{BatchID: parent}, {BatchID: uuid.NewString()}}}, ErrParentNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := newDependencyQuerier(batchsqlc.Batch{})
+			// Only the first parent exists
+			q.LockParentBatchesFunc = func(ctx context.Context, ids []uuid.UUID) ([]uuid.UUID, error) {
+				return ids[:1], nil
+			}
+
+			err := insertBatchDependencies(context.Background(), q, uuid.New(), tt.opts)
+			assert.ErrorIs(t, err, tt.want)
+			assert.Empty(t, q.InsertBatchDependencyCalls())
+		})
+	}
+}
+
+func TestResolveDependentBatch_Queued(t *testing.T) {
+	batch := batchsqlc.Batch{ID: uuid.New(), App: "bank", Status: batchsqlc.StatusEnumWait}
+	extract := parentRow("extract", batchsqlc.StatusEnumSuccess, ParentFailureAbort, `{"accounts.csv":"obj-1"}`)
+	audit := parentRow("audit", batchsqlc.StatusEnumFailed, ParentFailureRun, `null`)
+	q := newDependencyQuerier(batch, extract, audit)
+	jm := newTestJobManager(q, nil)
+
+	require.NoError(t, jm.resolveDependentBatch(context.Background(), q, batch.ID))
+
+	require.Len(t, q.ReleaseDependentBatchCalls(), 1)
+	release := q.ReleaseDependentBatchCalls()[0].Arg
+	assert.Equal(t, batch.ID, release.ID)
+	var outputs map[string]ParentOutput_t
+	require.NoError(t, json.Unmarshal(release.Parents, &outputs))
+	assert.Equal(t, map[string]ParentOutput_t{
+		"extract": {ID: extract.Parent.String(), Status: batchsqlc.StatusEnumSuccess, OutputFiles: map[string]string{"accounts.csv": "obj-1"}},
+		"audit":   {ID: audit.Parent.String(), Status: batchsqlc.StatusEnumFailed},
+	}, outputs)
+
+	require.Len(t, q.NotifyJobsQueuedCalls(), 1)
+	assert.Equal(t, "bank", q.NotifyJobsQueuedCalls()[0].App)
+	assert.Empty(t, q.UpdateBatchSummaryCalls())
+}
+
+func TestResolveDependentBatch_StillWaiting(t *testing.T) {
+	batch := batchsqlc.Batch{ID: uuid.New(), App: "bank", Status: batchsqlc.StatusEnumWait}
+	q := newDependencyQuerier(batch,
+		parentRow("extract", batchsqlc.StatusEnumSuccess, ParentFailureAbort, `{}`),
+		parentRow("load", batchsqlc.StatusEnumInprog, ParentFailureAbort, `null`),
+	)
+	jm := newTestJobManager(q, nil)
+
+	require.NoError(t, jm.resolveDependentBatch(context.Background(), q, batch.ID))
+	assert.Empty(t, q.ReleaseDependentBatchCalls())
+	assert.Empty(t, q.UpdateBatchSummaryCalls())
+}
+
+func TestResolveDependentBatch_ParentFailed(t *testing.T) {
+	batch := batchsqlc.Batch{ID: uuid.New(), App: "bank", Status: batchsqlc.StatusEnumWait}
+	q := newDependencyQuerier(batch,
+		parentRow("extract", batchsqlc.StatusEnumAborted, ParentFailureAbort, `null`),
+		parentRow("load", batchsqlc.StatusEnumInprog, ParentFailureAbort, `null`),
+	)
+	jm := newTestJobManager(q, nil)
+
+	// The batch is aborted without waiting for its other parent
+	require.NoError(t, jm.resolveDependentBatch(context.Background(), q, batch.ID))
+	assert.Empty(t, q.ReleaseDependentBatchCalls())
+
+	require.Len(t, q.UpdateBatchRowsStatusCalls(), 1)
+	assert.Equal(t, batchsqlc.StatusEnumAborted, q.UpdateBatchRowsStatusCalls()[0].Arg.Status)
+	assert.Equal(t, []int64{21, 22}, q.UpdateBatchRowsStatusCalls()[0].Arg.Column2)
+
+	require.Len(t, q.UpdateBatchSummaryCalls(), 1)
+	summary := q.UpdateBatchSummaryCalls()[0].Arg
+	assert.Equal(t, batchsqlc.StatusEnumAborted, summary.Status)
+	assert.Equal(t, int32(2), summary.Naborted.Int32)
+
+	// and its own dependents are resolved in turn
+	require.Len(t, q.GetWaitingDependentBatchesCalls(), 1)
+	assert.Equal(t, batch.ID, q.GetWaitingDependentBatchesCalls()[0].Parent)
+}
+
+func TestReleaseDependents(t *testing.T) {
+	waiting := batchsqlc.Batch{ID: uuid.New(), App: "bank", Status: batchsqlc.StatusEnumWait}
+	aborted := batchsqlc.Batch{ID: uuid.New(), App: "bank", Status: batchsqlc.StatusEnumAborted}
+	q := newDependencyQuerier(batchsqlc.Batch{}, parentRow("extract", batchsqlc.StatusEnumSuccess, ParentFailureAbort, `{}`))
+	q.GetWaitingDependentBatchesFunc = func(ctx context.Context, parent uuid.UUID) ([]uuid.UUID, error) {
+		return []uuid.UUID{waiting.ID, aborted.ID}, nil
+	}
+	q.GetBatchByIDFunc = func(ctx context.Context, id uuid.UUID) (batchsqlc.Batch, error) {
+		if id == waiting.ID {
+			return waiting, nil
+		}
+		return aborted, nil
+	}
+	jm := newTestJobManager(q, nil)
+
+	require.NoError(t, jm.releaseDependents(context.Background(), q, uuid.New()))
+	require.Len(t, q.GetBatchByIDCalls(), 2)
+	require.Len(t, q.ReleaseDependentBatchCalls(), 1)
+	assert.Equal(t, waiting.ID, q.ReleaseDependentBatchCalls()[0].Arg.ID)
+}
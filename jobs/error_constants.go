@@ -11,6 +11,9 @@ MsgIDUnknownConfigurationError = 6 // Unknown configuration error
 
 // Message IDs for other error types
 MsgIDProcessingError           = 10 // General processing error
+MsgIDTransientError            = 11 // Transient processing error, row will be retried
+MsgIDRowTimeout                = 12 // Row processing ran past the timeout of its (app, op)
+MsgIDInvalidRowInput           = 13 // Row context or input did not decode or validate for a typed processor
 )
 
 // Error codes for machine-to-machine communication
@@ -20,4 +23,13 @@ ErrCodeConfiguration = "configerror"
 
 // Standard error code for processing errors
 ErrCodeProcessing = "processing_error"
+
+// Error code recorded on rows that were requeued for another attempt
+ErrCodeTransient = "transient_error"
+
+// Error code recorded on rows that ran past their row timeout
+ErrCodeTimeout = "timeout"
+
+// Error code recorded on rows whose context or input does not fit their typed processor
+ErrCodeInvalidInput = "invalid_input"
 )
@@ -0,0 +1,70 @@
+package jobs
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/remiges-tech/alya/jobs/pg/batchsqlc"
+)
+
+// FetchPolicy_t selects how RunOneIterationWithContext picks the block of rows it processes.
+type FetchPolicy_t string
+
+const (
+	// FetchPolicyPriority picks the rows of the highest priority batches first, oldest rows
+	// first within the same priority, regardless of which app they belong to.
+	FetchPolicyPriority FetchPolicy_t = "priority"
+
+	// FetchPolicyFairShare shares every block of rows among the apps that have rows waiting,
+	// round-robin, weighted by JobManagerConfig.AppWeights. Within an app, rows are picked
+	// by priority as with FetchPolicyPriority. This keeps a large batch of one app from
+	// holding up the slow queries and small batches of the others.
+	FetchPolicyFairShare FetchPolicy_t = "fairshare"
+)
+
+// fetchBlockOfRows fetches and locks the next block of queued rows according to the
+// configured fetch policy.
+func (jm *JobManager) fetchBlockOfRows(ctx context.Context, q batchsqlc.Querier, now time.Time) ([]batchsqlc.FetchBlockOfRowsRow, error) {
+	if jm.config.FetchPolicy != FetchPolicyFairShare {
+		return q.FetchBlockOfRows(ctx, batchsqlc.FetchBlockOfRowsParams{
+			Status: batchsqlc.StatusEnumQueued,
+			Now:    pgtype.Timestamp{Time: now, Valid: true},
+			Limit:  int32(jm.config.BatchChunkNRows),
+		})
+	}
+
+	apps, weights := jm.appWeights()
+	fairRows, err := q.FetchBlockOfRowsFair(ctx, batchsqlc.FetchBlockOfRowsFairParams{
+		Status:     batchsqlc.StatusEnumQueued,
+		Now:        pgtype.Timestamp{Time: now, Valid: true},
+		Limit:      int32(jm.config.BatchChunkNRows),
+		WeightApps: apps,
+		Weights:    weights,
+	})
+	if err != nil {
+		return nil, err
+	}
+	rows := make([]batchsqlc.FetchBlockOfRowsRow, len(fairRows))
+	for i, r := range fairRows {
+		rows[i] = batchsqlc.FetchBlockOfRowsRow(r)
+	}
+	return rows, nil
+}
+
+// appWeights returns the configured app weights as two parallel slices, sorted by app.
+// Weights below 1 are raised to 1.
+func (jm *JobManager) appWeights() (apps []string, weights []int32) {
+	apps = make([]string, 0, len(jm.config.AppWeights))
+	for app := range jm.config.AppWeights {
+		apps = append(apps, app)
+	}
+	sort.Strings(apps)
+
+	weights = make([]int32, len(apps))
+	for i, app := range apps {
+		weights[i] = int32(max(jm.config.AppWeights[app], 1))
+	}
+	return apps, weights
+}
@@ -0,0 +1,76 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/remiges-tech/alya/jobs/pg/batchsqlc"
+	"github.com/remiges-tech/alya/jobs/pg/batchsqlc/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewJobManager_FetchPolicy(t *testing.T) {
+	jm := newTestJobManager(&mocks.QuerierMock{}, nil)
+	assert.Equal(t, FetchPolicyPriority, jm.config.FetchPolicy)
+
+	jm = newTestJobManager(&mocks.QuerierMock{}, &JobManagerConfig{FetchPolicy: FetchPolicyFairShare})
+	assert.Equal(t, FetchPolicyFairShare, jm.config.FetchPolicy)
+
+	jm = newTestJobManager(&mocks.QuerierMock{}, &JobManagerConfig{FetchPolicy: "roundrobin"})
+	assert.Equal(t, FetchPolicyPriority, jm.config.FetchPolicy)
+}
+
+func TestFetchBlockOfRows_Priority(t *testing.T) {
+	batch := uuid.New()
+	mockQuerier := &mocks.QuerierMock{
+		FetchBlockOfRowsFunc: func(ctx context.Context, arg batchsqlc.FetchBlockOfRowsParams) ([]batchsqlc.FetchBlockOfRowsRow, error) {
+			return []batchsqlc.FetchBlockOfRowsRow{{App: "banking", Op: "transfer", Batch: batch, Rowid: 1, Line: 1}}, nil
+		},
+	}
+	jm := newTestJobManager(mockQuerier, &JobManagerConfig{BatchChunkNRows: 25})
+
+	now := time.Now()
+	rows, err := jm.fetchBlockOfRows(context.Background(), mockQuerier, now)
+	require.NoError(t, err)
+	assert.Len(t, rows, 1)
+
+	require.Len(t, mockQuerier.FetchBlockOfRowsCalls(), 1)
+	arg := mockQuerier.FetchBlockOfRowsCalls()[0].Arg
+	assert.Equal(t, batchsqlc.StatusEnumQueued, arg.Status)
+	assert.Equal(t, int32(25), arg.Limit)
+	assert.True(t, arg.Now.Time.Equal(now))
+	assert.Empty(t, mockQuerier.FetchBlockOfRowsFairCalls())
+}
+
+func TestFetchBlockOfRows_FairShare(t *testing.T) {
+	batch := uuid.New()
+	mockQuerier := &mocks.QuerierMock{
+		FetchBlockOfRowsFairFunc: func(ctx context.Context, arg batchsqlc.FetchBlockOfRowsFairParams) ([]batchsqlc.FetchBlockOfRowsFairRow, error) {
+			return []batchsqlc.FetchBlockOfRowsFairRow{
+				{App: "banking", Op: "transfer", Batch: batch, Rowid: 1, Line: 1, Attempts: 2},
+				{App: "reports", Op: "statement", Batch: uuid.New(), Rowid: 9, Line: 0},
+			}, nil
+		},
+	}
+	jm := newTestJobManager(mockQuerier, &JobManagerConfig{
+		BatchChunkNRows: 10,
+		FetchPolicy:     FetchPolicyFairShare,
+		AppWeights:      map[string]int{"reports": 3, "banking": 1, "archive": 0},
+	})
+
+	rows, err := jm.fetchBlockOfRows(context.Background(), mockQuerier, time.Now())
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	assert.Equal(t, batchsqlc.FetchBlockOfRowsRow{App: "banking", Op: "transfer", Batch: batch, Rowid: 1, Line: 1, Attempts: 2}, rows[0])
+	assert.Equal(t, "reports", rows[1].App)
+
+	require.Len(t, mockQuerier.FetchBlockOfRowsFairCalls(), 1)
+	arg := mockQuerier.FetchBlockOfRowsFairCalls()[0].Arg
+	assert.Equal(t, int32(10), arg.Limit)
+	assert.Equal(t, []string{"archive", "banking", "reports"}, arg.WeightApps)
+	assert.Equal(t, []int32{1, 1, 3}, arg.Weights)
+	assert.Empty(t, mockQuerier.FetchBlockOfRowsCalls())
+}
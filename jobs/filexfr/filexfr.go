@@ -3,6 +3,7 @@ package filexfr
 import (
 	"context"
 	"crypto/md5"
+	"encoding/json"
 	"fmt"
 	"io"
 	"path/filepath"
@@ -22,6 +23,10 @@ import (
 // FileChk is the type for file checking functions
 type FileChk func(fileContents string, fileName string, batchctx jobs.JSONstr) (bool, jobs.JSONstr, []jobs.BatchInput_t, string, string, string)
 
+// batchOwnerClass is the ownerclass, in their object info, of the files submitted
+// as batches
+const batchOwnerClass = "batch"
+
 // FileXfrConfig holds configuration for file transfer operations
 type FileXfrConfig struct {
 	// MaxObjectIDLength sets the maximum length for object IDs in the object store.
@@ -165,7 +170,7 @@ func (fxs *FileXfrServer) BulkfileinProcess(file, filename, filetype string, bat
 	}
 
 	if objectID == "" {
-		objectID, err = fxs.storeFileContents(fileContents, filename)
+		objectID, err = fxs.storeFileContents(fileContents, filename, batchID)
 		if err != nil {
 			fxs.logger.Debug2().LogActivity("Failed to store file contents", map[string]any{
 				"filename": filename,
@@ -173,6 +178,13 @@ func (fxs *FileXfrServer) BulkfileinProcess(file, filename, filetype string, bat
 			})
 			return "", fmt.Errorf("failed to store file contents: %v", err)
 		}
+	} else if err := fxs.setObjectOwner(objectID, batchID); err != nil {
+		// The batch is already submitted, so the file is processed regardless
+		fxs.logger.Warn().LogActivity("Failed to set the batch as owner of the object", map[string]any{
+			"objectID": objectID,
+			"batchID":  batchID,
+			"error":    err.Error(),
+		})
 	}
 
 	if err := fxs.recordBatchFile(objectID, len(fileContents), batchID, isgood); err != nil {
@@ -212,38 +224,29 @@ func (fxs *FileXfrServer) getObjectContents(objectID string) (string, error) {
 
 // moveObjectToFailedBucket moves an object from the incoming bucket to the failed bucket
 func (fxs *FileXfrServer) moveObjectToFailedBucket(objectID string) error {
-	ctx := context.Background()
-
-	// Get the object from the incoming bucket
-	reader, err := fxs.objStore.Get(ctx, fxs.config.IncomingBucket, objectID)
+	err := objstore.ObjectMove(context.Background(), fxs.objStore, objectID, fxs.config.IncomingBucket, fxs.config.FailedBucket)
 	if err != nil {
-		return fmt.Errorf("failed to get object %s from incoming bucket: %w", objectID, err)
+		return fmt.Errorf("failed to move object %s to failed bucket: %w", objectID, err)
 	}
-	defer reader.Close()
-
-	// Put the object in the failed bucket using the same object ID
-	err = fxs.objStore.Put(ctx, fxs.config.FailedBucket, objectID, reader, -1, "application/octet-stream")
-	if err != nil {
-		return fmt.Errorf("failed to put object %s in failed bucket: %w", objectID, err)
-	}
-
-	// Delete the original object from the incoming bucket
-	err = fxs.objStore.Delete(ctx, fxs.config.IncomingBucket, objectID)
-	if err != nil {
-		return fmt.Errorf("failed to delete object %s from incoming bucket: %w", objectID, err)
-	}
-
 	return nil
 }
 
-// storeFileContents stores the file contents in the object store and returns the object ID
-func (fxs *FileXfrServer) storeFileContents(contents, filename string) (string, error) {
+// storeFileContents stores the file contents in the object store, with the batch as
+// its owner, and returns the object ID
+func (fxs *FileXfrServer) storeFileContents(contents, filename, batchID string) (string, error) {
 	ctx := context.Background()
 
 	objectID := fxs.generateObjectID(filename)
 	reader := strings.NewReader(contents)
+	info := objstore.Info{
+		MimeType:   detectContentType(contents, filename),
+		OwnerClass: batchOwnerClass,
+		OwnerID:    batchID,
+		Filename:   filename,
+		From:       "filexfr",
+	}
 
-	err := fxs.objStore.Put(ctx, fxs.config.IncomingBucket, objectID, reader, int64(len(contents)), detectContentType(contents, filename))
+	err := objstore.ObjectPut(ctx, fxs.objStore, fxs.config.IncomingBucket, objectID, reader, int64(len(contents)), info.String())
 	if err != nil {
 		return "", fmt.Errorf("failed to store file contents: %w", err)
 	}
@@ -251,6 +254,29 @@ func (fxs *FileXfrServer) storeFileContents(contents, filename string) (string,
 	return objectID, nil
 }
 
+// setObjectOwner records the batch as the owner of an object in the incoming bucket,
+// keeping the other attributes of its info block
+func (fxs *FileXfrServer) setObjectOwner(objectID, batchID string) error {
+	ctx := context.Background()
+
+	info, _, _, err := objstore.ObjectInfo(ctx, fxs.objStore, objectID, fxs.config.IncomingBucket)
+	if err != nil {
+		return err
+	}
+	attrs := make(map[string]any)
+	if err := json.Unmarshal([]byte(info), &attrs); err != nil {
+		return fmt.Errorf("failed to parse object info: %w", err)
+	}
+	attrs["ownerclass"] = batchOwnerClass
+	attrs["ownerid"] = batchID
+	newInfo, err := json.Marshal(attrs)
+	if err != nil {
+		return fmt.Errorf("failed to marshal object info: %w", err)
+	}
+
+	return objstore.ObjectInfoUpdate(ctx, fxs.objStore, objectID, fxs.config.IncomingBucket, string(newInfo))
+}
+
 // generateObjectID creates an object ID for storing in the object store.
 // The ID is derived from the sanitized filename and truncated to MaxObjectIDLength if needed.
 //
@@ -242,3 +242,46 @@ func TestGenerateObjectID(t *testing.T) {
 		assert.Equal(t, 50, len(objectID), "Object ID should be exactly 50 characters")
 	})
 }
+
+func TestIncomingObjectInfo(t *testing.T) {
+	logger := setupTestLogger(t)
+	ctx := context.Background()
+	store := objstore.NewMemObjectStore()
+	fxs := NewFileXfrServer(nil, store, nil, FileXfrConfig{MaxObjectIDLength: 200}, logger)
+
+	t.Run("stored contents are owned by the batch", func(t *testing.T) {
+		objectID, err := fxs.storeFileContents("a,b\n1,2\n", "txns.csv", "batch-1")
+		assert.NoError(t, err)
+
+		info, size, isCompressed, err := objstore.ObjectInfo(ctx, store, objectID, fxs.config.IncomingBucket)
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"mimetype": "text/csv", "ownerclass": "batch", "ownerid": "batch-1", "filename": "txns.csv", "from": "filexfr"}`, info)
+		assert.Equal(t, 8, size)
+		assert.False(t, isCompressed)
+	})
+
+	t.Run("owner is added to the info of an object", func(t *testing.T) {
+		info := objstore.Info{Filename: "in.csv", From: "infiled"}.String()
+		err := objstore.ObjectPut(ctx, store, fxs.config.IncomingBucket, "in.csv", strings.NewReader("x"), 1, info)
+		assert.NoError(t, err)
+
+		assert.NoError(t, fxs.setObjectOwner("in.csv", "batch-2"))
+		got, _, _, err := objstore.ObjectInfo(ctx, store, "in.csv", fxs.config.IncomingBucket)
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"ownerclass": "batch", "ownerid": "batch-2", "filename": "in.csv", "from": "infiled"}`, got)
+	})
+
+	t.Run("failed objects keep their info", func(t *testing.T) {
+		objectID, err := fxs.storeFileContents("bad", "bad.txt", "batch-3")
+		assert.NoError(t, err)
+
+		assert.NoError(t, fxs.moveObjectToFailedBucket(objectID))
+		_, _, _, err = objstore.ObjectInfo(ctx, store, objectID, fxs.config.IncomingBucket)
+		assert.ErrorIs(t, err, objstore.ErrObjectNotFound)
+		info, _, _, err := objstore.ObjectInfo(ctx, store, objectID, fxs.config.FailedBucket)
+		assert.NoError(t, err)
+		assert.Contains(t, info, `"filename":"bad.txt"`)
+
+		assert.ErrorIs(t, fxs.moveObjectToFailedBucket(objectID), objstore.ErrObjectNotFound)
+	})
+}
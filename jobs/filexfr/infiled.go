@@ -39,6 +39,7 @@ import (
 
 	"github.com/bmatcuk/doublestar/v4"
 	"github.com/remiges-tech/alya/jobs"
+	"github.com/remiges-tech/alya/jobs/objstore"
 	"github.com/remiges-tech/logharbour/logharbour"
 )
 
@@ -230,11 +231,18 @@ func (i *Infiled) storeFileInIncomingBucket(filePath string) (string, error) {
 	}
 	defer file.Close()
 
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return "", fmt.Errorf("error getting info of file %s: %w", filePath, err)
+	}
+
 	// Use the file's base name as the object ID
 	objectID := filepath.Base(filePath)
 
-	// Store the file in the incoming bucket
-	err = i.fxs.objStore.Put(context.TODO(), i.fxs.config.IncomingBucket, objectID, file, -1, "application/octet-stream")
+	// Store the file in the incoming bucket, with its name and origin in the object
+	// info; its owner is set once it is submitted as a batch
+	info := objstore.Info{Filename: objectID, From: "infiled"}
+	err = objstore.ObjectPut(context.TODO(), i.fxs.objStore, i.fxs.config.IncomingBucket, objectID, file, fileInfo.Size(), info.String())
 	if err != nil {
 		return "", fmt.Errorf("error storing object %s: %w", objectID, err)
 	}
@@ -244,29 +252,10 @@ func (i *Infiled) storeFileInIncomingBucket(filePath string) (string, error) {
 
 // moveObjectToFailedBucket moves an object from the incoming bucket to the failed bucket
 func (i *Infiled) moveObjectToFailedBucket(objectID string) error {
-	ctx := context.Background()
-
-	// Get the object from the incoming bucket
-	srcObject, err := i.fxs.objStore.Get(ctx, i.fxs.config.IncomingBucket, objectID)
-	if err != nil {
-		return fmt.Errorf("error getting object %s from incoming bucket: %w", objectID, err)
-	}
-	defer srcObject.Close()
-
 	// Use the same object ID when moving to the failed bucket
-	failedObjectID := objectID
-
-	// Put the object in the failed bucket
-	err = i.fxs.objStore.Put(ctx, i.fxs.config.FailedBucket, failedObjectID, srcObject, -1, "application/octet-stream")
-	if err != nil {
-		return fmt.Errorf("error storing object %s in failed bucket: %w", failedObjectID, err)
-	}
-
-	// Remove the original object from the incoming bucket
-	err = i.fxs.objStore.Delete(ctx, i.fxs.config.IncomingBucket, objectID)
+	err := objstore.ObjectMove(context.Background(), i.fxs.objStore, objectID, i.fxs.config.IncomingBucket, i.fxs.config.FailedBucket)
 	if err != nil {
-		i.logger.Error(err).LogActivity("Error removing object from incoming bucket", map[string]any{"objectID": objectID})
+		return fmt.Errorf("error moving object %s to failed bucket: %w", objectID, err)
 	}
-
 	return nil
 }
@@ -0,0 +1,63 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/remiges-tech/alya/jobs/pg/batchsqlc"
+)
+
+var ErrInvalidIdempotencyKey = errors.New("invalid idempotency key")
+
+// maxIdempotencyKeyLen is the size of the key column of batch_idempotency_keys.
+const maxIdempotencyKeyLen = 255
+
+// claimIdempotencyKey records key as used by app for the new batch or slow query
+// batchUUID, using the given (normally transaction-bound) queries. If app used key for
+// another one within the idempotency window, it returns the ID of that one instead, and
+// the new one must not be submitted; otherwise it returns uuid.Nil.
+//
+// The key is held by the primary key of batch_idempotency_keys until the transaction
+// ends, so a concurrent submission with the same key, from any instance, waits for it
+// and then finds the batch it submitted, or claims the key if it was rolled back.
+func (jm *JobManager) claimIdempotencyKey(ctx context.Context, q batchsqlc.Querier, app, key string, batchUUID uuid.UUID) (uuid.UUID, error) {
+	if len(key) > maxIdempotencyKeyLen {
+		return uuid.Nil, fmt.Errorf("%w: longer than %d bytes", ErrInvalidIdempotencyKey, maxIdempotencyKeyLen)
+	}
+
+	now := time.Now()
+	window := time.Duration(jm.config.IdempotencyWindowHours) * time.Hour
+	_, err := q.ClaimIdempotencyKey(ctx, batchsqlc.ClaimIdempotencyKeyParams{
+		App:       app,
+		Key:       key,
+		Batch:     batchUUID,
+		CreatedAt: pgtype.Timestamp{Time: now, Valid: true},
+		Cutoff:    pgtype.Timestamp{Time: now.Add(-window), Valid: true},
+	})
+	if err == nil {
+		return uuid.Nil, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return uuid.Nil, fmt.Errorf("failed to claim idempotency key: %w", err)
+	}
+
+	// The key is in use: find what it was used for
+	existing, err := q.GetIdempotencyKeyBatch(ctx, batchsqlc.GetIdempotencyKeyBatchParams{
+		App: app,
+		Key: key,
+	})
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to get batch of idempotency key: %w", err)
+	}
+	jm.logger.Info().LogActivity("Repeated submission found by its idempotency key", map[string]any{
+		"app":            app,
+		"idempotencyKey": key,
+		"batchId":        existing.String(),
+	})
+	return existing, nil
+}
@@ -0,0 +1,74 @@
+package jobs
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/remiges-tech/alya/jobs/pg/batchsqlc"
+	"github.com/remiges-tech/alya/jobs/pg/batchsqlc/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newIdempotencyQuerier returns a mock in which the key is held by existing, unless it
+// is uuid.Nil.
+func newIdempotencyQuerier(existing uuid.UUID) *mocks.QuerierMock {
+	return &mocks.QuerierMock{
+		ClaimIdempotencyKeyFunc: func(ctx context.Context, arg batchsqlc.ClaimIdempotencyKeyParams) (uuid.UUID, error) {
+			if existing != uuid.Nil {
+				return uuid.Nil, pgx.ErrNoRows
+			}
+			return arg.Batch, nil
+		},
+		GetIdempotencyKeyBatchFunc: func(ctx context.Context, arg batchsqlc.GetIdempotencyKeyBatchParams) (uuid.UUID, error) {
+			return existing, nil
+		},
+	}
+}
+
+func TestClaimIdempotencyKey(t *testing.T) {
+	q := newIdempotencyQuerier(uuid.Nil)
+	jm := newTestJobManager(q, nil)
+	batchUUID := uuid.New()
+
+	existing, err := jm.claimIdempotencyKey(context.Background(), q, "bank", "payroll-2024-03", batchUUID)
+	require.NoError(t, err)
+	assert.Equal(t, uuid.Nil, existing)
+
+	require.Len(t, q.ClaimIdempotencyKeyCalls(), 1)
+	claim := q.ClaimIdempotencyKeyCalls()[0].Arg
+	assert.Equal(t, "bank", claim.App)
+	assert.Equal(t, "payroll-2024-03", claim.Key)
+	assert.Equal(t, batchUUID, claim.Batch)
+	assert.Equal(t, ALYA_IDEMPOTENCY_WINDOW_HOURS*time.Hour, claim.CreatedAt.Time.Sub(claim.Cutoff.Time))
+	assert.Empty(t, q.GetIdempotencyKeyBatchCalls())
+}
+
+func TestClaimIdempotencyKey_Repeated(t *testing.T) {
+	first := uuid.New()
+	q := newIdempotencyQuerier(first)
+	jm := newTestJobManager(q, nil)
+	jm.config.IdempotencyWindowHours = 2
+
+	existing, err := jm.claimIdempotencyKey(context.Background(), q, "bank", "payroll-2024-03", uuid.New())
+	require.NoError(t, err)
+	assert.Equal(t, first, existing)
+
+	claim := q.ClaimIdempotencyKeyCalls()[0].Arg
+	assert.Equal(t, 2*time.Hour, claim.CreatedAt.Time.Sub(claim.Cutoff.Time))
+	require.Len(t, q.GetIdempotencyKeyBatchCalls(), 1)
+	assert.Equal(t, batchsqlc.GetIdempotencyKeyBatchParams{App: "bank", Key: "payroll-2024-03"}, q.GetIdempotencyKeyBatchCalls()[0].Arg)
+}
+
+func TestClaimIdempotencyKey_TooLong(t *testing.T) {
+	q := newIdempotencyQuerier(uuid.Nil)
+	jm := newTestJobManager(q, nil)
+
+	_, err := jm.claimIdempotencyKey(context.Background(), q, "bank", strings.Repeat("k", maxIdempotencyKeyLen+1), uuid.New())
+	assert.ErrorIs(t, err, ErrInvalidIdempotencyKey)
+	assert.Empty(t, q.ClaimIdempotencyKeyCalls())
+}
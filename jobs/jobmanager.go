@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"math/rand"
+	"net/http"
 	"os"
 	"runtime/debug"
 	"strings"
@@ -79,6 +80,13 @@ func (e ProcessorNotFoundError) Unwrap() error {
 const ALYA_BATCHCHUNK_NROWS = 10
 const ALYA_BATCHSTATUS_CACHEDUR_SEC = 60
 const ALYA_POLLING_INTERVAL_SEC = 45
+const ALYA_WORKERS = 1
+const ALYA_SUBMITCHUNK_NROWS = 1000
+const ALYA_WEBHOOK_MAX_ATTEMPTS = 8
+const ALYA_ARCHIVE_BUCKET = "alya-batch-archive"
+const ALYA_IDEMPOTENCY_WINDOW_HOURS = 24
+const ALYA_SUMMARYCHUNK_NROWS = 1000
+const ALYA_OUTPUTPART_SIZE = 16 << 20
 
 // Assuming global variables are defined elsewhere
 // make all the maps sync maps to make them thread safe
@@ -94,17 +102,31 @@ var (
 // 3. Update the corresponding batchrows and batches records with the results
 // 4. Check for completed batches and summarize them
 type JobManager struct {
-	db                      *pgxpool.Pool
+	store                   Store
 	queries                 batchsqlc.Querier
-	redisClient             *redis.Client
+	redisClient             redis.UniversalClient // Coordinates the instances; nil without Redis
+	statusCache             StatusCache           // Caches job status; nil to always read the database
 	objStore                objstore.ObjectStore
 	initblocks              map[string]InitBlock
 	initfuncs               map[string]Initializer
-	slowqueryprocessorfuncs map[string]SlowQueryProcessor
-	batchprocessorfuncs     map[string]BatchProcessor
+	slowqueryprocessorfuncs map[string]SlowQueryProcessorV2
+	batchprocessorfuncs     map[string]BatchProcessorV2
+	retrypolicies           map[string]RetryPolicy
+	schedules               map[string]*registeredSchedule
+	processorlimits         map[string]ProcessorLimits
+	rowtimeouts             map[string]time.Duration
+	retention               map[string]RetentionPolicy_t
+	outputformats           map[string]OutputFormat
+	outputpipelines         map[string]OutputPipeline
 	logger                  *logharbour.Logger
 	config                  JobManagerConfig
-	mu                      sync.RWMutex // Protects initblocks and initfuncs maps
+	mu                      sync.RWMutex // Protects initblocks, initfuncs, schedules, processorlimits, rowtimeouts, retention, outputformats and outputpipelines maps
+	slotsmu                 sync.Mutex   // Protects heldslots
+	heldslots               map[string]string // In-flight slots held by this instance, member -> Redis key
+	wakeup                  wakeup            // Wakes idle processing loops on queued jobs notifications
+	listenOnce              sync.Once         // Starts the notification listener once
+	webhookClient           *http.Client      // Posts completion webhooks
+	running                 runningRows       // Cancellation of the rows being processed
 	instanceID              string       // Unique identifier for this JobManager instance
 }
 
@@ -118,9 +140,28 @@ func generateInstanceID() string {
 	return fmt.Sprintf("%s-%d-%d", hostname, os.Getpid(), time.Now().UnixNano())
 }
 
-// NewJobManager creates a new instance of JobManager.
+// NewJobManager creates a new instance of JobManager on a PostgreSQL database, a Redis
+// status cache and a MinIO object store. redisClient may be nil, in which case the
+// JobManager runs without Redis (see "Running without Redis" in the README).
 // It initializes the necessary fields and returns a pointer to the JobManager.
 func NewJobManager(db *pgxpool.Pool, redisClient *redis.Client, minioClient *minio.Client, logger *logharbour.Logger, config *JobManagerConfig) *JobManager {
+	var store Store
+	if db != nil {
+		store = NewPgStore(db)
+	}
+	var statusCache StatusCache
+	if redisClient != nil {
+		statusCache = NewRedisStatusCache(redisClient)
+	}
+	return NewJobManagerWithBackends(store, statusCache, objstore.NewMinioObjectStore(minioClient), logger, config)
+}
+
+// NewJobManagerWithBackends creates a new instance of JobManager on the given store, status
+// cache and object store. With NewMemoryStore, NewMemoryCache and
+// objstore.NewMemObjectStore, the JobManager runs entirely in memory (see "Running in
+// Memory" in the README). Only a RedisStatusCache gives the JobManager Redis; with any
+// other status cache, or a nil one, it runs without Redis.
+func NewJobManagerWithBackends(store Store, statusCache StatusCache, objStore objstore.ObjectStore, logger *logharbour.Logger, config *JobManagerConfig) *JobManager {
 	if logger == nil {
 		panic("logger cannot be nil")
 	}
@@ -141,16 +182,68 @@ func NewJobManager(db *pgxpool.Pool, redisClient *redis.Client, minioClient *min
 	if config.PollingIntervalSec == 0 {
 		config.PollingIntervalSec = ALYA_POLLING_INTERVAL_SEC
 	}
+	if config.Workers <= 0 {
+		config.Workers = ALYA_WORKERS
+	}
+	if config.SubmitChunkNRows <= 0 {
+		config.SubmitChunkNRows = ALYA_SUBMITCHUNK_NROWS
+	}
+	if config.WebhookMaxAttempts <= 0 {
+		config.WebhookMaxAttempts = ALYA_WEBHOOK_MAX_ATTEMPTS
+	}
+	if config.ArchiveBucket == "" {
+		config.ArchiveBucket = ALYA_ARCHIVE_BUCKET
+	}
+	if config.IdempotencyWindowHours <= 0 {
+		config.IdempotencyWindowHours = ALYA_IDEMPOTENCY_WINDOW_HOURS
+	}
+	if config.SummaryChunkNRows <= 0 {
+		config.SummaryChunkNRows = ALYA_SUMMARYCHUNK_NROWS
+	}
+	if config.OutputPartSize <= 0 {
+		config.OutputPartSize = ALYA_OUTPUTPART_SIZE
+	} else if config.OutputPartSize < objstore.MinPartSize {
+		config.OutputPartSize = objstore.MinPartSize
+	}
+	switch config.FetchPolicy {
+	case FetchPolicyPriority, FetchPolicyFairShare:
+	case "":
+		config.FetchPolicy = FetchPolicyPriority
+	default:
+		logger.Warn().LogActivity("Unknown FetchPolicy configured, using default: priority", map[string]any{
+			"fetchPolicy": config.FetchPolicy,
+		})
+		config.FetchPolicy = FetchPolicyPriority
+	}
+
+	var queries batchsqlc.Querier
+	if store != nil {
+		queries = store.Queries()
+	}
+	var redisClient redis.UniversalClient
+	if c, ok := statusCache.(*RedisStatusCache); ok {
+		redisClient = c.client
+	}
 
 	return &JobManager{
-		db:                      db,
-		queries:                 batchsqlc.New(db),
+		store:                   store,
+		queries:                 queries,
 		redisClient:             redisClient,
-		objStore:                objstore.NewMinioObjectStore(minioClient),
+		statusCache:             statusCache,
+		objStore:                objStore,
 		initblocks:              make(map[string]InitBlock),
 		initfuncs:               make(map[string]Initializer),
-		slowqueryprocessorfuncs: make(map[string]SlowQueryProcessor),
-		batchprocessorfuncs:     make(map[string]BatchProcessor),
+		slowqueryprocessorfuncs: make(map[string]SlowQueryProcessorV2),
+		batchprocessorfuncs:     make(map[string]BatchProcessorV2),
+		retrypolicies:           make(map[string]RetryPolicy),
+		schedules:               make(map[string]*registeredSchedule),
+		processorlimits:         make(map[string]ProcessorLimits),
+		rowtimeouts:             make(map[string]time.Duration),
+		retention:               make(map[string]RetentionPolicy_t),
+		outputformats:           make(map[string]OutputFormat),
+		outputpipelines:         make(map[string]OutputPipeline),
+		heldslots:               make(map[string]string),
+		webhookClient:           &http.Client{Timeout: webhookTimeout},
 		logger:                  logger,
 		config:                  *config,
 		instanceID:              generateInstanceID(),
@@ -273,6 +366,11 @@ func (jm *JobManager) Run() {
 	go jm.runHeartbeat()
 	go jm.runPeriodicRecovery(ctx)
 	go jm.runPeriodicSweep(ctx)
+	go jm.runScheduler(ctx)
+	go jm.runWebhookDeliverer(ctx)
+	go jm.runAbortListener(ctx)
+	go jm.runRetention(ctx)
+	jm.startListener(ctx)
 
 	// Circuit breaker pattern at the supervisor layer:
 	// This is the ONLY layer where we make health decisions about the entire system.
@@ -309,6 +407,7 @@ func (jm *JobManager) Run() {
 
 			// Run a single iteration of the job processing loop
 			// Only sleep if no rows were processed
+			woken := jm.wakeup.wait()
 			hadRows := jm.RunOneIteration()
 			
 			// If we reach here, the iteration completed successfully
@@ -320,9 +419,10 @@ func (jm *JobManager) Run() {
 				consecutivePanics = 0
 			}
 
-			// Sleep only if no rows were found to process
+			// Sleep only if no rows were found to process, until new jobs are
+			// announced or the polling interval has passed
 			if !hadRows {
-				time.Sleep(jm.getRandomSleepDuration())
+				jm.sleepUntilWork(ctx, woken)
 			}
 		}()
 	}
@@ -340,6 +440,11 @@ func (jm *JobManager) RunWithContext(ctx context.Context) {
 	go jm.runHeartbeat()
 	go jm.runPeriodicRecovery(ctx)
 	go jm.runPeriodicSweep(ctx)
+	go jm.runScheduler(ctx)
+	go jm.runWebhookDeliverer(ctx)
+	go jm.runAbortListener(ctx)
+	go jm.runRetention(ctx)
+	jm.startListener(ctx)
 
 	// Circuit breaker pattern: same as Run() but respects context cancellation
 	consecutivePanics := 0
@@ -377,6 +482,7 @@ func (jm *JobManager) RunWithContext(ctx context.Context) {
 				}()
 
 				// Run a single iteration of the job processing loop
+				woken := jm.wakeup.wait()
 				hadRows := jm.RunOneIterationWithContext(ctx)
 				
 				// Success - reset panic counter
@@ -387,14 +493,10 @@ func (jm *JobManager) RunWithContext(ctx context.Context) {
 					consecutivePanics = 0
 				}
 
-				// Sleep only if no rows were found to process
+				// Sleep only if no rows were found to process, until new jobs are
+				// announced or the polling interval has passed
 				if !hadRows {
-					select {
-					case <-ctx.Done():
-						return
-					case <-time.After(jm.getRandomSleepDuration()):
-						// Continue to the next iteration
-					}
+					jm.sleepUntilWork(ctx, woken)
 				}
 			}()
 		}
@@ -446,7 +548,7 @@ func (jm *JobManager) RunOneIterationWithContext(ctx context.Context) (hadRows b
 		"iteration": "start",
 	})
 	txStartTime := time.Now()
-	tx, err := jm.db.Begin(ctx)
+	tx, err := jm.store.Begin(ctx)
 	if err != nil {
 		jm.logger.Error(err).LogActivity("Error starting transaction", map[string]any{
 			"elapsedMs": time.Since(txStartTime).Milliseconds(),
@@ -472,17 +574,15 @@ func (jm *JobManager) RunOneIterationWithContext(ctx context.Context) (hadRows b
 	}()
 
 	// Create a new Queries instance using the transaction
-	txQueries := batchsqlc.New(tx)
+	txQueries := tx.Queries()
 
 	// Fetch a block of rows from the database
 	jm.logger.Debug0().LogActivity("Fetching block of rows", map[string]any{
 		"status": "queued",
 		"limit": jm.config.BatchChunkNRows,
+		"policy": jm.config.FetchPolicy,
 	})
-	blockOfRows, err := txQueries.FetchBlockOfRows(ctx, batchsqlc.FetchBlockOfRowsParams{
-		Status: batchsqlc.StatusEnumQueued,
-		Limit:  int32(jm.config.BatchChunkNRows),
-	})
+	blockOfRows, err := jm.fetchBlockOfRows(ctx, txQueries, time.Now())
 	if err != nil {
 		jm.logger.Error(err).LogActivity("Error fetching block of rows", nil)
 		tx.Rollback(ctx)
@@ -591,6 +691,15 @@ func (jm *JobManager) RunOneIterationWithContext(ctx context.Context) (hadRows b
 		return false
 	}
 
+	// Without Redis, lease the rows to this instance for crash recovery
+	if err := jm.leaseRows(ctx, txQueries, rowIDs); err != nil {
+		jm.logger.Error(err).LogActivity("Error leasing batch rows", map[string]any{
+			"rowCount": len(rowIDs),
+		})
+		tx.Rollback(ctx)
+		return false
+	}
+
 	// CANCELLATION POINT 3: Before committing first transaction
 	// Avoids committing changes if shutdown was requested during row status updates
 	// Ensures database consistency by rolling back incomplete work
@@ -621,45 +730,33 @@ func (jm *JobManager) RunOneIterationWithContext(ctx context.Context) (hadRows b
 		}
 	}
 
-	// Process the rows
-	// Track which batch+app+op combinations we've already processed for errors
-	processedErrorCombinations := make(map[string]bool)
+	// Keep the progress counters of the batches up to date
+	jm.trackRowsStarted(ctx, blockOfRows)
 
+	// Process the rows, in parallel if more than one worker is configured
+	// Track which batch+app+op combinations we've already processed for errors
+	errs := &processedErrors{combinations: make(map[string]bool)}
+
+	workers := min(max(jm.config.Workers, 1), len(blockOfRows))
+	rowsCh := make(chan batchsqlc.FetchBlockOfRowsRow)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for row := range rowsCh {
+				jm.processFetchedRow(ctx, row, errs)
+			}
+		}()
+	}
+	// No cancellation point here. Once rows are committed as inprog,
+	// we finish processing them all. If SIGKILL arrives before completion,
+	// crash recovery resets the remaining rows via heartbeat expiry.
 	for _, row := range blockOfRows {
-		// No cancellation point here. Once rows are committed as inprog,
-		// we finish processing them all. If SIGKILL arrives before completion,
-		// crash recovery resets the remaining rows via heartbeat expiry.
-
-		// send queries instance, not transaction
-		q := jm.queries
-		jm.logger.Debug0().LogActivity("Processing row", map[string]any{
-			"rowId": row.Rowid,
-			"batchId": row.Batch.String(),
-			"app": row.App,
-			"op": row.Op,
-			"line": row.Line,
-		})
-		_, err = jm.processRow(q, row)
-
-		if untrackErr := jm.untrackRowProcessing(row.Rowid); untrackErr != nil {
-			jm.logger.Warn().LogActivity("Failed to untrack row from Redis", map[string]any{
-				"rowId": row.Rowid,
-				"error": untrackErr.Error(),
-			})
-		}
-
-		if err != nil {
-			jm.logger.Error(err).LogActivity("Error processing row", map[string]any{
-				"rowId": row.Rowid,
-				"batchId": row.Batch.String(),
-				"app": row.App,
-				"op": row.Op,
-			})
-			// Apply appropriate error handling strategy based on error type
-			jm.handleProcessingError(err, row, processedErrorCombinations)
-			continue
-		}
+		rowsCh <- row
 	}
+	close(rowsCh)
+	wg.Wait()
 
 	// CANCELLATION POINT 5: Before starting summary transaction
 	// Avoids starting a new transaction phase if shutdown is requested
@@ -704,7 +801,103 @@ func (jm *JobManager) RunOneIterationWithContext(ctx context.Context) (hadRows b
 	return true
 }
 
-func (jm *JobManager) processRow(txQueries batchsqlc.Querier, row batchsqlc.FetchBlockOfRowsRow) (status batchsqlc.StatusEnum, err error) {
+// processedErrors records the batch+app and batch+app+op combinations whose
+// configuration errors have already been handled during one iteration.
+// Workers share it, so handleProcessingError calls are serialised by mu.
+type processedErrors struct {
+	mu           sync.Mutex
+	combinations map[string]bool
+}
+
+// processFetchedRow processes one row of the fetched block: it waits for the
+// processor limits of the row's (app, op), runs the processor, untracks the row
+// and handles any error. It is run by the iteration's worker goroutines.
+func (jm *JobManager) processFetchedRow(ctx context.Context, row batchsqlc.FetchBlockOfRowsRow, errs *processedErrors) {
+	// processRow isolates panics in processors; this catches the rest so
+	// that a bug here cannot take down the whole process from a worker goroutine.
+	// The row stays tracked, so crash recovery resets it if needed.
+	defer func() {
+		if r := recover(); r != nil {
+			jm.logger.Error(fmt.Errorf("panic recovered: %v", r)).LogActivity("Panic in processFetchedRow", map[string]any{
+				"panic": fmt.Sprintf("%v", r),
+				"rowId": row.Rowid,
+				"batchId": row.Batch.String(),
+				"stackTrace": string(debug.Stack()),
+			})
+		}
+	}()
+
+	// send queries instance, not transaction
+	q := jm.queries
+	jm.logger.Debug0().LogActivity("Processing row", map[string]any{
+		"rowId": row.Rowid,
+		"batchId": row.Batch.String(),
+		"app": row.App,
+		"op": row.Op,
+		"line": row.Line,
+	})
+	// Wait for the concurrency and rate limits of the (app, op), if any
+	release, limitErr := jm.waitForProcessorLimits(ctx, row.App, row.Op, row.Rowid)
+	if limitErr != nil {
+		if ctx.Err() != nil {
+			// Shutdown while waiting: hand the row back to the queue
+			jm.requeueRow(row)
+			return
+		}
+		jm.logger.Warn().LogActivity("Processor limits not enforced for row", map[string]any{
+			"rowId": row.Rowid,
+			"app": row.App,
+			"op": row.Op,
+			"error": limitErr.Error(),
+		})
+		release = func() {}
+	}
+	rowCtx, done := jm.rowContext(ctx, row)
+	_, err := jm.processRow(rowCtx, q, row)
+	done()
+	release()
+
+	// A row interrupted by shutdown goes back to the queue, and is untracked once it is
+	if errors.Is(err, ErrShuttingDown) {
+		jm.requeueRow(row)
+		return
+	}
+
+	if untrackErr := jm.untrackRowProcessing(row.Rowid); untrackErr != nil {
+		jm.logger.Warn().LogActivity("Failed to untrack row from Redis", map[string]any{
+			"rowId": row.Rowid,
+			"error": untrackErr.Error(),
+		})
+	}
+
+	if errors.Is(err, ErrJobAborted) {
+		// BatchAbort or SlowQueryAbort has already marked the row aborted
+		jm.logger.Info().LogActivity("Row interrupted by abort", map[string]any{
+			"rowId": row.Rowid,
+			"batchId": row.Batch.String(),
+		})
+		return
+	}
+	if err != nil {
+		jm.logger.Error(err).LogActivity("Error processing row", map[string]any{
+			"rowId": row.Rowid,
+			"batchId": row.Batch.String(),
+			"app": row.App,
+			"op": row.Op,
+		})
+		// Rows that failed with a transient error go back to the queue
+		// if their retry policy allows another attempt
+		if jm.retryRow(row, err) {
+			return
+		}
+		// Apply appropriate error handling strategy based on error type
+		errs.mu.Lock()
+		defer errs.mu.Unlock()
+		jm.handleProcessingError(err, row, errs.combinations)
+	}
+}
+
+func (jm *JobManager) processRow(ctx context.Context, txQueries batchsqlc.Querier, row batchsqlc.FetchBlockOfRowsRow) (status batchsqlc.StatusEnum, err error) {
 	// Row-level recovery: MUST ALWAYS recover to isolate failures.
 	// This layer handles individual row failures without affecting other rows.
 	// 
@@ -739,7 +932,7 @@ func (jm *JobManager) processRow(txQueries batchsqlc.Querier, row batchsqlc.Fetc
 				jm.logger.Error(nil).LogActivity("Panic occurred in getOrCreateInitBlock", panicDetails)
 			}
 			status = batchsqlc.StatusEnumFailed
-			err = fmt.Errorf("panic during row processing: %v", r)
+			err = fmt.Errorf("%w: %v", errRowPanicked, r)
 		}
 	}()
 
@@ -751,7 +944,7 @@ func (jm *JobManager) processRow(txQueries batchsqlc.Querier, row batchsqlc.Fetc
 			"app": row.App,
 			"op": row.Op,
 		})
-		return jm.processSlowQuery(txQueries, row)
+		return jm.processSlowQuery(ctx, row)
 	} else {
 		jm.logger.Debug0().LogActivity("Processing batch job", map[string]any{
 			"rowId": row.Rowid,
@@ -760,16 +953,17 @@ func (jm *JobManager) processRow(txQueries batchsqlc.Querier, row batchsqlc.Fetc
 			"op": row.Op,
 			"line": row.Line,
 		})
-		return jm.processBatchJob(txQueries, row)
+		return jm.processBatchJob(ctx, txQueries, row)
 	}
 }
 
 // processSlowQuery processes a single slow query job. It retrieves the registered SlowQueryProcessor
 // for the given app and op, fetches the associated InitBlock, and invokes the processor's DoSlowQuery
 // method. It then calls updateSlowQueryResult to update the corresponding batchrows and batches records
-// with the processing results. If the processor is not found or the processing fails, an error is returned.
+// with the processing results, in a transaction that also queues the completion webhook and releases the
+// batches waiting on the slow query. If the processor is not found or the processing fails, an error is returned.
 
-func (jm *JobManager) processSlowQuery(txQueries batchsqlc.Querier, row batchsqlc.FetchBlockOfRowsRow) (batchsqlc.StatusEnum, error) {
+func (jm *JobManager) processSlowQuery(ctx context.Context, row batchsqlc.FetchBlockOfRowsRow) (batchsqlc.StatusEnum, error) {
 	jm.logger.Info().LogActivity("Starting slow query processing", map[string]any{
 		"rowId": row.Rowid,
 		"batchId": row.Batch.String(),
@@ -787,7 +981,7 @@ func (jm *JobManager) processSlowQuery(txQueries batchsqlc.Querier, row batchsql
 	}
 
 	// Assert that the processor is of the correct type
-	processor, ok := p.(SlowQueryProcessor)
+	processor, ok := p.(SlowQueryProcessorV2)
 	if !ok {
 		return batchsqlc.StatusEnumFailed, &ConfigurationError{
 			BaseErr: ErrInvalidProcessorType,
@@ -836,7 +1030,7 @@ func (jm *JobManager) processSlowQuery(txQueries batchsqlc.Querier, row batchsql
 		"processor": fmt.Sprintf("%T", processor),
 	})
 	startTime := time.Now()
-	status, result, messages, outputFiles, err := processor.DoSlowQuery(initBlock, rowContext, rowInput)
+	status, result, messages, outputFiles, err := processor.DoSlowQuery(ctx, initBlock, rowContext, rowInput)
 	elapsedTime := time.Since(startTime)
 	jm.logger.Debug0().LogActivity("Slow query processor execution completed", map[string]any{
 		"rowId": row.Rowid,
@@ -844,13 +1038,17 @@ func (jm *JobManager) processSlowQuery(txQueries batchsqlc.Querier, row batchsql
 		"status": status,
 		"error": err != nil,
 	})
+	// A slow query interrupted by an abort or shutdown, or that ran out of time, is not recorded here
+	if cancelErr := rowCancelledError(ctx, err); cancelErr != nil {
+		return batchsqlc.StatusEnumFailed, cancelErr
+	}
 	if err != nil {
 		jm.logger.Error(err).LogActivity("Slow query processor failed", map[string]any{
 			"rowId": row.Rowid,
 			"app": row.App,
 			"op": row.Op,
 		})
-		return batchsqlc.StatusEnumFailed, fmt.Errorf("error processing slow query for app %s and op %s: %v", row.App, row.Op, err)
+		return batchsqlc.StatusEnumFailed, fmt.Errorf("error processing slow query for app %s and op %s: %w", row.App, row.Op, err)
 	}
 	
 	jm.logger.Info().LogActivity("Slow query processor completed", map[string]any{
@@ -860,12 +1058,21 @@ func (jm *JobManager) processSlowQuery(txQueries batchsqlc.Querier, row batchsql
 		"hasOutputFiles": len(outputFiles) > 0,
 	})
 
+	// Record the results, queue the completion webhook and release the batches waiting on
+	// this slow query in one transaction, so that a failure leaves none of them done
+	tx, err := jm.store.Begin(context.Background())
+	if err != nil {
+		return batchsqlc.StatusEnumFailed, fmt.Errorf("error starting transaction for slow query result: %w", err)
+	}
+	defer tx.Rollback(context.Background())
+	q := tx.Queries()
+
 	// Update the corresponding batchrows and batches records with the results
 	jm.logger.Debug0().LogActivity("Updating slow query result", map[string]any{
 		"rowId": row.Rowid,
 		"status": status,
 	})
-	if err := updateSlowQueryResult(txQueries, row, status, result, messages, outputFiles); err != nil {
+	if err := updateSlowQueryResult(q, row, status, result, messages, outputFiles); err != nil {
 		jm.logger.Error(err).LogActivity("Error updating slow query result", map[string]any{
 			"rowId": row.Rowid,
 			"app": row.App,
@@ -873,6 +1080,37 @@ func (jm *JobManager) processSlowQuery(txQueries batchsqlc.Querier, row batchsql
 		})
 		return batchsqlc.StatusEnumFailed, fmt.Errorf("error updating slow query result for app %s and op %s: %v", row.App, row.Op, err)
 	}
+
+	// Queue the completion webhook, if the slow query has a callback URL
+	event := CompletionEvent_t{
+		Event:       WebhookEventSlowQueryDone,
+		ID:          row.Batch.String(),
+		App:         row.App,
+		Op:          row.Op,
+		Status:      status,
+		OutputFiles: outputFiles,
+		DoneAt:      time.Now(),
+	}
+	if err := enqueueCompletionWebhook(context.Background(), q, row.Batch, event); err != nil {
+		jm.logger.Error(err).LogActivity("Error queueing slow query completion webhook", map[string]any{
+			"rowId": row.Rowid,
+			"batchId": row.Batch.String(),
+		})
+		return batchsqlc.StatusEnumFailed, err
+	}
+
+	// Abort or queue the batches waiting on this slow query
+	if err := jm.releaseDependents(context.Background(), q, row.Batch); err != nil {
+		jm.logger.Error(err).LogActivity("Error releasing batches waiting on slow query", map[string]any{
+			"rowId": row.Rowid,
+			"batchId": row.Batch.String(),
+		})
+		return batchsqlc.StatusEnumFailed, err
+	}
+
+	if err := tx.Commit(context.Background()); err != nil {
+		return batchsqlc.StatusEnumFailed, fmt.Errorf("error committing slow query result: %w", err)
+	}
 	
 	// Log the row and batch status change
 	rowChangeDetails := logharbour.ChangeInfo{
@@ -914,7 +1152,7 @@ func (jm *JobManager) processSlowQuery(txQueries batchsqlc.Querier, row batchsql
 // given app and op, fetches the associated InitBlock, and invokes the processor's DoBatchJob method.
 // It then calls updateBatchJobResult to update the corresponding batchrows record with the processing results.
 // If the processor is not found or the processing fails, an error is returned.
-func (jm *JobManager) processBatchJob(txQueries batchsqlc.Querier, row batchsqlc.FetchBlockOfRowsRow) (batchsqlc.StatusEnum, error) {
+func (jm *JobManager) processBatchJob(ctx context.Context, txQueries batchsqlc.Querier, row batchsqlc.FetchBlockOfRowsRow) (batchsqlc.StatusEnum, error) {
 	jm.logger.Info().LogActivity("Starting batch job processing", map[string]any{
 		"rowId": row.Rowid,
 		"batchId": row.Batch.String(),
@@ -938,7 +1176,7 @@ func (jm *JobManager) processBatchJob(txQueries batchsqlc.Querier, row batchsqlc
 	}
 
 	// Assert that the processor is of the correct type
-	processor, ok := p.(BatchProcessor)
+	processor, ok := p.(BatchProcessorV2)
 	if !ok {
 		return batchsqlc.StatusEnumFailed, &ConfigurationError{
 			BaseErr: ErrInvalidProcessorType,
@@ -980,7 +1218,7 @@ func (jm *JobManager) processBatchJob(txQueries batchsqlc.Querier, row batchsqlc
 		"line": row.Line,
 	})
 	startTime := time.Now()
-	status, result, messages, blobRows, err := processor.DoBatchJob(initBlock, rowContext, int(row.Line), rowInput)
+	status, result, messages, blobRows, err := processor.DoBatchJob(ctx, initBlock, rowContext, int(row.Line), rowInput)
 	elapsedTime := time.Since(startTime)
 	jm.logger.Debug0().LogActivity("Batch processor execution completed", map[string]any{
 		"rowId": row.Rowid,
@@ -989,6 +1227,18 @@ func (jm *JobManager) processBatchJob(txQueries batchsqlc.Querier, row batchsqlc
 		"status": status,
 		"error": err != nil,
 	})
+	// A row interrupted by an abort or shutdown is not recorded; one that ran out of time fails
+	if cancelErr := rowCancelledError(ctx, err); cancelErr != nil {
+		if !errors.Is(cancelErr, ErrRowTimeout) {
+			return batchsqlc.StatusEnumFailed, cancelErr
+		}
+		status, err = batchsqlc.StatusEnumFailed, cancelErr
+		messages = append(messages, wscutils.ErrorMessage{
+			MsgID:   MsgIDRowTimeout,
+			ErrCode: ErrCodeTimeout,
+			Vals:    []string{cancelErr.Error()},
+		})
+	}
 	if err != nil {
 		jm.logger.Error(err).LogActivity("Batch processor failed", map[string]any{
 			"rowId": row.Rowid,
@@ -1005,6 +1255,13 @@ func (jm *JobManager) processBatchJob(txQueries batchsqlc.Querier, row batchsqlc
 		"hasBlobRows": len(blobRows) > 0,
 		"error": err != nil,
 	})
+	// A transient error with attempts left is handed back to the caller so
+	// that the row is requeued instead of recording this attempt's result
+	if err != nil {
+		if retry, _ := jm.shouldRetry(row, err); retry {
+			return batchsqlc.StatusEnumFailed, err
+		}
+	}
 	// TODO: check if it should return the error and what its effects are
 	// if err != nil {
 	// return batchsqlc.StatusEnumFailed, fmt.Errorf("error processing batch job for app %s and op %s: %v", row.App, row.Op, err)
@@ -1132,6 +1389,7 @@ func (jm *JobManager) updateBatchJobResult(txQueries batchsqlc.Querier, row batc
 	if err != nil {
 		return err
 	}
+	jm.trackRowDone(row.Batch, status)
 
 	return nil
 }
@@ -1157,7 +1415,7 @@ func (jm *JobManager) summarizeCompletedBatches(ctx context.Context, batchSet ma
 			}
 
 			// Start fresh transaction for each attempt (new snapshot)
-			tx, err := jm.db.Begin(ctx)
+			tx, err := jm.store.Begin(ctx)
 			if err != nil {
 				jm.logger.Error(err).LogActivity("Failed to start transaction for batch", map[string]any{
 					"batchId": batchID.String(),
@@ -1167,7 +1425,7 @@ func (jm *JobManager) summarizeCompletedBatches(ctx context.Context, batchSet ma
 				break // Cannot retry if cannot start transaction
 			}
 
-			txQueries := batchsqlc.New(tx)
+			txQueries := tx.Queries()
 
 			jm.logger.Info().LogActivity("Attempting batch summarization in individual transaction", map[string]any{
 				"batchId": batchID.String(),
@@ -1371,8 +1629,8 @@ func (jm *JobManager) updateBatchStatusToFailed(batchID uuid.UUID) error {
 
 	if err == nil {
 		// Also update Redis cache if applicable
-		if jm.redisClient != nil {
-			cacheErr := updateStatusInRedis(jm.redisClient, batchID, batchsqlc.StatusEnumFailed,
+		if jm.statusCache != nil {
+			cacheErr := updateStatusInRedis(jm.statusCache, batchID, batchsqlc.StatusEnumFailed,
 				jm.config.BatchStatusCacheDurSec)
 			if cacheErr != nil {
 				jm.logger.Warn().LogActivity("Failed to update Redis cache for batch", map[string]any{
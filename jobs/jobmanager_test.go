@@ -6,10 +6,21 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/remiges-tech/alya/jobs/pg/batchsqlc"
 	"github.com/remiges-tech/logharbour/logharbour"
 	"github.com/stretchr/testify/assert"
 )
 
+// newTestJobManager returns a JobManager with the given config, or the defaults if it is
+// nil, without Redis or object store, whose queries are q.
+func newTestJobManager(q batchsqlc.Querier, config *JobManagerConfig) *JobManager {
+	loggerCtx := &logharbour.LoggerContext{}
+	logger := logharbour.NewLogger(loggerCtx, "test", log.Writer())
+	jm := NewJobManager(nil, nil, nil, logger, config)
+	jm.queries = q
+	return jm
+}
+
 func TestJobManagerInstanceID(t *testing.T) {
 	loggerCtx := &logharbour.LoggerContext{}
 	logger := logharbour.NewLogger(loggerCtx, "test", log.Writer())
@@ -0,0 +1,113 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/remiges-tech/alya/jobs/pg/batchsqlc"
+)
+
+// A JobManager without a Redis client tracks its heartbeat and the rows it is
+// processing in the store instead: its heartbeat in the workers table, which is
+// also the worker registry, and its rows by leasing them to itself in batchrows.
+// Recovery then works as it does with Redis: the rows of an instance whose
+// heartbeat has expired are reset to queued by the other instances.
+
+// leasesInStore reports whether this instance tracks its heartbeat and rows in the
+// store, as it does when it has no Redis client.
+func (jm *JobManager) leasesInStore() bool {
+	return jm.redisClient == nil && jm.store != nil
+}
+
+// leaseRows leases the rows being picked up to this instance, in the transaction
+// that marks them inprog, so that no row is ever inprog without an owner. It does
+// nothing with Redis, where the rows are tracked by trackRowProcessing once the
+// transaction commits.
+func (jm *JobManager) leaseRows(ctx context.Context, txQueries batchsqlc.Querier, rowIDs []int64) error {
+	if !jm.leasesInStore() {
+		return nil
+	}
+	return txQueries.LeaseBatchRows(ctx, batchsqlc.LeaseBatchRowsParams{
+		Worker:      jm.instanceID,
+		LeasedUntil: pgtype.Timestamp{Time: time.Now().Add(workerRowsTTL), Valid: true},
+		RowIds:      rowIDs,
+	})
+}
+
+// renewRowLeases extends the leases of the rows this instance is still processing,
+// or the TTL of its rows SET in Redis. It is called on every heartbeat.
+func (jm *JobManager) renewRowLeases(ctx context.Context) error {
+	if jm.redisClient != nil {
+		// EXPIRE on a non-existent key (no rows tracked or all rows untracked) is a no-op
+		return jm.redisClient.Expire(ctx, workerRowsKey(jm.instanceID), workerRowsTTL).Err()
+	}
+	if jm.store == nil {
+		return nil
+	}
+	return jm.queries.RenewBatchRowLeases(ctx, batchsqlc.RenewBatchRowLeasesParams{
+		LeasedUntil: pgtype.Timestamp{Time: time.Now().Add(workerRowsTTL), Valid: true},
+		Worker:      jm.instanceID,
+	})
+}
+
+// recoverAbandonedRowsInStore is recoverAbandonedRows for instances without Redis.
+// It resets the rows leased to the instances whose heartbeat has expired, and then
+// removes those from the registry. Rows left leased to an instance that is no longer
+// registered, such as one that was shut down while processing them, are reset once
+// their lease expires.
+func (jm *JobManager) recoverAbandonedRowsInStore(ctx context.Context) (int, error) {
+	now := time.Now()
+	instanceIDs, err := jm.queries.GetDeadWorkers(ctx, pgtype.Timestamp{Time: now.Add(-heartbeatTTL), Valid: true})
+	if err != nil {
+		return 0, fmt.Errorf("failed to get dead workers: %w", err)
+	}
+
+	var totalRecovered int
+
+	for _, instanceID := range instanceIDs {
+		// Skip our own instance, in case our heartbeat is late
+		if instanceID == jm.instanceID {
+			continue
+		}
+
+		// The SQL guard (AND status = 'inprog') leaves the rows the instance
+		// finished alone, and makes concurrent recoveries of it harmless
+		recovered, err := jm.queries.ResetWorkerRowsToQueued(ctx, instanceID)
+		if err != nil {
+			jm.logger.Error(err).LogActivity("Failed to recover rows from dead instance", map[string]any{
+				"instanceID": instanceID,
+			})
+			continue
+		}
+		if recovered > 0 {
+			jm.logger.Info().LogActivity("Recovered rows from dead instance", map[string]any{
+				"instanceID": instanceID,
+				"rowCount":   recovered,
+			})
+		}
+		totalRecovered += int(recovered)
+
+		// Remove the dead worker from the registry after recovering its rows, not
+		// before, so that a failure in between is retried on the next cycle
+		if err := jm.queries.DeleteWorker(ctx, instanceID); err != nil {
+			jm.logger.Warn().LogActivity("Failed to remove dead worker from registry", map[string]any{
+				"instanceID": instanceID,
+				"error":      err.Error(),
+			})
+		}
+	}
+
+	expired, err := jm.queries.ResetExpiredLeasesToQueued(ctx, pgtype.Timestamp{Time: now, Valid: true})
+	if err != nil {
+		return totalRecovered, fmt.Errorf("failed to reset rows with expired leases: %w", err)
+	}
+	if expired > 0 {
+		jm.logger.Info().LogActivity("Recovered rows with expired leases", map[string]any{
+			"rowCount": expired,
+		})
+	}
+
+	return totalRecovered + int(expired), nil
+}
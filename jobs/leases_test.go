@@ -0,0 +1,170 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/remiges-tech/alya/jobs/objstore"
+	"github.com/remiges-tech/alya/jobs/pg/batchsqlc"
+	"github.com/remiges-tech/logharbour/logharbour"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newNoRedisTestJobManager returns a JobManager without Redis on store.
+func newNoRedisTestJobManager(store Store) *JobManager {
+	logger := logharbour.NewLogger(&logharbour.LoggerContext{}, "test", log.Writer())
+	return NewJobManagerWithBackends(store, nil, objstore.NewMemObjectStore(), logger, nil)
+}
+
+// submitLeasedRows submits a batch of n rows and marks them as picked up by worker,
+// with leases until leasedUntil. It returns the row IDs.
+func submitLeasedRows(t *testing.T, jm *JobManager, n int, worker string, leasedUntil time.Time) []int64 {
+	t.Helper()
+	ctx := context.Background()
+	input := make([]BatchInput_t, n)
+	for i := range input {
+		input[i] = BatchInput_t{Line: i + 1, Input: mustJSONstr(t, `{}`)}
+	}
+	batchID, err := jm.BatchSubmit("memapp", "memop", mustJSONstr(t, `{}`), input, false)
+	require.NoError(t, err)
+	rows, err := jm.queries.GetBatchRowsByBatchID(ctx, uuid.MustParse(batchID))
+	require.NoError(t, err)
+
+	rowIDs := make([]int64, len(rows))
+	for i, r := range rows {
+		rowIDs[i] = r.Rowid
+	}
+	require.NoError(t, jm.queries.UpdateBatchRowsStatusBulk(ctx, batchsqlc.UpdateBatchRowsStatusBulkParams{
+		RowIds: rowIDs,
+		Status: batchsqlc.StatusEnumInprog,
+	}))
+	require.NoError(t, jm.queries.LeaseBatchRows(ctx, batchsqlc.LeaseBatchRowsParams{
+		Worker:      worker,
+		LeasedUntil: pgtype.Timestamp{Time: leasedUntil, Valid: true},
+		RowIds:      rowIDs,
+	}))
+	return rowIDs
+}
+
+// rowStatuses returns the statuses of the rows, by row ID.
+func rowStatuses(t *testing.T, store *MemoryStore, rowIDs []int64) []batchsqlc.StatusEnum {
+	t.Helper()
+	statuses := make([]batchsqlc.StatusEnum, len(rowIDs))
+	for i, id := range rowIDs {
+		statuses[i] = store.data.rows[id].Status
+	}
+	return statuses
+}
+
+func TestNoRedis_BatchToDone(t *testing.T) {
+	store := NewMemoryStore()
+	jm := newNoRedisTestJobManager(store)
+	require.True(t, jm.leasesInStore())
+	require.NoError(t, jm.RegisterInitializer("memapp", &MockInitializer{}))
+	require.NoError(t, jm.RegisterProcessorBatchV2("memapp", "memop", &memTestProcessor{t: t}))
+
+	input := []BatchInput_t{
+		{Line: 1, Input: mustJSONstr(t, `{}`)},
+		{Line: 2, Input: mustJSONstr(t, `{}`)},
+	}
+	batchID, err := jm.BatchSubmit("memapp", "memop", mustJSONstr(t, `{}`), input, false)
+	require.NoError(t, err)
+
+	var status batchsqlc.StatusEnum
+	var nsuccess, nfailed int
+	for range 20 {
+		jm.RunOneIteration()
+		status, _, _, nsuccess, nfailed, _, err = jm.BatchDone(batchID)
+		require.NoError(t, err)
+		if status != batchsqlc.StatusEnumQueued && status != batchsqlc.StatusEnumInprog {
+			break
+		}
+	}
+	// Line 1 fails, which fails the batch
+	assert.Equal(t, batchsqlc.StatusEnumFailed, status)
+	assert.Equal(t, 1, nsuccess)
+	assert.Equal(t, 1, nfailed)
+
+	gotStatus, _, gotSuccess, gotFailed, _, err := jm.BatchStatus(batchID)
+	require.NoError(t, err)
+	assert.Equal(t, batchsqlc.StatusEnumFailed, gotStatus)
+	assert.Equal(t, 1, gotSuccess)
+	assert.Equal(t, 1, gotFailed)
+
+	// The leases end with the processing of the rows
+	rows, err := jm.queries.GetBatchRowsByBatchID(context.Background(), uuid.MustParse(batchID))
+	require.NoError(t, err)
+	for _, r := range rows {
+		assert.False(t, r.Worker.Valid, "row %d is still leased", r.Rowid)
+		assert.False(t, r.LeasedUntil.Valid)
+	}
+}
+
+func TestNoRedis_RecoverAbandonedRows(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	alive := newNoRedisTestJobManager(store)
+	recoverer := newNoRedisTestJobManager(store)
+	leasedUntil := time.Now().Add(workerRowsTTL)
+
+	// An instance whose heartbeat has expired, with rows still inprog
+	require.NoError(t, store.Queries().RecordWorkerHeartbeat(ctx, batchsqlc.RecordWorkerHeartbeatParams{
+		InstanceID: "dead-instance",
+		Heartbeat:  pgtype.Timestamp{Time: time.Now().Add(-2 * heartbeatTTL), Valid: true},
+	}))
+	deadRows := submitLeasedRows(t, recoverer, 2, "dead-instance", leasedUntil)
+
+	// A live instance, with rows of its own
+	require.NoError(t, alive.refreshHeartbeat(ctx))
+	aliveRows := submitLeasedRows(t, recoverer, 1, alive.instanceID, leasedUntil)
+
+	// Rows leased to an instance that is no longer registered, one with its lease expired
+	expiredRows := submitLeasedRows(t, recoverer, 1, "gone-instance", time.Now().Add(-time.Second))
+	unexpiredRows := submitLeasedRows(t, recoverer, 1, "gone-instance", leasedUntil)
+
+	recovered, err := recoverer.recoverAbandonedRows(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, recovered)
+
+	assert.Equal(t, []batchsqlc.StatusEnum{batchsqlc.StatusEnumQueued, batchsqlc.StatusEnumQueued}, rowStatuses(t, store, deadRows))
+	assert.Equal(t, []batchsqlc.StatusEnum{batchsqlc.StatusEnumQueued}, rowStatuses(t, store, expiredRows))
+	assert.Equal(t, []batchsqlc.StatusEnum{batchsqlc.StatusEnumInprog}, rowStatuses(t, store, aliveRows))
+	assert.Equal(t, []batchsqlc.StatusEnum{batchsqlc.StatusEnumInprog}, rowStatuses(t, store, unexpiredRows))
+	assert.False(t, store.data.rows[deadRows[0]].Worker.Valid)
+
+	// The dead instance is removed from the registry, the live one stays
+	assert.NotContains(t, store.data.workers, "dead-instance")
+	assert.Contains(t, store.data.workers, alive.instanceID)
+
+	// A second recovery finds nothing to do
+	recovered, err = recoverer.recoverAbandonedRows(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, recovered)
+}
+
+func TestNoRedis_HeartbeatAndShutdown(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	jm := newNoRedisTestJobManager(store)
+
+	require.NoError(t, jm.registerWorker(ctx))
+	require.NoError(t, jm.refreshHeartbeat(ctx))
+	require.Contains(t, store.data.workers, jm.instanceID)
+
+	rowIDs := submitLeasedRows(t, jm, 1, jm.instanceID, time.Now().Add(time.Second))
+	require.NoError(t, jm.renewRowLeases(ctx))
+	assert.True(t, store.data.rows[rowIDs[0]].LeasedUntil.Time.After(time.Now().Add(workerRowsTTL-time.Minute)))
+
+	require.NoError(t, jm.Shutdown(ctx))
+	assert.NotContains(t, store.data.workers, jm.instanceID)
+	// The row stays leased, to be recovered once its lease expires
+	assert.Equal(t, batchsqlc.StatusEnumInprog, store.data.rows[rowIDs[0]].Status)
+
+	require.NoError(t, jm.untrackRowProcessing(rowIDs[0]))
+	assert.False(t, store.data.rows[rowIDs[0]].Worker.Valid)
+}
@@ -0,0 +1,266 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/remiges-tech/alya/jobs/pg/batchsqlc"
+)
+
+const (
+	// processorSlotLease is how long an in-flight slot stays taken without being
+	// refreshed. Slots of live instances are refreshed by the heartbeat loop, so
+	// this only frees the slots of instances that died while processing.
+	processorSlotLease = heartbeatTTL
+
+	// processorSlotPollInterval is how often a row waiting for an in-flight
+	// slot checks whether one has been freed.
+	processorSlotPollInterval = 100 * time.Millisecond
+)
+
+var ErrInvalidProcessorLimits = errors.New("invalid processor limits")
+
+// ProcessorLimits throttles the processing of the rows of an (app, op) across all
+// JobManager instances sharing the same Redis.
+//
+// MaxInFlight caps the number of rows being processed at the same time.
+// RatePerSec caps the number of rows started per second, with Burst rows allowed to
+// start back to back after a quiet period (default 1). A zero value means no limit.
+type ProcessorLimits struct {
+	MaxInFlight int
+	RatePerSec  float64
+	Burst       int
+}
+
+// processorSlotScript takes an in-flight slot if fewer than the maximum are taken.
+// Slots are members of a sorted set scored by their lease expiry, so the slots of
+// dead instances drop out once their lease runs out.
+//
+// KEYS[1] slots key; ARGV: now (ms), lease expiry (ms), max in flight, member, lease (ms)
+var processorSlotScript = redis.NewScript(`
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', ARGV[1])
+if redis.call('ZCARD', KEYS[1]) < tonumber(ARGV[3]) then
+	redis.call('ZADD', KEYS[1], ARGV[2], ARGV[4])
+	redis.call('PEXPIRE', KEYS[1], ARGV[5])
+	return 1
+end
+return 0
+`)
+
+// processorRateScript takes a token from a token bucket. It returns 0 if a token was
+// taken, or else the number of milliseconds until the next token is available.
+//
+// KEYS[1] bucket key; ARGV: now (ms), tokens per ms, burst
+var processorRateScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local burst = tonumber(ARGV[3])
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(bucket[1])
+local ts = tonumber(bucket[2])
+if tokens == nil or ts == nil then
+	tokens = burst
+	ts = now
+end
+if now > ts then
+	tokens = math.min(burst, tokens + (now - ts) * rate)
+	ts = now
+end
+local wait = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+else
+	wait = math.ceil((1 - tokens) / rate)
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(ts))
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst / rate) + 1000)
+return wait
+`)
+
+// RegisterProcessorLimits sets the concurrency and rate limits for rows of the given
+// (app, op). It applies to both batch jobs and slow queries. The limits are enforced
+// through Redis before each row is handed to its processor; a row that has to wait
+// holds up the worker it is assigned to. Without a Redis client the limits are not
+// enforced.
+// The 'op' parameter is case-insensitive.
+func (jm *JobManager) RegisterProcessorLimits(app string, op string, limits ProcessorLimits) error {
+	if limits.MaxInFlight < 0 || limits.RatePerSec < 0 || limits.Burst < 0 {
+		return fmt.Errorf("%w: limits must not be negative", ErrInvalidProcessorLimits)
+	}
+	if limits.MaxInFlight == 0 && limits.RatePerSec == 0 {
+		return fmt.Errorf("%w: at least one of MaxInFlight and RatePerSec must be set", ErrInvalidProcessorLimits)
+	}
+	if limits.Burst == 0 {
+		limits.Burst = 1
+	}
+	if jm.redisClient == nil {
+		jm.logger.Warn().LogActivity("No Redis client, processor limits will not be enforced", map[string]any{
+			"app": app,
+			"op":  op,
+		})
+	}
+
+	op = strings.ToLower(op)
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+	jm.processorlimits[app+op] = limits
+	return nil
+}
+
+// waitForProcessorLimits blocks until the row may be processed under the limits of
+// its (app, op). The returned release function must be called once the row is done.
+// It returns an error if ctx is cancelled while waiting or Redis cannot be reached.
+func (jm *JobManager) waitForProcessorLimits(ctx context.Context, app, op string, rowID int64) (release func(), err error) {
+	jm.mu.RLock()
+	limits, ok := jm.processorlimits[app+op]
+	jm.mu.RUnlock()
+	release = func() {}
+	if !ok || jm.redisClient == nil {
+		return release, nil
+	}
+
+	if limits.MaxInFlight > 0 {
+		key := processorSlotsKey(app, op)
+		member := fmt.Sprintf("%s:%d", jm.instanceID, rowID)
+		for {
+			acquired, err := jm.tryAcquireProcessorSlot(ctx, key, member, limits.MaxInFlight)
+			if err != nil {
+				return nil, fmt.Errorf("failed to acquire in-flight slot for app %s op %s: %w", app, op, err)
+			}
+			if acquired {
+				break
+			}
+			if err := sleepContext(ctx, processorSlotPollInterval); err != nil {
+				return nil, err
+			}
+		}
+		release = func() { jm.releaseProcessorSlot(key, member) }
+	}
+
+	if limits.RatePerSec > 0 {
+		for {
+			wait, err := jm.takeRateToken(ctx, app, op, limits)
+			if err != nil {
+				release()
+				return nil, fmt.Errorf("failed to take rate token for app %s op %s: %w", app, op, err)
+			}
+			if wait == 0 {
+				break
+			}
+			if err := sleepContext(ctx, wait); err != nil {
+				release()
+				return nil, err
+			}
+		}
+	}
+	return release, nil
+}
+
+// tryAcquireProcessorSlot takes an in-flight slot for member if one is free.
+func (jm *JobManager) tryAcquireProcessorSlot(ctx context.Context, key, member string, maxInFlight int) (bool, error) {
+	now := time.Now()
+	acquired, err := processorSlotScript.Run(ctx, jm.redisClient, []string{key},
+		now.UnixMilli(), now.Add(processorSlotLease).UnixMilli(), maxInFlight, member, processorSlotLease.Milliseconds()).Int()
+	if err != nil {
+		return false, err
+	}
+	if acquired == 1 {
+		jm.slotsmu.Lock()
+		jm.heldslots[member] = key
+		jm.slotsmu.Unlock()
+	}
+	return acquired == 1, nil
+}
+
+// releaseProcessorSlot frees an in-flight slot. Like untrackRowProcessing, it uses
+// context.Background() so the slot is freed even during shutdown; if it still fails,
+// the slot is freed when its lease runs out.
+func (jm *JobManager) releaseProcessorSlot(key, member string) {
+	jm.slotsmu.Lock()
+	delete(jm.heldslots, member)
+	jm.slotsmu.Unlock()
+
+	if err := jm.redisClient.ZRem(context.Background(), key, member).Err(); err != nil {
+		jm.logger.Warn().LogActivity("Failed to release processor slot", map[string]any{
+			"key":    key,
+			"member": member,
+			"error":  err.Error(),
+		})
+	}
+}
+
+// refreshProcessorSlots extends the lease of the in-flight slots held by this instance.
+// It is called from the heartbeat loop.
+func (jm *JobManager) refreshProcessorSlots(ctx context.Context) {
+	jm.slotsmu.Lock()
+	held := make(map[string]string, len(jm.heldslots))
+	for member, key := range jm.heldslots {
+		held[member] = key
+	}
+	jm.slotsmu.Unlock()
+
+	expiry := float64(time.Now().Add(processorSlotLease).UnixMilli())
+	for member, key := range held {
+		err := jm.redisClient.ZAddXX(ctx, key, &redis.Z{Score: expiry, Member: member}).Err()
+		if err == nil {
+			err = jm.redisClient.PExpire(ctx, key, processorSlotLease).Err()
+		}
+		if err != nil {
+			jm.logger.Warn().LogActivity("Failed to refresh processor slot", map[string]any{
+				"key":    key,
+				"member": member,
+				"error":  err.Error(),
+			})
+		}
+	}
+}
+
+// takeRateToken takes a token from the (app, op) token bucket. It returns zero if a
+// token was taken, or else how long to wait before trying again.
+func (jm *JobManager) takeRateToken(ctx context.Context, app, op string, limits ProcessorLimits) (time.Duration, error) {
+	waitMs, err := processorRateScript.Run(ctx, jm.redisClient, []string{processorRateKey(app, op)},
+		time.Now().UnixMilli(), limits.RatePerSec/1000, limits.Burst).Int64()
+	if err != nil {
+		return 0, err
+	}
+	if waitMs < 0 {
+		waitMs = 0
+	}
+	return time.Duration(waitMs) * time.Millisecond, nil
+}
+
+// requeueRow puts a row whose processing did not complete back in the queue, e.g.
+// because shutdown began while it was waiting for its processor limits or while its
+// processor ran. If that fails the row stays tracked, so that crash recovery resets it later.
+func (jm *JobManager) requeueRow(row batchsqlc.FetchBlockOfRowsRow) {
+	if err := jm.resetRowsToQueued(context.Background(), []int64{row.Rowid}); err != nil {
+		jm.logger.Error(err).LogActivity("Failed to requeue row", map[string]any{
+			"rowId":   row.Rowid,
+			"batchId": row.Batch.String(),
+		})
+		return
+	}
+	jm.trackRowRequeued(row.Batch)
+	if err := jm.untrackRowProcessing(row.Rowid); err != nil {
+		jm.logger.Warn().LogActivity("Failed to untrack row from Redis", map[string]any{
+			"rowId": row.Rowid,
+			"error": err.Error(),
+		})
+	}
+}
+
+// sleepContext sleeps for d or until ctx is cancelled, whichever comes first.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,177 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/remiges-tech/logharbour/logharbour"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newLimitsTestJobManager(t *testing.T) (*JobManager, *redis.Client) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(mr.Close)
+
+	redisClient := redis.NewClient(&redis.Options{
+		Addr: mr.Addr(),
+	})
+	t.Cleanup(func() { redisClient.Close() })
+
+	loggerCtx := &logharbour.LoggerContext{}
+	logger := logharbour.NewLogger(loggerCtx, "test", log.Writer())
+	return NewJobManager(nil, redisClient, nil, logger, nil), redisClient
+}
+
+func TestRegisterProcessorLimits_Validation(t *testing.T) {
+	jm, _ := newLimitsTestJobManager(t)
+
+	tests := []struct {
+		name   string
+		limits ProcessorLimits
+	}{
+		{"no limits", ProcessorLimits{}},
+		{"negative in flight", ProcessorLimits{MaxInFlight: -1}},
+		{"negative rate", ProcessorLimits{RatePerSec: -5}},
+		{"negative burst", ProcessorLimits{RatePerSec: 5, Burst: -1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := jm.RegisterProcessorLimits("banking", "transfer", tt.limits)
+			assert.ErrorIs(t, err, ErrInvalidProcessorLimits)
+		})
+	}
+
+	require.NoError(t, jm.RegisterProcessorLimits("banking", "Transfer", ProcessorLimits{RatePerSec: 5}))
+	assert.Equal(t, 1, jm.processorlimits["bankingtransfer"].Burst)
+}
+
+func TestWaitForProcessorLimits_NoLimits(t *testing.T) {
+	jm, redisClient := newLimitsTestJobManager(t)
+	ctx := context.Background()
+
+	release, err := jm.waitForProcessorLimits(ctx, "banking", "transfer", 1)
+	require.NoError(t, err)
+	release()
+
+	keys, err := redisClient.Keys(ctx, "*").Result()
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+}
+
+func TestWaitForProcessorLimits_MaxInFlight(t *testing.T) {
+	jm1, redisClient := newLimitsTestJobManager(t)
+	loggerCtx := &logharbour.LoggerContext{}
+	jm2 := NewJobManager(nil, redisClient, nil, logharbour.NewLogger(loggerCtx, "test", log.Writer()), nil)
+	limits := ProcessorLimits{MaxInFlight: 2}
+	require.NoError(t, jm1.RegisterProcessorLimits("banking", "transfer", limits))
+	require.NoError(t, jm2.RegisterProcessorLimits("banking", "transfer", limits))
+	ctx := context.Background()
+
+	release1, err := jm1.waitForProcessorLimits(ctx, "banking", "transfer", 1)
+	require.NoError(t, err)
+	release2, err := jm2.waitForProcessorLimits(ctx, "banking", "transfer", 2)
+	require.NoError(t, err)
+	assert.Len(t, jm1.heldslots, 1)
+
+	// both slots are taken, across instances
+	waitCtx, cancel := context.WithTimeout(ctx, 300*time.Millisecond)
+	defer cancel()
+	_, err = jm1.waitForProcessorLimits(waitCtx, "banking", "transfer", 3)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// a released slot can be taken again
+	release2()
+	release3, err := jm1.waitForProcessorLimits(ctx, "banking", "transfer", 3)
+	require.NoError(t, err)
+
+	n, err := redisClient.ZCard(ctx, processorSlotsKey("banking", "transfer")).Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+
+	release1()
+	release3()
+	assert.Empty(t, jm1.heldslots)
+}
+
+func TestWaitForProcessorLimits_ExpiredSlot(t *testing.T) {
+	jm, redisClient := newLimitsTestJobManager(t)
+	require.NoError(t, jm.RegisterProcessorLimits("banking", "transfer", ProcessorLimits{MaxInFlight: 1}))
+	ctx := context.Background()
+
+	// a slot left behind by a dead instance whose lease ran out
+	key := processorSlotsKey("banking", "transfer")
+	expired := float64(time.Now().Add(-time.Second).UnixMilli())
+	require.NoError(t, redisClient.ZAdd(ctx, key, &redis.Z{Score: expired, Member: "dead:1"}).Err())
+
+	release, err := jm.waitForProcessorLimits(ctx, "banking", "transfer", 7)
+	require.NoError(t, err)
+	defer release()
+
+	members, err := redisClient.ZRange(ctx, key, 0, -1).Result()
+	require.NoError(t, err)
+	assert.Equal(t, []string{jm.instanceID + ":7"}, members)
+}
+
+func TestRefreshProcessorSlots(t *testing.T) {
+	jm, redisClient := newLimitsTestJobManager(t)
+	require.NoError(t, jm.RegisterProcessorLimits("banking", "transfer", ProcessorLimits{MaxInFlight: 1}))
+	ctx := context.Background()
+
+	release, err := jm.waitForProcessorLimits(ctx, "banking", "transfer", 1)
+	require.NoError(t, err)
+	defer release()
+
+	key := processorSlotsKey("banking", "transfer")
+	member := jm.instanceID + ":1"
+	require.NoError(t, redisClient.ZAdd(ctx, key, &redis.Z{Score: 0, Member: member}).Err())
+
+	jm.refreshProcessorSlots(ctx)
+	score, err := redisClient.ZScore(ctx, key, member).Result()
+	require.NoError(t, err)
+	assert.Greater(t, score, float64(time.Now().UnixMilli()))
+}
+
+func TestTakeRateToken(t *testing.T) {
+	jm, _ := newLimitsTestJobManager(t)
+	limits := ProcessorLimits{RatePerSec: 10, Burst: 2}
+	ctx := context.Background()
+
+	// the burst is available straight away
+	for i := 0; i < 2; i++ {
+		wait, err := jm.takeRateToken(ctx, "banking", "transfer", limits)
+		require.NoError(t, err)
+		assert.Zero(t, wait)
+	}
+
+	// then one token every 100ms
+	wait, err := jm.takeRateToken(ctx, "banking", "transfer", limits)
+	require.NoError(t, err)
+	assert.Greater(t, wait, time.Duration(0))
+	assert.LessOrEqual(t, wait, 100*time.Millisecond)
+
+	// other (app, op) combinations have their own bucket
+	wait, err = jm.takeRateToken(ctx, "banking", "report", limits)
+	require.NoError(t, err)
+	assert.Zero(t, wait)
+}
+
+func TestWaitForProcessorLimits_Rate(t *testing.T) {
+	jm, _ := newLimitsTestJobManager(t)
+	require.NoError(t, jm.RegisterProcessorLimits("banking", "transfer", ProcessorLimits{RatePerSec: 20}))
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := int64(0); i < 3; i++ {
+		release, err := jm.waitForProcessorLimits(ctx, "banking", "transfer", i)
+		require.NoError(t, err)
+		release()
+	}
+	// the first row starts at once, the other two 50ms apart
+	assert.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond)
+}
@@ -0,0 +1,229 @@
+package jobs
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/remiges-tech/alya/jobs/pg/batchsqlc"
+)
+
+const (
+	ALYA_LIST_PAGESIZE_DEFAULT = 100
+	ALYA_LIST_PAGESIZE_MAX     = 1000
+)
+
+var (
+	// ErrInvalidListParams is returned by BatchList and SlowQueryList when the
+	// filter parameters are missing or out of range.
+	ErrInvalidListParams = errors.New("invalid list parameters")
+	// ErrInvalidPageToken is returned when the page token passed in is not one
+	// handed out by a previous call.
+	ErrInvalidPageToken = errors.New("invalid page token")
+)
+
+// ListParams_t selects the records returned by BatchList and SlowQueryList.
+//
+// App and Age are mandatory; Age is in days and must be greater than 0. Op
+// and Status are optional filters. PageSize defaults to
+// ALYA_LIST_PAGESIZE_DEFAULT and is capped at ALYA_LIST_PAGESIZE_MAX.
+// PageToken is empty for the first page and is otherwise the token returned
+// by the previous call.
+type ListParams_t struct {
+	App       string
+	Op        string
+	Status    batchsqlc.StatusEnum
+	Age       int
+	PageSize  int
+	PageToken string
+}
+
+// SlowQueryList returns the slow queries of an app submitted within the last
+// params.Age days, newest first. If more records match than fit in one page,
+// nextPageToken is non-empty and can be passed back in params.PageToken to
+// fetch the next page.
+func (jm *JobManager) SlowQueryList(params ListParams_t) (sqlist []SlowQueryDetails_t, nextPageToken string, err error) {
+	arg, pageSize, err := listQueryParams(params)
+	if err != nil {
+		return nil, "", err
+	}
+
+	rows, err := jm.queries.ListSlowQueries(context.Background(), batchsqlc.ListSlowQueriesParams(arg))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list slow queries: %w", err)
+	}
+
+	if len(rows) > pageSize {
+		rows = rows[:pageSize]
+		last := rows[pageSize-1]
+		nextPageToken = encodePageToken(last.Reqat.Time, last.ID)
+	}
+
+	sqlist = make([]SlowQueryDetails_t, 0, len(rows))
+	for _, row := range rows {
+		outputFiles, err := unmarshalOutputFiles(row.Outputfiles)
+		if err != nil {
+			return nil, "", fmt.Errorf("slow query %s: %w", row.ID, err)
+		}
+		sqlist = append(sqlist, SlowQueryDetails_t{
+			ID:          row.ID.String(),
+			App:         row.App,
+			Op:          row.Op,
+			InputFile:   row.Inputfile.String,
+			Status:      row.Status,
+			ReqAt:       row.Reqat.Time,
+			DoneAt:      row.Doneat.Time,
+			OutputFiles: outputFiles,
+		})
+	}
+	return sqlist, nextPageToken, nil
+}
+
+// BatchList returns the batches of an app submitted within the last
+// params.Age days, newest first, along with the number of rows in each batch.
+// Pagination works the same way as in SlowQueryList.
+func (jm *JobManager) BatchList(params ListParams_t) (batchlist []BatchDetails_t, nextPageToken string, err error) {
+	arg, pageSize, err := listQueryParams(params)
+	if err != nil {
+		return nil, "", err
+	}
+
+	rows, err := jm.queries.ListBatches(context.Background(), arg)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list batches: %w", err)
+	}
+
+	if len(rows) > pageSize {
+		rows = rows[:pageSize]
+		last := rows[pageSize-1]
+		nextPageToken = encodePageToken(last.Reqat.Time, last.ID)
+	}
+
+	batchlist = make([]BatchDetails_t, 0, len(rows))
+	for _, row := range rows {
+		outputFiles, err := unmarshalOutputFiles(row.Outputfiles)
+		if err != nil {
+			return nil, "", fmt.Errorf("batch %s: %w", row.ID, err)
+		}
+		outputTypes, err := unmarshalOutputFiles(row.Outputtypes)
+		if err != nil {
+			return nil, "", fmt.Errorf("batch %s: %w", row.ID, err)
+		}
+		batchlist = append(batchlist, BatchDetails_t{
+			ID:              row.ID.String(),
+			App:             row.App,
+			Op:              row.Op,
+			Context:         JSONstr{value: string(row.Context), valid: true},
+			InputFile:       row.Inputfile.String,
+			Status:          row.Status,
+			ReqAt:           row.Reqat.Time,
+			DoneAt:          row.Doneat.Time,
+			OutputFiles:     outputFiles,
+			OutputFileTypes: outputTypes,
+			NRows:           int(row.Nrows),
+			NSuccess:        int(row.Nsuccess.Int32),
+			NFailed:         int(row.Nfailed.Int32),
+			NAborted:        int(row.Naborted.Int32),
+		})
+	}
+	return batchlist, nextPageToken, nil
+}
+
+// listQueryParams validates params and converts them to query parameters.
+// The query is asked for one row more than the page size so that the caller
+// can tell whether there is a next page.
+func listQueryParams(params ListParams_t) (arg batchsqlc.ListBatchesParams, pageSize int, err error) {
+	if params.App == "" {
+		return arg, 0, fmt.Errorf("%w: app is required", ErrInvalidListParams)
+	}
+	if params.Age <= 0 {
+		return arg, 0, fmt.Errorf("%w: age must be greater than 0, got %d", ErrInvalidListParams, params.Age)
+	}
+	if params.PageSize < 0 {
+		return arg, 0, fmt.Errorf("%w: page size must not be negative, got %d", ErrInvalidListParams, params.PageSize)
+	}
+
+	pageSize = params.PageSize
+	if pageSize == 0 {
+		pageSize = ALYA_LIST_PAGESIZE_DEFAULT
+	}
+	if pageSize > ALYA_LIST_PAGESIZE_MAX {
+		pageSize = ALYA_LIST_PAGESIZE_MAX
+	}
+
+	arg = batchsqlc.ListBatchesParams{
+		App:      params.App,
+		Since:    pgtype.Timestamp{Time: time.Now().AddDate(0, 0, -params.Age), Valid: true},
+		PageSize: int32(pageSize + 1),
+	}
+	if params.Op != "" {
+		// op is stored in lowercase, see BatchSubmit and SlowQuerySubmit
+		arg.Op = pgtype.Text{String: strings.ToLower(params.Op), Valid: true}
+	}
+	if params.Status != "" {
+		switch params.Status {
+		case batchsqlc.StatusEnumQueued, batchsqlc.StatusEnumInprog, batchsqlc.StatusEnumSuccess,
+			batchsqlc.StatusEnumFailed, batchsqlc.StatusEnumAborted, batchsqlc.StatusEnumWait,
+			batchsqlc.StatusEnumPaused:
+		default:
+			return arg, 0, fmt.Errorf("%w: unknown status %q", ErrInvalidListParams, params.Status)
+		}
+		arg.Status = batchsqlc.NullStatusEnum{StatusEnum: params.Status, Valid: true}
+	}
+	if params.PageToken != "" {
+		reqat, id, err := decodePageToken(params.PageToken)
+		if err != nil {
+			return arg, 0, err
+		}
+		arg.AfterReqat = pgtype.Timestamp{Time: reqat, Valid: true}
+		arg.AfterID = pgtype.UUID{Bytes: id, Valid: true}
+	}
+	return arg, pageSize, nil
+}
+
+// encodePageToken builds an opaque page token from the sort key of the last
+// record of a page.
+func encodePageToken(reqat time.Time, id uuid.UUID) string {
+	raw := strconv.FormatInt(reqat.UnixMicro(), 10) + "." + id.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodePageToken is the reverse of encodePageToken.
+// reqat is a timestamp without time zone, so it is returned in UTC like pgx
+// does when reading such columns.
+func decodePageToken(token string) (reqat time.Time, id uuid.UUID, err error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return reqat, id, ErrInvalidPageToken
+	}
+	micros, idStr, found := strings.Cut(string(raw), ".")
+	if !found {
+		return reqat, id, ErrInvalidPageToken
+	}
+	usec, err := strconv.ParseInt(micros, 10, 64)
+	if err != nil {
+		return reqat, id, ErrInvalidPageToken
+	}
+	id, err = uuid.Parse(idStr)
+	if err != nil {
+		return reqat, id, ErrInvalidPageToken
+	}
+	return time.UnixMicro(usec).UTC(), id, nil
+}
+
+func unmarshalOutputFiles(data []byte) (map[string]string, error) {
+	outputFiles := make(map[string]string)
+	if data != nil {
+		if err := json.Unmarshal(data, &outputFiles); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal output files: %w", err)
+		}
+	}
+	return outputFiles, nil
+}
@@ -0,0 +1,153 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/remiges-tech/alya/jobs/pg/batchsqlc"
+	"github.com/remiges-tech/alya/jobs/pg/batchsqlc/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchList_InvalidParams(t *testing.T) {
+	jm := newTestJobManager(&mocks.QuerierMock{}, nil)
+
+	tests := []struct {
+		name   string
+		params ListParams_t
+		err    error
+	}{
+		{"missing app", ListParams_t{Age: 1}, ErrInvalidListParams},
+		{"zero age", ListParams_t{App: "banking"}, ErrInvalidListParams},
+		{"negative page size", ListParams_t{App: "banking", Age: 1, PageSize: -1}, ErrInvalidListParams},
+		{"unknown status", ListParams_t{App: "banking", Age: 1, Status: "done"}, ErrInvalidListParams},
+		{"garbage page token", ListParams_t{App: "banking", Age: 1, PageToken: "not-a-token"}, ErrInvalidPageToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := jm.BatchList(tt.params)
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+}
+
+func TestBatchList_Pagination(t *testing.T) {
+	base := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	rows := make([]batchsqlc.ListBatchesRow, 5)
+	for i := range rows {
+		rows[i] = batchsqlc.ListBatchesRow{
+			ID:          uuid.New(),
+			App:         "banking",
+			Op:          "process_transactions",
+			Context:     []byte(`{}`),
+			Status:      batchsqlc.StatusEnumSuccess,
+			Reqat:       pgtype.Timestamp{Time: base.Add(-time.Duration(i) * time.Minute), Valid: true},
+			Outputfiles: []byte(`{"summary.txt":"obj-1"}`),
+			Nsuccess:    pgtype.Int4{Int32: 3, Valid: true},
+			Nrows:       3,
+		}
+	}
+
+	mockQuerier := &mocks.QuerierMock{}
+	mockQuerier.ListBatchesFunc = func(ctx context.Context, arg batchsqlc.ListBatchesParams) ([]batchsqlc.ListBatchesRow, error) {
+		// emulate the keyset condition of the query
+		var page []batchsqlc.ListBatchesRow
+		for _, r := range rows {
+			if arg.AfterReqat.Valid && !r.Reqat.Time.Before(arg.AfterReqat.Time) {
+				continue
+			}
+			page = append(page, r)
+			if len(page) == int(arg.PageSize) {
+				break
+			}
+		}
+		return page, nil
+	}
+	jm := newTestJobManager(mockQuerier, nil)
+
+	first, token, err := jm.BatchList(ListParams_t{App: "banking", Op: "Process_Transactions", Age: 7, PageSize: 2})
+	require.NoError(t, err)
+	require.Len(t, first, 2)
+	assert.NotEmpty(t, token)
+	assert.Equal(t, rows[0].ID.String(), first[0].ID)
+	assert.Equal(t, 3, first[0].NRows)
+	assert.Equal(t, 3, first[0].NSuccess)
+	assert.Equal(t, map[string]string{"summary.txt": "obj-1"}, first[0].OutputFiles)
+
+	call := mockQuerier.ListBatchesCalls()[0].Arg
+	assert.Equal(t, "banking", call.App)
+	assert.Equal(t, pgtype.Text{String: "process_transactions", Valid: true}, call.Op)
+	assert.False(t, call.Status.Valid)
+	assert.Equal(t, int32(3), call.PageSize)
+	assert.False(t, call.AfterReqat.Valid)
+
+	second, token, err := jm.BatchList(ListParams_t{App: "banking", Age: 7, PageSize: 2, PageToken: token})
+	require.NoError(t, err)
+	require.Len(t, second, 2)
+	assert.Equal(t, rows[2].ID.String(), second[0].ID)
+
+	call = mockQuerier.ListBatchesCalls()[1].Arg
+	assert.Equal(t, rows[1].Reqat.Time, call.AfterReqat.Time)
+	assert.Equal(t, pgtype.UUID{Bytes: rows[1].ID, Valid: true}, call.AfterID)
+
+	third, token, err := jm.BatchList(ListParams_t{App: "banking", Age: 7, PageSize: 2, PageToken: token})
+	require.NoError(t, err)
+	require.Len(t, third, 1)
+	assert.Empty(t, token)
+}
+
+func TestSlowQueryList(t *testing.T) {
+	reqat := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	id := uuid.New()
+
+	mockQuerier := &mocks.QuerierMock{}
+	mockQuerier.ListSlowQueriesFunc = func(ctx context.Context, arg batchsqlc.ListSlowQueriesParams) ([]batchsqlc.ListSlowQueriesRow, error) {
+		assert.Equal(t, batchsqlc.NullStatusEnum{StatusEnum: batchsqlc.StatusEnumQueued, Valid: true}, arg.Status)
+		assert.Equal(t, int32(ALYA_LIST_PAGESIZE_DEFAULT+1), arg.PageSize)
+		assert.WithinDuration(t, time.Now().AddDate(0, 0, -2), arg.Since.Time, time.Minute)
+		return []batchsqlc.ListSlowQueriesRow{{
+			ID:     id,
+			App:    "banking",
+			Op:     "generate_statement",
+			Status: batchsqlc.StatusEnumQueued,
+			Reqat:  pgtype.Timestamp{Time: reqat, Valid: true},
+		}}, nil
+	}
+	jm := newTestJobManager(mockQuerier, nil)
+
+	sqlist, token, err := jm.SlowQueryList(ListParams_t{App: "banking", Status: batchsqlc.StatusEnumQueued, Age: 2})
+	require.NoError(t, err)
+	assert.Empty(t, token)
+	require.Len(t, sqlist, 1)
+	assert.Equal(t, id.String(), sqlist[0].ID)
+	assert.Equal(t, reqat, sqlist[0].ReqAt)
+	assert.True(t, sqlist[0].DoneAt.IsZero())
+	assert.Empty(t, sqlist[0].OutputFiles)
+}
+
+func TestSlowQueryList_DBError(t *testing.T) {
+	dbErr := errors.New("connection refused")
+	mockQuerier := &mocks.QuerierMock{}
+	mockQuerier.ListSlowQueriesFunc = func(ctx context.Context, arg batchsqlc.ListSlowQueriesParams) ([]batchsqlc.ListSlowQueriesRow, error) {
+		return nil, dbErr
+	}
+	jm := newTestJobManager(mockQuerier, nil)
+
+	_, _, err := jm.SlowQueryList(ListParams_t{App: "banking", Age: 1})
+	assert.ErrorIs(t, err, dbErr)
+}
+
+func TestPageTokenRoundTrip(t *testing.T) {
+	reqat := time.Date(2024, 3, 1, 10, 30, 15, 123456000, time.UTC)
+	id := uuid.New()
+
+	gotReqat, gotID, err := decodePageToken(encodePageToken(reqat, id))
+	require.NoError(t, err)
+	assert.Equal(t, reqat, gotReqat)
+	assert.Equal(t, id, gotID)
+}
@@ -0,0 +1,89 @@
+package jobs
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// memoryCacheSweepNSets is the number of sets after which a MemoryCache drops its
+// expired keys. Expired keys are missing for Get right away.
+const memoryCacheSweepNSets = 1000
+
+// MemoryCache is a StatusCache that lives in memory. It is safe for concurrent use by
+// the JobManagers of one process.
+type MemoryCache struct {
+	mu      sync.Mutex
+	entries map[string]memoryCacheEntry
+	nsets   int // sets since the last sweep
+}
+
+// memoryCacheEntry is a cached value, which expires at expiresAt unless it is zero.
+type memoryCacheEntry struct {
+	value     string
+	expiresAt time.Time
+}
+
+// NewMemoryCache returns an empty MemoryCache, to use as the status cache of
+// NewJobManagerWithBackends for tests and single-node tools.
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{entries: make(map[string]memoryCacheEntry)}
+}
+
+// Get returns the value of key, or ErrStatusCacheMiss if it is not cached or has expired.
+func (c *MemoryCache) Get(ctx context.Context, key string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok || e.expired(time.Now()) {
+		return "", ErrStatusCacheMiss
+	}
+	return e.value, nil
+}
+
+// Set caches value as the value of key for expiry, or for good if expiry is 0.
+func (c *MemoryCache) Set(ctx context.Context, key, value string, expiry time.Duration) error {
+	return c.SetMulti(ctx, map[string]string{key: value}, expiry)
+}
+
+// SetMulti caches all the given values for expiry.
+func (c *MemoryCache) SetMulti(ctx context.Context, values map[string]string, expiry time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	var expiresAt time.Time
+	if expiry > 0 {
+		expiresAt = now.Add(expiry)
+	}
+	for key, value := range values {
+		c.entries[key] = memoryCacheEntry{value: value, expiresAt: expiresAt}
+	}
+
+	c.nsets += len(values)
+	if c.nsets >= memoryCacheSweepNSets {
+		for key, e := range c.entries {
+			if e.expired(now) {
+				delete(c.entries, key)
+			}
+		}
+		c.nsets = 0
+	}
+	return nil
+}
+
+// Del drops keys.
+func (c *MemoryCache) Del(ctx context.Context, keys ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, key := range keys {
+		delete(c.entries, key)
+	}
+	return nil
+}
+
+func (e memoryCacheEntry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && !now.Before(e.expiresAt)
+}
@@ -0,0 +1,196 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/remiges-tech/alya/jobs/pg/batchsqlc"
+)
+
+var ErrMemStoreConstraint = errors.New("memory store constraint violated")
+
+// MemoryStore is a Store that keeps everything in memory, for tests and single-node
+// tools: it is lost when the process ends, and can't be shared between processes.
+// It holds the same tables as the PostgreSQL store and its queries behave the same,
+// apart from the partition maintenance ones, which fail.
+//
+// Transactions are serializable: one runs at a time, on a copy of the data that
+// replaces it on Commit, while queries outside them wait. Row locks are thus never
+// contended, and TryAdvisoryLockBatch always succeeds.
+type MemoryStore struct {
+	sem        chan struct{} // Held by the running transaction or query
+	data       *memData
+	listenmu   sync.Mutex // Protects listeners and nextlisten
+	listeners  map[int]func(app string)
+	nextlisten int
+}
+
+// memData holds the tables of a MemoryStore. Rows are stored by value and their
+// slices are never written to once stored, so a shallow copy of the maps and
+// slices is a snapshot.
+type memData struct {
+	batches         map[uuid.UUID]batchsqlc.Batch
+	rows            map[int64]batchsqlc.Batchrow
+	files           []batchsqlc.BatchFile
+	schedules       map[string]batchsqlc.BatchSchedule
+	webhooks        map[int64]batchsqlc.WebhookDelivery
+	history         []batchsqlc.BatchrowHistory
+	dependencies    []batchsqlc.BatchDependency
+	idempotencyKeys map[memIdempotencyKey]batchsqlc.BatchIdempotencyKey
+	workers         map[string]batchsqlc.Worker
+
+	// Last values of the identity columns
+	lastRowID, lastFileID, lastWebhookID, lastHistoryID int64
+}
+
+// memIdempotencyKey is the primary key of batch_idempotency_keys.
+type memIdempotencyKey struct {
+	app, key string
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		sem: make(chan struct{}, 1),
+		data: &memData{
+			batches:         make(map[uuid.UUID]batchsqlc.Batch),
+			rows:            make(map[int64]batchsqlc.Batchrow),
+			schedules:       make(map[string]batchsqlc.BatchSchedule),
+			webhooks:        make(map[int64]batchsqlc.WebhookDelivery),
+			idempotencyKeys: make(map[memIdempotencyKey]batchsqlc.BatchIdempotencyKey),
+			workers:         make(map[string]batchsqlc.Worker),
+		},
+		listeners: make(map[int]func(app string)),
+	}
+}
+
+func (d *memData) clone() *memData {
+	c := *d
+	c.batches = maps.Clone(d.batches)
+	c.rows = maps.Clone(d.rows)
+	c.files = slices.Clone(d.files)
+	c.schedules = maps.Clone(d.schedules)
+	c.webhooks = maps.Clone(d.webhooks)
+	c.history = slices.Clone(d.history)
+	c.dependencies = slices.Clone(d.dependencies)
+	c.idempotencyKeys = maps.Clone(d.idempotencyKeys)
+	c.workers = maps.Clone(d.workers)
+	return &c
+}
+
+// checkDeferred checks the constraints that PostgreSQL checks at commit.
+func (d *memData) checkDeferred() error {
+	for _, k := range d.idempotencyKeys {
+		if _, ok := d.batches[k.Batch]; !ok {
+			return fmt.Errorf("%w: idempotency key %q of app %s is for batch %s, which does not exist", ErrMemStoreConstraint, k.Key, k.App, k.Batch)
+		}
+	}
+	return nil
+}
+
+// lock waits for the running transaction or query to end.
+func (s *MemoryStore) lock(ctx context.Context) error {
+	select {
+	case s.sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *MemoryStore) unlock() {
+	<-s.sem
+}
+
+// Queries returns queries that each run as a transaction of their own.
+func (s *MemoryStore) Queries() batchsqlc.Querier {
+	return &memQueries{store: s}
+}
+
+// Begin waits for the running transaction, if any, to end, and starts one.
+func (s *MemoryStore) Begin(ctx context.Context) (StoreTx, error) {
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	return &memTx{store: s, data: s.data.clone()}, nil
+}
+
+// Listen calls notify for every NotifyJobsQueued as its transaction commits, until
+// ctx is cancelled.
+func (s *MemoryStore) Listen(ctx context.Context, notify func(app string)) error {
+	s.listenmu.Lock()
+	id := s.nextlisten
+	s.nextlisten++
+	s.listeners[id] = notify
+	s.listenmu.Unlock()
+
+	<-ctx.Done()
+
+	s.listenmu.Lock()
+	delete(s.listeners, id)
+	s.listenmu.Unlock()
+	return ctx.Err()
+}
+
+// notify calls the listeners for the apps whose jobs were queued.
+func (s *MemoryStore) notify(apps []string) {
+	s.listenmu.Lock()
+	defer s.listenmu.Unlock()
+	for _, app := range apps {
+		for _, listener := range s.listeners {
+			listener(app)
+		}
+	}
+}
+
+// memTx is a transaction of a MemoryStore. Like pgx.Tx, it must not be used by more
+// than one goroutine at a time.
+type memTx struct {
+	store    *MemoryStore
+	data     *memData
+	notified []string // Apps of the NotifyJobsQueued calls, announced on Commit
+	closed   bool
+}
+
+func (t *memTx) Queries() batchsqlc.Querier {
+	return &memQueries{store: t.store, tx: t}
+}
+
+// Commit makes the data of the transaction that of the store, if it meets the
+// deferred constraints, and announces the jobs it queued.
+func (t *memTx) Commit(ctx context.Context) error {
+	if t.closed {
+		return pgx.ErrTxClosed
+	}
+	t.closed = true
+	if err := t.data.checkDeferred(); err != nil {
+		t.store.unlock()
+		return err
+	}
+	t.store.data = t.data
+	t.store.unlock()
+	t.store.notify(t.notified)
+	return nil
+}
+
+// Rollback drops the data of the transaction.
+func (t *memTx) Rollback(ctx context.Context) error {
+	if t.closed {
+		return pgx.ErrTxClosed
+	}
+	t.closed = true
+	t.store.unlock()
+	return nil
+}
+
+// Make sure MemoryStore and PgStore implement the Store interface
+var (
+	_ Store = (*MemoryStore)(nil)
+	_ Store = (*PgStore)(nil)
+)
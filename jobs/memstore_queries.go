@@ -0,0 +1,1932 @@
+package jobs
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/remiges-tech/alya/jobs/pg/batchsqlc"
+)
+
+var errMemStoreNotPartitioned = errors.New("batchrows of the memory store is not partitioned")
+
+// memQueries runs the queries of batch.sql on the data of a MemoryStore, in a
+// transaction or, if tx is nil, each in one of its own.
+type memQueries struct {
+	store *MemoryStore
+	tx    *memTx
+}
+
+// Make sure memQueries implements the batchsqlc.Querier interface
+var _ batchsqlc.Querier = (*memQueries)(nil)
+
+// begin returns the data a query runs on, and the function to call when it is done.
+// Queries check their arguments before they change anything, so that one that fails
+// leaves the data as it was.
+func (q *memQueries) begin(ctx context.Context) (*memData, func(), error) {
+	if q.tx != nil {
+		if q.tx.closed {
+			return nil, nil, pgx.ErrTxClosed
+		}
+		return q.tx.data, func() {}, nil
+	}
+	if err := q.store.lock(ctx); err != nil {
+		return nil, nil, err
+	}
+	return q.store.data, q.store.unlock, nil
+}
+
+func memNow() pgtype.Timestamp {
+	return pgtype.Timestamp{Time: time.Now(), Valid: true}
+}
+
+// tsBefore is a < b in SQL, which is false if either is NULL.
+func tsBefore(a, b pgtype.Timestamp) bool {
+	return a.Valid && b.Valid && a.Time.Before(b.Time)
+}
+
+// tsAtMost is a <= b in SQL.
+func tsAtMost(a, b pgtype.Timestamp) bool {
+	return a.Valid && b.Valid && !a.Time.After(b.Time)
+}
+
+// tsEqual is a = b in SQL.
+func tsEqual(a, b pgtype.Timestamp) bool {
+	return a.Valid && b.Valid && a.Time.Equal(b.Time)
+}
+
+func compareUUID(a, b uuid.UUID) int {
+	return bytes.Compare(a[:], b[:])
+}
+
+func isPendingStatus(status batchsqlc.StatusEnum) bool {
+	return status == batchsqlc.StatusEnumQueued || status == batchsqlc.StatusEnumInprog
+}
+
+func isDoneStatus(status batchsqlc.StatusEnum) bool {
+	return status == batchsqlc.StatusEnumSuccess || status == batchsqlc.StatusEnumFailed || status == batchsqlc.StatusEnumAborted
+}
+
+// jsonb checks a value for a JSONB column and copies it; nil stands for NULL.
+func jsonb(column string, value []byte) ([]byte, error) {
+	if value == nil {
+		return nil, nil
+	}
+	if !json.Valid(value) {
+		return nil, fmt.Errorf("%w: invalid JSON for %s", ErrMemStoreConstraint, column)
+	}
+	return bytes.Clone(value), nil
+}
+
+// jsonbNotNull is jsonb for a NOT NULL column.
+func jsonbNotNull(column string, value []byte) ([]byte, error) {
+	if value == nil {
+		return nil, fmt.Errorf("%w: %s is NULL", ErrMemStoreConstraint, column)
+	}
+	return jsonb(column, value)
+}
+
+// addInt4 is COALESCE(a, 0) + b.
+func addInt4(a, b pgtype.Int4) pgtype.Int4 {
+	if !b.Valid {
+		return pgtype.Int4{}
+	}
+	return pgtype.Int4{Int32: a.Int32 + b.Int32, Valid: true}
+}
+
+// limitTo is LIMIT n.
+func limitTo[T any](s []T, n int32) []T {
+	if n < 0 {
+		n = 0
+	}
+	if len(s) > int(n) {
+		return s[:n]
+	}
+	return s
+}
+
+// sortedRows returns the rows for which keep is true, by rowid.
+func (d *memData) sortedRows(keep func(r batchsqlc.Batchrow) bool) []batchsqlc.Batchrow {
+	var rows []batchsqlc.Batchrow
+	for _, r := range d.rows {
+		if keep(r) {
+			rows = append(rows, r)
+		}
+	}
+	slices.SortFunc(rows, func(a, b batchsqlc.Batchrow) int {
+		return cmp.Compare(a.Rowid, b.Rowid)
+	})
+	return rows
+}
+
+// batchRows returns the rows of a batch, by rowid.
+func (d *memData) batchRows(batch uuid.UUID) []batchsqlc.Batchrow {
+	return d.sortedRows(func(r batchsqlc.Batchrow) bool { return r.Batch == batch })
+}
+
+// sortedBatchIDs returns the IDs of the batches for which keep is true, in order.
+func (d *memData) sortedBatchIDs(keep func(b batchsqlc.Batch) bool) []uuid.UUID {
+	var ids []uuid.UUID
+	for id, b := range d.batches {
+		if keep(b) {
+			ids = append(ids, id)
+		}
+	}
+	slices.SortFunc(ids, compareUUID)
+	return ids
+}
+
+// updateRows calls update on each of the rows with the given rowids that exist.
+func (d *memData) updateRows(rowids []int64, update func(r *batchsqlc.Batchrow)) {
+	for _, rowid := range rowids {
+		if r, ok := d.rows[rowid]; ok {
+			update(&r)
+			d.rows[rowid] = r
+		}
+	}
+}
+
+// updateBatchRows calls update on each of the rows for which keep is true.
+func (d *memData) updateBatchRows(keep func(r batchsqlc.Batchrow) bool, update func(r *batchsqlc.Batchrow)) {
+	for rowid, r := range d.rows {
+		if keep(r) {
+			update(&r)
+			d.rows[rowid] = r
+		}
+	}
+}
+
+// updateBatch calls update on the batch, if it exists.
+func (d *memData) updateBatch(id uuid.UUID, update func(b *batchsqlc.Batch)) {
+	if b, ok := d.batches[id]; ok {
+		update(&b)
+		d.batches[id] = b
+	}
+}
+
+func (d *memData) hasLineZero(batch uuid.UUID) bool {
+	for _, r := range d.rows {
+		if r.Batch == batch && r.Line == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// newRow checks a new batch row and gives it the next rowid.
+func (d *memData) newRow(batch uuid.UUID, line int32, input []byte, reqat pgtype.Timestamp, priority int32) (batchsqlc.Batchrow, error) {
+	if _, ok := d.batches[batch]; !ok {
+		return batchsqlc.Batchrow{}, fmt.Errorf("%w: batch %s of batch row does not exist", ErrMemStoreConstraint, batch)
+	}
+	if !reqat.Valid {
+		return batchsqlc.Batchrow{}, fmt.Errorf("%w: reqat of batch row is NULL", ErrMemStoreConstraint)
+	}
+	input, err := jsonbNotNull("batchrows.input", input)
+	if err != nil {
+		return batchsqlc.Batchrow{}, err
+	}
+	d.lastRowID++
+	return batchsqlc.Batchrow{
+		Rowid:     d.lastRowID,
+		Batch:     batch,
+		Line:      line,
+		Input:     input,
+		Status:    batchsqlc.StatusEnumQueued,
+		Reqat:     reqat,
+		CreatedAt: memNow(),
+		Priority:  priority,
+	}, nil
+}
+
+// insertRows inserts new batch rows, all of them or none.
+func (d *memData) insertRows(batches []uuid.UUID, lines []int32, inputs [][]byte, reqats []pgtype.Timestamp, priorities []int32) (int64, error) {
+	n := len(batches)
+	if len(lines) != n || len(inputs) != n || len(reqats) != n || len(priorities) != n {
+		return 0, fmt.Errorf("%w: batch row columns of different lengths", ErrMemStoreConstraint)
+	}
+	lastRowID := d.lastRowID
+	rows := make([]batchsqlc.Batchrow, 0, n)
+	for i := range batches {
+		r, err := d.newRow(batches[i], lines[i], inputs[i], reqats[i], priorities[i])
+		if err != nil {
+			d.lastRowID = lastRowID
+			return 0, err
+		}
+		rows = append(rows, r)
+	}
+	for _, r := range rows {
+		d.rows[r.Rowid] = r
+	}
+	return int64(n), nil
+}
+
+func (q *memQueries) ArchiveBatchRows(ctx context.Context, rowids []int64) error {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	for _, r := range d.sortedRows(func(r batchsqlc.Batchrow) bool { return slices.Contains(rowids, r.Rowid) }) {
+		d.lastHistoryID++
+		d.history = append(d.history, batchsqlc.BatchrowHistory{
+			ID:          d.lastHistoryID,
+			Rowid:       r.Rowid,
+			Batch:       r.Batch,
+			Line:        r.Line,
+			Input:       r.Input,
+			Status:      r.Status,
+			Doneat:      r.Doneat,
+			Res:         r.Res,
+			Blobrows:    r.Blobrows,
+			Messages:    r.Messages,
+			Doneby:      r.Doneby,
+			Outputfiles: d.batches[r.Batch].Outputfiles,
+			ArchivedAt:  memNow(),
+		})
+	}
+	return nil
+}
+
+func (q *memQueries) BulkInsertIntoBatchRows(ctx context.Context, arg batchsqlc.BulkInsertIntoBatchRowsParams) (int64, error) {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer done()
+
+	priorities := make([]int32, len(arg.Batch))
+	for i := range priorities {
+		priorities[i] = arg.Priority
+	}
+	return d.insertRows(arg.Batch, arg.Line, arg.Input, arg.Reqat, priorities)
+}
+
+func (q *memQueries) ClaimIdempotencyKey(ctx context.Context, arg batchsqlc.ClaimIdempotencyKeyParams) (uuid.UUID, error) {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	defer done()
+
+	k := memIdempotencyKey{app: arg.App, key: arg.Key}
+	if existing, ok := d.idempotencyKeys[k]; ok && !tsBefore(existing.CreatedAt, arg.Cutoff) {
+		return uuid.Nil, pgx.ErrNoRows
+	}
+	if !arg.CreatedAt.Valid {
+		return uuid.Nil, fmt.Errorf("%w: created_at of idempotency key is NULL", ErrMemStoreConstraint)
+	}
+	d.idempotencyKeys[k] = batchsqlc.BatchIdempotencyKey{
+		App:       arg.App,
+		Key:       arg.Key,
+		Batch:     arg.Batch,
+		CreatedAt: arg.CreatedAt,
+	}
+	return arg.Batch, nil
+}
+
+func (q *memQueries) ClaimWebhookDeliveries(ctx context.Context, arg batchsqlc.ClaimWebhookDeliveriesParams) ([]batchsqlc.ClaimWebhookDeliveriesRow, error) {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	if !arg.LeaseUntil.Valid {
+		return nil, fmt.Errorf("%w: next_attempt_at of webhook delivery is NULL", ErrMemStoreConstraint)
+	}
+	var due []batchsqlc.WebhookDelivery
+	for _, w := range d.webhooks {
+		if w.Status == batchsqlc.DeliveryStatusEnumPending && tsAtMost(w.NextAttemptAt, arg.Now) {
+			due = append(due, w)
+		}
+	}
+	slices.SortFunc(due, func(a, b batchsqlc.WebhookDelivery) int {
+		return cmp.Or(a.NextAttemptAt.Time.Compare(b.NextAttemptAt.Time), cmp.Compare(a.ID, b.ID))
+	})
+	due = limitTo(due, arg.Limit)
+
+	rows := make([]batchsqlc.ClaimWebhookDeliveriesRow, 0, len(due))
+	for _, w := range due {
+		w.NextAttemptAt = arg.LeaseUntil
+		d.webhooks[w.ID] = w
+		rows = append(rows, batchsqlc.ClaimWebhookDeliveriesRow{
+			ID:            w.ID,
+			Batch:         w.Batch,
+			Url:           w.Url,
+			Payload:       w.Payload,
+			Attempts:      w.Attempts,
+			NextAttemptAt: w.NextAttemptAt,
+		})
+	}
+	return rows, nil
+}
+
+func (q *memQueries) CopyBatchRows(ctx context.Context, arg []batchsqlc.CopyBatchRowsParams) (int64, error) {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer done()
+
+	batches := make([]uuid.UUID, len(arg))
+	lines := make([]int32, len(arg))
+	inputs := make([][]byte, len(arg))
+	reqats := make([]pgtype.Timestamp, len(arg))
+	priorities := make([]int32, len(arg))
+	for i, r := range arg {
+		batches[i], lines[i], inputs[i], reqats[i], priorities[i] = r.Batch, r.Line, r.Input, r.Reqat, r.Priority
+	}
+	return d.insertRows(batches, lines, inputs, reqats, priorities)
+}
+
+func (q *memQueries) CountBatchDependencies(ctx context.Context, batch uuid.UUID) (int64, error) {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer done()
+
+	var n int64
+	for _, dep := range d.dependencies {
+		if dep.Batch == batch {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (q *memQueries) CountBatchRowsByBatchIDAndStatus(ctx context.Context, arg batchsqlc.CountBatchRowsByBatchIDAndStatusParams) (int64, error) {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer done()
+
+	return int64(len(d.sortedRows(func(r batchsqlc.Batchrow) bool {
+		return r.Batch == arg.Batch && (r.Status == arg.Status || r.Status == arg.Status_2)
+	}))), nil
+}
+
+// countBatchRows counts the rows of an existing batch with the given status.
+func (q *memQueries) countBatchRows(ctx context.Context, batch uuid.UUID, status batchsqlc.StatusEnum) (int64, error) {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer done()
+
+	if _, ok := d.batches[batch]; !ok {
+		return 0, nil
+	}
+	return int64(len(d.sortedRows(func(r batchsqlc.Batchrow) bool {
+		return r.Batch == batch && r.Status == status
+	}))), nil
+}
+
+func (q *memQueries) CountBatchRowsInProgByBatchID(ctx context.Context, batch uuid.UUID) (int64, error) {
+	return q.countBatchRows(ctx, batch, batchsqlc.StatusEnumInprog)
+}
+
+func (q *memQueries) CountBatchRowsQueuedByBatchID(ctx context.Context, batch uuid.UUID) (int64, error) {
+	return q.countBatchRows(ctx, batch, batchsqlc.StatusEnumQueued)
+}
+
+func (q *memQueries) CountBatchesToPurge(ctx context.Context, arg batchsqlc.CountBatchesToPurgeParams) (batchsqlc.CountBatchesToPurgeRow, error) {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return batchsqlc.CountBatchesToPurgeRow{}, err
+	}
+	defer done()
+
+	var counts batchsqlc.CountBatchesToPurgeRow
+	for _, b := range d.batches {
+		if b.App != arg.App || !isDoneStatus(b.Status) || !tsBefore(b.Doneat, arg.Cutoff) {
+			continue
+		}
+		counts.Nbatches++
+		counts.Nrows += int64(len(d.batchRows(b.ID)))
+		var outputFiles map[string]json.RawMessage
+		if bytes.HasPrefix(bytes.TrimSpace(b.Outputfiles), []byte("{")) && json.Unmarshal(b.Outputfiles, &outputFiles) == nil {
+			counts.Noutputfiles += int64(len(outputFiles))
+		}
+	}
+	return counts, nil
+}
+
+func (q *memQueries) DeleteBatchFilesByBatchIDs(ctx context.Context, batchIds []uuid.UUID) error {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	d.files = slices.DeleteFunc(d.files, func(f batchsqlc.BatchFile) bool {
+		return slices.Contains(batchIds, f.BatchID)
+	})
+	return nil
+}
+
+// deleteRows deletes the rows for which drop is true, with their history.
+func (d *memData) deleteRows(drop func(r batchsqlc.Batchrow) bool) {
+	for rowid, r := range d.rows {
+		if drop(r) {
+			delete(d.rows, rowid)
+		}
+	}
+	d.history = slices.DeleteFunc(d.history, func(h batchsqlc.BatchrowHistory) bool {
+		_, ok := d.rows[h.Rowid]
+		return !ok
+	})
+}
+
+func (q *memQueries) DeleteBatchRowsByBatchIDs(ctx context.Context, batchIds []uuid.UUID) error {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	d.deleteRows(func(r batchsqlc.Batchrow) bool { return slices.Contains(batchIds, r.Batch) })
+	return nil
+}
+
+func (q *memQueries) DeleteBatchesByIDs(ctx context.Context, ids []uuid.UUID) (int64, error) {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer done()
+
+	// batch_files is the one table whose rows are not deleted with their batch
+	for _, f := range d.files {
+		if slices.Contains(ids, f.BatchID) {
+			return 0, fmt.Errorf("%w: batch %s has batch files", ErrMemStoreConstraint, f.BatchID)
+		}
+	}
+
+	var n int64
+	for _, id := range ids {
+		if _, ok := d.batches[id]; ok {
+			delete(d.batches, id)
+			n++
+		}
+	}
+	deleted := func(id uuid.UUID) bool {
+		_, ok := d.batches[id]
+		return !ok
+	}
+	d.deleteRows(func(r batchsqlc.Batchrow) bool { return deleted(r.Batch) })
+	d.history = slices.DeleteFunc(d.history, func(h batchsqlc.BatchrowHistory) bool { return deleted(h.Batch) })
+	for id, w := range d.webhooks {
+		if deleted(w.Batch) {
+			delete(d.webhooks, id)
+		}
+	}
+	d.dependencies = slices.DeleteFunc(d.dependencies, func(dep batchsqlc.BatchDependency) bool {
+		return deleted(dep.Batch) || deleted(dep.Parent)
+	})
+	for k, key := range d.idempotencyKeys {
+		if deleted(key.Batch) {
+			delete(d.idempotencyKeys, k)
+		}
+	}
+	for name, s := range d.schedules {
+		if s.LastBatch.Valid && deleted(s.LastBatch.Bytes) {
+			s.LastBatch = pgtype.UUID{}
+			d.schedules[name] = s
+		}
+	}
+	for id, b := range d.batches {
+		if b.ParentID.Valid && deleted(b.ParentID.Bytes) {
+			b.ParentID = pgtype.UUID{}
+			d.batches[id] = b
+		}
+	}
+	return n, nil
+}
+
+// DropBatchRowPartitions drops nothing, as there are no partitions.
+func (q *memQueries) DeleteWorker(ctx context.Context, instanceID string) error {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	delete(d.workers, instanceID)
+	return nil
+}
+
+func (q *memQueries) DropBatchRowPartitions(ctx context.Context, cutoff pgtype.Timestamp) ([]string, error) {
+	return nil, nil
+}
+
+// EnsureBatchRowPartitions fails, as batchrows is not partitioned.
+func (q *memQueries) EnsureBatchRowPartitions(ctx context.Context, monthsAhead int32) ([]string, error) {
+	return nil, errMemStoreNotPartitioned
+}
+
+func (q *memQueries) FetchBatchRowsForBatchDone(ctx context.Context, batch uuid.UUID) ([]batchsqlc.FetchBatchRowsForBatchDoneRow, error) {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	var rows []batchsqlc.FetchBatchRowsForBatchDoneRow
+	for _, r := range d.batchRows(batch) {
+		rows = append(rows, batchsqlc.FetchBatchRowsForBatchDoneRow{
+			Line:     r.Line,
+			Status:   r.Status,
+			Res:      r.Res,
+			Messages: r.Messages,
+		})
+	}
+	return rows, nil
+}
+
+// fetchable tells if a row may be fetched for processing. Unlike the SQL, it has no
+// bound on reqat, which is only there to skip old partitions.
+func (d *memData) fetchable(r batchsqlc.Batchrow, status batchsqlc.StatusEnum, now pgtype.Timestamp) bool {
+	b := d.batches[r.Batch]
+	return r.Status == status &&
+		b.Status != batchsqlc.StatusEnumWait && b.Status != batchsqlc.StatusEnumPaused &&
+		(!r.NotBefore.Valid || tsAtMost(r.NotBefore, now)) &&
+		(!b.Runat.Valid || tsAtMost(b.Runat, now))
+}
+
+// byPriority orders rows by the priority copied from their batch, highest first, then by rowid.
+func (d *memData) byPriority(a, b batchsqlc.Batchrow) int {
+	return cmp.Or(
+		cmp.Compare(b.Priority, a.Priority),
+		cmp.Compare(a.Rowid, b.Rowid))
+}
+
+func (d *memData) fetchedRow(r batchsqlc.Batchrow) batchsqlc.FetchBlockOfRowsRow {
+	b := d.batches[r.Batch]
+	return batchsqlc.FetchBlockOfRowsRow{
+		App:      b.App,
+		Status:   b.Status,
+		Op:       b.Op,
+		Context:  b.Context,
+		Batch:    r.Batch,
+		Rowid:    r.Rowid,
+		Line:     r.Line,
+		Input:    r.Input,
+		Attempts: r.Attempts,
+	}
+}
+
+func (q *memQueries) FetchBlockOfRows(ctx context.Context, arg batchsqlc.FetchBlockOfRowsParams) ([]batchsqlc.FetchBlockOfRowsRow, error) {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	rows := d.sortedRows(func(r batchsqlc.Batchrow) bool { return d.fetchable(r, arg.Status, arg.Now) })
+	slices.SortStableFunc(rows, d.byPriority)
+
+	var block []batchsqlc.FetchBlockOfRowsRow
+	for _, r := range limitTo(rows, arg.Limit) {
+		block = append(block, d.fetchedRow(r))
+	}
+	return block, nil
+}
+
+func (q *memQueries) FetchBlockOfRowsFair(ctx context.Context, arg batchsqlc.FetchBlockOfRowsFairParams) ([]batchsqlc.FetchBlockOfRowsFairRow, error) {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	weights := make(map[string]int32)
+	for i, app := range arg.WeightApps {
+		weights[app] = 1
+		if i < len(arg.Weights) {
+			weights[app] = arg.Weights[i]
+		}
+	}
+
+	// Up to limit rows of each app with pending batches, ranked within the app
+	type candidate struct {
+		row   batchsqlc.Batchrow
+		round int32
+	}
+	var candidates []candidate
+	apps := make(map[string]bool)
+	for _, b := range d.batches {
+		if isPendingStatus(b.Status) {
+			apps[b.App] = true
+		}
+	}
+	for app := range apps {
+		rows := d.sortedRows(func(r batchsqlc.Batchrow) bool {
+			return d.batches[r.Batch].App == app && d.fetchable(r, arg.Status, arg.Now)
+		})
+		slices.SortStableFunc(rows, d.byPriority)
+		weight, ok := weights[app]
+		if !ok {
+			weight = 1
+		}
+		for rn, r := range limitTo(rows, arg.Limit) {
+			candidates = append(candidates, candidate{row: r, round: int32(rn) / max(weight, 1)})
+		}
+	}
+	slices.SortFunc(candidates, func(a, b candidate) int {
+		return cmp.Or(cmp.Compare(a.round, b.round), d.byPriority(a.row, b.row))
+	})
+
+	var block []batchsqlc.FetchBlockOfRowsFairRow
+	for _, c := range limitTo(candidates, arg.Limit) {
+		block = append(block, batchsqlc.FetchBlockOfRowsFairRow(d.fetchedRow(c.row)))
+	}
+	return block, nil
+}
+
+func (q *memQueries) GetBatchByID(ctx context.Context, id uuid.UUID) (batchsqlc.Batch, error) {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return batchsqlc.Batch{}, err
+	}
+	defer done()
+
+	b, ok := d.batches[id]
+	if !ok {
+		return batchsqlc.Batch{}, pgx.ErrNoRows
+	}
+	return b, nil
+}
+
+func (q *memQueries) GetBatchProgress(ctx context.Context, batch uuid.UUID) (batchsqlc.GetBatchProgressRow, error) {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return batchsqlc.GetBatchProgressRow{}, err
+	}
+	defer done()
+
+	var progress batchsqlc.GetBatchProgressRow
+	for _, r := range d.batchRows(batch) {
+		switch r.Status {
+		case batchsqlc.StatusEnumQueued:
+			progress.Nqueued++
+		case batchsqlc.StatusEnumInprog:
+			progress.Ninprog++
+		case batchsqlc.StatusEnumSuccess:
+			progress.Nsuccess++
+		case batchsqlc.StatusEnumFailed:
+			progress.Nfailed++
+		case batchsqlc.StatusEnumAborted:
+			progress.Naborted++
+		}
+		if r.Doneat.Valid && (!progress.FirstDoneat.Valid || tsBefore(r.Doneat, progress.FirstDoneat)) {
+			progress.FirstDoneat = r.Doneat
+		}
+	}
+	return progress, nil
+}
+
+func (q *memQueries) GetBatchRowHistory(ctx context.Context, batch uuid.UUID) ([]batchsqlc.BatchrowHistory, error) {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	var history []batchsqlc.BatchrowHistory
+	for _, h := range d.history {
+		if h.Batch == batch {
+			history = append(history, h)
+		}
+	}
+	slices.SortFunc(history, func(a, b batchsqlc.BatchrowHistory) int {
+		return cmp.Or(cmp.Compare(a.Line, b.Line), cmp.Compare(a.ID, b.ID))
+	})
+	return history, nil
+}
+
+func (q *memQueries) GetBatchRowsByBatchID(ctx context.Context, batch uuid.UUID) ([]batchsqlc.Batchrow, error) {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	return d.batchRows(batch), nil
+}
+
+// sortedBatchRows returns the rows of a batch for which keep is true, by line.
+func (d *memData) sortedBatchRows(batch uuid.UUID, keep func(r batchsqlc.Batchrow) bool) []batchsqlc.GetBatchRowsByBatchIDSortedRow {
+	rows := d.sortedRows(func(r batchsqlc.Batchrow) bool { return r.Batch == batch && keep(r) })
+	slices.SortStableFunc(rows, func(a, b batchsqlc.Batchrow) int {
+		return cmp.Compare(a.Line, b.Line)
+	})
+	var sorted []batchsqlc.GetBatchRowsByBatchIDSortedRow
+	for _, r := range rows {
+		sorted = append(sorted, batchsqlc.GetBatchRowsByBatchIDSortedRow{
+			Rowid:    r.Rowid,
+			Line:     r.Line,
+			Input:    r.Input,
+			Status:   r.Status,
+			Reqat:    r.Reqat,
+			Doneat:   r.Doneat,
+			Res:      r.Res,
+			Blobrows: r.Blobrows,
+			Messages: r.Messages,
+			Doneby:   r.Doneby,
+		})
+	}
+	return sorted
+}
+
+func (q *memQueries) GetBatchRowsByBatchIDSorted(ctx context.Context, batch uuid.UUID) ([]batchsqlc.GetBatchRowsByBatchIDSortedRow, error) {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	return d.sortedBatchRows(batch, func(r batchsqlc.Batchrow) bool { return true }), nil
+}
+
+func (q *memQueries) GetBatchRowsPage(ctx context.Context, arg batchsqlc.GetBatchRowsPageParams) ([]batchsqlc.Batchrow, error) {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	rows := d.sortedRows(func(r batchsqlc.Batchrow) bool {
+		return r.Batch == arg.Batch &&
+			(r.Line > arg.AfterLine || (r.Line == arg.AfterLine && r.Rowid > arg.AfterRowid))
+	})
+	slices.SortStableFunc(rows, func(a, b batchsqlc.Batchrow) int {
+		return cmp.Compare(a.Line, b.Line)
+	})
+	if len(rows) > int(arg.PageSize) {
+		rows = rows[:arg.PageSize]
+	}
+	return rows, nil
+}
+
+func (q *memQueries) GetBatchRowsCount(ctx context.Context, batch uuid.UUID) (int64, error) {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer done()
+
+	return int64(len(d.batchRows(batch))), nil
+}
+
+func (q *memQueries) GetBatchRowsForRerun(ctx context.Context, arg batchsqlc.GetBatchRowsForRerunParams) ([]batchsqlc.GetBatchRowsForRerunRow, error) {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	var rows []batchsqlc.GetBatchRowsForRerunRow
+	for _, r := range d.sortedBatchRows(arg.Batch, func(r batchsqlc.Batchrow) bool {
+		return slices.Contains(arg.Statuses, string(r.Status)) && (len(arg.Lines) == 0 || slices.Contains(arg.Lines, r.Line))
+	}) {
+		rows = append(rows, batchsqlc.GetBatchRowsForRerunRow{
+			Rowid:  r.Rowid,
+			Line:   r.Line,
+			Input:  r.Input,
+			Status: r.Status,
+		})
+	}
+	return rows, nil
+}
+
+func (q *memQueries) GetBatchScheduleForUpdate(ctx context.Context, name string) (batchsqlc.GetBatchScheduleForUpdateRow, error) {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return batchsqlc.GetBatchScheduleForUpdateRow{}, err
+	}
+	defer done()
+
+	s, ok := d.schedules[name]
+	if !ok {
+		return batchsqlc.GetBatchScheduleForUpdateRow{}, pgx.ErrNoRows
+	}
+	return batchsqlc.GetBatchScheduleForUpdateRow{
+		Name:       s.Name,
+		App:        s.App,
+		Op:         s.Op,
+		Cron:       s.Cron,
+		LastFireat: s.LastFireat,
+		LastBatch:  s.LastBatch,
+	}, nil
+}
+
+func (q *memQueries) GetBatchStatus(ctx context.Context, id uuid.UUID) (batchsqlc.StatusEnum, error) {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer done()
+
+	b, ok := d.batches[id]
+	if !ok {
+		return "", pgx.ErrNoRows
+	}
+	return b.Status, nil
+}
+
+func (q *memQueries) GetBatchStatusAndOutputFiles(ctx context.Context, id uuid.UUID) (batchsqlc.GetBatchStatusAndOutputFilesRow, error) {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return batchsqlc.GetBatchStatusAndOutputFilesRow{}, err
+	}
+	defer done()
+
+	b, ok := d.batches[id]
+	rows := d.batchRows(id)
+	if !ok || len(rows) == 0 {
+		return batchsqlc.GetBatchStatusAndOutputFilesRow{}, pgx.ErrNoRows
+	}
+	return batchsqlc.GetBatchStatusAndOutputFilesRow{
+		Status:      b.Status,
+		Outputfiles: b.Outputfiles,
+		Res:         rows[0].Res,
+	}, nil
+}
+
+func (q *memQueries) GetBatchesToPurge(ctx context.Context, arg batchsqlc.GetBatchesToPurgeParams) ([]batchsqlc.Batch, error) {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	var batches []batchsqlc.Batch
+	for _, b := range d.batches {
+		if b.App == arg.App && isDoneStatus(b.Status) && tsBefore(b.Doneat, arg.Cutoff) {
+			batches = append(batches, b)
+		}
+	}
+	slices.SortFunc(batches, func(a, b batchsqlc.Batch) int {
+		return cmp.Or(a.Doneat.Time.Compare(b.Doneat.Time), compareUUID(a.ID, b.ID))
+	})
+	return limitTo(batches, arg.MaxBatches), nil
+}
+
+func (q *memQueries) GetCompletedBatches(ctx context.Context) ([]uuid.UUID, error) {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	return d.sortedBatchIDs(func(b batchsqlc.Batch) bool { return isDoneStatus(b.Status) }), nil
+}
+
+func (q *memQueries) GetDeadWorkers(ctx context.Context, cutoff pgtype.Timestamp) ([]string, error) {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	var workers []string
+	for _, w := range d.workers {
+		if tsBefore(w.Heartbeat, cutoff) {
+			workers = append(workers, w.InstanceID)
+		}
+	}
+	slices.Sort(workers)
+	return workers, nil
+}
+
+func (q *memQueries) GetIdempotencyKeyBatch(ctx context.Context, arg batchsqlc.GetIdempotencyKeyBatchParams) (uuid.UUID, error) {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	defer done()
+
+	k, ok := d.idempotencyKeys[memIdempotencyKey{app: arg.App, key: arg.Key}]
+	if !ok {
+		return uuid.Nil, pgx.ErrNoRows
+	}
+	return k.Batch, nil
+}
+
+func (q *memQueries) GetParentBatches(ctx context.Context, batch uuid.UUID) ([]batchsqlc.GetParentBatchesRow, error) {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	var parents []batchsqlc.GetParentBatchesRow
+	for _, dep := range d.dependencies {
+		if dep.Batch != batch {
+			continue
+		}
+		parent := d.batches[dep.Parent]
+		parents = append(parents, batchsqlc.GetParentBatchesRow{
+			Parent:          dep.Parent,
+			Name:            dep.Name,
+			OnParentFailure: dep.OnParentFailure,
+			Status:          parent.Status,
+			Outputfiles:     parent.Outputfiles,
+		})
+	}
+	slices.SortStableFunc(parents, func(a, b batchsqlc.GetParentBatchesRow) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return parents, nil
+}
+
+func (q *memQueries) GetPendingBatchRows(ctx context.Context, batch uuid.UUID) ([]batchsqlc.GetPendingBatchRowsRow, error) {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	var rows []batchsqlc.GetPendingBatchRowsRow
+	for _, r := range d.batchRows(batch) {
+		if isPendingStatus(r.Status) {
+			rows = append(rows, batchsqlc.GetPendingBatchRowsRow{
+				Rowid:    r.Rowid,
+				Line:     r.Line,
+				Input:    r.Input,
+				Status:   r.Status,
+				Reqat:    r.Reqat,
+				Doneat:   r.Doneat,
+				Res:      r.Res,
+				Blobrows: r.Blobrows,
+				Messages: r.Messages,
+				Doneby:   r.Doneby,
+			})
+		}
+	}
+	return rows, nil
+}
+
+func (q *memQueries) GetProcessedBatchRowsPage(ctx context.Context, arg batchsqlc.GetProcessedBatchRowsPageParams) ([]batchsqlc.GetProcessedBatchRowsPageRow, error) {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	var rows []batchsqlc.GetProcessedBatchRowsPageRow
+	for _, r := range d.sortedBatchRows(arg.Batch, func(r batchsqlc.Batchrow) bool {
+		if r.Status != batchsqlc.StatusEnumSuccess && r.Status != batchsqlc.StatusEnumFailed {
+			return false
+		}
+		return r.Line > arg.AfterLine || (r.Line == arg.AfterLine && r.Rowid > arg.AfterRowid)
+	}) {
+		if len(rows) == int(arg.PageSize) {
+			break
+		}
+		rows = append(rows, batchsqlc.GetProcessedBatchRowsPageRow{Rowid: r.Rowid, Line: r.Line, Blobrows: r.Blobrows})
+	}
+	return rows, nil
+}
+
+func (q *memQueries) GetUnsummarizedBatches(ctx context.Context) ([]uuid.UUID, error) {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	return d.sortedBatchIDs(func(b batchsqlc.Batch) bool {
+		if b.Status != batchsqlc.StatusEnumInprog || b.Doneat.Valid {
+			return false
+		}
+		for _, r := range d.rows {
+			if r.Batch == b.ID && isPendingStatus(r.Status) {
+				return false
+			}
+		}
+		return true
+	}), nil
+}
+
+func (q *memQueries) GetWaitingDependentBatches(ctx context.Context, parent uuid.UUID) ([]uuid.UUID, error) {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	var ids []uuid.UUID
+	for _, dep := range d.dependencies {
+		if dep.Parent == parent && d.batches[dep.Batch].Status == batchsqlc.StatusEnumWait {
+			ids = append(ids, dep.Batch)
+		}
+	}
+	slices.SortFunc(ids, compareUUID)
+	return ids, nil
+}
+
+func (q *memQueries) GetWebhookDeliveriesByBatchID(ctx context.Context, batch uuid.UUID) ([]batchsqlc.WebhookDelivery, error) {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	var deliveries []batchsqlc.WebhookDelivery
+	for _, w := range d.webhooks {
+		if w.Batch == batch {
+			deliveries = append(deliveries, w)
+		}
+	}
+	slices.SortFunc(deliveries, func(a, b batchsqlc.WebhookDelivery) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return deliveries, nil
+}
+
+func (q *memQueries) InsertBatchDependency(ctx context.Context, arg batchsqlc.InsertBatchDependencyParams) error {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	if arg.OnParentFailure != string(ParentFailureAbort) && arg.OnParentFailure != string(ParentFailureRun) {
+		return fmt.Errorf("%w: on_parent_failure %q", ErrMemStoreConstraint, arg.OnParentFailure)
+	}
+	for _, id := range []uuid.UUID{arg.Batch, arg.Parent} {
+		if _, ok := d.batches[id]; !ok {
+			return fmt.Errorf("%w: batch %s of dependency does not exist", ErrMemStoreConstraint, id)
+		}
+	}
+	for _, dep := range d.dependencies {
+		if dep.Batch == arg.Batch && dep.Parent == arg.Parent {
+			return fmt.Errorf("%w: batch %s already depends on %s", ErrMemStoreConstraint, arg.Batch, arg.Parent)
+		}
+	}
+	d.dependencies = append(d.dependencies, batchsqlc.BatchDependency(arg))
+	return nil
+}
+
+func (q *memQueries) InsertBatchFile(ctx context.Context, arg batchsqlc.InsertBatchFileParams) error {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	if _, ok := d.batches[arg.BatchID]; !ok {
+		return fmt.Errorf("%w: batch %s of batch file does not exist", ErrMemStoreConstraint, arg.BatchID)
+	}
+	for _, f := range d.files {
+		if f.ObjectID == arg.ObjectID {
+			return fmt.Errorf("%w: object %s already has a batch file", ErrMemStoreConstraint, arg.ObjectID)
+		}
+	}
+	metadata, err := jsonb("batch_files.metadata", arg.Metadata)
+	if err != nil {
+		return err
+	}
+	d.lastFileID++
+	d.files = append(d.files, batchsqlc.BatchFile{
+		ID:          int32(d.lastFileID),
+		BatchID:     arg.BatchID,
+		ObjectID:    arg.ObjectID,
+		Filename:    arg.Filename,
+		Size:        arg.Size,
+		Checksum:    arg.Checksum,
+		ContentType: arg.ContentType,
+		Status:      arg.Status,
+		ReceivedAt:  arg.ReceivedAt,
+		Metadata:    metadata,
+		CreatedAt:   memNow(),
+	})
+	return nil
+}
+
+func (q *memQueries) InsertIntoBatchRows(ctx context.Context, arg batchsqlc.InsertIntoBatchRowsParams) error {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	r, err := d.newRow(arg.Batch, arg.Line, arg.Input, arg.Reqat, arg.Priority)
+	if err != nil {
+		return err
+	}
+	d.rows[r.Rowid] = r
+	return nil
+}
+
+func (q *memQueries) InsertIntoBatches(ctx context.Context, arg batchsqlc.InsertIntoBatchesParams) (uuid.UUID, error) {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	defer done()
+
+	if _, ok := d.batches[arg.ID]; ok {
+		return uuid.Nil, fmt.Errorf("%w: batch %s already exists", ErrMemStoreConstraint, arg.ID)
+	}
+	if arg.Op != strings.ToLower(arg.Op) {
+		return uuid.Nil, fmt.Errorf("%w: op %q is not in lower case", ErrMemStoreConstraint, arg.Op)
+	}
+	if !arg.Reqat.Valid {
+		return uuid.Nil, fmt.Errorf("%w: reqat of batch is NULL", ErrMemStoreConstraint)
+	}
+	batchctx, err := jsonbNotNull("batches.context", arg.Context)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	d.batches[arg.ID] = batchsqlc.Batch{
+		ID:          arg.ID,
+		App:         arg.App,
+		Op:          arg.Op,
+		Context:     batchctx,
+		Status:      arg.Status,
+		Reqat:       arg.Reqat,
+		CreatedAt:   memNow(),
+		Runat:       arg.Runat,
+		Priority:    arg.Priority,
+		CallbackUrl: arg.CallbackUrl,
+	}
+	return arg.ID, nil
+}
+
+func (q *memQueries) InsertWebhookDelivery(ctx context.Context, arg batchsqlc.InsertWebhookDeliveryParams) error {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	b, ok := d.batches[arg.Batch]
+	if !ok || !b.CallbackUrl.Valid {
+		return nil
+	}
+	payload, err := jsonbNotNull("webhook_deliveries.payload", arg.Payload)
+	if err != nil {
+		return err
+	}
+	if !arg.NextAttemptAt.Valid {
+		return fmt.Errorf("%w: next_attempt_at of webhook delivery is NULL", ErrMemStoreConstraint)
+	}
+	d.lastWebhookID++
+	d.webhooks[d.lastWebhookID] = batchsqlc.WebhookDelivery{
+		ID:            d.lastWebhookID,
+		Batch:         b.ID,
+		Url:           b.CallbackUrl.String,
+		Payload:       payload,
+		Status:        batchsqlc.DeliveryStatusEnumPending,
+		NextAttemptAt: arg.NextAttemptAt,
+		CreatedAt:     memNow(),
+	}
+	return nil
+}
+
+// memListFilter holds the arguments that ListBatches and ListSlowQueries share.
+type memListFilter struct {
+	App        string
+	Op         pgtype.Text
+	Status     batchsqlc.NullStatusEnum
+	Since      pgtype.Timestamp
+	AfterReqat pgtype.Timestamp
+	AfterID    pgtype.UUID
+	PageSize   int32
+}
+
+// list returns a page of the batches (or, if slowQueries, the slow queries) of an
+// app, newest first.
+func (d *memData) list(f memListFilter, slowQueries bool) []batchsqlc.Batch {
+	var batches []batchsqlc.Batch
+	for _, b := range d.batches {
+		if b.App != f.App ||
+			(f.Op.Valid && b.Op != f.Op.String) ||
+			(f.Status.Valid && b.Status != f.Status.StatusEnum) ||
+			!tsAtMost(f.Since, b.Reqat) ||
+			d.hasLineZero(b.ID) != slowQueries {
+			continue
+		}
+		// (reqat, id) < (after_reqat, after_id)
+		if f.AfterReqat.Valid && !tsBefore(b.Reqat, f.AfterReqat) &&
+			!(b.Reqat.Time.Equal(f.AfterReqat.Time) && f.AfterID.Valid && compareUUID(b.ID, f.AfterID.Bytes) < 0) {
+			continue
+		}
+		batches = append(batches, b)
+	}
+	slices.SortFunc(batches, func(a, b batchsqlc.Batch) int {
+		return cmp.Or(b.Reqat.Time.Compare(a.Reqat.Time), compareUUID(b.ID, a.ID))
+	})
+	return limitTo(batches, f.PageSize)
+}
+
+func (q *memQueries) LeaseBatchRows(ctx context.Context, arg batchsqlc.LeaseBatchRowsParams) error {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	d.updateRows(arg.RowIds, func(r *batchsqlc.Batchrow) {
+		r.Worker = pgtype.Text{String: arg.Worker, Valid: true}
+		r.LeasedUntil = arg.LeasedUntil
+	})
+	return nil
+}
+
+func (q *memQueries) ListBatches(ctx context.Context, arg batchsqlc.ListBatchesParams) ([]batchsqlc.ListBatchesRow, error) {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	var rows []batchsqlc.ListBatchesRow
+	for _, b := range d.list(memListFilter(arg), false) {
+		rows = append(rows, batchsqlc.ListBatchesRow{
+			ID:          b.ID,
+			App:         b.App,
+			Op:          b.Op,
+			Context:     b.Context,
+			Inputfile:   b.Inputfile,
+			Status:      b.Status,
+			Reqat:       b.Reqat,
+			Doneat:      b.Doneat,
+			Outputfiles: b.Outputfiles,
+			Nsuccess:    b.Nsuccess,
+			Nfailed:     b.Nfailed,
+			Naborted:    b.Naborted,
+			Outputtypes: b.Outputtypes,
+			Nrows:       int64(len(d.batchRows(b.ID))),
+		})
+	}
+	return rows, nil
+}
+
+func (q *memQueries) ListSlowQueries(ctx context.Context, arg batchsqlc.ListSlowQueriesParams) ([]batchsqlc.ListSlowQueriesRow, error) {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	var rows []batchsqlc.ListSlowQueriesRow
+	for _, b := range d.list(memListFilter(arg), true) {
+		rows = append(rows, batchsqlc.ListSlowQueriesRow{
+			ID:          b.ID,
+			App:         b.App,
+			Op:          b.Op,
+			Inputfile:   b.Inputfile,
+			Status:      b.Status,
+			Reqat:       b.Reqat,
+			Doneat:      b.Doneat,
+			Outputfiles: b.Outputfiles,
+		})
+	}
+	return rows, nil
+}
+
+func (q *memQueries) LockParentBatches(ctx context.Context, ids []uuid.UUID) ([]uuid.UUID, error) {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	return d.sortedBatchIDs(func(b batchsqlc.Batch) bool { return slices.Contains(ids, b.ID) }), nil
+}
+
+func (q *memQueries) MarkWebhookAttemptFailed(ctx context.Context, arg batchsqlc.MarkWebhookAttemptFailedParams) (int64, error) {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer done()
+
+	w, ok := d.webhooks[arg.ID]
+	if !ok || w.Status != batchsqlc.DeliveryStatusEnumPending || !tsEqual(w.NextAttemptAt, arg.LeasedUntil) {
+		return 0, nil
+	}
+	if !arg.NextAttemptAt.Valid {
+		return 0, fmt.Errorf("%w: next_attempt_at of webhook delivery is NULL", ErrMemStoreConstraint)
+	}
+	w.Status = arg.Status
+	w.Attempts++
+	w.NextAttemptAt = arg.NextAttemptAt
+	w.LastHttpStatus = arg.HttpStatus
+	w.LastError = arg.LastError
+	d.webhooks[arg.ID] = w
+	return 1, nil
+}
+
+func (q *memQueries) MarkWebhookDelivered(ctx context.Context, arg batchsqlc.MarkWebhookDeliveredParams) (int64, error) {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer done()
+
+	w, ok := d.webhooks[arg.ID]
+	if !ok || w.Status != batchsqlc.DeliveryStatusEnumPending || !tsEqual(w.NextAttemptAt, arg.LeasedUntil) {
+		return 0, nil
+	}
+	w.Status = batchsqlc.DeliveryStatusEnumDelivered
+	w.Attempts++
+	w.LastHttpStatus = arg.HttpStatus
+	w.LastError = pgtype.Text{}
+	w.DeliveredAt = arg.DeliveredAt
+	d.webhooks[arg.ID] = w
+	return 1, nil
+}
+
+// NotifyJobsQueued wakes up the listeners of the store when the transaction
+// commits, or at once outside a transaction.
+func (q *memQueries) NotifyJobsQueued(ctx context.Context, app string) (string, error) {
+	if q.tx != nil {
+		if q.tx.closed {
+			return "", pgx.ErrTxClosed
+		}
+		q.tx.notified = append(q.tx.notified, app)
+		return "", nil
+	}
+	q.store.notify([]string{app})
+	return "", nil
+}
+
+// setBatchStatus moves a batch from one of the statuses in from to status, and
+// returns its app, or pgx.ErrNoRows if it is in none of them.
+func (q *memQueries) setBatchStatus(ctx context.Context, id uuid.UUID, from []batchsqlc.StatusEnum, status batchsqlc.StatusEnum) (string, error) {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer done()
+
+	b, ok := d.batches[id]
+	if !ok || !slices.Contains(from, b.Status) {
+		return "", pgx.ErrNoRows
+	}
+	b.Status = status
+	d.batches[id] = b
+	return b.App, nil
+}
+
+func (q *memQueries) PauseBatch(ctx context.Context, id uuid.UUID) (string, error) {
+	return q.setBatchStatus(ctx, id, []batchsqlc.StatusEnum{batchsqlc.StatusEnumQueued, batchsqlc.StatusEnumInprog}, batchsqlc.StatusEnumPaused)
+}
+
+func (q *memQueries) ResumeBatch(ctx context.Context, id uuid.UUID) (string, error) {
+	return q.setBatchStatus(ctx, id, []batchsqlc.StatusEnum{batchsqlc.StatusEnumPaused}, batchsqlc.StatusEnumQueued)
+}
+
+func (q *memQueries) RecordWorkerHeartbeat(ctx context.Context, arg batchsqlc.RecordWorkerHeartbeatParams) error {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	if !arg.Heartbeat.Valid {
+		return fmt.Errorf("%w: heartbeat of worker is NULL", ErrMemStoreConstraint)
+	}
+	d.workers[arg.InstanceID] = batchsqlc.Worker{InstanceID: arg.InstanceID, Heartbeat: arg.Heartbeat}
+	return nil
+}
+
+func (q *memQueries) ReleaseBatchRowLease(ctx context.Context, arg batchsqlc.ReleaseBatchRowLeaseParams) error {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	d.updateRows([]int64{arg.Rowid}, func(r *batchsqlc.Batchrow) {
+		if r.Worker.Valid && r.Worker.String == arg.Worker {
+			r.Worker = pgtype.Text{}
+			r.LeasedUntil = pgtype.Timestamp{}
+		}
+	})
+	return nil
+}
+
+func (q *memQueries) ReleaseDependentBatch(ctx context.Context, arg batchsqlc.ReleaseDependentBatchParams) error {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	b, ok := d.batches[arg.ID]
+	if !ok {
+		return nil
+	}
+	parents := []byte("null")
+	if arg.Parents != nil {
+		if parents, err = jsonb("parents", arg.Parents); err != nil {
+			return err
+		}
+	}
+	var batchctx map[string]json.RawMessage
+	if bytes.HasPrefix(bytes.TrimSpace(b.Context), []byte("{")) && json.Unmarshal(b.Context, &batchctx) == nil {
+		batchctx[ParentsContextKey] = parents
+		if b.Context, err = json.Marshal(batchctx); err != nil {
+			return err
+		}
+	}
+	b.Status = batchsqlc.StatusEnumQueued
+	d.batches[arg.ID] = b
+	return nil
+}
+
+func (q *memQueries) RenewBatchRowLeases(ctx context.Context, arg batchsqlc.RenewBatchRowLeasesParams) error {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	d.updateBatchRows(func(r batchsqlc.Batchrow) bool {
+		return r.Worker.Valid && r.Worker.String == arg.Worker && r.Status == batchsqlc.StatusEnumInprog
+	}, func(r *batchsqlc.Batchrow) {
+		r.LeasedUntil = arg.LeasedUntil
+	})
+	return nil
+}
+
+func (q *memQueries) ReopenBatch(ctx context.Context, id uuid.UUID) error {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	d.updateBatch(id, func(b *batchsqlc.Batch) {
+		b.Status = batchsqlc.StatusEnumQueued
+		b.Doneat = pgtype.Timestamp{}
+		b.Outputfiles = nil
+		b.Nsuccess, b.Nfailed, b.Naborted = pgtype.Int4{}, pgtype.Int4{}, pgtype.Int4{}
+		b.Outputtypes = nil
+	})
+	return nil
+}
+
+func (q *memQueries) RequeueBatchRowForRetry(ctx context.Context, arg batchsqlc.RequeueBatchRowForRetryParams) error {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	messages, err := jsonb("batchrows.messages", arg.Messages)
+	if err != nil {
+		return err
+	}
+	d.updateBatchRows(func(r batchsqlc.Batchrow) bool {
+		return r.Rowid == arg.Rowid && r.Status == batchsqlc.StatusEnumInprog
+	}, func(r *batchsqlc.Batchrow) {
+		r.Status = batchsqlc.StatusEnumQueued
+		r.Attempts++
+		r.NotBefore = arg.NotBefore
+		r.Messages = messages
+	})
+	return nil
+}
+
+func (q *memQueries) RequeueBatchRowsForRerun(ctx context.Context, rowids []int64) error {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	d.updateRows(rowids, func(r *batchsqlc.Batchrow) {
+		r.Status = batchsqlc.StatusEnumQueued
+		r.Doneat = pgtype.Timestamp{}
+		r.Res, r.Blobrows, r.Messages = nil, nil, nil
+		r.Doneby = pgtype.Text{}
+		r.Attempts = 0
+		r.NotBefore = pgtype.Timestamp{}
+	})
+	return nil
+}
+
+func (q *memQueries) ResetExpiredLeasesToQueued(ctx context.Context, now pgtype.Timestamp) (int64, error) {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer done()
+
+	return d.resetLeasedRows(func(r batchsqlc.Batchrow) bool {
+		_, registered := d.workers[r.Worker.String]
+		return tsBefore(r.LeasedUntil, now) && !(r.Worker.Valid && registered)
+	}), nil
+}
+
+func (q *memQueries) ResetRowsToQueued(ctx context.Context, dollar_1 []int64) error {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	d.updateRows(dollar_1, func(r *batchsqlc.Batchrow) {
+		if r.Status == batchsqlc.StatusEnumInprog {
+			r.Status = batchsqlc.StatusEnumQueued
+		}
+	})
+	return nil
+}
+
+func (q *memQueries) ResetWorkerRowsToQueued(ctx context.Context, worker string) (int64, error) {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer done()
+
+	return d.resetLeasedRows(func(r batchsqlc.Batchrow) bool {
+		return r.Worker.Valid && r.Worker.String == worker
+	}), nil
+}
+
+// resetLeasedRows requeues the 'inprog' rows for which keep is true, ending their
+// leases, and returns how many it requeued.
+func (d *memData) resetLeasedRows(keep func(r batchsqlc.Batchrow) bool) int64 {
+	var n int64
+	d.updateBatchRows(func(r batchsqlc.Batchrow) bool {
+		return r.Status == batchsqlc.StatusEnumInprog && keep(r)
+	}, func(r *batchsqlc.Batchrow) {
+		r.Status = batchsqlc.StatusEnumQueued
+		r.Worker = pgtype.Text{}
+		r.LeasedUntil = pgtype.Timestamp{}
+		n++
+	})
+	return n
+}
+
+func (q *memQueries) SetBatchParent(ctx context.Context, arg batchsqlc.SetBatchParentParams) error {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	if _, ok := d.batches[arg.ParentID.Bytes]; arg.ParentID.Valid && !ok {
+		return fmt.Errorf("%w: parent batch %s does not exist", ErrMemStoreConstraint, uuid.UUID(arg.ParentID.Bytes))
+	}
+	d.updateBatch(arg.ID, func(b *batchsqlc.Batch) {
+		b.ParentID = arg.ParentID
+	})
+	return nil
+}
+
+// TryAdvisoryLockBatch always succeeds, as transactions run one at a time.
+func (q *memQueries) TryAdvisoryLockBatch(ctx context.Context, dollar_1 string) (bool, error) {
+	if q.tx != nil && q.tx.closed {
+		return false, pgx.ErrTxClosed
+	}
+	return true, nil
+}
+
+func (q *memQueries) UpdateBatchCounters(ctx context.Context, arg batchsqlc.UpdateBatchCountersParams) error {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	d.updateBatch(arg.ID, func(b *batchsqlc.Batch) {
+		b.Nsuccess = addInt4(b.Nsuccess, arg.Nsuccess)
+		b.Nfailed = addInt4(b.Nfailed, arg.Nfailed)
+		b.Naborted = addInt4(b.Naborted, arg.Naborted)
+	})
+	return nil
+}
+
+func (q *memQueries) UpdateBatchOutputFiles(ctx context.Context, arg batchsqlc.UpdateBatchOutputFilesParams) error {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	outputFiles, err := jsonb("batches.outputfiles", arg.Outputfiles)
+	if err != nil {
+		return err
+	}
+	d.updateBatch(arg.ID, func(b *batchsqlc.Batch) {
+		b.Outputfiles = outputFiles
+	})
+	return nil
+}
+
+func (q *memQueries) UpdateBatchResult(ctx context.Context, arg batchsqlc.UpdateBatchResultParams) error {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	outputFiles, err := jsonb("batches.outputfiles", arg.Outputfiles)
+	if err != nil {
+		return err
+	}
+	d.updateBatch(arg.ID, func(b *batchsqlc.Batch) {
+		b.Outputfiles = outputFiles
+		b.Status = arg.Status
+		b.Doneat = arg.Doneat
+	})
+	return nil
+}
+
+func (q *memQueries) UpdateBatchRowInput(ctx context.Context, arg batchsqlc.UpdateBatchRowInputParams) error {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	input, err := jsonbNotNull("batchrows.input", arg.Input)
+	if err != nil {
+		return err
+	}
+	d.updateRows([]int64{arg.Rowid}, func(r *batchsqlc.Batchrow) {
+		r.Input = input
+	})
+	return nil
+}
+
+func (q *memQueries) UpdateBatchRowStatus(ctx context.Context, arg batchsqlc.UpdateBatchRowStatusParams) error {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	d.updateRows([]int64{arg.Rowid}, func(r *batchsqlc.Batchrow) {
+		r.Status = arg.Status
+	})
+	return nil
+}
+
+// rowResult holds the JSONB results that the UpdateBatchRows queries set.
+type rowResult struct {
+	res, blobrows, messages []byte
+}
+
+func newRowResult(res, blobrows, messages []byte) (rowResult, error) {
+	var result rowResult
+	var err error
+	if result.res, err = jsonb("batchrows.res", res); err != nil {
+		return result, err
+	}
+	if result.blobrows, err = jsonb("batchrows.blobrows", blobrows); err != nil {
+		return result, err
+	}
+	result.messages, err = jsonb("batchrows.messages", messages)
+	return result, err
+}
+
+func (q *memQueries) UpdateBatchRowsBatchJob(ctx context.Context, arg batchsqlc.UpdateBatchRowsBatchJobParams) error {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	result, err := newRowResult(arg.Res, arg.Blobrows, arg.Messages)
+	if err != nil {
+		return err
+	}
+	d.updateRows([]int64{arg.Rowid}, func(r *batchsqlc.Batchrow) {
+		r.Status = arg.Status
+		r.Doneat = arg.Doneat
+		r.Res, r.Blobrows, r.Messages = result.res, result.blobrows, result.messages
+		r.Doneby = arg.Doneby
+	})
+	return nil
+}
+
+// abortPendingRows sets the status, doneat and messages of the pending rows of a
+// batch, if the batch passes check.
+func (q *memQueries) abortPendingRows(ctx context.Context, batch uuid.UUID, check func(b batchsqlc.Batch) bool, status batchsqlc.StatusEnum, doneat pgtype.Timestamp, messages []byte) error {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	if b, ok := d.batches[batch]; !ok || !check(b) {
+		return nil
+	}
+	messages, err = jsonb("batchrows.messages", messages)
+	if err != nil {
+		return err
+	}
+	d.updateBatchRows(func(r batchsqlc.Batchrow) bool {
+		return r.Batch == batch && isPendingStatus(r.Status)
+	}, func(r *batchsqlc.Batchrow) {
+		r.Status = status
+		r.Doneat = doneat
+		r.Messages = messages
+	})
+	return nil
+}
+
+func (q *memQueries) UpdateBatchRowsByBatchAndStatus(ctx context.Context, arg batchsqlc.UpdateBatchRowsByBatchAndStatusParams) error {
+	return q.abortPendingRows(ctx, arg.Batch, func(b batchsqlc.Batch) bool { return true }, arg.Status, arg.Doneat, arg.Messages)
+}
+
+func (q *memQueries) UpdateBatchRowsByBatchApp(ctx context.Context, arg batchsqlc.UpdateBatchRowsByBatchAppParams) error {
+	return q.abortPendingRows(ctx, arg.ID, func(b batchsqlc.Batch) bool {
+		return b.App == arg.App
+	}, arg.Status, arg.Doneat, arg.Messages)
+}
+
+func (q *memQueries) UpdateBatchRowsByBatchAppOp(ctx context.Context, arg batchsqlc.UpdateBatchRowsByBatchAppOpParams) error {
+	return q.abortPendingRows(ctx, arg.ID, func(b batchsqlc.Batch) bool {
+		return b.App == arg.App && b.Op == arg.Op
+	}, arg.Status, arg.Doneat, arg.Messages)
+}
+
+func (q *memQueries) UpdateBatchRowsSlowQuery(ctx context.Context, arg batchsqlc.UpdateBatchRowsSlowQueryParams) error {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	result, err := newRowResult(arg.Res, nil, arg.Messages)
+	if err != nil {
+		return err
+	}
+	d.updateRows([]int64{arg.Rowid}, func(r *batchsqlc.Batchrow) {
+		r.Status = arg.Status
+		r.Doneat = arg.Doneat
+		r.Res, r.Messages = result.res, result.messages
+		r.Doneby = arg.Doneby
+	})
+	return nil
+}
+
+func (q *memQueries) UpdateBatchRowsStatus(ctx context.Context, arg batchsqlc.UpdateBatchRowsStatusParams) error {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	d.updateRows(arg.Column2, func(r *batchsqlc.Batchrow) {
+		r.Status = arg.Status
+	})
+	return nil
+}
+
+func (q *memQueries) UpdateBatchRowsStatusBulk(ctx context.Context, arg batchsqlc.UpdateBatchRowsStatusBulkParams) error {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	d.updateRows(arg.RowIds, func(r *batchsqlc.Batchrow) {
+		r.Status = arg.Status
+	})
+	return nil
+}
+
+func (q *memQueries) UpdateBatchScheduleFired(ctx context.Context, arg batchsqlc.UpdateBatchScheduleFiredParams) error {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	s, ok := d.schedules[arg.Name]
+	if !ok {
+		return nil
+	}
+	if _, ok := d.batches[arg.LastBatch.Bytes]; arg.LastBatch.Valid && !ok {
+		return fmt.Errorf("%w: last batch %s of schedule does not exist", ErrMemStoreConstraint, uuid.UUID(arg.LastBatch.Bytes))
+	}
+	if !arg.LastFireat.Valid {
+		return fmt.Errorf("%w: last_fireat of schedule is NULL", ErrMemStoreConstraint)
+	}
+	s.LastFireat = arg.LastFireat
+	s.LastBatch = arg.LastBatch
+	d.schedules[arg.Name] = s
+	return nil
+}
+
+// setBatchSummary sets the status, doneat, output files and counters of a batch, and
+// the content types of its output files if outputTypes is not nil.
+func (q *memQueries) setBatchSummary(ctx context.Context, arg batchsqlc.UpdateBatchStatusParams, outputTypes *[]byte) error {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	outputFiles, err := jsonb("batches.outputfiles", arg.Outputfiles)
+	if err != nil {
+		return err
+	}
+	var types []byte
+	if outputTypes != nil {
+		if types, err = jsonb("batches.outputtypes", *outputTypes); err != nil {
+			return err
+		}
+	}
+	d.updateBatch(arg.ID, func(b *batchsqlc.Batch) {
+		b.Status = arg.Status
+		b.Doneat = arg.Doneat
+		b.Outputfiles = outputFiles
+		b.Nsuccess, b.Nfailed, b.Naborted = arg.Nsuccess, arg.Nfailed, arg.Naborted
+		if outputTypes != nil {
+			b.Outputtypes = types
+		}
+	})
+	return nil
+}
+
+func (q *memQueries) UpdateBatchStatus(ctx context.Context, arg batchsqlc.UpdateBatchStatusParams) error {
+	return q.setBatchSummary(ctx, arg, nil)
+}
+
+func (q *memQueries) UpdateBatchSummary(ctx context.Context, arg batchsqlc.UpdateBatchSummaryParams) error {
+	return q.setBatchSummary(ctx, batchsqlc.UpdateBatchStatusParams{
+		ID:          arg.ID,
+		Status:      arg.Status,
+		Doneat:      arg.Doneat,
+		Outputfiles: arg.Outputfiles,
+		Nsuccess:    arg.Nsuccess,
+		Nfailed:     arg.Nfailed,
+		Naborted:    arg.Naborted,
+	}, &arg.Outputtypes)
+}
+
+func (q *memQueries) UpdateBatchSummaryOnAbort(ctx context.Context, arg batchsqlc.UpdateBatchSummaryOnAbortParams) error {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	d.updateBatch(arg.ID, func(b *batchsqlc.Batch) {
+		b.Status = arg.Status
+		b.Doneat = arg.Doneat
+		b.Naborted = arg.Naborted
+	})
+	return nil
+}
+
+func (q *memQueries) UpdateBatchesStatusBulk(ctx context.Context, arg batchsqlc.UpdateBatchesStatusBulkParams) error {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	for _, id := range arg.BatchIds {
+		d.updateBatch(id, func(b *batchsqlc.Batch) {
+			if b.Status == batchsqlc.StatusEnumQueued {
+				b.Status = arg.Status
+			}
+		})
+	}
+	return nil
+}
+
+func (q *memQueries) UpsertBatchSchedule(ctx context.Context, arg batchsqlc.UpsertBatchScheduleParams) error {
+	d, done, err := q.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	s, ok := d.schedules[arg.Name]
+	if !ok {
+		if !arg.LastFireat.Valid {
+			return fmt.Errorf("%w: last_fireat of schedule is NULL", ErrMemStoreConstraint)
+		}
+		s = batchsqlc.BatchSchedule{Name: arg.Name, LastFireat: arg.LastFireat, CreatedAt: memNow()}
+	}
+	s.App, s.Op, s.Cron = arg.App, arg.Op, arg.Cron
+	d.schedules[arg.Name] = s
+	return nil
+}
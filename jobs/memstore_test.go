@@ -0,0 +1,213 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/remiges-tech/alya/jobs/objstore"
+	"github.com/remiges-tech/alya/jobs/pg/batchsqlc"
+	"github.com/remiges-tech/alya/wscutils"
+	"github.com/remiges-tech/logharbour/logharbour"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memTestProcessor succeeds on even lines, writing a line to out.txt, and fails
+// on odd ones.
+type memTestProcessor struct {
+	t *testing.T
+}
+
+func (p *memTestProcessor) DoBatchJob(ctx context.Context, initBlock InitBlock, jobctx JSONstr, line int, input JSONstr) (batchsqlc.StatusEnum, JSONstr, []wscutils.ErrorMessage, map[string]string, error) {
+	if line%2 == 1 {
+		return batchsqlc.StatusEnumFailed, mustJSONstr(p.t, `{}`), []wscutils.ErrorMessage{{MsgID: 1, ErrCode: "odd"}}, nil, nil
+	}
+	return batchsqlc.StatusEnumSuccess, mustJSONstr(p.t, `{"ok":true}`), nil, map[string]string{"out.txt": fmt.Sprintf("line %d\n", line)}, nil
+}
+
+func (p *memTestProcessor) MarkDone(ctx context.Context, initBlock InitBlock, jobctx JSONstr, details BatchDetails_t) error {
+	return nil
+}
+
+func newMemoryTestJobManager(t *testing.T) (*JobManager, *MemoryStore, *objstore.MemObjStore) {
+	t.Helper()
+	store := NewMemoryStore()
+	objStore := objstore.NewMemObjectStore()
+
+	logger := logharbour.NewLogger(&logharbour.LoggerContext{}, "test", log.Writer())
+	jm := NewJobManagerWithBackends(store, NewMemoryCache(), objStore, logger, nil)
+	return jm, store, objStore
+}
+
+func TestMemoryBackends_BatchToDone(t *testing.T) {
+	jm, _, objStore := newMemoryTestJobManager(t)
+	require.NoError(t, jm.RegisterInitializer("memapp", &MockInitializer{}))
+	require.NoError(t, jm.RegisterProcessorBatchV2("memapp", "memop", &memTestProcessor{t: t}))
+
+	input := []BatchInput_t{
+		{Line: 1, Input: mustJSONstr(t, `{"n":1}`)},
+		{Line: 2, Input: mustJSONstr(t, `{"n":2}`)},
+		{Line: 3, Input: mustJSONstr(t, `{"n":3}`)},
+		{Line: 4, Input: mustJSONstr(t, `{"n":4}`)},
+	}
+	batchID, err := jm.BatchSubmit("memapp", "memop", mustJSONstr(t, `{}`), input, false)
+	require.NoError(t, err)
+
+	var (
+		status      batchsqlc.StatusEnum
+		output      []BatchOutput_t
+		outputFiles map[string]string
+		nsuccess    int
+		nfailed     int
+	)
+	for range 20 {
+		jm.RunOneIteration()
+		status, output, outputFiles, nsuccess, nfailed, _, err = jm.BatchDone(batchID)
+		require.NoError(t, err)
+		if status != batchsqlc.StatusEnumQueued && status != batchsqlc.StatusEnumInprog {
+			break
+		}
+	}
+
+	// Any failed row fails the batch
+	assert.Equal(t, batchsqlc.StatusEnumFailed, status)
+	assert.Equal(t, 2, nsuccess)
+	assert.Equal(t, 2, nfailed)
+	assert.Len(t, output, 4)
+
+	// The final status is served from the status cache
+	cached, err := jm.statusCache.Get(context.Background(), BatchStatusKey(batchID))
+	require.NoError(t, err)
+	assert.Equal(t, string(batchsqlc.StatusEnumFailed), cached)
+	assert.Nil(t, jm.redisClient)
+
+	require.Contains(t, outputFiles, "out.txt")
+	r, err := objStore.Get(context.Background(), jm.config.BatchOutputBucket, outputFiles["out.txt"])
+	require.NoError(t, err)
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, "line 2\nline 4\n", string(data))
+
+	// The output file carries its batch and logical name in its metadata
+	info, size, _, err := objstore.ObjectInfo(context.Background(), objStore, outputFiles["out.txt"], jm.config.BatchOutputBucket)
+	require.NoError(t, err)
+	assert.JSONEq(t, fmt.Sprintf(`{"mimetype": "text/plain", "ownerclass": "batch", "ownerid": %q, "filename": "out.txt", "from": "memapp/memop"}`, batchID), info)
+	assert.Equal(t, len(data), size)
+}
+
+func TestMemoryCache(t *testing.T) {
+	ctx := context.Background()
+	cache := NewMemoryCache()
+
+	_, err := cache.Get(ctx, "missing")
+	assert.ErrorIs(t, err, ErrStatusCacheMiss)
+
+	require.NoError(t, cache.Set(ctx, "forever", "a", 0))
+	require.NoError(t, cache.Set(ctx, "brief", "b", time.Millisecond))
+	require.NoError(t, cache.SetMulti(ctx, map[string]string{"x": "1", "y": "2"}, time.Hour))
+	value, err := cache.Get(ctx, "forever")
+	require.NoError(t, err)
+	assert.Equal(t, "a", value)
+	value, err = cache.Get(ctx, "y")
+	require.NoError(t, err)
+	assert.Equal(t, "2", value)
+
+	time.Sleep(5 * time.Millisecond)
+	_, err = cache.Get(ctx, "brief")
+	assert.ErrorIs(t, err, ErrStatusCacheMiss)
+
+	require.NoError(t, cache.Del(ctx, "forever", "x", "missing"))
+	_, err = cache.Get(ctx, "x")
+	assert.ErrorIs(t, err, ErrStatusCacheMiss)
+	_, err = cache.Get(ctx, "y")
+	assert.NoError(t, err)
+
+	// Expired keys are swept out as keys are set
+	for i := range memoryCacheSweepNSets {
+		require.NoError(t, cache.Set(ctx, fmt.Sprintf("k%d", i), "v", time.Hour))
+	}
+	assert.NotContains(t, cache.entries, "brief")
+}
+
+func TestMemoryStore_Transactions(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	batchID := uuid.New()
+	insert := func(q batchsqlc.Querier) error {
+		_, err := q.InsertIntoBatches(ctx, batchsqlc.InsertIntoBatchesParams{
+			ID:      batchID,
+			App:     "memapp",
+			Op:      "memop",
+			Context: []byte(`{}`),
+			Status:  batchsqlc.StatusEnumQueued,
+			Reqat:   memNow(),
+		})
+		return err
+	}
+
+	t.Run("rollback discards", func(t *testing.T) {
+		tx, err := store.Begin(ctx)
+		require.NoError(t, err)
+		require.NoError(t, insert(tx.Queries()))
+		_, err = tx.Queries().GetBatchByID(ctx, batchID)
+		require.NoError(t, err)
+		require.NoError(t, tx.Rollback(ctx))
+		assert.ErrorIs(t, tx.Rollback(ctx), pgx.ErrTxClosed)
+
+		_, err = store.Queries().GetBatchByID(ctx, batchID)
+		assert.ErrorIs(t, err, pgx.ErrNoRows)
+	})
+
+	t.Run("commit applies and notifies", func(t *testing.T) {
+		lctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		woken := make(chan string, 1)
+		go store.Listen(lctx, func(app string) { woken <- app })
+		// Wait for the listener to be registered
+		require.Eventually(t, func() bool {
+			store.listenmu.Lock()
+			defer store.listenmu.Unlock()
+			return len(store.listeners) == 1
+		}, time.Second, time.Millisecond)
+
+		tx, err := store.Begin(ctx)
+		require.NoError(t, err)
+		require.NoError(t, insert(tx.Queries()))
+		failure, err := tx.Queries().NotifyJobsQueued(ctx, "memapp")
+		require.NoError(t, err)
+		assert.Empty(t, failure)
+		assert.Empty(t, woken)
+		require.NoError(t, tx.Commit(ctx))
+		assert.ErrorIs(t, tx.Rollback(ctx), pgx.ErrTxClosed)
+
+		assert.Equal(t, "memapp", <-woken)
+		batch, err := store.Queries().GetBatchByID(ctx, batchID)
+		require.NoError(t, err)
+		assert.Equal(t, batchsqlc.StatusEnumQueued, batch.Status)
+	})
+
+	t.Run("duplicate key", func(t *testing.T) {
+		assert.ErrorIs(t, insert(store.Queries()), ErrMemStoreConstraint)
+	})
+
+	t.Run("begin waits for the running transaction", func(t *testing.T) {
+		tx, err := store.Begin(ctx)
+		require.NoError(t, err)
+		defer tx.Rollback(ctx)
+
+		wctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		_, err = store.Begin(wctx)
+		assert.True(t, errors.Is(err, context.DeadlineExceeded))
+		_, err = store.Queries().GetBatchByID(wctx, batchID)
+		assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	})
+}
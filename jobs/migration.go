@@ -11,7 +11,7 @@ import (
 	"github.com/jackc/tern/v2/migrate"
 )
 
-//go:embed pg/migrations/*.sql
+//go:embed pg/migrations/*.sql pg/migrations/partitioned/*.sql
 var migrations embed.FS
 
 // MigrateDatabase runs the migrations using Tern.
@@ -37,6 +37,9 @@ func MigrateDatabase(conn *pgx.Conn) error {
 	}
 	log.Println("Migration files found:")
 	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
 		log.Printf("- %s", file.Name())
 
 		// Print the contents of the migration file
@@ -71,3 +74,35 @@ func MigrateDatabase(conn *pgx.Conn) error {
 
 	return nil
 }
+
+// MigrateBatchRowsPartitioned converts the batchrows table into one partitioned by
+// month of reqat, for high-volume installations. It is optional, and must be run after
+// MigrateDatabase. Its migrations are versioned separately, in the
+// schema_version_partitioned table. JobManagers using the converted table must be
+// configured with JobManagerConfig.PartitionedBatchRows.
+func MigrateBatchRowsPartitioned(conn *pgx.Conn) error {
+	ctx := context.Background()
+
+	migrator, err := migrate.NewMigrator(ctx, conn, "schema_version_partitioned")
+	if err != nil {
+		return fmt.Errorf("failed to create migrator: %w", err)
+	}
+
+	filesystem, err := fs.Sub(migrations, "pg/migrations/partitioned")
+	if err != nil {
+		return fmt.Errorf("failed to create sub-filesystem: %w", err)
+	}
+
+	err = migrator.LoadMigrations(filesystem)
+	if err != nil {
+		return fmt.Errorf("failed to load migrations: %w", err)
+	}
+
+	err = migrator.Migrate(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+
+	log.Printf("Applied %d batchrows partitioning migrations", len(migrator.Migrations))
+	return nil
+}
@@ -0,0 +1,126 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/remiges-tech/alya/jobs/pg/batchsqlc"
+)
+
+const (
+	// notifyChannel is the PostgreSQL channel on which NotifyJobsQueued
+	// announces newly queued work. It must match the channel in batch.sql.
+	notifyChannel = "alya_jobs"
+
+	// listenRetryInterval is how long the listener waits before reconnecting
+	// after it lost its database connection.
+	listenRetryInterval = 5 * time.Second
+)
+
+// wakeup lets any number of idle processing loops wait for a notification.
+// Each waiter takes the current channel before it checks for work; notify
+// closes that channel, so a notification that arrives while a loop is still
+// checking is not lost.
+type wakeup struct {
+	mu sync.Mutex
+	ch chan struct{}
+}
+
+// wait returns a channel that is closed at the next notification.
+func (w *wakeup) wait() <-chan struct{} {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.ch == nil {
+		w.ch = make(chan struct{})
+	}
+	return w.ch
+}
+
+// notify wakes up all current waiters.
+func (w *wakeup) notify() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.ch != nil {
+		close(w.ch)
+		w.ch = nil
+	}
+}
+
+// sleepUntilWork blocks until new work is announced on woken, the polling
+// interval has passed, or ctx is cancelled. Polling remains the fallback for
+// missed notifications and for work that becomes due without one, such as
+// rows requeued for retry or delayed batches.
+func (jm *JobManager) sleepUntilWork(ctx context.Context, woken <-chan struct{}) {
+	timer := time.NewTimer(jm.getRandomSleepDuration())
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	case <-woken:
+	}
+}
+
+// notifyJobsQueued announces newly queued work of app to idle JobManager
+// instances. q must be bound to the transaction that queues the work, so that
+// the notification goes out only if it commits. A notification that fails is
+// only logged, as polling picks up the work anyway: it is sent in a
+// subtransaction, which leaves the caller's transaction usable. The error
+// returned is that of the query itself, after which the transaction is
+// aborted and the caller must fail.
+func (jm *JobManager) notifyJobsQueued(ctx context.Context, q batchsqlc.Querier, app string) error {
+	if jm.config.DisableListenNotify {
+		return nil
+	}
+	failure, err := q.NotifyJobsQueued(ctx, app)
+	if err != nil {
+		return fmt.Errorf("failed to notify queued jobs: %w", err)
+	}
+	if failure != "" {
+		jm.logger.Warn().LogActivity("Failed to notify queued jobs", map[string]any{
+			"app":   app,
+			"error": failure,
+		})
+	}
+	return nil
+}
+
+// startListener starts the notification listener once per JobManager, however
+// many processing loops are run.
+func (jm *JobManager) startListener(ctx context.Context) {
+	if jm.store == nil || jm.config.DisableListenNotify {
+		return
+	}
+	jm.listenOnce.Do(func() {
+		go jm.runListener(ctx)
+	})
+}
+
+// runListener listens for notifications of the store and wakes up the idle
+// processing loops on every one. It reconnects after failures until ctx is
+// cancelled.
+func (jm *JobManager) runListener(ctx context.Context) {
+	for ctx.Err() == nil {
+		err := jm.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		jm.logger.Warn().LogActivity("Notification listener stopped, falling back to polling until it reconnects", map[string]any{
+			"error": err.Error(),
+		})
+		if sleepContext(ctx, listenRetryInterval) != nil {
+			return
+		}
+	}
+}
+
+// listen runs one listening session of the store.
+func (jm *JobManager) listen(ctx context.Context) error {
+	return jm.store.Listen(ctx, func(app string) {
+		jm.logger.Debug0().LogActivity("Woken up by queued jobs notification", map[string]any{
+			"app": app,
+		})
+		jm.wakeup.notify()
+	})
+}
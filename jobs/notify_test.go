@@ -0,0 +1,118 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/remiges-tech/alya/jobs/pg/batchsqlc/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWakeup(t *testing.T) {
+	var w wakeup
+
+	// a notification without waiters is not remembered
+	w.notify()
+	woken := w.wait()
+	select {
+	case <-woken:
+		t.Fatal("woken without a notification")
+	default:
+	}
+
+	// all waiters holding the channel are woken, even if they were still busy
+	other := w.wait()
+	w.notify()
+	for _, ch := range []<-chan struct{}{woken, other} {
+		select {
+		case <-ch:
+		default:
+			t.Fatal("waiter not woken")
+		}
+	}
+
+	// later waiters wait for the next notification
+	select {
+	case <-w.wait():
+		t.Fatal("woken by an earlier notification")
+	default:
+	}
+}
+
+func TestSleepUntilWork(t *testing.T) {
+	jm := newTestJobManager(&mocks.QuerierMock{}, nil)
+	jm.config.PollingIntervalSec = 60
+
+	t.Run("woken", func(t *testing.T) {
+		woken := jm.wakeup.wait()
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			jm.wakeup.notify()
+		}()
+
+		start := time.Now()
+		jm.sleepUntilWork(context.Background(), woken)
+		assert.Less(t, time.Since(start), 5*time.Second)
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		jm.sleepUntilWork(ctx, jm.wakeup.wait())
+		assert.Less(t, time.Since(start), 5*time.Second)
+	})
+}
+
+func TestNotifyJobsQueued(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("notifies", func(t *testing.T) {
+		q := &mocks.QuerierMock{
+			NotifyJobsQueuedFunc: func(ctx context.Context, app string) (string, error) {
+				return "", nil
+			},
+		}
+		jm := newTestJobManager(q, nil)
+
+		require.NoError(t, jm.notifyJobsQueued(ctx, q, "banking"))
+		require.Len(t, q.NotifyJobsQueuedCalls(), 1)
+		assert.Equal(t, "banking", q.NotifyJobsQueuedCalls()[0].App)
+	})
+
+	t.Run("failed notification is not fatal", func(t *testing.T) {
+		q := &mocks.QuerierMock{
+			NotifyJobsQueuedFunc: func(ctx context.Context, app string) (string, error) {
+				return "too many notifications in the NOTIFY queue", nil
+			},
+		}
+		jm := newTestJobManager(q, nil)
+
+		assert.NoError(t, jm.notifyJobsQueued(ctx, q, "banking"))
+		assert.Len(t, q.NotifyJobsQueuedCalls(), 1)
+	})
+
+	t.Run("query failure is returned", func(t *testing.T) {
+		q := &mocks.QuerierMock{
+			NotifyJobsQueuedFunc: func(ctx context.Context, app string) (string, error) {
+				return "", errors.New("connection reset")
+			},
+		}
+		jm := newTestJobManager(q, nil)
+
+		assert.Error(t, jm.notifyJobsQueued(ctx, q, "banking"))
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		q := &mocks.QuerierMock{}
+		jm := newTestJobManager(q, nil)
+		jm.config.DisableListenNotify = true
+
+		require.NoError(t, jm.notifyJobsQueued(ctx, q, "banking"))
+		assert.Empty(t, q.NotifyJobsQueuedCalls())
+	})
+}
@@ -0,0 +1,281 @@
+package objstore_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/remiges-tech/alya/jobs/objstore"
+)
+
+// testConformance checks that store behaves as described on ObjectStore, which is
+// how MinIO behaves. bucket and otherBucket must exist and be empty.
+func testConformance(t *testing.T, store objstore.ObjectStore, bucket, otherBucket string) {
+	ctx := context.Background()
+	data := []byte("Hello, World!")
+
+	put := func(t *testing.T, obj string, data []byte, contentType string, metadata map[string]string) {
+		t.Helper()
+		err := store.PutWithMetadata(ctx, bucket, obj, bytes.NewReader(data), int64(len(data)), contentType, metadata)
+		if err != nil {
+			t.Fatalf("PutWithMetadata(%s) failed: %v", obj, err)
+		}
+	}
+	get := func(t *testing.T, bucket, obj string) []byte {
+		t.Helper()
+		r, err := store.Get(ctx, bucket, obj)
+		if err != nil {
+			t.Fatalf("Get(%s/%s) failed: %v", bucket, obj, err)
+		}
+		defer r.Close()
+		got, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("Reading %s/%s failed: %v", bucket, obj, err)
+		}
+		return got
+	}
+	stat := func(t *testing.T, bucket, obj string) objstore.ObjectAttrs {
+		t.Helper()
+		attrs, err := store.Stat(ctx, bucket, obj)
+		if err != nil {
+			t.Fatalf("Stat(%s/%s) failed: %v", bucket, obj, err)
+		}
+		return attrs
+	}
+
+	t.Run("put and get", func(t *testing.T) {
+		before := time.Now().Add(-2 * time.Second) // MinIO keeps times to the second
+		if err := store.Put(ctx, bucket, "plain", bytes.NewReader(data), int64(len(data)), "text/plain"); err != nil {
+			t.Fatalf("Put failed: %v", err)
+		}
+		if got := get(t, bucket, "plain"); !bytes.Equal(got, data) {
+			t.Errorf("Get = %q, want %q", got, data)
+		}
+
+		attrs := stat(t, bucket, "plain")
+		if attrs.Size != int64(len(data)) || attrs.ContentType != "text/plain" || len(attrs.Metadata) != 0 {
+			t.Errorf("Stat = %+v, want size %d, text/plain and no metadata", attrs, len(data))
+		}
+		if attrs.LastModified.Before(before) || attrs.LastModified.After(time.Now().Add(2*time.Second)) {
+			t.Errorf("LastModified = %v, want about now", attrs.LastModified)
+		}
+	})
+
+	t.Run("unknown size and default content type", func(t *testing.T) {
+		if err := store.Put(ctx, bucket, "unsized", bytes.NewReader(data), -1, ""); err != nil {
+			t.Fatalf("Put of unknown size failed: %v", err)
+		}
+		attrs := stat(t, bucket, "unsized")
+		if attrs.Size != int64(len(data)) || attrs.ContentType != "application/octet-stream" {
+			t.Errorf("Stat = %+v, want size %d and application/octet-stream", attrs, len(data))
+		}
+	})
+
+	t.Run("short reader", func(t *testing.T) {
+		err := store.Put(ctx, bucket, "short", bytes.NewReader(data), int64(len(data))+10, "text/plain")
+		if err == nil {
+			t.Errorf("Put of fewer bytes than its size succeeded")
+		}
+	})
+
+	t.Run("metadata", func(t *testing.T) {
+		put(t, "meta", data, "text/plain", map[string]string{"alya-owner": "batch", "Alya-Note": "a b"})
+		attrs := stat(t, bucket, "meta")
+		want := map[string]string{"Alya-Owner": "batch", "Alya-Note": "a b"}
+		if !equalMetadata(attrs.Metadata, want) {
+			t.Errorf("Metadata = %v, want %v", attrs.Metadata, want)
+		}
+	})
+
+	t.Run("overwrite", func(t *testing.T) {
+		put(t, "over", data, "text/plain", map[string]string{"Alya-Old": "1"})
+		put(t, "over", []byte("new"), "application/json", map[string]string{"Alya-New": "2"})
+		if got := get(t, bucket, "over"); string(got) != "new" {
+			t.Errorf("Get after overwrite = %q, want new", got)
+		}
+		attrs := stat(t, bucket, "over")
+		if attrs.ContentType != "application/json" || !equalMetadata(attrs.Metadata, map[string]string{"Alya-New": "2"}) {
+			t.Errorf("Stat after overwrite = %+v", attrs)
+		}
+	})
+
+	t.Run("replace metadata", func(t *testing.T) {
+		put(t, "replace", data, "text/csv", map[string]string{"Alya-Old": "1"})
+		if err := store.ReplaceMetadata(ctx, bucket, "replace", map[string]string{"Alya-New": "2"}); err != nil {
+			t.Fatalf("ReplaceMetadata failed: %v", err)
+		}
+		attrs := stat(t, bucket, "replace")
+		if attrs.Size != int64(len(data)) || attrs.ContentType != "text/csv" || !equalMetadata(attrs.Metadata, map[string]string{"Alya-New": "2"}) {
+			t.Errorf("Stat after ReplaceMetadata = %+v", attrs)
+		}
+		if got := get(t, bucket, "replace"); !bytes.Equal(got, data) {
+			t.Errorf("ReplaceMetadata changed the data to %q", got)
+		}
+	})
+
+	t.Run("copy", func(t *testing.T) {
+		put(t, "copy", data, "text/csv", map[string]string{"Alya-Owner": "batch"})
+		if err := store.Copy(ctx, bucket, "copy", otherBucket); err != nil {
+			t.Fatalf("Copy failed: %v", err)
+		}
+		if got := get(t, otherBucket, "copy"); !bytes.Equal(got, data) {
+			t.Errorf("Copied data = %q, want %q", got, data)
+		}
+		attrs := stat(t, otherBucket, "copy")
+		if attrs.ContentType != "text/csv" || !equalMetadata(attrs.Metadata, map[string]string{"Alya-Owner": "batch"}) {
+			t.Errorf("Stat of the copy = %+v", attrs)
+		}
+		if got := get(t, bucket, "copy"); !bytes.Equal(got, data) {
+			t.Errorf("Copy changed the source to %q", got)
+		}
+	})
+
+	t.Run("names with slashes", func(t *testing.T) {
+		put(t, "app/2024/report.csv", data, "text/csv", nil)
+		if got := get(t, bucket, "app/2024/report.csv"); !bytes.Equal(got, data) {
+			t.Errorf("Get = %q, want %q", got, data)
+		}
+		if _, err := store.Get(ctx, bucket, "app/2024"); !errors.Is(err, objstore.ErrObjectNotFound) {
+			t.Errorf("Get of a name prefix returned %v, want ErrObjectNotFound", err)
+		}
+	})
+
+	t.Run("missing objects", func(t *testing.T) {
+		if _, err := store.Get(ctx, bucket, "missing"); !errors.Is(err, objstore.ErrObjectNotFound) {
+			t.Errorf("Get returned %v, want ErrObjectNotFound", err)
+		}
+		if _, err := store.Stat(ctx, bucket, "missing"); !errors.Is(err, objstore.ErrObjectNotFound) {
+			t.Errorf("Stat returned %v, want ErrObjectNotFound", err)
+		}
+		if err := store.ReplaceMetadata(ctx, bucket, "missing", nil); !errors.Is(err, objstore.ErrObjectNotFound) {
+			t.Errorf("ReplaceMetadata returned %v, want ErrObjectNotFound", err)
+		}
+		if err := store.Copy(ctx, bucket, "missing", otherBucket); !errors.Is(err, objstore.ErrObjectNotFound) {
+			t.Errorf("Copy returned %v, want ErrObjectNotFound", err)
+		}
+		if err := store.Delete(ctx, bucket, "missing"); err != nil {
+			t.Errorf("Delete returned %v, want nil", err)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		put(t, "delete", data, "text/plain", map[string]string{"Alya-Owner": "batch"})
+		if err := store.Delete(ctx, bucket, "delete"); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		if _, err := store.Stat(ctx, bucket, "delete"); !errors.Is(err, objstore.ErrObjectNotFound) {
+			t.Errorf("Stat after Delete returned %v, want ErrObjectNotFound", err)
+		}
+		// A new object of the same name does not inherit the old metadata
+		if err := store.Put(ctx, bucket, "delete", strings.NewReader("x"), 1, "text/plain"); err != nil {
+			t.Fatalf("Put failed: %v", err)
+		}
+		if attrs := stat(t, bucket, "delete"); len(attrs.Metadata) != 0 {
+			t.Errorf("Metadata of a new object = %v, want none", attrs.Metadata)
+		}
+	})
+
+	t.Run("multipart upload", func(t *testing.T) {
+		first := bytes.Repeat([]byte("a"), objstore.MinPartSize)
+		uploadID, err := store.NewMultipartUpload(ctx, bucket, "multi", "text/csv", map[string]string{"Alya-Owner": "batch"})
+		if err != nil {
+			t.Fatalf("NewMultipartUpload failed: %v", err)
+		}
+		// Parts may come in any order, and a part replaces one of the same number
+		part2, err := store.PutPart(ctx, bucket, "multi", uploadID, 2, strings.NewReader("old"), 3)
+		if err != nil {
+			t.Fatalf("PutPart(2) failed: %v", err)
+		}
+		if part2, err = store.PutPart(ctx, bucket, "multi", uploadID, 2, strings.NewReader("last"), 4); err != nil {
+			t.Fatalf("PutPart(2) again failed: %v", err)
+		}
+		part1, err := store.PutPart(ctx, bucket, "multi", uploadID, 1, bytes.NewReader(first), int64(len(first)))
+		if err != nil {
+			t.Fatalf("PutPart(1) failed: %v", err)
+		}
+		if part1.Number != 1 || part1.Size != int64(len(first)) || part1.ETag == "" || part2.Size != 4 {
+			t.Errorf("PutPart returned %+v and %+v", part1, part2)
+		}
+		if _, err := store.Stat(ctx, bucket, "multi"); !errors.Is(err, objstore.ErrObjectNotFound) {
+			t.Errorf("Stat before completion returned %v, want ErrObjectNotFound", err)
+		}
+
+		if err := store.CompleteMultipartUpload(ctx, bucket, "multi", uploadID, []objstore.Part{part1, part2}); err != nil {
+			t.Fatalf("CompleteMultipartUpload failed: %v", err)
+		}
+		if got := get(t, bucket, "multi"); !bytes.Equal(got, append(first, "last"...)) {
+			t.Errorf("Completed object has %d bytes, want %d", len(got), len(first)+4)
+		}
+		attrs := stat(t, bucket, "multi")
+		if attrs.ContentType != "text/csv" || !equalMetadata(attrs.Metadata, map[string]string{"Alya-Owner": "batch"}) {
+			t.Errorf("Stat of the completed object = %+v", attrs)
+		}
+		if _, err := store.PutPart(ctx, bucket, "multi", uploadID, 3, strings.NewReader("x"), 1); !errors.Is(err, objstore.ErrUploadNotFound) {
+			t.Errorf("PutPart after completion returned %v, want ErrUploadNotFound", err)
+		}
+	})
+
+	t.Run("multipart upload with small parts", func(t *testing.T) {
+		uploadID, err := store.NewMultipartUpload(ctx, bucket, "small", "", nil)
+		if err != nil {
+			t.Fatalf("NewMultipartUpload failed: %v", err)
+		}
+		part1, err := store.PutPart(ctx, bucket, "small", uploadID, 1, strings.NewReader("first"), 5)
+		if err != nil {
+			t.Fatalf("PutPart(1) failed: %v", err)
+		}
+		part2, err := store.PutPart(ctx, bucket, "small", uploadID, 2, strings.NewReader("second"), 6)
+		if err != nil {
+			t.Fatalf("PutPart(2) failed: %v", err)
+		}
+		err = store.CompleteMultipartUpload(ctx, bucket, "small", uploadID, []objstore.Part{part1, part2})
+		if !errors.Is(err, objstore.ErrInvalidPart) {
+			t.Errorf("CompleteMultipartUpload with a small first part returned %v, want ErrInvalidPart", err)
+		}
+
+		if err := store.AbortMultipartUpload(ctx, bucket, "small", uploadID); err != nil {
+			t.Fatalf("AbortMultipartUpload failed: %v", err)
+		}
+		if _, err := store.PutPart(ctx, bucket, "small", uploadID, 3, strings.NewReader("x"), 1); !errors.Is(err, objstore.ErrUploadNotFound) {
+			t.Errorf("PutPart after abort returned %v, want ErrUploadNotFound", err)
+		}
+		if _, err := store.Stat(ctx, bucket, "small"); !errors.Is(err, objstore.ErrObjectNotFound) {
+			t.Errorf("Stat of an aborted upload returned %v, want ErrObjectNotFound", err)
+		}
+	})
+
+	t.Run("object functions", func(t *testing.T) {
+		id, nbytes, err := objstore.ObjectNew(ctx, store, data, bucket, `{"ownerclass": "batch"}`, false, true)
+		if err != nil {
+			t.Fatalf("ObjectNew failed: %v", err)
+		}
+		if err := objstore.ObjectMove(ctx, store, id, bucket, otherBucket); err != nil {
+			t.Fatalf("ObjectMove failed: %v", err)
+		}
+		info, size, isCompressed, err := objstore.ObjectInfo(ctx, store, id, otherBucket)
+		if err != nil || info != `{"ownerclass": "batch"}` || size != nbytes || !isCompressed {
+			t.Errorf("ObjectInfo = %q, %d, %v, %v", info, size, isCompressed, err)
+		}
+		got, err := objstore.ObjectGet(ctx, store, id, otherBucket)
+		if err != nil || !bytes.Equal(got, data) {
+			t.Errorf("ObjectGet = %q, %v", got, err)
+		}
+	})
+}
+
+// equalMetadata reports whether two metadata maps are equal, treating nil as empty.
+func equalMetadata(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if w, ok := b[k]; !ok || v != w {
+			return false
+		}
+	}
+	return true
+}
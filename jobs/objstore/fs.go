@@ -0,0 +1,486 @@
+package objstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrInvalidObjectName = errors.New("invalid bucket or object name")
+
+// Directories under the root of an FSObjStore that are not buckets. Bucket names
+// can't start with a dot, as on MinIO, so these never clash with one.
+const (
+	fsMetaDir    = ".meta"
+	fsTmpDir     = ".tmp"
+	fsUploadsDir = ".uploads"
+)
+
+// FSObjStore is an implementation of ObjectStore on a local directory, for
+// development machines and installs without an object store service. Each bucket
+// is a directory under the root, created as objects are put in it, and holds each
+// object as a file with the object's name; slashes in the name make subdirectories.
+// The content type and user metadata of an object are kept in a sidecar JSON file
+// of the same name under root/.meta/<bucket>, and multipart uploads in progress in
+// a directory each under root/.uploads.
+//
+// Objects are written to a temporary file and renamed into place, so readers never
+// see a partly written object. Within a process, an object's data and sidecar are
+// also updated together; processes sharing a directory may briefly see the new data
+// of an object with its old metadata.
+type FSObjStore struct {
+	root string
+	mu   sync.RWMutex
+}
+
+// fsUpload is the description of a multipart upload in progress, kept in
+// root/.uploads/<upload ID>/upload.json next to its parts.
+type fsUpload struct {
+	Bucket string `json:"bucket"`
+	Obj    string `json:"obj"`
+	fsMeta
+}
+
+// fsMeta is the sidecar file of an object.
+type fsMeta struct {
+	ContentType  string            `json:"contenttype"`
+	LastModified time.Time         `json:"lastmodified"`
+	Metadata     map[string]string `json:"metadata"`
+}
+
+// NewFSObjectStore creates an FSObjStore on root, creating the directory if needed
+func NewFSObjectStore(root string) (*FSObjStore, error) {
+	for _, dir := range []string{root, filepath.Join(root, fsMetaDir), filepath.Join(root, fsTmpDir), filepath.Join(root, fsUploadsDir)} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create object store directory: %w", err)
+		}
+	}
+	return &FSObjStore{root: root}, nil
+}
+
+// Put stores an object, replacing any object of the same name. If size is not -1,
+// reader must hold exactly size bytes.
+func (s *FSObjStore) Put(ctx context.Context, bucket, obj string, reader io.Reader, size int64, contentType string) error {
+	return s.PutWithMetadata(ctx, bucket, obj, reader, size, contentType, nil)
+}
+
+// PutWithMetadata stores an object with user metadata, like Put.
+func (s *FSObjStore) PutWithMetadata(ctx context.Context, bucket, obj string, reader io.Reader, size int64, contentType string, metadata map[string]string) error {
+	dataPath, metaPath, err := s.paths(bucket, obj)
+	if err != nil {
+		return err
+	}
+	if contentType == "" {
+		contentType = defaultContentType
+	}
+
+	// Write the data outside the lock, as the reader may be slow
+	tmpData, err := s.writeTemp(func(f *os.File) error {
+		n, err := io.Copy(f, reader)
+		if err != nil {
+			return err
+		}
+		if size >= 0 && n != size {
+			return fmt.Errorf("object %s/%s has %d bytes, not %d", bucket, obj, n, size)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpData)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	meta := fsMeta{ContentType: contentType, LastModified: time.Now().UTC(), Metadata: canonicalMetadata(metadata)}
+	return s.commit(tmpData, dataPath, metaPath, meta)
+}
+
+// Get returns a reader of an object, or ErrObjectNotFound
+func (s *FSObjStore) Get(ctx context.Context, bucket, obj string) (io.ReadCloser, error) {
+	dataPath, _, err := s.paths(bucket, obj)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	// The open file keeps its data even if the object is replaced or deleted
+	f, err := os.Open(dataPath)
+	if err != nil {
+		return nil, fsError(err, bucket, obj)
+	}
+	if fi, err := f.Stat(); err != nil || fi.IsDir() {
+		// A directory is a prefix of other objects' names, not an object
+		f.Close()
+		return nil, fmt.Errorf("%w: %s/%s", ErrObjectNotFound, bucket, obj)
+	}
+	return f, nil
+}
+
+// Delete removes an object. Removing an object that does not exist is not an error,
+// as with MinIO.
+func (s *FSObjStore) Delete(ctx context.Context, bucket, obj string) error {
+	dataPath, metaPath, err := s.paths(bucket, obj)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if fi, err := os.Stat(dataPath); err == nil && fi.IsDir() {
+		return nil
+	}
+	for _, path := range []string{dataPath, metaPath} {
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to delete object %s/%s: %w", bucket, obj, err)
+		}
+	}
+	return nil
+}
+
+// Stat returns the attributes and user metadata of an object, or ErrObjectNotFound
+func (s *FSObjStore) Stat(ctx context.Context, bucket, obj string) (ObjectAttrs, error) {
+	dataPath, metaPath, err := s.paths(bucket, obj)
+	if err != nil {
+		return ObjectAttrs{}, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	fi, meta, err := s.stat(dataPath, metaPath)
+	if err != nil {
+		return ObjectAttrs{}, fsError(err, bucket, obj)
+	}
+	return ObjectAttrs{
+		Size:         fi.Size(),
+		ContentType:  meta.ContentType,
+		LastModified: meta.LastModified,
+		Metadata:     maps.Clone(meta.Metadata),
+	}, nil
+}
+
+// ReplaceMetadata replaces the user metadata of an object
+func (s *FSObjStore) ReplaceMetadata(ctx context.Context, bucket, obj string, metadata map[string]string) error {
+	dataPath, metaPath, err := s.paths(bucket, obj)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, meta, err := s.stat(dataPath, metaPath)
+	if err != nil {
+		return fsError(err, bucket, obj)
+	}
+	meta.Metadata = canonicalMetadata(metadata)
+	meta.LastModified = time.Now().UTC()
+	return s.writeMeta(metaPath, meta)
+}
+
+// Copy copies an object to another bucket
+func (s *FSObjStore) Copy(ctx context.Context, srcBucket, obj, dstBucket string) error {
+	srcData, srcMeta, err := s.paths(srcBucket, obj)
+	if err != nil {
+		return err
+	}
+	dstData, dstMeta, err := s.paths(dstBucket, obj)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, meta, err := s.stat(srcData, srcMeta)
+	if err != nil {
+		return fsError(err, srcBucket, obj)
+	}
+	src, err := os.Open(srcData)
+	if err != nil {
+		return fsError(err, srcBucket, obj)
+	}
+	defer src.Close()
+	tmpData, err := s.writeTemp(func(f *os.File) error {
+		_, err := io.Copy(f, src)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpData)
+
+	meta.LastModified = time.Now().UTC()
+	return s.commit(tmpData, dstData, dstMeta, meta)
+}
+
+// NewMultipartUpload starts a multipart upload, in a directory of its own
+func (s *FSObjStore) NewMultipartUpload(ctx context.Context, bucket, obj, contentType string, metadata map[string]string) (string, error) {
+	if _, _, err := s.paths(bucket, obj); err != nil {
+		return "", err
+	}
+	if contentType == "" {
+		contentType = defaultContentType
+	}
+	uploadID := newUploadID()
+	b, err := json.Marshal(fsUpload{
+		Bucket: bucket,
+		Obj:    obj,
+		fsMeta: fsMeta{ContentType: contentType, Metadata: canonicalMetadata(metadata)},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal upload: %w", err)
+	}
+	dir := filepath.Join(s.root, fsUploadsDir, uploadID)
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create upload directory: %w", err)
+	}
+	if err := s.writeFile(filepath.Join(dir, "upload.json"), b); err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+	return uploadID, nil
+}
+
+// PutPart uploads a part of a multipart upload, as a file and its description
+func (s *FSObjStore) PutPart(ctx context.Context, bucket, obj, uploadID string, number int, reader io.Reader, size int64) (Part, error) {
+	data, part, err := readPart(number, reader, size)
+	if err != nil {
+		return Part{}, err
+	}
+	b, err := json.Marshal(part)
+	if err != nil {
+		return Part{}, fmt.Errorf("failed to marshal part: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dir, _, err := s.upload(bucket, obj, uploadID)
+	if err != nil {
+		return Part{}, err
+	}
+	name := strconv.Itoa(number)
+	if err := s.writeFile(filepath.Join(dir, name), data); err != nil {
+		return Part{}, err
+	}
+	if err := s.writeFile(filepath.Join(dir, name+".json"), b); err != nil {
+		return Part{}, err
+	}
+	return part, nil
+}
+
+// CompleteMultipartUpload concatenates the parts of a multipart upload into its object
+func (s *FSObjStore) CompleteMultipartUpload(ctx context.Context, bucket, obj, uploadID string, parts []Part) error {
+	dataPath, metaPath, err := s.paths(bucket, obj)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dir, upload, err := s.upload(bucket, obj, uploadID)
+	if err != nil {
+		return err
+	}
+	uploaded := make(map[int]Part, len(parts))
+	for _, p := range parts {
+		b, err := os.ReadFile(filepath.Join(dir, strconv.Itoa(p.Number)+".json"))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		var part Part
+		if err := json.Unmarshal(b, &part); err != nil {
+			return fmt.Errorf("invalid part file: %w", err)
+		}
+		uploaded[p.Number] = part
+	}
+	if err := checkParts(uploaded, parts); err != nil {
+		return err
+	}
+
+	tmpData, err := s.writeTemp(func(f *os.File) error {
+		for _, p := range parts {
+			part, err := os.Open(filepath.Join(dir, strconv.Itoa(p.Number)))
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(f, part)
+			part.Close()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpData)
+
+	meta := upload.fsMeta
+	meta.LastModified = time.Now().UTC()
+	if err := s.commit(tmpData, dataPath, metaPath, meta); err != nil {
+		return err
+	}
+	return os.RemoveAll(dir)
+}
+
+// AbortMultipartUpload removes a multipart upload and its parts
+func (s *FSObjStore) AbortMultipartUpload(ctx context.Context, bucket, obj, uploadID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dir, _, err := s.upload(bucket, obj, uploadID)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(dir)
+}
+
+// upload returns the directory and description of a multipart upload of the object,
+// or ErrUploadNotFound. The caller holds s.mu.
+func (s *FSObjStore) upload(bucket, obj, uploadID string) (string, fsUpload, error) {
+	notFound := fmt.Errorf("%w: %s/%s", ErrUploadNotFound, bucket, obj)
+	if _, err := uuid.Parse(uploadID); err != nil {
+		return "", fsUpload{}, notFound
+	}
+	dir := filepath.Join(s.root, fsUploadsDir, uploadID)
+	b, err := os.ReadFile(filepath.Join(dir, "upload.json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", fsUpload{}, notFound
+	} else if err != nil {
+		return "", fsUpload{}, err
+	}
+	var upload fsUpload
+	if err := json.Unmarshal(b, &upload); err != nil {
+		return "", fsUpload{}, fmt.Errorf("invalid upload file: %w", err)
+	}
+	if upload.Bucket != bucket || upload.Obj != obj {
+		return "", fsUpload{}, notFound
+	}
+	return dir, upload, nil
+}
+
+// paths returns the paths of the data and sidecar files of an object, after checking
+// that the names stay inside the bucket.
+func (s *FSObjStore) paths(bucket, obj string) (dataPath, metaPath string, err error) {
+	if bucket == "" || strings.HasPrefix(bucket, ".") || strings.ContainsAny(bucket, `/\`) {
+		return "", "", fmt.Errorf("%w: bucket %q", ErrInvalidObjectName, bucket)
+	}
+	for _, elem := range strings.Split(obj, "/") {
+		if elem == "" || elem == "." || elem == ".." || strings.Contains(elem, `\`) {
+			return "", "", fmt.Errorf("%w: object %q", ErrInvalidObjectName, obj)
+		}
+	}
+	name := filepath.FromSlash(obj)
+	return filepath.Join(s.root, bucket, name), filepath.Join(s.root, fsMetaDir, bucket, name), nil
+}
+
+// stat returns the file info of an object's data and its sidecar. Objects put in
+// the bucket directory by hand have no sidecar, and get the default attributes.
+func (s *FSObjStore) stat(dataPath, metaPath string) (fs.FileInfo, fsMeta, error) {
+	fi, err := os.Stat(dataPath)
+	if err != nil {
+		return nil, fsMeta{}, err
+	}
+	if fi.IsDir() {
+		return nil, fsMeta{}, fs.ErrNotExist
+	}
+	meta := fsMeta{ContentType: defaultContentType, LastModified: fi.ModTime().UTC(), Metadata: map[string]string{}}
+	b, err := os.ReadFile(metaPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fi, meta, nil
+	} else if err != nil {
+		return nil, fsMeta{}, err
+	}
+	if err := json.Unmarshal(b, &meta); err != nil {
+		return nil, fsMeta{}, fmt.Errorf("invalid metadata file %s: %w", metaPath, err)
+	}
+	if meta.Metadata == nil {
+		meta.Metadata = map[string]string{}
+	}
+	return fi, meta, nil
+}
+
+// commit moves the data written to tmpData into place, with its sidecar. The sidecar
+// is written first, so that an object whose data is in place always has one.
+func (s *FSObjStore) commit(tmpData, dataPath, metaPath string, meta fsMeta) error {
+	if err := s.writeMeta(metaPath, meta); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dataPath), 0o755); err != nil {
+		return fmt.Errorf("failed to create bucket directory: %w", err)
+	}
+	if err := os.Rename(tmpData, dataPath); err != nil {
+		return fmt.Errorf("failed to store object: %w", err)
+	}
+	return nil
+}
+
+// writeMeta replaces the sidecar file of an object.
+func (s *FSObjStore) writeMeta(metaPath string, meta fsMeta) error {
+	b, err := json.Marshal(meta)
+	if err != nil {
+		return fmt.Errorf("failed to marshal object metadata: %w", err)
+	}
+	return s.writeFile(metaPath, b)
+}
+
+// writeFile atomically replaces the file at path with data, creating its directory
+// if needed.
+func (s *FSObjStore) writeFile(path string, data []byte) error {
+	tmp, err := s.writeTemp(func(f *os.File) error {
+		_, err := f.Write(data)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// writeTemp creates a temporary file under the root, on the same filesystem as the
+// buckets so that it can be renamed into one, and fills it with write. It returns the
+// path of the file, which the caller removes if it is not renamed.
+func (s *FSObjStore) writeTemp(write func(f *os.File) error) (string, error) {
+	f, err := os.CreateTemp(filepath.Join(s.root, fsTmpDir), "obj-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	err = write(f)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// fsError returns ErrObjectNotFound for the errors about a missing file.
+func fsError(err error, bucket, obj string) error {
+	if errors.Is(err, fs.ErrNotExist) || errors.Is(err, syscall.ENOTDIR) {
+		return fmt.Errorf("%w: %s/%s", ErrObjectNotFound, bucket, obj)
+	}
+	return err
+}
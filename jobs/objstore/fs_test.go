@@ -0,0 +1,93 @@
+package objstore_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/remiges-tech/alya/jobs/objstore"
+)
+
+func TestFSObjStore_Conformance(t *testing.T) {
+	store, err := objstore.NewFSObjectStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFSObjectStore failed: %v", err)
+	}
+	testConformance(t, store, "bucket", "otherbucket")
+}
+
+func TestFSObjStore(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	store, err := objstore.NewFSObjectStore(root)
+	if err != nil {
+		t.Fatalf("NewFSObjectStore failed: %v", err)
+	}
+
+	t.Run("buckets are directories", func(t *testing.T) {
+		if err := store.Put(ctx, "bucket", "app/out.txt", strings.NewReader("data"), 4, "text/plain"); err != nil {
+			t.Fatalf("Put failed: %v", err)
+		}
+		got, err := os.ReadFile(filepath.Join(root, "bucket", "app", "out.txt"))
+		if err != nil || string(got) != "data" {
+			t.Errorf("Object file = %q, %v", got, err)
+		}
+	})
+
+	t.Run("failed puts leave nothing behind", func(t *testing.T) {
+		if err := store.Put(ctx, "bucket", "short", strings.NewReader("data"), 10, "text/plain"); err == nil {
+			t.Fatalf("Put of fewer bytes than its size succeeded")
+		}
+		if _, err := os.Stat(filepath.Join(root, "bucket", "short")); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("Failed put left an object file: %v", err)
+		}
+		tmp, err := os.ReadDir(filepath.Join(root, ".tmp"))
+		if err != nil || len(tmp) != 0 {
+			t.Errorf("Temporary files left behind: %v, %v", tmp, err)
+		}
+	})
+
+	t.Run("files without sidecar", func(t *testing.T) {
+		if err := os.WriteFile(filepath.Join(root, "bucket", "dropped.csv"), []byte("a,b"), 0o644); err != nil {
+			t.Fatalf("WriteFile failed: %v", err)
+		}
+		attrs, err := store.Stat(ctx, "bucket", "dropped.csv")
+		if err != nil {
+			t.Fatalf("Stat failed: %v", err)
+		}
+		if attrs.Size != 3 || attrs.ContentType != "application/octet-stream" || len(attrs.Metadata) != 0 {
+			t.Errorf("Stat = %+v", attrs)
+		}
+	})
+
+	t.Run("names stay inside the bucket", func(t *testing.T) {
+		for _, name := range []string{"", "../escape", "a/../../escape", "/abs", "a//b", "a/"} {
+			if err := store.Put(ctx, "bucket", name, strings.NewReader("x"), 1, ""); !errors.Is(err, objstore.ErrInvalidObjectName) {
+				t.Errorf("Put(%q) returned %v, want ErrInvalidObjectName", name, err)
+			}
+		}
+		for _, bucket := range []string{"", ".meta", "a/b", ".."} {
+			if _, err := store.Stat(ctx, bucket, "obj"); !errors.Is(err, objstore.ErrInvalidObjectName) {
+				t.Errorf("Stat in bucket %q returned %v, want ErrInvalidObjectName", bucket, err)
+			}
+		}
+	})
+
+	t.Run("objects persist across instances", func(t *testing.T) {
+		err := store.PutWithMetadata(ctx, "bucket", "kept", strings.NewReader("x"), 1, "text/plain", map[string]string{"Alya-Owner": "batch"})
+		if err != nil {
+			t.Fatalf("PutWithMetadata failed: %v", err)
+		}
+		reopened, err := objstore.NewFSObjectStore(root)
+		if err != nil {
+			t.Fatalf("NewFSObjectStore failed: %v", err)
+		}
+		attrs, err := reopened.Stat(ctx, "bucket", "kept")
+		if err != nil || attrs.ContentType != "text/plain" || attrs.Metadata["Alya-Owner"] != "batch" {
+			t.Errorf("Stat after reopening = %+v, %v", attrs, err)
+		}
+	})
+}
@@ -0,0 +1,248 @@
+package objstore
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"net/textproto"
+	"sync"
+	"time"
+)
+
+// MemObjStore is an implementation of ObjectStore that keeps the objects in memory,
+// for tests and single-node tools. Buckets are created as objects are put in them.
+type MemObjStore struct {
+	mu      sync.RWMutex
+	buckets map[string]map[string]memObject
+	uploads map[string]*memUpload // by upload ID
+}
+
+// memObject is an object of a MemObjStore. Its data and metadata are never written
+// to once stored.
+type memObject struct {
+	data         []byte
+	contentType  string
+	lastModified time.Time
+	metadata     map[string]string
+}
+
+// memUpload is a multipart upload in progress in a MemObjStore.
+type memUpload struct {
+	bucket      string
+	obj         string
+	contentType string
+	metadata    map[string]string
+	parts       map[int]Part
+	data        map[int][]byte
+}
+
+// NewMemObjectStore creates a new, empty instance of MemObjStore
+func NewMemObjectStore() *MemObjStore {
+	return &MemObjStore{
+		buckets: make(map[string]map[string]memObject),
+		uploads: make(map[string]*memUpload),
+	}
+}
+
+// Put stores an object, replacing any object of the same name. If size is not -1,
+// reader must hold exactly size bytes.
+func (s *MemObjStore) Put(ctx context.Context, bucket, obj string, reader io.Reader, size int64, contentType string) error {
+	return s.PutWithMetadata(ctx, bucket, obj, reader, size, contentType, nil)
+}
+
+// PutWithMetadata stores an object with user metadata, like Put.
+func (s *MemObjStore) PutWithMetadata(ctx context.Context, bucket, obj string, reader io.Reader, size int64, contentType string, metadata map[string]string) error {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	if size >= 0 && int64(len(data)) != size {
+		return fmt.Errorf("object %s/%s has %d bytes, not %d", bucket, obj, len(data), size)
+	}
+
+	if contentType == "" {
+		contentType = defaultContentType
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.buckets[bucket] == nil {
+		s.buckets[bucket] = make(map[string]memObject)
+	}
+	s.buckets[bucket][obj] = memObject{
+		data:         data,
+		contentType:  contentType,
+		lastModified: time.Now(),
+		metadata:     canonicalMetadata(metadata),
+	}
+	return nil
+}
+
+// Get returns a reader of an object, or ErrObjectNotFound
+func (s *MemObjStore) Get(ctx context.Context, bucket, obj string) (io.ReadCloser, error) {
+	o, err := s.object(bucket, obj)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(o.data)), nil
+}
+
+// Delete removes an object. Removing an object that does not exist is not an error,
+// as with MinIO.
+func (s *MemObjStore) Delete(ctx context.Context, bucket, obj string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.buckets[bucket], obj)
+	return nil
+}
+
+// Stat returns the attributes and user metadata of an object, or ErrObjectNotFound
+func (s *MemObjStore) Stat(ctx context.Context, bucket, obj string) (ObjectAttrs, error) {
+	o, err := s.object(bucket, obj)
+	if err != nil {
+		return ObjectAttrs{}, err
+	}
+	return ObjectAttrs{
+		Size:         int64(len(o.data)),
+		ContentType:  o.contentType,
+		LastModified: o.lastModified,
+		Metadata:     maps.Clone(o.metadata),
+	}, nil
+}
+
+// ReplaceMetadata replaces the user metadata of an object
+func (s *MemObjStore) ReplaceMetadata(ctx context.Context, bucket, obj string, metadata map[string]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	o, ok := s.buckets[bucket][obj]
+	if !ok {
+		return fmt.Errorf("%w: %s/%s", ErrObjectNotFound, bucket, obj)
+	}
+	o.metadata = canonicalMetadata(metadata)
+	o.lastModified = time.Now()
+	s.buckets[bucket][obj] = o
+	return nil
+}
+
+// Copy copies an object to another bucket
+func (s *MemObjStore) Copy(ctx context.Context, srcBucket, obj, dstBucket string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	o, ok := s.buckets[srcBucket][obj]
+	if !ok {
+		return fmt.Errorf("%w: %s/%s", ErrObjectNotFound, srcBucket, obj)
+	}
+	if s.buckets[dstBucket] == nil {
+		s.buckets[dstBucket] = make(map[string]memObject)
+	}
+	o.lastModified = time.Now()
+	s.buckets[dstBucket][obj] = o
+	return nil
+}
+
+// NewMultipartUpload starts a multipart upload
+func (s *MemObjStore) NewMultipartUpload(ctx context.Context, bucket, obj, contentType string, metadata map[string]string) (string, error) {
+	if contentType == "" {
+		contentType = defaultContentType
+	}
+	uploadID := newUploadID()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.uploads[uploadID] = &memUpload{
+		bucket:      bucket,
+		obj:         obj,
+		contentType: contentType,
+		metadata:    canonicalMetadata(metadata),
+		parts:       make(map[int]Part),
+		data:        make(map[int][]byte),
+	}
+	return uploadID, nil
+}
+
+// PutPart uploads a part of a multipart upload
+func (s *MemObjStore) PutPart(ctx context.Context, bucket, obj, uploadID string, number int, reader io.Reader, size int64) (Part, error) {
+	data, part, err := readPart(number, reader, size)
+	if err != nil {
+		return Part{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, err := s.upload(bucket, obj, uploadID)
+	if err != nil {
+		return Part{}, err
+	}
+	u.parts[number] = part
+	u.data[number] = data
+	return part, nil
+}
+
+// CompleteMultipartUpload assembles the parts of a multipart upload into its object
+func (s *MemObjStore) CompleteMultipartUpload(ctx context.Context, bucket, obj, uploadID string, parts []Part) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, err := s.upload(bucket, obj, uploadID)
+	if err != nil {
+		return err
+	}
+	if err := checkParts(u.parts, parts); err != nil {
+		return err
+	}
+	var data []byte
+	for _, p := range parts {
+		data = append(data, u.data[p.Number]...)
+	}
+	if s.buckets[bucket] == nil {
+		s.buckets[bucket] = make(map[string]memObject)
+	}
+	s.buckets[bucket][obj] = memObject{
+		data:         data,
+		contentType:  u.contentType,
+		lastModified: time.Now(),
+		metadata:     u.metadata,
+	}
+	delete(s.uploads, uploadID)
+	return nil
+}
+
+// AbortMultipartUpload discards a multipart upload
+func (s *MemObjStore) AbortMultipartUpload(ctx context.Context, bucket, obj, uploadID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.upload(bucket, obj, uploadID); err != nil {
+		return err
+	}
+	delete(s.uploads, uploadID)
+	return nil
+}
+
+// upload returns a multipart upload of the object, or ErrUploadNotFound. The caller
+// holds s.mu.
+func (s *MemObjStore) upload(bucket, obj, uploadID string) (*memUpload, error) {
+	u, ok := s.uploads[uploadID]
+	if !ok || u.bucket != bucket || u.obj != obj {
+		return nil, fmt.Errorf("%w: %s/%s", ErrUploadNotFound, bucket, obj)
+	}
+	return u, nil
+}
+
+func (s *MemObjStore) object(bucket, obj string) (memObject, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	o, ok := s.buckets[bucket][obj]
+	if !ok {
+		return memObject{}, fmt.Errorf("%w: %s/%s", ErrObjectNotFound, bucket, obj)
+	}
+	return o, nil
+}
+
+// canonicalMetadata returns a copy of metadata with its keys in canonical MIME
+// header form, as MinIO returns them.
+func canonicalMetadata(metadata map[string]string) map[string]string {
+	c := make(map[string]string, len(metadata))
+	for k, v := range metadata {
+		c[textproto.CanonicalMIMEHeaderKey(k)] = v
+	}
+	return c
+}
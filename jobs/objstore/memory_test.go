@@ -0,0 +1,54 @@
+package objstore_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/remiges-tech/alya/jobs/objstore"
+)
+
+func TestMemObjStore(t *testing.T) {
+	ctx := context.Background()
+	store := objstore.NewMemObjectStore()
+	data := []byte("Hello, World!")
+
+	if err := store.Put(ctx, "bucket", "obj", bytes.NewReader(data), int64(len(data)), "text/plain"); err != nil {
+		t.Fatalf("Failed to put object: %v", err)
+	}
+	if err := store.Put(ctx, "bucket", "short", bytes.NewReader(data), 100, "text/plain"); err == nil {
+		t.Errorf("Put of an object shorter than its size succeeded")
+	}
+
+	reader, err := store.Get(ctx, "bucket", "obj")
+	if err != nil {
+		t.Fatalf("Failed to get object: %v", err)
+	}
+	got, err := io.ReadAll(reader)
+	reader.Close()
+	if err != nil {
+		t.Fatalf("Failed to read object: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("Object data mismatch: got %q, want %q", got, data)
+	}
+
+	if err := store.Delete(ctx, "bucket", "obj"); err != nil {
+		t.Fatalf("Failed to delete object: %v", err)
+	}
+	if err := store.Delete(ctx, "bucket", "obj"); err != nil {
+		t.Errorf("Deleting a missing object failed: %v", err)
+	}
+	if _, err := store.Get(ctx, "bucket", "obj"); !errors.Is(err, objstore.ErrObjectNotFound) {
+		t.Errorf("Get of a deleted object returned %v, want ErrObjectNotFound", err)
+	}
+	if _, err := store.Get(ctx, "nobucket", "obj"); !errors.Is(err, objstore.ErrObjectNotFound) {
+		t.Errorf("Get from a missing bucket returned %v, want ErrObjectNotFound", err)
+	}
+}
+
+func TestMemObjStore_Conformance(t *testing.T) {
+	testConformance(t, objstore.NewMemObjectStore(), "bucket", "otherbucket")
+}
@@ -0,0 +1,57 @@
+package objstore
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+
+	"github.com/google/uuid"
+)
+
+// maxPartNumber is the highest part number of a multipart upload, as on S3
+const maxPartNumber = 10000
+
+// newUploadID returns the ID of a new multipart upload in the memory and filesystem
+// stores.
+func newUploadID() string {
+	return uuid.New().String()
+}
+
+// readPart reads a part of a multipart upload, checking its number and size, and
+// returns its data and its description.
+func readPart(number int, reader io.Reader, size int64) ([]byte, Part, error) {
+	if number < 1 || number > maxPartNumber {
+		return nil, Part{}, fmt.Errorf("%w: number %d is not between 1 and %d", ErrInvalidPart, number, maxPartNumber)
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, Part{}, err
+	}
+	if size >= 0 && int64(len(data)) != size {
+		return nil, Part{}, fmt.Errorf("part %d has %d bytes, not %d", number, len(data), size)
+	}
+	sum := md5.Sum(data)
+	return data, Part{Number: number, ETag: hex.EncodeToString(sum[:]), Size: int64(len(data))}, nil
+}
+
+// checkParts checks the parts listed to complete a multipart upload against the
+// parts uploaded, as S3 does.
+func checkParts(uploaded map[int]Part, parts []Part) error {
+	if len(parts) == 0 {
+		return fmt.Errorf("%w: no parts", ErrInvalidPart)
+	}
+	for i, p := range parts {
+		u, ok := uploaded[p.Number]
+		if !ok || u.ETag != p.ETag {
+			return fmt.Errorf("%w: part %d was not uploaded", ErrInvalidPart, p.Number)
+		}
+		if i > 0 && p.Number <= parts[i-1].Number {
+			return fmt.Errorf("%w: parts are not in ascending order", ErrInvalidPart)
+		}
+		if i < len(parts)-1 && u.Size < MinPartSize {
+			return fmt.Errorf("%w: part %d has %d bytes, less than %d", ErrInvalidPart, p.Number, u.Size, MinPartSize)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,318 @@
+package objstore
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// The functions in this file are the object module of the wiki (see "Object
+// Metadata" in the README): objects carry an info JSON block, their uncompressed
+// size, when they were stored and whether they are compressed, all kept in the
+// user metadata of the object in the underlying ObjectStore.
+
+// User metadata keys of the objects stored by this module
+const (
+	MetaInfo       = "Alya-Info"       // The info block, base64-encoded
+	MetaSize       = "Alya-Size"       // Uncompressed size in bytes
+	MetaStoredAt   = "Alya-Stored-At"  // RFC 3339, UTC
+	MetaCompressed = "Alya-Compressed" // "true" or "false"
+)
+
+// MaxInfoSize is the largest info block accepted, in bytes. Object stores limit the
+// total size of the user metadata of an object (2 KB on S3), and the block grows by
+// a third when base64-encoded.
+const MaxInfoSize = 1024
+
+const compressedContentType = "application/gzip"
+
+var (
+	ErrInvalidInfo  = errors.New("info is not a JSON object")
+	ErrInfoTooLarge = errors.New("info is too large")
+	ErrInvalidBlob  = errors.New("blob is not valid base64")
+)
+
+// Info holds the suggested attributes of an info block. The module does not look at
+// the info block beyond its mimetype, which becomes the content type of uncompressed
+// objects; applications may put any other attributes in it.
+type Info struct {
+	MimeType   string `json:"mimetype,omitempty"`
+	OwnerClass string `json:"ownerclass,omitempty"`
+	OwnerID    string `json:"ownerid,omitempty"`
+	Filename   string `json:"filename,omitempty"`
+	From       string `json:"from,omitempty"`
+}
+
+// String returns the info block as a JSON string.
+func (i Info) String() string {
+	b, _ := json.Marshal(i)
+	return string(b)
+}
+
+// ObjectNew stores blob in bucket under a new unique ID, with the info block. If
+// isEncoded is true, blob is base64-decoded first; if compress is true, it is then
+// gzip-compressed. nbytes is the size of the blob before compression.
+func ObjectNew(ctx context.Context, store ObjectStore, blob []byte, bucket, info string, isEncoded, compress bool) (id string, nbytes int, err error) {
+	info, err = checkInfo(info)
+	if err != nil {
+		return "", 0, err
+	}
+
+	if isEncoded {
+		decoded := make([]byte, base64.StdEncoding.DecodedLen(len(blob)))
+		n, err := base64.StdEncoding.Decode(decoded, blob)
+		if err != nil {
+			return "", 0, fmt.Errorf("%w: %v", ErrInvalidBlob, err)
+		}
+		blob = decoded[:n]
+	}
+	nbytes = len(blob)
+
+	data := blob
+	contentType := infoContentType(info)
+	if compress {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(blob); err != nil {
+			return "", 0, fmt.Errorf("failed to compress object: %w", err)
+		}
+		if err := zw.Close(); err != nil {
+			return "", 0, fmt.Errorf("failed to compress object: %w", err)
+		}
+		data = buf.Bytes()
+		contentType = compressedContentType
+	}
+
+	id = uuid.New().String()
+	metadata := objectMetadata(info, int64(nbytes), compress)
+	err = store.PutWithMetadata(ctx, bucket, id, bytes.NewReader(data), int64(len(data)), contentType, metadata)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to put object in store: %w", err)
+	}
+	return id, nbytes, nil
+}
+
+// ObjectPut stores size bytes from reader in bucket as the object id, uncompressed,
+// with the info block. It is ObjectNew for callers that stream their data, such as
+// files, and choose their own IDs.
+func ObjectPut(ctx context.Context, store ObjectStore, bucket, id string, reader io.Reader, size int64, info string) error {
+	info, err := checkInfo(info)
+	if err != nil {
+		return err
+	}
+	if size < 0 {
+		return fmt.Errorf("object %s has unknown size", id)
+	}
+	metadata := objectMetadata(info, size, false)
+	err = store.PutWithMetadata(ctx, bucket, id, reader, size, infoContentType(info), metadata)
+	if err != nil {
+		return fmt.Errorf("failed to put object in store: %w", err)
+	}
+	return nil
+}
+
+// ObjectNewUpload starts a multipart upload (see ObjectStore) of the object id to
+// bucket, uncompressed, with the info block. It is ObjectPut for callers that do not
+// know the size of the object in advance, which is then not recorded: ObjectInfo
+// returns the stored size, the same for an uncompressed object.
+func ObjectNewUpload(ctx context.Context, store ObjectStore, bucket, id, info string) (uploadID string, err error) {
+	info, err = checkInfo(info)
+	if err != nil {
+		return "", err
+	}
+	metadata := objectMetadata(info, -1, false)
+	uploadID, err = store.NewMultipartUpload(ctx, bucket, id, infoContentType(info), metadata)
+	if err != nil {
+		return "", fmt.Errorf("failed to start upload to store: %w", err)
+	}
+	return uploadID, nil
+}
+
+// ObjectInfo returns the info block of an object, its uncompressed size and whether
+// it is stored compressed. Objects stored without this module's metadata have an
+// empty info block and their stored size.
+func ObjectInfo(ctx context.Context, store ObjectStore, id, bucket string) (info string, size int, isCompressed bool, err error) {
+	attrs, err := store.Stat(ctx, bucket, id)
+	if err != nil {
+		return "", 0, false, err
+	}
+	info, size64, isCompressed, err := parseMetadata(attrs)
+	if err != nil {
+		return "", 0, false, fmt.Errorf("object %s/%s: %w", bucket, id, err)
+	}
+	return info, int(size64), isCompressed, nil
+}
+
+// ObjectStoredAt returns when an object was stored, as recorded in its metadata, or
+// its last modification time for objects stored without this module's metadata.
+func ObjectStoredAt(ctx context.Context, store ObjectStore, id, bucket string) (time.Time, error) {
+	attrs, err := store.Stat(ctx, bucket, id)
+	if err != nil {
+		return time.Time{}, err
+	}
+	s, ok := attrs.Metadata[MetaStoredAt]
+	if !ok {
+		return attrs.LastModified, nil
+	}
+	storedAt, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("object %s/%s: invalid %s: %w", bucket, id, MetaStoredAt, err)
+	}
+	return storedAt, nil
+}
+
+// ObjectInfoUpdate replaces the info block of an object. An empty info string stores
+// the empty block, {}.
+func ObjectInfoUpdate(ctx context.Context, store ObjectStore, id, bucket, info string) error {
+	info, err := checkInfo(info)
+	if err != nil {
+		return err
+	}
+	attrs, err := store.Stat(ctx, bucket, id)
+	if err != nil {
+		return err
+	}
+	_, size, isCompressed, err := parseMetadata(attrs)
+	if err != nil {
+		return fmt.Errorf("object %s/%s: %w", bucket, id, err)
+	}
+
+	// Keep the other attributes, including the storage time, and any metadata set by
+	// others
+	metadata := objectMetadata(info, size, isCompressed)
+	for k, v := range attrs.Metadata {
+		if _, ok := metadata[k]; !ok || k == MetaStoredAt {
+			metadata[k] = v
+		}
+	}
+	return store.ReplaceMetadata(ctx, bucket, id, metadata)
+}
+
+// ObjectMove moves an object from srcBucket to dstBucket, keeping its ID and
+// metadata. It returns ErrObjectNotFound if the object is not in srcBucket.
+func ObjectMove(ctx context.Context, store ObjectStore, id, srcBucket, dstBucket string) error {
+	if _, err := store.Stat(ctx, srcBucket, id); err != nil {
+		return err
+	}
+	if err := store.Copy(ctx, srcBucket, id, dstBucket); err != nil {
+		return fmt.Errorf("failed to copy object to %s: %w", dstBucket, err)
+	}
+	if err := store.Delete(ctx, srcBucket, id); err != nil {
+		return fmt.Errorf("failed to delete object from %s: %w", srcBucket, err)
+	}
+	return nil
+}
+
+// ObjectGet returns the data of an object, uncompressed.
+func ObjectGet(ctx context.Context, store ObjectStore, id, bucket string) ([]byte, error) {
+	attrs, err := store.Stat(ctx, bucket, id)
+	if err != nil {
+		return nil, err
+	}
+	_, _, isCompressed, err := parseMetadata(attrs)
+	if err != nil {
+		return nil, fmt.Errorf("object %s/%s: %w", bucket, id, err)
+	}
+
+	r, err := store.Get(ctx, bucket, id)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var src io.Reader = r
+	if isCompressed {
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to uncompress object: %w", err)
+		}
+		defer zr.Close()
+		src = zr
+	}
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object: %w", err)
+	}
+	return data, nil
+}
+
+// ObjectDelete deletes an object.
+func ObjectDelete(ctx context.Context, store ObjectStore, id, bucket string) error {
+	return store.Delete(ctx, bucket, id)
+}
+
+// checkInfo validates an info block, returning {} for an empty one.
+func checkInfo(info string) (string, error) {
+	if info == "" {
+		return "{}", nil
+	}
+	if len(info) > MaxInfoSize {
+		return "", fmt.Errorf("%w: %d bytes, the maximum is %d", ErrInfoTooLarge, len(info), MaxInfoSize)
+	}
+	var obj map[string]any
+	if err := json.Unmarshal([]byte(info), &obj); err != nil || obj == nil {
+		return "", ErrInvalidInfo
+	}
+	return info, nil
+}
+
+// infoContentType returns the mimetype of a valid info block, or the default content
+// type if it has none.
+func infoContentType(info string) string {
+	var i Info
+	if err := json.Unmarshal([]byte(info), &i); err != nil || i.MimeType == "" {
+		return defaultContentType
+	}
+	return i.MimeType
+}
+
+// objectMetadata returns the user metadata of an object stored now, without its size
+// if size is negative. The info block is base64-encoded, as metadata values must be
+// ASCII.
+func objectMetadata(info string, size int64, isCompressed bool) map[string]string {
+	metadata := map[string]string{
+		MetaInfo:       base64.StdEncoding.EncodeToString([]byte(info)),
+		MetaStoredAt:   time.Now().UTC().Format(time.RFC3339Nano),
+		MetaCompressed: strconv.FormatBool(isCompressed),
+	}
+	if size >= 0 {
+		metadata[MetaSize] = strconv.FormatInt(size, 10)
+	}
+	return metadata
+}
+
+// parseMetadata returns the info block, uncompressed size and compression of an
+// object from its attributes.
+func parseMetadata(attrs ObjectAttrs) (info string, size int64, isCompressed bool, err error) {
+	info = "{}"
+	if s, ok := attrs.Metadata[MetaInfo]; ok {
+		b, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return "", 0, false, fmt.Errorf("invalid %s: %w", MetaInfo, err)
+		}
+		info = string(b)
+	}
+
+	size = attrs.Size
+	if s, ok := attrs.Metadata[MetaSize]; ok {
+		if size, err = strconv.ParseInt(s, 10, 64); err != nil {
+			return "", 0, false, fmt.Errorf("invalid %s: %w", MetaSize, err)
+		}
+	}
+
+	if s, ok := attrs.Metadata[MetaCompressed]; ok {
+		if isCompressed, err = strconv.ParseBool(s); err != nil {
+			return "", 0, false, fmt.Errorf("invalid %s: %w", MetaCompressed, err)
+		}
+	}
+	return info, size, isCompressed, nil
+}
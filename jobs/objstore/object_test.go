@@ -0,0 +1,217 @@
+package objstore_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/remiges-tech/alya/jobs/objstore"
+)
+
+func TestObjectNewAndGet(t *testing.T) {
+	ctx := context.Background()
+	store := objstore.NewMemObjectStore()
+	data := []byte(strings.Repeat("Hello, World! ", 100))
+	info := objstore.Info{MimeType: "text/plain", OwnerClass: "acctvoucher", OwnerID: "v42"}.String()
+
+	tests := []struct {
+		name      string
+		blob      []byte
+		isEncoded bool
+		compress  bool
+	}{
+		{"raw", data, false, false},
+		{"raw compressed", data, false, true},
+		{"encoded", []byte(base64.StdEncoding.EncodeToString(data)), true, false},
+		{"encoded compressed", []byte(base64.StdEncoding.EncodeToString(data)), true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := time.Now()
+			id, nbytes, err := objstore.ObjectNew(ctx, store, tt.blob, "bucket", info, tt.isEncoded, tt.compress)
+			if err != nil {
+				t.Fatalf("ObjectNew failed: %v", err)
+			}
+			if nbytes != len(data) {
+				t.Errorf("nbytes = %d, want %d", nbytes, len(data))
+			}
+
+			gotInfo, size, isCompressed, err := objstore.ObjectInfo(ctx, store, id, "bucket")
+			if err != nil {
+				t.Fatalf("ObjectInfo failed: %v", err)
+			}
+			if gotInfo != info || size != len(data) || isCompressed != tt.compress {
+				t.Errorf("ObjectInfo = %q, %d, %v; want %q, %d, %v", gotInfo, size, isCompressed, info, len(data), tt.compress)
+			}
+
+			attrs, err := store.Stat(ctx, "bucket", id)
+			if err != nil {
+				t.Fatalf("Stat failed: %v", err)
+			}
+			if tt.compress && attrs.Size >= int64(len(data)) {
+				t.Errorf("Compressed object has %d bytes, data has %d", attrs.Size, len(data))
+			}
+			wantType := "text/plain"
+			if tt.compress {
+				wantType = "application/gzip"
+			}
+			if attrs.ContentType != wantType {
+				t.Errorf("Content type = %q, want %q", attrs.ContentType, wantType)
+			}
+
+			storedAt, err := objstore.ObjectStoredAt(ctx, store, id, "bucket")
+			if err != nil {
+				t.Fatalf("ObjectStoredAt failed: %v", err)
+			}
+			if storedAt.Before(before.Add(-time.Second)) || storedAt.After(time.Now()) {
+				t.Errorf("ObjectStoredAt = %v, want about %v", storedAt, before)
+			}
+
+			got, err := objstore.ObjectGet(ctx, store, id, "bucket")
+			if err != nil {
+				t.Fatalf("ObjectGet failed: %v", err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Errorf("ObjectGet returned %d bytes, not the data", len(got))
+			}
+		})
+	}
+}
+
+func TestObjectNew_InvalidInput(t *testing.T) {
+	ctx := context.Background()
+	store := objstore.NewMemObjectStore()
+
+	if _, _, err := objstore.ObjectNew(ctx, store, []byte("x"), "bucket", `[1, 2]`, false, false); !errors.Is(err, objstore.ErrInvalidInfo) {
+		t.Errorf("ObjectNew with a non-object info: got %v, want ErrInvalidInfo", err)
+	}
+	large := `{"from": "` + strings.Repeat("x", objstore.MaxInfoSize) + `"}`
+	if _, _, err := objstore.ObjectNew(ctx, store, []byte("x"), "bucket", large, false, false); !errors.Is(err, objstore.ErrInfoTooLarge) {
+		t.Errorf("ObjectNew with a large info: got %v, want ErrInfoTooLarge", err)
+	}
+	if _, _, err := objstore.ObjectNew(ctx, store, []byte("not base64!"), "bucket", "", true, false); !errors.Is(err, objstore.ErrInvalidBlob) {
+		t.Errorf("ObjectNew with an invalid encoded blob: got %v, want ErrInvalidBlob", err)
+	}
+}
+
+func TestObjectInfoUpdate(t *testing.T) {
+	ctx := context.Background()
+	store := objstore.NewMemObjectStore()
+	id, _, err := objstore.ObjectNew(ctx, store, []byte("data"), "bucket", `{"ownerclass": "annualmis"}`, false, true)
+	if err != nil {
+		t.Fatalf("ObjectNew failed: %v", err)
+	}
+	storedAt, err := objstore.ObjectStoredAt(ctx, store, id, "bucket")
+	if err != nil {
+		t.Fatalf("ObjectStoredAt failed: %v", err)
+	}
+
+	if err := objstore.ObjectInfoUpdate(ctx, store, id, "bucket", `{"ownerid": "2024"}`); err != nil {
+		t.Fatalf("ObjectInfoUpdate failed: %v", err)
+	}
+	info, size, isCompressed, err := objstore.ObjectInfo(ctx, store, id, "bucket")
+	if err != nil {
+		t.Fatalf("ObjectInfo failed: %v", err)
+	}
+	if info != `{"ownerid": "2024"}` || size != 4 || !isCompressed {
+		t.Errorf("ObjectInfo after update = %q, %d, %v", info, size, isCompressed)
+	}
+	if got, _ := objstore.ObjectStoredAt(ctx, store, id, "bucket"); !got.Equal(storedAt) {
+		t.Errorf("ObjectInfoUpdate changed the storage time from %v to %v", storedAt, got)
+	}
+
+	// An empty block replaces the info too
+	if err := objstore.ObjectInfoUpdate(ctx, store, id, "bucket", ""); err != nil {
+		t.Fatalf("ObjectInfoUpdate failed: %v", err)
+	}
+	if info, _, _, _ := objstore.ObjectInfo(ctx, store, id, "bucket"); info != "{}" {
+		t.Errorf("ObjectInfo after empty update = %q, want {}", info)
+	}
+
+	if err := objstore.ObjectInfoUpdate(ctx, store, "missing", "bucket", "{}"); !errors.Is(err, objstore.ErrObjectNotFound) {
+		t.Errorf("ObjectInfoUpdate of a missing object: got %v, want ErrObjectNotFound", err)
+	}
+}
+
+func TestObjectMove(t *testing.T) {
+	ctx := context.Background()
+	store := objstore.NewMemObjectStore()
+	id, _, err := objstore.ObjectNew(ctx, store, []byte("data"), "incoming", `{"filename": "txns.csv"}`, false, false)
+	if err != nil {
+		t.Fatalf("ObjectNew failed: %v", err)
+	}
+
+	if err := objstore.ObjectMove(ctx, store, id, "wrong", "failed"); !errors.Is(err, objstore.ErrObjectNotFound) {
+		t.Errorf("ObjectMove from the wrong bucket: got %v, want ErrObjectNotFound", err)
+	}
+	if err := objstore.ObjectMove(ctx, store, id, "incoming", "failed"); err != nil {
+		t.Fatalf("ObjectMove failed: %v", err)
+	}
+
+	if _, _, _, err := objstore.ObjectInfo(ctx, store, id, "incoming"); !errors.Is(err, objstore.ErrObjectNotFound) {
+		t.Errorf("Object still in the source bucket: %v", err)
+	}
+	info, size, _, err := objstore.ObjectInfo(ctx, store, id, "failed")
+	if err != nil {
+		t.Fatalf("ObjectInfo in the destination bucket failed: %v", err)
+	}
+	if info != `{"filename": "txns.csv"}` || size != 4 {
+		t.Errorf("ObjectInfo after move = %q, %d", info, size)
+	}
+}
+
+func TestObjectInfo_WithoutMetadata(t *testing.T) {
+	ctx := context.Background()
+	store := objstore.NewMemObjectStore()
+	data := []byte("plain object")
+	if err := store.Put(ctx, "bucket", "plain", bytes.NewReader(data), int64(len(data)), "text/plain"); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	info, size, isCompressed, err := objstore.ObjectInfo(ctx, store, "plain", "bucket")
+	if err != nil {
+		t.Fatalf("ObjectInfo failed: %v", err)
+	}
+	if info != "{}" || size != len(data) || isCompressed {
+		t.Errorf("ObjectInfo = %q, %d, %v; want {}, %d, false", info, size, isCompressed, len(data))
+	}
+	got, err := objstore.ObjectGet(ctx, store, "plain", "bucket")
+	if err != nil || !bytes.Equal(got, data) {
+		t.Errorf("ObjectGet = %q, %v", got, err)
+	}
+}
+
+func TestObjectNewUpload(t *testing.T) {
+	ctx := context.Background()
+	store := objstore.NewMemObjectStore()
+	info := objstore.Info{MimeType: "text/csv", OwnerClass: "batch", Filename: "out.csv"}.String()
+	uploadID, err := objstore.ObjectNewUpload(ctx, store, "bucket", "out", info)
+	if err != nil {
+		t.Fatalf("ObjectNewUpload failed: %v", err)
+	}
+	part, err := store.PutPart(ctx, "bucket", "out", uploadID, 1, strings.NewReader("a,b\n"), 4)
+	if err != nil {
+		t.Fatalf("PutPart failed: %v", err)
+	}
+	if err := store.CompleteMultipartUpload(ctx, "bucket", "out", uploadID, []objstore.Part{part}); err != nil {
+		t.Fatalf("CompleteMultipartUpload failed: %v", err)
+	}
+
+	// The size is not known when the upload starts, so it is the stored size
+	got, size, isCompressed, err := objstore.ObjectInfo(ctx, store, "out", "bucket")
+	if err != nil || got != info || size != 4 || isCompressed {
+		t.Errorf("ObjectInfo = %q, %d, %v, %v", got, size, isCompressed, err)
+	}
+	attrs, err := store.Stat(ctx, "bucket", "out")
+	if err != nil || attrs.ContentType != "text/csv" {
+		t.Errorf("Stat = %+v, %v; want content type text/csv", attrs, err)
+	}
+
+	if _, err := objstore.ObjectNewUpload(ctx, store, "bucket", "bad", "[]"); !errors.Is(err, objstore.ErrInvalidInfo) {
+		t.Errorf("ObjectNewUpload with an invalid info block returned %v, want ErrInvalidInfo", err)
+	}
+}
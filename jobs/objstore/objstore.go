@@ -2,16 +2,78 @@ package objstore
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
+	"time"
 
 	"github.com/minio/minio-go/v7"
 )
 
+var (
+	ErrObjectNotFound = errors.New("object not found")
+	ErrUploadNotFound = errors.New("multipart upload not found")
+	ErrInvalidPart    = errors.New("invalid part")
+)
+
+// MinPartSize is the smallest size of the parts of a multipart upload, all but the
+// last. It is the limit of S3, which all implementations enforce.
+const MinPartSize = 5 << 20
+
+// defaultContentType is the content type of objects put without one
+const defaultContentType = "application/octet-stream"
+
 // ObjectStore is a generic interface for object store operations
+//
+// All implementations behave as MinIO does, which the conformance tests check: Get,
+// Stat, ReplaceMetadata and Copy return ErrObjectNotFound for a missing object,
+// Delete of a missing object succeeds, Put replaces any object of the same name, an
+// empty content type is stored as application/octet-stream, and object names may
+// contain slashes.
 type ObjectStore interface {
 	Put(ctx context.Context, bucket, obj string, reader io.Reader, size int64, contentType string) error
 	Get(ctx context.Context, bucket, obj string) (io.ReadCloser, error)
 	Delete(ctx context.Context, bucket, obj string) error
+
+	// PutWithMetadata uploads an object like Put, with user metadata. Keys are
+	// returned by Stat in canonical MIME header form, and values must be ASCII.
+	PutWithMetadata(ctx context.Context, bucket, obj string, reader io.Reader, size int64, contentType string, metadata map[string]string) error
+	// Stat returns the attributes and user metadata of an object, or ErrObjectNotFound
+	Stat(ctx context.Context, bucket, obj string) (ObjectAttrs, error)
+	// ReplaceMetadata replaces the user metadata of an object, keeping its data and
+	// content type
+	ReplaceMetadata(ctx context.Context, bucket, obj string, metadata map[string]string) error
+	// Copy copies an object, with its content type and user metadata, to the object
+	// of the same name in another bucket
+	Copy(ctx context.Context, srcBucket, obj, dstBucket string) error
+
+	// NewMultipartUpload starts the upload of an object in parts, returning its upload
+	// ID. The object appears, with the content type and metadata given here, once
+	// the upload is completed.
+	NewMultipartUpload(ctx context.Context, bucket, obj, contentType string, metadata map[string]string) (uploadID string, err error)
+	// PutPart uploads part number (from 1 to 10000) of an upload, replacing any part
+	// of the same number. It returns ErrUploadNotFound for an unknown upload ID.
+	PutPart(ctx context.Context, bucket, obj, uploadID string, number int, reader io.Reader, size int64) (Part, error)
+	// CompleteMultipartUpload assembles the parts, in ascending order of number, into
+	// the object. Parts other than the last must be at least MinPartSize bytes.
+	CompleteMultipartUpload(ctx context.Context, bucket, obj, uploadID string, parts []Part) error
+	// AbortMultipartUpload discards an upload and its parts
+	AbortMultipartUpload(ctx context.Context, bucket, obj, uploadID string) error
+}
+
+// Part is an uploaded part of a multipart upload
+type Part struct {
+	Number int    `json:"number"`
+	ETag   string `json:"etag"`
+	Size   int64  `json:"size"`
+}
+
+// ObjectAttrs are the attributes of a stored object
+type ObjectAttrs struct {
+	Size         int64 // Stored size in bytes
+	ContentType  string
+	LastModified time.Time
+	Metadata     map[string]string // User metadata
 }
 
 // MinioObjectStore is an implementation of ObjectStore using Minio
@@ -32,10 +94,116 @@ func (s *MinioObjStore) Put(ctx context.Context, bucket, obj string, reader io.R
 
 // Get retrieves an object from Minio
 func (s *MinioObjStore) Get(ctx context.Context, bucket, obj string) (io.ReadCloser, error) {
-	return s.client.GetObject(ctx, bucket, obj, minio.GetObjectOptions{})
+	object, err := s.client.GetObject(ctx, bucket, obj, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, minioError(err, bucket, obj)
+	}
+	// GetObject sends no request until the object is first used, so a missing object
+	// would only show on the first Read. Stat sends it now.
+	if _, err := object.Stat(); err != nil {
+		object.Close()
+		return nil, minioError(err, bucket, obj)
+	}
+	return object, nil
 }
 
 // Delete removes an object from Minio
 func (s *MinioObjStore) Delete(ctx context.Context, bucket, obj string) error {
 	return s.client.RemoveObject(ctx, bucket, obj, minio.RemoveObjectOptions{})
 }
+
+// PutWithMetadata uploads an object to Minio, with the metadata as its user metadata
+func (s *MinioObjStore) PutWithMetadata(ctx context.Context, bucket, obj string, reader io.Reader, size int64, contentType string, metadata map[string]string) error {
+	_, err := s.client.PutObject(ctx, bucket, obj, reader, size, minio.PutObjectOptions{ContentType: contentType, UserMetadata: metadata})
+	return err
+}
+
+// Stat retrieves the attributes and user metadata of an object from Minio
+func (s *MinioObjStore) Stat(ctx context.Context, bucket, obj string) (ObjectAttrs, error) {
+	info, err := s.client.StatObject(ctx, bucket, obj, minio.StatObjectOptions{})
+	if err != nil {
+		return ObjectAttrs{}, minioError(err, bucket, obj)
+	}
+	return ObjectAttrs{
+		Size:         info.Size,
+		ContentType:  info.ContentType,
+		LastModified: info.LastModified,
+		Metadata:     info.UserMetadata,
+	}, nil
+}
+
+// ReplaceMetadata copies an object onto itself in Minio with new user metadata
+func (s *MinioObjStore) ReplaceMetadata(ctx context.Context, bucket, obj string, metadata map[string]string) error {
+	attrs, err := s.Stat(ctx, bucket, obj)
+	if err != nil {
+		return err
+	}
+	// Replacing the metadata replaces the content type too, unless it is passed along
+	usermeta := make(map[string]string, len(metadata)+1)
+	for k, v := range metadata {
+		usermeta[k] = v
+	}
+	usermeta["Content-Type"] = attrs.ContentType
+	_, err = s.client.CopyObject(ctx,
+		minio.CopyDestOptions{Bucket: bucket, Object: obj, UserMetadata: usermeta, ReplaceMetadata: true},
+		minio.CopySrcOptions{Bucket: bucket, Object: obj})
+	return minioError(err, bucket, obj)
+}
+
+// Copy copies an object to another bucket in Minio, server-side
+func (s *MinioObjStore) Copy(ctx context.Context, srcBucket, obj, dstBucket string) error {
+	_, err := s.client.CopyObject(ctx,
+		minio.CopyDestOptions{Bucket: dstBucket, Object: obj},
+		minio.CopySrcOptions{Bucket: srcBucket, Object: obj})
+	return minioError(err, srcBucket, obj)
+}
+
+// NewMultipartUpload starts a multipart upload in Minio
+func (s *MinioObjStore) NewMultipartUpload(ctx context.Context, bucket, obj, contentType string, metadata map[string]string) (string, error) {
+	core := minio.Core{Client: s.client}
+	return core.NewMultipartUpload(ctx, bucket, obj, minio.PutObjectOptions{ContentType: contentType, UserMetadata: metadata})
+}
+
+// PutPart uploads a part of a multipart upload to Minio
+func (s *MinioObjStore) PutPart(ctx context.Context, bucket, obj, uploadID string, number int, reader io.Reader, size int64) (Part, error) {
+	core := minio.Core{Client: s.client}
+	part, err := core.PutObjectPart(ctx, bucket, obj, uploadID, number, reader, size, minio.PutObjectPartOptions{})
+	if err != nil {
+		return Part{}, minioError(err, bucket, obj)
+	}
+	return Part{Number: part.PartNumber, ETag: part.ETag, Size: part.Size}, nil
+}
+
+// CompleteMultipartUpload completes a multipart upload in Minio
+func (s *MinioObjStore) CompleteMultipartUpload(ctx context.Context, bucket, obj, uploadID string, parts []Part) error {
+	core := minio.Core{Client: s.client}
+	completeParts := make([]minio.CompletePart, len(parts))
+	for i, p := range parts {
+		completeParts[i] = minio.CompletePart{PartNumber: p.Number, ETag: p.ETag}
+	}
+	_, err := core.CompleteMultipartUpload(ctx, bucket, obj, uploadID, completeParts, minio.PutObjectOptions{})
+	return minioError(err, bucket, obj)
+}
+
+// AbortMultipartUpload aborts a multipart upload in Minio
+func (s *MinioObjStore) AbortMultipartUpload(ctx context.Context, bucket, obj, uploadID string) error {
+	core := minio.Core{Client: s.client}
+	return minioError(core.AbortMultipartUpload(ctx, bucket, obj, uploadID), bucket, obj)
+}
+
+// minioError returns ErrObjectNotFound, ErrUploadNotFound or ErrInvalidPart for the
+// Minio errors they stand for.
+func minioError(err error, bucket, obj string) error {
+	if err == nil {
+		return nil
+	}
+	switch minio.ToErrorResponse(err).Code {
+	case "NoSuchKey":
+		return fmt.Errorf("%w: %s/%s", ErrObjectNotFound, bucket, obj)
+	case "NoSuchUpload":
+		return fmt.Errorf("%w: %s/%s", ErrUploadNotFound, bucket, obj)
+	case "InvalidPart", "InvalidPartOrder", "EntityTooSmall":
+		return fmt.Errorf("%w: %s/%s: %v", ErrInvalidPart, bucket, obj, err)
+	}
+	return err
+}
@@ -1,15 +1,29 @@
 package objstore
 
 import (
+	"bytes"
 	"context"
 	"io"
 )
 
 // ObjectStoreMock is a mock implementation of the ObjectStore interface.
+//
+// The methods that were added to ObjectStore later fall back on PutFunc and GetFunc
+// when their own Func is not set, so that a mock set up for Put, Get and Delete only
+// keeps working.
 type ObjectStoreMock struct {
-	PutFunc    func(ctx context.Context, bucket, obj string, reader io.Reader, size int64, contentType string) error
-	GetFunc    func(ctx context.Context, bucket, obj string) (io.ReadCloser, error)
-	DeleteFunc func(ctx context.Context, bucket, obj string) error
+	PutFunc             func(ctx context.Context, bucket, obj string, reader io.Reader, size int64, contentType string) error
+	GetFunc             func(ctx context.Context, bucket, obj string) (io.ReadCloser, error)
+	DeleteFunc          func(ctx context.Context, bucket, obj string) error
+	PutWithMetadataFunc func(ctx context.Context, bucket, obj string, reader io.Reader, size int64, contentType string, metadata map[string]string) error
+	StatFunc            func(ctx context.Context, bucket, obj string) (ObjectAttrs, error)
+	ReplaceMetadataFunc func(ctx context.Context, bucket, obj string, metadata map[string]string) error
+	CopyFunc            func(ctx context.Context, srcBucket, obj, dstBucket string) error
+
+	NewMultipartUploadFunc      func(ctx context.Context, bucket, obj, contentType string, metadata map[string]string) (string, error)
+	PutPartFunc                 func(ctx context.Context, bucket, obj, uploadID string, number int, reader io.Reader, size int64) (Part, error)
+	CompleteMultipartUploadFunc func(ctx context.Context, bucket, obj, uploadID string, parts []Part) error
+	AbortMultipartUploadFunc    func(ctx context.Context, bucket, obj, uploadID string) error
 }
 
 // Put is a mock implementation of the Put method.
@@ -17,8 +31,11 @@ func (m *ObjectStoreMock) Put(ctx context.Context, bucket, obj string, reader io
 	return m.PutFunc(ctx, bucket, obj, reader, size, contentType)
 }
 
-// Get is a mock implementation of the Get method.
+// Get is a mock implementation of the Get method. Without GetFunc, no object exists.
 func (m *ObjectStoreMock) Get(ctx context.Context, bucket, obj string) (io.ReadCloser, error) {
+	if m.GetFunc == nil {
+		return nil, ErrObjectNotFound
+	}
 	return m.GetFunc(ctx, bucket, obj)
 }
 
@@ -27,6 +44,85 @@ func (m *ObjectStoreMock) Delete(ctx context.Context, bucket, obj string) error
 	return m.DeleteFunc(ctx, bucket, obj)
 }
 
+// PutWithMetadata is a mock implementation of the PutWithMetadata method. Without
+// PutWithMetadataFunc, it calls PutFunc and drops the metadata.
+func (m *ObjectStoreMock) PutWithMetadata(ctx context.Context, bucket, obj string, reader io.Reader, size int64, contentType string, metadata map[string]string) error {
+	if m.PutWithMetadataFunc == nil {
+		return m.PutFunc(ctx, bucket, obj, reader, size, contentType)
+	}
+	return m.PutWithMetadataFunc(ctx, bucket, obj, reader, size, contentType, metadata)
+}
+
+// Stat is a mock implementation of the Stat method. Without StatFunc, it calls
+// GetFunc and returns the size of the object, with no metadata.
+func (m *ObjectStoreMock) Stat(ctx context.Context, bucket, obj string) (ObjectAttrs, error) {
+	if m.StatFunc != nil {
+		return m.StatFunc(ctx, bucket, obj)
+	}
+	r, err := m.Get(ctx, bucket, obj)
+	if err != nil {
+		return ObjectAttrs{}, err
+	}
+	var size int64
+	if r != nil {
+		defer r.Close()
+		size, err = io.Copy(io.Discard, r)
+		if err != nil {
+			return ObjectAttrs{}, err
+		}
+	}
+	return ObjectAttrs{Size: size, Metadata: map[string]string{}}, nil
+}
+
+// ReplaceMetadata is a mock implementation of the ReplaceMetadata method. Without
+// ReplaceMetadataFunc, it does nothing.
+func (m *ObjectStoreMock) ReplaceMetadata(ctx context.Context, bucket, obj string, metadata map[string]string) error {
+	if m.ReplaceMetadataFunc == nil {
+		return nil
+	}
+	return m.ReplaceMetadataFunc(ctx, bucket, obj, metadata)
+}
+
+// Copy is a mock implementation of the Copy method. Without CopyFunc, it calls
+// GetFunc on the source and PutFunc on the destination.
+func (m *ObjectStoreMock) Copy(ctx context.Context, srcBucket, obj, dstBucket string) error {
+	if m.CopyFunc != nil {
+		return m.CopyFunc(ctx, srcBucket, obj, dstBucket)
+	}
+	r, err := m.Get(ctx, srcBucket, obj)
+	if err != nil {
+		return err
+	}
+	var data []byte
+	if r != nil {
+		defer r.Close()
+		if data, err = io.ReadAll(r); err != nil {
+			return err
+		}
+	}
+	return m.PutFunc(ctx, dstBucket, obj, bytes.NewReader(data), int64(len(data)), "application/octet-stream")
+}
+
+// NewMultipartUpload is a mock implementation of the NewMultipartUpload method.
+func (m *ObjectStoreMock) NewMultipartUpload(ctx context.Context, bucket, obj, contentType string, metadata map[string]string) (string, error) {
+	return m.NewMultipartUploadFunc(ctx, bucket, obj, contentType, metadata)
+}
+
+// PutPart is a mock implementation of the PutPart method.
+func (m *ObjectStoreMock) PutPart(ctx context.Context, bucket, obj, uploadID string, number int, reader io.Reader, size int64) (Part, error) {
+	return m.PutPartFunc(ctx, bucket, obj, uploadID, number, reader, size)
+}
+
+// CompleteMultipartUpload is a mock implementation of the CompleteMultipartUpload method.
+func (m *ObjectStoreMock) CompleteMultipartUpload(ctx context.Context, bucket, obj, uploadID string, parts []Part) error {
+	return m.CompleteMultipartUploadFunc(ctx, bucket, obj, uploadID, parts)
+}
+
+// AbortMultipartUpload is a mock implementation of the AbortMultipartUpload method.
+func (m *ObjectStoreMock) AbortMultipartUpload(ctx context.Context, bucket, obj, uploadID string) error {
+	return m.AbortMultipartUploadFunc(ctx, bucket, obj, uploadID)
+}
+
 // GenerateObjectStoreMock generates a new mock instance of the ObjectStore interface.
 func GenerateObjectStoreMock() *ObjectStoreMock {
 	return &ObjectStoreMock{
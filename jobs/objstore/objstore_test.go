@@ -3,7 +3,9 @@ package objstore_test
 import (
 	"bytes"
 	"context"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/remiges-tech/alya/jobs/objstore"
 
@@ -69,3 +71,34 @@ func TestPutGetObject(t *testing.T) {
 		t.Fatalf("Retrieved object content does not match the original content")
 	}
 }
+
+func TestMinioObjStore_Conformance(t *testing.T) {
+	minioClient, err := minio.New("localhost:9000", &minio.Options{
+		Creds:  credentials.NewStaticV4("minioadmin", "minioadmin", ""),
+		Secure: false,
+	})
+	if err != nil {
+		t.Fatalf("Error creating Minio client: %v", err)
+	}
+
+	// Fresh buckets, so that earlier runs leave no objects behind
+	ctx := context.Background()
+	suffix := strconv.FormatInt(time.Now().UnixNano(), 36)
+	buckets := []string{"conformance-" + suffix, "conformance-other-" + suffix}
+	if _, err := minioClient.BucketExists(ctx, buckets[0]); err != nil {
+		t.Skipf("minio not available on localhost:9000: %v", err)
+	}
+	for _, bucket := range buckets {
+		if err := minioClient.MakeBucket(ctx, bucket, minio.MakeBucketOptions{}); err != nil {
+			t.Fatalf("Error creating bucket %s: %v", bucket, err)
+		}
+		t.Cleanup(func() {
+			for obj := range minioClient.ListObjects(ctx, bucket, minio.ListObjectsOptions{Recursive: true}) {
+				minioClient.RemoveObject(ctx, bucket, obj.Key, minio.RemoveObjectOptions{})
+			}
+			minioClient.RemoveBucket(ctx, bucket)
+		})
+	}
+
+	testConformance(t, objstore.NewMinioObjectStore(minioClient), buckets[0], buckets[1])
+}
@@ -0,0 +1,432 @@
+package jobs
+
+import (
+	"bufio"
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+)
+
+// Encryption and signing of output files (see "Output Encryption and Signing" in the
+// README), and the helpers with which their consumers decrypt and verify them.
+
+var (
+	// ErrOutputDecrypt is returned when an output file cannot be decrypted, because the
+	// key is wrong or the file has been altered or cut short.
+	ErrOutputDecrypt = errors.New("output file cannot be decrypted")
+
+	// ErrOutputSignature is returned when the signature or checksum of an output file
+	// does not match it.
+	ErrOutputSignature = errors.New("output file does not match its signature")
+)
+
+// OutputEncryption encrypts output files.
+type OutputEncryption interface {
+	// Encrypt returns a writer that writes to w the encryption of what is written to
+	// it; the encryption is complete once the writer is closed
+	Encrypt(w io.Writer) (io.WriteCloser, error)
+	// Extension is appended to the file names of encrypted files
+	Extension() string
+	ContentType() string
+}
+
+// OutputSigner makes detached signatures of output files.
+type OutputSigner interface {
+	Sign(message io.Reader) ([]byte, error)
+	// Extension is appended to the file name of a file to name its signature
+	Extension() string
+	ContentType() string
+}
+
+// The AES-GCM format of encrypted output files splits them into segments, each sealed
+// on its own, so that files of any size are encrypted and decrypted as streams. A file
+// starts with aesgcmMagic, a random salt and a random nonce prefix. Its segments are
+// sealed with a key derived from the key and the salt, and a nonce made of the prefix,
+// the number of the segment and a flag set for the last segment, which is what stops a
+// file cut at a segment boundary from decrypting.
+const (
+	aesgcmMagic       = "AGM1"
+	aesgcmSaltSize    = 32
+	aesgcmPrefixSize  = 7
+	aesgcmSegmentSize = 64 << 10
+	aesgcmHeaderSize  = len(aesgcmMagic) + aesgcmSaltSize + aesgcmPrefixSize
+	aesgcmKeyInfo     = "alya output file"
+)
+
+// AESGCMEncryption encrypts output files with AES-GCM under Key, which has 16, 24 or
+// 32 bytes. Consumers decrypt them with NewAESGCMReader.
+type AESGCMEncryption struct {
+	Key []byte
+}
+
+func (e AESGCMEncryption) Extension() string   { return ".enc" }
+func (e AESGCMEncryption) ContentType() string { return "application/octet-stream" }
+
+func (e AESGCMEncryption) check() error {
+	if _, err := aes.NewCipher(e.Key); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (e AESGCMEncryption) Encrypt(w io.Writer) (io.WriteCloser, error) {
+	header := make([]byte, aesgcmHeaderSize)
+	copy(header, aesgcmMagic)
+	if _, err := rand.Read(header[len(aesgcmMagic):]); err != nil {
+		return nil, err
+	}
+	aead, err := aesgcmAEAD(e.Key, header)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return &aesgcmWriter{
+		w:      w,
+		aead:   aead,
+		prefix: header[len(aesgcmMagic)+aesgcmSaltSize:],
+		buf:    make([]byte, 0, aesgcmSegmentSize),
+	}, nil
+}
+
+// aesgcmAEAD returns the AEAD that seals the segments of the file with the header.
+func aesgcmAEAD(key, header []byte) (cipher.AEAD, error) {
+	salt := header[len(aesgcmMagic) : len(aesgcmMagic)+aesgcmSaltSize]
+	fileKey, err := hkdf.Key(sha256.New, key, salt, aesgcmKeyInfo, len(key))
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(fileKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func aesgcmNonce(prefix []byte, segment uint32, last bool) []byte {
+	nonce := make([]byte, 0, aesgcmPrefixSize+5)
+	nonce = append(nonce, prefix...)
+	nonce = binary.BigEndian.AppendUint32(nonce, segment)
+	if last {
+		return append(nonce, 1)
+	}
+	return append(nonce, 0)
+}
+
+type aesgcmWriter struct {
+	w       io.Writer
+	aead    cipher.AEAD
+	prefix  []byte
+	segment uint32
+	buf     []byte // plaintext of the current segment
+	out     []byte
+}
+
+func (e *aesgcmWriter) Write(p []byte) (int, error) {
+	n := 0
+	for len(p) > 0 {
+		if len(e.buf) == aesgcmSegmentSize {
+			if err := e.seal(false); err != nil {
+				return n, err
+			}
+		}
+		k := min(len(p), aesgcmSegmentSize-len(e.buf))
+		e.buf = append(e.buf, p[:k]...)
+		p = p[k:]
+		n += k
+	}
+	return n, nil
+}
+
+// Close seals the last segment, which may be empty.
+func (e *aesgcmWriter) Close() error {
+	return e.seal(true)
+}
+
+func (e *aesgcmWriter) seal(last bool) error {
+	if e.segment == ^uint32(0) {
+		return errors.New("encrypted output file has too many segments")
+	}
+	e.out = e.aead.Seal(e.out[:0], aesgcmNonce(e.prefix, e.segment, last), e.buf, nil)
+	e.segment++
+	e.buf = e.buf[:0]
+	_, err := e.w.Write(e.out)
+	return err
+}
+
+// NewAESGCMReader returns a reader of the decryption of r, an output file encrypted
+// with AESGCMEncryption under key. Reads return ErrOutputDecrypt as soon as a segment
+// of the file fails to decrypt; nothing of that segment is returned.
+func NewAESGCMReader(r io.Reader, key []byte) (io.Reader, error) {
+	header := make([]byte, aesgcmHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOutputDecrypt, err)
+	}
+	if string(header[:len(aesgcmMagic)]) != aesgcmMagic {
+		return nil, fmt.Errorf("%w: not an AES-GCM output file", ErrOutputDecrypt)
+	}
+	aead, err := aesgcmAEAD(key, header)
+	if err != nil {
+		return nil, err
+	}
+	return &aesgcmReader{
+		r:      bufio.NewReaderSize(r, aesgcmSegmentSize+aead.Overhead()+1),
+		aead:   aead,
+		prefix: header[len(aesgcmMagic)+aesgcmSaltSize:],
+		ct:     make([]byte, aesgcmSegmentSize+aead.Overhead()),
+	}, nil
+}
+
+type aesgcmReader struct {
+	r       *bufio.Reader
+	aead    cipher.AEAD
+	prefix  []byte
+	segment uint32
+	ct      []byte
+	plain   []byte
+	done    bool // the last segment has been decrypted
+}
+
+func (d *aesgcmReader) Read(p []byte) (int, error) {
+	for len(d.plain) == 0 {
+		if d.done {
+			return 0, io.EOF
+		}
+		if err := d.open(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, d.plain)
+	d.plain = d.plain[n:]
+	return n, nil
+}
+
+// open decrypts the next segment. It is the last one if nothing follows it.
+func (d *aesgcmReader) open() error {
+	n, err := io.ReadFull(d.r, d.ct)
+	last := false
+	switch {
+	case err == io.EOF || err == io.ErrUnexpectedEOF:
+		last = true
+	case err != nil:
+		return err
+	default:
+		if _, err := d.r.Peek(1); err == io.EOF {
+			last = true
+		} else if err != nil {
+			return err
+		}
+	}
+	plain, err := d.aead.Open(d.ct[:0], aesgcmNonce(d.prefix, d.segment, last), d.ct[:n], nil)
+	if err != nil {
+		return fmt.Errorf("%w: segment %d", ErrOutputDecrypt, d.segment)
+	}
+	d.segment++
+	d.plain = plain
+	d.done = last
+	return nil
+}
+
+// PGPEncryption encrypts output files with OpenPGP for Recipients, armored if Armor is
+// set. Consumers decrypt them with DecryptPGP.
+type PGPEncryption struct {
+	Recipients openpgp.EntityList
+	Armor      bool
+}
+
+func (e PGPEncryption) Extension() string {
+	if e.Armor {
+		return ".asc"
+	}
+	return ".pgp"
+}
+
+func (e PGPEncryption) ContentType() string { return "application/pgp-encrypted" }
+
+func (e PGPEncryption) check() error {
+	if len(e.Recipients) == 0 {
+		return errors.New("no PGP recipients")
+	}
+	return nil
+}
+
+func (e PGPEncryption) Encrypt(w io.Writer) (io.WriteCloser, error) {
+	if !e.Armor {
+		return openpgp.Encrypt(w, e.Recipients, nil, &openpgp.FileHints{IsBinary: true}, nil)
+	}
+	aw, err := armor.Encode(w, "PGP MESSAGE", nil)
+	if err != nil {
+		return nil, err
+	}
+	pw, err := openpgp.Encrypt(aw, e.Recipients, nil, &openpgp.FileHints{IsBinary: true}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &chainWriter{Writer: pw, closers: []io.Closer{pw, aw}}, nil
+}
+
+// DecryptPGP returns a reader of the decryption of r, an output file encrypted with
+// PGPEncryption, armored or not, for a private key in keyring. Once the reader is
+// exhausted without error, the integrity of the file has been checked.
+func DecryptPGP(r io.Reader, keyring openpgp.KeyRing) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	if start, _ := br.Peek(5); string(start) == "-----" {
+		block, err := armor.Decode(br)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrOutputDecrypt, err)
+		}
+		r = block.Body
+	} else {
+		r = br
+	}
+	md, err := openpgp.ReadMessage(r, keyring, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOutputDecrypt, err)
+	}
+	return md.UnverifiedBody, nil
+}
+
+// PGPSigner makes OpenPGP detached signatures with the private key of Entity, which
+// must have been decrypted, armored if Armor is set. Consumers check them with
+// VerifyPGPSignature.
+type PGPSigner struct {
+	Entity *openpgp.Entity
+	Armor  bool
+}
+
+func (s PGPSigner) Extension() string {
+	if s.Armor {
+		return ".asc"
+	}
+	return ".sig"
+}
+
+func (s PGPSigner) ContentType() string { return "application/pgp-signature" }
+
+func (s PGPSigner) check() error {
+	if s.Entity == nil || s.Entity.PrivateKey == nil {
+		return errors.New("PGP signer has no private key")
+	}
+	if s.Entity.PrivateKey.Encrypted {
+		return errors.New("PGP signing key is not decrypted")
+	}
+	return nil
+}
+
+func (s PGPSigner) Sign(message io.Reader) ([]byte, error) {
+	var sig bytes.Buffer
+	var err error
+	if s.Armor {
+		err = openpgp.ArmoredDetachSign(&sig, s.Entity, message, nil)
+	} else {
+		err = openpgp.DetachSign(&sig, s.Entity, message, nil)
+	}
+	return sig.Bytes(), err
+}
+
+// VerifyPGPSignature checks that signature, armored or not, is a signature of message
+// by a key in keyring, and returns the signer.
+func VerifyPGPSignature(keyring openpgp.KeyRing, message, signature io.Reader) (*openpgp.Entity, error) {
+	br := bufio.NewReader(signature)
+	var signer *openpgp.Entity
+	var err error
+	if start, _ := br.Peek(5); string(start) == "-----" {
+		signer, err = openpgp.CheckArmoredDetachedSignature(keyring, message, br, nil)
+	} else {
+		signer, err = openpgp.CheckDetachedSignature(keyring, message, br, nil)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOutputSignature, err)
+	}
+	return signer, nil
+}
+
+// Ed25519Signer signs output files with an Ed25519 key. Consumers check the signatures
+// with VerifyEd25519Signature.
+type Ed25519Signer struct {
+	Key ed25519.PrivateKey
+}
+
+func (s Ed25519Signer) Extension() string   { return ".sig" }
+func (s Ed25519Signer) ContentType() string { return "application/octet-stream" }
+
+func (s Ed25519Signer) check() error {
+	if len(s.Key) != ed25519.PrivateKeySize {
+		return fmt.Errorf("Ed25519 key has %d bytes, not %d", len(s.Key), ed25519.PrivateKeySize)
+	}
+	return nil
+}
+
+// Sign signs the SHA-512 digest of message with Ed25519ph, so that the message is
+// not held in memory.
+func (s Ed25519Signer) Sign(message io.Reader) ([]byte, error) {
+	digest, err := ed25519phDigest(message)
+	if err != nil {
+		return nil, err
+	}
+	return s.Key.Sign(nil, digest, &ed25519.Options{Hash: ed25519phHash})
+}
+
+// ed25519phHash is the hash of messages signed with Ed25519ph.
+const ed25519phHash = crypto.SHA512
+
+func ed25519phDigest(message io.Reader) ([]byte, error) {
+	h := sha512.New()
+	if _, err := io.Copy(h, message); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// VerifyEd25519Signature checks that signature is a signature of message made with
+// Ed25519Signer by the private key of key.
+func VerifyEd25519Signature(key ed25519.PublicKey, message io.Reader, signature []byte) error {
+	digest, err := ed25519phDigest(message)
+	if err != nil {
+		return err
+	}
+	if err := ed25519.VerifyWithOptions(key, digest, signature, &ed25519.Options{Hash: ed25519phHash}); err != nil {
+		return fmt.Errorf("%w: %v", ErrOutputSignature, err)
+	}
+	return nil
+}
+
+// VerifyOutputManifest checks that file has the checksum listed for name in manifest,
+// a checksum manifest of output files.
+func VerifyOutputManifest(manifest []byte, name string, file io.Reader) error {
+	want := ""
+	for _, line := range strings.Split(string(manifest), "\n") {
+		sum, file, ok := strings.Cut(line, "  ")
+		if ok && file == name {
+			want = sum
+			break
+		}
+	}
+	if want == "" {
+		return fmt.Errorf("%w: %s is not in the manifest", ErrOutputSignature, name)
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return err
+	}
+	if hex.EncodeToString(h.Sum(nil)) != want {
+		return fmt.Errorf("%w: checksum of %s differs from the manifest", ErrOutputSignature, name)
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package jobs
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// encryptOutput returns the encryption of plain by e.
+func encryptOutput(t *testing.T, e OutputEncryption, plain []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := e.Encrypt(&buf)
+	require.NoError(t, err)
+	_, err = w.Write(plain)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func TestAESGCMEncryption(t *testing.T) {
+	key := make([]byte, 32)
+	rand.Read(key)
+	e := AESGCMEncryption{Key: key}
+
+	for _, size := range []int{0, 1, aesgcmSegmentSize, aesgcmSegmentSize + 1, 3*aesgcmSegmentSize + 100} {
+		plain := make([]byte, size)
+		rand.Read(plain)
+		sealed := encryptOutput(t, e, plain)
+
+		r, err := NewAESGCMReader(bytes.NewReader(sealed), key)
+		require.NoError(t, err)
+		got, err := io.ReadAll(r)
+		require.NoError(t, err, "size %d", size)
+		assert.True(t, bytes.Equal(plain, got), "size %d differs", size)
+	}
+
+	plain := bytes.Repeat([]byte("0123456789"), aesgcmSegmentSize/5)
+	sealed := encryptOutput(t, e, plain)
+	assert.NotEqual(t, sealed, encryptOutput(t, e, plain), "encryptions of a file must differ")
+
+	decrypt := func(data, key []byte) error {
+		r, err := NewAESGCMReader(bytes.NewReader(data), key)
+		if err != nil {
+			return err
+		}
+		_, err = io.ReadAll(r)
+		return err
+	}
+	wrongKey := make([]byte, 32)
+	rand.Read(wrongKey)
+	assert.ErrorIs(t, decrypt(sealed, wrongKey), ErrOutputDecrypt)
+
+	tampered := bytes.Clone(sealed)
+	tampered[aesgcmHeaderSize+aesgcmSegmentSize+40]++
+	assert.ErrorIs(t, decrypt(tampered, key), ErrOutputDecrypt)
+
+	// A file cut after its first segment does not pass for a complete one
+	firstSegment := aesgcmHeaderSize + aesgcmSegmentSize + 16
+	assert.ErrorIs(t, decrypt(sealed[:firstSegment], key), ErrOutputDecrypt)
+	assert.ErrorIs(t, decrypt(sealed[:aesgcmHeaderSize-1], key), ErrOutputDecrypt)
+
+	jm, _, _ := newMemoryTestJobManager(t)
+	err := jm.RegisterOutputPipeline("bankapp", "txns", OutputPipeline{Encryption: AESGCMEncryption{Key: key[:10]}})
+	assert.ErrorIs(t, err, ErrInvalidOutputPipeline)
+}
+
+// newPGPEntity returns a new OpenPGP key pair, small enough to be quick to generate.
+func newPGPEntity(t *testing.T, name string) *openpgp.Entity {
+	t.Helper()
+	entity, err := openpgp.NewEntity(name, "", name+"@example.com", &packet.Config{RSABits: 1024})
+	require.NoError(t, err)
+	return entity
+}
+
+func TestPGPEncryption(t *testing.T) {
+	bank := newPGPEntity(t, "bank")
+	other := newPGPEntity(t, "other")
+	plain := []byte(strings.Repeat("TX001,1000.50\n", 1000))
+
+	for _, armored := range []bool{false, true} {
+		e := PGPEncryption{Recipients: openpgp.EntityList{bank}, Armor: armored}
+		sealed := encryptOutput(t, e, plain)
+		assert.Equal(t, armored, bytes.HasPrefix(sealed, []byte("-----BEGIN PGP MESSAGE-----")))
+
+		r, err := DecryptPGP(bytes.NewReader(sealed), openpgp.EntityList{bank})
+		require.NoError(t, err)
+		got, err := io.ReadAll(r)
+		require.NoError(t, err)
+		assert.Equal(t, plain, got)
+
+		_, err = DecryptPGP(bytes.NewReader(sealed), openpgp.EntityList{other})
+		assert.ErrorIs(t, err, ErrOutputDecrypt)
+	}
+	assert.Equal(t, ".pgp", PGPEncryption{}.Extension())
+	assert.Equal(t, ".asc", PGPEncryption{Armor: true}.Extension())
+}
+
+func TestOutputSigners(t *testing.T) {
+	message := []byte("TX001,1000.50\n")
+	altered := []byte("TX001,9000.50\n")
+
+	sender := newPGPEntity(t, "sender")
+	for _, armored := range []bool{false, true} {
+		sig, err := PGPSigner{Entity: sender, Armor: armored}.Sign(bytes.NewReader(message))
+		require.NoError(t, err)
+		signer, err := VerifyPGPSignature(openpgp.EntityList{sender}, bytes.NewReader(message), bytes.NewReader(sig))
+		require.NoError(t, err)
+		assert.Equal(t, sender.PrimaryKey.KeyId, signer.PrimaryKey.KeyId)
+		_, err = VerifyPGPSignature(openpgp.EntityList{sender}, bytes.NewReader(altered), bytes.NewReader(sig))
+		assert.ErrorIs(t, err, ErrOutputSignature)
+	}
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	sig, err := Ed25519Signer{Key: priv}.Sign(bytes.NewReader(message))
+	require.NoError(t, err)
+	assert.NoError(t, VerifyEd25519Signature(pub, bytes.NewReader(message), sig))
+	assert.ErrorIs(t, VerifyEd25519Signature(pub, bytes.NewReader(altered), sig), ErrOutputSignature)
+
+	jm, _, _ := newMemoryTestJobManager(t)
+	err = jm.RegisterOutputPipeline("bankapp", "txns", OutputPipeline{Signer: Ed25519Signer{Key: priv[:10]}})
+	assert.ErrorIs(t, err, ErrInvalidOutputPipeline)
+}
+
+func TestVerifyOutputManifest(t *testing.T) {
+	manifest := []byte("2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae  a.txt\n" +
+		"fcde2b2edba56bf408601fb721fe9b5c338d10ee429ea04fae5511b68fbf8fb9  b.txt\n")
+	assert.NoError(t, VerifyOutputManifest(manifest, "a.txt", strings.NewReader("foo")))
+	assert.NoError(t, VerifyOutputManifest(manifest, "b.txt", strings.NewReader("bar")))
+	assert.ErrorIs(t, VerifyOutputManifest(manifest, "a.txt", strings.NewReader("bar")), ErrOutputSignature)
+	assert.ErrorIs(t, VerifyOutputManifest(manifest, "c.txt", strings.NewReader("foo")), ErrOutputSignature)
+}
@@ -0,0 +1,508 @@
+package jobs
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// The contents that processors return for a logical output file are written to the
+// file by the output format registered for it with RegisterOutputFormat (see "Output
+// Formats" in the README). Logical files without a registered format are text files
+// with one line per content.
+
+var ErrInvalidOutputFormat = errors.New("invalid output format")
+
+// OutputSummary_t is passed to the functions of an output format that compute header
+// and trailer records. NRecords is the number of records written to the file from the
+// rows of the batch; it is 0 for header records.
+type OutputSummary_t struct {
+	BatchID     string
+	App         string
+	Op          string
+	LogicalFile string
+	NSuccess    int
+	NFailed     int
+	NAborted    int
+	NRecords    int
+}
+
+// OutputFormat is the format of a logical output file.
+type OutputFormat interface {
+	// ContentType is the MIME type of the files, recorded in their info block
+	ContentType() string
+	// NewEncoder returns an encoder writing one file to w
+	NewEncoder(w io.Writer) OutputEncoder
+	// Resumable reports whether a file can be continued by a new encoder, i.e. whether
+	// Encode writes out each record in full and keeps no state between records. A
+	// file in a format that is not resumable is written again from the start when the
+	// summarisation of its batch is resumed.
+	Resumable() bool
+}
+
+// OutputEncoder writes one output file. Begin is called before the first record of the
+// file and End after the last one; neither is called again for a file whose writing is
+// resumed by a new encoder.
+type OutputEncoder interface {
+	Begin(summary OutputSummary_t) error
+	// Encode writes the record for one content returned by a processor
+	Encode(content string) error
+	End(summary OutputSummary_t) error
+}
+
+// outputChecker is implemented by the output formats, encryptions and signers of this
+// package, which check their settings when they are registered.
+type outputChecker interface {
+	check() error
+}
+
+// RegisterOutputFormat sets the format of the logical output file logicalFile of the
+// batches of the given (app, op). The format is applied when the output files of a
+// batch are assembled during its summarisation.
+// The 'op' parameter is case-insensitive.
+func (jm *JobManager) RegisterOutputFormat(app string, op string, logicalFile string, format OutputFormat) error {
+	if format == nil {
+		return fmt.Errorf("%w: format is nil", ErrInvalidOutputFormat)
+	}
+	if logicalFile == "" {
+		return fmt.Errorf("%w: logical file name is empty", ErrInvalidOutputFormat)
+	}
+	if c, ok := format.(outputChecker); ok {
+		if err := c.check(); err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrInvalidOutputFormat, logicalFile, err)
+		}
+	}
+
+	op = strings.ToLower(op)
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+	jm.outputformats[outputFormatKey(app, op, logicalFile)] = format
+	return nil
+}
+
+// outputFormat returns the format of a logical output file of the batches of (app, op).
+func (jm *JobManager) outputFormat(app, op, logicalFile string) OutputFormat {
+	jm.mu.RLock()
+	format, ok := jm.outputformats[outputFormatKey(app, strings.ToLower(op), logicalFile)]
+	jm.mu.RUnlock()
+	if !ok {
+		return TextFormat{}
+	}
+	return format
+}
+
+func outputFormatKey(app, op, logicalFile string) string {
+	return app + op + "/" + logicalFile
+}
+
+// TextFormat writes each content on its own line, as it is. It is the format of the
+// logical files that have none registered.
+type TextFormat struct{}
+
+func (TextFormat) ContentType() string { return "text/plain" }
+func (TextFormat) Resumable() bool     { return true }
+
+func (TextFormat) NewEncoder(w io.Writer) OutputEncoder {
+	return textEncoder{w: w}
+}
+
+type textEncoder struct {
+	w io.Writer
+}
+
+func (e textEncoder) Begin(OutputSummary_t) error { return nil }
+func (e textEncoder) End(OutputSummary_t) error   { return nil }
+
+func (e textEncoder) Encode(content string) error {
+	_, err := io.WriteString(e.w, content+"\n")
+	return err
+}
+
+// CSVFormat writes a CSV file, with a header row of the column names followed by a
+// record for each content. A content is either a JSON object, whose members are
+// written in the columns of the same names, or a JSON array of the values of the
+// columns in order; fields are quoted as needed.
+//
+// Header returns the records written before the header row, and Trailer those
+// written after the last record, e.g. a count of records or a control total.
+type CSVFormat struct {
+	Columns  []string
+	NoHeader bool // leave out the header row
+	Comma    rune // field delimiter, ',' if 0
+	UseCRLF  bool // end records with \r\n instead of \n
+	Header   func(summary OutputSummary_t) [][]any
+	Trailer  func(summary OutputSummary_t) [][]any
+}
+
+func (f CSVFormat) ContentType() string { return "text/csv" }
+func (f CSVFormat) Resumable() bool     { return true }
+
+func (f CSVFormat) check() error {
+	if f.Comma == '"' || f.Comma == '\r' || f.Comma == '\n' || f.Comma == utf8.RuneError {
+		return fmt.Errorf("invalid CSV delimiter %q", f.Comma)
+	}
+	return checkColumns(f.Columns)
+}
+
+func (f CSVFormat) NewEncoder(w io.Writer) OutputEncoder {
+	cw := csv.NewWriter(w)
+	if f.Comma != 0 {
+		cw.Comma = f.Comma
+	}
+	cw.UseCRLF = f.UseCRLF
+	return &csvEncoder{format: f, w: cw}
+}
+
+type csvEncoder struct {
+	format CSVFormat
+	w      *csv.Writer
+}
+
+func (e *csvEncoder) Begin(summary OutputSummary_t) error {
+	var records [][]any
+	if e.format.Header != nil {
+		records = e.format.Header(summary)
+	}
+	for _, record := range records {
+		if err := e.w.Write(fieldTexts(record)); err != nil {
+			return err
+		}
+	}
+	if !e.format.NoHeader && len(e.format.Columns) > 0 {
+		if err := e.w.Write(e.format.Columns); err != nil {
+			return err
+		}
+	}
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func (e *csvEncoder) Encode(content string) error {
+	fields, err := outputFields(content, e.format.Columns)
+	if err != nil {
+		return err
+	}
+	if err := e.w.Write(fieldTexts(fields)); err != nil {
+		return err
+	}
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func (e *csvEncoder) End(summary OutputSummary_t) error {
+	if e.format.Trailer == nil {
+		return nil
+	}
+	for _, record := range e.format.Trailer(summary) {
+		if err := e.w.Write(fieldTexts(record)); err != nil {
+			return err
+		}
+	}
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// JSONLFormat writes a JSON Lines file: each content, which must be a JSON value, is
+// written compacted on its own line. Header and Trailer return values written as the
+// first and last lines.
+type JSONLFormat struct {
+	Header  func(summary OutputSummary_t) []any
+	Trailer func(summary OutputSummary_t) []any
+}
+
+func (f JSONLFormat) ContentType() string { return "application/x-ndjson" }
+func (f JSONLFormat) Resumable() bool     { return true }
+
+func (f JSONLFormat) NewEncoder(w io.Writer) OutputEncoder {
+	return &jsonlEncoder{format: f, w: w}
+}
+
+type jsonlEncoder struct {
+	format JSONLFormat
+	w      io.Writer
+	buf    bytes.Buffer
+}
+
+func (e *jsonlEncoder) Begin(summary OutputSummary_t) error {
+	if e.format.Header == nil {
+		return nil
+	}
+	return e.writeValues(e.format.Header(summary))
+}
+
+func (e *jsonlEncoder) Encode(content string) error {
+	e.buf.Reset()
+	if err := json.Compact(&e.buf, []byte(content)); err != nil {
+		return fmt.Errorf("content is not valid JSON: %w", err)
+	}
+	e.buf.WriteByte('\n')
+	_, err := e.w.Write(e.buf.Bytes())
+	return err
+}
+
+func (e *jsonlEncoder) End(summary OutputSummary_t) error {
+	if e.format.Trailer == nil {
+		return nil
+	}
+	return e.writeValues(e.format.Trailer(summary))
+}
+
+func (e *jsonlEncoder) writeValues(values []any) error {
+	for _, v := range values {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		if _, err := e.w.Write(append(data, '\n')); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FixedWidthFormat writes a file of fixed-width records, as used by banks. Each content
+// is a JSON object or array of the values of Fields, as for CSVFormat, and is written as
+// a record of the fields padded to their widths. A value longer than its field is an
+// error rather than being cut.
+//
+// Header and Trailer are optional records, with their own fields, written before the
+// first record and after the last one.
+type FixedWidthFormat struct {
+	Fields  []FixedWidthField
+	Header  *FixedWidthRecord
+	Trailer *FixedWidthRecord
+	LineEnd string // record terminator, "\n" if empty
+}
+
+// FixedWidthField is a field of a fixed-width record. Values are padded on the right
+// with Pad, or on the left if AlignRight is set; Pad is a space if 0.
+type FixedWidthField struct {
+	Name       string
+	Width      int
+	AlignRight bool
+	Pad        rune
+}
+
+// FixedWidthRecord is a header or trailer record of a FixedWidthFormat. Values returns
+// the values of its fields by name; fields without a value are left blank.
+type FixedWidthRecord struct {
+	Fields []FixedWidthField
+	Values func(summary OutputSummary_t) map[string]any
+}
+
+func (f FixedWidthFormat) ContentType() string { return "text/plain" }
+func (f FixedWidthFormat) Resumable() bool     { return true }
+
+func (f FixedWidthFormat) check() error {
+	if len(f.Fields) == 0 {
+		return errors.New("no fields")
+	}
+	if err := checkFixedWidthFields(f.Fields); err != nil {
+		return err
+	}
+	for _, r := range []*FixedWidthRecord{f.Header, f.Trailer} {
+		if r == nil {
+			continue
+		}
+		if r.Values == nil {
+			return errors.New("header or trailer record without Values")
+		}
+		if err := checkFixedWidthFields(r.Fields); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f FixedWidthFormat) NewEncoder(w io.Writer) OutputEncoder {
+	e := &fixedWidthEncoder{format: f, w: w, lineEnd: f.LineEnd}
+	if e.lineEnd == "" {
+		e.lineEnd = "\n"
+	}
+	for _, field := range f.Fields {
+		e.names = append(e.names, field.Name)
+	}
+	return e
+}
+
+type fixedWidthEncoder struct {
+	format  FixedWidthFormat
+	w       io.Writer
+	names   []string
+	lineEnd string
+}
+
+func (e *fixedWidthEncoder) Begin(summary OutputSummary_t) error {
+	return e.writeRecord(e.format.Header, summary)
+}
+
+func (e *fixedWidthEncoder) Encode(content string) error {
+	values, err := outputFields(content, e.names)
+	if err != nil {
+		return err
+	}
+	record, err := fixedWidthRecord(e.format.Fields, values)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(e.w, record+e.lineEnd)
+	return err
+}
+
+func (e *fixedWidthEncoder) End(summary OutputSummary_t) error {
+	return e.writeRecord(e.format.Trailer, summary)
+}
+
+func (e *fixedWidthEncoder) writeRecord(r *FixedWidthRecord, summary OutputSummary_t) error {
+	if r == nil {
+		return nil
+	}
+	byName := r.Values(summary)
+	values := make([]any, len(r.Fields))
+	for i, field := range r.Fields {
+		values[i] = byName[field.Name]
+	}
+	record, err := fixedWidthRecord(r.Fields, values)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(e.w, record+e.lineEnd)
+	return err
+}
+
+// fixedWidthRecord returns the record of the values of fields.
+func fixedWidthRecord(fields []FixedWidthField, values []any) (string, error) {
+	var sb strings.Builder
+	for i, field := range fields {
+		text := fieldText(values[i])
+		if strings.ContainsAny(text, "\r\n") {
+			return "", fmt.Errorf("value of field %s has a line break", field.Name)
+		}
+		n := utf8.RuneCountInString(text)
+		if n > field.Width {
+			return "", fmt.Errorf("value of field %s has %d characters, more than its width %d", field.Name, n, field.Width)
+		}
+		pad := field.Pad
+		if pad == 0 {
+			pad = ' '
+		}
+		padding := strings.Repeat(string(pad), field.Width-n)
+		if field.AlignRight {
+			sb.WriteString(padding + text)
+		} else {
+			sb.WriteString(text + padding)
+		}
+	}
+	return sb.String(), nil
+}
+
+func checkFixedWidthFields(fields []FixedWidthField) error {
+	names := make([]string, len(fields))
+	for i, field := range fields {
+		if field.Width < 1 {
+			return fmt.Errorf("field %s has width %d", field.Name, field.Width)
+		}
+		names[i] = field.Name
+	}
+	return checkColumns(names)
+}
+
+func checkColumns(columns []string) error {
+	seen := make(map[string]bool, len(columns))
+	for _, c := range columns {
+		if c == "" {
+			return errors.New("empty column name")
+		}
+		if seen[c] {
+			return fmt.Errorf("duplicate column %s", c)
+		}
+		seen[c] = true
+	}
+	return nil
+}
+
+// outputFields returns the values of columns in content, a JSON object with members
+// named after the columns, or a JSON array of values in the order of the columns.
+// Without columns, content must be an array, and all its values are returned.
+// Numbers are returned as json.Number, so that they are written as the processor
+// wrote them.
+func outputFields(content string, columns []string) ([]any, error) {
+	dec := json.NewDecoder(strings.NewReader(content))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("content is not valid JSON: %w", err)
+	}
+	if dec.More() {
+		return nil, errors.New("content has data after its JSON value")
+	}
+
+	switch v := v.(type) {
+	case []any:
+		if len(columns) > 0 && len(v) != len(columns) {
+			return nil, fmt.Errorf("content has %d values for %d columns", len(v), len(columns))
+		}
+		return v, nil
+	case map[string]any:
+		if len(columns) == 0 {
+			return nil, errors.New("content is a JSON object but the format has no columns")
+		}
+		fields := make([]any, len(columns))
+		for i, c := range columns {
+			fields[i] = v[c]
+		}
+		for name := range v {
+			if !containsString(columns, name) {
+				return nil, fmt.Errorf("content has unknown column %s", name)
+			}
+		}
+		return fields, nil
+	default:
+		return nil, errors.New("content is not a JSON object or array")
+	}
+}
+
+func containsString(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+func fieldTexts(values []any) []string {
+	texts := make([]string, len(values))
+	for i, v := range values {
+		texts[i] = fieldText(v)
+	}
+	return texts
+}
+
+// fieldText returns the text of a field value: strings as they are, nulls as empty
+// strings, and other values in their JSON form.
+func fieldText(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	case fmt.Stringer:
+		return v.String()
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
@@ -0,0 +1,197 @@
+package jobs
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterOutputFormat_Validation(t *testing.T) {
+	jm, _, _ := newMemoryTestJobManager(t)
+
+	tests := []struct {
+		name   string
+		file   string
+		format OutputFormat
+	}{
+		{"nil format", "out.csv", nil},
+		{"no file name", "", CSVFormat{}},
+		{"duplicate column", "out.csv", CSVFormat{Columns: []string{"id", "id"}}},
+		{"quote delimiter", "out.csv", CSVFormat{Comma: '"'}},
+		{"bad sheet name", "out.xlsx", XLSXFormat{Sheet: "a/b"}},
+		{"no fields", "out.txt", FixedWidthFormat{}},
+		{"zero width", "out.txt", FixedWidthFormat{Fields: []FixedWidthField{{Name: "id"}}}},
+		{"trailer without values", "out.txt", FixedWidthFormat{
+			Fields:  []FixedWidthField{{Name: "id", Width: 4}},
+			Trailer: &FixedWidthRecord{Fields: []FixedWidthField{{Name: "count", Width: 6}}},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := jm.RegisterOutputFormat("bankapp", "txns", tt.file, tt.format)
+			assert.ErrorIs(t, err, ErrInvalidOutputFormat)
+		})
+	}
+
+	require.NoError(t, jm.RegisterOutputFormat("bankapp", "TXNS", "out.csv", CSVFormat{Columns: []string{"id"}}))
+	assert.IsType(t, CSVFormat{}, jm.outputFormat("bankapp", "txns", "out.csv"))
+	assert.IsType(t, TextFormat{}, jm.outputFormat("bankapp", "txns", "other.txt"))
+}
+
+// encodeOutput writes a file of contents in format, with the summary counters of a
+// batch of three rows.
+func encodeOutput(t *testing.T, format OutputFormat, contents ...string) (string, error) {
+	t.Helper()
+	var buf bytes.Buffer
+	summary := OutputSummary_t{BatchID: "b1", App: "bankapp", Op: "txns", LogicalFile: "out", NSuccess: 2, NFailed: 1}
+	enc := format.NewEncoder(&buf)
+	if err := enc.Begin(summary); err != nil {
+		return "", err
+	}
+	for _, content := range contents {
+		if err := enc.Encode(content); err != nil {
+			return "", err
+		}
+		summary.NRecords++
+	}
+	if err := enc.End(summary); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func TestCSVFormat(t *testing.T) {
+	format := CSVFormat{
+		Columns: []string{"id", "note", "amount"},
+		Header: func(s OutputSummary_t) [][]any {
+			return [][]any{{"batch", s.BatchID}}
+		},
+		Trailer: func(s OutputSummary_t) [][]any {
+			return [][]any{{"records", s.NRecords}, {"failed", s.NFailed}}
+		},
+	}
+	out, err := encodeOutput(t, format,
+		`{"id": "TX001", "note": "rent, March", "amount": 1000.50}`,
+		`["TX002", "said \"hi\"", 20]`,
+		`{"id": "TX003", "amount": null}`,
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "batch,b1\n"+
+		"id,note,amount\n"+
+		"TX001,\"rent, March\",1000.50\n"+
+		"TX002,\"said \"\"hi\"\"\",20\n"+
+		"TX003,,\n"+
+		"records,3\n"+
+		"failed,1\n", out)
+
+	_, err = encodeOutput(t, format, `{"id": "TX001", "amout": 5}`)
+	assert.ErrorContains(t, err, "unknown column amout")
+	_, err = encodeOutput(t, format, `["TX001"]`)
+	assert.ErrorContains(t, err, "1 values for 3 columns")
+	_, err = encodeOutput(t, format, `TX001,rent,5`)
+	assert.Error(t, err)
+
+	out, err = encodeOutput(t, CSVFormat{Columns: []string{"a", "b"}, NoHeader: true, Comma: ';', UseCRLF: true}, `["x;y", "z"]`)
+	require.NoError(t, err)
+	assert.Equal(t, "\"x;y\";z\r\n", out)
+}
+
+func TestFixedWidthFormat(t *testing.T) {
+	format := FixedWidthFormat{
+		Fields: []FixedWidthField{
+			{Name: "type", Width: 1},
+			{Name: "account", Width: 8},
+			{Name: "amount", Width: 10, AlignRight: true, Pad: '0'},
+		},
+		Header: &FixedWidthRecord{
+			Fields: []FixedWidthField{{Name: "type", Width: 1}, {Name: "batch", Width: 18}},
+			Values: func(s OutputSummary_t) map[string]any {
+				return map[string]any{"type": "H", "batch": s.BatchID}
+			},
+		},
+		Trailer: &FixedWidthRecord{
+			Fields: []FixedWidthField{{Name: "type", Width: 1}, {Name: "count", Width: 6, AlignRight: true, Pad: '0'}},
+			Values: func(s OutputSummary_t) map[string]any {
+				return map[string]any{"type": "T", "count": s.NRecords}
+			},
+		},
+		LineEnd: "\r\n",
+	}
+	out, err := encodeOutput(t, format, `{"type": "D", "account": "AC01", "amount": 100050}`, `["D", "AC0002", "75"]`)
+	require.NoError(t, err)
+	assert.Equal(t, "Hb1                \r\n"+
+		"DAC01    0000100050\r\n"+
+		"DAC0002  0000000075\r\n"+
+		"T000002\r\n", out)
+
+	_, err = encodeOutput(t, format, `{"type": "D", "account": "AC000000001", "amount": 1}`)
+	assert.ErrorContains(t, err, "more than its width 8")
+	_, err = encodeOutput(t, format, `{"type": "D", "account": "AC\n01", "amount": 1}`)
+	assert.ErrorContains(t, err, "line break")
+}
+
+func TestJSONLFormat(t *testing.T) {
+	format := JSONLFormat{
+		Trailer: func(s OutputSummary_t) []any {
+			return []any{map[string]any{"records": s.NRecords, "success": s.NSuccess}}
+		},
+	}
+	out, err := encodeOutput(t, format, "{\n  \"id\": \"TX001\",\n  \"amount\": 5\n}", `"plain"`)
+	require.NoError(t, err)
+	assert.Equal(t, "{\"id\":\"TX001\",\"amount\":5}\n\"plain\"\n{\"records\":2,\"success\":2}\n", out)
+
+	_, err = encodeOutput(t, format, `{"id": `)
+	assert.ErrorContains(t, err, "not valid JSON")
+}
+
+func TestXLSXFormat(t *testing.T) {
+	format := XLSXFormat{
+		Sheet:   "Payments & refunds",
+		Columns: []string{"id", "amount", "ok"},
+		Trailer: func(s OutputSummary_t) [][]any {
+			return [][]any{{"Total records", s.NRecords}}
+		},
+	}
+	out, err := encodeOutput(t, format, `{"id": "TX<1>", "amount": 10.5, "ok": true}`, `["TX2", null, false]`)
+	require.NoError(t, err)
+
+	zr, err := zip.NewReader(bytes.NewReader([]byte(out)), int64(len(out)))
+	require.NoError(t, err)
+	parts := map[string]string{}
+	for _, f := range zr.File {
+		r, err := f.Open()
+		require.NoError(t, err)
+		data, err := io.ReadAll(r)
+		require.NoError(t, err)
+		r.Close()
+		parts[f.Name] = string(data)
+	}
+	require.Contains(t, parts, "[Content_Types].xml")
+	require.Contains(t, parts, "_rels/.rels")
+	require.Contains(t, parts, "xl/_rels/workbook.xml.rels")
+	assert.Contains(t, parts["xl/workbook.xml"], `<sheet name="Payments &amp; refunds" sheetId="1" r:id="rId1"/>`)
+
+	sheet := parts["xl/worksheets/sheet1.xml"]
+	for _, row := range []string{
+		`<row r="1"><c r="A1" t="inlineStr"><is><t xml:space="preserve">id</t></is></c><c r="B1" t="inlineStr"><is><t xml:space="preserve">amount</t></is></c><c r="C1" t="inlineStr"><is><t xml:space="preserve">ok</t></is></c></row>`,
+		`<row r="2"><c r="A2" t="inlineStr"><is><t xml:space="preserve">TX&lt;1&gt;</t></is></c><c r="B2"><v>10.5</v></c><c r="C2" t="b"><v>1</v></c></row>`,
+		`<row r="3"><c r="A3" t="inlineStr"><is><t xml:space="preserve">TX2</t></is></c><c r="C3" t="b"><v>0</v></c></row>`,
+		`<row r="4"><c r="A4" t="inlineStr"><is><t xml:space="preserve">Total records</t></is></c><c r="B4"><v>2</v></c></row>`,
+	} {
+		assert.Contains(t, sheet, row)
+	}
+	assert.True(t, bytes.HasSuffix([]byte(sheet), []byte("</sheetData></worksheet>")))
+}
+
+func TestXLSXColumn(t *testing.T) {
+	assert.Equal(t, "A", xlsxColumn(0))
+	assert.Equal(t, "Z", xlsxColumn(25))
+	assert.Equal(t, "AA", xlsxColumn(26))
+	assert.Equal(t, "AZ", xlsxColumn(51))
+	assert.Equal(t, "ZZ", xlsxColumn(701))
+	assert.Equal(t, "AAA", xlsxColumn(702))
+}
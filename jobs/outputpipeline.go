@@ -0,0 +1,235 @@
+package jobs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/google/uuid"
+	"github.com/remiges-tech/alya/jobs/objstore"
+)
+
+// OutputManifestName is the logical name of the checksum manifest of the output files
+// of a batch whose output pipeline asks for one.
+const OutputManifestName = "MANIFEST.sha256"
+
+var ErrInvalidOutputPipeline = errors.New("invalid output pipeline")
+
+// OutputPipeline is the processing applied to the output files of the batches of an
+// (app, op) when they are summarised. Each file is compressed with gzip if Compress is
+// set, and then encrypted with Encryption if it is not nil. Once all the files are
+// stored, Signer, if not nil, stores a detached signature of each of them, and, if
+// Manifest is set, a manifest of their SHA-256 checksums is stored, signed too.
+//
+// Signatures and the manifest are added to the output files of the batch: the
+// signature of a logical file is named after it with the extension of the signer
+// appended, and the manifest is named OutputManifestName.
+type OutputPipeline struct {
+	Compress   bool
+	Encryption OutputEncryption
+	Signer     OutputSigner
+	Manifest   bool
+}
+
+// RegisterOutputPipeline sets the output pipeline of the batches of the given (app, op).
+// The 'op' parameter is case-insensitive.
+func (jm *JobManager) RegisterOutputPipeline(app string, op string, pipeline OutputPipeline) error {
+	for _, step := range []any{pipeline.Encryption, pipeline.Signer} {
+		if c, ok := step.(outputChecker); ok {
+			if err := c.check(); err != nil {
+				return fmt.Errorf("%w: %v", ErrInvalidOutputPipeline, err)
+			}
+		}
+	}
+
+	op = strings.ToLower(op)
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+	jm.outputpipelines[app+op] = pipeline
+	return nil
+}
+
+// outputPipeline returns the output pipeline of the batches of (app, op).
+func (jm *JobManager) outputPipeline(app, op string) OutputPipeline {
+	jm.mu.RLock()
+	defer jm.mu.RUnlock()
+	return jm.outputpipelines[app+strings.ToLower(op)]
+}
+
+// transforms reports whether the pipeline changes the contents of the files, which
+// makes them impossible to continue from their uploaded parts.
+func (p OutputPipeline) transforms() bool {
+	return p.Compress || p.Encryption != nil
+}
+
+// extension returns what the pipeline appends to the file names of the files.
+func (p OutputPipeline) extension() string {
+	ext := ""
+	if p.Compress {
+		ext += ".gz"
+	}
+	if p.Encryption != nil {
+		ext += p.Encryption.Extension()
+	}
+	return ext
+}
+
+// contentType returns the content type of the files of a format once processed.
+func (p OutputPipeline) contentType(formatType string) string {
+	switch {
+	case p.Encryption != nil:
+		return p.Encryption.ContentType()
+	case p.Compress:
+		return "application/gzip"
+	}
+	return formatType
+}
+
+// writer returns a writer that compresses and encrypts what is written to it into w,
+// and that must be closed once the file is complete.
+func (p OutputPipeline) writer(w io.Writer) (io.WriteCloser, error) {
+	cw := &chainWriter{Writer: w}
+	if p.Encryption != nil {
+		ew, err := p.Encryption.Encrypt(w)
+		if err != nil {
+			return nil, err
+		}
+		cw.Writer = ew
+		cw.closers = append(cw.closers, ew)
+	}
+	if p.Compress {
+		gw := gzip.NewWriter(cw.Writer)
+		cw.Writer = gw
+		cw.closers = append([]io.Closer{gw}, cw.closers...)
+	}
+	return cw, nil
+}
+
+// chainWriter writes through a chain of writers, which are closed in order, outermost
+// first.
+type chainWriter struct {
+	io.Writer
+	closers []io.Closer
+}
+
+func (c *chainWriter) Close() error {
+	for _, closer := range c.closers {
+		if err := closer.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// OutputKeys holds the keys with which the consumers of output files decrypt them.
+type OutputKeys struct {
+	AESKey     []byte
+	PGPKeyring openpgp.KeyRing
+}
+
+// DecodeOutputFile returns a reader of the original contents of r, an output file named
+// filename in its info block, undoing the steps of the output pipeline named by the
+// extensions of the file name: first the decryption of a file ending with .enc (AES-GCM),
+// .pgp or .asc (OpenPGP), then the decompression of a file ending with .gz. A file
+// whose name ends with none of these is returned as it is, so logical files should not
+// be named with these extensions.
+func DecodeOutputFile(r io.Reader, filename string, keys OutputKeys) (io.Reader, error) {
+	var err error
+	switch path.Ext(filename) {
+	case ".enc":
+		r, err = NewAESGCMReader(r, keys.AESKey)
+		filename = strings.TrimSuffix(filename, ".enc")
+	case ".pgp", ".asc":
+		r, err = DecryptPGP(r, keys.PGPKeyring)
+		filename = strings.TrimSuffix(filename, path.Ext(filename))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if path.Ext(filename) == ".gz" {
+		return gzip.NewReader(r)
+	}
+	return r, nil
+}
+
+// seal stores the signatures of the output files and their manifest, as the pipeline
+// asks, and adds them to outputFiles and outputTypes. Their object IDs are derived from
+// those of the files, so that a resumed summarisation replaces them.
+func (w *outputWriter) seal(logicalFiles []string, outputFiles, outputTypes map[string]string) error {
+	store, bucket := w.jm.objStore, w.jm.config.BatchOutputBucket
+	signer := w.pipeline.Signer
+
+	var manifest strings.Builder
+	var objectIDs []string
+	for _, logicalFile := range logicalFiles {
+		f := w.cp.Files[logicalFile]
+		r, err := store.Get(w.ctx, bucket, f.ObjectID)
+		if err != nil {
+			return fmt.Errorf("failed to get %s to sign it: %w", logicalFile, err)
+		}
+		h := sha256.New()
+		var sig []byte
+		if signer != nil {
+			sig, err = signer.Sign(io.TeeReader(r, h))
+		} else {
+			_, err = io.Copy(h, r)
+		}
+		r.Close()
+		if err != nil {
+			return fmt.Errorf("failed to sign %s: %w", logicalFile, err)
+		}
+
+		filename := logicalFile + w.pipeline.extension()
+		fmt.Fprintf(&manifest, "%s  %s\n", hex.EncodeToString(h.Sum(nil)), filename)
+		objectIDs = append(objectIDs, f.ObjectID)
+		if signer != nil {
+			id := uuid.NewSHA1(w.batch.ID, []byte(f.ObjectID+signer.Extension())).String()
+			err := w.putSealObject(logicalFile+signer.Extension(), filename+signer.Extension(), id, signer.ContentType(), sig, outputFiles, outputTypes)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if !w.pipeline.Manifest || len(logicalFiles) == 0 {
+		return nil
+	}
+	data := []byte(manifest.String())
+	id := uuid.NewSHA1(w.batch.ID, []byte(strings.Join(objectIDs, ","))).String()
+	if err := w.putSealObject(OutputManifestName, OutputManifestName, id, "text/plain", data, outputFiles, outputTypes); err != nil {
+		return err
+	}
+	if signer != nil {
+		sig, err := signer.Sign(bytes.NewReader(data))
+		if err != nil {
+			return fmt.Errorf("failed to sign %s: %w", OutputManifestName, err)
+		}
+		name := OutputManifestName + signer.Extension()
+		sigID := uuid.NewSHA1(w.batch.ID, []byte(id+signer.Extension())).String()
+		if err := w.putSealObject(name, name, sigID, signer.ContentType(), sig, outputFiles, outputTypes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// putSealObject stores a signature or manifest as the output file logicalFile.
+func (w *outputWriter) putSealObject(logicalFile, filename, id, contentType string, data []byte, outputFiles, outputTypes map[string]string) error {
+	if _, ok := outputFiles[logicalFile]; ok {
+		return fmt.Errorf("output file %s clashes with a signature or manifest of the output pipeline", logicalFile)
+	}
+	err := objstore.ObjectPut(w.ctx, w.jm.objStore, w.jm.config.BatchOutputBucket, id, bytes.NewReader(data), int64(len(data)), w.objectInfo(filename, contentType))
+	if err != nil {
+		return fmt.Errorf("failed to put %s in object store: %w", logicalFile, err)
+	}
+	outputFiles[logicalFile] = id
+	outputTypes[logicalFile] = contentType
+	return nil
+}
@@ -0,0 +1,199 @@
+package jobs
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// xlsxMaxRows is the number of rows of an Excel worksheet.
+const xlsxMaxRows = 1 << 20
+
+// XLSXFormat writes an Excel workbook with a single sheet: a header row of the column
+// names followed by a row for each content, given as for CSVFormat. Numbers and
+// booleans are written as such, other values as text.
+//
+// Header returns the rows written before the header row, and Trailer those written
+// after the last row. The workbook is compressed as it is written, so a file in this
+// format is written again from the start if the summarisation of its batch is resumed.
+type XLSXFormat struct {
+	Sheet    string // name of the sheet, "Sheet1" if empty
+	Columns  []string
+	NoHeader bool // leave out the header row
+	Header   func(summary OutputSummary_t) [][]any
+	Trailer  func(summary OutputSummary_t) [][]any
+}
+
+func (f XLSXFormat) ContentType() string {
+	return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+}
+
+func (f XLSXFormat) Resumable() bool { return false }
+
+func (f XLSXFormat) check() error {
+	if len(f.Sheet) > 31 || strings.ContainsAny(f.Sheet, `[]:*?/\`) {
+		return fmt.Errorf("invalid sheet name %q", f.Sheet)
+	}
+	return checkColumns(f.Columns)
+}
+
+func (f XLSXFormat) NewEncoder(w io.Writer) OutputEncoder {
+	return &xlsxEncoder{format: f, zw: zip.NewWriter(w)}
+}
+
+type xlsxEncoder struct {
+	format XLSXFormat
+	zw     *zip.Writer
+	sheet  *bufio.Writer
+	nrows  int
+}
+
+const xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+	`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+	`<Default Extension="xml" ContentType="application/xml"/>` +
+	`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+	`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+	`</Types>`
+
+const xlsxRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+const xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+	`</Relationships>`
+
+const xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+	`<sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets></workbook>`
+
+const xlsxSheetStart = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+
+const xlsxSheetEnd = `</sheetData></worksheet>`
+
+// Begin writes the parts of the workbook other than the sheet, and then starts the
+// sheet, which is the last part of the archive so that its rows can be streamed.
+func (e *xlsxEncoder) Begin(summary OutputSummary_t) error {
+	sheet := e.format.Sheet
+	if sheet == "" {
+		sheet = "Sheet1"
+	}
+	var name strings.Builder
+	xml.EscapeText(&name, []byte(sheet))
+	parts := []struct{ name, data string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRels},
+		{"xl/workbook.xml", fmt.Sprintf(xlsxWorkbook, name.String())},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+	}
+	for _, p := range parts {
+		pw, err := e.zw.Create(p.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(pw, p.data); err != nil {
+			return err
+		}
+	}
+
+	sw, err := e.zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	e.sheet = bufio.NewWriter(sw)
+	e.sheet.WriteString(xlsxSheetStart)
+
+	if e.format.Header != nil {
+		for _, row := range e.format.Header(summary) {
+			if err := e.writeRow(row); err != nil {
+				return err
+			}
+		}
+	}
+	if !e.format.NoHeader && len(e.format.Columns) > 0 {
+		row := make([]any, len(e.format.Columns))
+		for i, c := range e.format.Columns {
+			row[i] = c
+		}
+		if err := e.writeRow(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *xlsxEncoder) Encode(content string) error {
+	fields, err := outputFields(content, e.format.Columns)
+	if err != nil {
+		return err
+	}
+	return e.writeRow(fields)
+}
+
+func (e *xlsxEncoder) End(summary OutputSummary_t) error {
+	if e.format.Trailer != nil {
+		for _, row := range e.format.Trailer(summary) {
+			if err := e.writeRow(row); err != nil {
+				return err
+			}
+		}
+	}
+	e.sheet.WriteString(xlsxSheetEnd)
+	if err := e.sheet.Flush(); err != nil {
+		return err
+	}
+	return e.zw.Close()
+}
+
+// writeRow writes a row of the sheet, with a cell for each non-null value.
+func (e *xlsxEncoder) writeRow(values []any) error {
+	if e.sheet == nil {
+		return errors.New("XLSX row written before the start of the sheet")
+	}
+	if e.nrows == xlsxMaxRows {
+		return fmt.Errorf("sheet has more than %d rows", xlsxMaxRows)
+	}
+	e.nrows++
+	fmt.Fprintf(e.sheet, `<row r="%d">`, e.nrows)
+	for i, v := range values {
+		if v == nil {
+			continue
+		}
+		ref := xlsxColumn(i) + strconv.Itoa(e.nrows)
+		switch v := v.(type) {
+		case bool:
+			b := 0
+			if v {
+				b = 1
+			}
+			fmt.Fprintf(e.sheet, `<c r="%s" t="b"><v>%d</v></c>`, ref, b)
+		case json.Number, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+			fmt.Fprintf(e.sheet, `<c r="%s"><v>%v</v></c>`, ref, v)
+		default:
+			fmt.Fprintf(e.sheet, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">`, ref)
+			xml.EscapeText(e.sheet, []byte(fieldText(v)))
+			e.sheet.WriteString(`</t></is></c>`)
+		}
+	}
+	_, err := e.sheet.WriteString(`</row>`)
+	return err
+}
+
+// xlsxColumn returns the letters of the column with the zero-based index i.
+func xlsxColumn(i int) string {
+	var col []byte
+	for i++; i > 0; i = (i - 1) / 26 {
+		col = append([]byte{byte('A' + (i-1)%26)}, col...)
+	}
+	return string(col)
+}
@@ -0,0 +1,24 @@
+package jobs
+
+import "context"
+
+// partitionMonthsAhead is how many months ahead of the current one the partitions
+// of a partitioned batchrows table are created.
+const partitionMonthsAhead = 3
+
+// ensureBatchRowPartitions creates the partitions of a partitioned batchrows table
+// for the current month and the next partitionMonthsAhead months, if they do not
+// exist yet. Rows whose month has no partition go to the default partition, from
+// which they can't be dropped, so this runs long before they are needed.
+func (jm *JobManager) ensureBatchRowPartitions(ctx context.Context) {
+	created, err := jm.queries.EnsureBatchRowPartitions(ctx, partitionMonthsAhead)
+	if err != nil {
+		jm.logger.Error(err).LogActivity("Failed to create batchrows partitions", nil)
+		return
+	}
+	if len(created) > 0 {
+		jm.logger.Info().LogActivity("Created batchrows partitions", map[string]any{
+			"partitions": created,
+		})
+	}
+}
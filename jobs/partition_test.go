@@ -0,0 +1,68 @@
+package jobs
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"testing"
+
+	"github.com/jackc/tern/v2/migrate"
+	"github.com/remiges-tech/alya/jobs/objstore"
+	"github.com/remiges-tech/alya/jobs/pg/batchsqlc"
+	"github.com/remiges-tech/alya/jobs/pg/batchsqlc/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPartitionedMigrationsAreSeparate(t *testing.T) {
+	main, err := fs.Sub(migrations, "pg/migrations")
+	require.NoError(t, err)
+	paths, err := migrate.FindMigrations(main)
+	require.NoError(t, err)
+	for _, path := range paths {
+		assert.NotContains(t, path, "partitioned")
+	}
+
+	partitioned, err := fs.Sub(migrations, "pg/migrations/partitioned")
+	require.NoError(t, err)
+	paths, err = migrate.FindMigrations(partitioned)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"001_batchrows_partitioned.sql",
+		"002_batchrows_leases.sql",
+		"003_batchrows_line_index.sql",
+		"004_batchrows_priority.sql",
+	}, paths)
+}
+
+func TestEnsureBatchRowPartitions(t *testing.T) {
+	q := &mocks.QuerierMock{
+		EnsureBatchRowPartitionsFunc: func(ctx context.Context, monthsAhead int32) ([]string, error) {
+			return []string{"batchrows_203001"}, nil
+		},
+	}
+	jm := newTestJobManager(q, nil)
+
+	jm.ensureBatchRowPartitions(context.Background())
+	require.Len(t, q.EnsureBatchRowPartitionsCalls(), 1)
+	assert.Equal(t, int32(partitionMonthsAhead), q.EnsureBatchRowPartitionsCalls()[0].MonthsAhead)
+}
+
+func TestArchiveAndDeleteBatches_Partitioned(t *testing.T) {
+	batch := purgeTestBatch(`{}`)
+	q := newPurgeQuerier([]batchsqlc.Batch{batch}, []batchsqlc.Batchrow{{Batch: batch.ID, Line: 1, Input: []byte(`{}`)}})
+	jm := newTestJobManager(q, nil)
+	jm.config.PartitionedBatchRows = true
+	jm.objStore = &objstore.ObjectStoreMock{
+		PutFunc: func(ctx context.Context, bucket, obj string, reader io.Reader, size int64, contentType string) error {
+			return nil
+		},
+	}
+
+	purged, nrows, _, err := jm.archiveAndDeleteBatches(context.Background(), q, "bank", batch.Doneat)
+	require.NoError(t, err)
+	assert.Len(t, purged, 1)
+	assert.Equal(t, 1, nrows)
+	assert.Empty(t, q.DeleteBatchRowsByBatchIDsCalls())
+	assert.Len(t, q.DeleteBatchesByIDsCalls(), 1)
+}
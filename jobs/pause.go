@@ -0,0 +1,108 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/remiges-tech/alya/jobs/pg/batchsqlc"
+)
+
+var (
+	ErrBatchNotPausable = errors.New("only queued and in-progress batches can be paused")
+	ErrBatchNotPaused   = errors.New("batch is not paused")
+)
+
+// BatchPause stops the queued rows of a batch or slow query from being picked up until
+// BatchResume is called, for instance while a system its processor depends on is down.
+// Rows already in progress are left to finish, and no row or counter is changed: the
+// batch is only moved to the 'paused' status. Pausing a paused batch does nothing.
+func (jm *JobManager) BatchPause(batchID string) error {
+	ctx := context.Background()
+
+	batchUUID, err := uuid.Parse(batchID)
+	if err != nil {
+		return fmt.Errorf("invalid batch ID: %w", err)
+	}
+
+	app, err := jm.queries.PauseBatch(ctx, batchUUID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		batch, err := jm.queries.GetBatchByID(ctx, batchUUID)
+		if err != nil {
+			return fmt.Errorf("failed to get batch by ID: %w", err)
+		}
+		if batch.Status == batchsqlc.StatusEnumPaused {
+			return nil
+		}
+		return fmt.Errorf("%w: batch %s is %s", ErrBatchNotPausable, batchID, batch.Status)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to pause batch: %w", err)
+	}
+	jm.cachePausedStatus(batchUUID, batchsqlc.StatusEnumPaused)
+
+	jm.logger.Info().LogActivity("Batch paused", map[string]any{
+		"batchId": batchID,
+		"app":     app,
+	})
+	return nil
+}
+
+// BatchResume lets the queued rows of a batch paused by BatchPause be picked up again.
+// Resuming a batch that is queued or in progress does nothing.
+func (jm *JobManager) BatchResume(batchID string) error {
+	ctx := context.Background()
+
+	batchUUID, err := uuid.Parse(batchID)
+	if err != nil {
+		return fmt.Errorf("invalid batch ID: %w", err)
+	}
+
+	app, err := jm.queries.ResumeBatch(ctx, batchUUID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		batch, err := jm.queries.GetBatchByID(ctx, batchUUID)
+		if err != nil {
+			return fmt.Errorf("failed to get batch by ID: %w", err)
+		}
+		if batch.Status == batchsqlc.StatusEnumQueued || batch.Status == batchsqlc.StatusEnumInprog {
+			return nil
+		}
+		return fmt.Errorf("%w: batch %s is %s", ErrBatchNotPaused, batchID, batch.Status)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to resume batch: %w", err)
+	}
+	// The batch is resumed already; polling picks it up if the notification fails
+	if err := jm.notifyJobsQueued(ctx, jm.queries, app); err != nil {
+		jm.logger.Warn().LogActivity("Failed to notify queued jobs", map[string]any{
+			"app":   app,
+			"error": err.Error(),
+		})
+	}
+	jm.cachePausedStatus(batchUUID, batchsqlc.StatusEnumQueued)
+
+	jm.logger.Info().LogActivity("Batch resumed", map[string]any{
+		"batchId": batchID,
+		"app":     app,
+	})
+	return nil
+}
+
+// cachePausedStatus caches the status of a batch that has just been paused or resumed
+// in Redis, and drops its cached progress, which holds the old status.
+func (jm *JobManager) cachePausedStatus(batchUUID uuid.UUID, status batchsqlc.StatusEnum) {
+	jm.clearBatchProgress(batchUUID)
+	if jm.statusCache == nil {
+		return
+	}
+	err := updateStatusInRedis(jm.statusCache, batchUUID, status, jm.config.BatchStatusCacheDurSec)
+	if err != nil {
+		jm.logger.Warn().LogActivity("Failed to update status in Redis cache", map[string]any{
+			"batchId": batchUUID.String(),
+			"error":   err.Error(),
+		})
+		// Continue despite Redis failure - Redis is just a cache
+	}
+}
@@ -0,0 +1,117 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/remiges-tech/alya/jobs/pg/batchsqlc"
+	"github.com/remiges-tech/alya/jobs/pg/batchsqlc/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newPauseQuerier returns a mock for a batch with the given status, which PauseBatch
+// and ResumeBatch move between queued or in progress and paused.
+func newPauseQuerier(status batchsqlc.StatusEnum) *mocks.QuerierMock {
+	return &mocks.QuerierMock{
+		PauseBatchFunc: func(ctx context.Context, id uuid.UUID) (string, error) {
+			if status != batchsqlc.StatusEnumQueued && status != batchsqlc.StatusEnumInprog {
+				return "", pgx.ErrNoRows
+			}
+			return "bank", nil
+		},
+		ResumeBatchFunc: func(ctx context.Context, id uuid.UUID) (string, error) {
+			if status != batchsqlc.StatusEnumPaused {
+				return "", pgx.ErrNoRows
+			}
+			return "bank", nil
+		},
+		GetBatchByIDFunc: func(ctx context.Context, id uuid.UUID) (batchsqlc.Batch, error) {
+			return batchsqlc.Batch{ID: id, App: "bank", Status: status}, nil
+		},
+		NotifyJobsQueuedFunc: func(ctx context.Context, app string) (string, error) {
+			return "", nil
+		},
+	}
+}
+
+func TestBatchPause(t *testing.T) {
+	jm, redisClient := newLimitsTestJobManager(t)
+	q := newPauseQuerier(batchsqlc.StatusEnumInprog)
+	jm.queries = q
+	ctx := context.Background()
+	batchID := uuid.NewString()
+	require.NoError(t, redisClient.HSet(ctx, BatchProgressKey(batchID), progressStatusField, "inprog").Err())
+
+	require.NoError(t, jm.BatchPause(batchID))
+	require.Len(t, q.PauseBatchCalls(), 1)
+	assert.Equal(t, batchID, q.PauseBatchCalls()[0].ID.String())
+
+	status, err := redisClient.Get(ctx, BatchStatusKey(batchID)).Result()
+	require.NoError(t, err)
+	assert.Equal(t, "paused", status)
+	n, err := redisClient.Exists(ctx, BatchProgressKey(batchID)).Result()
+	require.NoError(t, err)
+	assert.Zero(t, n)
+}
+
+func TestBatchPause_NotPausable(t *testing.T) {
+	jm, redisClient := newLimitsTestJobManager(t)
+	batchID := uuid.NewString()
+
+	jm.queries = newPauseQuerier(batchsqlc.StatusEnumSuccess)
+	assert.ErrorIs(t, jm.BatchPause(batchID), ErrBatchNotPausable)
+	jm.queries = newPauseQuerier(batchsqlc.StatusEnumWait)
+	assert.ErrorIs(t, jm.BatchPause(batchID), ErrBatchNotPausable)
+
+	// Pausing again is not an error
+	jm.queries = newPauseQuerier(batchsqlc.StatusEnumPaused)
+	assert.NoError(t, jm.BatchPause(batchID))
+
+	n, err := redisClient.Exists(context.Background(), BatchStatusKey(batchID)).Result()
+	require.NoError(t, err)
+	assert.Zero(t, n)
+
+	assert.Error(t, jm.BatchPause("not-a-uuid"))
+}
+
+func TestBatchResume(t *testing.T) {
+	jm, redisClient := newLimitsTestJobManager(t)
+	q := newPauseQuerier(batchsqlc.StatusEnumPaused)
+	jm.queries = q
+	batchID := uuid.NewString()
+
+	require.NoError(t, jm.BatchResume(batchID))
+	require.Len(t, q.ResumeBatchCalls(), 1)
+	require.Len(t, q.NotifyJobsQueuedCalls(), 1)
+	assert.Equal(t, "bank", q.NotifyJobsQueuedCalls()[0].App)
+
+	status, err := redisClient.Get(context.Background(), BatchStatusKey(batchID)).Result()
+	require.NoError(t, err)
+	assert.Equal(t, "queued", status)
+}
+
+func TestBatchResume_NotPaused(t *testing.T) {
+	jm, _ := newLimitsTestJobManager(t)
+	batchID := uuid.NewString()
+
+	jm.queries = newPauseQuerier(batchsqlc.StatusEnumFailed)
+	assert.ErrorIs(t, jm.BatchResume(batchID), ErrBatchNotPaused)
+
+	// Resuming a batch that is running is not an error
+	q := newPauseQuerier(batchsqlc.StatusEnumInprog)
+	jm.queries = q
+	assert.NoError(t, jm.BatchResume(batchID))
+	assert.Empty(t, q.NotifyJobsQueuedCalls())
+}
+
+func TestBatchProgress_Paused(t *testing.T) {
+	progress := BatchProgress_t{Status: batchsqlc.StatusEnumPaused, NQueued: 6, NSuccess: 10}
+	progress.estimate(time.Now().Add(-10*time.Second), time.Now())
+	assert.InDelta(t, 1.0, progress.Throughput, 0.05)
+	assert.Zero(t, progress.ETA)
+	assert.Equal(t, BatchPaused, mapStatusEnum(progress.Status))
+}
@@ -12,17 +12,33 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const archiveBatchRows = `-- name: ArchiveBatchRows :exec
+INSERT INTO batchrow_history (rowid, batch, line, input, status, doneat, res, blobrows, messages, doneby, outputfiles)
+SELECT r.rowid, r.batch, r.line, r.input, r.status, r.doneat, r.res, r.blobrows, r.messages, r.doneby, b.outputfiles
+FROM batchrows r
+JOIN batches b ON b.id = r.batch
+WHERE r.rowid = ANY($1::bigint[])
+`
+
+// Copies the results of the rows, and the output files of their batch, to
+// batchrow_history before the rows are re-run.
+func (q *Queries) ArchiveBatchRows(ctx context.Context, rowids []int64) error {
+	_, err := q.db.Exec(ctx, archiveBatchRows, rowids)
+	return err
+}
+
 const bulkInsertIntoBatchRows = `-- name: BulkInsertIntoBatchRows :execrows
-INSERT INTO batchrows (batch, line, input, status, reqat) 
+INSERT INTO batchrows (batch, line, input, status, reqat, priority) 
 VALUES 
-    (unnest($1::uuid[]), unnest($2::int[]), unnest($3::jsonb[]), 'queued', unnest($4::timestamp[]))
+    (unnest($1::uuid[]), unnest($2::int[]), unnest($3::jsonb[]), 'queued', unnest($4::timestamp[]), $5::int)
 `
 
 type BulkInsertIntoBatchRowsParams struct {
-	Batch []uuid.UUID        `json:"batch"`
-	Line  []int32            `json:"line"`
-	Input [][]byte           `json:"input"`
-	Reqat []pgtype.Timestamp `json:"reqat"`
+	Batch    []uuid.UUID        `json:"batch"`
+	Line     []int32            `json:"line"`
+	Input    [][]byte           `json:"input"`
+	Reqat    []pgtype.Timestamp `json:"reqat"`
+	Priority int32              `json:"priority"`
 }
 
 func (q *Queries) BulkInsertIntoBatchRows(ctx context.Context, arg BulkInsertIntoBatchRowsParams) (int64, error) {
@@ -31,6 +47,7 @@ func (q *Queries) BulkInsertIntoBatchRows(ctx context.Context, arg BulkInsertInt
 		arg.Line,
 		arg.Input,
 		arg.Reqat,
+		arg.Priority,
 	)
 	if err != nil {
 		return 0, err
@@ -38,6 +55,118 @@ func (q *Queries) BulkInsertIntoBatchRows(ctx context.Context, arg BulkInsertInt
 	return result.RowsAffected(), nil
 }
 
+const claimIdempotencyKey = `-- name: ClaimIdempotencyKey :one
+INSERT INTO batch_idempotency_keys (app, key, batch, created_at)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (app, key) DO UPDATE
+SET batch = EXCLUDED.batch, created_at = EXCLUDED.created_at
+WHERE batch_idempotency_keys.created_at < $5
+RETURNING batch
+`
+
+type ClaimIdempotencyKeyParams struct {
+	App       string           `json:"app"`
+	Key       string           `json:"key"`
+	Batch     uuid.UUID        `json:"batch"`
+	CreatedAt pgtype.Timestamp `json:"created_at"`
+	Cutoff    pgtype.Timestamp `json:"cutoff"`
+}
+
+// Records @key for @batch, unless the app used it for another batch at or after
+// @cutoff, in which case no row is returned. A concurrent claim of the same key
+// waits for the first one to commit or roll back.
+func (q *Queries) ClaimIdempotencyKey(ctx context.Context, arg ClaimIdempotencyKeyParams) (uuid.UUID, error) {
+	row := q.db.QueryRow(ctx, claimIdempotencyKey,
+		arg.App,
+		arg.Key,
+		arg.Batch,
+		arg.CreatedAt,
+		arg.Cutoff,
+	)
+	var batch uuid.UUID
+	err := row.Scan(&batch)
+	return batch, err
+}
+
+const claimWebhookDeliveries = `-- name: ClaimWebhookDeliveries :many
+UPDATE webhook_deliveries
+SET next_attempt_at = $1
+WHERE id IN (
+    SELECT id
+    FROM webhook_deliveries
+    WHERE status = 'pending' AND next_attempt_at <= $2
+    ORDER BY next_attempt_at
+    LIMIT $3
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, batch, url, payload, attempts, next_attempt_at
+`
+
+type ClaimWebhookDeliveriesParams struct {
+	LeaseUntil pgtype.Timestamp `json:"lease_until"`
+	Now        pgtype.Timestamp `json:"now"`
+	Limit      int32            `json:"limit"`
+}
+
+type ClaimWebhookDeliveriesRow struct {
+	ID            int64            `json:"id"`
+	Batch         uuid.UUID        `json:"batch"`
+	Url           string           `json:"url"`
+	Payload       []byte           `json:"payload"`
+	Attempts      int32            `json:"attempts"`
+	NextAttemptAt pgtype.Timestamp `json:"next_attempt_at"`
+}
+
+// Takes up to limit pending deliveries that are due, and leases them to the caller
+// by moving their next attempt to lease_until. A delivery whose caller dies before
+// recording the outcome is tried again once the lease has run out.
+func (q *Queries) ClaimWebhookDeliveries(ctx context.Context, arg ClaimWebhookDeliveriesParams) ([]ClaimWebhookDeliveriesRow, error) {
+	rows, err := q.db.Query(ctx, claimWebhookDeliveries, arg.LeaseUntil, arg.Now, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ClaimWebhookDeliveriesRow
+	for rows.Next() {
+		var i ClaimWebhookDeliveriesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Batch,
+			&i.Url,
+			&i.Payload,
+			&i.Attempts,
+			&i.NextAttemptAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+type CopyBatchRowsParams struct {
+	Batch    uuid.UUID        `json:"batch"`
+	Line     int32            `json:"line"`
+	Input    []byte           `json:"input"`
+	Reqat    pgtype.Timestamp `json:"reqat"`
+	Priority int32            `json:"priority"`
+}
+
+const countBatchDependencies = `-- name: CountBatchDependencies :one
+SELECT COUNT(*) FROM batch_dependencies
+WHERE batch = $1
+`
+
+func (q *Queries) CountBatchDependencies(ctx context.Context, batch uuid.UUID) (int64, error) {
+	row := q.db.QueryRow(ctx, countBatchDependencies, batch)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const countBatchRowsByBatchIDAndStatus = `-- name: CountBatchRowsByBatchIDAndStatus :one
 SELECT COUNT(*)
 FROM batchrows
@@ -61,6 +190,7 @@ const countBatchRowsInProgByBatchID = `-- name: CountBatchRowsInProgByBatchID :o
 SELECT COUNT(*)
 FROM batchrows
 WHERE batch = $1 AND status = 'inprog'
+  AND reqat >= (SELECT reqat FROM batches WHERE id = $1) - INTERVAL '1 day'
 `
 
 func (q *Queries) CountBatchRowsInProgByBatchID(ctx context.Context, batch uuid.UUID) (int64, error) {
@@ -74,6 +204,7 @@ const countBatchRowsQueuedByBatchID = `-- name: CountBatchRowsQueuedByBatchID :o
 SELECT COUNT(*)
 FROM batchrows
 WHERE batch = $1 AND status = 'queued'
+  AND reqat >= (SELECT reqat FROM batches WHERE id = $1) - INTERVAL '1 day'
 `
 
 func (q *Queries) CountBatchRowsQueuedByBatchID(ctx context.Context, batch uuid.UUID) (int64, error) {
@@ -83,6 +214,134 @@ func (q *Queries) CountBatchRowsQueuedByBatchID(ctx context.Context, batch uuid.
 	return count, err
 }
 
+const countBatchesToPurge = `-- name: CountBatchesToPurge :one
+SELECT COUNT(*) AS nbatches,
+  COALESCE(SUM((SELECT COUNT(*) FROM batchrows r WHERE r.batch = b.id)), 0)::bigint AS nrows,
+  COALESCE(SUM(CASE WHEN jsonb_typeof(b.outputfiles) = 'object'
+    THEN (SELECT COUNT(*) FROM jsonb_object_keys(b.outputfiles)) ELSE 0 END), 0)::bigint AS noutputfiles
+FROM batches b
+WHERE b.app = $1
+  AND b.status IN ('success', 'failed', 'aborted')
+  AND b.doneat < $2
+`
+
+type CountBatchesToPurgeParams struct {
+	App    string           `json:"app"`
+	Cutoff pgtype.Timestamp `json:"cutoff"`
+}
+
+type CountBatchesToPurgeRow struct {
+	Nbatches     int64 `json:"nbatches"`
+	Nrows        int64 `json:"nrows"`
+	Noutputfiles int64 `json:"noutputfiles"`
+}
+
+// Counts what GetBatchesToPurge would purge in all, for dry runs.
+func (q *Queries) CountBatchesToPurge(ctx context.Context, arg CountBatchesToPurgeParams) (CountBatchesToPurgeRow, error) {
+	row := q.db.QueryRow(ctx, countBatchesToPurge, arg.App, arg.Cutoff)
+	var i CountBatchesToPurgeRow
+	err := row.Scan(
+		&i.Nbatches,
+		&i.Nrows,
+		&i.Noutputfiles,
+	)
+	return i, err
+}
+
+const deleteBatchFilesByBatchIDs = `-- name: DeleteBatchFilesByBatchIDs :exec
+DELETE FROM batch_files
+WHERE batch_id = ANY($1::uuid[])
+`
+
+func (q *Queries) DeleteBatchFilesByBatchIDs(ctx context.Context, batchIds []uuid.UUID) error {
+	_, err := q.db.Exec(ctx, deleteBatchFilesByBatchIDs, batchIds)
+	return err
+}
+
+const deleteBatchRowsByBatchIDs = `-- name: DeleteBatchRowsByBatchIDs :exec
+DELETE FROM batchrows
+WHERE batch = ANY($1::uuid[])
+`
+
+func (q *Queries) DeleteBatchRowsByBatchIDs(ctx context.Context, batchIds []uuid.UUID) error {
+	_, err := q.db.Exec(ctx, deleteBatchRowsByBatchIDs, batchIds)
+	return err
+}
+
+const deleteBatchesByIDs = `-- name: DeleteBatchesByIDs :execrows
+DELETE FROM batches
+WHERE id = ANY($1::uuid[])
+`
+
+func (q *Queries) DeleteBatchesByIDs(ctx context.Context, ids []uuid.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteBatchesByIDs, ids)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteWorker = `-- name: DeleteWorker :exec
+DELETE FROM workers
+WHERE instance_id = $1
+`
+
+// Removes a JobManager instance from the worker registry.
+func (q *Queries) DeleteWorker(ctx context.Context, instanceID string) error {
+	_, err := q.db.Exec(ctx, deleteWorker, instanceID)
+	return err
+}
+
+const dropBatchRowPartitions = `-- name: DropBatchRowPartitions :many
+SELECT name::text FROM alya_drop_batchrows_partitions($1::timestamp) AS name
+`
+
+// Only for a batchrows table partitioned by MigrateBatchRowsPartitioned.
+func (q *Queries) DropBatchRowPartitions(ctx context.Context, cutoff pgtype.Timestamp) ([]string, error) {
+	rows, err := q.db.Query(ctx, dropBatchRowPartitions, cutoff)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		items = append(items, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ensureBatchRowPartitions = `-- name: EnsureBatchRowPartitions :many
+SELECT name::text FROM alya_ensure_batchrows_partitions($1::int) AS name
+`
+
+// Only for a batchrows table partitioned by MigrateBatchRowsPartitioned.
+func (q *Queries) EnsureBatchRowPartitions(ctx context.Context, monthsAhead int32) ([]string, error) {
+	rows, err := q.db.Query(ctx, ensureBatchRowPartitions, monthsAhead)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		items = append(items, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const fetchBatchRowsForBatchDone = `-- name: FetchBatchRowsForBatchDone :many
 SELECT line, status, res, messages
 FROM batchrows
@@ -122,32 +381,49 @@ func (q *Queries) FetchBatchRowsForBatchDone(ctx context.Context, batch uuid.UUI
 }
 
 const fetchBlockOfRows = `-- name: FetchBlockOfRows :many
-SELECT batches.app, batches.status, batches.op, batches.context, batchrows.batch, batchrows.rowid, batchrows.line, batchrows.input
+SELECT batches.app, batches.status, batches.op, batches.context, batchrows.batch, batchrows.rowid, batchrows.line, batchrows.input, batchrows.attempts
 FROM batchrows
 INNER JOIN batches ON batchrows.batch = batches.id
-WHERE batchrows.status = $1 AND batches.status != 'wait'
-LIMIT $2
+WHERE batchrows.status = $1 AND batches.status NOT IN ('wait', 'paused')
+  AND (batchrows.not_before IS NULL OR batchrows.not_before <= $2::timestamp)
+  AND (batches.runat IS NULL OR batches.runat <= $2::timestamp)
+  AND batchrows.reqat >= (
+      SELECT COALESCE(MIN(pending.reqat), 'infinity'::timestamp) - INTERVAL '1 day'
+      FROM batches pending
+      WHERE pending.status IN ('queued', 'inprog'))
+ORDER BY batchrows.priority DESC, batchrows.rowid
+LIMIT $3
 FOR UPDATE OF batchrows, batches SKIP LOCKED
 `
 
 type FetchBlockOfRowsParams struct {
-	Status StatusEnum `json:"status"`
-	Limit  int32      `json:"limit"`
+	Status StatusEnum       `json:"status"`
+	Now    pgtype.Timestamp `json:"now"`
+	Limit  int32            `json:"limit"`
 }
 
 type FetchBlockOfRowsRow struct {
-	App     string     `json:"app"`
-	Status  StatusEnum `json:"status"`
-	Op      string     `json:"op"`
-	Context []byte     `json:"context"`
-	Batch   uuid.UUID  `json:"batch"`
-	Rowid   int64      `json:"rowid"`
-	Line    int32      `json:"line"`
-	Input   []byte     `json:"input"`
+	App      string     `json:"app"`
+	Status   StatusEnum `json:"status"`
+	Op       string     `json:"op"`
+	Context  []byte     `json:"context"`
+	Batch    uuid.UUID  `json:"batch"`
+	Rowid    int64      `json:"rowid"`
+	Line     int32      `json:"line"`
+	Input    []byte     `json:"input"`
+	Attempts int32      `json:"attempts"`
 }
 
+// Rows requeued for retry are skipped until their not_before time has passed,
+// and rows of a delayed batch until the batch's runat time has passed.
+// Rows of higher priority batches are returned first, oldest rows first
+// within the same priority. The priority of the batch is copied onto its rows,
+// so that the rows are read in this order from idx_batchrows_fetch.
+// The bound on reqat lets a batchrows table partitioned by month skip the
+// partitions older than any pending batch; the day of slack allows for clock
+// steps between the insert of a batch and of its rows.
 func (q *Queries) FetchBlockOfRows(ctx context.Context, arg FetchBlockOfRowsParams) ([]FetchBlockOfRowsRow, error) {
-	rows, err := q.db.Query(ctx, fetchBlockOfRows, arg.Status, arg.Limit)
+	rows, err := q.db.Query(ctx, fetchBlockOfRows, arg.Status, arg.Now, arg.Limit)
 	if err != nil {
 		return nil, err
 	}
@@ -164,6 +440,103 @@ func (q *Queries) FetchBlockOfRows(ctx context.Context, arg FetchBlockOfRowsPara
 			&i.Rowid,
 			&i.Line,
 			&i.Input,
+			&i.Attempts,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const fetchBlockOfRowsFair = `-- name: FetchBlockOfRowsFair :many
+WITH apps AS (
+    SELECT DISTINCT batches.app
+    FROM batches
+    WHERE batches.status IN ('queued', 'inprog')
+), candidates AS (
+    SELECT c.*
+    FROM apps
+    CROSS JOIN LATERAL (
+        SELECT batches.app, batches.status, batches.op, batches.context, batchrows.priority, batchrows.batch, batchrows.rowid, batchrows.line, batchrows.input, batchrows.attempts
+        FROM batchrows
+        INNER JOIN batches ON batchrows.batch = batches.id
+        WHERE batches.app = apps.app
+          AND batchrows.status = $1 AND batches.status NOT IN ('wait', 'paused')
+          AND (batchrows.not_before IS NULL OR batchrows.not_before <= $2::timestamp)
+          AND (batches.runat IS NULL OR batches.runat <= $2::timestamp)
+          AND batchrows.reqat >= (
+              SELECT COALESCE(MIN(pending.reqat), 'infinity'::timestamp) - INTERVAL '1 day'
+              FROM batches pending
+              WHERE pending.status IN ('queued', 'inprog'))
+        ORDER BY batchrows.priority DESC, batchrows.rowid
+        LIMIT $3
+        FOR UPDATE OF batchrows, batches SKIP LOCKED
+    ) c
+), ranked AS (
+    SELECT candidates.*, row_number() OVER (PARTITION BY candidates.app ORDER BY candidates.priority DESC, candidates.rowid) AS rn
+    FROM candidates
+)
+SELECT ranked.app, ranked.status, ranked.op, ranked.context, ranked.batch, ranked.rowid, ranked.line, ranked.input, ranked.attempts
+FROM ranked
+LEFT JOIN unnest($4::text[], $5::int[]) AS w(app, weight) ON w.app = ranked.app
+ORDER BY (ranked.rn - 1) / GREATEST(COALESCE(w.weight, 1), 1), ranked.priority DESC, ranked.rowid
+LIMIT $3
+`
+
+type FetchBlockOfRowsFairParams struct {
+	Status     StatusEnum       `json:"status"`
+	Now        pgtype.Timestamp `json:"now"`
+	Limit      int32            `json:"limit"`
+	WeightApps []string         `json:"weight_apps"`
+	Weights    []int32          `json:"weights"`
+}
+
+type FetchBlockOfRowsFairRow struct {
+	App      string     `json:"app"`
+	Status   StatusEnum `json:"status"`
+	Op       string     `json:"op"`
+	Context  []byte     `json:"context"`
+	Batch    uuid.UUID  `json:"batch"`
+	Rowid    int64      `json:"rowid"`
+	Line     int32      `json:"line"`
+	Input    []byte     `json:"input"`
+	Attempts int32      `json:"attempts"`
+}
+
+// Fair-share variant of FetchBlockOfRows. Up to limit eligible rows are locked
+// per app, highest priority first, and the block is then filled round-robin
+// across apps: in every round an app contributes as many rows as its weight.
+// Apps not listed in weight_apps have weight 1. Locked rows that do not make
+// it into the block are released when the transaction ends.
+func (q *Queries) FetchBlockOfRowsFair(ctx context.Context, arg FetchBlockOfRowsFairParams) ([]FetchBlockOfRowsFairRow, error) {
+	rows, err := q.db.Query(ctx, fetchBlockOfRowsFair,
+		arg.Status,
+		arg.Now,
+		arg.Limit,
+		arg.WeightApps,
+		arg.Weights,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FetchBlockOfRowsFairRow
+	for rows.Next() {
+		var i FetchBlockOfRowsFairRow
+		if err := rows.Scan(
+			&i.App,
+			&i.Status,
+			&i.Op,
+			&i.Context,
+			&i.Batch,
+			&i.Rowid,
+			&i.Line,
+			&i.Input,
+			&i.Attempts,
 		); err != nil {
 			return nil, err
 		}
@@ -176,7 +549,7 @@ func (q *Queries) FetchBlockOfRows(ctx context.Context, arg FetchBlockOfRowsPara
 }
 
 const getBatchByID = `-- name: GetBatchByID :one
-SELECT id, app, op, context, inputfile, status, reqat, doneat, outputfiles, nsuccess, nfailed, naborted, created_at
+SELECT id, app, op, context, inputfile, status, reqat, doneat, outputfiles, nsuccess, nfailed, naborted, created_at, runat, priority, callback_url, parent_id, outputtypes
 FROM batches
 WHERE id = $1 
 FOR UPDATE
@@ -199,12 +572,93 @@ func (q *Queries) GetBatchByID(ctx context.Context, id uuid.UUID) (Batch, error)
 		&i.Nfailed,
 		&i.Naborted,
 		&i.CreatedAt,
+		&i.Runat,
+		&i.Priority,
+		&i.CallbackUrl,
+		&i.ParentID,
+		&i.Outputtypes,
+	)
+	return i, err
+}
+
+const getBatchProgress = `-- name: GetBatchProgress :one
+SELECT
+    COUNT(*) FILTER (WHERE status = 'queued') AS nqueued,
+    COUNT(*) FILTER (WHERE status = 'inprog') AS ninprog,
+    COUNT(*) FILTER (WHERE status = 'success') AS nsuccess,
+    COUNT(*) FILTER (WHERE status = 'failed') AS nfailed,
+    COUNT(*) FILTER (WHERE status = 'aborted') AS naborted,
+    MIN(doneat)::timestamp AS first_doneat
+FROM batchrows
+WHERE batch = $1
+`
+
+type GetBatchProgressRow struct {
+	Nqueued     int64            `json:"nqueued"`
+	Ninprog     int64            `json:"ninprog"`
+	Nsuccess    int64            `json:"nsuccess"`
+	Nfailed     int64            `json:"nfailed"`
+	Naborted    int64            `json:"naborted"`
+	FirstDoneat pgtype.Timestamp `json:"first_doneat"`
+}
+
+// Counts the rows of a batch by status, with the time the first of them was done.
+func (q *Queries) GetBatchProgress(ctx context.Context, batch uuid.UUID) (GetBatchProgressRow, error) {
+	row := q.db.QueryRow(ctx, getBatchProgress, batch)
+	var i GetBatchProgressRow
+	err := row.Scan(
+		&i.Nqueued,
+		&i.Ninprog,
+		&i.Nsuccess,
+		&i.Nfailed,
+		&i.Naborted,
+		&i.FirstDoneat,
 	)
 	return i, err
 }
 
+const getBatchRowHistory = `-- name: GetBatchRowHistory :many
+SELECT id, rowid, batch, line, input, status, doneat, res, blobrows, messages, doneby, outputfiles, archived_at FROM batchrow_history
+WHERE batch = $1
+ORDER BY line, id
+`
+
+func (q *Queries) GetBatchRowHistory(ctx context.Context, batch uuid.UUID) ([]BatchrowHistory, error) {
+	rows, err := q.db.Query(ctx, getBatchRowHistory, batch)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []BatchrowHistory
+	for rows.Next() {
+		var i BatchrowHistory
+		if err := rows.Scan(
+			&i.ID,
+			&i.Rowid,
+			&i.Batch,
+			&i.Line,
+			&i.Input,
+			&i.Status,
+			&i.Doneat,
+			&i.Res,
+			&i.Blobrows,
+			&i.Messages,
+			&i.Doneby,
+			&i.Outputfiles,
+			&i.ArchivedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getBatchRowsByBatchID = `-- name: GetBatchRowsByBatchID :many
-SELECT rowid, batch, line, input, status, reqat, doneat, res, blobrows, messages, doneby, created_at FROM batchrows WHERE batch = $1
+SELECT rowid, batch, line, input, status, reqat, doneat, res, blobrows, messages, doneby, created_at, attempts, not_before, worker, leased_until, priority FROM batchrows WHERE batch = $1
 `
 
 func (q *Queries) GetBatchRowsByBatchID(ctx context.Context, batch uuid.UUID) ([]Batchrow, error) {
@@ -229,6 +683,11 @@ func (q *Queries) GetBatchRowsByBatchID(ctx context.Context, batch uuid.UUID) ([
 			&i.Messages,
 			&i.Doneby,
 			&i.CreatedAt,
+			&i.Attempts,
+			&i.NotBefore,
+			&i.Worker,
+			&i.LeasedUntil,
+			&i.Priority,
 		); err != nil {
 			return nil, err
 		}
@@ -303,62 +762,345 @@ func (q *Queries) GetBatchRowsCount(ctx context.Context, batch uuid.UUID) (int64
 	return count, err
 }
 
-const getBatchStatus = `-- name: GetBatchStatus :one
-SELECT status
-FROM batches
-WHERE id = $1
-`
-
-func (q *Queries) GetBatchStatus(ctx context.Context, id uuid.UUID) (StatusEnum, error) {
-	row := q.db.QueryRow(ctx, getBatchStatus, id)
-	var status StatusEnum
-	err := row.Scan(&status)
-	return status, err
-}
-
-const getBatchStatusAndOutputFiles = `-- name: GetBatchStatusAndOutputFiles :one
-SELECT a.status, a.outputfiles, b.res
-FROM batches a
-JOIN batchrows b
-ON b.batch = a.id
-WHERE a.id = $1
+const getBatchRowsForRerun = `-- name: GetBatchRowsForRerun :many
+SELECT rowid, line, input, status
+FROM batchrows
+WHERE batch = $1
+  AND status::text = ANY($2::text[])
+  AND (cardinality($3::int[]) = 0 OR line = ANY($3::int[]))
+ORDER BY line
+FOR UPDATE
 `
 
-type GetBatchStatusAndOutputFilesRow struct {
-	Status      StatusEnum `json:"status"`
-	Outputfiles []byte     `json:"outputfiles"`
-	Res         []byte     `json:"res"`
+type GetBatchRowsForRerunParams struct {
+	Batch    uuid.UUID `json:"batch"`
+	Statuses []string  `json:"statuses"`
+	Lines    []int32   `json:"lines"`
 }
 
-func (q *Queries) GetBatchStatusAndOutputFiles(ctx context.Context, id uuid.UUID) (GetBatchStatusAndOutputFilesRow, error) {
-	row := q.db.QueryRow(ctx, getBatchStatusAndOutputFiles, id)
-	var i GetBatchStatusAndOutputFilesRow
-	err := row.Scan(&i.Status, &i.Outputfiles, &i.Res)
-	return i, err
+type GetBatchRowsForRerunRow struct {
+	Rowid  int64      `json:"rowid"`
+	Line   int32      `json:"line"`
+	Input  []byte     `json:"input"`
+	Status StatusEnum `json:"status"`
 }
 
-const getCompletedBatches = `-- name: GetCompletedBatches :many
-SELECT id
-FROM batches
-WHERE status IN ('success', 'failed', 'aborted')
-FOR UPDATE
-`
-
-func (q *Queries) GetCompletedBatches(ctx context.Context) ([]uuid.UUID, error) {
-	rows, err := q.db.Query(ctx, getCompletedBatches)
+// Locks the rows of a batch in one of statuses, and on one of lines if any are given,
+// for BatchRetryFailed.
+func (q *Queries) GetBatchRowsForRerun(ctx context.Context, arg GetBatchRowsForRerunParams) ([]GetBatchRowsForRerunRow, error) {
+	rows, err := q.db.Query(ctx, getBatchRowsForRerun, arg.Batch, arg.Statuses, arg.Lines)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []uuid.UUID
+	var items []GetBatchRowsForRerunRow
 	for rows.Next() {
-		var id uuid.UUID
-		if err := rows.Scan(&id); err != nil {
-			return nil, err
-		}
-		items = append(items, id)
-	}
-	if err := rows.Err(); err != nil {
+		var i GetBatchRowsForRerunRow
+		if err := rows.Scan(
+			&i.Rowid,
+			&i.Line,
+			&i.Input,
+			&i.Status,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getBatchRowsPage = `-- name: GetBatchRowsPage :many
+SELECT rowid, batch, line, input, status, reqat, doneat, res, blobrows, messages, doneby, created_at, attempts, not_before, worker, leased_until, priority FROM batchrows
+WHERE batch = $1
+  AND (line, rowid) > ($2::integer, $3::bigint)
+ORDER BY line, rowid
+LIMIT $4
+`
+
+type GetBatchRowsPageParams struct {
+	Batch      uuid.UUID `json:"batch"`
+	AfterLine  int32     `json:"after_line"`
+	AfterRowid int64     `json:"after_rowid"`
+	PageSize   int32     `json:"page_size"`
+}
+
+// Returns a page of the rows of a batch, in the order of their lines, for archiving
+// it. Pages follow each other by keyset, as with GetProcessedBatchRowsPage.
+func (q *Queries) GetBatchRowsPage(ctx context.Context, arg GetBatchRowsPageParams) ([]Batchrow, error) {
+	rows, err := q.db.Query(ctx, getBatchRowsPage,
+		arg.Batch,
+		arg.AfterLine,
+		arg.AfterRowid,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Batchrow
+	for rows.Next() {
+		var i Batchrow
+		if err := rows.Scan(
+			&i.Rowid,
+			&i.Batch,
+			&i.Line,
+			&i.Input,
+			&i.Status,
+			&i.Reqat,
+			&i.Doneat,
+			&i.Res,
+			&i.Blobrows,
+			&i.Messages,
+			&i.Doneby,
+			&i.CreatedAt,
+			&i.Attempts,
+			&i.NotBefore,
+			&i.Worker,
+			&i.LeasedUntil,
+			&i.Priority,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getBatchScheduleForUpdate = `-- name: GetBatchScheduleForUpdate :one
+SELECT name, app, op, cron, last_fireat, last_batch
+FROM batch_schedules
+WHERE name = $1
+FOR UPDATE
+`
+
+type GetBatchScheduleForUpdateRow struct {
+	Name       string           `json:"name"`
+	App        string           `json:"app"`
+	Op         string           `json:"op"`
+	Cron       string           `json:"cron"`
+	LastFireat pgtype.Timestamp `json:"last_fireat"`
+	LastBatch  pgtype.UUID      `json:"last_batch"`
+}
+
+func (q *Queries) GetBatchScheduleForUpdate(ctx context.Context, name string) (GetBatchScheduleForUpdateRow, error) {
+	row := q.db.QueryRow(ctx, getBatchScheduleForUpdate, name)
+	var i GetBatchScheduleForUpdateRow
+	err := row.Scan(
+		&i.Name,
+		&i.App,
+		&i.Op,
+		&i.Cron,
+		&i.LastFireat,
+		&i.LastBatch,
+	)
+	return i, err
+}
+
+const getBatchStatus = `-- name: GetBatchStatus :one
+SELECT status
+FROM batches
+WHERE id = $1
+`
+
+func (q *Queries) GetBatchStatus(ctx context.Context, id uuid.UUID) (StatusEnum, error) {
+	row := q.db.QueryRow(ctx, getBatchStatus, id)
+	var status StatusEnum
+	err := row.Scan(&status)
+	return status, err
+}
+
+const getBatchStatusAndOutputFiles = `-- name: GetBatchStatusAndOutputFiles :one
+SELECT a.status, a.outputfiles, b.res
+FROM batches a
+JOIN batchrows b
+ON b.batch = a.id
+WHERE a.id = $1
+`
+
+type GetBatchStatusAndOutputFilesRow struct {
+	Status      StatusEnum `json:"status"`
+	Outputfiles []byte     `json:"outputfiles"`
+	Res         []byte     `json:"res"`
+}
+
+func (q *Queries) GetBatchStatusAndOutputFiles(ctx context.Context, id uuid.UUID) (GetBatchStatusAndOutputFilesRow, error) {
+	row := q.db.QueryRow(ctx, getBatchStatusAndOutputFiles, id)
+	var i GetBatchStatusAndOutputFilesRow
+	err := row.Scan(&i.Status, &i.Outputfiles, &i.Res)
+	return i, err
+}
+
+const getBatchesToPurge = `-- name: GetBatchesToPurge :many
+SELECT id, app, op, context, inputfile, status, reqat, doneat, outputfiles, nsuccess, nfailed, naborted, created_at, runat, priority, callback_url, parent_id, outputtypes FROM batches
+WHERE app = $1
+  AND status IN ('success', 'failed', 'aborted')
+  AND doneat < $2
+ORDER BY doneat
+LIMIT $3
+FOR UPDATE SKIP LOCKED
+`
+
+type GetBatchesToPurgeParams struct {
+	App        string           `json:"app"`
+	Cutoff     pgtype.Timestamp `json:"cutoff"`
+	MaxBatches int32            `json:"max_batches"`
+}
+
+// Locks up to @max_batches done batches and slow queries of an app that were done before
+// @cutoff, oldest first. Batches locked by a concurrent purge are skipped.
+func (q *Queries) GetBatchesToPurge(ctx context.Context, arg GetBatchesToPurgeParams) ([]Batch, error) {
+	rows, err := q.db.Query(ctx, getBatchesToPurge, arg.App, arg.Cutoff, arg.MaxBatches)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Batch
+	for rows.Next() {
+		var i Batch
+		if err := rows.Scan(
+			&i.ID,
+			&i.App,
+			&i.Op,
+			&i.Context,
+			&i.Inputfile,
+			&i.Status,
+			&i.Reqat,
+			&i.Doneat,
+			&i.Outputfiles,
+			&i.Nsuccess,
+			&i.Nfailed,
+			&i.Naborted,
+			&i.CreatedAt,
+			&i.Runat,
+			&i.Priority,
+			&i.CallbackUrl,
+			&i.ParentID,
+			&i.Outputtypes,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getCompletedBatches = `-- name: GetCompletedBatches :many
+SELECT id
+FROM batches
+WHERE status IN ('success', 'failed', 'aborted')
+FOR UPDATE
+`
+
+func (q *Queries) GetCompletedBatches(ctx context.Context) ([]uuid.UUID, error) {
+	rows, err := q.db.Query(ctx, getCompletedBatches)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDeadWorkers = `-- name: GetDeadWorkers :many
+SELECT instance_id
+FROM workers
+WHERE heartbeat < $1::timestamp
+`
+
+// Returns the instances of the worker registry whose last heartbeat is older than the cutoff.
+func (q *Queries) GetDeadWorkers(ctx context.Context, cutoff pgtype.Timestamp) ([]string, error) {
+	rows, err := q.db.Query(ctx, getDeadWorkers, cutoff)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var instance_id string
+		if err := rows.Scan(&instance_id); err != nil {
+			return nil, err
+		}
+		items = append(items, instance_id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getIdempotencyKeyBatch = `-- name: GetIdempotencyKeyBatch :one
+SELECT batch FROM batch_idempotency_keys
+WHERE app = $1 AND key = $2
+`
+
+type GetIdempotencyKeyBatchParams struct {
+	App string `json:"app"`
+	Key string `json:"key"`
+}
+
+func (q *Queries) GetIdempotencyKeyBatch(ctx context.Context, arg GetIdempotencyKeyBatchParams) (uuid.UUID, error) {
+	row := q.db.QueryRow(ctx, getIdempotencyKeyBatch, arg.App, arg.Key)
+	var batch uuid.UUID
+	err := row.Scan(&batch)
+	return batch, err
+}
+
+const getParentBatches = `-- name: GetParentBatches :many
+SELECT d.parent, d.name, d.on_parent_failure, b.status, b.outputfiles
+FROM batch_dependencies d
+JOIN batches b ON b.id = d.parent
+WHERE d.batch = $1
+ORDER BY d.name
+`
+
+type GetParentBatchesRow struct {
+	Parent          uuid.UUID  `json:"parent"`
+	Name            string     `json:"name"`
+	OnParentFailure string     `json:"on_parent_failure"`
+	Status          StatusEnum `json:"status"`
+	Outputfiles     []byte     `json:"outputfiles"`
+}
+
+func (q *Queries) GetParentBatches(ctx context.Context, batch uuid.UUID) ([]GetParentBatchesRow, error) {
+	rows, err := q.db.Query(ctx, getParentBatches, batch)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetParentBatchesRow
+	for rows.Next() {
+		var i GetParentBatchesRow
+		if err := rows.Scan(
+			&i.Parent,
+			&i.Name,
+			&i.OnParentFailure,
+			&i.Status,
+			&i.Outputfiles,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
 		return nil, err
 	}
 	return items, nil
@@ -384,26 +1126,432 @@ type GetPendingBatchRowsRow struct {
 	Doneby   pgtype.Text      `json:"doneby"`
 }
 
-func (q *Queries) GetPendingBatchRows(ctx context.Context, batch uuid.UUID) ([]GetPendingBatchRowsRow, error) {
-	rows, err := q.db.Query(ctx, getPendingBatchRows, batch)
+func (q *Queries) GetPendingBatchRows(ctx context.Context, batch uuid.UUID) ([]GetPendingBatchRowsRow, error) {
+	rows, err := q.db.Query(ctx, getPendingBatchRows, batch)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPendingBatchRowsRow
+	for rows.Next() {
+		var i GetPendingBatchRowsRow
+		if err := rows.Scan(
+			&i.Rowid,
+			&i.Line,
+			&i.Input,
+			&i.Status,
+			&i.Reqat,
+			&i.Doneat,
+			&i.Res,
+			&i.Blobrows,
+			&i.Messages,
+			&i.Doneby,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getProcessedBatchRowsPage = `-- name: GetProcessedBatchRowsPage :many
+SELECT rowid, line, blobrows
+FROM batchrows
+WHERE batch = $1 AND status IN ('success', 'failed')
+  AND (line, rowid) > ($2::integer, $3::bigint)
+ORDER BY line, rowid
+LIMIT $4
+`
+
+type GetProcessedBatchRowsPageParams struct {
+	Batch      uuid.UUID `json:"batch"`
+	AfterLine  int32     `json:"after_line"`
+	AfterRowid int64     `json:"after_rowid"`
+	PageSize   int32     `json:"page_size"`
+}
+
+type GetProcessedBatchRowsPageRow struct {
+	Rowid    int64  `json:"rowid"`
+	Line     int32  `json:"line"`
+	Blobrows []byte `json:"blobrows"`
+}
+
+// Returns a page of the processed rows of a batch, in the order of their lines,
+// for assembling its output files. Pages follow each other by keyset: pass the
+// line and rowid of the last row of the previous page as after_line and
+// after_rowid.
+func (q *Queries) GetProcessedBatchRowsPage(ctx context.Context, arg GetProcessedBatchRowsPageParams) ([]GetProcessedBatchRowsPageRow, error) {
+	rows, err := q.db.Query(ctx, getProcessedBatchRowsPage,
+		arg.Batch,
+		arg.AfterLine,
+		arg.AfterRowid,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetProcessedBatchRowsPageRow
+	for rows.Next() {
+		var i GetProcessedBatchRowsPageRow
+		if err := rows.Scan(
+			&i.Rowid,
+			&i.Line,
+			&i.Blobrows,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUnsummarizedBatches = `-- name: GetUnsummarizedBatches :many
+SELECT b.id FROM batches b
+WHERE b.status = 'inprog'
+  AND b.doneat IS NULL
+  AND NOT EXISTS (
+    SELECT 1 FROM batchrows br
+    WHERE br.batch = b.id
+    AND br.status IN ('queued', 'inprog')
+  )
+`
+
+// Finds batches stuck in 'inprog' with doneat=NULL where all rows have reached
+// terminal status (no queued or inprog rows remain). These batches need
+// summarization that was missed due to race conditions or failed retries.
+func (q *Queries) GetUnsummarizedBatches(ctx context.Context) ([]uuid.UUID, error) {
+	rows, err := q.db.Query(ctx, getUnsummarizedBatches)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getWaitingDependentBatches = `-- name: GetWaitingDependentBatches :many
+SELECT d.batch
+FROM batch_dependencies d
+JOIN batches b ON b.id = d.batch
+WHERE d.parent = $1
+  AND b.status = 'wait'
+ORDER BY d.batch
+`
+
+func (q *Queries) GetWaitingDependentBatches(ctx context.Context, parent uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := q.db.Query(ctx, getWaitingDependentBatches, parent)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var batch uuid.UUID
+		if err := rows.Scan(&batch); err != nil {
+			return nil, err
+		}
+		items = append(items, batch)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getWebhookDeliveriesByBatchID = `-- name: GetWebhookDeliveriesByBatchID :many
+SELECT id, batch, url, payload, status, attempts, next_attempt_at, last_http_status, last_error, created_at, delivered_at FROM webhook_deliveries
+WHERE batch = $1
+ORDER BY id
+`
+
+func (q *Queries) GetWebhookDeliveriesByBatchID(ctx context.Context, batch uuid.UUID) ([]WebhookDelivery, error) {
+	rows, err := q.db.Query(ctx, getWebhookDeliveriesByBatchID, batch)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []WebhookDelivery
+	for rows.Next() {
+		var i WebhookDelivery
+		if err := rows.Scan(
+			&i.ID,
+			&i.Batch,
+			&i.Url,
+			&i.Payload,
+			&i.Status,
+			&i.Attempts,
+			&i.NextAttemptAt,
+			&i.LastHttpStatus,
+			&i.LastError,
+			&i.CreatedAt,
+			&i.DeliveredAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const insertBatchDependency = `-- name: InsertBatchDependency :exec
+INSERT INTO batch_dependencies (batch, parent, name, on_parent_failure)
+VALUES ($1, $2, $3, $4)
+`
+
+type InsertBatchDependencyParams struct {
+	Batch           uuid.UUID `json:"batch"`
+	Parent          uuid.UUID `json:"parent"`
+	Name            string    `json:"name"`
+	OnParentFailure string    `json:"on_parent_failure"`
+}
+
+func (q *Queries) InsertBatchDependency(ctx context.Context, arg InsertBatchDependencyParams) error {
+	_, err := q.db.Exec(ctx, insertBatchDependency,
+		arg.Batch,
+		arg.Parent,
+		arg.Name,
+		arg.OnParentFailure,
+	)
+	return err
+}
+
+const insertBatchFile = `-- name: InsertBatchFile :exec
+INSERT INTO batch_files (
+    batch_id,
+    object_id,
+    filename,
+    size,
+    checksum,
+    content_type,
+    status,
+    received_at,
+    metadata
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9
+)
+`
+
+type InsertBatchFileParams struct {
+	BatchID     uuid.UUID          `json:"batch_id"`
+	ObjectID    string             `json:"object_id"`
+	Filename    string             `json:"filename"`
+	Size        int64              `json:"size"`
+	Checksum    string             `json:"checksum"`
+	ContentType string             `json:"content_type"`
+	Status      bool               `json:"status"`
+	ReceivedAt  pgtype.Timestamptz `json:"received_at"`
+	Metadata    []byte             `json:"metadata"`
+}
+
+func (q *Queries) InsertBatchFile(ctx context.Context, arg InsertBatchFileParams) error {
+	_, err := q.db.Exec(ctx, insertBatchFile,
+		arg.BatchID,
+		arg.ObjectID,
+		arg.Filename,
+		arg.Size,
+		arg.Checksum,
+		arg.ContentType,
+		arg.Status,
+		arg.ReceivedAt,
+		arg.Metadata,
+	)
+	return err
+}
+
+const insertIntoBatchRows = `-- name: InsertIntoBatchRows :exec
+INSERT INTO batchrows (batch, line, input, status, reqat, priority)
+VALUES ($1, $2, $3, 'queued', $4, $5)
+`
+
+type InsertIntoBatchRowsParams struct {
+	Batch    uuid.UUID        `json:"batch"`
+	Line     int32            `json:"line"`
+	Input    []byte           `json:"input"`
+	Reqat    pgtype.Timestamp `json:"reqat"`
+	Priority int32            `json:"priority"`
+}
+
+func (q *Queries) InsertIntoBatchRows(ctx context.Context, arg InsertIntoBatchRowsParams) error {
+	_, err := q.db.Exec(ctx, insertIntoBatchRows,
+		arg.Batch,
+		arg.Line,
+		arg.Input,
+		arg.Reqat,
+		arg.Priority,
+	)
+	return err
+}
+
+const insertIntoBatches = `-- name: InsertIntoBatches :one
+INSERT INTO batches (id, app, op, context, status, reqat, runat, priority, callback_url)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING id
+`
+
+type InsertIntoBatchesParams struct {
+	ID          uuid.UUID        `json:"id"`
+	App         string           `json:"app"`
+	Op          string           `json:"op"`
+	Context     []byte           `json:"context"`
+	Status      StatusEnum       `json:"status"`
+	Reqat       pgtype.Timestamp `json:"reqat"`
+	Runat       pgtype.Timestamp `json:"runat"`
+	Priority    int32            `json:"priority"`
+	CallbackUrl pgtype.Text      `json:"callback_url"`
+}
+
+func (q *Queries) InsertIntoBatches(ctx context.Context, arg InsertIntoBatchesParams) (uuid.UUID, error) {
+	row := q.db.QueryRow(ctx, insertIntoBatches,
+		arg.ID,
+		arg.App,
+		arg.Op,
+		arg.Context,
+		arg.Status,
+		arg.Reqat,
+		arg.Runat,
+		arg.Priority,
+		arg.CallbackUrl,
+	)
+	var id uuid.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
+const insertWebhookDelivery = `-- name: InsertWebhookDelivery :exec
+INSERT INTO webhook_deliveries (batch, url, payload, next_attempt_at)
+SELECT id, callback_url, $1::jsonb, $2::timestamp
+FROM batches
+WHERE id = $3 AND callback_url IS NOT NULL
+`
+
+type InsertWebhookDeliveryParams struct {
+	Payload       []byte           `json:"payload"`
+	NextAttemptAt pgtype.Timestamp `json:"next_attempt_at"`
+	Batch         uuid.UUID        `json:"batch"`
+}
+
+// Queues a delivery of payload to the callback URL of the batch, if it has one.
+func (q *Queries) InsertWebhookDelivery(ctx context.Context, arg InsertWebhookDeliveryParams) error {
+	_, err := q.db.Exec(ctx, insertWebhookDelivery, arg.Payload, arg.NextAttemptAt, arg.Batch)
+	return err
+}
+
+const leaseBatchRows = `-- name: LeaseBatchRows :exec
+UPDATE batchrows
+SET worker = $1::text, leased_until = $2::timestamp
+WHERE rowid = ANY($3::bigint[])
+`
+
+type LeaseBatchRowsParams struct {
+	Worker      string           `json:"worker"`
+	LeasedUntil pgtype.Timestamp `json:"leased_until"`
+	RowIds      []int64          `json:"row_ids"`
+}
+
+// Records the rows picked up by a worker as leased to it until leased_until.
+func (q *Queries) LeaseBatchRows(ctx context.Context, arg LeaseBatchRowsParams) error {
+	_, err := q.db.Exec(ctx, leaseBatchRows, arg.Worker, arg.LeasedUntil, arg.RowIds)
+	return err
+}
+
+const listBatches = `-- name: ListBatches :many
+SELECT b.id, b.app, b.op, b.context, b.inputfile, b.status, b.reqat, b.doneat, b.outputfiles,
+    b.nsuccess, b.nfailed, b.naborted, b.outputtypes,
+    (SELECT COUNT(*) FROM batchrows r WHERE r.batch = b.id) AS nrows
+FROM batches b
+WHERE b.app = $1
+  AND ($2::text IS NULL OR b.op = $2)
+  AND ($3::status_enum IS NULL OR b.status = $3)
+  AND b.reqat >= $4
+  AND ($5::timestamp IS NULL OR (b.reqat, b.id) < ($5, $6::uuid))
+  AND NOT EXISTS (SELECT 1 FROM batchrows r WHERE r.batch = b.id AND r.line = 0)
+ORDER BY b.reqat DESC, b.id DESC
+LIMIT $7
+`
+
+type ListBatchesParams struct {
+	App        string           `json:"app"`
+	Op         pgtype.Text      `json:"op"`
+	Status     NullStatusEnum   `json:"status"`
+	Since      pgtype.Timestamp `json:"since"`
+	AfterReqat pgtype.Timestamp `json:"after_reqat"`
+	AfterID    pgtype.UUID      `json:"after_id"`
+	PageSize   int32            `json:"page_size"`
+}
+
+type ListBatchesRow struct {
+	ID          uuid.UUID        `json:"id"`
+	App         string           `json:"app"`
+	Op          string           `json:"op"`
+	Context     []byte           `json:"context"`
+	Inputfile   pgtype.Text      `json:"inputfile"`
+	Status      StatusEnum       `json:"status"`
+	Reqat       pgtype.Timestamp `json:"reqat"`
+	Doneat      pgtype.Timestamp `json:"doneat"`
+	Outputfiles []byte           `json:"outputfiles"`
+	Nsuccess    pgtype.Int4      `json:"nsuccess"`
+	Nfailed     pgtype.Int4      `json:"nfailed"`
+	Naborted    pgtype.Int4      `json:"naborted"`
+	Outputtypes []byte           `json:"outputtypes"`
+	Nrows       int64            `json:"nrows"`
+}
+
+// Lists batch jobs of an app, newest first. Slow queries (batches with a
+// line 0 row) are excluded. op and status are optional filters. Pagination
+// is keyset based: pass the reqat and id of the last row of the previous page
+// as after_reqat and after_id, or NULL for the first page.
+func (q *Queries) ListBatches(ctx context.Context, arg ListBatchesParams) ([]ListBatchesRow, error) {
+	rows, err := q.db.Query(ctx, listBatches,
+		arg.App,
+		arg.Op,
+		arg.Status,
+		arg.Since,
+		arg.AfterReqat,
+		arg.AfterID,
+		arg.PageSize,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []GetPendingBatchRowsRow
+	var items []ListBatchesRow
 	for rows.Next() {
-		var i GetPendingBatchRowsRow
+		var i ListBatchesRow
 		if err := rows.Scan(
-			&i.Rowid,
-			&i.Line,
-			&i.Input,
+			&i.ID,
+			&i.App,
+			&i.Op,
+			&i.Context,
+			&i.Inputfile,
 			&i.Status,
 			&i.Reqat,
 			&i.Doneat,
-			&i.Res,
-			&i.Blobrows,
-			&i.Messages,
-			&i.Doneby,
+			&i.Outputfiles,
+			&i.Nsuccess,
+			&i.Nfailed,
+			&i.Naborted,
+			&i.Outputtypes,
+			&i.Nrows,
 		); err != nil {
 			return nil, err
 		}
@@ -415,46 +1563,68 @@ func (q *Queries) GetPendingBatchRows(ctx context.Context, batch uuid.UUID) ([]G
 	return items, nil
 }
 
-const getProcessedBatchRowsByBatchIDSorted = `-- name: GetProcessedBatchRowsByBatchIDSorted :many
-SELECT rowid, line, input, status, reqat, doneat, res, blobrows, messages, doneby
-FROM batchrows
-WHERE batch = $1 AND status IN ('success', 'failed')
-ORDER BY line
+const listSlowQueries = `-- name: ListSlowQueries :many
+SELECT b.id, b.app, b.op, b.inputfile, b.status, b.reqat, b.doneat, b.outputfiles
+FROM batches b
+WHERE b.app = $1
+  AND ($2::text IS NULL OR b.op = $2)
+  AND ($3::status_enum IS NULL OR b.status = $3)
+  AND b.reqat >= $4
+  AND ($5::timestamp IS NULL OR (b.reqat, b.id) < ($5, $6::uuid))
+  AND EXISTS (SELECT 1 FROM batchrows r WHERE r.batch = b.id AND r.line = 0)
+ORDER BY b.reqat DESC, b.id DESC
+LIMIT $7
 `
 
-type GetProcessedBatchRowsByBatchIDSortedRow struct {
-	Rowid    int64            `json:"rowid"`
-	Line     int32            `json:"line"`
-	Input    []byte           `json:"input"`
-	Status   StatusEnum       `json:"status"`
-	Reqat    pgtype.Timestamp `json:"reqat"`
-	Doneat   pgtype.Timestamp `json:"doneat"`
-	Res      []byte           `json:"res"`
-	Blobrows []byte           `json:"blobrows"`
-	Messages []byte           `json:"messages"`
-	Doneby   pgtype.Text      `json:"doneby"`
+type ListSlowQueriesParams struct {
+	App        string           `json:"app"`
+	Op         pgtype.Text      `json:"op"`
+	Status     NullStatusEnum   `json:"status"`
+	Since      pgtype.Timestamp `json:"since"`
+	AfterReqat pgtype.Timestamp `json:"after_reqat"`
+	AfterID    pgtype.UUID      `json:"after_id"`
+	PageSize   int32            `json:"page_size"`
 }
 
-func (q *Queries) GetProcessedBatchRowsByBatchIDSorted(ctx context.Context, batch uuid.UUID) ([]GetProcessedBatchRowsByBatchIDSortedRow, error) {
-	rows, err := q.db.Query(ctx, getProcessedBatchRowsByBatchIDSorted, batch)
+type ListSlowQueriesRow struct {
+	ID          uuid.UUID        `json:"id"`
+	App         string           `json:"app"`
+	Op          string           `json:"op"`
+	Inputfile   pgtype.Text      `json:"inputfile"`
+	Status      StatusEnum       `json:"status"`
+	Reqat       pgtype.Timestamp `json:"reqat"`
+	Doneat      pgtype.Timestamp `json:"doneat"`
+	Outputfiles []byte           `json:"outputfiles"`
+}
+
+// Lists slow queries (batches with a single line 0 row) of an app, newest
+// first. Filters and keyset pagination work the same way as ListBatches.
+func (q *Queries) ListSlowQueries(ctx context.Context, arg ListSlowQueriesParams) ([]ListSlowQueriesRow, error) {
+	rows, err := q.db.Query(ctx, listSlowQueries,
+		arg.App,
+		arg.Op,
+		arg.Status,
+		arg.Since,
+		arg.AfterReqat,
+		arg.AfterID,
+		arg.PageSize,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []GetProcessedBatchRowsByBatchIDSortedRow
+	var items []ListSlowQueriesRow
 	for rows.Next() {
-		var i GetProcessedBatchRowsByBatchIDSortedRow
+		var i ListSlowQueriesRow
 		if err := rows.Scan(
-			&i.Rowid,
-			&i.Line,
-			&i.Input,
+			&i.ID,
+			&i.App,
+			&i.Op,
+			&i.Inputfile,
 			&i.Status,
 			&i.Reqat,
 			&i.Doneat,
-			&i.Res,
-			&i.Blobrows,
-			&i.Messages,
-			&i.Doneby,
+			&i.Outputfiles,
 		); err != nil {
 			return nil, err
 		}
@@ -466,22 +1636,17 @@ func (q *Queries) GetProcessedBatchRowsByBatchIDSorted(ctx context.Context, batc
 	return items, nil
 }
 
-const getUnsummarizedBatches = `-- name: GetUnsummarizedBatches :many
-SELECT b.id FROM batches b
-WHERE b.status = 'inprog'
-  AND b.doneat IS NULL
-  AND NOT EXISTS (
-    SELECT 1 FROM batchrows br
-    WHERE br.batch = b.id
-    AND br.status IN ('queued', 'inprog')
-  )
+const lockParentBatches = `-- name: LockParentBatches :many
+SELECT id FROM batches
+WHERE id = ANY($1::uuid[])
+ORDER BY id
+FOR SHARE
 `
 
-// Finds batches stuck in 'inprog' with doneat=NULL where all rows have reached
-// terminal status (no queued or inprog rows remain). These batches need
-// summarization that was missed due to race conditions or failed retries.
-func (q *Queries) GetUnsummarizedBatches(ctx context.Context) ([]uuid.UUID, error) {
-	rows, err := q.db.Query(ctx, getUnsummarizedBatches)
+// Keeps the given batches from being summarised until the calling transaction ends,
+// so that a batch depending on them, inserted in it, cannot miss their completion.
+func (q *Queries) LockParentBatches(ctx context.Context, ids []uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := q.db.Query(ctx, lockParentBatches, ids)
 	if err != nil {
 		return nil, err
 	}
@@ -500,98 +1665,231 @@ func (q *Queries) GetUnsummarizedBatches(ctx context.Context) ([]uuid.UUID, erro
 	return items, nil
 }
 
-const insertBatchFile = `-- name: InsertBatchFile :exec
-INSERT INTO batch_files (
-    batch_id,
-    object_id,
-    filename,
-    size,
-    checksum,
-    content_type,
-    status,
-    received_at,
-    metadata
-) VALUES (
-    $1, $2, $3, $4, $5, $6, $7, $8, $9
-)
+const markWebhookAttemptFailed = `-- name: MarkWebhookAttemptFailed :execrows
+UPDATE webhook_deliveries
+SET status = $1, attempts = attempts + 1, next_attempt_at = $2, last_http_status = $3, last_error = $4
+WHERE id = $5 AND status = 'pending' AND next_attempt_at = $6
 `
 
-type InsertBatchFileParams struct {
-	BatchID     uuid.UUID          `json:"batch_id"`
-	ObjectID    string             `json:"object_id"`
-	Filename    string             `json:"filename"`
-	Size        int64              `json:"size"`
-	Checksum    string             `json:"checksum"`
-	ContentType string             `json:"content_type"`
-	Status      bool               `json:"status"`
-	ReceivedAt  pgtype.Timestamptz `json:"received_at"`
-	Metadata    []byte             `json:"metadata"`
+type MarkWebhookAttemptFailedParams struct {
+	Status        DeliveryStatusEnum `json:"status"`
+	NextAttemptAt pgtype.Timestamp   `json:"next_attempt_at"`
+	HttpStatus    pgtype.Int4        `json:"http_status"`
+	LastError     pgtype.Text        `json:"last_error"`
+	ID            int64              `json:"id"`
+	LeasedUntil   pgtype.Timestamp   `json:"leased_until"`
 }
 
-func (q *Queries) InsertBatchFile(ctx context.Context, arg InsertBatchFileParams) error {
-	_, err := q.db.Exec(ctx, insertBatchFile,
-		arg.BatchID,
-		arg.ObjectID,
-		arg.Filename,
-		arg.Size,
-		arg.Checksum,
-		arg.ContentType,
+// Records a failed attempt by the caller that claimed the delivery until
+// leased_until. status stays 'pending' while attempts remain. It does nothing if
+// the delivery has been claimed again since.
+func (q *Queries) MarkWebhookAttemptFailed(ctx context.Context, arg MarkWebhookAttemptFailedParams) (int64, error) {
+	result, err := q.db.Exec(ctx, markWebhookAttemptFailed,
 		arg.Status,
-		arg.ReceivedAt,
-		arg.Metadata,
+		arg.NextAttemptAt,
+		arg.HttpStatus,
+		arg.LastError,
+		arg.ID,
+		arg.LeasedUntil,
 	)
-	return err
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
 }
 
-const insertIntoBatchRows = `-- name: InsertIntoBatchRows :exec
-INSERT INTO batchrows (batch, line, input, status, reqat)
-VALUES ($1, $2, $3, 'queued', $4)
+const markWebhookDelivered = `-- name: MarkWebhookDelivered :execrows
+UPDATE webhook_deliveries
+SET status = 'delivered', attempts = attempts + 1, last_http_status = $1, last_error = NULL, delivered_at = $2
+WHERE id = $3 AND status = 'pending' AND next_attempt_at = $4
 `
 
-type InsertIntoBatchRowsParams struct {
-	Batch uuid.UUID        `json:"batch"`
-	Line  int32            `json:"line"`
-	Input []byte           `json:"input"`
-	Reqat pgtype.Timestamp `json:"reqat"`
+type MarkWebhookDeliveredParams struct {
+	HttpStatus  pgtype.Int4      `json:"http_status"`
+	DeliveredAt pgtype.Timestamp `json:"delivered_at"`
+	ID          int64            `json:"id"`
+	LeasedUntil pgtype.Timestamp `json:"leased_until"`
 }
 
-func (q *Queries) InsertIntoBatchRows(ctx context.Context, arg InsertIntoBatchRowsParams) error {
-	_, err := q.db.Exec(ctx, insertIntoBatchRows,
-		arg.Batch,
-		arg.Line,
-		arg.Input,
-		arg.Reqat,
+// Records a successful attempt by the caller that claimed the delivery until
+// leased_until. It does nothing if the delivery has been claimed again since.
+func (q *Queries) MarkWebhookDelivered(ctx context.Context, arg MarkWebhookDeliveredParams) (int64, error) {
+	result, err := q.db.Exec(ctx, markWebhookDelivered,
+		arg.HttpStatus,
+		arg.DeliveredAt,
+		arg.ID,
+		arg.LeasedUntil,
 	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const notifyJobsQueued = `-- name: NotifyJobsQueued :one
+SELECT alya_notify_jobs_queued($1::text)::text AS failure
+`
+
+// Wakes up idle JobManager instances listening on the alya_jobs channel.
+// The notification is only delivered when the surrounding transaction commits.
+// A failed notification is returned as its error message and leaves the
+// transaction usable.
+func (q *Queries) NotifyJobsQueued(ctx context.Context, app string) (string, error) {
+	row := q.db.QueryRow(ctx, notifyJobsQueued, app)
+	var failure string
+	err := row.Scan(&failure)
+	return failure, err
+}
+
+const pauseBatch = `-- name: PauseBatch :one
+UPDATE batches
+SET status = 'paused'
+WHERE id = $1
+  AND status IN ('queued', 'inprog')
+RETURNING app
+`
+
+func (q *Queries) PauseBatch(ctx context.Context, id uuid.UUID) (string, error) {
+	row := q.db.QueryRow(ctx, pauseBatch, id)
+	var app string
+	err := row.Scan(&app)
+	return app, err
+}
+
+const recordWorkerHeartbeat = `-- name: RecordWorkerHeartbeat :exec
+INSERT INTO workers (instance_id, heartbeat)
+VALUES ($1, $2)
+ON CONFLICT (instance_id) DO UPDATE SET heartbeat = EXCLUDED.heartbeat
+`
+
+type RecordWorkerHeartbeatParams struct {
+	InstanceID string           `json:"instance_id"`
+	Heartbeat  pgtype.Timestamp `json:"heartbeat"`
+}
+
+// Registers a JobManager instance in the worker registry, or refreshes its heartbeat.
+// Used instead of Redis by JobManagers without a Redis client.
+func (q *Queries) RecordWorkerHeartbeat(ctx context.Context, arg RecordWorkerHeartbeatParams) error {
+	_, err := q.db.Exec(ctx, recordWorkerHeartbeat, arg.InstanceID, arg.Heartbeat)
 	return err
 }
 
-const insertIntoBatches = `-- name: InsertIntoBatches :one
-INSERT INTO batches (id, app, op, context, status, reqat)
-VALUES ($1, $2, $3, $4, $5, $6)
-RETURNING id
+const releaseBatchRowLease = `-- name: ReleaseBatchRowLease :exec
+UPDATE batchrows
+SET worker = NULL, leased_until = NULL
+WHERE rowid = $1 AND worker = $2::text
 `
 
-type InsertIntoBatchesParams struct {
-	ID      uuid.UUID        `json:"id"`
-	App     string           `json:"app"`
-	Op      string           `json:"op"`
-	Context []byte           `json:"context"`
-	Status  StatusEnum       `json:"status"`
-	Reqat   pgtype.Timestamp `json:"reqat"`
+type ReleaseBatchRowLeaseParams struct {
+	Rowid  int64  `json:"rowid"`
+	Worker string `json:"worker"`
 }
 
-func (q *Queries) InsertIntoBatches(ctx context.Context, arg InsertIntoBatchesParams) (uuid.UUID, error) {
-	row := q.db.QueryRow(ctx, insertIntoBatches,
-		arg.ID,
-		arg.App,
-		arg.Op,
-		arg.Context,
-		arg.Status,
-		arg.Reqat,
-	)
-	var id uuid.UUID
-	err := row.Scan(&id)
-	return id, err
+// Ends the lease of a row once its worker is done with it.
+func (q *Queries) ReleaseBatchRowLease(ctx context.Context, arg ReleaseBatchRowLeaseParams) error {
+	_, err := q.db.Exec(ctx, releaseBatchRowLease, arg.Rowid, arg.Worker)
+	return err
+}
+
+const releaseDependentBatch = `-- name: ReleaseDependentBatch :exec
+UPDATE batches
+SET status = 'queued',
+    context = CASE WHEN jsonb_typeof(context) = 'object'
+        THEN context || jsonb_build_object('alya_parents', $1::jsonb)
+        ELSE context END
+WHERE id = $2
+`
+
+type ReleaseDependentBatchParams struct {
+	Parents []byte    `json:"parents"`
+	ID      uuid.UUID `json:"id"`
+}
+
+// Queues a batch whose parents are done, adding them to its context if it is an object.
+func (q *Queries) ReleaseDependentBatch(ctx context.Context, arg ReleaseDependentBatchParams) error {
+	_, err := q.db.Exec(ctx, releaseDependentBatch, arg.Parents, arg.ID)
+	return err
+}
+
+const renewBatchRowLeases = `-- name: RenewBatchRowLeases :exec
+UPDATE batchrows
+SET leased_until = $1::timestamp
+WHERE worker = $2::text AND status = 'inprog'
+`
+
+type RenewBatchRowLeasesParams struct {
+	LeasedUntil pgtype.Timestamp `json:"leased_until"`
+	Worker      string           `json:"worker"`
+}
+
+// Extends the leases of the rows a worker is still processing.
+func (q *Queries) RenewBatchRowLeases(ctx context.Context, arg RenewBatchRowLeasesParams) error {
+	_, err := q.db.Exec(ctx, renewBatchRowLeases, arg.LeasedUntil, arg.Worker)
+	return err
+}
+
+const reopenBatch = `-- name: ReopenBatch :exec
+UPDATE batches
+SET status = 'queued', doneat = NULL, outputfiles = NULL, nsuccess = NULL, nfailed = NULL, naborted = NULL, outputtypes = NULL
+WHERE id = $1
+`
+
+// Puts a summarised batch back in the queue, to be summarised again once its
+// requeued rows are done.
+func (q *Queries) ReopenBatch(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.Exec(ctx, reopenBatch, id)
+	return err
+}
+
+const requeueBatchRowForRetry = `-- name: RequeueBatchRowForRetry :exec
+UPDATE batchrows
+SET status = 'queued', attempts = attempts + 1, not_before = $1, messages = $2
+WHERE rowid = $3 AND status = 'inprog'
+`
+
+type RequeueBatchRowForRetryParams struct {
+	NotBefore pgtype.Timestamp `json:"not_before"`
+	Messages  []byte           `json:"messages"`
+	Rowid     int64            `json:"rowid"`
+}
+
+// Puts a row that failed with a retryable error back in the queue. attempts
+// counts the failed attempts so far; the row is not picked up again before
+// not_before. Only rows still 'inprog' are requeued, so a row that was
+// aborted in the meantime stays aborted.
+func (q *Queries) RequeueBatchRowForRetry(ctx context.Context, arg RequeueBatchRowForRetryParams) error {
+	_, err := q.db.Exec(ctx, requeueBatchRowForRetry, arg.NotBefore, arg.Messages, arg.Rowid)
+	return err
+}
+
+const requeueBatchRowsForRerun = `-- name: RequeueBatchRowsForRerun :exec
+UPDATE batchrows
+SET status = 'queued', doneat = NULL, res = NULL, blobrows = NULL, messages = NULL, doneby = NULL, attempts = 0, not_before = NULL
+WHERE rowid = ANY($1::bigint[])
+`
+
+// Puts rows back in the queue as if they had never been processed.
+func (q *Queries) RequeueBatchRowsForRerun(ctx context.Context, rowids []int64) error {
+	_, err := q.db.Exec(ctx, requeueBatchRowsForRerun, rowids)
+	return err
+}
+
+const resetExpiredLeasesToQueued = `-- name: ResetExpiredLeasesToQueued :execrows
+UPDATE batchrows
+SET status = 'queued', worker = NULL, leased_until = NULL
+WHERE status = 'inprog' AND leased_until < $1::timestamp
+  AND NOT EXISTS (SELECT 1 FROM workers WHERE workers.instance_id = batchrows.worker)
+`
+
+// Resets the 'inprog' rows whose lease has expired and whose worker is no longer
+// registered to 'queued', for recovery of the rows of workers that were removed from
+// the registry, such as by Shutdown, with rows still leased to them.
+func (q *Queries) ResetExpiredLeasesToQueued(ctx context.Context, now pgtype.Timestamp) (int64, error) {
+	result, err := q.db.Exec(ctx, resetExpiredLeasesToQueued, now)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
 }
 
 const resetRowsToQueued = `-- name: ResetRowsToQueued :exec
@@ -607,6 +1905,54 @@ func (q *Queries) ResetRowsToQueued(ctx context.Context, dollar_1 []int64) error
 	return err
 }
 
+const resetWorkerRowsToQueued = `-- name: ResetWorkerRowsToQueued :execrows
+UPDATE batchrows
+SET status = 'queued', worker = NULL, leased_until = NULL
+WHERE worker = $1::text AND status = 'inprog'
+`
+
+// Resets the rows of a dead worker that are still 'inprog' to 'queued', for recovery
+// of abandoned rows. Rows it finished or that were reset already are left alone.
+func (q *Queries) ResetWorkerRowsToQueued(ctx context.Context, worker string) (int64, error) {
+	result, err := q.db.Exec(ctx, resetWorkerRowsToQueued, worker)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const resumeBatch = `-- name: ResumeBatch :one
+UPDATE batches
+SET status = 'queued'
+WHERE id = $1
+  AND status = 'paused'
+RETURNING app
+`
+
+// The batch is queued again; picking up its rows puts it back in progress.
+func (q *Queries) ResumeBatch(ctx context.Context, id uuid.UUID) (string, error) {
+	row := q.db.QueryRow(ctx, resumeBatch, id)
+	var app string
+	err := row.Scan(&app)
+	return app, err
+}
+
+const setBatchParent = `-- name: SetBatchParent :exec
+UPDATE batches
+SET parent_id = $1
+WHERE id = $2
+`
+
+type SetBatchParentParams struct {
+	ParentID pgtype.UUID `json:"parent_id"`
+	ID       uuid.UUID   `json:"id"`
+}
+
+func (q *Queries) SetBatchParent(ctx context.Context, arg SetBatchParentParams) error {
+	_, err := q.db.Exec(ctx, setBatchParent, arg.ParentID, arg.ID)
+	return err
+}
+
 const tryAdvisoryLockBatch = `-- name: TryAdvisoryLockBatch :one
 SELECT pg_try_advisory_xact_lock(
   ('x' || substr(md5($1::text), 1, 16))::bit(64)::bigint
@@ -690,6 +2036,22 @@ func (q *Queries) UpdateBatchResult(ctx context.Context, arg UpdateBatchResultPa
 	return err
 }
 
+const updateBatchRowInput = `-- name: UpdateBatchRowInput :exec
+UPDATE batchrows
+SET input = $1
+WHERE rowid = $2
+`
+
+type UpdateBatchRowInputParams struct {
+	Input []byte `json:"input"`
+	Rowid int64  `json:"rowid"`
+}
+
+func (q *Queries) UpdateBatchRowInput(ctx context.Context, arg UpdateBatchRowInputParams) error {
+	_, err := q.db.Exec(ctx, updateBatchRowInput, arg.Input, arg.Rowid)
+	return err
+}
+
 const updateBatchRowStatus = `-- name: UpdateBatchRowStatus :exec
 UPDATE batchrows
 SET status = $2
@@ -882,6 +2244,23 @@ func (q *Queries) UpdateBatchRowsStatusBulk(ctx context.Context, arg UpdateBatch
 	return err
 }
 
+const updateBatchScheduleFired = `-- name: UpdateBatchScheduleFired :exec
+UPDATE batch_schedules
+SET last_fireat = $2, last_batch = $3
+WHERE name = $1
+`
+
+type UpdateBatchScheduleFiredParams struct {
+	Name       string           `json:"name"`
+	LastFireat pgtype.Timestamp `json:"last_fireat"`
+	LastBatch  pgtype.UUID      `json:"last_batch"`
+}
+
+func (q *Queries) UpdateBatchScheduleFired(ctx context.Context, arg UpdateBatchScheduleFiredParams) error {
+	_, err := q.db.Exec(ctx, updateBatchScheduleFired, arg.Name, arg.LastFireat, arg.LastBatch)
+	return err
+}
+
 const updateBatchStatus = `-- name: UpdateBatchStatus :exec
 UPDATE batches
 SET status = $2, doneat = $3, outputfiles = $4, nsuccess = $5, nfailed = $6, naborted = $7
@@ -913,7 +2292,7 @@ func (q *Queries) UpdateBatchStatus(ctx context.Context, arg UpdateBatchStatusPa
 
 const updateBatchSummary = `-- name: UpdateBatchSummary :exec
 UPDATE batches
-SET status = $2, doneat = $3, outputfiles = $4, nsuccess = $5, nfailed = $6, naborted = $7
+SET status = $2, doneat = $3, outputfiles = $4, nsuccess = $5, nfailed = $6, naborted = $7, outputtypes = $8
 WHERE id = $1
 `
 
@@ -925,6 +2304,7 @@ type UpdateBatchSummaryParams struct {
 	Nsuccess    pgtype.Int4      `json:"nsuccess"`
 	Nfailed     pgtype.Int4      `json:"nfailed"`
 	Naborted    pgtype.Int4      `json:"naborted"`
+	Outputtypes []byte           `json:"outputtypes"`
 }
 
 func (q *Queries) UpdateBatchSummary(ctx context.Context, arg UpdateBatchSummaryParams) error {
@@ -936,6 +2316,7 @@ func (q *Queries) UpdateBatchSummary(ctx context.Context, arg UpdateBatchSummary
 		arg.Nsuccess,
 		arg.Nfailed,
 		arg.Naborted,
+		arg.Outputtypes,
 	)
 	return err
 }
@@ -982,3 +2363,31 @@ func (q *Queries) UpdateBatchesStatusBulk(ctx context.Context, arg UpdateBatches
 	_, err := q.db.Exec(ctx, updateBatchesStatusBulk, arg.Status, arg.BatchIds)
 	return err
 }
+
+const upsertBatchSchedule = `-- name: UpsertBatchSchedule :exec
+INSERT INTO batch_schedules (name, app, op, cron, last_fireat)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (name) DO UPDATE
+SET app = EXCLUDED.app, op = EXCLUDED.op, cron = EXCLUDED.cron
+`
+
+type UpsertBatchScheduleParams struct {
+	Name       string           `json:"name"`
+	App        string           `json:"app"`
+	Op         string           `json:"op"`
+	Cron       string           `json:"cron"`
+	LastFireat pgtype.Timestamp `json:"last_fireat"`
+}
+
+// Records a registered schedule. A new schedule starts counting from
+// last_fireat; for an existing one only the definition is updated.
+func (q *Queries) UpsertBatchSchedule(ctx context.Context, arg UpsertBatchScheduleParams) error {
+	_, err := q.db.Exec(ctx, upsertBatchSchedule,
+		arg.Name,
+		arg.App,
+		arg.Op,
+		arg.Cron,
+		arg.LastFireat,
+	)
+	return err
+}
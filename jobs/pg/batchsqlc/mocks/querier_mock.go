@@ -6,6 +6,7 @@ package mocks
 import (
 	"context"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/remiges-tech/alya/jobs/pg/batchsqlc"
 	"sync"
 )
@@ -20,9 +21,24 @@ var _ batchsqlc.Querier = &QuerierMock{}
 //
 //		// make and configure a mocked batchsqlc.Querier
 //		mockedQuerier := &QuerierMock{
+//			ArchiveBatchRowsFunc: func(ctx context.Context, rowids []int64) error {
+//				panic("mock out the ArchiveBatchRows method")
+//			},
 //			BulkInsertIntoBatchRowsFunc: func(ctx context.Context, arg batchsqlc.BulkInsertIntoBatchRowsParams) (int64, error) {
 //				panic("mock out the BulkInsertIntoBatchRows method")
 //			},
+//			ClaimIdempotencyKeyFunc: func(ctx context.Context, arg batchsqlc.ClaimIdempotencyKeyParams) (uuid.UUID, error) {
+//				panic("mock out the ClaimIdempotencyKey method")
+//			},
+//			ClaimWebhookDeliveriesFunc: func(ctx context.Context, arg batchsqlc.ClaimWebhookDeliveriesParams) ([]batchsqlc.ClaimWebhookDeliveriesRow, error) {
+//				panic("mock out the ClaimWebhookDeliveries method")
+//			},
+//			CopyBatchRowsFunc: func(ctx context.Context, arg []batchsqlc.CopyBatchRowsParams) (int64, error) {
+//				panic("mock out the CopyBatchRows method")
+//			},
+//			CountBatchDependenciesFunc: func(ctx context.Context, batch uuid.UUID) (int64, error) {
+//				panic("mock out the CountBatchDependencies method")
+//			},
 //			CountBatchRowsByBatchIDAndStatusFunc: func(ctx context.Context, arg batchsqlc.CountBatchRowsByBatchIDAndStatusParams) (int64, error) {
 //				panic("mock out the CountBatchRowsByBatchIDAndStatus method")
 //			},
@@ -32,15 +48,45 @@ var _ batchsqlc.Querier = &QuerierMock{}
 //			CountBatchRowsQueuedByBatchIDFunc: func(ctx context.Context, batch uuid.UUID) (int64, error) {
 //				panic("mock out the CountBatchRowsQueuedByBatchID method")
 //			},
+//			CountBatchesToPurgeFunc: func(ctx context.Context, arg batchsqlc.CountBatchesToPurgeParams) (batchsqlc.CountBatchesToPurgeRow, error) {
+//				panic("mock out the CountBatchesToPurge method")
+//			},
+//			DeleteBatchFilesByBatchIDsFunc: func(ctx context.Context, batchIds []uuid.UUID) error {
+//				panic("mock out the DeleteBatchFilesByBatchIDs method")
+//			},
+//			DeleteBatchRowsByBatchIDsFunc: func(ctx context.Context, batchIds []uuid.UUID) error {
+//				panic("mock out the DeleteBatchRowsByBatchIDs method")
+//			},
+//			DeleteBatchesByIDsFunc: func(ctx context.Context, ids []uuid.UUID) (int64, error) {
+//				panic("mock out the DeleteBatchesByIDs method")
+//			},
+//			DeleteWorkerFunc: func(ctx context.Context, instanceID string) error {
+//				panic("mock out the DeleteWorker method")
+//			},
+//			DropBatchRowPartitionsFunc: func(ctx context.Context, cutoff pgtype.Timestamp) ([]string, error) {
+//				panic("mock out the DropBatchRowPartitions method")
+//			},
+//			EnsureBatchRowPartitionsFunc: func(ctx context.Context, monthsAhead int32) ([]string, error) {
+//				panic("mock out the EnsureBatchRowPartitions method")
+//			},
 //			FetchBatchRowsForBatchDoneFunc: func(ctx context.Context, batch uuid.UUID) ([]batchsqlc.FetchBatchRowsForBatchDoneRow, error) {
 //				panic("mock out the FetchBatchRowsForBatchDone method")
 //			},
 //			FetchBlockOfRowsFunc: func(ctx context.Context, arg batchsqlc.FetchBlockOfRowsParams) ([]batchsqlc.FetchBlockOfRowsRow, error) {
 //				panic("mock out the FetchBlockOfRows method")
 //			},
+//			FetchBlockOfRowsFairFunc: func(ctx context.Context, arg batchsqlc.FetchBlockOfRowsFairParams) ([]batchsqlc.FetchBlockOfRowsFairRow, error) {
+//				panic("mock out the FetchBlockOfRowsFair method")
+//			},
 //			GetBatchByIDFunc: func(ctx context.Context, id uuid.UUID) (batchsqlc.Batch, error) {
 //				panic("mock out the GetBatchByID method")
 //			},
+//			GetBatchProgressFunc: func(ctx context.Context, batch uuid.UUID) (batchsqlc.GetBatchProgressRow, error) {
+//				panic("mock out the GetBatchProgress method")
+//			},
+//			GetBatchRowHistoryFunc: func(ctx context.Context, batch uuid.UUID) ([]batchsqlc.BatchrowHistory, error) {
+//				panic("mock out the GetBatchRowHistory method")
+//			},
 //			GetBatchRowsByBatchIDFunc: func(ctx context.Context, batch uuid.UUID) ([]batchsqlc.Batchrow, error) {
 //				panic("mock out the GetBatchRowsByBatchID method")
 //			},
@@ -50,24 +96,54 @@ var _ batchsqlc.Querier = &QuerierMock{}
 //			GetBatchRowsCountFunc: func(ctx context.Context, batch uuid.UUID) (int64, error) {
 //				panic("mock out the GetBatchRowsCount method")
 //			},
+//			GetBatchRowsForRerunFunc: func(ctx context.Context, arg batchsqlc.GetBatchRowsForRerunParams) ([]batchsqlc.GetBatchRowsForRerunRow, error) {
+//				panic("mock out the GetBatchRowsForRerun method")
+//			},
+//			GetBatchRowsPageFunc: func(ctx context.Context, arg batchsqlc.GetBatchRowsPageParams) ([]batchsqlc.Batchrow, error) {
+//				panic("mock out the GetBatchRowsPage method")
+//			},
+//			GetBatchScheduleForUpdateFunc: func(ctx context.Context, name string) (batchsqlc.GetBatchScheduleForUpdateRow, error) {
+//				panic("mock out the GetBatchScheduleForUpdate method")
+//			},
 //			GetBatchStatusFunc: func(ctx context.Context, id uuid.UUID) (batchsqlc.StatusEnum, error) {
 //				panic("mock out the GetBatchStatus method")
 //			},
 //			GetBatchStatusAndOutputFilesFunc: func(ctx context.Context, id uuid.UUID) (batchsqlc.GetBatchStatusAndOutputFilesRow, error) {
 //				panic("mock out the GetBatchStatusAndOutputFiles method")
 //			},
+//			GetBatchesToPurgeFunc: func(ctx context.Context, arg batchsqlc.GetBatchesToPurgeParams) ([]batchsqlc.Batch, error) {
+//				panic("mock out the GetBatchesToPurge method")
+//			},
 //			GetCompletedBatchesFunc: func(ctx context.Context) ([]uuid.UUID, error) {
 //				panic("mock out the GetCompletedBatches method")
 //			},
+//			GetDeadWorkersFunc: func(ctx context.Context, cutoff pgtype.Timestamp) ([]string, error) {
+//				panic("mock out the GetDeadWorkers method")
+//			},
+//			GetIdempotencyKeyBatchFunc: func(ctx context.Context, arg batchsqlc.GetIdempotencyKeyBatchParams) (uuid.UUID, error) {
+//				panic("mock out the GetIdempotencyKeyBatch method")
+//			},
+//			GetParentBatchesFunc: func(ctx context.Context, batch uuid.UUID) ([]batchsqlc.GetParentBatchesRow, error) {
+//				panic("mock out the GetParentBatches method")
+//			},
 //			GetPendingBatchRowsFunc: func(ctx context.Context, batch uuid.UUID) ([]batchsqlc.GetPendingBatchRowsRow, error) {
 //				panic("mock out the GetPendingBatchRows method")
 //			},
-//			GetProcessedBatchRowsByBatchIDSortedFunc: func(ctx context.Context, batch uuid.UUID) ([]batchsqlc.GetProcessedBatchRowsByBatchIDSortedRow, error) {
-//				panic("mock out the GetProcessedBatchRowsByBatchIDSorted method")
+//			GetProcessedBatchRowsPageFunc: func(ctx context.Context, arg batchsqlc.GetProcessedBatchRowsPageParams) ([]batchsqlc.GetProcessedBatchRowsPageRow, error) {
+//				panic("mock out the GetProcessedBatchRowsPage method")
 //			},
 //			GetUnsummarizedBatchesFunc: func(ctx context.Context) ([]uuid.UUID, error) {
 //				panic("mock out the GetUnsummarizedBatches method")
 //			},
+//			GetWaitingDependentBatchesFunc: func(ctx context.Context, parent uuid.UUID) ([]uuid.UUID, error) {
+//				panic("mock out the GetWaitingDependentBatches method")
+//			},
+//			GetWebhookDeliveriesByBatchIDFunc: func(ctx context.Context, batch uuid.UUID) ([]batchsqlc.WebhookDelivery, error) {
+//				panic("mock out the GetWebhookDeliveriesByBatchID method")
+//			},
+//			InsertBatchDependencyFunc: func(ctx context.Context, arg batchsqlc.InsertBatchDependencyParams) error {
+//				panic("mock out the InsertBatchDependency method")
+//			},
 //			InsertBatchFileFunc: func(ctx context.Context, arg batchsqlc.InsertBatchFileParams) error {
 //				panic("mock out the InsertBatchFile method")
 //			},
@@ -77,9 +153,69 @@ var _ batchsqlc.Querier = &QuerierMock{}
 //			InsertIntoBatchesFunc: func(ctx context.Context, arg batchsqlc.InsertIntoBatchesParams) (uuid.UUID, error) {
 //				panic("mock out the InsertIntoBatches method")
 //			},
+//			InsertWebhookDeliveryFunc: func(ctx context.Context, arg batchsqlc.InsertWebhookDeliveryParams) error {
+//				panic("mock out the InsertWebhookDelivery method")
+//			},
+//			LeaseBatchRowsFunc: func(ctx context.Context, arg batchsqlc.LeaseBatchRowsParams) error {
+//				panic("mock out the LeaseBatchRows method")
+//			},
+//			ListBatchesFunc: func(ctx context.Context, arg batchsqlc.ListBatchesParams) ([]batchsqlc.ListBatchesRow, error) {
+//				panic("mock out the ListBatches method")
+//			},
+//			ListSlowQueriesFunc: func(ctx context.Context, arg batchsqlc.ListSlowQueriesParams) ([]batchsqlc.ListSlowQueriesRow, error) {
+//				panic("mock out the ListSlowQueries method")
+//			},
+//			LockParentBatchesFunc: func(ctx context.Context, ids []uuid.UUID) ([]uuid.UUID, error) {
+//				panic("mock out the LockParentBatches method")
+//			},
+//			MarkWebhookAttemptFailedFunc: func(ctx context.Context, arg batchsqlc.MarkWebhookAttemptFailedParams) (int64, error) {
+//				panic("mock out the MarkWebhookAttemptFailed method")
+//			},
+//			MarkWebhookDeliveredFunc: func(ctx context.Context, arg batchsqlc.MarkWebhookDeliveredParams) (int64, error) {
+//				panic("mock out the MarkWebhookDelivered method")
+//			},
+//			NotifyJobsQueuedFunc: func(ctx context.Context, app string) (string, error) {
+//				panic("mock out the NotifyJobsQueued method")
+//			},
+//			PauseBatchFunc: func(ctx context.Context, id uuid.UUID) (string, error) {
+//				panic("mock out the PauseBatch method")
+//			},
+//			RecordWorkerHeartbeatFunc: func(ctx context.Context, arg batchsqlc.RecordWorkerHeartbeatParams) error {
+//				panic("mock out the RecordWorkerHeartbeat method")
+//			},
+//			ReleaseBatchRowLeaseFunc: func(ctx context.Context, arg batchsqlc.ReleaseBatchRowLeaseParams) error {
+//				panic("mock out the ReleaseBatchRowLease method")
+//			},
+//			ReleaseDependentBatchFunc: func(ctx context.Context, arg batchsqlc.ReleaseDependentBatchParams) error {
+//				panic("mock out the ReleaseDependentBatch method")
+//			},
+//			RenewBatchRowLeasesFunc: func(ctx context.Context, arg batchsqlc.RenewBatchRowLeasesParams) error {
+//				panic("mock out the RenewBatchRowLeases method")
+//			},
+//			ReopenBatchFunc: func(ctx context.Context, id uuid.UUID) error {
+//				panic("mock out the ReopenBatch method")
+//			},
+//			RequeueBatchRowForRetryFunc: func(ctx context.Context, arg batchsqlc.RequeueBatchRowForRetryParams) error {
+//				panic("mock out the RequeueBatchRowForRetry method")
+//			},
+//			RequeueBatchRowsForRerunFunc: func(ctx context.Context, rowids []int64) error {
+//				panic("mock out the RequeueBatchRowsForRerun method")
+//			},
+//			ResetExpiredLeasesToQueuedFunc: func(ctx context.Context, now pgtype.Timestamp) (int64, error) {
+//				panic("mock out the ResetExpiredLeasesToQueued method")
+//			},
 //			ResetRowsToQueuedFunc: func(ctx context.Context, dollar_1 []int64) error {
 //				panic("mock out the ResetRowsToQueued method")
 //			},
+//			ResetWorkerRowsToQueuedFunc: func(ctx context.Context, worker string) (int64, error) {
+//				panic("mock out the ResetWorkerRowsToQueued method")
+//			},
+//			ResumeBatchFunc: func(ctx context.Context, id uuid.UUID) (string, error) {
+//				panic("mock out the ResumeBatch method")
+//			},
+//			SetBatchParentFunc: func(ctx context.Context, arg batchsqlc.SetBatchParentParams) error {
+//				panic("mock out the SetBatchParent method")
+//			},
 //			TryAdvisoryLockBatchFunc: func(ctx context.Context, dollar_1 string) (bool, error) {
 //				panic("mock out the TryAdvisoryLockBatch method")
 //			},
@@ -92,6 +228,9 @@ var _ batchsqlc.Querier = &QuerierMock{}
 //			UpdateBatchResultFunc: func(ctx context.Context, arg batchsqlc.UpdateBatchResultParams) error {
 //				panic("mock out the UpdateBatchResult method")
 //			},
+//			UpdateBatchRowInputFunc: func(ctx context.Context, arg batchsqlc.UpdateBatchRowInputParams) error {
+//				panic("mock out the UpdateBatchRowInput method")
+//			},
 //			UpdateBatchRowStatusFunc: func(ctx context.Context, arg batchsqlc.UpdateBatchRowStatusParams) error {
 //				panic("mock out the UpdateBatchRowStatus method")
 //			},
@@ -116,6 +255,9 @@ var _ batchsqlc.Querier = &QuerierMock{}
 //			UpdateBatchRowsStatusBulkFunc: func(ctx context.Context, arg batchsqlc.UpdateBatchRowsStatusBulkParams) error {
 //				panic("mock out the UpdateBatchRowsStatusBulk method")
 //			},
+//			UpdateBatchScheduleFiredFunc: func(ctx context.Context, arg batchsqlc.UpdateBatchScheduleFiredParams) error {
+//				panic("mock out the UpdateBatchScheduleFired method")
+//			},
 //			UpdateBatchStatusFunc: func(ctx context.Context, arg batchsqlc.UpdateBatchStatusParams) error {
 //				panic("mock out the UpdateBatchStatus method")
 //			},
@@ -128,6 +270,9 @@ var _ batchsqlc.Querier = &QuerierMock{}
 //			UpdateBatchesStatusBulkFunc: func(ctx context.Context, arg batchsqlc.UpdateBatchesStatusBulkParams) error {
 //				panic("mock out the UpdateBatchesStatusBulk method")
 //			},
+//			UpsertBatchScheduleFunc: func(ctx context.Context, arg batchsqlc.UpsertBatchScheduleParams) error {
+//				panic("mock out the UpsertBatchSchedule method")
+//			},
 //		}
 //
 //		// use mockedQuerier in code that requires batchsqlc.Querier
@@ -135,9 +280,24 @@ var _ batchsqlc.Querier = &QuerierMock{}
 //
 //	}
 type QuerierMock struct {
+	// ArchiveBatchRowsFunc mocks the ArchiveBatchRows method.
+	ArchiveBatchRowsFunc func(ctx context.Context, rowids []int64) error
+
 	// BulkInsertIntoBatchRowsFunc mocks the BulkInsertIntoBatchRows method.
 	BulkInsertIntoBatchRowsFunc func(ctx context.Context, arg batchsqlc.BulkInsertIntoBatchRowsParams) (int64, error)
 
+	// ClaimIdempotencyKeyFunc mocks the ClaimIdempotencyKey method.
+	ClaimIdempotencyKeyFunc func(ctx context.Context, arg batchsqlc.ClaimIdempotencyKeyParams) (uuid.UUID, error)
+
+	// ClaimWebhookDeliveriesFunc mocks the ClaimWebhookDeliveries method.
+	ClaimWebhookDeliveriesFunc func(ctx context.Context, arg batchsqlc.ClaimWebhookDeliveriesParams) ([]batchsqlc.ClaimWebhookDeliveriesRow, error)
+
+	// CopyBatchRowsFunc mocks the CopyBatchRows method.
+	CopyBatchRowsFunc func(ctx context.Context, arg []batchsqlc.CopyBatchRowsParams) (int64, error)
+
+	// CountBatchDependenciesFunc mocks the CountBatchDependencies method.
+	CountBatchDependenciesFunc func(ctx context.Context, batch uuid.UUID) (int64, error)
+
 	// CountBatchRowsByBatchIDAndStatusFunc mocks the CountBatchRowsByBatchIDAndStatus method.
 	CountBatchRowsByBatchIDAndStatusFunc func(ctx context.Context, arg batchsqlc.CountBatchRowsByBatchIDAndStatusParams) (int64, error)
 
@@ -147,15 +307,45 @@ type QuerierMock struct {
 	// CountBatchRowsQueuedByBatchIDFunc mocks the CountBatchRowsQueuedByBatchID method.
 	CountBatchRowsQueuedByBatchIDFunc func(ctx context.Context, batch uuid.UUID) (int64, error)
 
+	// CountBatchesToPurgeFunc mocks the CountBatchesToPurge method.
+	CountBatchesToPurgeFunc func(ctx context.Context, arg batchsqlc.CountBatchesToPurgeParams) (batchsqlc.CountBatchesToPurgeRow, error)
+
+	// DeleteBatchFilesByBatchIDsFunc mocks the DeleteBatchFilesByBatchIDs method.
+	DeleteBatchFilesByBatchIDsFunc func(ctx context.Context, batchIds []uuid.UUID) error
+
+	// DeleteBatchRowsByBatchIDsFunc mocks the DeleteBatchRowsByBatchIDs method.
+	DeleteBatchRowsByBatchIDsFunc func(ctx context.Context, batchIds []uuid.UUID) error
+
+	// DeleteBatchesByIDsFunc mocks the DeleteBatchesByIDs method.
+	DeleteBatchesByIDsFunc func(ctx context.Context, ids []uuid.UUID) (int64, error)
+
+	// DeleteWorkerFunc mocks the DeleteWorker method.
+	DeleteWorkerFunc func(ctx context.Context, instanceID string) error
+
+	// DropBatchRowPartitionsFunc mocks the DropBatchRowPartitions method.
+	DropBatchRowPartitionsFunc func(ctx context.Context, cutoff pgtype.Timestamp) ([]string, error)
+
+	// EnsureBatchRowPartitionsFunc mocks the EnsureBatchRowPartitions method.
+	EnsureBatchRowPartitionsFunc func(ctx context.Context, monthsAhead int32) ([]string, error)
+
 	// FetchBatchRowsForBatchDoneFunc mocks the FetchBatchRowsForBatchDone method.
 	FetchBatchRowsForBatchDoneFunc func(ctx context.Context, batch uuid.UUID) ([]batchsqlc.FetchBatchRowsForBatchDoneRow, error)
 
 	// FetchBlockOfRowsFunc mocks the FetchBlockOfRows method.
 	FetchBlockOfRowsFunc func(ctx context.Context, arg batchsqlc.FetchBlockOfRowsParams) ([]batchsqlc.FetchBlockOfRowsRow, error)
 
+	// FetchBlockOfRowsFairFunc mocks the FetchBlockOfRowsFair method.
+	FetchBlockOfRowsFairFunc func(ctx context.Context, arg batchsqlc.FetchBlockOfRowsFairParams) ([]batchsqlc.FetchBlockOfRowsFairRow, error)
+
 	// GetBatchByIDFunc mocks the GetBatchByID method.
 	GetBatchByIDFunc func(ctx context.Context, id uuid.UUID) (batchsqlc.Batch, error)
 
+	// GetBatchProgressFunc mocks the GetBatchProgress method.
+	GetBatchProgressFunc func(ctx context.Context, batch uuid.UUID) (batchsqlc.GetBatchProgressRow, error)
+
+	// GetBatchRowHistoryFunc mocks the GetBatchRowHistory method.
+	GetBatchRowHistoryFunc func(ctx context.Context, batch uuid.UUID) ([]batchsqlc.BatchrowHistory, error)
+
 	// GetBatchRowsByBatchIDFunc mocks the GetBatchRowsByBatchID method.
 	GetBatchRowsByBatchIDFunc func(ctx context.Context, batch uuid.UUID) ([]batchsqlc.Batchrow, error)
 
@@ -165,24 +355,54 @@ type QuerierMock struct {
 	// GetBatchRowsCountFunc mocks the GetBatchRowsCount method.
 	GetBatchRowsCountFunc func(ctx context.Context, batch uuid.UUID) (int64, error)
 
+	// GetBatchRowsForRerunFunc mocks the GetBatchRowsForRerun method.
+	GetBatchRowsForRerunFunc func(ctx context.Context, arg batchsqlc.GetBatchRowsForRerunParams) ([]batchsqlc.GetBatchRowsForRerunRow, error)
+
+	// GetBatchRowsPageFunc mocks the GetBatchRowsPage method.
+	GetBatchRowsPageFunc func(ctx context.Context, arg batchsqlc.GetBatchRowsPageParams) ([]batchsqlc.Batchrow, error)
+
+	// GetBatchScheduleForUpdateFunc mocks the GetBatchScheduleForUpdate method.
+	GetBatchScheduleForUpdateFunc func(ctx context.Context, name string) (batchsqlc.GetBatchScheduleForUpdateRow, error)
+
 	// GetBatchStatusFunc mocks the GetBatchStatus method.
 	GetBatchStatusFunc func(ctx context.Context, id uuid.UUID) (batchsqlc.StatusEnum, error)
 
 	// GetBatchStatusAndOutputFilesFunc mocks the GetBatchStatusAndOutputFiles method.
 	GetBatchStatusAndOutputFilesFunc func(ctx context.Context, id uuid.UUID) (batchsqlc.GetBatchStatusAndOutputFilesRow, error)
 
+	// GetBatchesToPurgeFunc mocks the GetBatchesToPurge method.
+	GetBatchesToPurgeFunc func(ctx context.Context, arg batchsqlc.GetBatchesToPurgeParams) ([]batchsqlc.Batch, error)
+
 	// GetCompletedBatchesFunc mocks the GetCompletedBatches method.
 	GetCompletedBatchesFunc func(ctx context.Context) ([]uuid.UUID, error)
 
+	// GetDeadWorkersFunc mocks the GetDeadWorkers method.
+	GetDeadWorkersFunc func(ctx context.Context, cutoff pgtype.Timestamp) ([]string, error)
+
+	// GetIdempotencyKeyBatchFunc mocks the GetIdempotencyKeyBatch method.
+	GetIdempotencyKeyBatchFunc func(ctx context.Context, arg batchsqlc.GetIdempotencyKeyBatchParams) (uuid.UUID, error)
+
+	// GetParentBatchesFunc mocks the GetParentBatches method.
+	GetParentBatchesFunc func(ctx context.Context, batch uuid.UUID) ([]batchsqlc.GetParentBatchesRow, error)
+
 	// GetPendingBatchRowsFunc mocks the GetPendingBatchRows method.
 	GetPendingBatchRowsFunc func(ctx context.Context, batch uuid.UUID) ([]batchsqlc.GetPendingBatchRowsRow, error)
 
-	// GetProcessedBatchRowsByBatchIDSortedFunc mocks the GetProcessedBatchRowsByBatchIDSorted method.
-	GetProcessedBatchRowsByBatchIDSortedFunc func(ctx context.Context, batch uuid.UUID) ([]batchsqlc.GetProcessedBatchRowsByBatchIDSortedRow, error)
+	// GetProcessedBatchRowsPageFunc mocks the GetProcessedBatchRowsPage method.
+	GetProcessedBatchRowsPageFunc func(ctx context.Context, arg batchsqlc.GetProcessedBatchRowsPageParams) ([]batchsqlc.GetProcessedBatchRowsPageRow, error)
 
 	// GetUnsummarizedBatchesFunc mocks the GetUnsummarizedBatches method.
 	GetUnsummarizedBatchesFunc func(ctx context.Context) ([]uuid.UUID, error)
 
+	// GetWaitingDependentBatchesFunc mocks the GetWaitingDependentBatches method.
+	GetWaitingDependentBatchesFunc func(ctx context.Context, parent uuid.UUID) ([]uuid.UUID, error)
+
+	// GetWebhookDeliveriesByBatchIDFunc mocks the GetWebhookDeliveriesByBatchID method.
+	GetWebhookDeliveriesByBatchIDFunc func(ctx context.Context, batch uuid.UUID) ([]batchsqlc.WebhookDelivery, error)
+
+	// InsertBatchDependencyFunc mocks the InsertBatchDependency method.
+	InsertBatchDependencyFunc func(ctx context.Context, arg batchsqlc.InsertBatchDependencyParams) error
+
 	// InsertBatchFileFunc mocks the InsertBatchFile method.
 	InsertBatchFileFunc func(ctx context.Context, arg batchsqlc.InsertBatchFileParams) error
 
@@ -192,9 +412,69 @@ type QuerierMock struct {
 	// InsertIntoBatchesFunc mocks the InsertIntoBatches method.
 	InsertIntoBatchesFunc func(ctx context.Context, arg batchsqlc.InsertIntoBatchesParams) (uuid.UUID, error)
 
+	// InsertWebhookDeliveryFunc mocks the InsertWebhookDelivery method.
+	InsertWebhookDeliveryFunc func(ctx context.Context, arg batchsqlc.InsertWebhookDeliveryParams) error
+
+	// LeaseBatchRowsFunc mocks the LeaseBatchRows method.
+	LeaseBatchRowsFunc func(ctx context.Context, arg batchsqlc.LeaseBatchRowsParams) error
+
+	// ListBatchesFunc mocks the ListBatches method.
+	ListBatchesFunc func(ctx context.Context, arg batchsqlc.ListBatchesParams) ([]batchsqlc.ListBatchesRow, error)
+
+	// ListSlowQueriesFunc mocks the ListSlowQueries method.
+	ListSlowQueriesFunc func(ctx context.Context, arg batchsqlc.ListSlowQueriesParams) ([]batchsqlc.ListSlowQueriesRow, error)
+
+	// LockParentBatchesFunc mocks the LockParentBatches method.
+	LockParentBatchesFunc func(ctx context.Context, ids []uuid.UUID) ([]uuid.UUID, error)
+
+	// MarkWebhookAttemptFailedFunc mocks the MarkWebhookAttemptFailed method.
+	MarkWebhookAttemptFailedFunc func(ctx context.Context, arg batchsqlc.MarkWebhookAttemptFailedParams) (int64, error)
+
+	// MarkWebhookDeliveredFunc mocks the MarkWebhookDelivered method.
+	MarkWebhookDeliveredFunc func(ctx context.Context, arg batchsqlc.MarkWebhookDeliveredParams) (int64, error)
+
+	// NotifyJobsQueuedFunc mocks the NotifyJobsQueued method.
+	NotifyJobsQueuedFunc func(ctx context.Context, app string) (string, error)
+
+	// PauseBatchFunc mocks the PauseBatch method.
+	PauseBatchFunc func(ctx context.Context, id uuid.UUID) (string, error)
+
+	// RecordWorkerHeartbeatFunc mocks the RecordWorkerHeartbeat method.
+	RecordWorkerHeartbeatFunc func(ctx context.Context, arg batchsqlc.RecordWorkerHeartbeatParams) error
+
+	// ReleaseBatchRowLeaseFunc mocks the ReleaseBatchRowLease method.
+	ReleaseBatchRowLeaseFunc func(ctx context.Context, arg batchsqlc.ReleaseBatchRowLeaseParams) error
+
+	// ReleaseDependentBatchFunc mocks the ReleaseDependentBatch method.
+	ReleaseDependentBatchFunc func(ctx context.Context, arg batchsqlc.ReleaseDependentBatchParams) error
+
+	// RenewBatchRowLeasesFunc mocks the RenewBatchRowLeases method.
+	RenewBatchRowLeasesFunc func(ctx context.Context, arg batchsqlc.RenewBatchRowLeasesParams) error
+
+	// ReopenBatchFunc mocks the ReopenBatch method.
+	ReopenBatchFunc func(ctx context.Context, id uuid.UUID) error
+
+	// RequeueBatchRowForRetryFunc mocks the RequeueBatchRowForRetry method.
+	RequeueBatchRowForRetryFunc func(ctx context.Context, arg batchsqlc.RequeueBatchRowForRetryParams) error
+
+	// RequeueBatchRowsForRerunFunc mocks the RequeueBatchRowsForRerun method.
+	RequeueBatchRowsForRerunFunc func(ctx context.Context, rowids []int64) error
+
+	// ResetExpiredLeasesToQueuedFunc mocks the ResetExpiredLeasesToQueued method.
+	ResetExpiredLeasesToQueuedFunc func(ctx context.Context, now pgtype.Timestamp) (int64, error)
+
 	// ResetRowsToQueuedFunc mocks the ResetRowsToQueued method.
 	ResetRowsToQueuedFunc func(ctx context.Context, dollar_1 []int64) error
 
+	// ResetWorkerRowsToQueuedFunc mocks the ResetWorkerRowsToQueued method.
+	ResetWorkerRowsToQueuedFunc func(ctx context.Context, worker string) (int64, error)
+
+	// ResumeBatchFunc mocks the ResumeBatch method.
+	ResumeBatchFunc func(ctx context.Context, id uuid.UUID) (string, error)
+
+	// SetBatchParentFunc mocks the SetBatchParent method.
+	SetBatchParentFunc func(ctx context.Context, arg batchsqlc.SetBatchParentParams) error
+
 	// TryAdvisoryLockBatchFunc mocks the TryAdvisoryLockBatch method.
 	TryAdvisoryLockBatchFunc func(ctx context.Context, dollar_1 string) (bool, error)
 
@@ -207,6 +487,9 @@ type QuerierMock struct {
 	// UpdateBatchResultFunc mocks the UpdateBatchResult method.
 	UpdateBatchResultFunc func(ctx context.Context, arg batchsqlc.UpdateBatchResultParams) error
 
+	// UpdateBatchRowInputFunc mocks the UpdateBatchRowInput method.
+	UpdateBatchRowInputFunc func(ctx context.Context, arg batchsqlc.UpdateBatchRowInputParams) error
+
 	// UpdateBatchRowStatusFunc mocks the UpdateBatchRowStatus method.
 	UpdateBatchRowStatusFunc func(ctx context.Context, arg batchsqlc.UpdateBatchRowStatusParams) error
 
@@ -231,6 +514,9 @@ type QuerierMock struct {
 	// UpdateBatchRowsStatusBulkFunc mocks the UpdateBatchRowsStatusBulk method.
 	UpdateBatchRowsStatusBulkFunc func(ctx context.Context, arg batchsqlc.UpdateBatchRowsStatusBulkParams) error
 
+	// UpdateBatchScheduleFiredFunc mocks the UpdateBatchScheduleFired method.
+	UpdateBatchScheduleFiredFunc func(ctx context.Context, arg batchsqlc.UpdateBatchScheduleFiredParams) error
+
 	// UpdateBatchStatusFunc mocks the UpdateBatchStatus method.
 	UpdateBatchStatusFunc func(ctx context.Context, arg batchsqlc.UpdateBatchStatusParams) error
 
@@ -243,8 +529,18 @@ type QuerierMock struct {
 	// UpdateBatchesStatusBulkFunc mocks the UpdateBatchesStatusBulk method.
 	UpdateBatchesStatusBulkFunc func(ctx context.Context, arg batchsqlc.UpdateBatchesStatusBulkParams) error
 
+	// UpsertBatchScheduleFunc mocks the UpsertBatchSchedule method.
+	UpsertBatchScheduleFunc func(ctx context.Context, arg batchsqlc.UpsertBatchScheduleParams) error
+
 	// calls tracks calls to the methods.
 	calls struct {
+		// ArchiveBatchRows holds details about calls to the ArchiveBatchRows method.
+		ArchiveBatchRows []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Rowids is the rowids argument value.
+			Rowids []int64
+		}
 		// BulkInsertIntoBatchRows holds details about calls to the BulkInsertIntoBatchRows method.
 		BulkInsertIntoBatchRows []struct {
 			// Ctx is the ctx argument value.
@@ -252,6 +548,34 @@ type QuerierMock struct {
 			// Arg is the arg argument value.
 			Arg batchsqlc.BulkInsertIntoBatchRowsParams
 		}
+		// ClaimIdempotencyKey holds details about calls to the ClaimIdempotencyKey method.
+		ClaimIdempotencyKey []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Arg is the arg argument value.
+			Arg batchsqlc.ClaimIdempotencyKeyParams
+		}
+		// ClaimWebhookDeliveries holds details about calls to the ClaimWebhookDeliveries method.
+		ClaimWebhookDeliveries []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Arg is the arg argument value.
+			Arg batchsqlc.ClaimWebhookDeliveriesParams
+		}
+		// CopyBatchRows holds details about calls to the CopyBatchRows method.
+		CopyBatchRows []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Arg is the arg argument value.
+			Arg []batchsqlc.CopyBatchRowsParams
+		}
+		// CountBatchDependencies holds details about calls to the CountBatchDependencies method.
+		CountBatchDependencies []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Batch is the batch argument value.
+			Batch uuid.UUID
+		}
 		// CountBatchRowsByBatchIDAndStatus holds details about calls to the CountBatchRowsByBatchIDAndStatus method.
 		CountBatchRowsByBatchIDAndStatus []struct {
 			// Ctx is the ctx argument value.
@@ -273,6 +597,55 @@ type QuerierMock struct {
 			// Batch is the batch argument value.
 			Batch uuid.UUID
 		}
+		// CountBatchesToPurge holds details about calls to the CountBatchesToPurge method.
+		CountBatchesToPurge []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Arg is the arg argument value.
+			Arg batchsqlc.CountBatchesToPurgeParams
+		}
+		// DeleteBatchFilesByBatchIDs holds details about calls to the DeleteBatchFilesByBatchIDs method.
+		DeleteBatchFilesByBatchIDs []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// BatchIds is the batchIds argument value.
+			BatchIds []uuid.UUID
+		}
+		// DeleteBatchRowsByBatchIDs holds details about calls to the DeleteBatchRowsByBatchIDs method.
+		DeleteBatchRowsByBatchIDs []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// BatchIds is the batchIds argument value.
+			BatchIds []uuid.UUID
+		}
+		// DeleteBatchesByIDs holds details about calls to the DeleteBatchesByIDs method.
+		DeleteBatchesByIDs []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Ids is the ids argument value.
+			Ids []uuid.UUID
+		}
+		// DeleteWorker holds details about calls to the DeleteWorker method.
+		DeleteWorker []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// InstanceID is the instanceID argument value.
+			InstanceID string
+		}
+		// DropBatchRowPartitions holds details about calls to the DropBatchRowPartitions method.
+		DropBatchRowPartitions []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Cutoff is the cutoff argument value.
+			Cutoff pgtype.Timestamp
+		}
+		// EnsureBatchRowPartitions holds details about calls to the EnsureBatchRowPartitions method.
+		EnsureBatchRowPartitions []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// MonthsAhead is the monthsAhead argument value.
+			MonthsAhead int32
+		}
 		// FetchBatchRowsForBatchDone holds details about calls to the FetchBatchRowsForBatchDone method.
 		FetchBatchRowsForBatchDone []struct {
 			// Ctx is the ctx argument value.
@@ -287,6 +660,13 @@ type QuerierMock struct {
 			// Arg is the arg argument value.
 			Arg batchsqlc.FetchBlockOfRowsParams
 		}
+		// FetchBlockOfRowsFair holds details about calls to the FetchBlockOfRowsFair method.
+		FetchBlockOfRowsFair []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Arg is the arg argument value.
+			Arg batchsqlc.FetchBlockOfRowsFairParams
+		}
 		// GetBatchByID holds details about calls to the GetBatchByID method.
 		GetBatchByID []struct {
 			// Ctx is the ctx argument value.
@@ -294,6 +674,20 @@ type QuerierMock struct {
 			// ID is the id argument value.
 			ID uuid.UUID
 		}
+		// GetBatchProgress holds details about calls to the GetBatchProgress method.
+		GetBatchProgress []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Batch is the batch argument value.
+			Batch uuid.UUID
+		}
+		// GetBatchRowHistory holds details about calls to the GetBatchRowHistory method.
+		GetBatchRowHistory []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Batch is the batch argument value.
+			Batch uuid.UUID
+		}
 		// GetBatchRowsByBatchID holds details about calls to the GetBatchRowsByBatchID method.
 		GetBatchRowsByBatchID []struct {
 			// Ctx is the ctx argument value.
@@ -315,6 +709,27 @@ type QuerierMock struct {
 			// Batch is the batch argument value.
 			Batch uuid.UUID
 		}
+		// GetBatchRowsForRerun holds details about calls to the GetBatchRowsForRerun method.
+		GetBatchRowsForRerun []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Arg is the arg argument value.
+			Arg batchsqlc.GetBatchRowsForRerunParams
+		}
+		// GetBatchRowsPage holds details about calls to the GetBatchRowsPage method.
+		GetBatchRowsPage []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Arg is the arg argument value.
+			Arg batchsqlc.GetBatchRowsPageParams
+		}
+		// GetBatchScheduleForUpdate holds details about calls to the GetBatchScheduleForUpdate method.
+		GetBatchScheduleForUpdate []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Name is the name argument value.
+			Name string
+		}
 		// GetBatchStatus holds details about calls to the GetBatchStatus method.
 		GetBatchStatus []struct {
 			// Ctx is the ctx argument value.
@@ -329,30 +744,79 @@ type QuerierMock struct {
 			// ID is the id argument value.
 			ID uuid.UUID
 		}
+		// GetBatchesToPurge holds details about calls to the GetBatchesToPurge method.
+		GetBatchesToPurge []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Arg is the arg argument value.
+			Arg batchsqlc.GetBatchesToPurgeParams
+		}
 		// GetCompletedBatches holds details about calls to the GetCompletedBatches method.
 		GetCompletedBatches []struct {
 			// Ctx is the ctx argument value.
 			Ctx context.Context
 		}
-		// GetPendingBatchRows holds details about calls to the GetPendingBatchRows method.
-		GetPendingBatchRows []struct {
+		// GetDeadWorkers holds details about calls to the GetDeadWorkers method.
+		GetDeadWorkers []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Cutoff is the cutoff argument value.
+			Cutoff pgtype.Timestamp
+		}
+		// GetIdempotencyKeyBatch holds details about calls to the GetIdempotencyKeyBatch method.
+		GetIdempotencyKeyBatch []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Arg is the arg argument value.
+			Arg batchsqlc.GetIdempotencyKeyBatchParams
+		}
+		// GetParentBatches holds details about calls to the GetParentBatches method.
+		GetParentBatches []struct {
 			// Ctx is the ctx argument value.
 			Ctx context.Context
 			// Batch is the batch argument value.
 			Batch uuid.UUID
 		}
-		// GetProcessedBatchRowsByBatchIDSorted holds details about calls to the GetProcessedBatchRowsByBatchIDSorted method.
-		GetProcessedBatchRowsByBatchIDSorted []struct {
+		// GetPendingBatchRows holds details about calls to the GetPendingBatchRows method.
+		GetPendingBatchRows []struct {
 			// Ctx is the ctx argument value.
 			Ctx context.Context
 			// Batch is the batch argument value.
 			Batch uuid.UUID
 		}
+		// GetProcessedBatchRowsPage holds details about calls to the GetProcessedBatchRowsPage method.
+		GetProcessedBatchRowsPage []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Arg is the arg argument value.
+			Arg batchsqlc.GetProcessedBatchRowsPageParams
+		}
 		// GetUnsummarizedBatches holds details about calls to the GetUnsummarizedBatches method.
 		GetUnsummarizedBatches []struct {
 			// Ctx is the ctx argument value.
 			Ctx context.Context
 		}
+		// GetWaitingDependentBatches holds details about calls to the GetWaitingDependentBatches method.
+		GetWaitingDependentBatches []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Parent is the parent argument value.
+			Parent uuid.UUID
+		}
+		// GetWebhookDeliveriesByBatchID holds details about calls to the GetWebhookDeliveriesByBatchID method.
+		GetWebhookDeliveriesByBatchID []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Batch is the batch argument value.
+			Batch uuid.UUID
+		}
+		// InsertBatchDependency holds details about calls to the InsertBatchDependency method.
+		InsertBatchDependency []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Arg is the arg argument value.
+			Arg batchsqlc.InsertBatchDependencyParams
+		}
 		// InsertBatchFile holds details about calls to the InsertBatchFile method.
 		InsertBatchFile []struct {
 			// Ctx is the ctx argument value.
@@ -374,162 +838,407 @@ type QuerierMock struct {
 			// Arg is the arg argument value.
 			Arg batchsqlc.InsertIntoBatchesParams
 		}
-		// ResetRowsToQueued holds details about calls to the ResetRowsToQueued method.
-		ResetRowsToQueued []struct {
+		// InsertWebhookDelivery holds details about calls to the InsertWebhookDelivery method.
+		InsertWebhookDelivery []struct {
 			// Ctx is the ctx argument value.
 			Ctx context.Context
-			// Dollar_1 is the dollar_1 argument value.
-			Dollar_1 []int64
+			// Arg is the arg argument value.
+			Arg batchsqlc.InsertWebhookDeliveryParams
 		}
-		// TryAdvisoryLockBatch holds details about calls to the TryAdvisoryLockBatch method.
-		TryAdvisoryLockBatch []struct {
+		// LeaseBatchRows holds details about calls to the LeaseBatchRows method.
+		LeaseBatchRows []struct {
 			// Ctx is the ctx argument value.
 			Ctx context.Context
-			// Dollar_1 is the dollar_1 argument value.
-			Dollar_1 string
+			// Arg is the arg argument value.
+			Arg batchsqlc.LeaseBatchRowsParams
 		}
-		// UpdateBatchCounters holds details about calls to the UpdateBatchCounters method.
-		UpdateBatchCounters []struct {
+		// ListBatches holds details about calls to the ListBatches method.
+		ListBatches []struct {
 			// Ctx is the ctx argument value.
 			Ctx context.Context
 			// Arg is the arg argument value.
-			Arg batchsqlc.UpdateBatchCountersParams
+			Arg batchsqlc.ListBatchesParams
 		}
-		// UpdateBatchOutputFiles holds details about calls to the UpdateBatchOutputFiles method.
-		UpdateBatchOutputFiles []struct {
+		// ListSlowQueries holds details about calls to the ListSlowQueries method.
+		ListSlowQueries []struct {
 			// Ctx is the ctx argument value.
 			Ctx context.Context
 			// Arg is the arg argument value.
-			Arg batchsqlc.UpdateBatchOutputFilesParams
+			Arg batchsqlc.ListSlowQueriesParams
 		}
-		// UpdateBatchResult holds details about calls to the UpdateBatchResult method.
-		UpdateBatchResult []struct {
+		// LockParentBatches holds details about calls to the LockParentBatches method.
+		LockParentBatches []struct {
 			// Ctx is the ctx argument value.
 			Ctx context.Context
-			// Arg is the arg argument value.
-			Arg batchsqlc.UpdateBatchResultParams
+			// Ids is the ids argument value.
+			Ids []uuid.UUID
 		}
-		// UpdateBatchRowStatus holds details about calls to the UpdateBatchRowStatus method.
-		UpdateBatchRowStatus []struct {
+		// MarkWebhookAttemptFailed holds details about calls to the MarkWebhookAttemptFailed method.
+		MarkWebhookAttemptFailed []struct {
 			// Ctx is the ctx argument value.
 			Ctx context.Context
 			// Arg is the arg argument value.
-			Arg batchsqlc.UpdateBatchRowStatusParams
+			Arg batchsqlc.MarkWebhookAttemptFailedParams
 		}
-		// UpdateBatchRowsBatchJob holds details about calls to the UpdateBatchRowsBatchJob method.
-		UpdateBatchRowsBatchJob []struct {
+		// MarkWebhookDelivered holds details about calls to the MarkWebhookDelivered method.
+		MarkWebhookDelivered []struct {
 			// Ctx is the ctx argument value.
 			Ctx context.Context
 			// Arg is the arg argument value.
-			Arg batchsqlc.UpdateBatchRowsBatchJobParams
+			Arg batchsqlc.MarkWebhookDeliveredParams
 		}
-		// UpdateBatchRowsByBatchAndStatus holds details about calls to the UpdateBatchRowsByBatchAndStatus method.
-		UpdateBatchRowsByBatchAndStatus []struct {
+		// NotifyJobsQueued holds details about calls to the NotifyJobsQueued method.
+		NotifyJobsQueued []struct {
 			// Ctx is the ctx argument value.
 			Ctx context.Context
-			// Arg is the arg argument value.
-			Arg batchsqlc.UpdateBatchRowsByBatchAndStatusParams
+			// App is the app argument value.
+			App string
 		}
-		// UpdateBatchRowsByBatchApp holds details about calls to the UpdateBatchRowsByBatchApp method.
-		UpdateBatchRowsByBatchApp []struct {
+		// PauseBatch holds details about calls to the PauseBatch method.
+		PauseBatch []struct {
 			// Ctx is the ctx argument value.
 			Ctx context.Context
-			// Arg is the arg argument value.
-			Arg batchsqlc.UpdateBatchRowsByBatchAppParams
+			// ID is the id argument value.
+			ID uuid.UUID
 		}
-		// UpdateBatchRowsByBatchAppOp holds details about calls to the UpdateBatchRowsByBatchAppOp method.
-		UpdateBatchRowsByBatchAppOp []struct {
+		// RecordWorkerHeartbeat holds details about calls to the RecordWorkerHeartbeat method.
+		RecordWorkerHeartbeat []struct {
 			// Ctx is the ctx argument value.
 			Ctx context.Context
 			// Arg is the arg argument value.
-			Arg batchsqlc.UpdateBatchRowsByBatchAppOpParams
+			Arg batchsqlc.RecordWorkerHeartbeatParams
 		}
-		// UpdateBatchRowsSlowQuery holds details about calls to the UpdateBatchRowsSlowQuery method.
-		UpdateBatchRowsSlowQuery []struct {
+		// ReleaseBatchRowLease holds details about calls to the ReleaseBatchRowLease method.
+		ReleaseBatchRowLease []struct {
 			// Ctx is the ctx argument value.
 			Ctx context.Context
 			// Arg is the arg argument value.
-			Arg batchsqlc.UpdateBatchRowsSlowQueryParams
+			Arg batchsqlc.ReleaseBatchRowLeaseParams
 		}
-		// UpdateBatchRowsStatus holds details about calls to the UpdateBatchRowsStatus method.
-		UpdateBatchRowsStatus []struct {
+		// ReleaseDependentBatch holds details about calls to the ReleaseDependentBatch method.
+		ReleaseDependentBatch []struct {
 			// Ctx is the ctx argument value.
 			Ctx context.Context
 			// Arg is the arg argument value.
-			Arg batchsqlc.UpdateBatchRowsStatusParams
+			Arg batchsqlc.ReleaseDependentBatchParams
 		}
-		// UpdateBatchRowsStatusBulk holds details about calls to the UpdateBatchRowsStatusBulk method.
-		UpdateBatchRowsStatusBulk []struct {
+		// RenewBatchRowLeases holds details about calls to the RenewBatchRowLeases method.
+		RenewBatchRowLeases []struct {
 			// Ctx is the ctx argument value.
 			Ctx context.Context
 			// Arg is the arg argument value.
-			Arg batchsqlc.UpdateBatchRowsStatusBulkParams
+			Arg batchsqlc.RenewBatchRowLeasesParams
 		}
-		// UpdateBatchStatus holds details about calls to the UpdateBatchStatus method.
-		UpdateBatchStatus []struct {
+		// ReopenBatch holds details about calls to the ReopenBatch method.
+		ReopenBatch []struct {
 			// Ctx is the ctx argument value.
 			Ctx context.Context
-			// Arg is the arg argument value.
-			Arg batchsqlc.UpdateBatchStatusParams
+			// ID is the id argument value.
+			ID uuid.UUID
 		}
-		// UpdateBatchSummary holds details about calls to the UpdateBatchSummary method.
-		UpdateBatchSummary []struct {
+		// RequeueBatchRowForRetry holds details about calls to the RequeueBatchRowForRetry method.
+		RequeueBatchRowForRetry []struct {
 			// Ctx is the ctx argument value.
 			Ctx context.Context
 			// Arg is the arg argument value.
-			Arg batchsqlc.UpdateBatchSummaryParams
+			Arg batchsqlc.RequeueBatchRowForRetryParams
 		}
-		// UpdateBatchSummaryOnAbort holds details about calls to the UpdateBatchSummaryOnAbort method.
-		UpdateBatchSummaryOnAbort []struct {
+		// RequeueBatchRowsForRerun holds details about calls to the RequeueBatchRowsForRerun method.
+		RequeueBatchRowsForRerun []struct {
 			// Ctx is the ctx argument value.
 			Ctx context.Context
-			// Arg is the arg argument value.
-			Arg batchsqlc.UpdateBatchSummaryOnAbortParams
+			// Rowids is the rowids argument value.
+			Rowids []int64
 		}
-		// UpdateBatchesStatusBulk holds details about calls to the UpdateBatchesStatusBulk method.
+		// ResetExpiredLeasesToQueued holds details about calls to the ResetExpiredLeasesToQueued method.
+		ResetExpiredLeasesToQueued []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Now is the now argument value.
+			Now pgtype.Timestamp
+		}
+		// ResetRowsToQueued holds details about calls to the ResetRowsToQueued method.
+		ResetRowsToQueued []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Dollar_1 is the dollar_1 argument value.
+			Dollar_1 []int64
+		}
+		// ResetWorkerRowsToQueued holds details about calls to the ResetWorkerRowsToQueued method.
+		ResetWorkerRowsToQueued []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Worker is the worker argument value.
+			Worker string
+		}
+		// ResumeBatch holds details about calls to the ResumeBatch method.
+		ResumeBatch []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID uuid.UUID
+		}
+		// SetBatchParent holds details about calls to the SetBatchParent method.
+		SetBatchParent []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Arg is the arg argument value.
+			Arg batchsqlc.SetBatchParentParams
+		}
+		// TryAdvisoryLockBatch holds details about calls to the TryAdvisoryLockBatch method.
+		TryAdvisoryLockBatch []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Dollar_1 is the dollar_1 argument value.
+			Dollar_1 string
+		}
+		// UpdateBatchCounters holds details about calls to the UpdateBatchCounters method.
+		UpdateBatchCounters []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Arg is the arg argument value.
+			Arg batchsqlc.UpdateBatchCountersParams
+		}
+		// UpdateBatchOutputFiles holds details about calls to the UpdateBatchOutputFiles method.
+		UpdateBatchOutputFiles []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Arg is the arg argument value.
+			Arg batchsqlc.UpdateBatchOutputFilesParams
+		}
+		// UpdateBatchResult holds details about calls to the UpdateBatchResult method.
+		UpdateBatchResult []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Arg is the arg argument value.
+			Arg batchsqlc.UpdateBatchResultParams
+		}
+		// UpdateBatchRowInput holds details about calls to the UpdateBatchRowInput method.
+		UpdateBatchRowInput []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Arg is the arg argument value.
+			Arg batchsqlc.UpdateBatchRowInputParams
+		}
+		// UpdateBatchRowStatus holds details about calls to the UpdateBatchRowStatus method.
+		UpdateBatchRowStatus []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Arg is the arg argument value.
+			Arg batchsqlc.UpdateBatchRowStatusParams
+		}
+		// UpdateBatchRowsBatchJob holds details about calls to the UpdateBatchRowsBatchJob method.
+		UpdateBatchRowsBatchJob []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Arg is the arg argument value.
+			Arg batchsqlc.UpdateBatchRowsBatchJobParams
+		}
+		// UpdateBatchRowsByBatchAndStatus holds details about calls to the UpdateBatchRowsByBatchAndStatus method.
+		UpdateBatchRowsByBatchAndStatus []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Arg is the arg argument value.
+			Arg batchsqlc.UpdateBatchRowsByBatchAndStatusParams
+		}
+		// UpdateBatchRowsByBatchApp holds details about calls to the UpdateBatchRowsByBatchApp method.
+		UpdateBatchRowsByBatchApp []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Arg is the arg argument value.
+			Arg batchsqlc.UpdateBatchRowsByBatchAppParams
+		}
+		// UpdateBatchRowsByBatchAppOp holds details about calls to the UpdateBatchRowsByBatchAppOp method.
+		UpdateBatchRowsByBatchAppOp []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Arg is the arg argument value.
+			Arg batchsqlc.UpdateBatchRowsByBatchAppOpParams
+		}
+		// UpdateBatchRowsSlowQuery holds details about calls to the UpdateBatchRowsSlowQuery method.
+		UpdateBatchRowsSlowQuery []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Arg is the arg argument value.
+			Arg batchsqlc.UpdateBatchRowsSlowQueryParams
+		}
+		// UpdateBatchRowsStatus holds details about calls to the UpdateBatchRowsStatus method.
+		UpdateBatchRowsStatus []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Arg is the arg argument value.
+			Arg batchsqlc.UpdateBatchRowsStatusParams
+		}
+		// UpdateBatchRowsStatusBulk holds details about calls to the UpdateBatchRowsStatusBulk method.
+		UpdateBatchRowsStatusBulk []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Arg is the arg argument value.
+			Arg batchsqlc.UpdateBatchRowsStatusBulkParams
+		}
+		// UpdateBatchScheduleFired holds details about calls to the UpdateBatchScheduleFired method.
+		UpdateBatchScheduleFired []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Arg is the arg argument value.
+			Arg batchsqlc.UpdateBatchScheduleFiredParams
+		}
+		// UpdateBatchStatus holds details about calls to the UpdateBatchStatus method.
+		UpdateBatchStatus []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Arg is the arg argument value.
+			Arg batchsqlc.UpdateBatchStatusParams
+		}
+		// UpdateBatchSummary holds details about calls to the UpdateBatchSummary method.
+		UpdateBatchSummary []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Arg is the arg argument value.
+			Arg batchsqlc.UpdateBatchSummaryParams
+		}
+		// UpdateBatchSummaryOnAbort holds details about calls to the UpdateBatchSummaryOnAbort method.
+		UpdateBatchSummaryOnAbort []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Arg is the arg argument value.
+			Arg batchsqlc.UpdateBatchSummaryOnAbortParams
+		}
+		// UpdateBatchesStatusBulk holds details about calls to the UpdateBatchesStatusBulk method.
 		UpdateBatchesStatusBulk []struct {
 			// Ctx is the ctx argument value.
 			Ctx context.Context
 			// Arg is the arg argument value.
 			Arg batchsqlc.UpdateBatchesStatusBulkParams
 		}
+		// UpsertBatchSchedule holds details about calls to the UpsertBatchSchedule method.
+		UpsertBatchSchedule []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Arg is the arg argument value.
+			Arg batchsqlc.UpsertBatchScheduleParams
+		}
+	}
+	lockArchiveBatchRows                 sync.RWMutex
+	lockBulkInsertIntoBatchRows          sync.RWMutex
+	lockClaimIdempotencyKey              sync.RWMutex
+	lockClaimWebhookDeliveries           sync.RWMutex
+	lockCopyBatchRows                    sync.RWMutex
+	lockCountBatchDependencies           sync.RWMutex
+	lockCountBatchRowsByBatchIDAndStatus sync.RWMutex
+	lockCountBatchRowsInProgByBatchID    sync.RWMutex
+	lockCountBatchRowsQueuedByBatchID    sync.RWMutex
+	lockCountBatchesToPurge              sync.RWMutex
+	lockDeleteBatchFilesByBatchIDs       sync.RWMutex
+	lockDeleteBatchRowsByBatchIDs        sync.RWMutex
+	lockDeleteBatchesByIDs               sync.RWMutex
+	lockDeleteWorker                     sync.RWMutex
+	lockDropBatchRowPartitions           sync.RWMutex
+	lockEnsureBatchRowPartitions         sync.RWMutex
+	lockFetchBatchRowsForBatchDone       sync.RWMutex
+	lockFetchBlockOfRows                 sync.RWMutex
+	lockFetchBlockOfRowsFair             sync.RWMutex
+	lockGetBatchByID                     sync.RWMutex
+	lockGetBatchProgress                 sync.RWMutex
+	lockGetBatchRowHistory               sync.RWMutex
+	lockGetBatchRowsByBatchID            sync.RWMutex
+	lockGetBatchRowsByBatchIDSorted      sync.RWMutex
+	lockGetBatchRowsCount                sync.RWMutex
+	lockGetBatchRowsForRerun             sync.RWMutex
+	lockGetBatchRowsPage                 sync.RWMutex
+	lockGetBatchScheduleForUpdate        sync.RWMutex
+	lockGetBatchStatus                   sync.RWMutex
+	lockGetBatchStatusAndOutputFiles     sync.RWMutex
+	lockGetBatchesToPurge                sync.RWMutex
+	lockGetCompletedBatches              sync.RWMutex
+	lockGetDeadWorkers                   sync.RWMutex
+	lockGetIdempotencyKeyBatch           sync.RWMutex
+	lockGetParentBatches                 sync.RWMutex
+	lockGetPendingBatchRows              sync.RWMutex
+	lockGetProcessedBatchRowsPage        sync.RWMutex
+	lockGetUnsummarizedBatches           sync.RWMutex
+	lockGetWaitingDependentBatches       sync.RWMutex
+	lockGetWebhookDeliveriesByBatchID    sync.RWMutex
+	lockInsertBatchDependency            sync.RWMutex
+	lockInsertBatchFile                  sync.RWMutex
+	lockInsertIntoBatchRows              sync.RWMutex
+	lockInsertIntoBatches                sync.RWMutex
+	lockInsertWebhookDelivery            sync.RWMutex
+	lockLeaseBatchRows                   sync.RWMutex
+	lockListBatches                      sync.RWMutex
+	lockListSlowQueries                  sync.RWMutex
+	lockLockParentBatches                sync.RWMutex
+	lockMarkWebhookAttemptFailed         sync.RWMutex
+	lockMarkWebhookDelivered             sync.RWMutex
+	lockNotifyJobsQueued                 sync.RWMutex
+	lockPauseBatch                       sync.RWMutex
+	lockRecordWorkerHeartbeat            sync.RWMutex
+	lockReleaseBatchRowLease             sync.RWMutex
+	lockReleaseDependentBatch            sync.RWMutex
+	lockRenewBatchRowLeases              sync.RWMutex
+	lockReopenBatch                      sync.RWMutex
+	lockRequeueBatchRowForRetry          sync.RWMutex
+	lockRequeueBatchRowsForRerun         sync.RWMutex
+	lockResetExpiredLeasesToQueued       sync.RWMutex
+	lockResetRowsToQueued                sync.RWMutex
+	lockResetWorkerRowsToQueued          sync.RWMutex
+	lockResumeBatch                      sync.RWMutex
+	lockSetBatchParent                   sync.RWMutex
+	lockTryAdvisoryLockBatch             sync.RWMutex
+	lockUpdateBatchCounters              sync.RWMutex
+	lockUpdateBatchOutputFiles           sync.RWMutex
+	lockUpdateBatchResult                sync.RWMutex
+	lockUpdateBatchRowInput              sync.RWMutex
+	lockUpdateBatchRowStatus             sync.RWMutex
+	lockUpdateBatchRowsBatchJob          sync.RWMutex
+	lockUpdateBatchRowsByBatchAndStatus  sync.RWMutex
+	lockUpdateBatchRowsByBatchApp        sync.RWMutex
+	lockUpdateBatchRowsByBatchAppOp      sync.RWMutex
+	lockUpdateBatchRowsSlowQuery         sync.RWMutex
+	lockUpdateBatchRowsStatus            sync.RWMutex
+	lockUpdateBatchRowsStatusBulk        sync.RWMutex
+	lockUpdateBatchScheduleFired         sync.RWMutex
+	lockUpdateBatchStatus                sync.RWMutex
+	lockUpdateBatchSummary               sync.RWMutex
+	lockUpdateBatchSummaryOnAbort        sync.RWMutex
+	lockUpdateBatchesStatusBulk          sync.RWMutex
+	lockUpsertBatchSchedule              sync.RWMutex
+}
+
+// ArchiveBatchRows calls ArchiveBatchRowsFunc.
+func (mock *QuerierMock) ArchiveBatchRows(ctx context.Context, rowids []int64) error {
+	if mock.ArchiveBatchRowsFunc == nil {
+		panic("QuerierMock.ArchiveBatchRowsFunc: method is nil but Querier.ArchiveBatchRows was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Rowids []int64
+	}{
+		Ctx:    ctx,
+		Rowids: rowids,
+	}
+	mock.lockArchiveBatchRows.Lock()
+	mock.calls.ArchiveBatchRows = append(mock.calls.ArchiveBatchRows, callInfo)
+	mock.lockArchiveBatchRows.Unlock()
+	return mock.ArchiveBatchRowsFunc(ctx, rowids)
+}
+
+// ArchiveBatchRowsCalls gets all the calls that were made to ArchiveBatchRows.
+// Check the length with:
+//
+//	len(mockedQuerier.ArchiveBatchRowsCalls())
+func (mock *QuerierMock) ArchiveBatchRowsCalls() []struct {
+	Ctx    context.Context
+	Rowids []int64
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Rowids []int64
 	}
-	lockBulkInsertIntoBatchRows              sync.RWMutex
-	lockCountBatchRowsByBatchIDAndStatus     sync.RWMutex
-	lockCountBatchRowsInProgByBatchID        sync.RWMutex
-	lockCountBatchRowsQueuedByBatchID        sync.RWMutex
-	lockFetchBatchRowsForBatchDone           sync.RWMutex
-	lockFetchBlockOfRows                     sync.RWMutex
-	lockGetBatchByID                         sync.RWMutex
-	lockGetBatchRowsByBatchID                sync.RWMutex
-	lockGetBatchRowsByBatchIDSorted          sync.RWMutex
-	lockGetBatchRowsCount                    sync.RWMutex
-	lockGetBatchStatus                       sync.RWMutex
-	lockGetBatchStatusAndOutputFiles         sync.RWMutex
-	lockGetCompletedBatches                  sync.RWMutex
-	lockGetPendingBatchRows                  sync.RWMutex
-	lockGetProcessedBatchRowsByBatchIDSorted sync.RWMutex
-	lockGetUnsummarizedBatches               sync.RWMutex
-	lockInsertBatchFile                      sync.RWMutex
-	lockInsertIntoBatchRows                  sync.RWMutex
-	lockInsertIntoBatches                    sync.RWMutex
-	lockResetRowsToQueued                    sync.RWMutex
-	lockTryAdvisoryLockBatch                 sync.RWMutex
-	lockUpdateBatchCounters                  sync.RWMutex
-	lockUpdateBatchOutputFiles               sync.RWMutex
-	lockUpdateBatchResult                    sync.RWMutex
-	lockUpdateBatchRowStatus                 sync.RWMutex
-	lockUpdateBatchRowsBatchJob              sync.RWMutex
-	lockUpdateBatchRowsByBatchAndStatus      sync.RWMutex
-	lockUpdateBatchRowsByBatchApp            sync.RWMutex
-	lockUpdateBatchRowsByBatchAppOp          sync.RWMutex
-	lockUpdateBatchRowsSlowQuery             sync.RWMutex
-	lockUpdateBatchRowsStatus                sync.RWMutex
-	lockUpdateBatchRowsStatusBulk            sync.RWMutex
-	lockUpdateBatchStatus                    sync.RWMutex
-	lockUpdateBatchSummary                   sync.RWMutex
-	lockUpdateBatchSummaryOnAbort            sync.RWMutex
-	lockUpdateBatchesStatusBulk              sync.RWMutex
+	mock.lockArchiveBatchRows.RLock()
+	calls = mock.calls.ArchiveBatchRows
+	mock.lockArchiveBatchRows.RUnlock()
+	return calls
 }
 
 // BulkInsertIntoBatchRows calls BulkInsertIntoBatchRowsFunc.
@@ -562,340 +1271,1808 @@ func (mock *QuerierMock) BulkInsertIntoBatchRowsCalls() []struct {
 		Ctx context.Context
 		Arg batchsqlc.BulkInsertIntoBatchRowsParams
 	}
-	mock.lockBulkInsertIntoBatchRows.RLock()
-	calls = mock.calls.BulkInsertIntoBatchRows
-	mock.lockBulkInsertIntoBatchRows.RUnlock()
+	mock.lockBulkInsertIntoBatchRows.RLock()
+	calls = mock.calls.BulkInsertIntoBatchRows
+	mock.lockBulkInsertIntoBatchRows.RUnlock()
+	return calls
+}
+
+// ClaimIdempotencyKey calls ClaimIdempotencyKeyFunc.
+func (mock *QuerierMock) ClaimIdempotencyKey(ctx context.Context, arg batchsqlc.ClaimIdempotencyKeyParams) (uuid.UUID, error) {
+	if mock.ClaimIdempotencyKeyFunc == nil {
+		panic("QuerierMock.ClaimIdempotencyKeyFunc: method is nil but Querier.ClaimIdempotencyKey was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Arg batchsqlc.ClaimIdempotencyKeyParams
+	}{
+		Ctx: ctx,
+		Arg: arg,
+	}
+	mock.lockClaimIdempotencyKey.Lock()
+	mock.calls.ClaimIdempotencyKey = append(mock.calls.ClaimIdempotencyKey, callInfo)
+	mock.lockClaimIdempotencyKey.Unlock()
+	return mock.ClaimIdempotencyKeyFunc(ctx, arg)
+}
+
+// ClaimIdempotencyKeyCalls gets all the calls that were made to ClaimIdempotencyKey.
+// Check the length with:
+//
+//	len(mockedQuerier.ClaimIdempotencyKeyCalls())
+func (mock *QuerierMock) ClaimIdempotencyKeyCalls() []struct {
+	Ctx context.Context
+	Arg batchsqlc.ClaimIdempotencyKeyParams
+} {
+	var calls []struct {
+		Ctx context.Context
+		Arg batchsqlc.ClaimIdempotencyKeyParams
+	}
+	mock.lockClaimIdempotencyKey.RLock()
+	calls = mock.calls.ClaimIdempotencyKey
+	mock.lockClaimIdempotencyKey.RUnlock()
+	return calls
+}
+
+// ClaimWebhookDeliveries calls ClaimWebhookDeliveriesFunc.
+func (mock *QuerierMock) ClaimWebhookDeliveries(ctx context.Context, arg batchsqlc.ClaimWebhookDeliveriesParams) ([]batchsqlc.ClaimWebhookDeliveriesRow, error) {
+	if mock.ClaimWebhookDeliveriesFunc == nil {
+		panic("QuerierMock.ClaimWebhookDeliveriesFunc: method is nil but Querier.ClaimWebhookDeliveries was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Arg batchsqlc.ClaimWebhookDeliveriesParams
+	}{
+		Ctx: ctx,
+		Arg: arg,
+	}
+	mock.lockClaimWebhookDeliveries.Lock()
+	mock.calls.ClaimWebhookDeliveries = append(mock.calls.ClaimWebhookDeliveries, callInfo)
+	mock.lockClaimWebhookDeliveries.Unlock()
+	return mock.ClaimWebhookDeliveriesFunc(ctx, arg)
+}
+
+// ClaimWebhookDeliveriesCalls gets all the calls that were made to ClaimWebhookDeliveries.
+// Check the length with:
+//
+//	len(mockedQuerier.ClaimWebhookDeliveriesCalls())
+func (mock *QuerierMock) ClaimWebhookDeliveriesCalls() []struct {
+	Ctx context.Context
+	Arg batchsqlc.ClaimWebhookDeliveriesParams
+} {
+	var calls []struct {
+		Ctx context.Context
+		Arg batchsqlc.ClaimWebhookDeliveriesParams
+	}
+	mock.lockClaimWebhookDeliveries.RLock()
+	calls = mock.calls.ClaimWebhookDeliveries
+	mock.lockClaimWebhookDeliveries.RUnlock()
+	return calls
+}
+
+// CopyBatchRows calls CopyBatchRowsFunc.
+func (mock *QuerierMock) CopyBatchRows(ctx context.Context, arg []batchsqlc.CopyBatchRowsParams) (int64, error) {
+	if mock.CopyBatchRowsFunc == nil {
+		panic("QuerierMock.CopyBatchRowsFunc: method is nil but Querier.CopyBatchRows was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Arg []batchsqlc.CopyBatchRowsParams
+	}{
+		Ctx: ctx,
+		Arg: arg,
+	}
+	mock.lockCopyBatchRows.Lock()
+	mock.calls.CopyBatchRows = append(mock.calls.CopyBatchRows, callInfo)
+	mock.lockCopyBatchRows.Unlock()
+	return mock.CopyBatchRowsFunc(ctx, arg)
+}
+
+// CopyBatchRowsCalls gets all the calls that were made to CopyBatchRows.
+// Check the length with:
+//
+//	len(mockedQuerier.CopyBatchRowsCalls())
+func (mock *QuerierMock) CopyBatchRowsCalls() []struct {
+	Ctx context.Context
+	Arg []batchsqlc.CopyBatchRowsParams
+} {
+	var calls []struct {
+		Ctx context.Context
+		Arg []batchsqlc.CopyBatchRowsParams
+	}
+	mock.lockCopyBatchRows.RLock()
+	calls = mock.calls.CopyBatchRows
+	mock.lockCopyBatchRows.RUnlock()
+	return calls
+}
+
+// CountBatchDependencies calls CountBatchDependenciesFunc.
+func (mock *QuerierMock) CountBatchDependencies(ctx context.Context, batch uuid.UUID) (int64, error) {
+	if mock.CountBatchDependenciesFunc == nil {
+		panic("QuerierMock.CountBatchDependenciesFunc: method is nil but Querier.CountBatchDependencies was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		Batch uuid.UUID
+	}{
+		Ctx:   ctx,
+		Batch: batch,
+	}
+	mock.lockCountBatchDependencies.Lock()
+	mock.calls.CountBatchDependencies = append(mock.calls.CountBatchDependencies, callInfo)
+	mock.lockCountBatchDependencies.Unlock()
+	return mock.CountBatchDependenciesFunc(ctx, batch)
+}
+
+// CountBatchDependenciesCalls gets all the calls that were made to CountBatchDependencies.
+// Check the length with:
+//
+//	len(mockedQuerier.CountBatchDependenciesCalls())
+func (mock *QuerierMock) CountBatchDependenciesCalls() []struct {
+	Ctx   context.Context
+	Batch uuid.UUID
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Batch uuid.UUID
+	}
+	mock.lockCountBatchDependencies.RLock()
+	calls = mock.calls.CountBatchDependencies
+	mock.lockCountBatchDependencies.RUnlock()
+	return calls
+}
+
+// CountBatchRowsByBatchIDAndStatus calls CountBatchRowsByBatchIDAndStatusFunc.
+func (mock *QuerierMock) CountBatchRowsByBatchIDAndStatus(ctx context.Context, arg batchsqlc.CountBatchRowsByBatchIDAndStatusParams) (int64, error) {
+	if mock.CountBatchRowsByBatchIDAndStatusFunc == nil {
+		panic("QuerierMock.CountBatchRowsByBatchIDAndStatusFunc: method is nil but Querier.CountBatchRowsByBatchIDAndStatus was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Arg batchsqlc.CountBatchRowsByBatchIDAndStatusParams
+	}{
+		Ctx: ctx,
+		Arg: arg,
+	}
+	mock.lockCountBatchRowsByBatchIDAndStatus.Lock()
+	mock.calls.CountBatchRowsByBatchIDAndStatus = append(mock.calls.CountBatchRowsByBatchIDAndStatus, callInfo)
+	mock.lockCountBatchRowsByBatchIDAndStatus.Unlock()
+	return mock.CountBatchRowsByBatchIDAndStatusFunc(ctx, arg)
+}
+
+// CountBatchRowsByBatchIDAndStatusCalls gets all the calls that were made to CountBatchRowsByBatchIDAndStatus.
+// Check the length with:
+//
+//	len(mockedQuerier.CountBatchRowsByBatchIDAndStatusCalls())
+func (mock *QuerierMock) CountBatchRowsByBatchIDAndStatusCalls() []struct {
+	Ctx context.Context
+	Arg batchsqlc.CountBatchRowsByBatchIDAndStatusParams
+} {
+	var calls []struct {
+		Ctx context.Context
+		Arg batchsqlc.CountBatchRowsByBatchIDAndStatusParams
+	}
+	mock.lockCountBatchRowsByBatchIDAndStatus.RLock()
+	calls = mock.calls.CountBatchRowsByBatchIDAndStatus
+	mock.lockCountBatchRowsByBatchIDAndStatus.RUnlock()
+	return calls
+}
+
+// CountBatchRowsInProgByBatchID calls CountBatchRowsInProgByBatchIDFunc.
+func (mock *QuerierMock) CountBatchRowsInProgByBatchID(ctx context.Context, batch uuid.UUID) (int64, error) {
+	if mock.CountBatchRowsInProgByBatchIDFunc == nil {
+		panic("QuerierMock.CountBatchRowsInProgByBatchIDFunc: method is nil but Querier.CountBatchRowsInProgByBatchID was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		Batch uuid.UUID
+	}{
+		Ctx:   ctx,
+		Batch: batch,
+	}
+	mock.lockCountBatchRowsInProgByBatchID.Lock()
+	mock.calls.CountBatchRowsInProgByBatchID = append(mock.calls.CountBatchRowsInProgByBatchID, callInfo)
+	mock.lockCountBatchRowsInProgByBatchID.Unlock()
+	return mock.CountBatchRowsInProgByBatchIDFunc(ctx, batch)
+}
+
+// CountBatchRowsInProgByBatchIDCalls gets all the calls that were made to CountBatchRowsInProgByBatchID.
+// Check the length with:
+//
+//	len(mockedQuerier.CountBatchRowsInProgByBatchIDCalls())
+func (mock *QuerierMock) CountBatchRowsInProgByBatchIDCalls() []struct {
+	Ctx   context.Context
+	Batch uuid.UUID
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Batch uuid.UUID
+	}
+	mock.lockCountBatchRowsInProgByBatchID.RLock()
+	calls = mock.calls.CountBatchRowsInProgByBatchID
+	mock.lockCountBatchRowsInProgByBatchID.RUnlock()
+	return calls
+}
+
+// CountBatchRowsQueuedByBatchID calls CountBatchRowsQueuedByBatchIDFunc.
+func (mock *QuerierMock) CountBatchRowsQueuedByBatchID(ctx context.Context, batch uuid.UUID) (int64, error) {
+	if mock.CountBatchRowsQueuedByBatchIDFunc == nil {
+		panic("QuerierMock.CountBatchRowsQueuedByBatchIDFunc: method is nil but Querier.CountBatchRowsQueuedByBatchID was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		Batch uuid.UUID
+	}{
+		Ctx:   ctx,
+		Batch: batch,
+	}
+	mock.lockCountBatchRowsQueuedByBatchID.Lock()
+	mock.calls.CountBatchRowsQueuedByBatchID = append(mock.calls.CountBatchRowsQueuedByBatchID, callInfo)
+	mock.lockCountBatchRowsQueuedByBatchID.Unlock()
+	return mock.CountBatchRowsQueuedByBatchIDFunc(ctx, batch)
+}
+
+// CountBatchRowsQueuedByBatchIDCalls gets all the calls that were made to CountBatchRowsQueuedByBatchID.
+// Check the length with:
+//
+//	len(mockedQuerier.CountBatchRowsQueuedByBatchIDCalls())
+func (mock *QuerierMock) CountBatchRowsQueuedByBatchIDCalls() []struct {
+	Ctx   context.Context
+	Batch uuid.UUID
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Batch uuid.UUID
+	}
+	mock.lockCountBatchRowsQueuedByBatchID.RLock()
+	calls = mock.calls.CountBatchRowsQueuedByBatchID
+	mock.lockCountBatchRowsQueuedByBatchID.RUnlock()
+	return calls
+}
+
+// CountBatchesToPurge calls CountBatchesToPurgeFunc.
+func (mock *QuerierMock) CountBatchesToPurge(ctx context.Context, arg batchsqlc.CountBatchesToPurgeParams) (batchsqlc.CountBatchesToPurgeRow, error) {
+	if mock.CountBatchesToPurgeFunc == nil {
+		panic("QuerierMock.CountBatchesToPurgeFunc: method is nil but Querier.CountBatchesToPurge was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Arg batchsqlc.CountBatchesToPurgeParams
+	}{
+		Ctx: ctx,
+		Arg: arg,
+	}
+	mock.lockCountBatchesToPurge.Lock()
+	mock.calls.CountBatchesToPurge = append(mock.calls.CountBatchesToPurge, callInfo)
+	mock.lockCountBatchesToPurge.Unlock()
+	return mock.CountBatchesToPurgeFunc(ctx, arg)
+}
+
+// CountBatchesToPurgeCalls gets all the calls that were made to CountBatchesToPurge.
+// Check the length with:
+//
+//	len(mockedQuerier.CountBatchesToPurgeCalls())
+func (mock *QuerierMock) CountBatchesToPurgeCalls() []struct {
+	Ctx context.Context
+	Arg batchsqlc.CountBatchesToPurgeParams
+} {
+	var calls []struct {
+		Ctx context.Context
+		Arg batchsqlc.CountBatchesToPurgeParams
+	}
+	mock.lockCountBatchesToPurge.RLock()
+	calls = mock.calls.CountBatchesToPurge
+	mock.lockCountBatchesToPurge.RUnlock()
+	return calls
+}
+
+// DeleteBatchFilesByBatchIDs calls DeleteBatchFilesByBatchIDsFunc.
+func (mock *QuerierMock) DeleteBatchFilesByBatchIDs(ctx context.Context, batchIds []uuid.UUID) error {
+	if mock.DeleteBatchFilesByBatchIDsFunc == nil {
+		panic("QuerierMock.DeleteBatchFilesByBatchIDsFunc: method is nil but Querier.DeleteBatchFilesByBatchIDs was just called")
+	}
+	callInfo := struct {
+		Ctx      context.Context
+		BatchIds []uuid.UUID
+	}{
+		Ctx:      ctx,
+		BatchIds: batchIds,
+	}
+	mock.lockDeleteBatchFilesByBatchIDs.Lock()
+	mock.calls.DeleteBatchFilesByBatchIDs = append(mock.calls.DeleteBatchFilesByBatchIDs, callInfo)
+	mock.lockDeleteBatchFilesByBatchIDs.Unlock()
+	return mock.DeleteBatchFilesByBatchIDsFunc(ctx, batchIds)
+}
+
+// DeleteBatchFilesByBatchIDsCalls gets all the calls that were made to DeleteBatchFilesByBatchIDs.
+// Check the length with:
+//
+//	len(mockedQuerier.DeleteBatchFilesByBatchIDsCalls())
+func (mock *QuerierMock) DeleteBatchFilesByBatchIDsCalls() []struct {
+	Ctx      context.Context
+	BatchIds []uuid.UUID
+} {
+	var calls []struct {
+		Ctx      context.Context
+		BatchIds []uuid.UUID
+	}
+	mock.lockDeleteBatchFilesByBatchIDs.RLock()
+	calls = mock.calls.DeleteBatchFilesByBatchIDs
+	mock.lockDeleteBatchFilesByBatchIDs.RUnlock()
+	return calls
+}
+
+// DeleteBatchRowsByBatchIDs calls DeleteBatchRowsByBatchIDsFunc.
+func (mock *QuerierMock) DeleteBatchRowsByBatchIDs(ctx context.Context, batchIds []uuid.UUID) error {
+	if mock.DeleteBatchRowsByBatchIDsFunc == nil {
+		panic("QuerierMock.DeleteBatchRowsByBatchIDsFunc: method is nil but Querier.DeleteBatchRowsByBatchIDs was just called")
+	}
+	callInfo := struct {
+		Ctx      context.Context
+		BatchIds []uuid.UUID
+	}{
+		Ctx:      ctx,
+		BatchIds: batchIds,
+	}
+	mock.lockDeleteBatchRowsByBatchIDs.Lock()
+	mock.calls.DeleteBatchRowsByBatchIDs = append(mock.calls.DeleteBatchRowsByBatchIDs, callInfo)
+	mock.lockDeleteBatchRowsByBatchIDs.Unlock()
+	return mock.DeleteBatchRowsByBatchIDsFunc(ctx, batchIds)
+}
+
+// DeleteBatchRowsByBatchIDsCalls gets all the calls that were made to DeleteBatchRowsByBatchIDs.
+// Check the length with:
+//
+//	len(mockedQuerier.DeleteBatchRowsByBatchIDsCalls())
+func (mock *QuerierMock) DeleteBatchRowsByBatchIDsCalls() []struct {
+	Ctx      context.Context
+	BatchIds []uuid.UUID
+} {
+	var calls []struct {
+		Ctx      context.Context
+		BatchIds []uuid.UUID
+	}
+	mock.lockDeleteBatchRowsByBatchIDs.RLock()
+	calls = mock.calls.DeleteBatchRowsByBatchIDs
+	mock.lockDeleteBatchRowsByBatchIDs.RUnlock()
+	return calls
+}
+
+// DeleteBatchesByIDs calls DeleteBatchesByIDsFunc.
+func (mock *QuerierMock) DeleteBatchesByIDs(ctx context.Context, ids []uuid.UUID) (int64, error) {
+	if mock.DeleteBatchesByIDsFunc == nil {
+		panic("QuerierMock.DeleteBatchesByIDsFunc: method is nil but Querier.DeleteBatchesByIDs was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Ids []uuid.UUID
+	}{
+		Ctx: ctx,
+		Ids: ids,
+	}
+	mock.lockDeleteBatchesByIDs.Lock()
+	mock.calls.DeleteBatchesByIDs = append(mock.calls.DeleteBatchesByIDs, callInfo)
+	mock.lockDeleteBatchesByIDs.Unlock()
+	return mock.DeleteBatchesByIDsFunc(ctx, ids)
+}
+
+// DeleteBatchesByIDsCalls gets all the calls that were made to DeleteBatchesByIDs.
+// Check the length with:
+//
+//	len(mockedQuerier.DeleteBatchesByIDsCalls())
+func (mock *QuerierMock) DeleteBatchesByIDsCalls() []struct {
+	Ctx context.Context
+	Ids []uuid.UUID
+} {
+	var calls []struct {
+		Ctx context.Context
+		Ids []uuid.UUID
+	}
+	mock.lockDeleteBatchesByIDs.RLock()
+	calls = mock.calls.DeleteBatchesByIDs
+	mock.lockDeleteBatchesByIDs.RUnlock()
+	return calls
+}
+
+// DeleteWorker calls DeleteWorkerFunc.
+func (mock *QuerierMock) DeleteWorker(ctx context.Context, instanceID string) error {
+	if mock.DeleteWorkerFunc == nil {
+		panic("QuerierMock.DeleteWorkerFunc: method is nil but Querier.DeleteWorker was just called")
+	}
+	callInfo := struct {
+		Ctx        context.Context
+		InstanceID string
+	}{
+		Ctx:        ctx,
+		InstanceID: instanceID,
+	}
+	mock.lockDeleteWorker.Lock()
+	mock.calls.DeleteWorker = append(mock.calls.DeleteWorker, callInfo)
+	mock.lockDeleteWorker.Unlock()
+	return mock.DeleteWorkerFunc(ctx, instanceID)
+}
+
+// DeleteWorkerCalls gets all the calls that were made to DeleteWorker.
+// Check the length with:
+//
+//	len(mockedQuerier.DeleteWorkerCalls())
+func (mock *QuerierMock) DeleteWorkerCalls() []struct {
+	Ctx        context.Context
+	InstanceID string
+} {
+	var calls []struct {
+		Ctx        context.Context
+		InstanceID string
+	}
+	mock.lockDeleteWorker.RLock()
+	calls = mock.calls.DeleteWorker
+	mock.lockDeleteWorker.RUnlock()
+	return calls
+}
+
+// DropBatchRowPartitions calls DropBatchRowPartitionsFunc.
+func (mock *QuerierMock) DropBatchRowPartitions(ctx context.Context, cutoff pgtype.Timestamp) ([]string, error) {
+	if mock.DropBatchRowPartitionsFunc == nil {
+		panic("QuerierMock.DropBatchRowPartitionsFunc: method is nil but Querier.DropBatchRowPartitions was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Cutoff pgtype.Timestamp
+	}{
+		Ctx:    ctx,
+		Cutoff: cutoff,
+	}
+	mock.lockDropBatchRowPartitions.Lock()
+	mock.calls.DropBatchRowPartitions = append(mock.calls.DropBatchRowPartitions, callInfo)
+	mock.lockDropBatchRowPartitions.Unlock()
+	return mock.DropBatchRowPartitionsFunc(ctx, cutoff)
+}
+
+// DropBatchRowPartitionsCalls gets all the calls that were made to DropBatchRowPartitions.
+// Check the length with:
+//
+//	len(mockedQuerier.DropBatchRowPartitionsCalls())
+func (mock *QuerierMock) DropBatchRowPartitionsCalls() []struct {
+	Ctx    context.Context
+	Cutoff pgtype.Timestamp
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Cutoff pgtype.Timestamp
+	}
+	mock.lockDropBatchRowPartitions.RLock()
+	calls = mock.calls.DropBatchRowPartitions
+	mock.lockDropBatchRowPartitions.RUnlock()
+	return calls
+}
+
+// EnsureBatchRowPartitions calls EnsureBatchRowPartitionsFunc.
+func (mock *QuerierMock) EnsureBatchRowPartitions(ctx context.Context, monthsAhead int32) ([]string, error) {
+	if mock.EnsureBatchRowPartitionsFunc == nil {
+		panic("QuerierMock.EnsureBatchRowPartitionsFunc: method is nil but Querier.EnsureBatchRowPartitions was just called")
+	}
+	callInfo := struct {
+		Ctx         context.Context
+		MonthsAhead int32
+	}{
+		Ctx:         ctx,
+		MonthsAhead: monthsAhead,
+	}
+	mock.lockEnsureBatchRowPartitions.Lock()
+	mock.calls.EnsureBatchRowPartitions = append(mock.calls.EnsureBatchRowPartitions, callInfo)
+	mock.lockEnsureBatchRowPartitions.Unlock()
+	return mock.EnsureBatchRowPartitionsFunc(ctx, monthsAhead)
+}
+
+// EnsureBatchRowPartitionsCalls gets all the calls that were made to EnsureBatchRowPartitions.
+// Check the length with:
+//
+//	len(mockedQuerier.EnsureBatchRowPartitionsCalls())
+func (mock *QuerierMock) EnsureBatchRowPartitionsCalls() []struct {
+	Ctx         context.Context
+	MonthsAhead int32
+} {
+	var calls []struct {
+		Ctx         context.Context
+		MonthsAhead int32
+	}
+	mock.lockEnsureBatchRowPartitions.RLock()
+	calls = mock.calls.EnsureBatchRowPartitions
+	mock.lockEnsureBatchRowPartitions.RUnlock()
+	return calls
+}
+
+// FetchBatchRowsForBatchDone calls FetchBatchRowsForBatchDoneFunc.
+func (mock *QuerierMock) FetchBatchRowsForBatchDone(ctx context.Context, batch uuid.UUID) ([]batchsqlc.FetchBatchRowsForBatchDoneRow, error) {
+	if mock.FetchBatchRowsForBatchDoneFunc == nil {
+		panic("QuerierMock.FetchBatchRowsForBatchDoneFunc: method is nil but Querier.FetchBatchRowsForBatchDone was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		Batch uuid.UUID
+	}{
+		Ctx:   ctx,
+		Batch: batch,
+	}
+	mock.lockFetchBatchRowsForBatchDone.Lock()
+	mock.calls.FetchBatchRowsForBatchDone = append(mock.calls.FetchBatchRowsForBatchDone, callInfo)
+	mock.lockFetchBatchRowsForBatchDone.Unlock()
+	return mock.FetchBatchRowsForBatchDoneFunc(ctx, batch)
+}
+
+// FetchBatchRowsForBatchDoneCalls gets all the calls that were made to FetchBatchRowsForBatchDone.
+// Check the length with:
+//
+//	len(mockedQuerier.FetchBatchRowsForBatchDoneCalls())
+func (mock *QuerierMock) FetchBatchRowsForBatchDoneCalls() []struct {
+	Ctx   context.Context
+	Batch uuid.UUID
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Batch uuid.UUID
+	}
+	mock.lockFetchBatchRowsForBatchDone.RLock()
+	calls = mock.calls.FetchBatchRowsForBatchDone
+	mock.lockFetchBatchRowsForBatchDone.RUnlock()
+	return calls
+}
+
+// FetchBlockOfRows calls FetchBlockOfRowsFunc.
+func (mock *QuerierMock) FetchBlockOfRows(ctx context.Context, arg batchsqlc.FetchBlockOfRowsParams) ([]batchsqlc.FetchBlockOfRowsRow, error) {
+	if mock.FetchBlockOfRowsFunc == nil {
+		panic("QuerierMock.FetchBlockOfRowsFunc: method is nil but Querier.FetchBlockOfRows was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Arg batchsqlc.FetchBlockOfRowsParams
+	}{
+		Ctx: ctx,
+		Arg: arg,
+	}
+	mock.lockFetchBlockOfRows.Lock()
+	mock.calls.FetchBlockOfRows = append(mock.calls.FetchBlockOfRows, callInfo)
+	mock.lockFetchBlockOfRows.Unlock()
+	return mock.FetchBlockOfRowsFunc(ctx, arg)
+}
+
+// FetchBlockOfRowsCalls gets all the calls that were made to FetchBlockOfRows.
+// Check the length with:
+//
+//	len(mockedQuerier.FetchBlockOfRowsCalls())
+func (mock *QuerierMock) FetchBlockOfRowsCalls() []struct {
+	Ctx context.Context
+	Arg batchsqlc.FetchBlockOfRowsParams
+} {
+	var calls []struct {
+		Ctx context.Context
+		Arg batchsqlc.FetchBlockOfRowsParams
+	}
+	mock.lockFetchBlockOfRows.RLock()
+	calls = mock.calls.FetchBlockOfRows
+	mock.lockFetchBlockOfRows.RUnlock()
+	return calls
+}
+
+// FetchBlockOfRowsFair calls FetchBlockOfRowsFairFunc.
+func (mock *QuerierMock) FetchBlockOfRowsFair(ctx context.Context, arg batchsqlc.FetchBlockOfRowsFairParams) ([]batchsqlc.FetchBlockOfRowsFairRow, error) {
+	if mock.FetchBlockOfRowsFairFunc == nil {
+		panic("QuerierMock.FetchBlockOfRowsFairFunc: method is nil but Querier.FetchBlockOfRowsFair was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Arg batchsqlc.FetchBlockOfRowsFairParams
+	}{
+		Ctx: ctx,
+		Arg: arg,
+	}
+	mock.lockFetchBlockOfRowsFair.Lock()
+	mock.calls.FetchBlockOfRowsFair = append(mock.calls.FetchBlockOfRowsFair, callInfo)
+	mock.lockFetchBlockOfRowsFair.Unlock()
+	return mock.FetchBlockOfRowsFairFunc(ctx, arg)
+}
+
+// FetchBlockOfRowsFairCalls gets all the calls that were made to FetchBlockOfRowsFair.
+// Check the length with:
+//
+//	len(mockedQuerier.FetchBlockOfRowsFairCalls())
+func (mock *QuerierMock) FetchBlockOfRowsFairCalls() []struct {
+	Ctx context.Context
+	Arg batchsqlc.FetchBlockOfRowsFairParams
+} {
+	var calls []struct {
+		Ctx context.Context
+		Arg batchsqlc.FetchBlockOfRowsFairParams
+	}
+	mock.lockFetchBlockOfRowsFair.RLock()
+	calls = mock.calls.FetchBlockOfRowsFair
+	mock.lockFetchBlockOfRowsFair.RUnlock()
+	return calls
+}
+
+// GetBatchByID calls GetBatchByIDFunc.
+func (mock *QuerierMock) GetBatchByID(ctx context.Context, id uuid.UUID) (batchsqlc.Batch, error) {
+	if mock.GetBatchByIDFunc == nil {
+		panic("QuerierMock.GetBatchByIDFunc: method is nil but Querier.GetBatchByID was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		ID  uuid.UUID
+	}{
+		Ctx: ctx,
+		ID:  id,
+	}
+	mock.lockGetBatchByID.Lock()
+	mock.calls.GetBatchByID = append(mock.calls.GetBatchByID, callInfo)
+	mock.lockGetBatchByID.Unlock()
+	return mock.GetBatchByIDFunc(ctx, id)
+}
+
+// GetBatchByIDCalls gets all the calls that were made to GetBatchByID.
+// Check the length with:
+//
+//	len(mockedQuerier.GetBatchByIDCalls())
+func (mock *QuerierMock) GetBatchByIDCalls() []struct {
+	Ctx context.Context
+	ID  uuid.UUID
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  uuid.UUID
+	}
+	mock.lockGetBatchByID.RLock()
+	calls = mock.calls.GetBatchByID
+	mock.lockGetBatchByID.RUnlock()
+	return calls
+}
+
+// GetBatchProgress calls GetBatchProgressFunc.
+func (mock *QuerierMock) GetBatchProgress(ctx context.Context, batch uuid.UUID) (batchsqlc.GetBatchProgressRow, error) {
+	if mock.GetBatchProgressFunc == nil {
+		panic("QuerierMock.GetBatchProgressFunc: method is nil but Querier.GetBatchProgress was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		Batch uuid.UUID
+	}{
+		Ctx:   ctx,
+		Batch: batch,
+	}
+	mock.lockGetBatchProgress.Lock()
+	mock.calls.GetBatchProgress = append(mock.calls.GetBatchProgress, callInfo)
+	mock.lockGetBatchProgress.Unlock()
+	return mock.GetBatchProgressFunc(ctx, batch)
+}
+
+// GetBatchProgressCalls gets all the calls that were made to GetBatchProgress.
+// Check the length with:
+//
+//	len(mockedQuerier.GetBatchProgressCalls())
+func (mock *QuerierMock) GetBatchProgressCalls() []struct {
+	Ctx   context.Context
+	Batch uuid.UUID
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Batch uuid.UUID
+	}
+	mock.lockGetBatchProgress.RLock()
+	calls = mock.calls.GetBatchProgress
+	mock.lockGetBatchProgress.RUnlock()
+	return calls
+}
+
+// GetBatchRowHistory calls GetBatchRowHistoryFunc.
+func (mock *QuerierMock) GetBatchRowHistory(ctx context.Context, batch uuid.UUID) ([]batchsqlc.BatchrowHistory, error) {
+	if mock.GetBatchRowHistoryFunc == nil {
+		panic("QuerierMock.GetBatchRowHistoryFunc: method is nil but Querier.GetBatchRowHistory was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		Batch uuid.UUID
+	}{
+		Ctx:   ctx,
+		Batch: batch,
+	}
+	mock.lockGetBatchRowHistory.Lock()
+	mock.calls.GetBatchRowHistory = append(mock.calls.GetBatchRowHistory, callInfo)
+	mock.lockGetBatchRowHistory.Unlock()
+	return mock.GetBatchRowHistoryFunc(ctx, batch)
+}
+
+// GetBatchRowHistoryCalls gets all the calls that were made to GetBatchRowHistory.
+// Check the length with:
+//
+//	len(mockedQuerier.GetBatchRowHistoryCalls())
+func (mock *QuerierMock) GetBatchRowHistoryCalls() []struct {
+	Ctx   context.Context
+	Batch uuid.UUID
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Batch uuid.UUID
+	}
+	mock.lockGetBatchRowHistory.RLock()
+	calls = mock.calls.GetBatchRowHistory
+	mock.lockGetBatchRowHistory.RUnlock()
+	return calls
+}
+
+// GetBatchRowsByBatchID calls GetBatchRowsByBatchIDFunc.
+func (mock *QuerierMock) GetBatchRowsByBatchID(ctx context.Context, batch uuid.UUID) ([]batchsqlc.Batchrow, error) {
+	if mock.GetBatchRowsByBatchIDFunc == nil {
+		panic("QuerierMock.GetBatchRowsByBatchIDFunc: method is nil but Querier.GetBatchRowsByBatchID was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		Batch uuid.UUID
+	}{
+		Ctx:   ctx,
+		Batch: batch,
+	}
+	mock.lockGetBatchRowsByBatchID.Lock()
+	mock.calls.GetBatchRowsByBatchID = append(mock.calls.GetBatchRowsByBatchID, callInfo)
+	mock.lockGetBatchRowsByBatchID.Unlock()
+	return mock.GetBatchRowsByBatchIDFunc(ctx, batch)
+}
+
+// GetBatchRowsByBatchIDCalls gets all the calls that were made to GetBatchRowsByBatchID.
+// Check the length with:
+//
+//	len(mockedQuerier.GetBatchRowsByBatchIDCalls())
+func (mock *QuerierMock) GetBatchRowsByBatchIDCalls() []struct {
+	Ctx   context.Context
+	Batch uuid.UUID
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Batch uuid.UUID
+	}
+	mock.lockGetBatchRowsByBatchID.RLock()
+	calls = mock.calls.GetBatchRowsByBatchID
+	mock.lockGetBatchRowsByBatchID.RUnlock()
+	return calls
+}
+
+// GetBatchRowsByBatchIDSorted calls GetBatchRowsByBatchIDSortedFunc.
+func (mock *QuerierMock) GetBatchRowsByBatchIDSorted(ctx context.Context, batch uuid.UUID) ([]batchsqlc.GetBatchRowsByBatchIDSortedRow, error) {
+	if mock.GetBatchRowsByBatchIDSortedFunc == nil {
+		panic("QuerierMock.GetBatchRowsByBatchIDSortedFunc: method is nil but Querier.GetBatchRowsByBatchIDSorted was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		Batch uuid.UUID
+	}{
+		Ctx:   ctx,
+		Batch: batch,
+	}
+	mock.lockGetBatchRowsByBatchIDSorted.Lock()
+	mock.calls.GetBatchRowsByBatchIDSorted = append(mock.calls.GetBatchRowsByBatchIDSorted, callInfo)
+	mock.lockGetBatchRowsByBatchIDSorted.Unlock()
+	return mock.GetBatchRowsByBatchIDSortedFunc(ctx, batch)
+}
+
+// GetBatchRowsByBatchIDSortedCalls gets all the calls that were made to GetBatchRowsByBatchIDSorted.
+// Check the length with:
+//
+//	len(mockedQuerier.GetBatchRowsByBatchIDSortedCalls())
+func (mock *QuerierMock) GetBatchRowsByBatchIDSortedCalls() []struct {
+	Ctx   context.Context
+	Batch uuid.UUID
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Batch uuid.UUID
+	}
+	mock.lockGetBatchRowsByBatchIDSorted.RLock()
+	calls = mock.calls.GetBatchRowsByBatchIDSorted
+	mock.lockGetBatchRowsByBatchIDSorted.RUnlock()
+	return calls
+}
+
+// GetBatchRowsCount calls GetBatchRowsCountFunc.
+func (mock *QuerierMock) GetBatchRowsCount(ctx context.Context, batch uuid.UUID) (int64, error) {
+	if mock.GetBatchRowsCountFunc == nil {
+		panic("QuerierMock.GetBatchRowsCountFunc: method is nil but Querier.GetBatchRowsCount was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		Batch uuid.UUID
+	}{
+		Ctx:   ctx,
+		Batch: batch,
+	}
+	mock.lockGetBatchRowsCount.Lock()
+	mock.calls.GetBatchRowsCount = append(mock.calls.GetBatchRowsCount, callInfo)
+	mock.lockGetBatchRowsCount.Unlock()
+	return mock.GetBatchRowsCountFunc(ctx, batch)
+}
+
+// GetBatchRowsCountCalls gets all the calls that were made to GetBatchRowsCount.
+// Check the length with:
+//
+//	len(mockedQuerier.GetBatchRowsCountCalls())
+func (mock *QuerierMock) GetBatchRowsCountCalls() []struct {
+	Ctx   context.Context
+	Batch uuid.UUID
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Batch uuid.UUID
+	}
+	mock.lockGetBatchRowsCount.RLock()
+	calls = mock.calls.GetBatchRowsCount
+	mock.lockGetBatchRowsCount.RUnlock()
+	return calls
+}
+
+// GetBatchRowsForRerun calls GetBatchRowsForRerunFunc.
+func (mock *QuerierMock) GetBatchRowsForRerun(ctx context.Context, arg batchsqlc.GetBatchRowsForRerunParams) ([]batchsqlc.GetBatchRowsForRerunRow, error) {
+	if mock.GetBatchRowsForRerunFunc == nil {
+		panic("QuerierMock.GetBatchRowsForRerunFunc: method is nil but Querier.GetBatchRowsForRerun was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Arg batchsqlc.GetBatchRowsForRerunParams
+	}{
+		Ctx: ctx,
+		Arg: arg,
+	}
+	mock.lockGetBatchRowsForRerun.Lock()
+	mock.calls.GetBatchRowsForRerun = append(mock.calls.GetBatchRowsForRerun, callInfo)
+	mock.lockGetBatchRowsForRerun.Unlock()
+	return mock.GetBatchRowsForRerunFunc(ctx, arg)
+}
+
+// GetBatchRowsForRerunCalls gets all the calls that were made to GetBatchRowsForRerun.
+// Check the length with:
+//
+//	len(mockedQuerier.GetBatchRowsForRerunCalls())
+func (mock *QuerierMock) GetBatchRowsForRerunCalls() []struct {
+	Ctx context.Context
+	Arg batchsqlc.GetBatchRowsForRerunParams
+} {
+	var calls []struct {
+		Ctx context.Context
+		Arg batchsqlc.GetBatchRowsForRerunParams
+	}
+	mock.lockGetBatchRowsForRerun.RLock()
+	calls = mock.calls.GetBatchRowsForRerun
+	mock.lockGetBatchRowsForRerun.RUnlock()
+	return calls
+}
+
+// GetBatchRowsPage calls GetBatchRowsPageFunc.
+func (mock *QuerierMock) GetBatchRowsPage(ctx context.Context, arg batchsqlc.GetBatchRowsPageParams) ([]batchsqlc.Batchrow, error) {
+	if mock.GetBatchRowsPageFunc == nil {
+		panic("QuerierMock.GetBatchRowsPageFunc: method is nil but Querier.GetBatchRowsPage was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Arg batchsqlc.GetBatchRowsPageParams
+	}{
+		Ctx: ctx,
+		Arg: arg,
+	}
+	mock.lockGetBatchRowsPage.Lock()
+	mock.calls.GetBatchRowsPage = append(mock.calls.GetBatchRowsPage, callInfo)
+	mock.lockGetBatchRowsPage.Unlock()
+	return mock.GetBatchRowsPageFunc(ctx, arg)
+}
+
+// GetBatchRowsPageCalls gets all the calls that were made to GetBatchRowsPage.
+// Check the length with:
+//
+//	len(mockedQuerier.GetBatchRowsPageCalls())
+func (mock *QuerierMock) GetBatchRowsPageCalls() []struct {
+	Ctx context.Context
+	Arg batchsqlc.GetBatchRowsPageParams
+} {
+	var calls []struct {
+		Ctx context.Context
+		Arg batchsqlc.GetBatchRowsPageParams
+	}
+	mock.lockGetBatchRowsPage.RLock()
+	calls = mock.calls.GetBatchRowsPage
+	mock.lockGetBatchRowsPage.RUnlock()
+	return calls
+}
+
+// GetBatchScheduleForUpdate calls GetBatchScheduleForUpdateFunc.
+func (mock *QuerierMock) GetBatchScheduleForUpdate(ctx context.Context, name string) (batchsqlc.GetBatchScheduleForUpdateRow, error) {
+	if mock.GetBatchScheduleForUpdateFunc == nil {
+		panic("QuerierMock.GetBatchScheduleForUpdateFunc: method is nil but Querier.GetBatchScheduleForUpdate was just called")
+	}
+	callInfo := struct {
+		Ctx  context.Context
+		Name string
+	}{
+		Ctx:  ctx,
+		Name: name,
+	}
+	mock.lockGetBatchScheduleForUpdate.Lock()
+	mock.calls.GetBatchScheduleForUpdate = append(mock.calls.GetBatchScheduleForUpdate, callInfo)
+	mock.lockGetBatchScheduleForUpdate.Unlock()
+	return mock.GetBatchScheduleForUpdateFunc(ctx, name)
+}
+
+// GetBatchScheduleForUpdateCalls gets all the calls that were made to GetBatchScheduleForUpdate.
+// Check the length with:
+//
+//	len(mockedQuerier.GetBatchScheduleForUpdateCalls())
+func (mock *QuerierMock) GetBatchScheduleForUpdateCalls() []struct {
+	Ctx  context.Context
+	Name string
+} {
+	var calls []struct {
+		Ctx  context.Context
+		Name string
+	}
+	mock.lockGetBatchScheduleForUpdate.RLock()
+	calls = mock.calls.GetBatchScheduleForUpdate
+	mock.lockGetBatchScheduleForUpdate.RUnlock()
+	return calls
+}
+
+// GetBatchStatus calls GetBatchStatusFunc.
+func (mock *QuerierMock) GetBatchStatus(ctx context.Context, id uuid.UUID) (batchsqlc.StatusEnum, error) {
+	if mock.GetBatchStatusFunc == nil {
+		panic("QuerierMock.GetBatchStatusFunc: method is nil but Querier.GetBatchStatus was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		ID  uuid.UUID
+	}{
+		Ctx: ctx,
+		ID:  id,
+	}
+	mock.lockGetBatchStatus.Lock()
+	mock.calls.GetBatchStatus = append(mock.calls.GetBatchStatus, callInfo)
+	mock.lockGetBatchStatus.Unlock()
+	return mock.GetBatchStatusFunc(ctx, id)
+}
+
+// GetBatchStatusCalls gets all the calls that were made to GetBatchStatus.
+// Check the length with:
+//
+//	len(mockedQuerier.GetBatchStatusCalls())
+func (mock *QuerierMock) GetBatchStatusCalls() []struct {
+	Ctx context.Context
+	ID  uuid.UUID
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  uuid.UUID
+	}
+	mock.lockGetBatchStatus.RLock()
+	calls = mock.calls.GetBatchStatus
+	mock.lockGetBatchStatus.RUnlock()
+	return calls
+}
+
+// GetBatchStatusAndOutputFiles calls GetBatchStatusAndOutputFilesFunc.
+func (mock *QuerierMock) GetBatchStatusAndOutputFiles(ctx context.Context, id uuid.UUID) (batchsqlc.GetBatchStatusAndOutputFilesRow, error) {
+	if mock.GetBatchStatusAndOutputFilesFunc == nil {
+		panic("QuerierMock.GetBatchStatusAndOutputFilesFunc: method is nil but Querier.GetBatchStatusAndOutputFiles was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		ID  uuid.UUID
+	}{
+		Ctx: ctx,
+		ID:  id,
+	}
+	mock.lockGetBatchStatusAndOutputFiles.Lock()
+	mock.calls.GetBatchStatusAndOutputFiles = append(mock.calls.GetBatchStatusAndOutputFiles, callInfo)
+	mock.lockGetBatchStatusAndOutputFiles.Unlock()
+	return mock.GetBatchStatusAndOutputFilesFunc(ctx, id)
+}
+
+// GetBatchStatusAndOutputFilesCalls gets all the calls that were made to GetBatchStatusAndOutputFiles.
+// Check the length with:
+//
+//	len(mockedQuerier.GetBatchStatusAndOutputFilesCalls())
+func (mock *QuerierMock) GetBatchStatusAndOutputFilesCalls() []struct {
+	Ctx context.Context
+	ID  uuid.UUID
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  uuid.UUID
+	}
+	mock.lockGetBatchStatusAndOutputFiles.RLock()
+	calls = mock.calls.GetBatchStatusAndOutputFiles
+	mock.lockGetBatchStatusAndOutputFiles.RUnlock()
+	return calls
+}
+
+// GetBatchesToPurge calls GetBatchesToPurgeFunc.
+func (mock *QuerierMock) GetBatchesToPurge(ctx context.Context, arg batchsqlc.GetBatchesToPurgeParams) ([]batchsqlc.Batch, error) {
+	if mock.GetBatchesToPurgeFunc == nil {
+		panic("QuerierMock.GetBatchesToPurgeFunc: method is nil but Querier.GetBatchesToPurge was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Arg batchsqlc.GetBatchesToPurgeParams
+	}{
+		Ctx: ctx,
+		Arg: arg,
+	}
+	mock.lockGetBatchesToPurge.Lock()
+	mock.calls.GetBatchesToPurge = append(mock.calls.GetBatchesToPurge, callInfo)
+	mock.lockGetBatchesToPurge.Unlock()
+	return mock.GetBatchesToPurgeFunc(ctx, arg)
+}
+
+// GetBatchesToPurgeCalls gets all the calls that were made to GetBatchesToPurge.
+// Check the length with:
+//
+//	len(mockedQuerier.GetBatchesToPurgeCalls())
+func (mock *QuerierMock) GetBatchesToPurgeCalls() []struct {
+	Ctx context.Context
+	Arg batchsqlc.GetBatchesToPurgeParams
+} {
+	var calls []struct {
+		Ctx context.Context
+		Arg batchsqlc.GetBatchesToPurgeParams
+	}
+	mock.lockGetBatchesToPurge.RLock()
+	calls = mock.calls.GetBatchesToPurge
+	mock.lockGetBatchesToPurge.RUnlock()
+	return calls
+}
+
+// GetCompletedBatches calls GetCompletedBatchesFunc.
+func (mock *QuerierMock) GetCompletedBatches(ctx context.Context) ([]uuid.UUID, error) {
+	if mock.GetCompletedBatchesFunc == nil {
+		panic("QuerierMock.GetCompletedBatchesFunc: method is nil but Querier.GetCompletedBatches was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
+	mock.lockGetCompletedBatches.Lock()
+	mock.calls.GetCompletedBatches = append(mock.calls.GetCompletedBatches, callInfo)
+	mock.lockGetCompletedBatches.Unlock()
+	return mock.GetCompletedBatchesFunc(ctx)
+}
+
+// GetCompletedBatchesCalls gets all the calls that were made to GetCompletedBatches.
+// Check the length with:
+//
+//	len(mockedQuerier.GetCompletedBatchesCalls())
+func (mock *QuerierMock) GetCompletedBatchesCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	mock.lockGetCompletedBatches.RLock()
+	calls = mock.calls.GetCompletedBatches
+	mock.lockGetCompletedBatches.RUnlock()
+	return calls
+}
+
+// GetDeadWorkers calls GetDeadWorkersFunc.
+func (mock *QuerierMock) GetDeadWorkers(ctx context.Context, cutoff pgtype.Timestamp) ([]string, error) {
+	if mock.GetDeadWorkersFunc == nil {
+		panic("QuerierMock.GetDeadWorkersFunc: method is nil but Querier.GetDeadWorkers was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Cutoff pgtype.Timestamp
+	}{
+		Ctx:    ctx,
+		Cutoff: cutoff,
+	}
+	mock.lockGetDeadWorkers.Lock()
+	mock.calls.GetDeadWorkers = append(mock.calls.GetDeadWorkers, callInfo)
+	mock.lockGetDeadWorkers.Unlock()
+	return mock.GetDeadWorkersFunc(ctx, cutoff)
+}
+
+// GetDeadWorkersCalls gets all the calls that were made to GetDeadWorkers.
+// Check the length with:
+//
+//	len(mockedQuerier.GetDeadWorkersCalls())
+func (mock *QuerierMock) GetDeadWorkersCalls() []struct {
+	Ctx    context.Context
+	Cutoff pgtype.Timestamp
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Cutoff pgtype.Timestamp
+	}
+	mock.lockGetDeadWorkers.RLock()
+	calls = mock.calls.GetDeadWorkers
+	mock.lockGetDeadWorkers.RUnlock()
+	return calls
+}
+
+// GetIdempotencyKeyBatch calls GetIdempotencyKeyBatchFunc.
+func (mock *QuerierMock) GetIdempotencyKeyBatch(ctx context.Context, arg batchsqlc.GetIdempotencyKeyBatchParams) (uuid.UUID, error) {
+	if mock.GetIdempotencyKeyBatchFunc == nil {
+		panic("QuerierMock.GetIdempotencyKeyBatchFunc: method is nil but Querier.GetIdempotencyKeyBatch was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Arg batchsqlc.GetIdempotencyKeyBatchParams
+	}{
+		Ctx: ctx,
+		Arg: arg,
+	}
+	mock.lockGetIdempotencyKeyBatch.Lock()
+	mock.calls.GetIdempotencyKeyBatch = append(mock.calls.GetIdempotencyKeyBatch, callInfo)
+	mock.lockGetIdempotencyKeyBatch.Unlock()
+	return mock.GetIdempotencyKeyBatchFunc(ctx, arg)
+}
+
+// GetIdempotencyKeyBatchCalls gets all the calls that were made to GetIdempotencyKeyBatch.
+// Check the length with:
+//
+//	len(mockedQuerier.GetIdempotencyKeyBatchCalls())
+func (mock *QuerierMock) GetIdempotencyKeyBatchCalls() []struct {
+	Ctx context.Context
+	Arg batchsqlc.GetIdempotencyKeyBatchParams
+} {
+	var calls []struct {
+		Ctx context.Context
+		Arg batchsqlc.GetIdempotencyKeyBatchParams
+	}
+	mock.lockGetIdempotencyKeyBatch.RLock()
+	calls = mock.calls.GetIdempotencyKeyBatch
+	mock.lockGetIdempotencyKeyBatch.RUnlock()
+	return calls
+}
+
+// GetParentBatches calls GetParentBatchesFunc.
+func (mock *QuerierMock) GetParentBatches(ctx context.Context, batch uuid.UUID) ([]batchsqlc.GetParentBatchesRow, error) {
+	if mock.GetParentBatchesFunc == nil {
+		panic("QuerierMock.GetParentBatchesFunc: method is nil but Querier.GetParentBatches was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		Batch uuid.UUID
+	}{
+		Ctx:   ctx,
+		Batch: batch,
+	}
+	mock.lockGetParentBatches.Lock()
+	mock.calls.GetParentBatches = append(mock.calls.GetParentBatches, callInfo)
+	mock.lockGetParentBatches.Unlock()
+	return mock.GetParentBatchesFunc(ctx, batch)
+}
+
+// GetParentBatchesCalls gets all the calls that were made to GetParentBatches.
+// Check the length with:
+//
+//	len(mockedQuerier.GetParentBatchesCalls())
+func (mock *QuerierMock) GetParentBatchesCalls() []struct {
+	Ctx   context.Context
+	Batch uuid.UUID
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Batch uuid.UUID
+	}
+	mock.lockGetParentBatches.RLock()
+	calls = mock.calls.GetParentBatches
+	mock.lockGetParentBatches.RUnlock()
+	return calls
+}
+
+// GetPendingBatchRows calls GetPendingBatchRowsFunc.
+func (mock *QuerierMock) GetPendingBatchRows(ctx context.Context, batch uuid.UUID) ([]batchsqlc.GetPendingBatchRowsRow, error) {
+	if mock.GetPendingBatchRowsFunc == nil {
+		panic("QuerierMock.GetPendingBatchRowsFunc: method is nil but Querier.GetPendingBatchRows was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		Batch uuid.UUID
+	}{
+		Ctx:   ctx,
+		Batch: batch,
+	}
+	mock.lockGetPendingBatchRows.Lock()
+	mock.calls.GetPendingBatchRows = append(mock.calls.GetPendingBatchRows, callInfo)
+	mock.lockGetPendingBatchRows.Unlock()
+	return mock.GetPendingBatchRowsFunc(ctx, batch)
+}
+
+// GetPendingBatchRowsCalls gets all the calls that were made to GetPendingBatchRows.
+// Check the length with:
+//
+//	len(mockedQuerier.GetPendingBatchRowsCalls())
+func (mock *QuerierMock) GetPendingBatchRowsCalls() []struct {
+	Ctx   context.Context
+	Batch uuid.UUID
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Batch uuid.UUID
+	}
+	mock.lockGetPendingBatchRows.RLock()
+	calls = mock.calls.GetPendingBatchRows
+	mock.lockGetPendingBatchRows.RUnlock()
+	return calls
+}
+
+// GetProcessedBatchRowsPage calls GetProcessedBatchRowsPageFunc.
+func (mock *QuerierMock) GetProcessedBatchRowsPage(ctx context.Context, arg batchsqlc.GetProcessedBatchRowsPageParams) ([]batchsqlc.GetProcessedBatchRowsPageRow, error) {
+	if mock.GetProcessedBatchRowsPageFunc == nil {
+		panic("QuerierMock.GetProcessedBatchRowsPageFunc: method is nil but Querier.GetProcessedBatchRowsPage was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Arg batchsqlc.GetProcessedBatchRowsPageParams
+	}{
+		Ctx: ctx,
+		Arg: arg,
+	}
+	mock.lockGetProcessedBatchRowsPage.Lock()
+	mock.calls.GetProcessedBatchRowsPage = append(mock.calls.GetProcessedBatchRowsPage, callInfo)
+	mock.lockGetProcessedBatchRowsPage.Unlock()
+	return mock.GetProcessedBatchRowsPageFunc(ctx, arg)
+}
+
+// GetProcessedBatchRowsPageCalls gets all the calls that were made to GetProcessedBatchRowsPage.
+// Check the length with:
+//
+//	len(mockedQuerier.GetProcessedBatchRowsPageCalls())
+func (mock *QuerierMock) GetProcessedBatchRowsPageCalls() []struct {
+	Ctx context.Context
+	Arg batchsqlc.GetProcessedBatchRowsPageParams
+} {
+	var calls []struct {
+		Ctx context.Context
+		Arg batchsqlc.GetProcessedBatchRowsPageParams
+	}
+	mock.lockGetProcessedBatchRowsPage.RLock()
+	calls = mock.calls.GetProcessedBatchRowsPage
+	mock.lockGetProcessedBatchRowsPage.RUnlock()
+	return calls
+}
+
+// GetUnsummarizedBatches calls GetUnsummarizedBatchesFunc.
+func (mock *QuerierMock) GetUnsummarizedBatches(ctx context.Context) ([]uuid.UUID, error) {
+	if mock.GetUnsummarizedBatchesFunc == nil {
+		panic("QuerierMock.GetUnsummarizedBatchesFunc: method is nil but Querier.GetUnsummarizedBatches was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
+	mock.lockGetUnsummarizedBatches.Lock()
+	mock.calls.GetUnsummarizedBatches = append(mock.calls.GetUnsummarizedBatches, callInfo)
+	mock.lockGetUnsummarizedBatches.Unlock()
+	return mock.GetUnsummarizedBatchesFunc(ctx)
+}
+
+// GetUnsummarizedBatchesCalls gets all the calls that were made to GetUnsummarizedBatches.
+// Check the length with:
+//
+//	len(mockedQuerier.GetUnsummarizedBatchesCalls())
+func (mock *QuerierMock) GetUnsummarizedBatchesCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	mock.lockGetUnsummarizedBatches.RLock()
+	calls = mock.calls.GetUnsummarizedBatches
+	mock.lockGetUnsummarizedBatches.RUnlock()
+	return calls
+}
+
+// GetWaitingDependentBatches calls GetWaitingDependentBatchesFunc.
+func (mock *QuerierMock) GetWaitingDependentBatches(ctx context.Context, parent uuid.UUID) ([]uuid.UUID, error) {
+	if mock.GetWaitingDependentBatchesFunc == nil {
+		panic("QuerierMock.GetWaitingDependentBatchesFunc: method is nil but Querier.GetWaitingDependentBatches was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Parent uuid.UUID
+	}{
+		Ctx:    ctx,
+		Parent: parent,
+	}
+	mock.lockGetWaitingDependentBatches.Lock()
+	mock.calls.GetWaitingDependentBatches = append(mock.calls.GetWaitingDependentBatches, callInfo)
+	mock.lockGetWaitingDependentBatches.Unlock()
+	return mock.GetWaitingDependentBatchesFunc(ctx, parent)
+}
+
+// GetWaitingDependentBatchesCalls gets all the calls that were made to GetWaitingDependentBatches.
+// Check the length with:
+//
+//	len(mockedQuerier.GetWaitingDependentBatchesCalls())
+func (mock *QuerierMock) GetWaitingDependentBatchesCalls() []struct {
+	Ctx    context.Context
+	Parent uuid.UUID
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Parent uuid.UUID
+	}
+	mock.lockGetWaitingDependentBatches.RLock()
+	calls = mock.calls.GetWaitingDependentBatches
+	mock.lockGetWaitingDependentBatches.RUnlock()
+	return calls
+}
+
+// GetWebhookDeliveriesByBatchID calls GetWebhookDeliveriesByBatchIDFunc.
+func (mock *QuerierMock) GetWebhookDeliveriesByBatchID(ctx context.Context, batch uuid.UUID) ([]batchsqlc.WebhookDelivery, error) {
+	if mock.GetWebhookDeliveriesByBatchIDFunc == nil {
+		panic("QuerierMock.GetWebhookDeliveriesByBatchIDFunc: method is nil but Querier.GetWebhookDeliveriesByBatchID was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		Batch uuid.UUID
+	}{
+		Ctx:   ctx,
+		Batch: batch,
+	}
+	mock.lockGetWebhookDeliveriesByBatchID.Lock()
+	mock.calls.GetWebhookDeliveriesByBatchID = append(mock.calls.GetWebhookDeliveriesByBatchID, callInfo)
+	mock.lockGetWebhookDeliveriesByBatchID.Unlock()
+	return mock.GetWebhookDeliveriesByBatchIDFunc(ctx, batch)
+}
+
+// GetWebhookDeliveriesByBatchIDCalls gets all the calls that were made to GetWebhookDeliveriesByBatchID.
+// Check the length with:
+//
+//	len(mockedQuerier.GetWebhookDeliveriesByBatchIDCalls())
+func (mock *QuerierMock) GetWebhookDeliveriesByBatchIDCalls() []struct {
+	Ctx   context.Context
+	Batch uuid.UUID
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Batch uuid.UUID
+	}
+	mock.lockGetWebhookDeliveriesByBatchID.RLock()
+	calls = mock.calls.GetWebhookDeliveriesByBatchID
+	mock.lockGetWebhookDeliveriesByBatchID.RUnlock()
+	return calls
+}
+
+// InsertBatchDependency calls InsertBatchDependencyFunc.
+func (mock *QuerierMock) InsertBatchDependency(ctx context.Context, arg batchsqlc.InsertBatchDependencyParams) error {
+	if mock.InsertBatchDependencyFunc == nil {
+		panic("QuerierMock.InsertBatchDependencyFunc: method is nil but Querier.InsertBatchDependency was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Arg batchsqlc.InsertBatchDependencyParams
+	}{
+		Ctx: ctx,
+		Arg: arg,
+	}
+	mock.lockInsertBatchDependency.Lock()
+	mock.calls.InsertBatchDependency = append(mock.calls.InsertBatchDependency, callInfo)
+	mock.lockInsertBatchDependency.Unlock()
+	return mock.InsertBatchDependencyFunc(ctx, arg)
+}
+
+// InsertBatchDependencyCalls gets all the calls that were made to InsertBatchDependency.
+// Check the length with:
+//
+//	len(mockedQuerier.InsertBatchDependencyCalls())
+func (mock *QuerierMock) InsertBatchDependencyCalls() []struct {
+	Ctx context.Context
+	Arg batchsqlc.InsertBatchDependencyParams
+} {
+	var calls []struct {
+		Ctx context.Context
+		Arg batchsqlc.InsertBatchDependencyParams
+	}
+	mock.lockInsertBatchDependency.RLock()
+	calls = mock.calls.InsertBatchDependency
+	mock.lockInsertBatchDependency.RUnlock()
+	return calls
+}
+
+// InsertBatchFile calls InsertBatchFileFunc.
+func (mock *QuerierMock) InsertBatchFile(ctx context.Context, arg batchsqlc.InsertBatchFileParams) error {
+	if mock.InsertBatchFileFunc == nil {
+		panic("QuerierMock.InsertBatchFileFunc: method is nil but Querier.InsertBatchFile was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Arg batchsqlc.InsertBatchFileParams
+	}{
+		Ctx: ctx,
+		Arg: arg,
+	}
+	mock.lockInsertBatchFile.Lock()
+	mock.calls.InsertBatchFile = append(mock.calls.InsertBatchFile, callInfo)
+	mock.lockInsertBatchFile.Unlock()
+	return mock.InsertBatchFileFunc(ctx, arg)
+}
+
+// InsertBatchFileCalls gets all the calls that were made to InsertBatchFile.
+// Check the length with:
+//
+//	len(mockedQuerier.InsertBatchFileCalls())
+func (mock *QuerierMock) InsertBatchFileCalls() []struct {
+	Ctx context.Context
+	Arg batchsqlc.InsertBatchFileParams
+} {
+	var calls []struct {
+		Ctx context.Context
+		Arg batchsqlc.InsertBatchFileParams
+	}
+	mock.lockInsertBatchFile.RLock()
+	calls = mock.calls.InsertBatchFile
+	mock.lockInsertBatchFile.RUnlock()
+	return calls
+}
+
+// InsertIntoBatchRows calls InsertIntoBatchRowsFunc.
+func (mock *QuerierMock) InsertIntoBatchRows(ctx context.Context, arg batchsqlc.InsertIntoBatchRowsParams) error {
+	if mock.InsertIntoBatchRowsFunc == nil {
+		panic("QuerierMock.InsertIntoBatchRowsFunc: method is nil but Querier.InsertIntoBatchRows was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Arg batchsqlc.InsertIntoBatchRowsParams
+	}{
+		Ctx: ctx,
+		Arg: arg,
+	}
+	mock.lockInsertIntoBatchRows.Lock()
+	mock.calls.InsertIntoBatchRows = append(mock.calls.InsertIntoBatchRows, callInfo)
+	mock.lockInsertIntoBatchRows.Unlock()
+	return mock.InsertIntoBatchRowsFunc(ctx, arg)
+}
+
+// InsertIntoBatchRowsCalls gets all the calls that were made to InsertIntoBatchRows.
+// Check the length with:
+//
+//	len(mockedQuerier.InsertIntoBatchRowsCalls())
+func (mock *QuerierMock) InsertIntoBatchRowsCalls() []struct {
+	Ctx context.Context
+	Arg batchsqlc.InsertIntoBatchRowsParams
+} {
+	var calls []struct {
+		Ctx context.Context
+		Arg batchsqlc.InsertIntoBatchRowsParams
+	}
+	mock.lockInsertIntoBatchRows.RLock()
+	calls = mock.calls.InsertIntoBatchRows
+	mock.lockInsertIntoBatchRows.RUnlock()
 	return calls
 }
 
-// CountBatchRowsByBatchIDAndStatus calls CountBatchRowsByBatchIDAndStatusFunc.
-func (mock *QuerierMock) CountBatchRowsByBatchIDAndStatus(ctx context.Context, arg batchsqlc.CountBatchRowsByBatchIDAndStatusParams) (int64, error) {
-	if mock.CountBatchRowsByBatchIDAndStatusFunc == nil {
-		panic("QuerierMock.CountBatchRowsByBatchIDAndStatusFunc: method is nil but Querier.CountBatchRowsByBatchIDAndStatus was just called")
+// InsertIntoBatches calls InsertIntoBatchesFunc.
+func (mock *QuerierMock) InsertIntoBatches(ctx context.Context, arg batchsqlc.InsertIntoBatchesParams) (uuid.UUID, error) {
+	if mock.InsertIntoBatchesFunc == nil {
+		panic("QuerierMock.InsertIntoBatchesFunc: method is nil but Querier.InsertIntoBatches was just called")
 	}
 	callInfo := struct {
 		Ctx context.Context
-		Arg batchsqlc.CountBatchRowsByBatchIDAndStatusParams
+		Arg batchsqlc.InsertIntoBatchesParams
 	}{
 		Ctx: ctx,
 		Arg: arg,
 	}
-	mock.lockCountBatchRowsByBatchIDAndStatus.Lock()
-	mock.calls.CountBatchRowsByBatchIDAndStatus = append(mock.calls.CountBatchRowsByBatchIDAndStatus, callInfo)
-	mock.lockCountBatchRowsByBatchIDAndStatus.Unlock()
-	return mock.CountBatchRowsByBatchIDAndStatusFunc(ctx, arg)
+	mock.lockInsertIntoBatches.Lock()
+	mock.calls.InsertIntoBatches = append(mock.calls.InsertIntoBatches, callInfo)
+	mock.lockInsertIntoBatches.Unlock()
+	return mock.InsertIntoBatchesFunc(ctx, arg)
 }
 
-// CountBatchRowsByBatchIDAndStatusCalls gets all the calls that were made to CountBatchRowsByBatchIDAndStatus.
+// InsertIntoBatchesCalls gets all the calls that were made to InsertIntoBatches.
 // Check the length with:
 //
-//	len(mockedQuerier.CountBatchRowsByBatchIDAndStatusCalls())
-func (mock *QuerierMock) CountBatchRowsByBatchIDAndStatusCalls() []struct {
+//	len(mockedQuerier.InsertIntoBatchesCalls())
+func (mock *QuerierMock) InsertIntoBatchesCalls() []struct {
 	Ctx context.Context
-	Arg batchsqlc.CountBatchRowsByBatchIDAndStatusParams
+	Arg batchsqlc.InsertIntoBatchesParams
 } {
 	var calls []struct {
 		Ctx context.Context
-		Arg batchsqlc.CountBatchRowsByBatchIDAndStatusParams
+		Arg batchsqlc.InsertIntoBatchesParams
 	}
-	mock.lockCountBatchRowsByBatchIDAndStatus.RLock()
-	calls = mock.calls.CountBatchRowsByBatchIDAndStatus
-	mock.lockCountBatchRowsByBatchIDAndStatus.RUnlock()
+	mock.lockInsertIntoBatches.RLock()
+	calls = mock.calls.InsertIntoBatches
+	mock.lockInsertIntoBatches.RUnlock()
 	return calls
 }
 
-// CountBatchRowsInProgByBatchID calls CountBatchRowsInProgByBatchIDFunc.
-func (mock *QuerierMock) CountBatchRowsInProgByBatchID(ctx context.Context, batch uuid.UUID) (int64, error) {
-	if mock.CountBatchRowsInProgByBatchIDFunc == nil {
-		panic("QuerierMock.CountBatchRowsInProgByBatchIDFunc: method is nil but Querier.CountBatchRowsInProgByBatchID was just called")
+// InsertWebhookDelivery calls InsertWebhookDeliveryFunc.
+func (mock *QuerierMock) InsertWebhookDelivery(ctx context.Context, arg batchsqlc.InsertWebhookDeliveryParams) error {
+	if mock.InsertWebhookDeliveryFunc == nil {
+		panic("QuerierMock.InsertWebhookDeliveryFunc: method is nil but Querier.InsertWebhookDelivery was just called")
 	}
 	callInfo := struct {
-		Ctx   context.Context
-		Batch uuid.UUID
+		Ctx context.Context
+		Arg batchsqlc.InsertWebhookDeliveryParams
 	}{
-		Ctx:   ctx,
-		Batch: batch,
+		Ctx: ctx,
+		Arg: arg,
 	}
-	mock.lockCountBatchRowsInProgByBatchID.Lock()
-	mock.calls.CountBatchRowsInProgByBatchID = append(mock.calls.CountBatchRowsInProgByBatchID, callInfo)
-	mock.lockCountBatchRowsInProgByBatchID.Unlock()
-	return mock.CountBatchRowsInProgByBatchIDFunc(ctx, batch)
+	mock.lockInsertWebhookDelivery.Lock()
+	mock.calls.InsertWebhookDelivery = append(mock.calls.InsertWebhookDelivery, callInfo)
+	mock.lockInsertWebhookDelivery.Unlock()
+	return mock.InsertWebhookDeliveryFunc(ctx, arg)
 }
 
-// CountBatchRowsInProgByBatchIDCalls gets all the calls that were made to CountBatchRowsInProgByBatchID.
+// InsertWebhookDeliveryCalls gets all the calls that were made to InsertWebhookDelivery.
 // Check the length with:
 //
-//	len(mockedQuerier.CountBatchRowsInProgByBatchIDCalls())
-func (mock *QuerierMock) CountBatchRowsInProgByBatchIDCalls() []struct {
-	Ctx   context.Context
-	Batch uuid.UUID
+//	len(mockedQuerier.InsertWebhookDeliveryCalls())
+func (mock *QuerierMock) InsertWebhookDeliveryCalls() []struct {
+	Ctx context.Context
+	Arg batchsqlc.InsertWebhookDeliveryParams
 } {
 	var calls []struct {
-		Ctx   context.Context
-		Batch uuid.UUID
+		Ctx context.Context
+		Arg batchsqlc.InsertWebhookDeliveryParams
 	}
-	mock.lockCountBatchRowsInProgByBatchID.RLock()
-	calls = mock.calls.CountBatchRowsInProgByBatchID
-	mock.lockCountBatchRowsInProgByBatchID.RUnlock()
+	mock.lockInsertWebhookDelivery.RLock()
+	calls = mock.calls.InsertWebhookDelivery
+	mock.lockInsertWebhookDelivery.RUnlock()
 	return calls
 }
 
-// CountBatchRowsQueuedByBatchID calls CountBatchRowsQueuedByBatchIDFunc.
-func (mock *QuerierMock) CountBatchRowsQueuedByBatchID(ctx context.Context, batch uuid.UUID) (int64, error) {
-	if mock.CountBatchRowsQueuedByBatchIDFunc == nil {
-		panic("QuerierMock.CountBatchRowsQueuedByBatchIDFunc: method is nil but Querier.CountBatchRowsQueuedByBatchID was just called")
+// LeaseBatchRows calls LeaseBatchRowsFunc.
+func (mock *QuerierMock) LeaseBatchRows(ctx context.Context, arg batchsqlc.LeaseBatchRowsParams) error {
+	if mock.LeaseBatchRowsFunc == nil {
+		panic("QuerierMock.LeaseBatchRowsFunc: method is nil but Querier.LeaseBatchRows was just called")
 	}
 	callInfo := struct {
-		Ctx   context.Context
-		Batch uuid.UUID
+		Ctx context.Context
+		Arg batchsqlc.LeaseBatchRowsParams
 	}{
-		Ctx:   ctx,
-		Batch: batch,
+		Ctx: ctx,
+		Arg: arg,
 	}
-	mock.lockCountBatchRowsQueuedByBatchID.Lock()
-	mock.calls.CountBatchRowsQueuedByBatchID = append(mock.calls.CountBatchRowsQueuedByBatchID, callInfo)
-	mock.lockCountBatchRowsQueuedByBatchID.Unlock()
-	return mock.CountBatchRowsQueuedByBatchIDFunc(ctx, batch)
+	mock.lockLeaseBatchRows.Lock()
+	mock.calls.LeaseBatchRows = append(mock.calls.LeaseBatchRows, callInfo)
+	mock.lockLeaseBatchRows.Unlock()
+	return mock.LeaseBatchRowsFunc(ctx, arg)
 }
 
-// CountBatchRowsQueuedByBatchIDCalls gets all the calls that were made to CountBatchRowsQueuedByBatchID.
+// LeaseBatchRowsCalls gets all the calls that were made to LeaseBatchRows.
 // Check the length with:
 //
-//	len(mockedQuerier.CountBatchRowsQueuedByBatchIDCalls())
-func (mock *QuerierMock) CountBatchRowsQueuedByBatchIDCalls() []struct {
-	Ctx   context.Context
-	Batch uuid.UUID
+//	len(mockedQuerier.LeaseBatchRowsCalls())
+func (mock *QuerierMock) LeaseBatchRowsCalls() []struct {
+	Ctx context.Context
+	Arg batchsqlc.LeaseBatchRowsParams
 } {
 	var calls []struct {
-		Ctx   context.Context
-		Batch uuid.UUID
+		Ctx context.Context
+		Arg batchsqlc.LeaseBatchRowsParams
 	}
-	mock.lockCountBatchRowsQueuedByBatchID.RLock()
-	calls = mock.calls.CountBatchRowsQueuedByBatchID
-	mock.lockCountBatchRowsQueuedByBatchID.RUnlock()
+	mock.lockLeaseBatchRows.RLock()
+	calls = mock.calls.LeaseBatchRows
+	mock.lockLeaseBatchRows.RUnlock()
 	return calls
 }
 
-// FetchBatchRowsForBatchDone calls FetchBatchRowsForBatchDoneFunc.
-func (mock *QuerierMock) FetchBatchRowsForBatchDone(ctx context.Context, batch uuid.UUID) ([]batchsqlc.FetchBatchRowsForBatchDoneRow, error) {
-	if mock.FetchBatchRowsForBatchDoneFunc == nil {
-		panic("QuerierMock.FetchBatchRowsForBatchDoneFunc: method is nil but Querier.FetchBatchRowsForBatchDone was just called")
+// ListBatches calls ListBatchesFunc.
+func (mock *QuerierMock) ListBatches(ctx context.Context, arg batchsqlc.ListBatchesParams) ([]batchsqlc.ListBatchesRow, error) {
+	if mock.ListBatchesFunc == nil {
+		panic("QuerierMock.ListBatchesFunc: method is nil but Querier.ListBatches was just called")
 	}
 	callInfo := struct {
-		Ctx   context.Context
-		Batch uuid.UUID
+		Ctx context.Context
+		Arg batchsqlc.ListBatchesParams
 	}{
-		Ctx:   ctx,
-		Batch: batch,
+		Ctx: ctx,
+		Arg: arg,
 	}
-	mock.lockFetchBatchRowsForBatchDone.Lock()
-	mock.calls.FetchBatchRowsForBatchDone = append(mock.calls.FetchBatchRowsForBatchDone, callInfo)
-	mock.lockFetchBatchRowsForBatchDone.Unlock()
-	return mock.FetchBatchRowsForBatchDoneFunc(ctx, batch)
+	mock.lockListBatches.Lock()
+	mock.calls.ListBatches = append(mock.calls.ListBatches, callInfo)
+	mock.lockListBatches.Unlock()
+	return mock.ListBatchesFunc(ctx, arg)
 }
 
-// FetchBatchRowsForBatchDoneCalls gets all the calls that were made to FetchBatchRowsForBatchDone.
+// ListBatchesCalls gets all the calls that were made to ListBatches.
 // Check the length with:
 //
-//	len(mockedQuerier.FetchBatchRowsForBatchDoneCalls())
-func (mock *QuerierMock) FetchBatchRowsForBatchDoneCalls() []struct {
-	Ctx   context.Context
-	Batch uuid.UUID
+//	len(mockedQuerier.ListBatchesCalls())
+func (mock *QuerierMock) ListBatchesCalls() []struct {
+	Ctx context.Context
+	Arg batchsqlc.ListBatchesParams
 } {
 	var calls []struct {
-		Ctx   context.Context
-		Batch uuid.UUID
+		Ctx context.Context
+		Arg batchsqlc.ListBatchesParams
 	}
-	mock.lockFetchBatchRowsForBatchDone.RLock()
-	calls = mock.calls.FetchBatchRowsForBatchDone
-	mock.lockFetchBatchRowsForBatchDone.RUnlock()
+	mock.lockListBatches.RLock()
+	calls = mock.calls.ListBatches
+	mock.lockListBatches.RUnlock()
 	return calls
 }
 
-// FetchBlockOfRows calls FetchBlockOfRowsFunc.
-func (mock *QuerierMock) FetchBlockOfRows(ctx context.Context, arg batchsqlc.FetchBlockOfRowsParams) ([]batchsqlc.FetchBlockOfRowsRow, error) {
-	if mock.FetchBlockOfRowsFunc == nil {
-		panic("QuerierMock.FetchBlockOfRowsFunc: method is nil but Querier.FetchBlockOfRows was just called")
+// ListSlowQueries calls ListSlowQueriesFunc.
+func (mock *QuerierMock) ListSlowQueries(ctx context.Context, arg batchsqlc.ListSlowQueriesParams) ([]batchsqlc.ListSlowQueriesRow, error) {
+	if mock.ListSlowQueriesFunc == nil {
+		panic("QuerierMock.ListSlowQueriesFunc: method is nil but Querier.ListSlowQueries was just called")
 	}
 	callInfo := struct {
 		Ctx context.Context
-		Arg batchsqlc.FetchBlockOfRowsParams
+		Arg batchsqlc.ListSlowQueriesParams
 	}{
 		Ctx: ctx,
 		Arg: arg,
 	}
-	mock.lockFetchBlockOfRows.Lock()
-	mock.calls.FetchBlockOfRows = append(mock.calls.FetchBlockOfRows, callInfo)
-	mock.lockFetchBlockOfRows.Unlock()
-	return mock.FetchBlockOfRowsFunc(ctx, arg)
+	mock.lockListSlowQueries.Lock()
+	mock.calls.ListSlowQueries = append(mock.calls.ListSlowQueries, callInfo)
+	mock.lockListSlowQueries.Unlock()
+	return mock.ListSlowQueriesFunc(ctx, arg)
 }
 
-// FetchBlockOfRowsCalls gets all the calls that were made to FetchBlockOfRows.
+// ListSlowQueriesCalls gets all the calls that were made to ListSlowQueries.
 // Check the length with:
 //
-//	len(mockedQuerier.FetchBlockOfRowsCalls())
-func (mock *QuerierMock) FetchBlockOfRowsCalls() []struct {
+//	len(mockedQuerier.ListSlowQueriesCalls())
+func (mock *QuerierMock) ListSlowQueriesCalls() []struct {
 	Ctx context.Context
-	Arg batchsqlc.FetchBlockOfRowsParams
+	Arg batchsqlc.ListSlowQueriesParams
 } {
 	var calls []struct {
 		Ctx context.Context
-		Arg batchsqlc.FetchBlockOfRowsParams
+		Arg batchsqlc.ListSlowQueriesParams
 	}
-	mock.lockFetchBlockOfRows.RLock()
-	calls = mock.calls.FetchBlockOfRows
-	mock.lockFetchBlockOfRows.RUnlock()
+	mock.lockListSlowQueries.RLock()
+	calls = mock.calls.ListSlowQueries
+	mock.lockListSlowQueries.RUnlock()
 	return calls
 }
 
-// GetBatchByID calls GetBatchByIDFunc.
-func (mock *QuerierMock) GetBatchByID(ctx context.Context, id uuid.UUID) (batchsqlc.Batch, error) {
-	if mock.GetBatchByIDFunc == nil {
-		panic("QuerierMock.GetBatchByIDFunc: method is nil but Querier.GetBatchByID was just called")
+// LockParentBatches calls LockParentBatchesFunc.
+func (mock *QuerierMock) LockParentBatches(ctx context.Context, ids []uuid.UUID) ([]uuid.UUID, error) {
+	if mock.LockParentBatchesFunc == nil {
+		panic("QuerierMock.LockParentBatchesFunc: method is nil but Querier.LockParentBatches was just called")
 	}
 	callInfo := struct {
 		Ctx context.Context
-		ID  uuid.UUID
+		Ids []uuid.UUID
 	}{
 		Ctx: ctx,
-		ID:  id,
+		Ids: ids,
 	}
-	mock.lockGetBatchByID.Lock()
-	mock.calls.GetBatchByID = append(mock.calls.GetBatchByID, callInfo)
-	mock.lockGetBatchByID.Unlock()
-	return mock.GetBatchByIDFunc(ctx, id)
+	mock.lockLockParentBatches.Lock()
+	mock.calls.LockParentBatches = append(mock.calls.LockParentBatches, callInfo)
+	mock.lockLockParentBatches.Unlock()
+	return mock.LockParentBatchesFunc(ctx, ids)
 }
 
-// GetBatchByIDCalls gets all the calls that were made to GetBatchByID.
+// LockParentBatchesCalls gets all the calls that were made to LockParentBatches.
 // Check the length with:
 //
-//	len(mockedQuerier.GetBatchByIDCalls())
-func (mock *QuerierMock) GetBatchByIDCalls() []struct {
+//	len(mockedQuerier.LockParentBatchesCalls())
+func (mock *QuerierMock) LockParentBatchesCalls() []struct {
 	Ctx context.Context
-	ID  uuid.UUID
+	Ids []uuid.UUID
 } {
 	var calls []struct {
 		Ctx context.Context
-		ID  uuid.UUID
+		Ids []uuid.UUID
 	}
-	mock.lockGetBatchByID.RLock()
-	calls = mock.calls.GetBatchByID
-	mock.lockGetBatchByID.RUnlock()
+	mock.lockLockParentBatches.RLock()
+	calls = mock.calls.LockParentBatches
+	mock.lockLockParentBatches.RUnlock()
 	return calls
 }
 
-// GetBatchRowsByBatchID calls GetBatchRowsByBatchIDFunc.
-func (mock *QuerierMock) GetBatchRowsByBatchID(ctx context.Context, batch uuid.UUID) ([]batchsqlc.Batchrow, error) {
-	if mock.GetBatchRowsByBatchIDFunc == nil {
-		panic("QuerierMock.GetBatchRowsByBatchIDFunc: method is nil but Querier.GetBatchRowsByBatchID was just called")
+// MarkWebhookAttemptFailed calls MarkWebhookAttemptFailedFunc.
+func (mock *QuerierMock) MarkWebhookAttemptFailed(ctx context.Context, arg batchsqlc.MarkWebhookAttemptFailedParams) (int64, error) {
+	if mock.MarkWebhookAttemptFailedFunc == nil {
+		panic("QuerierMock.MarkWebhookAttemptFailedFunc: method is nil but Querier.MarkWebhookAttemptFailed was just called")
 	}
 	callInfo := struct {
-		Ctx   context.Context
-		Batch uuid.UUID
+		Ctx context.Context
+		Arg batchsqlc.MarkWebhookAttemptFailedParams
 	}{
-		Ctx:   ctx,
-		Batch: batch,
+		Ctx: ctx,
+		Arg: arg,
 	}
-	mock.lockGetBatchRowsByBatchID.Lock()
-	mock.calls.GetBatchRowsByBatchID = append(mock.calls.GetBatchRowsByBatchID, callInfo)
-	mock.lockGetBatchRowsByBatchID.Unlock()
-	return mock.GetBatchRowsByBatchIDFunc(ctx, batch)
+	mock.lockMarkWebhookAttemptFailed.Lock()
+	mock.calls.MarkWebhookAttemptFailed = append(mock.calls.MarkWebhookAttemptFailed, callInfo)
+	mock.lockMarkWebhookAttemptFailed.Unlock()
+	return mock.MarkWebhookAttemptFailedFunc(ctx, arg)
 }
 
-// GetBatchRowsByBatchIDCalls gets all the calls that were made to GetBatchRowsByBatchID.
+// MarkWebhookAttemptFailedCalls gets all the calls that were made to MarkWebhookAttemptFailed.
 // Check the length with:
 //
-//	len(mockedQuerier.GetBatchRowsByBatchIDCalls())
-func (mock *QuerierMock) GetBatchRowsByBatchIDCalls() []struct {
-	Ctx   context.Context
-	Batch uuid.UUID
+//	len(mockedQuerier.MarkWebhookAttemptFailedCalls())
+func (mock *QuerierMock) MarkWebhookAttemptFailedCalls() []struct {
+	Ctx context.Context
+	Arg batchsqlc.MarkWebhookAttemptFailedParams
 } {
 	var calls []struct {
-		Ctx   context.Context
-		Batch uuid.UUID
+		Ctx context.Context
+		Arg batchsqlc.MarkWebhookAttemptFailedParams
 	}
-	mock.lockGetBatchRowsByBatchID.RLock()
-	calls = mock.calls.GetBatchRowsByBatchID
-	mock.lockGetBatchRowsByBatchID.RUnlock()
+	mock.lockMarkWebhookAttemptFailed.RLock()
+	calls = mock.calls.MarkWebhookAttemptFailed
+	mock.lockMarkWebhookAttemptFailed.RUnlock()
 	return calls
 }
 
-// GetBatchRowsByBatchIDSorted calls GetBatchRowsByBatchIDSortedFunc.
-func (mock *QuerierMock) GetBatchRowsByBatchIDSorted(ctx context.Context, batch uuid.UUID) ([]batchsqlc.GetBatchRowsByBatchIDSortedRow, error) {
-	if mock.GetBatchRowsByBatchIDSortedFunc == nil {
-		panic("QuerierMock.GetBatchRowsByBatchIDSortedFunc: method is nil but Querier.GetBatchRowsByBatchIDSorted was just called")
+// MarkWebhookDelivered calls MarkWebhookDeliveredFunc.
+func (mock *QuerierMock) MarkWebhookDelivered(ctx context.Context, arg batchsqlc.MarkWebhookDeliveredParams) (int64, error) {
+	if mock.MarkWebhookDeliveredFunc == nil {
+		panic("QuerierMock.MarkWebhookDeliveredFunc: method is nil but Querier.MarkWebhookDelivered was just called")
 	}
 	callInfo := struct {
-		Ctx   context.Context
-		Batch uuid.UUID
+		Ctx context.Context
+		Arg batchsqlc.MarkWebhookDeliveredParams
 	}{
-		Ctx:   ctx,
-		Batch: batch,
+		Ctx: ctx,
+		Arg: arg,
 	}
-	mock.lockGetBatchRowsByBatchIDSorted.Lock()
-	mock.calls.GetBatchRowsByBatchIDSorted = append(mock.calls.GetBatchRowsByBatchIDSorted, callInfo)
-	mock.lockGetBatchRowsByBatchIDSorted.Unlock()
-	return mock.GetBatchRowsByBatchIDSortedFunc(ctx, batch)
+	mock.lockMarkWebhookDelivered.Lock()
+	mock.calls.MarkWebhookDelivered = append(mock.calls.MarkWebhookDelivered, callInfo)
+	mock.lockMarkWebhookDelivered.Unlock()
+	return mock.MarkWebhookDeliveredFunc(ctx, arg)
 }
 
-// GetBatchRowsByBatchIDSortedCalls gets all the calls that were made to GetBatchRowsByBatchIDSorted.
+// MarkWebhookDeliveredCalls gets all the calls that were made to MarkWebhookDelivered.
 // Check the length with:
 //
-//	len(mockedQuerier.GetBatchRowsByBatchIDSortedCalls())
-func (mock *QuerierMock) GetBatchRowsByBatchIDSortedCalls() []struct {
-	Ctx   context.Context
-	Batch uuid.UUID
+//	len(mockedQuerier.MarkWebhookDeliveredCalls())
+func (mock *QuerierMock) MarkWebhookDeliveredCalls() []struct {
+	Ctx context.Context
+	Arg batchsqlc.MarkWebhookDeliveredParams
 } {
 	var calls []struct {
-		Ctx   context.Context
-		Batch uuid.UUID
+		Ctx context.Context
+		Arg batchsqlc.MarkWebhookDeliveredParams
 	}
-	mock.lockGetBatchRowsByBatchIDSorted.RLock()
-	calls = mock.calls.GetBatchRowsByBatchIDSorted
-	mock.lockGetBatchRowsByBatchIDSorted.RUnlock()
+	mock.lockMarkWebhookDelivered.RLock()
+	calls = mock.calls.MarkWebhookDelivered
+	mock.lockMarkWebhookDelivered.RUnlock()
 	return calls
 }
 
-// GetBatchRowsCount calls GetBatchRowsCountFunc.
-func (mock *QuerierMock) GetBatchRowsCount(ctx context.Context, batch uuid.UUID) (int64, error) {
-	if mock.GetBatchRowsCountFunc == nil {
-		panic("QuerierMock.GetBatchRowsCountFunc: method is nil but Querier.GetBatchRowsCount was just called")
+// NotifyJobsQueued calls NotifyJobsQueuedFunc.
+func (mock *QuerierMock) NotifyJobsQueued(ctx context.Context, app string) (string, error) {
+	if mock.NotifyJobsQueuedFunc == nil {
+		panic("QuerierMock.NotifyJobsQueuedFunc: method is nil but Querier.NotifyJobsQueued was just called")
 	}
 	callInfo := struct {
-		Ctx   context.Context
-		Batch uuid.UUID
+		Ctx context.Context
+		App string
 	}{
-		Ctx:   ctx,
-		Batch: batch,
+		Ctx: ctx,
+		App: app,
 	}
-	mock.lockGetBatchRowsCount.Lock()
-	mock.calls.GetBatchRowsCount = append(mock.calls.GetBatchRowsCount, callInfo)
-	mock.lockGetBatchRowsCount.Unlock()
-	return mock.GetBatchRowsCountFunc(ctx, batch)
+	mock.lockNotifyJobsQueued.Lock()
+	mock.calls.NotifyJobsQueued = append(mock.calls.NotifyJobsQueued, callInfo)
+	mock.lockNotifyJobsQueued.Unlock()
+	return mock.NotifyJobsQueuedFunc(ctx, app)
 }
 
-// GetBatchRowsCountCalls gets all the calls that were made to GetBatchRowsCount.
+// NotifyJobsQueuedCalls gets all the calls that were made to NotifyJobsQueued.
 // Check the length with:
 //
-//	len(mockedQuerier.GetBatchRowsCountCalls())
-func (mock *QuerierMock) GetBatchRowsCountCalls() []struct {
-	Ctx   context.Context
-	Batch uuid.UUID
+//	len(mockedQuerier.NotifyJobsQueuedCalls())
+func (mock *QuerierMock) NotifyJobsQueuedCalls() []struct {
+	Ctx context.Context
+	App string
 } {
 	var calls []struct {
-		Ctx   context.Context
-		Batch uuid.UUID
+		Ctx context.Context
+		App string
 	}
-	mock.lockGetBatchRowsCount.RLock()
-	calls = mock.calls.GetBatchRowsCount
-	mock.lockGetBatchRowsCount.RUnlock()
+	mock.lockNotifyJobsQueued.RLock()
+	calls = mock.calls.NotifyJobsQueued
+	mock.lockNotifyJobsQueued.RUnlock()
 	return calls
 }
 
-// GetBatchStatus calls GetBatchStatusFunc.
-func (mock *QuerierMock) GetBatchStatus(ctx context.Context, id uuid.UUID) (batchsqlc.StatusEnum, error) {
-	if mock.GetBatchStatusFunc == nil {
-		panic("QuerierMock.GetBatchStatusFunc: method is nil but Querier.GetBatchStatus was just called")
+// PauseBatch calls PauseBatchFunc.
+func (mock *QuerierMock) PauseBatch(ctx context.Context, id uuid.UUID) (string, error) {
+	if mock.PauseBatchFunc == nil {
+		panic("QuerierMock.PauseBatchFunc: method is nil but Querier.PauseBatch was just called")
 	}
 	callInfo := struct {
 		Ctx context.Context
@@ -904,17 +3081,17 @@ func (mock *QuerierMock) GetBatchStatus(ctx context.Context, id uuid.UUID) (batc
 		Ctx: ctx,
 		ID:  id,
 	}
-	mock.lockGetBatchStatus.Lock()
-	mock.calls.GetBatchStatus = append(mock.calls.GetBatchStatus, callInfo)
-	mock.lockGetBatchStatus.Unlock()
-	return mock.GetBatchStatusFunc(ctx, id)
+	mock.lockPauseBatch.Lock()
+	mock.calls.PauseBatch = append(mock.calls.PauseBatch, callInfo)
+	mock.lockPauseBatch.Unlock()
+	return mock.PauseBatchFunc(ctx, id)
 }
 
-// GetBatchStatusCalls gets all the calls that were made to GetBatchStatus.
+// PauseBatchCalls gets all the calls that were made to PauseBatch.
 // Check the length with:
 //
-//	len(mockedQuerier.GetBatchStatusCalls())
-func (mock *QuerierMock) GetBatchStatusCalls() []struct {
+//	len(mockedQuerier.PauseBatchCalls())
+func (mock *QuerierMock) PauseBatchCalls() []struct {
 	Ctx context.Context
 	ID  uuid.UUID
 } {
@@ -922,289 +3099,297 @@ func (mock *QuerierMock) GetBatchStatusCalls() []struct {
 		Ctx context.Context
 		ID  uuid.UUID
 	}
-	mock.lockGetBatchStatus.RLock()
-	calls = mock.calls.GetBatchStatus
-	mock.lockGetBatchStatus.RUnlock()
+	mock.lockPauseBatch.RLock()
+	calls = mock.calls.PauseBatch
+	mock.lockPauseBatch.RUnlock()
 	return calls
 }
 
-// GetBatchStatusAndOutputFiles calls GetBatchStatusAndOutputFilesFunc.
-func (mock *QuerierMock) GetBatchStatusAndOutputFiles(ctx context.Context, id uuid.UUID) (batchsqlc.GetBatchStatusAndOutputFilesRow, error) {
-	if mock.GetBatchStatusAndOutputFilesFunc == nil {
-		panic("QuerierMock.GetBatchStatusAndOutputFilesFunc: method is nil but Querier.GetBatchStatusAndOutputFiles was just called")
+// RecordWorkerHeartbeat calls RecordWorkerHeartbeatFunc.
+func (mock *QuerierMock) RecordWorkerHeartbeat(ctx context.Context, arg batchsqlc.RecordWorkerHeartbeatParams) error {
+	if mock.RecordWorkerHeartbeatFunc == nil {
+		panic("QuerierMock.RecordWorkerHeartbeatFunc: method is nil but Querier.RecordWorkerHeartbeat was just called")
 	}
 	callInfo := struct {
 		Ctx context.Context
-		ID  uuid.UUID
+		Arg batchsqlc.RecordWorkerHeartbeatParams
 	}{
 		Ctx: ctx,
-		ID:  id,
+		Arg: arg,
 	}
-	mock.lockGetBatchStatusAndOutputFiles.Lock()
-	mock.calls.GetBatchStatusAndOutputFiles = append(mock.calls.GetBatchStatusAndOutputFiles, callInfo)
-	mock.lockGetBatchStatusAndOutputFiles.Unlock()
-	return mock.GetBatchStatusAndOutputFilesFunc(ctx, id)
+	mock.lockRecordWorkerHeartbeat.Lock()
+	mock.calls.RecordWorkerHeartbeat = append(mock.calls.RecordWorkerHeartbeat, callInfo)
+	mock.lockRecordWorkerHeartbeat.Unlock()
+	return mock.RecordWorkerHeartbeatFunc(ctx, arg)
 }
 
-// GetBatchStatusAndOutputFilesCalls gets all the calls that were made to GetBatchStatusAndOutputFiles.
+// RecordWorkerHeartbeatCalls gets all the calls that were made to RecordWorkerHeartbeat.
 // Check the length with:
 //
-//	len(mockedQuerier.GetBatchStatusAndOutputFilesCalls())
-func (mock *QuerierMock) GetBatchStatusAndOutputFilesCalls() []struct {
+//	len(mockedQuerier.RecordWorkerHeartbeatCalls())
+func (mock *QuerierMock) RecordWorkerHeartbeatCalls() []struct {
 	Ctx context.Context
-	ID  uuid.UUID
+	Arg batchsqlc.RecordWorkerHeartbeatParams
 } {
 	var calls []struct {
 		Ctx context.Context
-		ID  uuid.UUID
+		Arg batchsqlc.RecordWorkerHeartbeatParams
 	}
-	mock.lockGetBatchStatusAndOutputFiles.RLock()
-	calls = mock.calls.GetBatchStatusAndOutputFiles
-	mock.lockGetBatchStatusAndOutputFiles.RUnlock()
+	mock.lockRecordWorkerHeartbeat.RLock()
+	calls = mock.calls.RecordWorkerHeartbeat
+	mock.lockRecordWorkerHeartbeat.RUnlock()
 	return calls
 }
 
-// GetCompletedBatches calls GetCompletedBatchesFunc.
-func (mock *QuerierMock) GetCompletedBatches(ctx context.Context) ([]uuid.UUID, error) {
-	if mock.GetCompletedBatchesFunc == nil {
-		panic("QuerierMock.GetCompletedBatchesFunc: method is nil but Querier.GetCompletedBatches was just called")
+// ReleaseBatchRowLease calls ReleaseBatchRowLeaseFunc.
+func (mock *QuerierMock) ReleaseBatchRowLease(ctx context.Context, arg batchsqlc.ReleaseBatchRowLeaseParams) error {
+	if mock.ReleaseBatchRowLeaseFunc == nil {
+		panic("QuerierMock.ReleaseBatchRowLeaseFunc: method is nil but Querier.ReleaseBatchRowLease was just called")
 	}
 	callInfo := struct {
 		Ctx context.Context
+		Arg batchsqlc.ReleaseBatchRowLeaseParams
 	}{
 		Ctx: ctx,
+		Arg: arg,
 	}
-	mock.lockGetCompletedBatches.Lock()
-	mock.calls.GetCompletedBatches = append(mock.calls.GetCompletedBatches, callInfo)
-	mock.lockGetCompletedBatches.Unlock()
-	return mock.GetCompletedBatchesFunc(ctx)
+	mock.lockReleaseBatchRowLease.Lock()
+	mock.calls.ReleaseBatchRowLease = append(mock.calls.ReleaseBatchRowLease, callInfo)
+	mock.lockReleaseBatchRowLease.Unlock()
+	return mock.ReleaseBatchRowLeaseFunc(ctx, arg)
 }
 
-// GetCompletedBatchesCalls gets all the calls that were made to GetCompletedBatches.
+// ReleaseBatchRowLeaseCalls gets all the calls that were made to ReleaseBatchRowLease.
 // Check the length with:
 //
-//	len(mockedQuerier.GetCompletedBatchesCalls())
-func (mock *QuerierMock) GetCompletedBatchesCalls() []struct {
+//	len(mockedQuerier.ReleaseBatchRowLeaseCalls())
+func (mock *QuerierMock) ReleaseBatchRowLeaseCalls() []struct {
 	Ctx context.Context
+	Arg batchsqlc.ReleaseBatchRowLeaseParams
 } {
 	var calls []struct {
 		Ctx context.Context
+		Arg batchsqlc.ReleaseBatchRowLeaseParams
 	}
-	mock.lockGetCompletedBatches.RLock()
-	calls = mock.calls.GetCompletedBatches
-	mock.lockGetCompletedBatches.RUnlock()
+	mock.lockReleaseBatchRowLease.RLock()
+	calls = mock.calls.ReleaseBatchRowLease
+	mock.lockReleaseBatchRowLease.RUnlock()
 	return calls
 }
 
-// GetPendingBatchRows calls GetPendingBatchRowsFunc.
-func (mock *QuerierMock) GetPendingBatchRows(ctx context.Context, batch uuid.UUID) ([]batchsqlc.GetPendingBatchRowsRow, error) {
-	if mock.GetPendingBatchRowsFunc == nil {
-		panic("QuerierMock.GetPendingBatchRowsFunc: method is nil but Querier.GetPendingBatchRows was just called")
+// ReleaseDependentBatch calls ReleaseDependentBatchFunc.
+func (mock *QuerierMock) ReleaseDependentBatch(ctx context.Context, arg batchsqlc.ReleaseDependentBatchParams) error {
+	if mock.ReleaseDependentBatchFunc == nil {
+		panic("QuerierMock.ReleaseDependentBatchFunc: method is nil but Querier.ReleaseDependentBatch was just called")
 	}
 	callInfo := struct {
-		Ctx   context.Context
-		Batch uuid.UUID
+		Ctx context.Context
+		Arg batchsqlc.ReleaseDependentBatchParams
 	}{
-		Ctx:   ctx,
-		Batch: batch,
+		Ctx: ctx,
+		Arg: arg,
 	}
-	mock.lockGetPendingBatchRows.Lock()
-	mock.calls.GetPendingBatchRows = append(mock.calls.GetPendingBatchRows, callInfo)
-	mock.lockGetPendingBatchRows.Unlock()
-	return mock.GetPendingBatchRowsFunc(ctx, batch)
+	mock.lockReleaseDependentBatch.Lock()
+	mock.calls.ReleaseDependentBatch = append(mock.calls.ReleaseDependentBatch, callInfo)
+	mock.lockReleaseDependentBatch.Unlock()
+	return mock.ReleaseDependentBatchFunc(ctx, arg)
 }
 
-// GetPendingBatchRowsCalls gets all the calls that were made to GetPendingBatchRows.
+// ReleaseDependentBatchCalls gets all the calls that were made to ReleaseDependentBatch.
 // Check the length with:
 //
-//	len(mockedQuerier.GetPendingBatchRowsCalls())
-func (mock *QuerierMock) GetPendingBatchRowsCalls() []struct {
-	Ctx   context.Context
-	Batch uuid.UUID
+//	len(mockedQuerier.ReleaseDependentBatchCalls())
+func (mock *QuerierMock) ReleaseDependentBatchCalls() []struct {
+	Ctx context.Context
+	Arg batchsqlc.ReleaseDependentBatchParams
 } {
 	var calls []struct {
-		Ctx   context.Context
-		Batch uuid.UUID
+		Ctx context.Context
+		Arg batchsqlc.ReleaseDependentBatchParams
 	}
-	mock.lockGetPendingBatchRows.RLock()
-	calls = mock.calls.GetPendingBatchRows
-	mock.lockGetPendingBatchRows.RUnlock()
+	mock.lockReleaseDependentBatch.RLock()
+	calls = mock.calls.ReleaseDependentBatch
+	mock.lockReleaseDependentBatch.RUnlock()
 	return calls
 }
 
-// GetProcessedBatchRowsByBatchIDSorted calls GetProcessedBatchRowsByBatchIDSortedFunc.
-func (mock *QuerierMock) GetProcessedBatchRowsByBatchIDSorted(ctx context.Context, batch uuid.UUID) ([]batchsqlc.GetProcessedBatchRowsByBatchIDSortedRow, error) {
-	if mock.GetProcessedBatchRowsByBatchIDSortedFunc == nil {
-		panic("QuerierMock.GetProcessedBatchRowsByBatchIDSortedFunc: method is nil but Querier.GetProcessedBatchRowsByBatchIDSorted was just called")
+// RenewBatchRowLeases calls RenewBatchRowLeasesFunc.
+func (mock *QuerierMock) RenewBatchRowLeases(ctx context.Context, arg batchsqlc.RenewBatchRowLeasesParams) error {
+	if mock.RenewBatchRowLeasesFunc == nil {
+		panic("QuerierMock.RenewBatchRowLeasesFunc: method is nil but Querier.RenewBatchRowLeases was just called")
 	}
 	callInfo := struct {
-		Ctx   context.Context
-		Batch uuid.UUID
+		Ctx context.Context
+		Arg batchsqlc.RenewBatchRowLeasesParams
 	}{
-		Ctx:   ctx,
-		Batch: batch,
+		Ctx: ctx,
+		Arg: arg,
 	}
-	mock.lockGetProcessedBatchRowsByBatchIDSorted.Lock()
-	mock.calls.GetProcessedBatchRowsByBatchIDSorted = append(mock.calls.GetProcessedBatchRowsByBatchIDSorted, callInfo)
-	mock.lockGetProcessedBatchRowsByBatchIDSorted.Unlock()
-	return mock.GetProcessedBatchRowsByBatchIDSortedFunc(ctx, batch)
+	mock.lockRenewBatchRowLeases.Lock()
+	mock.calls.RenewBatchRowLeases = append(mock.calls.RenewBatchRowLeases, callInfo)
+	mock.lockRenewBatchRowLeases.Unlock()
+	return mock.RenewBatchRowLeasesFunc(ctx, arg)
 }
 
-// GetProcessedBatchRowsByBatchIDSortedCalls gets all the calls that were made to GetProcessedBatchRowsByBatchIDSorted.
+// RenewBatchRowLeasesCalls gets all the calls that were made to RenewBatchRowLeases.
 // Check the length with:
 //
-//	len(mockedQuerier.GetProcessedBatchRowsByBatchIDSortedCalls())
-func (mock *QuerierMock) GetProcessedBatchRowsByBatchIDSortedCalls() []struct {
-	Ctx   context.Context
-	Batch uuid.UUID
+//	len(mockedQuerier.RenewBatchRowLeasesCalls())
+func (mock *QuerierMock) RenewBatchRowLeasesCalls() []struct {
+	Ctx context.Context
+	Arg batchsqlc.RenewBatchRowLeasesParams
 } {
 	var calls []struct {
-		Ctx   context.Context
-		Batch uuid.UUID
+		Ctx context.Context
+		Arg batchsqlc.RenewBatchRowLeasesParams
 	}
-	mock.lockGetProcessedBatchRowsByBatchIDSorted.RLock()
-	calls = mock.calls.GetProcessedBatchRowsByBatchIDSorted
-	mock.lockGetProcessedBatchRowsByBatchIDSorted.RUnlock()
+	mock.lockRenewBatchRowLeases.RLock()
+	calls = mock.calls.RenewBatchRowLeases
+	mock.lockRenewBatchRowLeases.RUnlock()
 	return calls
 }
 
-// GetUnsummarizedBatches calls GetUnsummarizedBatchesFunc.
-func (mock *QuerierMock) GetUnsummarizedBatches(ctx context.Context) ([]uuid.UUID, error) {
-	if mock.GetUnsummarizedBatchesFunc == nil {
-		panic("QuerierMock.GetUnsummarizedBatchesFunc: method is nil but Querier.GetUnsummarizedBatches was just called")
+// ReopenBatch calls ReopenBatchFunc.
+func (mock *QuerierMock) ReopenBatch(ctx context.Context, id uuid.UUID) error {
+	if mock.ReopenBatchFunc == nil {
+		panic("QuerierMock.ReopenBatchFunc: method is nil but Querier.ReopenBatch was just called")
 	}
 	callInfo := struct {
 		Ctx context.Context
+		ID  uuid.UUID
 	}{
 		Ctx: ctx,
+		ID:  id,
 	}
-	mock.lockGetUnsummarizedBatches.Lock()
-	mock.calls.GetUnsummarizedBatches = append(mock.calls.GetUnsummarizedBatches, callInfo)
-	mock.lockGetUnsummarizedBatches.Unlock()
-	return mock.GetUnsummarizedBatchesFunc(ctx)
+	mock.lockReopenBatch.Lock()
+	mock.calls.ReopenBatch = append(mock.calls.ReopenBatch, callInfo)
+	mock.lockReopenBatch.Unlock()
+	return mock.ReopenBatchFunc(ctx, id)
 }
 
-// GetUnsummarizedBatchesCalls gets all the calls that were made to GetUnsummarizedBatches.
+// ReopenBatchCalls gets all the calls that were made to ReopenBatch.
 // Check the length with:
 //
-//	len(mockedQuerier.GetUnsummarizedBatchesCalls())
-func (mock *QuerierMock) GetUnsummarizedBatchesCalls() []struct {
+//	len(mockedQuerier.ReopenBatchCalls())
+func (mock *QuerierMock) ReopenBatchCalls() []struct {
 	Ctx context.Context
+	ID  uuid.UUID
 } {
 	var calls []struct {
 		Ctx context.Context
+		ID  uuid.UUID
 	}
-	mock.lockGetUnsummarizedBatches.RLock()
-	calls = mock.calls.GetUnsummarizedBatches
-	mock.lockGetUnsummarizedBatches.RUnlock()
+	mock.lockReopenBatch.RLock()
+	calls = mock.calls.ReopenBatch
+	mock.lockReopenBatch.RUnlock()
 	return calls
 }
 
-// InsertBatchFile calls InsertBatchFileFunc.
-func (mock *QuerierMock) InsertBatchFile(ctx context.Context, arg batchsqlc.InsertBatchFileParams) error {
-	if mock.InsertBatchFileFunc == nil {
-		panic("QuerierMock.InsertBatchFileFunc: method is nil but Querier.InsertBatchFile was just called")
+// RequeueBatchRowForRetry calls RequeueBatchRowForRetryFunc.
+func (mock *QuerierMock) RequeueBatchRowForRetry(ctx context.Context, arg batchsqlc.RequeueBatchRowForRetryParams) error {
+	if mock.RequeueBatchRowForRetryFunc == nil {
+		panic("QuerierMock.RequeueBatchRowForRetryFunc: method is nil but Querier.RequeueBatchRowForRetry was just called")
 	}
 	callInfo := struct {
 		Ctx context.Context
-		Arg batchsqlc.InsertBatchFileParams
+		Arg batchsqlc.RequeueBatchRowForRetryParams
 	}{
 		Ctx: ctx,
 		Arg: arg,
 	}
-	mock.lockInsertBatchFile.Lock()
-	mock.calls.InsertBatchFile = append(mock.calls.InsertBatchFile, callInfo)
-	mock.lockInsertBatchFile.Unlock()
-	return mock.InsertBatchFileFunc(ctx, arg)
+	mock.lockRequeueBatchRowForRetry.Lock()
+	mock.calls.RequeueBatchRowForRetry = append(mock.calls.RequeueBatchRowForRetry, callInfo)
+	mock.lockRequeueBatchRowForRetry.Unlock()
+	return mock.RequeueBatchRowForRetryFunc(ctx, arg)
 }
 
-// InsertBatchFileCalls gets all the calls that were made to InsertBatchFile.
+// RequeueBatchRowForRetryCalls gets all the calls that were made to RequeueBatchRowForRetry.
 // Check the length with:
 //
-//	len(mockedQuerier.InsertBatchFileCalls())
-func (mock *QuerierMock) InsertBatchFileCalls() []struct {
+//	len(mockedQuerier.RequeueBatchRowForRetryCalls())
+func (mock *QuerierMock) RequeueBatchRowForRetryCalls() []struct {
 	Ctx context.Context
-	Arg batchsqlc.InsertBatchFileParams
+	Arg batchsqlc.RequeueBatchRowForRetryParams
 } {
 	var calls []struct {
 		Ctx context.Context
-		Arg batchsqlc.InsertBatchFileParams
+		Arg batchsqlc.RequeueBatchRowForRetryParams
 	}
-	mock.lockInsertBatchFile.RLock()
-	calls = mock.calls.InsertBatchFile
-	mock.lockInsertBatchFile.RUnlock()
+	mock.lockRequeueBatchRowForRetry.RLock()
+	calls = mock.calls.RequeueBatchRowForRetry
+	mock.lockRequeueBatchRowForRetry.RUnlock()
 	return calls
 }
 
-// InsertIntoBatchRows calls InsertIntoBatchRowsFunc.
-func (mock *QuerierMock) InsertIntoBatchRows(ctx context.Context, arg batchsqlc.InsertIntoBatchRowsParams) error {
-	if mock.InsertIntoBatchRowsFunc == nil {
-		panic("QuerierMock.InsertIntoBatchRowsFunc: method is nil but Querier.InsertIntoBatchRows was just called")
+// RequeueBatchRowsForRerun calls RequeueBatchRowsForRerunFunc.
+func (mock *QuerierMock) RequeueBatchRowsForRerun(ctx context.Context, rowids []int64) error {
+	if mock.RequeueBatchRowsForRerunFunc == nil {
+		panic("QuerierMock.RequeueBatchRowsForRerunFunc: method is nil but Querier.RequeueBatchRowsForRerun was just called")
 	}
 	callInfo := struct {
-		Ctx context.Context
-		Arg batchsqlc.InsertIntoBatchRowsParams
+		Ctx    context.Context
+		Rowids []int64
 	}{
-		Ctx: ctx,
-		Arg: arg,
+		Ctx:    ctx,
+		Rowids: rowids,
 	}
-	mock.lockInsertIntoBatchRows.Lock()
-	mock.calls.InsertIntoBatchRows = append(mock.calls.InsertIntoBatchRows, callInfo)
-	mock.lockInsertIntoBatchRows.Unlock()
-	return mock.InsertIntoBatchRowsFunc(ctx, arg)
+	mock.lockRequeueBatchRowsForRerun.Lock()
+	mock.calls.RequeueBatchRowsForRerun = append(mock.calls.RequeueBatchRowsForRerun, callInfo)
+	mock.lockRequeueBatchRowsForRerun.Unlock()
+	return mock.RequeueBatchRowsForRerunFunc(ctx, rowids)
 }
 
-// InsertIntoBatchRowsCalls gets all the calls that were made to InsertIntoBatchRows.
+// RequeueBatchRowsForRerunCalls gets all the calls that were made to RequeueBatchRowsForRerun.
 // Check the length with:
 //
-//	len(mockedQuerier.InsertIntoBatchRowsCalls())
-func (mock *QuerierMock) InsertIntoBatchRowsCalls() []struct {
-	Ctx context.Context
-	Arg batchsqlc.InsertIntoBatchRowsParams
+//	len(mockedQuerier.RequeueBatchRowsForRerunCalls())
+func (mock *QuerierMock) RequeueBatchRowsForRerunCalls() []struct {
+	Ctx    context.Context
+	Rowids []int64
 } {
 	var calls []struct {
-		Ctx context.Context
-		Arg batchsqlc.InsertIntoBatchRowsParams
+		Ctx    context.Context
+		Rowids []int64
 	}
-	mock.lockInsertIntoBatchRows.RLock()
-	calls = mock.calls.InsertIntoBatchRows
-	mock.lockInsertIntoBatchRows.RUnlock()
+	mock.lockRequeueBatchRowsForRerun.RLock()
+	calls = mock.calls.RequeueBatchRowsForRerun
+	mock.lockRequeueBatchRowsForRerun.RUnlock()
 	return calls
 }
 
-// InsertIntoBatches calls InsertIntoBatchesFunc.
-func (mock *QuerierMock) InsertIntoBatches(ctx context.Context, arg batchsqlc.InsertIntoBatchesParams) (uuid.UUID, error) {
-	if mock.InsertIntoBatchesFunc == nil {
-		panic("QuerierMock.InsertIntoBatchesFunc: method is nil but Querier.InsertIntoBatches was just called")
+// ResetExpiredLeasesToQueued calls ResetExpiredLeasesToQueuedFunc.
+func (mock *QuerierMock) ResetExpiredLeasesToQueued(ctx context.Context, now pgtype.Timestamp) (int64, error) {
+	if mock.ResetExpiredLeasesToQueuedFunc == nil {
+		panic("QuerierMock.ResetExpiredLeasesToQueuedFunc: method is nil but Querier.ResetExpiredLeasesToQueued was just called")
 	}
 	callInfo := struct {
 		Ctx context.Context
-		Arg batchsqlc.InsertIntoBatchesParams
+		Now pgtype.Timestamp
 	}{
 		Ctx: ctx,
-		Arg: arg,
+		Now: now,
 	}
-	mock.lockInsertIntoBatches.Lock()
-	mock.calls.InsertIntoBatches = append(mock.calls.InsertIntoBatches, callInfo)
-	mock.lockInsertIntoBatches.Unlock()
-	return mock.InsertIntoBatchesFunc(ctx, arg)
+	mock.lockResetExpiredLeasesToQueued.Lock()
+	mock.calls.ResetExpiredLeasesToQueued = append(mock.calls.ResetExpiredLeasesToQueued, callInfo)
+	mock.lockResetExpiredLeasesToQueued.Unlock()
+	return mock.ResetExpiredLeasesToQueuedFunc(ctx, now)
 }
 
-// InsertIntoBatchesCalls gets all the calls that were made to InsertIntoBatches.
+// ResetExpiredLeasesToQueuedCalls gets all the calls that were made to ResetExpiredLeasesToQueued.
 // Check the length with:
 //
-//	len(mockedQuerier.InsertIntoBatchesCalls())
-func (mock *QuerierMock) InsertIntoBatchesCalls() []struct {
+//	len(mockedQuerier.ResetExpiredLeasesToQueuedCalls())
+func (mock *QuerierMock) ResetExpiredLeasesToQueuedCalls() []struct {
 	Ctx context.Context
-	Arg batchsqlc.InsertIntoBatchesParams
+	Now pgtype.Timestamp
 } {
 	var calls []struct {
 		Ctx context.Context
-		Arg batchsqlc.InsertIntoBatchesParams
+		Now pgtype.Timestamp
 	}
-	mock.lockInsertIntoBatches.RLock()
-	calls = mock.calls.InsertIntoBatches
-	mock.lockInsertIntoBatches.RUnlock()
+	mock.lockResetExpiredLeasesToQueued.RLock()
+	calls = mock.calls.ResetExpiredLeasesToQueued
+	mock.lockResetExpiredLeasesToQueued.RUnlock()
 	return calls
 }
 
@@ -1244,6 +3429,114 @@ func (mock *QuerierMock) ResetRowsToQueuedCalls() []struct {
 	return calls
 }
 
+// ResetWorkerRowsToQueued calls ResetWorkerRowsToQueuedFunc.
+func (mock *QuerierMock) ResetWorkerRowsToQueued(ctx context.Context, worker string) (int64, error) {
+	if mock.ResetWorkerRowsToQueuedFunc == nil {
+		panic("QuerierMock.ResetWorkerRowsToQueuedFunc: method is nil but Querier.ResetWorkerRowsToQueued was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Worker string
+	}{
+		Ctx:    ctx,
+		Worker: worker,
+	}
+	mock.lockResetWorkerRowsToQueued.Lock()
+	mock.calls.ResetWorkerRowsToQueued = append(mock.calls.ResetWorkerRowsToQueued, callInfo)
+	mock.lockResetWorkerRowsToQueued.Unlock()
+	return mock.ResetWorkerRowsToQueuedFunc(ctx, worker)
+}
+
+// ResetWorkerRowsToQueuedCalls gets all the calls that were made to ResetWorkerRowsToQueued.
+// Check the length with:
+//
+//	len(mockedQuerier.ResetWorkerRowsToQueuedCalls())
+func (mock *QuerierMock) ResetWorkerRowsToQueuedCalls() []struct {
+	Ctx    context.Context
+	Worker string
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Worker string
+	}
+	mock.lockResetWorkerRowsToQueued.RLock()
+	calls = mock.calls.ResetWorkerRowsToQueued
+	mock.lockResetWorkerRowsToQueued.RUnlock()
+	return calls
+}
+
+// ResumeBatch calls ResumeBatchFunc.
+func (mock *QuerierMock) ResumeBatch(ctx context.Context, id uuid.UUID) (string, error) {
+	if mock.ResumeBatchFunc == nil {
+		panic("QuerierMock.ResumeBatchFunc: method is nil but Querier.ResumeBatch was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		ID  uuid.UUID
+	}{
+		Ctx: ctx,
+		ID:  id,
+	}
+	mock.lockResumeBatch.Lock()
+	mock.calls.ResumeBatch = append(mock.calls.ResumeBatch, callInfo)
+	mock.lockResumeBatch.Unlock()
+	return mock.ResumeBatchFunc(ctx, id)
+}
+
+// ResumeBatchCalls gets all the calls that were made to ResumeBatch.
+// Check the length with:
+//
+//	len(mockedQuerier.ResumeBatchCalls())
+func (mock *QuerierMock) ResumeBatchCalls() []struct {
+	Ctx context.Context
+	ID  uuid.UUID
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  uuid.UUID
+	}
+	mock.lockResumeBatch.RLock()
+	calls = mock.calls.ResumeBatch
+	mock.lockResumeBatch.RUnlock()
+	return calls
+}
+
+// SetBatchParent calls SetBatchParentFunc.
+func (mock *QuerierMock) SetBatchParent(ctx context.Context, arg batchsqlc.SetBatchParentParams) error {
+	if mock.SetBatchParentFunc == nil {
+		panic("QuerierMock.SetBatchParentFunc: method is nil but Querier.SetBatchParent was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Arg batchsqlc.SetBatchParentParams
+	}{
+		Ctx: ctx,
+		Arg: arg,
+	}
+	mock.lockSetBatchParent.Lock()
+	mock.calls.SetBatchParent = append(mock.calls.SetBatchParent, callInfo)
+	mock.lockSetBatchParent.Unlock()
+	return mock.SetBatchParentFunc(ctx, arg)
+}
+
+// SetBatchParentCalls gets all the calls that were made to SetBatchParent.
+// Check the length with:
+//
+//	len(mockedQuerier.SetBatchParentCalls())
+func (mock *QuerierMock) SetBatchParentCalls() []struct {
+	Ctx context.Context
+	Arg batchsqlc.SetBatchParentParams
+} {
+	var calls []struct {
+		Ctx context.Context
+		Arg batchsqlc.SetBatchParentParams
+	}
+	mock.lockSetBatchParent.RLock()
+	calls = mock.calls.SetBatchParent
+	mock.lockSetBatchParent.RUnlock()
+	return calls
+}
+
 // TryAdvisoryLockBatch calls TryAdvisoryLockBatchFunc.
 func (mock *QuerierMock) TryAdvisoryLockBatch(ctx context.Context, dollar_1 string) (bool, error) {
 	if mock.TryAdvisoryLockBatchFunc == nil {
@@ -1388,6 +3681,42 @@ func (mock *QuerierMock) UpdateBatchResultCalls() []struct {
 	return calls
 }
 
+// UpdateBatchRowInput calls UpdateBatchRowInputFunc.
+func (mock *QuerierMock) UpdateBatchRowInput(ctx context.Context, arg batchsqlc.UpdateBatchRowInputParams) error {
+	if mock.UpdateBatchRowInputFunc == nil {
+		panic("QuerierMock.UpdateBatchRowInputFunc: method is nil but Querier.UpdateBatchRowInput was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Arg batchsqlc.UpdateBatchRowInputParams
+	}{
+		Ctx: ctx,
+		Arg: arg,
+	}
+	mock.lockUpdateBatchRowInput.Lock()
+	mock.calls.UpdateBatchRowInput = append(mock.calls.UpdateBatchRowInput, callInfo)
+	mock.lockUpdateBatchRowInput.Unlock()
+	return mock.UpdateBatchRowInputFunc(ctx, arg)
+}
+
+// UpdateBatchRowInputCalls gets all the calls that were made to UpdateBatchRowInput.
+// Check the length with:
+//
+//	len(mockedQuerier.UpdateBatchRowInputCalls())
+func (mock *QuerierMock) UpdateBatchRowInputCalls() []struct {
+	Ctx context.Context
+	Arg batchsqlc.UpdateBatchRowInputParams
+} {
+	var calls []struct {
+		Ctx context.Context
+		Arg batchsqlc.UpdateBatchRowInputParams
+	}
+	mock.lockUpdateBatchRowInput.RLock()
+	calls = mock.calls.UpdateBatchRowInput
+	mock.lockUpdateBatchRowInput.RUnlock()
+	return calls
+}
+
 // UpdateBatchRowStatus calls UpdateBatchRowStatusFunc.
 func (mock *QuerierMock) UpdateBatchRowStatus(ctx context.Context, arg batchsqlc.UpdateBatchRowStatusParams) error {
 	if mock.UpdateBatchRowStatusFunc == nil {
@@ -1676,6 +4005,42 @@ func (mock *QuerierMock) UpdateBatchRowsStatusBulkCalls() []struct {
 	return calls
 }
 
+// UpdateBatchScheduleFired calls UpdateBatchScheduleFiredFunc.
+func (mock *QuerierMock) UpdateBatchScheduleFired(ctx context.Context, arg batchsqlc.UpdateBatchScheduleFiredParams) error {
+	if mock.UpdateBatchScheduleFiredFunc == nil {
+		panic("QuerierMock.UpdateBatchScheduleFiredFunc: method is nil but Querier.UpdateBatchScheduleFired was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Arg batchsqlc.UpdateBatchScheduleFiredParams
+	}{
+		Ctx: ctx,
+		Arg: arg,
+	}
+	mock.lockUpdateBatchScheduleFired.Lock()
+	mock.calls.UpdateBatchScheduleFired = append(mock.calls.UpdateBatchScheduleFired, callInfo)
+	mock.lockUpdateBatchScheduleFired.Unlock()
+	return mock.UpdateBatchScheduleFiredFunc(ctx, arg)
+}
+
+// UpdateBatchScheduleFiredCalls gets all the calls that were made to UpdateBatchScheduleFired.
+// Check the length with:
+//
+//	len(mockedQuerier.UpdateBatchScheduleFiredCalls())
+func (mock *QuerierMock) UpdateBatchScheduleFiredCalls() []struct {
+	Ctx context.Context
+	Arg batchsqlc.UpdateBatchScheduleFiredParams
+} {
+	var calls []struct {
+		Ctx context.Context
+		Arg batchsqlc.UpdateBatchScheduleFiredParams
+	}
+	mock.lockUpdateBatchScheduleFired.RLock()
+	calls = mock.calls.UpdateBatchScheduleFired
+	mock.lockUpdateBatchScheduleFired.RUnlock()
+	return calls
+}
+
 // UpdateBatchStatus calls UpdateBatchStatusFunc.
 func (mock *QuerierMock) UpdateBatchStatus(ctx context.Context, arg batchsqlc.UpdateBatchStatusParams) error {
 	if mock.UpdateBatchStatusFunc == nil {
@@ -1819,3 +4184,39 @@ func (mock *QuerierMock) UpdateBatchesStatusBulkCalls() []struct {
 	mock.lockUpdateBatchesStatusBulk.RUnlock()
 	return calls
 }
+
+// UpsertBatchSchedule calls UpsertBatchScheduleFunc.
+func (mock *QuerierMock) UpsertBatchSchedule(ctx context.Context, arg batchsqlc.UpsertBatchScheduleParams) error {
+	if mock.UpsertBatchScheduleFunc == nil {
+		panic("QuerierMock.UpsertBatchScheduleFunc: method is nil but Querier.UpsertBatchSchedule was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Arg batchsqlc.UpsertBatchScheduleParams
+	}{
+		Ctx: ctx,
+		Arg: arg,
+	}
+	mock.lockUpsertBatchSchedule.Lock()
+	mock.calls.UpsertBatchSchedule = append(mock.calls.UpsertBatchSchedule, callInfo)
+	mock.lockUpsertBatchSchedule.Unlock()
+	return mock.UpsertBatchScheduleFunc(ctx, arg)
+}
+
+// UpsertBatchScheduleCalls gets all the calls that were made to UpsertBatchSchedule.
+// Check the length with:
+//
+//	len(mockedQuerier.UpsertBatchScheduleCalls())
+func (mock *QuerierMock) UpsertBatchScheduleCalls() []struct {
+	Ctx context.Context
+	Arg batchsqlc.UpsertBatchScheduleParams
+} {
+	var calls []struct {
+		Ctx context.Context
+		Arg batchsqlc.UpsertBatchScheduleParams
+	}
+	mock.lockUpsertBatchSchedule.RLock()
+	calls = mock.calls.UpsertBatchSchedule
+	mock.lockUpsertBatchSchedule.RUnlock()
+	return calls
+}
@@ -12,6 +12,49 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type DeliveryStatusEnum string
+
+const (
+	DeliveryStatusEnumPending   DeliveryStatusEnum = "pending"
+	DeliveryStatusEnumDelivered DeliveryStatusEnum = "delivered"
+	DeliveryStatusEnumFailed    DeliveryStatusEnum = "failed"
+)
+
+func (e *DeliveryStatusEnum) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = DeliveryStatusEnum(s)
+	case string:
+		*e = DeliveryStatusEnum(s)
+	default:
+		return fmt.Errorf("unsupported scan type for DeliveryStatusEnum: %T", src)
+	}
+	return nil
+}
+
+type NullDeliveryStatusEnum struct {
+	DeliveryStatusEnum DeliveryStatusEnum `json:"delivery_status_enum"`
+	Valid              bool               `json:"valid"` // Valid is true if DeliveryStatusEnum is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullDeliveryStatusEnum) Scan(value interface{}) error {
+	if value == nil {
+		ns.DeliveryStatusEnum, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.DeliveryStatusEnum.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullDeliveryStatusEnum) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.DeliveryStatusEnum), nil
+}
+
 type StatusEnum string
 
 const (
@@ -21,6 +64,7 @@ const (
 	StatusEnumFailed  StatusEnum = "failed"
 	StatusEnumAborted StatusEnum = "aborted"
 	StatusEnumWait    StatusEnum = "wait"
+	StatusEnumPaused  StatusEnum = "paused"
 )
 
 func (e *StatusEnum) Scan(src interface{}) error {
@@ -72,6 +116,18 @@ type Batch struct {
 	Nfailed     pgtype.Int4      `json:"nfailed"`
 	Naborted    pgtype.Int4      `json:"naborted"`
 	CreatedAt   pgtype.Timestamp `json:"created_at"`
+	Runat       pgtype.Timestamp `json:"runat"`
+	Priority    int32            `json:"priority"`
+	CallbackUrl pgtype.Text      `json:"callback_url"`
+	ParentID    pgtype.UUID      `json:"parent_id"`
+	Outputtypes []byte           `json:"outputtypes"`
+}
+
+type BatchDependency struct {
+	Batch           uuid.UUID `json:"batch"`
+	Parent          uuid.UUID `json:"parent"`
+	Name            string    `json:"name"`
+	OnParentFailure string    `json:"on_parent_failure"`
 }
 
 // Stores metadata for files associated with batch jobs
@@ -103,17 +159,74 @@ type BatchFile struct {
 	CreatedAt pgtype.Timestamp `json:"created_at"`
 }
 
-type Batchrow struct {
-	Rowid     int64            `json:"rowid"`
+type BatchIdempotencyKey struct {
+	App       string           `json:"app"`
+	Key       string           `json:"key"`
 	Batch     uuid.UUID        `json:"batch"`
-	Line      int32            `json:"line"`
-	Input     []byte           `json:"input"`
-	Status    StatusEnum       `json:"status"`
-	Reqat     pgtype.Timestamp `json:"reqat"`
-	Doneat    pgtype.Timestamp `json:"doneat"`
-	Res       []byte           `json:"res"`
-	Blobrows  []byte           `json:"blobrows"`
-	Messages  []byte           `json:"messages"`
-	Doneby    pgtype.Text      `json:"doneby"`
 	CreatedAt pgtype.Timestamp `json:"created_at"`
 }
+
+type BatchSchedule struct {
+	Name       string           `json:"name"`
+	App        string           `json:"app"`
+	Op         string           `json:"op"`
+	Cron       string           `json:"cron"`
+	LastFireat pgtype.Timestamp `json:"last_fireat"`
+	LastBatch  pgtype.UUID      `json:"last_batch"`
+	CreatedAt  pgtype.Timestamp `json:"created_at"`
+}
+
+type Batchrow struct {
+	Rowid       int64            `json:"rowid"`
+	Batch       uuid.UUID        `json:"batch"`
+	Line        int32            `json:"line"`
+	Input       []byte           `json:"input"`
+	Status      StatusEnum       `json:"status"`
+	Reqat       pgtype.Timestamp `json:"reqat"`
+	Doneat      pgtype.Timestamp `json:"doneat"`
+	Res         []byte           `json:"res"`
+	Blobrows    []byte           `json:"blobrows"`
+	Messages    []byte           `json:"messages"`
+	Doneby      pgtype.Text      `json:"doneby"`
+	CreatedAt   pgtype.Timestamp `json:"created_at"`
+	Attempts    int32            `json:"attempts"`
+	NotBefore   pgtype.Timestamp `json:"not_before"`
+	Worker      pgtype.Text      `json:"worker"`
+	LeasedUntil pgtype.Timestamp `json:"leased_until"`
+	Priority    int32            `json:"priority"`
+}
+
+type BatchrowHistory struct {
+	ID          int64            `json:"id"`
+	Rowid       int64            `json:"rowid"`
+	Batch       uuid.UUID        `json:"batch"`
+	Line        int32            `json:"line"`
+	Input       []byte           `json:"input"`
+	Status      StatusEnum       `json:"status"`
+	Doneat      pgtype.Timestamp `json:"doneat"`
+	Res         []byte           `json:"res"`
+	Blobrows    []byte           `json:"blobrows"`
+	Messages    []byte           `json:"messages"`
+	Doneby      pgtype.Text      `json:"doneby"`
+	Outputfiles []byte           `json:"outputfiles"`
+	ArchivedAt  pgtype.Timestamp `json:"archived_at"`
+}
+
+type WebhookDelivery struct {
+	ID             int64              `json:"id"`
+	Batch          uuid.UUID          `json:"batch"`
+	Url            string             `json:"url"`
+	Payload        []byte             `json:"payload"`
+	Status         DeliveryStatusEnum `json:"status"`
+	Attempts       int32              `json:"attempts"`
+	NextAttemptAt  pgtype.Timestamp   `json:"next_attempt_at"`
+	LastHttpStatus pgtype.Int4        `json:"last_http_status"`
+	LastError      pgtype.Text        `json:"last_error"`
+	CreatedAt      pgtype.Timestamp   `json:"created_at"`
+	DeliveredAt    pgtype.Timestamp   `json:"delivered_at"`
+}
+
+type Worker struct {
+	InstanceID string           `json:"instance_id"`
+	Heartbeat  pgtype.Timestamp `json:"heartbeat"`
+}
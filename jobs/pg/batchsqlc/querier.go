@@ -8,34 +8,156 @@ import (
 	"context"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type Querier interface {
+	// Copies the results of the rows, and the output files of their batch, to
+	// batchrow_history before the rows are re-run.
+	ArchiveBatchRows(ctx context.Context, rowids []int64) error
 	BulkInsertIntoBatchRows(ctx context.Context, arg BulkInsertIntoBatchRowsParams) (int64, error)
+	// Records @key for @batch, unless the app used it for another batch at or after
+	// @cutoff, in which case no row is returned. A concurrent claim of the same key
+	// waits for the first one to commit or roll back.
+	ClaimIdempotencyKey(ctx context.Context, arg ClaimIdempotencyKeyParams) (uuid.UUID, error)
+	// Takes up to limit pending deliveries that are due, and leases them to the caller
+	// by moving their next attempt to lease_until. A delivery whose caller dies before
+	// recording the outcome is tried again once the lease has run out.
+	ClaimWebhookDeliveries(ctx context.Context, arg ClaimWebhookDeliveriesParams) ([]ClaimWebhookDeliveriesRow, error)
+	// Loads batch rows with the COPY protocol, for batches too large to insert in one statement.
+	// The rows are queued through the column default of status.
+	CopyBatchRows(ctx context.Context, arg []CopyBatchRowsParams) (int64, error)
+	CountBatchDependencies(ctx context.Context, batch uuid.UUID) (int64, error)
 	CountBatchRowsByBatchIDAndStatus(ctx context.Context, arg CountBatchRowsByBatchIDAndStatusParams) (int64, error)
 	CountBatchRowsInProgByBatchID(ctx context.Context, batch uuid.UUID) (int64, error)
 	CountBatchRowsQueuedByBatchID(ctx context.Context, batch uuid.UUID) (int64, error)
+	// Counts what GetBatchesToPurge would purge in all, for dry runs.
+	CountBatchesToPurge(ctx context.Context, arg CountBatchesToPurgeParams) (CountBatchesToPurgeRow, error)
+	DeleteBatchFilesByBatchIDs(ctx context.Context, batchIds []uuid.UUID) error
+	DeleteBatchRowsByBatchIDs(ctx context.Context, batchIds []uuid.UUID) error
+	DeleteBatchesByIDs(ctx context.Context, ids []uuid.UUID) (int64, error)
+	// Removes a JobManager instance from the worker registry.
+	DeleteWorker(ctx context.Context, instanceID string) error
+	// Only for a batchrows table partitioned by MigrateBatchRowsPartitioned.
+	DropBatchRowPartitions(ctx context.Context, cutoff pgtype.Timestamp) ([]string, error)
+	// Only for a batchrows table partitioned by MigrateBatchRowsPartitioned.
+	EnsureBatchRowPartitions(ctx context.Context, monthsAhead int32) ([]string, error)
 	FetchBatchRowsForBatchDone(ctx context.Context, batch uuid.UUID) ([]FetchBatchRowsForBatchDoneRow, error)
+	// Rows requeued for retry are skipped until their not_before time has passed,
+	// and rows of a delayed batch until the batch's runat time has passed.
+	// Rows of higher priority batches are returned first, oldest rows first
+	// within the same priority. The priority of the batch is copied onto its rows,
+	// so that the rows are read in this order from idx_batchrows_fetch.
+	// The bound on reqat lets a batchrows table partitioned by month skip the
+	// partitions older than any pending batch; the day of slack allows for clock
+	// steps between the insert of a batch and of its rows.
 	FetchBlockOfRows(ctx context.Context, arg FetchBlockOfRowsParams) ([]FetchBlockOfRowsRow, error)
+	// Fair-share variant of FetchBlockOfRows. Up to limit eligible rows are locked
+	// per app, highest priority first, and the block is then filled round-robin
+	// across apps: in every round an app contributes as many rows as its weight.
+	// Apps not listed in weight_apps have weight 1. Locked rows that do not make
+	// it into the block are released when the transaction ends.
+	FetchBlockOfRowsFair(ctx context.Context, arg FetchBlockOfRowsFairParams) ([]FetchBlockOfRowsFairRow, error)
 	GetBatchByID(ctx context.Context, id uuid.UUID) (Batch, error)
+	// Counts the rows of a batch by status, with the time the first of them was done.
+	GetBatchProgress(ctx context.Context, batch uuid.UUID) (GetBatchProgressRow, error)
+	GetBatchRowHistory(ctx context.Context, batch uuid.UUID) ([]BatchrowHistory, error)
 	GetBatchRowsByBatchID(ctx context.Context, batch uuid.UUID) ([]Batchrow, error)
 	GetBatchRowsByBatchIDSorted(ctx context.Context, batch uuid.UUID) ([]GetBatchRowsByBatchIDSortedRow, error)
 	GetBatchRowsCount(ctx context.Context, batch uuid.UUID) (int64, error)
+	// Locks the rows of a batch in one of statuses, and on one of lines if any are given,
+	// for BatchRetryFailed.
+	GetBatchRowsForRerun(ctx context.Context, arg GetBatchRowsForRerunParams) ([]GetBatchRowsForRerunRow, error)
+	// Returns a page of the rows of a batch, in the order of their lines, for archiving
+	// it. Pages follow each other by keyset, as with GetProcessedBatchRowsPage.
+	GetBatchRowsPage(ctx context.Context, arg GetBatchRowsPageParams) ([]Batchrow, error)
+	GetBatchScheduleForUpdate(ctx context.Context, name string) (GetBatchScheduleForUpdateRow, error)
 	GetBatchStatus(ctx context.Context, id uuid.UUID) (StatusEnum, error)
 	GetBatchStatusAndOutputFiles(ctx context.Context, id uuid.UUID) (GetBatchStatusAndOutputFilesRow, error)
+	// Locks up to @max_batches done batches and slow queries of an app that were done before
+	// @cutoff, oldest first. Batches locked by a concurrent purge are skipped.
+	GetBatchesToPurge(ctx context.Context, arg GetBatchesToPurgeParams) ([]Batch, error)
 	GetCompletedBatches(ctx context.Context) ([]uuid.UUID, error)
+	// Returns the instances of the worker registry whose last heartbeat is older than the cutoff.
+	GetDeadWorkers(ctx context.Context, cutoff pgtype.Timestamp) ([]string, error)
+	GetIdempotencyKeyBatch(ctx context.Context, arg GetIdempotencyKeyBatchParams) (uuid.UUID, error)
+	GetParentBatches(ctx context.Context, batch uuid.UUID) ([]GetParentBatchesRow, error)
 	GetPendingBatchRows(ctx context.Context, batch uuid.UUID) ([]GetPendingBatchRowsRow, error)
-	GetProcessedBatchRowsByBatchIDSorted(ctx context.Context, batch uuid.UUID) ([]GetProcessedBatchRowsByBatchIDSortedRow, error)
+	// Returns a page of the processed rows of a batch, in the order of their lines,
+	// for assembling its output files. Pages follow each other by keyset: pass the
+	// line and rowid of the last row of the previous page as after_line and
+	// after_rowid.
+	GetProcessedBatchRowsPage(ctx context.Context, arg GetProcessedBatchRowsPageParams) ([]GetProcessedBatchRowsPageRow, error)
 	// Finds batches stuck in 'inprog' with doneat=NULL where all rows have reached
 	// terminal status (no queued or inprog rows remain). These batches need
 	// summarization that was missed due to race conditions or failed retries.
 	GetUnsummarizedBatches(ctx context.Context) ([]uuid.UUID, error)
+	GetWaitingDependentBatches(ctx context.Context, parent uuid.UUID) ([]uuid.UUID, error)
+	GetWebhookDeliveriesByBatchID(ctx context.Context, batch uuid.UUID) ([]WebhookDelivery, error)
+	InsertBatchDependency(ctx context.Context, arg InsertBatchDependencyParams) error
 	InsertBatchFile(ctx context.Context, arg InsertBatchFileParams) error
 	InsertIntoBatchRows(ctx context.Context, arg InsertIntoBatchRowsParams) error
 	InsertIntoBatches(ctx context.Context, arg InsertIntoBatchesParams) (uuid.UUID, error)
+	// Queues a delivery of payload to the callback URL of the batch, if it has one.
+	InsertWebhookDelivery(ctx context.Context, arg InsertWebhookDeliveryParams) error
+	// Records the rows picked up by a worker as leased to it until leased_until.
+	LeaseBatchRows(ctx context.Context, arg LeaseBatchRowsParams) error
+	// Lists batch jobs of an app, newest first. Slow queries (batches with a
+	// line 0 row) are excluded. op and status are optional filters. Pagination
+	// is keyset based: pass the reqat and id of the last row of the previous page
+	// as after_reqat and after_id, or NULL for the first page.
+	ListBatches(ctx context.Context, arg ListBatchesParams) ([]ListBatchesRow, error)
+	// Lists slow queries (batches with a single line 0 row) of an app, newest
+	// first. Filters and keyset pagination work the same way as ListBatches.
+	ListSlowQueries(ctx context.Context, arg ListSlowQueriesParams) ([]ListSlowQueriesRow, error)
+	// Keeps the given batches from being summarised until the calling transaction ends,
+	// so that a batch depending on them, inserted in it, cannot miss their completion.
+	LockParentBatches(ctx context.Context, ids []uuid.UUID) ([]uuid.UUID, error)
+	// Records a failed attempt by the caller that claimed the delivery until
+	// leased_until. status stays 'pending' while attempts remain. It does nothing if
+	// the delivery has been claimed again since.
+	MarkWebhookAttemptFailed(ctx context.Context, arg MarkWebhookAttemptFailedParams) (int64, error)
+	// Records a successful attempt by the caller that claimed the delivery until
+	// leased_until. It does nothing if the delivery has been claimed again since.
+	MarkWebhookDelivered(ctx context.Context, arg MarkWebhookDeliveredParams) (int64, error)
+	// Wakes up idle JobManager instances listening on the alya_jobs channel.
+	// The notification is only delivered when the surrounding transaction commits.
+	// A failed notification is returned as its error message and leaves the
+	// transaction usable.
+	NotifyJobsQueued(ctx context.Context, app string) (string, error)
+	PauseBatch(ctx context.Context, id uuid.UUID) (string, error)
+	// Registers a JobManager instance in the worker registry, or refreshes its heartbeat.
+	// Used instead of Redis by JobManagers without a Redis client.
+	RecordWorkerHeartbeat(ctx context.Context, arg RecordWorkerHeartbeatParams) error
+	// Ends the lease of a row once its worker is done with it.
+	ReleaseBatchRowLease(ctx context.Context, arg ReleaseBatchRowLeaseParams) error
+	// Queues a batch whose parents are done, adding them to its context if it is an object.
+	ReleaseDependentBatch(ctx context.Context, arg ReleaseDependentBatchParams) error
+	// Extends the leases of the rows a worker is still processing.
+	RenewBatchRowLeases(ctx context.Context, arg RenewBatchRowLeasesParams) error
+	// Puts a summarised batch back in the queue, to be summarised again once its
+	// requeued rows are done.
+	ReopenBatch(ctx context.Context, id uuid.UUID) error
+	// Puts a row that failed with a retryable error back in the queue. attempts
+	// counts the failed attempts so far; the row is not picked up again before
+	// not_before. Only rows still 'inprog' are requeued, so a row that was
+	// aborted in the meantime stays aborted.
+	RequeueBatchRowForRetry(ctx context.Context, arg RequeueBatchRowForRetryParams) error
+	// Puts rows back in the queue as if they had never been processed.
+	RequeueBatchRowsForRerun(ctx context.Context, rowids []int64) error
+	// Resets the 'inprog' rows whose lease has expired and whose worker is no longer
+	// registered to 'queued', for recovery of the rows of workers that were removed from
+	// the registry, such as by Shutdown, with rows still leased to them.
+	ResetExpiredLeasesToQueued(ctx context.Context, now pgtype.Timestamp) (int64, error)
 	// Resets rows with status 'inprog' to 'queued' for recovery of abandoned rows.
 	// Only resets rows that are currently in 'inprog' status to avoid race conditions.
 	ResetRowsToQueued(ctx context.Context, dollar_1 []int64) error
+	// Resets the rows of a dead worker that are still 'inprog' to 'queued', for recovery
+	// of abandoned rows. Rows it finished or that were reset already are left alone.
+	ResetWorkerRowsToQueued(ctx context.Context, worker string) (int64, error)
+	// The batch is queued again; picking up its rows puts it back in progress.
+	ResumeBatch(ctx context.Context, id uuid.UUID) (string, error)
+	SetBatchParent(ctx context.Context, arg SetBatchParentParams) error
 	// Attempts to acquire a transaction-scoped advisory lock for a batch.
 	// Returns true if lock was acquired, false if another session holds it.
 	// The lock is automatically released when the transaction commits or rolls back.
@@ -44,6 +166,7 @@ type Querier interface {
 	UpdateBatchCounters(ctx context.Context, arg UpdateBatchCountersParams) error
 	UpdateBatchOutputFiles(ctx context.Context, arg UpdateBatchOutputFilesParams) error
 	UpdateBatchResult(ctx context.Context, arg UpdateBatchResultParams) error
+	UpdateBatchRowInput(ctx context.Context, arg UpdateBatchRowInputParams) error
 	UpdateBatchRowStatus(ctx context.Context, arg UpdateBatchRowStatusParams) error
 	UpdateBatchRowsBatchJob(ctx context.Context, arg UpdateBatchRowsBatchJobParams) error
 	UpdateBatchRowsByBatchAndStatus(ctx context.Context, arg UpdateBatchRowsByBatchAndStatusParams) error
@@ -55,6 +178,7 @@ type Querier interface {
 	// This significantly improves performance when processing multiple rows
 	// by reducing database round trips from N queries to 1 query.
 	UpdateBatchRowsStatusBulk(ctx context.Context, arg UpdateBatchRowsStatusBulkParams) error
+	UpdateBatchScheduleFired(ctx context.Context, arg UpdateBatchScheduleFiredParams) error
 	UpdateBatchStatus(ctx context.Context, arg UpdateBatchStatusParams) error
 	UpdateBatchSummary(ctx context.Context, arg UpdateBatchSummaryParams) error
 	UpdateBatchSummaryOnAbort(ctx context.Context, arg UpdateBatchSummaryOnAbortParams) error
@@ -63,6 +187,9 @@ type Querier interface {
 	// as 'inprog' in a single query instead of updating each one individually.
 	// Only updates batches that are currently 'queued' to prevent unnecessary updates.
 	UpdateBatchesStatusBulk(ctx context.Context, arg UpdateBatchesStatusBulkParams) error
+	// Records a registered schedule. A new schedule starts counting from
+	// last_fireat; for an existing one only the definition is updated.
+	UpsertBatchSchedule(ctx context.Context, arg UpsertBatchScheduleParams) error
 }
 
 var _ Querier = (*Queries)(nil)
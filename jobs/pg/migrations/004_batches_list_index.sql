@@ -0,0 +1,6 @@
+-- For listing batches and slow queries of an app, newest first, with keyset pagination
+CREATE INDEX IF NOT EXISTS idx_batches_app_reqat ON batches(app, reqat DESC, id DESC);
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS idx_batches_app_reqat;
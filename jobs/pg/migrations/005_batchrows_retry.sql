@@ -0,0 +1,9 @@
+-- Retry support for batch rows: number of failed attempts so far, and the
+-- earliest time at which a requeued row may be picked up again
+ALTER TABLE batchrows ADD COLUMN attempts INT NOT NULL DEFAULT 0;
+ALTER TABLE batchrows ADD COLUMN not_before TIMESTAMP WITHOUT TIME ZONE;
+
+---- create above / drop below ----
+
+ALTER TABLE batchrows DROP COLUMN IF EXISTS not_before;
+ALTER TABLE batchrows DROP COLUMN IF EXISTS attempts;
@@ -0,0 +1,19 @@
+-- Delayed batches: rows of a batch are not picked up before batches.runat
+ALTER TABLE batches ADD COLUMN runat TIMESTAMP WITHOUT TIME ZONE;
+
+-- Recurring batch schedules registered with JobManager.RegisterBatchSchedule.
+-- last_fireat is the most recent cron fire time for which a batch was created.
+CREATE TABLE batch_schedules (
+    name VARCHAR(255) NOT NULL PRIMARY KEY,
+    app VARCHAR(255) NOT NULL,
+    op VARCHAR(255) NOT NULL,
+    cron VARCHAR(255) NOT NULL,
+    last_fireat TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+    last_batch UUID REFERENCES batches(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT NOW()
+);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS batch_schedules;
+ALTER TABLE batches DROP COLUMN IF EXISTS runat;
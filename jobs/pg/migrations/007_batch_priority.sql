@@ -0,0 +1,6 @@
+-- Batch priority: rows of higher priority batches are picked up first
+ALTER TABLE batches ADD COLUMN priority INT NOT NULL DEFAULT 0;
+
+---- create above / drop below ----
+
+ALTER TABLE batches DROP COLUMN IF EXISTS priority;
@@ -0,0 +1,7 @@
+-- New batch rows are queued unless stated otherwise, so that they can be
+-- loaded with COPY without spelling out the enum value
+ALTER TABLE batchrows ALTER COLUMN status SET DEFAULT 'queued';
+
+---- create above / drop below ----
+
+ALTER TABLE batchrows ALTER COLUMN status DROP DEFAULT;
@@ -0,0 +1,31 @@
+-- Completion webhooks: an optional callback URL per batch or slow query, and the
+-- outbox of deliveries to it, which is kept as the delivery log
+ALTER TABLE batches ADD COLUMN callback_url TEXT;
+
+CREATE TYPE delivery_status_enum AS ENUM ('pending', 'delivered', 'failed');
+
+CREATE TABLE webhook_deliveries (
+    id BIGSERIAL PRIMARY KEY,
+    batch UUID NOT NULL REFERENCES batches(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    status delivery_status_enum NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+    last_http_status INT,
+    last_error TEXT,
+    created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT NOW(),
+    delivered_at TIMESTAMP WITHOUT TIME ZONE
+);
+
+-- For picking up the deliveries that are due
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+
+-- For listing the deliveries of a batch
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_batch ON webhook_deliveries(batch);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TYPE IF EXISTS delivery_status_enum;
+ALTER TABLE batches DROP COLUMN IF EXISTS callback_url;
@@ -0,0 +1,30 @@
+-- Re-runs of failed rows: the batch a child batch re-runs rows of, and the
+-- results of the earlier attempts of rows that were re-run in their own batch
+ALTER TABLE batches ADD COLUMN parent_id UUID REFERENCES batches(id) ON DELETE SET NULL;
+
+CREATE TABLE batchrow_history (
+    id BIGSERIAL PRIMARY KEY,
+    rowid BIGINT NOT NULL REFERENCES batchrows(rowid) ON DELETE CASCADE,
+    batch UUID NOT NULL REFERENCES batches(id) ON DELETE CASCADE,
+    line INT NOT NULL,
+    input JSONB NOT NULL,
+    status status_enum NOT NULL,
+    doneat TIMESTAMP WITHOUT TIME ZONE,
+    res JSONB,
+    blobrows JSONB,
+    messages JSONB,
+    doneby VARCHAR(255),
+    outputfiles JSONB, -- output files of the batch as summarised with this attempt
+    archived_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- For listing the history of a batch
+CREATE INDEX IF NOT EXISTS idx_batchrow_history_batch ON batchrow_history(batch, line);
+
+-- For the cascade from batchrows
+CREATE INDEX IF NOT EXISTS idx_batchrow_history_rowid ON batchrow_history(rowid);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS batchrow_history;
+ALTER TABLE batches DROP COLUMN IF EXISTS parent_id;
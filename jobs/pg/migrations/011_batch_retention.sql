@@ -0,0 +1,6 @@
+-- For finding the done batches of an app that are due to be purged, oldest first
+CREATE INDEX IF NOT EXISTS idx_batches_app_doneat ON batches(app, doneat) WHERE doneat IS NOT NULL;
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS idx_batches_app_doneat;
@@ -0,0 +1,59 @@
+-- Maintenance of the monthly partitions of batchrows, for installations that have
+-- converted it to a partitioned table with the migrations in partitioned/. The
+-- functions are created everywhere, but are only of use there.
+-- Partitions are named batchrows_YYYYMM and hold the rows whose reqat falls in
+-- that month.
+
+-- Creates the partitions from the current month to months_ahead months ahead
+-- that do not exist yet, and returns their names.
+CREATE OR REPLACE FUNCTION alya_ensure_batchrows_partitions(months_ahead INT)
+RETURNS SETOF TEXT AS $$
+DECLARE
+    month TIMESTAMP := date_trunc('month', LOCALTIMESTAMP);
+    name TEXT;
+BEGIN
+    FOR i IN 0..months_ahead LOOP
+        name := 'batchrows_' || to_char(month, 'YYYYMM');
+        IF to_regclass(name) IS NULL THEN
+            EXECUTE format('CREATE TABLE %I PARTITION OF batchrows FOR VALUES FROM (%L) TO (%L)',
+                name, month, month + INTERVAL '1 month');
+            RETURN NEXT name;
+        END IF;
+        month := month + INTERVAL '1 month';
+    END LOOP;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Drops the partitions that end before cutoff and hold no rows of a batch that
+-- still exists, and returns their names. Rows of purged batches are left in place
+-- until their partition is dropped.
+CREATE OR REPLACE FUNCTION alya_drop_batchrows_partitions(cutoff TIMESTAMP)
+RETURNS SETOF TEXT AS $$
+DECLARE
+    name TEXT;
+    in_use BOOLEAN;
+BEGIN
+    FOR name IN
+        SELECT c.relname::TEXT
+        FROM pg_inherits i
+        JOIN pg_class c ON c.oid = i.inhrelid
+        WHERE i.inhparent = 'batchrows'::regclass
+          AND c.relname ~ '^batchrows_[0-9]{6}$'
+        ORDER BY c.relname
+    LOOP
+        IF to_date(substr(name, 11), 'YYYYMM')::TIMESTAMP + INTERVAL '1 month' > cutoff THEN
+            CONTINUE;
+        END IF;
+        EXECUTE format('SELECT EXISTS (SELECT 1 FROM %I r JOIN batches b ON b.id = r.batch)', name) INTO in_use;
+        IF NOT in_use THEN
+            EXECUTE format('DROP TABLE %I', name);
+            RETURN NEXT name;
+        END IF;
+    END LOOP;
+END;
+$$ LANGUAGE plpgsql;
+
+---- create above / drop below ----
+
+DROP FUNCTION IF EXISTS alya_drop_batchrows_partitions(TIMESTAMP);
+DROP FUNCTION IF EXISTS alya_ensure_batchrows_partitions(INT);
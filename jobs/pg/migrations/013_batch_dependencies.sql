@@ -0,0 +1,17 @@
+-- Batch dependencies: a batch with parents stays in 'wait' until all of them are
+-- done, and is then queued, or aborted if one of them did not succeed and its
+-- policy says so
+CREATE TABLE batch_dependencies (
+    batch UUID NOT NULL REFERENCES batches(id) ON DELETE CASCADE,
+    parent UUID NOT NULL REFERENCES batches(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL, -- key of the parent in the context of the batch
+    on_parent_failure VARCHAR(8) NOT NULL DEFAULT 'abort' CHECK (on_parent_failure IN ('abort', 'run')),
+    PRIMARY KEY (batch, parent)
+);
+
+-- For finding the batches waiting on a batch once it is done
+CREATE INDEX IF NOT EXISTS idx_batch_dependencies_parent ON batch_dependencies(parent);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS batch_dependencies;
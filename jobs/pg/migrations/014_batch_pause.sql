@@ -0,0 +1,7 @@
+-- Paused batches: their queued rows are not picked up until they are resumed
+ALTER TYPE status_enum ADD VALUE IF NOT EXISTS 'paused';
+
+---- create above / drop below ----
+
+-- Values cannot be dropped from an enum; resume paused batches instead
+UPDATE batches SET status = 'queued' WHERE status = 'paused';
@@ -0,0 +1,18 @@
+-- Idempotency keys of submissions: a key is used for one batch or slow query of
+-- an app at a time, so that a repeated submit finds the original one. The
+-- reference to the batch is deferred because the key is claimed before the batch
+-- is inserted.
+CREATE TABLE batch_idempotency_keys (
+    app VARCHAR(255) NOT NULL,
+    key VARCHAR(255) NOT NULL,
+    batch UUID NOT NULL REFERENCES batches(id) ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED,
+    created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+    PRIMARY KEY (app, key)
+);
+
+-- For the cascade from batches
+CREATE INDEX IF NOT EXISTS idx_batch_idempotency_keys_batch ON batch_idempotency_keys(batch);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS batch_idempotency_keys;
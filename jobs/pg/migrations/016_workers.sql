@@ -0,0 +1,17 @@
+-- Worker registry and row leases, used by JobManagers without Redis instead of
+-- the Redis heartbeat keys and rows sets: each instance records its heartbeat in
+-- workers, and the rows it is processing carry its ID and the time until which
+-- they are leased to it.
+CREATE TABLE workers (
+    instance_id VARCHAR(255) PRIMARY KEY,
+    heartbeat TIMESTAMP WITHOUT TIME ZONE NOT NULL
+);
+
+ALTER TABLE batchrows ADD COLUMN worker VARCHAR(255);
+ALTER TABLE batchrows ADD COLUMN leased_until TIMESTAMP WITHOUT TIME ZONE;
+
+---- create above / drop below ----
+
+ALTER TABLE batchrows DROP COLUMN IF EXISTS leased_until;
+ALTER TABLE batchrows DROP COLUMN IF EXISTS worker;
+DROP TABLE IF EXISTS workers;
@@ -0,0 +1,7 @@
+-- For reading the processed rows of a batch page by page, in the order of their
+-- lines, when assembling its output files
+CREATE INDEX IF NOT EXISTS idx_batchrows_batch_line ON batchrows(batch, line, rowid);
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS idx_batchrows_batch_line;
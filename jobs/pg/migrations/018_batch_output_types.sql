@@ -0,0 +1,7 @@
+-- Content types of the output files of batches, by logical file name, as written
+-- by the output formats registered for them
+ALTER TABLE batches ADD COLUMN outputtypes JSONB;
+
+---- create above / drop below ----
+
+ALTER TABLE batches DROP COLUMN IF EXISTS outputtypes;
@@ -0,0 +1,15 @@
+-- The priority of a batch is copied onto its rows, so that FetchBlockOfRows can
+-- read the queued rows in order of priority from an index, without a join and a
+-- sort on every poll
+ALTER TABLE batchrows ADD COLUMN priority INT NOT NULL DEFAULT 0;
+
+UPDATE batchrows SET priority = batches.priority
+FROM batches
+WHERE batchrows.batch = batches.id AND batches.priority <> 0;
+
+CREATE INDEX IF NOT EXISTS idx_batchrows_fetch ON batchrows(status, priority DESC, rowid);
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS idx_batchrows_fetch;
+ALTER TABLE batchrows DROP COLUMN IF EXISTS priority;
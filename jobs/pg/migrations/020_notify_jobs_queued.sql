@@ -0,0 +1,17 @@
+-- Announces queued work of app on the alya_jobs channel, and returns the error
+-- message if that fails, or an empty string. The exception block runs pg_notify
+-- in a subtransaction, so that a failed notification, such as one on a full
+-- notification queue, does not abort the transaction that queues the work.
+CREATE OR REPLACE FUNCTION alya_notify_jobs_queued(app TEXT)
+RETURNS TEXT AS $$
+BEGIN
+    PERFORM pg_notify('alya_jobs', app);
+    RETURN '';
+EXCEPTION WHEN OTHERS THEN
+    RETURN SQLERRM;
+END;
+$$ LANGUAGE plpgsql;
+
+---- create above / drop below ----
+
+DROP FUNCTION IF EXISTS alya_notify_jobs_queued(TEXT);
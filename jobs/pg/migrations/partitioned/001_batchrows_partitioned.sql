@@ -0,0 +1,111 @@
+-- Optional: converts batchrows into a table partitioned by month of reqat, for
+-- installations with tens of millions of rows a day. Applied by
+-- MigrateBatchRowsPartitioned, after all the migrations in the parent directory.
+--
+-- The existing rows are copied into the new table, so run it in a maintenance
+-- window. Differences from the plain table:
+--   - the primary key is (rowid, reqat), as it must include the partition key, and
+--     rowid comes from a sequence instead of an identity column;
+--   - rows have no foreign key to batches, so that purged batches do not cascade
+--     to their rows: those stay until their partition is dropped;
+--   - batchrow_history.rowid no longer references batchrows;
+--   - the index on status covers only queued and inprog rows, so that the indexes
+--     of old partitions stay empty.
+
+CREATE SEQUENCE batchrows_rowid_seq AS BIGINT;
+SELECT setval('batchrows_rowid_seq', COALESCE((SELECT MAX(rowid) FROM batchrows), 0) + 1, false);
+
+ALTER TABLE batchrow_history DROP CONSTRAINT IF EXISTS batchrow_history_rowid_fkey;
+DROP INDEX IF EXISTS idx_batchrows_status;
+DROP INDEX IF EXISTS idx_batchrows_batch_status;
+ALTER TABLE batchrows RENAME TO batchrows_unpartitioned;
+ALTER TABLE batchrows_unpartitioned RENAME CONSTRAINT batchrows_pkey TO batchrows_unpartitioned_pkey;
+
+CREATE TABLE batchrows (
+    rowid BIGINT NOT NULL DEFAULT nextval('batchrows_rowid_seq'),
+    batch UUID NOT NULL,
+    line INT NOT NULL,
+    input JSONB NOT NULL,
+    status status_enum NOT NULL DEFAULT 'queued',
+    reqat TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+    doneat TIMESTAMP WITHOUT TIME ZONE,
+    res JSONB,
+    blobrows JSONB,
+    messages JSONB,
+    doneby VARCHAR(255),
+    created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT NOW(),
+    attempts INT NOT NULL DEFAULT 0,
+    not_before TIMESTAMP WITHOUT TIME ZONE,
+    PRIMARY KEY (rowid, reqat)
+) PARTITION BY RANGE (reqat);
+ALTER SEQUENCE batchrows_rowid_seq OWNED BY batchrows.rowid;
+
+-- For per-batch queries
+CREATE INDEX idx_batchrows_batch_status ON batchrows(batch, status);
+
+-- For fetching and recovering rows
+CREATE INDEX idx_batchrows_pending ON batchrows(status) WHERE status IN ('queued', 'inprog');
+
+-- Catches rows outside the monthly partitions, e.g. far in the future
+CREATE TABLE batchrows_default PARTITION OF batchrows DEFAULT;
+
+-- Partitions for the months of the existing rows, and a few months ahead
+DO $$
+DECLARE
+    month TIMESTAMP;
+BEGIN
+    month := date_trunc('month', COALESCE((SELECT MIN(reqat) FROM batchrows_unpartitioned), LOCALTIMESTAMP));
+    WHILE month < date_trunc('month', LOCALTIMESTAMP) LOOP
+        EXECUTE format('CREATE TABLE %I PARTITION OF batchrows FOR VALUES FROM (%L) TO (%L)',
+            'batchrows_' || to_char(month, 'YYYYMM'), month, month + INTERVAL '1 month');
+        month := month + INTERVAL '1 month';
+    END LOOP;
+END;
+$$;
+SELECT alya_ensure_batchrows_partitions(3);
+
+INSERT INTO batchrows (rowid, batch, line, input, status, reqat, doneat, res, blobrows, messages, doneby, created_at, attempts, not_before)
+SELECT rowid, batch, line, input, status, reqat, doneat, res, blobrows, messages, doneby, created_at, attempts, not_before
+FROM batchrows_unpartitioned;
+
+DROP TABLE batchrows_unpartitioned;
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS idx_batchrows_pending;
+DROP INDEX IF EXISTS idx_batchrows_batch_status;
+ALTER TABLE batchrows RENAME TO batchrows_partitioned;
+ALTER TABLE batchrows_partitioned RENAME CONSTRAINT batchrows_pkey TO batchrows_partitioned_pkey;
+
+CREATE TABLE batchrows (
+    rowid BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    batch UUID NOT NULL REFERENCES batches(id) ON DELETE CASCADE,
+    line INT NOT NULL,
+    input JSONB NOT NULL,
+    status status_enum NOT NULL DEFAULT 'queued',
+    reqat TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+    doneat TIMESTAMP WITHOUT TIME ZONE,
+    res JSONB,
+    blobrows JSONB,
+    messages JSONB,
+    doneby VARCHAR(255),
+    created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT NOW(),
+    attempts INT NOT NULL DEFAULT 0,
+    not_before TIMESTAMP WITHOUT TIME ZONE,
+    CONSTRAINT fk_batch FOREIGN KEY (batch) REFERENCES batches(id)
+);
+
+-- Rows of purged batches that are still waiting for their partition to be dropped are not copied back
+INSERT INTO batchrows (rowid, batch, line, input, status, reqat, doneat, res, blobrows, messages, doneby, created_at, attempts, not_before)
+OVERRIDING SYSTEM VALUE
+SELECT r.rowid, r.batch, r.line, r.input, r.status, r.reqat, r.doneat, r.res, r.blobrows, r.messages, r.doneby, r.created_at, r.attempts, r.not_before
+FROM batchrows_partitioned r
+WHERE EXISTS (SELECT 1 FROM batches b WHERE b.id = r.batch);
+SELECT setval(pg_get_serial_sequence('batchrows', 'rowid'), COALESCE((SELECT MAX(rowid) FROM batchrows), 0) + 1, false);
+
+DROP TABLE batchrows_partitioned;
+
+CREATE INDEX IF NOT EXISTS idx_batchrows_status ON batchrows(status);
+CREATE INDEX IF NOT EXISTS idx_batchrows_batch_status ON batchrows(batch, status);
+DELETE FROM batchrow_history h WHERE NOT EXISTS (SELECT 1 FROM batchrows r WHERE r.rowid = h.rowid);
+ALTER TABLE batchrow_history ADD CONSTRAINT batchrow_history_rowid_fkey FOREIGN KEY (rowid) REFERENCES batchrows(rowid) ON DELETE CASCADE;
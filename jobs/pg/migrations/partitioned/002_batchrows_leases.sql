@@ -0,0 +1,11 @@
+-- The partitioned batchrows table of 001 does not have the worker and leased_until
+-- columns of 016_workers.sql in the parent directory, which were dropped with the
+-- plain table if 001 ran after 016. Leases of rows in progress during the conversion
+-- are not kept.
+ALTER TABLE batchrows ADD COLUMN IF NOT EXISTS worker VARCHAR(255);
+ALTER TABLE batchrows ADD COLUMN IF NOT EXISTS leased_until TIMESTAMP WITHOUT TIME ZONE;
+
+---- create above / drop below ----
+
+-- The columns belong to 016_workers.sql and are left in place. Rolling back 001 as
+-- well recreates the plain table without them.
@@ -0,0 +1,8 @@
+-- The partitioned batchrows table of 001 does not have the index of
+-- 017_batchrows_line_index.sql in the parent directory, which was dropped with the
+-- plain table.
+CREATE INDEX IF NOT EXISTS idx_batchrows_batch_line ON batchrows(batch, line, rowid);
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS idx_batchrows_batch_line;
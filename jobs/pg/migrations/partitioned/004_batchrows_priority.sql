@@ -0,0 +1,16 @@
+-- The partitioned batchrows table of 001 does not have the priority column of
+-- 019_batchrows_priority.sql in the parent directory: it is added here, and filled
+-- in from batches if 001 ran after 019. As with idx_batchrows_pending, the index
+-- covers only queued and inprog rows.
+ALTER TABLE batchrows ADD COLUMN IF NOT EXISTS priority INT NOT NULL DEFAULT 0;
+
+UPDATE batchrows SET priority = batches.priority
+FROM batches
+WHERE batchrows.batch = batches.id AND batches.priority <> 0 AND batchrows.priority <> batches.priority;
+
+CREATE INDEX IF NOT EXISTS idx_batchrows_fetch ON batchrows(status, priority DESC, rowid)
+WHERE status IN ('queued', 'inprog');
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS idx_batchrows_fetch;
@@ -1,16 +1,22 @@
 -- name: InsertIntoBatches :one
-INSERT INTO batches (id, app, op, context, status, reqat)
-VALUES ($1, $2, $3, $4, $5, $6)
+INSERT INTO batches (id, app, op, context, status, reqat, runat, priority, callback_url)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 RETURNING id;
 
 -- name: InsertIntoBatchRows :exec
-INSERT INTO batchrows (batch, line, input, status, reqat)
-VALUES ($1, $2, $3, 'queued', $4);
+INSERT INTO batchrows (batch, line, input, status, reqat, priority)
+VALUES ($1, $2, $3, 'queued', $4, $5);
 
 -- name: BulkInsertIntoBatchRows :execrows
-INSERT INTO batchrows (batch, line, input, status, reqat) 
+INSERT INTO batchrows (batch, line, input, status, reqat, priority) 
 VALUES 
-    (unnest(@batch::uuid[]), unnest(@line::int[]), unnest(@input::jsonb[]), 'queued', unnest(@reqat::timestamp[]));
+    (unnest(@batch::uuid[]), unnest(@line::int[]), unnest(@input::jsonb[]), 'queued', unnest(@reqat::timestamp[]), @priority::int);
+
+-- name: CopyBatchRows :copyfrom
+-- Loads batch rows with the COPY protocol, for batches too large to insert in one statement.
+-- The rows are queued through the column default of status.
+INSERT INTO batchrows (batch, line, input, reqat, priority)
+VALUES (@batch, @line, @input, @reqat, @priority);
 
 -- name: GetBatchStatus :one
 SELECT status
@@ -47,13 +53,67 @@ WHERE rowid = $1;
 
 
 -- name: FetchBlockOfRows :many
-SELECT batches.app, batches.status, batches.op, batches.context, batchrows.batch, batchrows.rowid, batchrows.line, batchrows.input
+-- Rows requeued for retry are skipped until their not_before time has passed,
+-- and rows of a delayed batch until the batch's runat time has passed.
+-- Rows of higher priority batches are returned first, oldest rows first
+-- within the same priority. The priority of the batch is copied onto its rows,
+-- so that the rows are read in this order from idx_batchrows_fetch.
+-- The bound on reqat lets a batchrows table partitioned by month skip the
+-- partitions older than any pending batch; the day of slack allows for clock
+-- steps between the insert of a batch and of its rows.
+SELECT batches.app, batches.status, batches.op, batches.context, batchrows.batch, batchrows.rowid, batchrows.line, batchrows.input, batchrows.attempts
 FROM batchrows
 INNER JOIN batches ON batchrows.batch = batches.id
-WHERE batchrows.status = $1 AND batches.status != 'wait'
-LIMIT $2
+WHERE batchrows.status = @status AND batches.status NOT IN ('wait', 'paused')
+  AND (batchrows.not_before IS NULL OR batchrows.not_before <= @now::timestamp)
+  AND (batches.runat IS NULL OR batches.runat <= @now::timestamp)
+  AND batchrows.reqat >= (
+      SELECT COALESCE(MIN(pending.reqat), 'infinity'::timestamp) - INTERVAL '1 day'
+      FROM batches pending
+      WHERE pending.status IN ('queued', 'inprog'))
+ORDER BY batchrows.priority DESC, batchrows.rowid
+LIMIT sqlc.arg('limit')
 FOR UPDATE OF batchrows, batches SKIP LOCKED;
 
+-- name: FetchBlockOfRowsFair :many
+-- Fair-share variant of FetchBlockOfRows. Up to limit eligible rows are locked
+-- per app, highest priority first, and the block is then filled round-robin
+-- across apps: in every round an app contributes as many rows as its weight.
+-- Apps not listed in weight_apps have weight 1. Locked rows that do not make
+-- it into the block are released when the transaction ends.
+WITH apps AS (
+    SELECT DISTINCT batches.app
+    FROM batches
+    WHERE batches.status IN ('queued', 'inprog')
+), candidates AS (
+    SELECT c.*
+    FROM apps
+    CROSS JOIN LATERAL (
+        SELECT batches.app, batches.status, batches.op, batches.context, batchrows.priority, batchrows.batch, batchrows.rowid, batchrows.line, batchrows.input, batchrows.attempts
+        FROM batchrows
+        INNER JOIN batches ON batchrows.batch = batches.id
+        WHERE batches.app = apps.app
+          AND batchrows.status = @status AND batches.status NOT IN ('wait', 'paused')
+          AND (batchrows.not_before IS NULL OR batchrows.not_before <= @now::timestamp)
+          AND (batches.runat IS NULL OR batches.runat <= @now::timestamp)
+          AND batchrows.reqat >= (
+              SELECT COALESCE(MIN(pending.reqat), 'infinity'::timestamp) - INTERVAL '1 day'
+              FROM batches pending
+              WHERE pending.status IN ('queued', 'inprog'))
+        ORDER BY batchrows.priority DESC, batchrows.rowid
+        LIMIT sqlc.arg('limit')
+        FOR UPDATE OF batchrows, batches SKIP LOCKED
+    ) c
+), ranked AS (
+    SELECT candidates.*, row_number() OVER (PARTITION BY candidates.app ORDER BY candidates.priority DESC, candidates.rowid) AS rn
+    FROM candidates
+)
+SELECT ranked.app, ranked.status, ranked.op, ranked.context, ranked.batch, ranked.rowid, ranked.line, ranked.input, ranked.attempts
+FROM ranked
+LEFT JOIN unnest(@weight_apps::text[], @weights::int[]) AS w(app, weight) ON w.app = ranked.app
+ORDER BY (ranked.rn - 1) / GREATEST(COALESCE(w.weight, 1), 1), ranked.priority DESC, ranked.rowid
+LIMIT sqlc.arg('limit');
+
 
 -- name: UpdateBatchRowsStatus :exec
 UPDATE batchrows
@@ -85,11 +145,17 @@ WHERE batch = $1
 ORDER BY line
 FOR UPDATE;
 
--- name: GetProcessedBatchRowsByBatchIDSorted :many
-SELECT rowid, line, input, status, reqat, doneat, res, blobrows, messages, doneby
+-- name: GetProcessedBatchRowsPage :many
+-- Returns a page of the processed rows of a batch, in the order of their lines,
+-- for assembling its output files. Pages follow each other by keyset: pass the
+-- line and rowid of the last row of the previous page as after_line and
+-- after_rowid.
+SELECT rowid, line, blobrows
 FROM batchrows
-WHERE batch = $1 AND status IN ('success', 'failed')
-ORDER BY line;
+WHERE batch = @batch AND status IN ('success', 'failed')
+  AND (line, rowid) > (@after_line::integer, @after_rowid::bigint)
+ORDER BY line, rowid
+LIMIT @page_size;
 
 -- name: CountBatchRowsByBatchIDAndStatus :one
 SELECT COUNT(*)
@@ -99,16 +165,18 @@ WHERE batch = $1 AND status IN ($2, $3);
 -- name: CountBatchRowsQueuedByBatchID :one
 SELECT COUNT(*)
 FROM batchrows
-WHERE batch = $1 AND status = 'queued';
+WHERE batch = $1 AND status = 'queued'
+  AND reqat >= (SELECT reqat FROM batches WHERE id = $1) - INTERVAL '1 day';
 
 -- name: CountBatchRowsInProgByBatchID :one
 SELECT COUNT(*)
 FROM batchrows
-WHERE batch = $1 AND status = 'inprog';
+WHERE batch = $1 AND status = 'inprog'
+  AND reqat >= (SELECT reqat FROM batches WHERE id = $1) - INTERVAL '1 day';
 
 -- name: UpdateBatchSummary :exec
 UPDATE batches
-SET status = $2, doneat = $3, outputfiles = $4, nsuccess = $5, nfailed = $6, naborted = $7
+SET status = $2, doneat = $3, outputfiles = $4, nsuccess = $5, nfailed = $6, naborted = $7, outputtypes = $8
 WHERE id = $1;
 
 -- name: UpdateBatchSummaryOnAbort :exec
@@ -134,6 +202,18 @@ WHERE id = $1;
 -- name: GetBatchRowsCount :one
 SELECT COUNT(*) FROM batchrows WHERE batch = $1;
 
+-- name: GetBatchProgress :one
+-- Counts the rows of a batch by status, with the time the first of them was done.
+SELECT
+    COUNT(*) FILTER (WHERE status = 'queued') AS nqueued,
+    COUNT(*) FILTER (WHERE status = 'inprog') AS ninprog,
+    COUNT(*) FILTER (WHERE status = 'success') AS nsuccess,
+    COUNT(*) FILTER (WHERE status = 'failed') AS nfailed,
+    COUNT(*) FILTER (WHERE status = 'aborted') AS naborted,
+    MIN(doneat)::timestamp AS first_doneat
+FROM batchrows
+WHERE batch = @batch;
+
 -- name: UpdateBatchRowStatus :exec
 UPDATE batchrows
 SET status = $2
@@ -219,6 +299,58 @@ UPDATE batchrows
 SET status = 'queued'
 WHERE rowid = ANY($1::bigint[]) AND status = 'inprog';
 
+-- name: RecordWorkerHeartbeat :exec
+-- Registers a JobManager instance in the worker registry, or refreshes its heartbeat.
+-- Used instead of Redis by JobManagers without a Redis client.
+INSERT INTO workers (instance_id, heartbeat)
+VALUES (@instance_id, @heartbeat)
+ON CONFLICT (instance_id) DO UPDATE SET heartbeat = EXCLUDED.heartbeat;
+
+-- name: DeleteWorker :exec
+-- Removes a JobManager instance from the worker registry.
+DELETE FROM workers
+WHERE instance_id = $1;
+
+-- name: GetDeadWorkers :many
+-- Returns the instances of the worker registry whose last heartbeat is older than the cutoff.
+SELECT instance_id
+FROM workers
+WHERE heartbeat < @cutoff::timestamp;
+
+-- name: LeaseBatchRows :exec
+-- Records the rows picked up by a worker as leased to it until leased_until.
+UPDATE batchrows
+SET worker = @worker::text, leased_until = @leased_until::timestamp
+WHERE rowid = ANY(@row_ids::bigint[]);
+
+-- name: RenewBatchRowLeases :exec
+-- Extends the leases of the rows a worker is still processing.
+UPDATE batchrows
+SET leased_until = @leased_until::timestamp
+WHERE worker = @worker::text AND status = 'inprog';
+
+-- name: ReleaseBatchRowLease :exec
+-- Ends the lease of a row once its worker is done with it.
+UPDATE batchrows
+SET worker = NULL, leased_until = NULL
+WHERE rowid = @rowid AND worker = @worker::text;
+
+-- name: ResetWorkerRowsToQueued :execrows
+-- Resets the rows of a dead worker that are still 'inprog' to 'queued', for recovery
+-- of abandoned rows. Rows it finished or that were reset already are left alone.
+UPDATE batchrows
+SET status = 'queued', worker = NULL, leased_until = NULL
+WHERE worker = @worker::text AND status = 'inprog';
+
+-- name: ResetExpiredLeasesToQueued :execrows
+-- Resets the 'inprog' rows whose lease has expired and whose worker is no longer
+-- registered to 'queued', for recovery of the rows of workers that were removed from
+-- the registry, such as by Shutdown, with rows still leased to them.
+UPDATE batchrows
+SET status = 'queued', worker = NULL, leased_until = NULL
+WHERE status = 'inprog' AND leased_until < @now::timestamp
+  AND NOT EXISTS (SELECT 1 FROM workers WHERE workers.instance_id = batchrows.worker);
+
 -- name: GetUnsummarizedBatches :many
 -- Finds batches stuck in 'inprog' with doneat=NULL where all rows have reached
 -- terminal status (no queued or inprog rows remain). These batches need
@@ -232,3 +364,282 @@ WHERE b.status = 'inprog'
     AND br.status IN ('queued', 'inprog')
   );
 
+
+-- name: ListBatches :many
+-- Lists batch jobs of an app, newest first. Slow queries (batches with a
+-- line 0 row) are excluded. op and status are optional filters. Pagination
+-- is keyset based: pass the reqat and id of the last row of the previous page
+-- as after_reqat and after_id, or NULL for the first page.
+SELECT b.id, b.app, b.op, b.context, b.inputfile, b.status, b.reqat, b.doneat, b.outputfiles,
+    b.nsuccess, b.nfailed, b.naborted, b.outputtypes,
+    (SELECT COUNT(*) FROM batchrows r WHERE r.batch = b.id) AS nrows
+FROM batches b
+WHERE b.app = @app
+  AND (sqlc.narg('op')::text IS NULL OR b.op = sqlc.narg('op'))
+  AND (sqlc.narg('status')::status_enum IS NULL OR b.status = sqlc.narg('status'))
+  AND b.reqat >= @since
+  AND (sqlc.narg('after_reqat')::timestamp IS NULL OR (b.reqat, b.id) < (sqlc.narg('after_reqat'), sqlc.narg('after_id')::uuid))
+  AND NOT EXISTS (SELECT 1 FROM batchrows r WHERE r.batch = b.id AND r.line = 0)
+ORDER BY b.reqat DESC, b.id DESC
+LIMIT @page_size;
+
+-- name: ListSlowQueries :many
+-- Lists slow queries (batches with a single line 0 row) of an app, newest
+-- first. Filters and keyset pagination work the same way as ListBatches.
+SELECT b.id, b.app, b.op, b.inputfile, b.status, b.reqat, b.doneat, b.outputfiles
+FROM batches b
+WHERE b.app = @app
+  AND (sqlc.narg('op')::text IS NULL OR b.op = sqlc.narg('op'))
+  AND (sqlc.narg('status')::status_enum IS NULL OR b.status = sqlc.narg('status'))
+  AND b.reqat >= @since
+  AND (sqlc.narg('after_reqat')::timestamp IS NULL OR (b.reqat, b.id) < (sqlc.narg('after_reqat'), sqlc.narg('after_id')::uuid))
+  AND EXISTS (SELECT 1 FROM batchrows r WHERE r.batch = b.id AND r.line = 0)
+ORDER BY b.reqat DESC, b.id DESC
+LIMIT @page_size;
+
+-- name: RequeueBatchRowForRetry :exec
+-- Puts a row that failed with a retryable error back in the queue. attempts
+-- counts the failed attempts so far; the row is not picked up again before
+-- not_before. Only rows still 'inprog' are requeued, so a row that was
+-- aborted in the meantime stays aborted.
+UPDATE batchrows
+SET status = 'queued', attempts = attempts + 1, not_before = @not_before, messages = @messages
+WHERE rowid = @rowid AND status = 'inprog';
+
+-- name: UpsertBatchSchedule :exec
+-- Records a registered schedule. A new schedule starts counting from
+-- last_fireat; for an existing one only the definition is updated.
+INSERT INTO batch_schedules (name, app, op, cron, last_fireat)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (name) DO UPDATE
+SET app = EXCLUDED.app, op = EXCLUDED.op, cron = EXCLUDED.cron;
+
+-- name: GetBatchScheduleForUpdate :one
+SELECT name, app, op, cron, last_fireat, last_batch
+FROM batch_schedules
+WHERE name = $1
+FOR UPDATE;
+
+-- name: UpdateBatchScheduleFired :exec
+UPDATE batch_schedules
+SET last_fireat = $2, last_batch = $3
+WHERE name = $1;
+
+-- name: NotifyJobsQueued :one
+-- Wakes up idle JobManager instances listening on the alya_jobs channel.
+-- The notification is only delivered when the surrounding transaction commits.
+-- A failed notification is returned as its error message and leaves the
+-- transaction usable.
+SELECT alya_notify_jobs_queued(@app::text)::text AS failure;
+
+-- name: InsertWebhookDelivery :exec
+-- Queues a delivery of payload to the callback URL of the batch, if it has one.
+INSERT INTO webhook_deliveries (batch, url, payload, next_attempt_at)
+SELECT id, callback_url, @payload::jsonb, @next_attempt_at::timestamp
+FROM batches
+WHERE id = @batch AND callback_url IS NOT NULL;
+
+-- name: ClaimWebhookDeliveries :many
+-- Takes up to limit pending deliveries that are due, and leases them to the caller
+-- by moving their next attempt to lease_until. A delivery whose caller dies before
+-- recording the outcome is tried again once the lease has run out.
+UPDATE webhook_deliveries
+SET next_attempt_at = @lease_until
+WHERE id IN (
+    SELECT id
+    FROM webhook_deliveries
+    WHERE status = 'pending' AND next_attempt_at <= @now
+    ORDER BY next_attempt_at
+    LIMIT @limit
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, batch, url, payload, attempts, next_attempt_at;
+
+-- name: MarkWebhookDelivered :execrows
+-- Records a successful attempt by the caller that claimed the delivery until
+-- leased_until. It does nothing if the delivery has been claimed again since.
+UPDATE webhook_deliveries
+SET status = 'delivered', attempts = attempts + 1, last_http_status = @http_status, last_error = NULL, delivered_at = @delivered_at
+WHERE id = @id AND status = 'pending' AND next_attempt_at = @leased_until;
+
+-- name: MarkWebhookAttemptFailed :execrows
+-- Records a failed attempt by the caller that claimed the delivery until
+-- leased_until. status stays 'pending' while attempts remain. It does nothing if
+-- the delivery has been claimed again since.
+UPDATE webhook_deliveries
+SET status = @status, attempts = attempts + 1, next_attempt_at = @next_attempt_at, last_http_status = @http_status, last_error = @last_error
+WHERE id = @id AND status = 'pending' AND next_attempt_at = @leased_until;
+
+-- name: GetWebhookDeliveriesByBatchID :many
+SELECT * FROM webhook_deliveries
+WHERE batch = $1
+ORDER BY id;
+
+-- name: GetBatchRowsForRerun :many
+-- Locks the rows of a batch in one of statuses, and on one of lines if any are given,
+-- for BatchRetryFailed.
+SELECT rowid, line, input, status
+FROM batchrows
+WHERE batch = @batch
+  AND status::text = ANY(@statuses::text[])
+  AND (cardinality(@lines::int[]) = 0 OR line = ANY(@lines::int[]))
+ORDER BY line
+FOR UPDATE;
+
+-- name: ArchiveBatchRows :exec
+-- Copies the results of the rows, and the output files of their batch, to
+-- batchrow_history before the rows are re-run.
+INSERT INTO batchrow_history (rowid, batch, line, input, status, doneat, res, blobrows, messages, doneby, outputfiles)
+SELECT r.rowid, r.batch, r.line, r.input, r.status, r.doneat, r.res, r.blobrows, r.messages, r.doneby, b.outputfiles
+FROM batchrows r
+JOIN batches b ON b.id = r.batch
+WHERE r.rowid = ANY(@rowids::bigint[]);
+
+-- name: RequeueBatchRowsForRerun :exec
+-- Puts rows back in the queue as if they had never been processed.
+UPDATE batchrows
+SET status = 'queued', doneat = NULL, res = NULL, blobrows = NULL, messages = NULL, doneby = NULL, attempts = 0, not_before = NULL
+WHERE rowid = ANY(@rowids::bigint[]);
+
+-- name: UpdateBatchRowInput :exec
+UPDATE batchrows
+SET input = @input
+WHERE rowid = @rowid;
+
+-- name: ReopenBatch :exec
+-- Puts a summarised batch back in the queue, to be summarised again once its
+-- requeued rows are done.
+UPDATE batches
+SET status = 'queued', doneat = NULL, outputfiles = NULL, nsuccess = NULL, nfailed = NULL, naborted = NULL, outputtypes = NULL
+WHERE id = @id;
+
+-- name: SetBatchParent :exec
+UPDATE batches
+SET parent_id = @parent_id
+WHERE id = @id;
+
+-- name: GetBatchRowHistory :many
+SELECT * FROM batchrow_history
+WHERE batch = $1
+ORDER BY line, id;
+
+-- name: GetBatchesToPurge :many
+-- Locks up to @max_batches done batches and slow queries of an app that were done before
+-- @cutoff, oldest first. Batches locked by a concurrent purge are skipped.
+SELECT * FROM batches
+WHERE app = @app
+  AND status IN ('success', 'failed', 'aborted')
+  AND doneat < @cutoff
+ORDER BY doneat
+LIMIT @max_batches
+FOR UPDATE SKIP LOCKED;
+
+-- name: CountBatchesToPurge :one
+-- Counts what GetBatchesToPurge would purge in all, for dry runs.
+SELECT COUNT(*) AS nbatches,
+  COALESCE(SUM((SELECT COUNT(*) FROM batchrows r WHERE r.batch = b.id)), 0)::bigint AS nrows,
+  COALESCE(SUM(CASE WHEN jsonb_typeof(b.outputfiles) = 'object'
+    THEN (SELECT COUNT(*) FROM jsonb_object_keys(b.outputfiles)) ELSE 0 END), 0)::bigint AS noutputfiles
+FROM batches b
+WHERE b.app = @app
+  AND b.status IN ('success', 'failed', 'aborted')
+  AND b.doneat < @cutoff;
+
+-- name: GetBatchRowsPage :many
+-- Returns a page of the rows of a batch, in the order of their lines, for archiving
+-- it. Pages follow each other by keyset, as with GetProcessedBatchRowsPage.
+SELECT * FROM batchrows
+WHERE batch = @batch
+  AND (line, rowid) > (@after_line::integer, @after_rowid::bigint)
+ORDER BY line, rowid
+LIMIT @page_size;
+
+-- name: DeleteBatchFilesByBatchIDs :exec
+DELETE FROM batch_files
+WHERE batch_id = ANY(@batch_ids::uuid[]);
+
+-- name: DeleteBatchRowsByBatchIDs :exec
+DELETE FROM batchrows
+WHERE batch = ANY(@batch_ids::uuid[]);
+
+-- name: DeleteBatchesByIDs :execrows
+DELETE FROM batches
+WHERE id = ANY(@ids::uuid[]);
+
+-- name: EnsureBatchRowPartitions :many
+-- Only for a batchrows table partitioned by MigrateBatchRowsPartitioned.
+SELECT name::text FROM alya_ensure_batchrows_partitions(@months_ahead::int) AS name;
+
+-- name: DropBatchRowPartitions :many
+-- Only for a batchrows table partitioned by MigrateBatchRowsPartitioned.
+SELECT name::text FROM alya_drop_batchrows_partitions(@cutoff::timestamp) AS name;
+
+-- name: InsertBatchDependency :exec
+INSERT INTO batch_dependencies (batch, parent, name, on_parent_failure)
+VALUES (@batch, @parent, @name, @on_parent_failure);
+
+-- name: LockParentBatches :many
+-- Keeps the given batches from being summarised until the calling transaction ends,
+-- so that a batch depending on them, inserted in it, cannot miss their completion.
+SELECT id FROM batches
+WHERE id = ANY(@ids::uuid[])
+ORDER BY id
+FOR SHARE;
+
+-- name: GetParentBatches :many
+SELECT d.parent, d.name, d.on_parent_failure, b.status, b.outputfiles
+FROM batch_dependencies d
+JOIN batches b ON b.id = d.parent
+WHERE d.batch = @batch
+ORDER BY d.name;
+
+-- name: GetWaitingDependentBatches :many
+SELECT d.batch
+FROM batch_dependencies d
+JOIN batches b ON b.id = d.batch
+WHERE d.parent = @parent
+  AND b.status = 'wait'
+ORDER BY d.batch;
+
+-- name: ReleaseDependentBatch :exec
+-- Queues a batch whose parents are done, adding them to its context if it is an object.
+UPDATE batches
+SET status = 'queued',
+    context = CASE WHEN jsonb_typeof(context) = 'object'
+        THEN context || jsonb_build_object('alya_parents', @parents::jsonb)
+        ELSE context END
+WHERE id = @id;
+
+-- name: CountBatchDependencies :one
+SELECT COUNT(*) FROM batch_dependencies
+WHERE batch = @batch;
+
+-- name: PauseBatch :one
+UPDATE batches
+SET status = 'paused'
+WHERE id = @id
+  AND status IN ('queued', 'inprog')
+RETURNING app;
+
+-- name: ResumeBatch :one
+-- The batch is queued again; picking up its rows puts it back in progress.
+UPDATE batches
+SET status = 'queued'
+WHERE id = @id
+  AND status = 'paused'
+RETURNING app;
+
+-- name: ClaimIdempotencyKey :one
+-- Records @key for @batch, unless the app used it for another batch at or after
+-- @cutoff, in which case no row is returned. A concurrent claim of the same key
+-- waits for the first one to commit or roll back.
+INSERT INTO batch_idempotency_keys (app, key, batch, created_at)
+VALUES (@app, @key, @batch, @created_at)
+ON CONFLICT (app, key) DO UPDATE
+SET batch = EXCLUDED.batch, created_at = EXCLUDED.created_at
+WHERE batch_idempotency_keys.created_at < @cutoff
+RETURNING batch;
+
+-- name: GetIdempotencyKeyBatch :one
+SELECT batch FROM batch_idempotency_keys
+WHERE app = @app AND key = @key;
@@ -0,0 +1,160 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/remiges-tech/alya/jobs/pg/batchsqlc"
+)
+
+var ErrInvalidPipeline = errors.New("invalid pipeline")
+
+// PipelineStep_t is one step of a pipeline submitted with PipelineSubmit: a batch that
+// runs once the batches of the steps it comes after are done.
+type PipelineStep_t struct {
+	Name    string
+	App     string
+	Op      string
+	Context JSONstr
+	Input   []BatchInput_t
+
+	// After names the steps whose batches must be done before this one runs. Once
+	// they are, the context of this step's batch holds a ParentOutput_t for each of
+	// them under ParentsContextKey, by step name, so that its rows can read their
+	// output files.
+	After           []string
+	OnParentFailure ParentFailurePolicy_t // default: ParentFailureAbort
+
+	Priority    int
+	CallbackURL string
+}
+
+// PipelineSubmit submits the steps of a pipeline as batches in a single transaction,
+// each depending on the batches of the steps it comes after, and returns the batch
+// IDs by step name. Steps that come after no other step are queued right away; the
+// others stay in 'wait' until their parents are done. The steps must form a DAG.
+func (jm *JobManager) PipelineSubmit(steps []PipelineStep_t) (batchIDs map[string]string, err error) {
+	ctx := context.Background()
+
+	order, err := pipelineOrder(steps)
+	if err != nil {
+		return nil, err
+	}
+
+	// Start a transaction
+	tx, err := jm.store.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Create transaction-bound queries
+	txQueries := tx.Queries()
+
+	batchIDs, err = jm.insertPipeline(ctx, txQueries, steps, order)
+	if err != nil {
+		return nil, err
+	}
+
+	// Commit the transaction
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	jm.logger.Info().LogActivity("Pipeline submitted", map[string]any{
+		"nsteps":   len(steps),
+		"batchIds": batchIDs,
+	})
+	return batchIDs, nil
+}
+
+// insertPipeline inserts the batches of the steps of a pipeline in the given order,
+// which must list every step after the steps it comes after, using the given
+// (normally transaction-bound) queries. It returns the batch IDs by step name.
+func (jm *JobManager) insertPipeline(ctx context.Context, q batchsqlc.Querier, steps []PipelineStep_t, order []int) (map[string]string, error) {
+	batchIDs := make(map[string]string, len(steps))
+	for _, i := range order {
+		step := steps[i]
+		batchUUID, err := uuid.NewUUID()
+		if err != nil {
+			return nil, err
+		}
+
+		opts := SubmitOptions_t{
+			Priority:        step.Priority,
+			CallbackURL:     step.CallbackURL,
+			OnParentFailure: step.OnParentFailure,
+		}
+		for _, name := range step.After {
+			opts.DependsOn = append(opts.DependsOn, Dependency_t{BatchID: batchIDs[name], Name: name})
+		}
+
+		err = insertBatch(ctx, q, batchUUID, step.App, step.Op, step.Context, step.Input, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to submit step %s: %w", step.Name, err)
+		}
+		if len(opts.DependsOn) == 0 {
+			if err := jm.notifyJobsQueued(ctx, q, step.App); err != nil {
+				return nil, err
+			}
+		}
+		batchIDs[step.Name] = batchUUID.String()
+	}
+	return batchIDs, nil
+}
+
+// pipelineOrder checks the steps of a pipeline and returns their indexes in an order
+// in which every step comes after the steps it depends on, keeping the order of the
+// steps otherwise.
+func pipelineOrder(steps []PipelineStep_t) ([]int, error) {
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("%w: no steps", ErrInvalidPipeline)
+	}
+
+	index := make(map[string]int, len(steps))
+	for i, step := range steps {
+		if step.Name == "" || step.App == "" || step.Op == "" {
+			return nil, fmt.Errorf("%w: step %d: name, app and op are required", ErrInvalidPipeline, i)
+		}
+		if _, ok := index[step.Name]; ok {
+			return nil, fmt.Errorf("%w: step name %q is used twice", ErrInvalidPipeline, step.Name)
+		}
+		// A batch without rows is never done, so the steps after it would never run
+		if len(step.Input) == 0 {
+			return nil, fmt.Errorf("%w: step %s has no input rows", ErrInvalidPipeline, step.Name)
+		}
+		index[step.Name] = i
+	}
+	for _, step := range steps {
+		for j, name := range step.After {
+			if _, ok := index[name]; !ok {
+				return nil, fmt.Errorf("%w: step %s comes after unknown step %q", ErrInvalidPipeline, step.Name, name)
+			}
+			if slices.Contains(step.After[:j], name) {
+				return nil, fmt.Errorf("%w: step %s lists step %q twice", ErrInvalidPipeline, step.Name, name)
+			}
+		}
+	}
+
+	placed := make([]bool, len(steps))
+	order := make([]int, 0, len(steps))
+	for len(order) < len(steps) {
+		progress := false
+		for i, step := range steps {
+			if placed[i] || slices.ContainsFunc(step.After, func(name string) bool { return !placed[index[name]] }) {
+				continue
+			}
+			placed[i] = true
+			order = append(order, i)
+			progress = true
+		}
+		if !progress {
+			return nil, fmt.Errorf("%w: steps form a cycle", ErrInvalidPipeline)
+		}
+	}
+	return order, nil
+}
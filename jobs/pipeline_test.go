@@ -0,0 +1,101 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/remiges-tech/alya/jobs/pg/batchsqlc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func pipelineStep(t *testing.T, name string, after ...string) PipelineStep_t {
+	return PipelineStep_t{
+		Name:    name,
+		App:     "bank",
+		Op:      name,
+		Context: mustJSONstr(t, `{}`),
+		Input:   []BatchInput_t{{Line: 1, Input: mustJSONstr(t, `{}`)}},
+		After:   after,
+	}
+}
+
+func TestPipelineOrder(t *testing.T) {
+	steps := []PipelineStep_t{
+		pipelineStep(t, "report", "load", "audit"),
+		pipelineStep(t, "load", "extract"),
+		pipelineStep(t, "extract"),
+		pipelineStep(t, "audit", "extract"),
+	}
+
+	order, err := pipelineOrder(steps)
+	require.NoError(t, err)
+	assert.Equal(t, []int{2, 3, 1, 0}, order)
+}
+
+func TestPipelineOrder_Rejected(t *testing.T) {
+	noInput := pipelineStep(t, "extract")
+	noInput.Input = nil
+
+	tests := []struct {
+		name  string
+		steps []PipelineStep_t
+	}{
+		{"no steps", nil},
+		{"no name", []PipelineStep_t{pipelineStep(t, "")}},
+		{"name twice", []PipelineStep_t{pipelineStep(t, "extract"), pipelineStep(t, "extract")}},
+		{"no input", []PipelineStep_t{noInput}},
+		{"unknown step", []PipelineStep_t{pipelineStep(t, "load", "extract")}},
+		{"step twice", []PipelineStep_t{pipelineStep(t, "extract"), pipelineStep(t, "load", "extract", "extract")}},
+		{"self", []PipelineStep_t{pipelineStep(t, "extract", "extract")}},
+		{"cycle", []PipelineStep_t{pipelineStep(t, "extract"), pipelineStep(t, "load", "extract", "report"), pipelineStep(t, "report", "load")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := pipelineOrder(tt.steps)
+			assert.ErrorIs(t, err, ErrInvalidPipeline)
+		})
+	}
+}
+
+func TestInsertPipeline(t *testing.T) {
+	q := newDependencyQuerier(batchsqlc.Batch{})
+	q.BulkInsertIntoBatchRowsFunc = func(ctx context.Context, arg batchsqlc.BulkInsertIntoBatchRowsParams) (int64, error) {
+		return int64(len(arg.Line)), nil
+	}
+	jm := newTestJobManager(q, nil)
+	steps := []PipelineStep_t{
+		pipelineStep(t, "report", "load"),
+		pipelineStep(t, "load", "extract"),
+		pipelineStep(t, "extract"),
+	}
+	steps[1].OnParentFailure = ParentFailureRun
+	order, err := pipelineOrder(steps)
+	require.NoError(t, err)
+
+	batchIDs, err := jm.insertPipeline(context.Background(), q, steps, order)
+	require.NoError(t, err)
+	require.Len(t, batchIDs, 3)
+
+	inserted := q.InsertIntoBatchesCalls()
+	require.Len(t, inserted, 3)
+	assert.Equal(t, batchIDs["extract"], inserted[0].Arg.ID.String())
+	assert.Equal(t, batchsqlc.StatusEnumQueued, inserted[0].Arg.Status)
+	assert.Equal(t, batchsqlc.StatusEnumWait, inserted[1].Arg.Status)
+	assert.Equal(t, batchsqlc.StatusEnumWait, inserted[2].Arg.Status)
+
+	deps := q.InsertBatchDependencyCalls()
+	require.Len(t, deps, 2)
+	assert.Equal(t, batchsqlc.InsertBatchDependencyParams{
+		Batch:           uuid.MustParse(batchIDs["load"]),
+		Parent:          uuid.MustParse(batchIDs["extract"]),
+		Name:            "extract",
+		OnParentFailure: "run",
+	}, deps[0].Arg)
+	assert.Equal(t, uuid.MustParse(batchIDs["load"]), deps[1].Arg.Parent)
+	assert.Equal(t, "abort", deps[1].Arg.OnParentFailure)
+
+	// Only the first step is ready to run
+	assert.Len(t, q.NotifyJobsQueuedCalls(), 1)
+}
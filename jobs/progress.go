@@ -0,0 +1,264 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/remiges-tech/alya/jobs/pg/batchsqlc"
+)
+
+// Fields of the progress counters hash of a batch. The row counters are named
+// after the row statuses, so that a row's new status can be used as the field.
+const (
+	progressStatusField = "status"
+	progressFirstField  = "first"
+)
+
+// BatchProgress_t is a snapshot of the progress of a batch.
+//
+// Throughput is the average number of rows done per second since the first row of
+// the batch was done. ETA is the time the remaining rows will take at that rate;
+// it is zero while it cannot be estimated, while the batch is paused and once it
+// is done.
+type BatchProgress_t struct {
+	Status     batchsqlc.StatusEnum `json:"status"`
+	NTotal     int                  `json:"ntotal"`
+	NQueued    int                  `json:"nqueued"`
+	NInProg    int                  `json:"ninprog"`
+	NSuccess   int                  `json:"nsuccess"`
+	NFailed    int                  `json:"nfailed"`
+	NAborted   int                  `json:"naborted"`
+	Throughput float64              `json:"throughput"`
+	ETA        time.Duration        `json:"eta"`
+}
+
+// batchProgressScript applies increments to the progress counters of a batch, but only
+// if they are cached: counters started from zero would be wrong.
+//
+// KEYS[1] progress key; ARGV: time a row was done (ms, 0 if none), then field, increment pairs
+var batchProgressScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 0
+end
+if ARGV[1] ~= '0' then
+	redis.call('HSETNX', KEYS[1], 'first', ARGV[1])
+end
+for i = 2, #ARGV, 2 do
+	redis.call('HINCRBY', KEYS[1], ARGV[i], ARGV[i + 1])
+end
+return 1
+`)
+
+// BatchProgress returns the live progress of a batch: its row counts by status, the rate
+// at which rows are being done and an estimate of the time left. It is meant to be polled
+// while the batch is processed, unlike BatchStatus whose counters are only filled in when
+// the batch is summarised.
+//
+// The counts are kept as Redis counters that are updated as rows are picked up and done.
+// When they are not cached, they are counted in the batchrows table and cached for
+// BatchStatusCacheDurSec. Rows moved by crash recovery or bulk failure handling are not
+// tracked, so the cached counts may be off by those rows until they are counted again.
+func (jm *JobManager) BatchProgress(batchID string) (BatchProgress_t, error) {
+	ctx := context.Background()
+	batchUUID, err := uuid.Parse(batchID)
+	if err != nil {
+		return BatchProgress_t{}, fmt.Errorf("invalid batch ID: %w", err)
+	}
+
+	// Check Redis for cached counters
+	if jm.redisClient != nil {
+		fields, err := jm.redisClient.HGetAll(ctx, BatchProgressKey(batchID)).Result()
+		if err == nil && len(fields) > 0 {
+			return progressFromCounters(fields, time.Now()), nil
+		}
+		if err != nil {
+			jm.logger.Warn().LogActivity("Redis error checking batch progress cache", map[string]any{
+				"batchId": batchID,
+				"error":   err.Error(),
+			})
+			// Continue to DB query despite Redis error
+		}
+	}
+
+	// Cache miss - count the rows in the database
+	batch, err := jm.queries.GetBatchByID(ctx, batchUUID)
+	if err != nil {
+		return BatchProgress_t{}, fmt.Errorf("failed to get batch: %w", err)
+	}
+	counts, err := jm.queries.GetBatchProgress(ctx, batchUUID)
+	if err != nil {
+		return BatchProgress_t{}, fmt.Errorf("failed to count batch rows: %w", err)
+	}
+
+	progress := BatchProgress_t{
+		Status:   batch.Status,
+		NQueued:  int(counts.Nqueued),
+		NInProg:  int(counts.Ninprog),
+		NSuccess: int(counts.Nsuccess),
+		NFailed:  int(counts.Nfailed),
+		NAborted: int(counts.Naborted),
+	}
+	var first time.Time
+	if counts.FirstDoneat.Valid {
+		first = localTime(counts.FirstDoneat.Time)
+	}
+	progress.estimate(first, time.Now())
+
+	if jm.redisClient != nil {
+		if err := jm.cacheBatchProgress(ctx, batchID, progress, first); err != nil {
+			jm.logger.Warn().LogActivity("Failed to cache batch progress in Redis", map[string]any{
+				"batchId": batchID,
+				"error":   err.Error(),
+			})
+			// Continue despite Redis failure - Redis is just a cache
+		}
+	}
+	return progress, nil
+}
+
+// cacheBatchProgress stores the counters of a batch in Redis, where they are kept up to
+// date as rows are picked up and done until they expire.
+func (jm *JobManager) cacheBatchProgress(ctx context.Context, batchID string, progress BatchProgress_t, first time.Time) error {
+	expirySec := jm.config.BatchStatusCacheDurSec
+	if isFinalStatus(progress.Status) {
+		// Final status - cache for longer
+		expirySec = 100 * jm.config.BatchStatusCacheDurSec
+	}
+
+	fields := map[string]any{
+		progressStatusField:                 string(progress.Status),
+		string(batchsqlc.StatusEnumQueued):  progress.NQueued,
+		string(batchsqlc.StatusEnumInprog):  progress.NInProg,
+		string(batchsqlc.StatusEnumSuccess): progress.NSuccess,
+		string(batchsqlc.StatusEnumFailed):  progress.NFailed,
+		string(batchsqlc.StatusEnumAborted): progress.NAborted,
+	}
+	if !first.IsZero() {
+		fields[progressFirstField] = first.UnixMilli()
+	}
+
+	key := BatchProgressKey(batchID)
+	_, err := jm.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, key)
+		pipe.HSet(ctx, key, fields)
+		pipe.Expire(ctx, key, time.Duration(expirySec)*time.Second)
+		return nil
+	})
+	return err
+}
+
+// progressFromCounters builds the progress of a batch from its cached counters.
+func progressFromCounters(fields map[string]string, now time.Time) BatchProgress_t {
+	count := func(status batchsqlc.StatusEnum) int {
+		n, _ := strconv.Atoi(fields[string(status)])
+		return max(n, 0)
+	}
+	progress := BatchProgress_t{
+		Status:   batchsqlc.StatusEnum(fields[progressStatusField]),
+		NQueued:  count(batchsqlc.StatusEnumQueued),
+		NInProg:  count(batchsqlc.StatusEnumInprog),
+		NSuccess: count(batchsqlc.StatusEnumSuccess),
+		NFailed:  count(batchsqlc.StatusEnumFailed),
+		NAborted: count(batchsqlc.StatusEnumAborted),
+	}
+	// The batch status is only cached when the counters are, so a batch that was
+	// queued then may have been picked up since
+	if progress.Status == batchsqlc.StatusEnumQueued && progress.NQueued < progress.total() {
+		progress.Status = batchsqlc.StatusEnumInprog
+	}
+
+	var first time.Time
+	if ms, err := strconv.ParseInt(fields[progressFirstField], 10, 64); err == nil {
+		first = time.UnixMilli(ms)
+	}
+	progress.estimate(first, now)
+	return progress
+}
+
+// total returns the number of rows of the batch, whatever their status.
+func (p BatchProgress_t) total() int {
+	return p.NQueued + p.NInProg + p.NSuccess + p.NFailed + p.NAborted
+}
+
+// estimate fills in NTotal, Throughput and ETA, given the time the first row was done.
+func (p *BatchProgress_t) estimate(first, now time.Time) {
+	p.NTotal = p.total()
+	done := p.NSuccess + p.NFailed + p.NAborted
+	elapsed := now.Sub(first).Seconds()
+	if first.IsZero() || done == 0 || elapsed <= 0 {
+		return
+	}
+	p.Throughput = float64(done) / elapsed
+
+	remaining := p.NQueued + p.NInProg
+	if remaining > 0 && !isFinalStatus(p.Status) && p.Status != batchsqlc.StatusEnumPaused {
+		p.ETA = time.Duration(float64(remaining) / p.Throughput * float64(time.Second))
+	}
+}
+
+// trackRowsStarted moves the rows of a fetched block from queued to inprog in the
+// cached progress counters of their batches.
+func (jm *JobManager) trackRowsStarted(ctx context.Context, rows []batchsqlc.FetchBlockOfRowsRow) {
+	started := make(map[uuid.UUID]int)
+	for _, row := range rows {
+		started[row.Batch]++
+	}
+	for batchID, n := range started {
+		jm.updateBatchProgress(ctx, batchID, 0,
+			string(batchsqlc.StatusEnumQueued), -n, string(batchsqlc.StatusEnumInprog), n)
+	}
+}
+
+// trackRowDone moves a row from inprog to its final status in the cached progress
+// counters of its batch.
+func (jm *JobManager) trackRowDone(batchID uuid.UUID, status batchsqlc.StatusEnum) {
+	jm.updateBatchProgress(context.Background(), batchID, time.Now().UnixMilli(),
+		string(batchsqlc.StatusEnumInprog), -1, string(status), 1)
+}
+
+// trackRowRequeued moves a row from inprog back to queued in the cached progress
+// counters of its batch.
+func (jm *JobManager) trackRowRequeued(batchID uuid.UUID) {
+	jm.updateBatchProgress(context.Background(), batchID, 0,
+		string(batchsqlc.StatusEnumInprog), -1, string(batchsqlc.StatusEnumQueued), 1)
+}
+
+// updateBatchProgress applies the field, increment pairs to the cached progress counters
+// of a batch, if there are any. Failures are only logged; the counters are corrected the
+// next time they are counted in the database.
+func (jm *JobManager) updateBatchProgress(ctx context.Context, batchID uuid.UUID, doneAtMs int64, increments ...any) {
+	if jm.redisClient == nil {
+		return
+	}
+	args := append([]any{doneAtMs}, increments...)
+	err := batchProgressScript.Run(ctx, jm.redisClient, []string{BatchProgressKey(batchID.String())}, args...).Err()
+	if err != nil {
+		jm.logger.Warn().LogActivity("Failed to update batch progress in Redis", map[string]any{
+			"batchId": batchID.String(),
+			"error":   err.Error(),
+		})
+	}
+}
+
+// clearBatchProgress drops the cached progress counters of a batch once it is done, so
+// that the next BatchProgress call counts its final rows in the database.
+func (jm *JobManager) clearBatchProgress(batchID uuid.UUID) {
+	if jm.redisClient == nil {
+		return
+	}
+	if err := jm.redisClient.Del(context.Background(), BatchProgressKey(batchID.String())).Err(); err != nil {
+		jm.logger.Warn().LogActivity("Failed to clear batch progress in Redis", map[string]any{
+			"batchId": batchID.String(),
+			"error":   err.Error(),
+		})
+	}
+}
+
+// isFinalStatus reports whether a batch with this status is done.
+func isFinalStatus(status batchsqlc.StatusEnum) bool {
+	return status == batchsqlc.StatusEnumSuccess || status == batchsqlc.StatusEnumFailed || status == batchsqlc.StatusEnumAborted
+}
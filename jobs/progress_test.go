@@ -0,0 +1,142 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/remiges-tech/alya/jobs/pg/batchsqlc"
+	"github.com/remiges-tech/alya/jobs/pg/batchsqlc/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newProgressQuerier(status batchsqlc.StatusEnum, counts batchsqlc.GetBatchProgressRow) *mocks.QuerierMock {
+	return &mocks.QuerierMock{
+		GetBatchByIDFunc: func(ctx context.Context, id uuid.UUID) (batchsqlc.Batch, error) {
+			return batchsqlc.Batch{ID: id, Status: status}, nil
+		},
+		GetBatchProgressFunc: func(ctx context.Context, batch uuid.UUID) (batchsqlc.GetBatchProgressRow, error) {
+			return counts, nil
+		},
+	}
+}
+
+func TestBatchProgress(t *testing.T) {
+	jm, redisClient := newLimitsTestJobManager(t)
+	q := newProgressQuerier(batchsqlc.StatusEnumInprog, batchsqlc.GetBatchProgressRow{
+		Nqueued:     6,
+		Ninprog:     2,
+		Nsuccess:    10,
+		Nfailed:     2,
+		FirstDoneat: dbTimestamp(time.Now().Add(-10 * time.Second)),
+	})
+	jm.queries = q
+	batchID := uuid.New()
+	ctx := context.Background()
+
+	// counted in the database when not cached
+	progress, err := jm.BatchProgress(batchID.String())
+	require.NoError(t, err)
+	assert.Equal(t, batchsqlc.StatusEnumInprog, progress.Status)
+	assert.Equal(t, 20, progress.NTotal)
+	assert.Equal(t, 6, progress.NQueued)
+	assert.Equal(t, 2, progress.NInProg)
+	assert.Equal(t, 10, progress.NSuccess)
+	assert.Equal(t, 2, progress.NFailed)
+	assert.InDelta(t, 1.2, progress.Throughput, 0.05)
+	assert.InDelta(t, 8/1.2, progress.ETA.Seconds(), 0.5)
+
+	ttl, err := redisClient.TTL(ctx, BatchProgressKey(batchID.String())).Result()
+	require.NoError(t, err)
+	assert.Greater(t, ttl, time.Duration(0))
+
+	// then kept up to date in Redis
+	rows := []batchsqlc.FetchBlockOfRowsRow{{Batch: batchID, Rowid: 1}, {Batch: batchID, Rowid: 2}, {Batch: uuid.New(), Rowid: 3}}
+	jm.trackRowsStarted(ctx, rows)
+	jm.trackRowDone(batchID, batchsqlc.StatusEnumSuccess)
+	jm.trackRowDone(batchID, batchsqlc.StatusEnumAborted)
+	jm.trackRowRequeued(batchID)
+
+	progress, err = jm.BatchProgress(batchID.String())
+	require.NoError(t, err)
+	assert.Len(t, q.GetBatchProgressCalls(), 1)
+	assert.Equal(t, 20, progress.NTotal)
+	assert.Equal(t, 5, progress.NQueued)
+	assert.Equal(t, 1, progress.NInProg)
+	assert.Equal(t, 11, progress.NSuccess)
+	assert.Equal(t, 2, progress.NFailed)
+	assert.Equal(t, 1, progress.NAborted)
+
+	// and counted again once the batch is done
+	jm.clearBatchProgress(batchID)
+	_, err = jm.BatchProgress(batchID.String())
+	require.NoError(t, err)
+	assert.Len(t, q.GetBatchProgressCalls(), 2)
+}
+
+func TestRequeueRow_Progress(t *testing.T) {
+	jm, redisClient := newLimitsTestJobManager(t)
+	jm.store = NewMemoryStore()
+	batchID := uuid.New()
+	ctx := context.Background()
+	key := BatchProgressKey(batchID.String())
+	require.NoError(t, redisClient.HSet(ctx, key, "status", "inprog", "queued", 3, "inprog", 1).Err())
+
+	// a row handed back to the queue by shutdown or processor limits is counted as queued again
+	jm.requeueRow(batchsqlc.FetchBlockOfRowsRow{Batch: batchID, Rowid: 1})
+
+	counters, err := redisClient.HGetAll(ctx, key).Result()
+	require.NoError(t, err)
+	assert.Equal(t, "4", counters["queued"])
+	assert.Equal(t, "0", counters["inprog"])
+}
+
+func TestTrackRowDone_NotCached(t *testing.T) {
+	jm, redisClient := newLimitsTestJobManager(t)
+	batchID := uuid.New()
+
+	// counters that were never counted are not started from zero
+	jm.trackRowDone(batchID, batchsqlc.StatusEnumSuccess)
+	n, err := redisClient.Exists(context.Background(), BatchProgressKey(batchID.String())).Result()
+	require.NoError(t, err)
+	assert.Zero(t, n)
+}
+
+func TestProgressFromCounters(t *testing.T) {
+	now := time.Now()
+
+	t.Run("not started", func(t *testing.T) {
+		progress := progressFromCounters(map[string]string{
+			"status": "queued",
+			"queued": "4",
+		}, now)
+		assert.Equal(t, batchsqlc.StatusEnumQueued, progress.Status)
+		assert.Equal(t, 4, progress.NTotal)
+		assert.Zero(t, progress.Throughput)
+		assert.Zero(t, progress.ETA)
+	})
+
+	t.Run("picked up since cached", func(t *testing.T) {
+		progress := progressFromCounters(map[string]string{
+			"status":  "queued",
+			"queued":  "2",
+			"inprog":  "1",
+			"success": "1",
+			"first":   "0",
+		}, now)
+		assert.Equal(t, batchsqlc.StatusEnumInprog, progress.Status)
+	})
+
+	t.Run("done", func(t *testing.T) {
+		progress := progressFromCounters(map[string]string{
+			"status":  "success",
+			"success": "5",
+			"first":   "1700000000000",
+		}, now)
+		assert.Equal(t, 5, progress.NTotal)
+		assert.Greater(t, progress.Throughput, 0.0)
+		assert.Zero(t, progress.ETA)
+	})
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/remiges-tech/alya/jobs/pg/batchsqlc"
 )
 
@@ -33,21 +34,35 @@ func (jm *JobManager) trackRowProcessing(ctx context.Context, rowID int64) error
 	return jm.redisClient.Expire(ctx, key, workerRowsTTL).Err()
 }
 
-// untrackRowProcessing removes a row from this instance's active rows SET in Redis.
+// untrackRowProcessing removes a row from this instance's active rows SET in Redis,
+// or ends its lease without Redis.
 // Uses context.Background() internally instead of accepting a caller context.
 // During shutdown the processing context is cancelled, but this SREM must still
 // succeed -- otherwise the row ID stays in the SET and recovery resets an
 // already-completed row back to 'queued', causing double processing.
 func (jm *JobManager) untrackRowProcessing(rowID int64) error {
+	if jm.leasesInStore() {
+		return jm.queries.ReleaseBatchRowLease(context.Background(), batchsqlc.ReleaseBatchRowLeaseParams{
+			Rowid:  rowID,
+			Worker: jm.instanceID,
+		})
+	}
 	if jm.redisClient == nil {
 		return nil
 	}
 	return jm.redisClient.SRem(context.Background(), workerRowsKey(jm.instanceID), rowID).Err()
 }
 
-// refreshHeartbeat updates this instance's heartbeat TTL in Redis.
+// refreshHeartbeat updates this instance's heartbeat TTL in Redis, or its heartbeat
+// in the workers table without Redis.
 // The heartbeat indicates that this instance is alive and processing.
 func (jm *JobManager) refreshHeartbeat(ctx context.Context) error {
+	if jm.leasesInStore() {
+		return jm.queries.RecordWorkerHeartbeat(ctx, batchsqlc.RecordWorkerHeartbeatParams{
+			InstanceID: jm.instanceID,
+			Heartbeat:  pgtype.Timestamp{Time: time.Now(), Valid: true},
+		})
+	}
 	if jm.redisClient == nil {
 		return nil
 	}
@@ -56,7 +71,8 @@ func (jm *JobManager) refreshHeartbeat(ctx context.Context) error {
 
 // registerWorker adds this instance's ID to the global worker registry SET.
 // This allows recovery to discover all workers without using SCAN
-// (which doesn't work across Redis Cluster nodes).
+// (which doesn't work across Redis Cluster nodes). Without Redis, the heartbeat
+// registers the instance.
 func (jm *JobManager) registerWorker(ctx context.Context) error {
 	if jm.redisClient == nil {
 		return nil
@@ -65,8 +81,12 @@ func (jm *JobManager) registerWorker(ctx context.Context) error {
 }
 
 // deregisterWorker removes this instance's ID from the global worker registry SET.
-// Called during graceful shutdown after heartbeat removal.
+// Called during graceful shutdown after heartbeat removal. Without Redis, it removes
+// the instance and its heartbeat from the workers table.
 func (jm *JobManager) deregisterWorker(ctx context.Context) error {
+	if jm.leasesInStore() {
+		return jm.queries.DeleteWorker(ctx, jm.instanceID)
+	}
 	if jm.redisClient == nil {
 		return nil
 	}
@@ -76,11 +96,15 @@ func (jm *JobManager) deregisterWorker(ctx context.Context) error {
 // recoverAbandonedRows finds rows from dead worker instances and resets them to queued.
 // It reads the worker registry SET to discover all workers, checks if each worker's
 // heartbeat has expired, and if so, resets those rows in the database.
-// Uses the registry instead of SCAN for Redis Cluster compatibility.
+// Uses the registry instead of SCAN for Redis Cluster compatibility. Without Redis,
+// the registry and heartbeats are in the store (see recoverAbandonedRowsInStore).
 func (jm *JobManager) recoverAbandonedRows(ctx context.Context) (int, error) {
-	if jm.redisClient == nil || jm.db == nil {
+	if jm.store == nil {
 		return 0, nil
 	}
+	if jm.redisClient == nil {
+		return jm.recoverAbandonedRowsInStore(ctx)
+	}
 
 	// Get all registered worker instance IDs from the registry
 	instanceIDs, err := jm.redisClient.SMembers(ctx, workerRegistryKey()).Result()
@@ -200,16 +224,16 @@ func (jm *JobManager) recoverRowsFromDeadInstance(ctx context.Context, instanceI
 
 // resetRowsToQueued resets the given rows to 'queued' status.
 // The parent batch status is left as 'inprog'. FetchBlockOfRows selects rows
-// where batchrows.status = 'queued' AND batches.status != 'wait', so the
+// where batchrows.status = 'queued' AND batches.status NOT IN ('wait', 'paused'), so the
 // recovered rows will be picked up without resetting the batch.
 func (jm *JobManager) resetRowsToQueued(ctx context.Context, rowIDs []int64) error {
-	tx, err := jm.db.Begin(ctx)
+	tx, err := jm.store.Begin(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
-	txQueries := batchsqlc.New(tx)
+	txQueries := tx.Queries()
 
 	err = txQueries.ResetRowsToQueued(ctx, rowIDs)
 	if err != nil {
@@ -223,8 +247,8 @@ func (jm *JobManager) resetRowsToQueued(ctx context.Context, rowIDs []int64) err
 // It runs until the process exits. Does not accept a context parameter
 // because the heartbeat must stay alive while the processing loop finishes
 // its current block of rows after shutdown is initiated. Uses
-// context.Background() for Redis operations so they continue working
-// after the caller's context is cancelled.
+// context.Background() for Redis and database operations so they continue
+// working after the caller's context is cancelled.
 func (jm *JobManager) runHeartbeat() {
 	ctx := context.Background()
 
@@ -253,9 +277,12 @@ func (jm *JobManager) runHeartbeat() {
 		if err := jm.refreshHeartbeat(ctx); err != nil {
 			jm.logger.Error(err).LogActivity("Failed to refresh heartbeat", nil)
 		}
-		// Refresh TTL on the rows SET. EXPIRE on a non-existent key (no rows
-		// tracked or all rows untracked) is a no-op.
-		jm.redisClient.Expire(ctx, workerRowsKey(jm.instanceID), workerRowsTTL)
+		// Refresh TTL on the rows SET, or the leases of the rows without Redis
+		if err := jm.renewRowLeases(ctx); err != nil {
+			jm.logger.Error(err).LogActivity("Failed to renew row leases", nil)
+		}
+		// Extend the leases of the in-flight slots of rows still being processed
+		jm.refreshProcessorSlots(ctx)
 	}
 }
 
@@ -338,10 +365,26 @@ func (jm *JobManager) runPeriodicSweep(ctx context.Context) {
 	}
 }
 
-// Shutdown cleans up this instance's Redis keys on graceful shutdown.
-// It removes the heartbeat key. The rows key is intentionally left in place
+// Shutdown cleans up this instance's Redis keys, or its row in the workers table
+// without Redis, on graceful shutdown.
+// It cancels the contexts of the rows being processed, so that context-aware
+// processors wind down and their rows are requeued, and removes the heartbeat key. The rows key is intentionally left in place
 // so that if this instance has active rows, they can be recovered by other instances.
 func (jm *JobManager) Shutdown(ctx context.Context) error {
+	// Ask the processors of the rows being processed to wind down
+	jm.running.cancelAll(ErrShuttingDown)
+
+	if jm.leasesInStore() {
+		// Removing the worker removes its heartbeat too; rows still leased to it are
+		// recovered once their leases expire
+		if err := jm.deregisterWorker(ctx); err != nil {
+			return fmt.Errorf("failed to remove heartbeat: %w", err)
+		}
+		jm.logger.Info().LogActivity("JobManager shutdown complete", map[string]any{
+			"instanceID": jm.instanceID,
+		})
+		return nil
+	}
 	if jm.redisClient == nil {
 		return nil
 	}
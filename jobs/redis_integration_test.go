@@ -48,7 +48,7 @@ func TestUpdateStatusInRedis_Integration(t *testing.T) {
 		status := batchsqlc.StatusEnumSuccess
 		expirySec := 60
 
-		err := updateStatusInRedis(client, batchID, status, expirySec)
+		err := updateStatusInRedis(NewRedisStatusCache(client), batchID, status, expirySec)
 		require.NoError(t, err)
 
 		key := BatchStatusKey(batchID.String())
@@ -69,10 +69,10 @@ func TestUpdateStatusInRedis_Integration(t *testing.T) {
 		batchID := uuid.New()
 		expirySec := 60
 
-		err := updateStatusInRedis(client, batchID, batchsqlc.StatusEnumQueued, expirySec)
+		err := updateStatusInRedis(NewRedisStatusCache(client), batchID, batchsqlc.StatusEnumQueued, expirySec)
 		require.NoError(t, err)
 
-		err = updateStatusInRedis(client, batchID, batchsqlc.StatusEnumSuccess, expirySec)
+		err = updateStatusInRedis(NewRedisStatusCache(client), batchID, batchsqlc.StatusEnumSuccess, expirySec)
 		require.NoError(t, err)
 
 		key := BatchStatusKey(batchID.String())
@@ -94,7 +94,7 @@ func TestUpdateStatusInRedis_Integration(t *testing.T) {
 
 		for _, status := range statuses {
 			batchID := uuid.New()
-			err := updateStatusInRedis(client, batchID, status, 60)
+			err := updateStatusInRedis(NewRedisStatusCache(client), batchID, status, 60)
 			require.NoError(t, err, "Failed for status %s", status)
 
 			key := BatchStatusKey(batchID.String())
@@ -130,7 +130,7 @@ func TestUpdateStatusAndOutputFilesDataInRedis_Integration(t *testing.T) {
 		result := `{"data": "test result"}`
 		expirySec := 60
 
-		err := updateStatusAndOutputFilesDataInRedis(client, batchID, status, outputFiles, result, expirySec)
+		err := updateStatusAndOutputFilesDataInRedis(NewRedisStatusCache(client), batchID, status, outputFiles, result, expirySec)
 		require.NoError(t, err)
 
 		statusKey := BatchStatusKey(batchID.String())
@@ -164,14 +164,14 @@ func TestUpdateStatusAndOutputFilesDataInRedis_Integration(t *testing.T) {
 		batchID := uuid.New()
 		expirySec := 60
 
-		err := updateStatusAndOutputFilesDataInRedis(client, batchID,
+		err := updateStatusAndOutputFilesDataInRedis(NewRedisStatusCache(client), batchID,
 			batchsqlc.StatusEnumInprog,
 			map[string]string{"old.txt": "old-id"},
 			`{"old": "data"}`,
 			expirySec)
 		require.NoError(t, err)
 
-		err = updateStatusAndOutputFilesDataInRedis(client, batchID,
+		err = updateStatusAndOutputFilesDataInRedis(NewRedisStatusCache(client), batchID,
 			batchsqlc.StatusEnumSuccess,
 			map[string]string{"new.txt": "new-id"},
 			`{"new": "data"}`,
@@ -202,7 +202,7 @@ func TestUpdateStatusAndOutputFilesDataInRedis_Integration(t *testing.T) {
 		batchID := uuid.New()
 		expirySec := 60
 
-		err := updateStatusAndOutputFilesDataInRedis(client, batchID,
+		err := updateStatusAndOutputFilesDataInRedis(NewRedisStatusCache(client), batchID,
 			batchsqlc.StatusEnumSuccess,
 			map[string]string{},
 			`{"result": "no files"}`,
@@ -244,7 +244,7 @@ func TestUpdateBatchSummaryInRedis_Integration(t *testing.T) {
 		nsuccess, nfailed, naborted := 10, 2, 1
 		expirySec := 60
 
-		err := updateBatchSummaryInRedis(client, batchID, status, outputFiles,
+		err := updateBatchSummaryInRedis(NewRedisStatusCache(client), batchID, status, outputFiles,
 			nsuccess, nfailed, naborted, expirySec)
 		require.NoError(t, err)
 
@@ -277,7 +277,7 @@ func TestUpdateBatchSummaryInRedis_Integration(t *testing.T) {
 		expirySec := 60
 
 		// First write
-		err := updateBatchSummaryInRedis(client, batchID,
+		err := updateBatchSummaryInRedis(NewRedisStatusCache(client), batchID,
 			batchsqlc.StatusEnumInprog,
 			map[string]string{"old.txt": "old-id"},
 			5, 0, 0,
@@ -285,7 +285,7 @@ func TestUpdateBatchSummaryInRedis_Integration(t *testing.T) {
 		require.NoError(t, err)
 
 		// Second write (overwrite)
-		err = updateBatchSummaryInRedis(client, batchID,
+		err = updateBatchSummaryInRedis(NewRedisStatusCache(client), batchID,
 			batchsqlc.StatusEnumSuccess,
 			map[string]string{"new.txt": "new-id"},
 			10, 2, 1,
@@ -313,7 +313,7 @@ func TestUpdateBatchSummaryInRedis_Integration(t *testing.T) {
 		batchID := uuid.New()
 		expirySec := 60
 
-		err := updateBatchSummaryInRedis(client, batchID,
+		err := updateBatchSummaryInRedis(NewRedisStatusCache(client), batchID,
 			batchsqlc.StatusEnumSuccess,
 			map[string]string{},
 			5, 0, 0,
@@ -26,6 +26,19 @@ func BatchSummaryKey(batchID string) string {
 	return fmt.Sprintf("ALYA_{%s}_SUMMARY", batchID)
 }
 
+// BatchProgressKey returns the Redis key for the progress counters of a batch.
+// Uses hash tag {batchID} for Redis Cluster slot co-location.
+func BatchProgressKey(batchID string) string {
+	return fmt.Sprintf("ALYA_{%s}_PROGRESS", batchID)
+}
+
+// abortChannel returns the Redis pub/sub channel on which the IDs of aborted
+// batches and slow queries are published, so that every instance can interrupt
+// the rows of them it is processing.
+func abortChannel() string {
+	return "ALYA_ABORTS"
+}
+
 // workerRegistryKey returns the Redis key for the global worker registry SET.
 // All workers register their instance IDs in this SET so recovery can discover
 // them without using SCAN (which doesn't work across Redis Cluster nodes).
@@ -45,3 +58,16 @@ func workerRowsKey(instanceID string) string {
 	return fmt.Sprintf("ALYA_{%s}_ROWS", instanceID)
 }
 
+// processorSlotsKey returns the Redis key for the sorted set of rows of an
+// (app, op) currently being processed, used to enforce ProcessorLimits.MaxInFlight.
+// Uses hash tag {app:op} for Redis Cluster slot co-location.
+func processorSlotsKey(app, op string) string {
+	return fmt.Sprintf("ALYA_{%s:%s}_INFLIGHT", app, op)
+}
+
+// processorRateKey returns the Redis key for the token bucket of an (app, op),
+// used to enforce ProcessorLimits.RatePerSec.
+// Uses hash tag {app:op} for Redis Cluster slot co-location.
+func processorRateKey(app, op string) string {
+	return fmt.Sprintf("ALYA_{%s:%s}_RATE", app, op)
+}
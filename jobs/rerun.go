@@ -0,0 +1,305 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/remiges-tech/alya/jobs/pg/batchsqlc"
+)
+
+var (
+	ErrBatchNotDone       = errors.New("batch is not done")
+	ErrNoRowsToRetry      = errors.New("batch has no rows to retry")
+	ErrInvalidRetryFilter = errors.New("invalid retry filter")
+	ErrSlowQueryRerun     = errors.New("slow queries cannot be re-run")
+)
+
+// RetryFilter_t selects the rows of a done batch that BatchRetryFailed re-runs.
+type RetryFilter_t struct {
+	// Statuses of the rows to re-run: failed, aborted or both. Both if empty.
+	Statuses []batchsqlc.StatusEnum
+	// Lines to re-run, among the rows with one of Statuses. All of them if empty.
+	Lines []int
+	// Inputs holds corrected input by line number, for rows that should not be
+	// re-run with their original input. Every line must be one that is re-run.
+	Inputs map[int]JSONstr
+	// ChildBatch re-runs the rows in a new batch whose parent is the original one,
+	// instead of requeueing them in the original batch.
+	ChildBatch bool
+}
+
+// BatchRowAttempt_t is an earlier attempt of a row that was re-run in its own batch.
+type BatchRowAttempt_t struct {
+	Line        int
+	Input       JSONstr
+	Status      BatchStatus_t
+	Res         JSONstr
+	Messages    JSONstr
+	DoneBy      string
+	DoneAt      time.Time
+	OutputFiles map[string]string // output files of the batch as summarised after this attempt
+	ArchivedAt  time.Time
+}
+
+// BatchRetryFailed re-runs the failed and aborted rows of a done batch, as selected by
+// filter, with their corrected input if filter has any.
+//
+// By default the rows are requeued in the batch itself: their earlier results are moved
+// to the row history (see BatchRowHistory), the batch goes back to queued, and once the
+// rows are done it is summarised again, with new output files and a new completion
+// webhook. With filter.ChildBatch the batch is left as it is and the rows are copied,
+// with their original line numbers, to a new batch with the same app, op, context,
+// priority and callback URL, whose parent is the original batch.
+//
+// It returns the ID of the batch in which the rows run, which is batchID unless
+// filter.ChildBatch is set, and the number of rows re-run.
+func (jm *JobManager) BatchRetryFailed(batchID string, filter RetryFilter_t) (rerunBatchID string, nrows int, err error) {
+	ctx := context.Background()
+
+	batchUUID, err := uuid.Parse(batchID)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid batch ID: %w", err)
+	}
+
+	// Start a transaction
+	tx, err := jm.store.Begin(ctx)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Create transaction-bound queries
+	txQueries := tx.Queries()
+
+	batch, err := txQueries.GetBatchByID(ctx, batchUUID)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to get batch by ID: %w", err)
+	}
+
+	rerunUUID, nrows, err := jm.rerunRows(ctx, txQueries, batch, filter)
+	if err != nil {
+		return "", 0, err
+	}
+
+	// Commit the transaction
+	err = tx.Commit(ctx)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	if rerunUUID == batchUUID {
+		jm.clearBatchCache(batchUUID)
+		// Its output files are assembled again from scratch
+		jm.discardOutputCheckpoint(ctx, batchUUID)
+	}
+
+	jm.logger.Info().LogActivity("Batch rows queued for re-run", map[string]any{
+		"batchId":      batchID,
+		"rerunBatchId": rerunUUID.String(),
+		"nrows":        nrows,
+	})
+	return rerunUUID.String(), nrows, nil
+}
+
+// rerunRows requeues the rows of batch selected by filter, in batch itself or in a new
+// child batch, using the given (normally transaction-bound) queries. It returns the ID
+// of the batch in which the rows run and their number.
+func (jm *JobManager) rerunRows(ctx context.Context, q batchsqlc.Querier, batch batchsqlc.Batch, filter RetryFilter_t) (uuid.UUID, int, error) {
+	if !isFinalStatus(batch.Status) {
+		return uuid.Nil, 0, fmt.Errorf("%w: batch %s is %s", ErrBatchNotDone, batch.ID, batch.Status)
+	}
+
+	statuses := filter.Statuses
+	if len(statuses) == 0 {
+		statuses = []batchsqlc.StatusEnum{batchsqlc.StatusEnumFailed, batchsqlc.StatusEnumAborted}
+	}
+	statusParams := make([]string, len(statuses))
+	for i, status := range statuses {
+		if status != batchsqlc.StatusEnumFailed && status != batchsqlc.StatusEnumAborted {
+			return uuid.Nil, 0, fmt.Errorf("%w: only failed and aborted rows can be re-run, not %s", ErrInvalidRetryFilter, status)
+		}
+		statusParams[i] = string(status)
+	}
+	lineParams := make([]int32, len(filter.Lines))
+	for i, line := range filter.Lines {
+		lineParams[i] = int32(line)
+	}
+
+	rows, err := q.GetBatchRowsForRerun(ctx, batchsqlc.GetBatchRowsForRerunParams{
+		Batch:    batch.ID,
+		Statuses: statusParams,
+		Lines:    lineParams,
+	})
+	if err != nil {
+		return uuid.Nil, 0, fmt.Errorf("failed to get rows to re-run: %w", err)
+	}
+	if len(rows) == 0 {
+		return uuid.Nil, 0, fmt.Errorf("%w: batch %s", ErrNoRowsToRetry, batch.ID)
+	}
+	if rows[0].Line == 0 {
+		return uuid.Nil, 0, fmt.Errorf("%w: %s", ErrSlowQueryRerun, batch.ID)
+	}
+
+	// Every corrected input must belong to a row that is re-run
+	for line, input := range filter.Inputs {
+		if !input.IsValid() {
+			return uuid.Nil, 0, fmt.Errorf("%w: invalid input for line %d", ErrInvalidRetryFilter, line)
+		}
+		if !slices.ContainsFunc(rows, func(row batchsqlc.GetBatchRowsForRerunRow) bool { return int(row.Line) == line }) {
+			return uuid.Nil, 0, fmt.Errorf("%w: line %d is not re-run", ErrInvalidRetryFilter, line)
+		}
+	}
+
+	if filter.ChildBatch {
+		childUUID, err := rerunInChildBatch(ctx, q, batch, rows, filter.Inputs)
+		if err != nil {
+			return uuid.Nil, 0, err
+		}
+		if err := jm.notifyJobsQueued(ctx, q, batch.App); err != nil {
+			return uuid.Nil, 0, err
+		}
+		return childUUID, len(rows), nil
+	}
+
+	rowids := make([]int64, len(rows))
+	for i, row := range rows {
+		rowids[i] = row.Rowid
+	}
+
+	// Keep the earlier results of the rows before they are cleared
+	if err := q.ArchiveBatchRows(ctx, rowids); err != nil {
+		return uuid.Nil, 0, fmt.Errorf("failed to archive batch rows: %w", err)
+	}
+	if err := q.RequeueBatchRowsForRerun(ctx, rowids); err != nil {
+		return uuid.Nil, 0, fmt.Errorf("failed to requeue batch rows: %w", err)
+	}
+	for _, row := range rows {
+		input, ok := filter.Inputs[int(row.Line)]
+		if !ok {
+			continue
+		}
+		err := q.UpdateBatchRowInput(ctx, batchsqlc.UpdateBatchRowInputParams{
+			Input: []byte(input.String()),
+			Rowid: row.Rowid,
+		})
+		if err != nil {
+			return uuid.Nil, 0, fmt.Errorf("failed to update input of line %d: %w", row.Line, err)
+		}
+	}
+
+	// Put the batch back in the queue, so that it is summarised again when its rows are done
+	if err := q.ReopenBatch(ctx, batch.ID); err != nil {
+		return uuid.Nil, 0, fmt.Errorf("failed to reopen batch: %w", err)
+	}
+	if err := jm.notifyJobsQueued(ctx, q, batch.App); err != nil {
+		return uuid.Nil, 0, err
+	}
+	return batch.ID, len(rows), nil
+}
+
+// rerunInChildBatch creates a new batch, whose parent is batch, with the given rows of
+// batch, and returns its ID.
+func rerunInChildBatch(ctx context.Context, q batchsqlc.Querier, batch batchsqlc.Batch, rows []batchsqlc.GetBatchRowsForRerunRow, inputs map[int]JSONstr) (uuid.UUID, error) {
+	childUUID, err := uuid.NewUUID()
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	err = insertBatchRecord(ctx, q, childUUID, batch.App, batch.Op, JSONstr{value: string(batch.Context), valid: true}, SubmitOptions_t{
+		Priority:    int(batch.Priority),
+		CallbackURL: batch.CallbackUrl.String,
+	})
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to insert child batch: %w", err)
+	}
+	err = q.SetBatchParent(ctx, batchsqlc.SetBatchParentParams{
+		ParentID: pgtype.UUID{Bytes: batch.ID, Valid: true},
+		ID:       childUUID,
+	})
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to set parent of child batch: %w", err)
+	}
+
+	reqat := pgtype.Timestamp{Time: time.Now(), Valid: true}
+	childRows := make([]batchsqlc.CopyBatchRowsParams, len(rows))
+	for i, row := range rows {
+		input := row.Input
+		if corrected, ok := inputs[int(row.Line)]; ok {
+			input = []byte(corrected.String())
+		}
+		childRows[i] = batchsqlc.CopyBatchRowsParams{
+			Batch:    childUUID,
+			Line:     row.Line,
+			Input:    input,
+			Reqat:    reqat,
+			Priority: batch.Priority,
+		}
+	}
+	if _, err := q.CopyBatchRows(ctx, childRows); err != nil {
+		return uuid.Nil, fmt.Errorf("failed to copy batch rows: %w", err)
+	}
+	return childUUID, nil
+}
+
+// clearBatchCache drops everything cached about a batch that has been put back in the
+// queue, so that BatchDone and BatchStatus stop reporting its earlier summary.
+func (jm *JobManager) clearBatchCache(batchID uuid.UUID) {
+	jm.clearBatchProgress(batchID)
+	if jm.statusCache == nil {
+		return
+	}
+	id := batchID.String()
+	err := jm.statusCache.Del(context.Background(), BatchStatusKey(id), BatchSummaryKey(id), BatchOutputFilesKey(id), BatchResultKey(id))
+	if err != nil {
+		jm.logger.Warn().LogActivity("Failed to clear batch status cache", map[string]any{
+			"batchId": id,
+			"error":   err.Error(),
+		})
+	}
+}
+
+// BatchRowHistory returns the earlier attempts of the rows of a batch that were re-run
+// in the batch itself by BatchRetryFailed, by line and then oldest first.
+func (jm *JobManager) BatchRowHistory(batchID string) ([]BatchRowAttempt_t, error) {
+	batchUUID, err := uuid.Parse(batchID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid batch ID: %w", err)
+	}
+	rows, err := jm.queries.GetBatchRowHistory(context.Background(), batchUUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get batch row history: %w", err)
+	}
+
+	attempts := make([]BatchRowAttempt_t, 0, len(rows))
+	for _, row := range rows {
+		a := BatchRowAttempt_t{
+			Line:       int(row.Line),
+			Input:      JSONstr{value: string(row.Input), valid: true},
+			Status:     mapStatusEnum(row.Status),
+			DoneBy:     row.Doneby.String,
+			ArchivedAt: localTime(row.ArchivedAt.Time),
+		}
+		if a.Res, err = NewJSONstr(string(row.Res)); err != nil {
+			return nil, fmt.Errorf("failed to parse Res JSON for line %d: %w", row.Line, err)
+		}
+		if a.Messages, err = NewJSONstr(string(row.Messages)); err != nil {
+			return nil, fmt.Errorf("failed to parse Messages JSON for line %d: %w", row.Line, err)
+		}
+		if row.Doneat.Valid {
+			a.DoneAt = localTime(row.Doneat.Time)
+		}
+		if len(row.Outputfiles) > 0 {
+			if err := json.Unmarshal(row.Outputfiles, &a.OutputFiles); err != nil {
+				return nil, fmt.Errorf("failed to parse output files for line %d: %w", row.Line, err)
+			}
+		}
+		attempts = append(attempts, a)
+	}
+	return attempts, nil
+}
@@ -0,0 +1,188 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/remiges-tech/alya/jobs/pg/batchsqlc"
+	"github.com/remiges-tech/alya/jobs/pg/batchsqlc/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newRerunQuerier returns a mock that hands out rows as the rows to re-run and accepts
+// every update.
+func newRerunQuerier(rows ...batchsqlc.GetBatchRowsForRerunRow) *mocks.QuerierMock {
+	return &mocks.QuerierMock{
+		GetBatchRowsForRerunFunc: func(ctx context.Context, arg batchsqlc.GetBatchRowsForRerunParams) ([]batchsqlc.GetBatchRowsForRerunRow, error) {
+			return rows, nil
+		},
+		ArchiveBatchRowsFunc: func(ctx context.Context, rowids []int64) error {
+			return nil
+		},
+		RequeueBatchRowsForRerunFunc: func(ctx context.Context, rowids []int64) error {
+			return nil
+		},
+		UpdateBatchRowInputFunc: func(ctx context.Context, arg batchsqlc.UpdateBatchRowInputParams) error {
+			return nil
+		},
+		ReopenBatchFunc: func(ctx context.Context, id uuid.UUID) error {
+			return nil
+		},
+		InsertIntoBatchesFunc: func(ctx context.Context, arg batchsqlc.InsertIntoBatchesParams) (uuid.UUID, error) {
+			return arg.ID, nil
+		},
+		SetBatchParentFunc: func(ctx context.Context, arg batchsqlc.SetBatchParentParams) error {
+			return nil
+		},
+		CopyBatchRowsFunc: func(ctx context.Context, arg []batchsqlc.CopyBatchRowsParams) (int64, error) {
+			return int64(len(arg)), nil
+		},
+		NotifyJobsQueuedFunc: func(ctx context.Context, app string) (string, error) {
+			return "", nil
+		},
+	}
+}
+
+func rerunTestBatch(status batchsqlc.StatusEnum) batchsqlc.Batch {
+	return batchsqlc.Batch{ID: uuid.New(), App: "bank", Op: "transfer", Context: []byte(`{"branch":"north"}`), Status: status, Priority: 3}
+}
+
+func TestRerunRows_SameBatch(t *testing.T) {
+	q := newRerunQuerier(
+		batchsqlc.GetBatchRowsForRerunRow{Rowid: 11, Line: 2, Input: []byte(`{"amount":0}`), Status: batchsqlc.StatusEnumFailed},
+		batchsqlc.GetBatchRowsForRerunRow{Rowid: 12, Line: 5, Input: []byte(`{"amount":9}`), Status: batchsqlc.StatusEnumAborted},
+	)
+	jm := newTestJobManager(q, nil)
+	batch := rerunTestBatch(batchsqlc.StatusEnumFailed)
+
+	rerunID, nrows, err := jm.rerunRows(context.Background(), q, batch, RetryFilter_t{
+		Inputs: map[int]JSONstr{2: mustJSONstr(t, `{"amount":4}`)},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, batch.ID, rerunID)
+	assert.Equal(t, 2, nrows)
+
+	fetch := q.GetBatchRowsForRerunCalls()[0].Arg
+	assert.Equal(t, []string{"failed", "aborted"}, fetch.Statuses)
+	assert.Empty(t, fetch.Lines)
+
+	// The rows are archived before they are requeued
+	require.Len(t, q.ArchiveBatchRowsCalls(), 1)
+	assert.Equal(t, []int64{11, 12}, q.ArchiveBatchRowsCalls()[0].Rowids)
+	require.Len(t, q.RequeueBatchRowsForRerunCalls(), 1)
+	assert.Equal(t, []int64{11, 12}, q.RequeueBatchRowsForRerunCalls()[0].Rowids)
+
+	require.Len(t, q.UpdateBatchRowInputCalls(), 1)
+	assert.Equal(t, int64(11), q.UpdateBatchRowInputCalls()[0].Arg.Rowid)
+	assert.JSONEq(t, `{"amount":4}`, string(q.UpdateBatchRowInputCalls()[0].Arg.Input))
+
+	require.Len(t, q.ReopenBatchCalls(), 1)
+	assert.Equal(t, batch.ID, q.ReopenBatchCalls()[0].ID)
+	assert.Empty(t, q.InsertIntoBatchesCalls())
+}
+
+func TestRerunRows_ChildBatch(t *testing.T) {
+	q := newRerunQuerier(
+		batchsqlc.GetBatchRowsForRerunRow{Rowid: 11, Line: 2, Input: []byte(`{"amount":0}`), Status: batchsqlc.StatusEnumFailed},
+		batchsqlc.GetBatchRowsForRerunRow{Rowid: 12, Line: 5, Input: []byte(`{"amount":9}`), Status: batchsqlc.StatusEnumFailed},
+	)
+	jm := newTestJobManager(q, nil)
+	batch := rerunTestBatch(batchsqlc.StatusEnumFailed)
+
+	rerunID, nrows, err := jm.rerunRows(context.Background(), q, batch, RetryFilter_t{
+		Statuses:   []batchsqlc.StatusEnum{batchsqlc.StatusEnumFailed},
+		Lines:      []int{2, 5},
+		Inputs:     map[int]JSONstr{5: mustJSONstr(t, `{"amount":1}`)},
+		ChildBatch: true,
+	})
+	require.NoError(t, err)
+	assert.NotEqual(t, batch.ID, rerunID)
+	assert.Equal(t, 2, nrows)
+	assert.Equal(t, []int32{2, 5}, q.GetBatchRowsForRerunCalls()[0].Arg.Lines)
+
+	require.Len(t, q.InsertIntoBatchesCalls(), 1)
+	child := q.InsertIntoBatchesCalls()[0].Arg
+	assert.Equal(t, rerunID, child.ID)
+	assert.Equal(t, "transfer", child.Op)
+	assert.Equal(t, int32(3), child.Priority)
+	assert.JSONEq(t, `{"branch":"north"}`, string(child.Context))
+
+	require.Len(t, q.SetBatchParentCalls(), 1)
+	assert.Equal(t, rerunID, q.SetBatchParentCalls()[0].Arg.ID)
+	assert.Equal(t, [16]byte(batch.ID), q.SetBatchParentCalls()[0].Arg.ParentID.Bytes)
+
+	require.Len(t, q.CopyBatchRowsCalls(), 1)
+	rows := q.CopyBatchRowsCalls()[0].Arg
+	require.Len(t, rows, 2)
+	assert.Equal(t, int32(2), rows[0].Line)
+	assert.JSONEq(t, `{"amount":0}`, string(rows[0].Input))
+	assert.JSONEq(t, `{"amount":1}`, string(rows[1].Input))
+
+	// The original batch is left alone
+	assert.Empty(t, q.ArchiveBatchRowsCalls())
+	assert.Empty(t, q.ReopenBatchCalls())
+}
+
+func TestRerunRows_Rejected(t *testing.T) {
+	failedRow := batchsqlc.GetBatchRowsForRerunRow{Rowid: 11, Line: 2, Input: []byte(`{}`), Status: batchsqlc.StatusEnumFailed}
+
+	tests := []struct {
+		name   string
+		status batchsqlc.StatusEnum
+		rows   []batchsqlc.GetBatchRowsForRerunRow
+		filter RetryFilter_t
+		want   error
+	}{
+		{"batch not done", batchsqlc.StatusEnumInprog, []batchsqlc.GetBatchRowsForRerunRow{failedRow}, RetryFilter_t{}, ErrBatchNotDone},
+		{"successful rows", batchsqlc.StatusEnumSuccess, []batchsqlc.GetBatchRowsForRerunRow{failedRow}, RetryFilter_t{Statuses: []batchsqlc.StatusEnum{batchsqlc.StatusEnumSuccess}}, ErrInvalidRetryFilter},
+		{"no rows", batchsqlc.StatusEnumSuccess, nil, RetryFilter_t{}, ErrNoRowsToRetry},
+		{"input for other line", batchsqlc.StatusEnumFailed, []batchsqlc.GetBatchRowsForRerunRow{failedRow}, RetryFilter_t{Inputs: map[int]JSONstr{3: {value: `{}`, valid: true}}}, ErrInvalidRetryFilter},
+		{"invalid input", batchsqlc.StatusEnumFailed, []batchsqlc.GetBatchRowsForRerunRow{failedRow}, RetryFilter_t{Inputs: map[int]JSONstr{2: {}}}, ErrInvalidRetryFilter},
+		{"slow query", batchsqlc.StatusEnumFailed, []batchsqlc.GetBatchRowsForRerunRow{{Rowid: 11, Line: 0, Status: batchsqlc.StatusEnumFailed}}, RetryFilter_t{}, ErrSlowQueryRerun},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := newRerunQuerier(tt.rows...)
+			jm := newTestJobManager(q, nil)
+
+			_, _, err := jm.rerunRows(context.Background(), q, rerunTestBatch(tt.status), tt.filter)
+			assert.ErrorIs(t, err, tt.want)
+			assert.Empty(t, q.RequeueBatchRowsForRerunCalls())
+			assert.Empty(t, q.InsertIntoBatchesCalls())
+		})
+	}
+}
+
+func TestBatchRowHistory(t *testing.T) {
+	q := &mocks.QuerierMock{
+		GetBatchRowHistoryFunc: func(ctx context.Context, batch uuid.UUID) ([]batchsqlc.BatchrowHistory, error) {
+			return []batchsqlc.BatchrowHistory{{
+				ID:          1,
+				Batch:       batch,
+				Line:        2,
+				Input:       []byte(`{"amount":0}`),
+				Status:      batchsqlc.StatusEnumFailed,
+				Doneat:      dbTimestamp(time.Now().Add(-time.Hour)),
+				Messages:    []byte(`[{"msgid":1}]`),
+				Outputfiles: []byte(`{"errors.csv":"obj-1"}`),
+				ArchivedAt:  dbTimestamp(time.Now()),
+			}}, nil
+		},
+	}
+	jm := newTestJobManager(q, nil)
+
+	attempts, err := jm.BatchRowHistory(uuid.NewString())
+	require.NoError(t, err)
+	require.Len(t, attempts, 1)
+	assert.Equal(t, 2, attempts[0].Line)
+	assert.Equal(t, BatchFailed, attempts[0].Status)
+	assert.Equal(t, "{}", attempts[0].Res.String())
+	assert.Equal(t, map[string]string{"errors.csv": "obj-1"}, attempts[0].OutputFiles)
+	assert.WithinDuration(t, time.Now().Add(-time.Hour), attempts[0].DoneAt, 5*time.Second)
+
+	_, err = jm.BatchRowHistory("not-a-uuid")
+	assert.Error(t, err)
+}
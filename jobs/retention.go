@@ -0,0 +1,543 @@
+package jobs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/remiges-tech/alya/jobs/objstore"
+	"github.com/remiges-tech/alya/jobs/pg/batchsqlc"
+)
+
+const (
+	// retentionInterval is how often the retention loop purges the batches of
+	// the apps with a retention policy.
+	retentionInterval = time.Hour
+
+	// purgeChunkNBatches is the number of batches archived and deleted per
+	// transaction, and so per archive object.
+	purgeChunkNBatches = 100
+
+	// archivePageNRows is the number of rows of a batch read at a time while
+	// archiving it.
+	archivePageNRows = 1000
+)
+
+var ErrInvalidRetentionPolicy = errors.New("invalid retention policy")
+
+// RetentionPolicy_t says how long the done batches and slow queries of an app are kept.
+type RetentionPolicy_t struct {
+	KeepDays int  // jobs done more than this many days ago are purged
+	DryRun   bool // only report what would be purged
+}
+
+// PurgeReport_t describes one purge of the jobs of an app. In a dry run it describes
+// what would have been purged, and ArchiveObjects is empty.
+type PurgeReport_t struct {
+	App            string
+	Cutoff         time.Time // jobs done before this time are purged
+	DryRun         bool
+	NBatches       int      // batches and slow queries purged
+	NRows          int      // their batchrows records
+	NOutputFiles   int      // their output files in the object store
+	ArchiveObjects []string // objects in JobManagerConfig.ArchiveBucket the jobs were exported to
+
+	// DroppedPartitions are the batchrows partitions dropped, with
+	// JobManagerConfig.PartitionedBatchRows
+	DroppedPartitions []string
+}
+
+// ArchivedBatch_t is one line of an archive object: a purged batch or slow query with
+// all its rows.
+type ArchivedBatch_t struct {
+	ID          string               `json:"id"`
+	App         string               `json:"app"`
+	Op          string               `json:"op"`
+	Context     json.RawMessage      `json:"context"`
+	InputFile   string               `json:"inputfile,omitempty"`
+	Status      batchsqlc.StatusEnum `json:"status"`
+	ReqAt       time.Time            `json:"reqat"`
+	DoneAt      time.Time            `json:"doneat"`
+	OutputFiles map[string]string    `json:"outputfiles,omitempty"`
+	NSuccess    int                  `json:"nsuccess"`
+	NFailed     int                  `json:"nfailed"`
+	NAborted    int                  `json:"naborted"`
+	Rows        []ArchivedBatchRow_t `json:"rows"`
+}
+
+// ArchivedBatchRow_t is a row of an ArchivedBatch_t.
+type ArchivedBatchRow_t struct {
+	Line     int                  `json:"line"`
+	Input    json.RawMessage      `json:"input"`
+	Status   batchsqlc.StatusEnum `json:"status"`
+	DoneAt   time.Time            `json:"doneat,omitzero"`
+	Res      json.RawMessage      `json:"res,omitempty"`
+	BlobRows json.RawMessage      `json:"blobrows,omitempty"`
+	Messages json.RawMessage      `json:"messages,omitempty"`
+	DoneBy   string               `json:"doneby,omitempty"`
+}
+
+// RegisterRetentionPolicy sets the retention policy of an app. Run and RunWithContext
+// then purge the app's batches and slow queries once they have been done for more than
+// policy.KeepDays days, checking every hour; see PurgeBatches. Registering a policy for
+// an app again replaces it.
+func (jm *JobManager) RegisterRetentionPolicy(app string, policy RetentionPolicy_t) error {
+	if app == "" {
+		return fmt.Errorf("%w: app is required", ErrInvalidRetentionPolicy)
+	}
+	if policy.KeepDays <= 0 {
+		return fmt.Errorf("%w: KeepDays must be positive, got %d", ErrInvalidRetentionPolicy, policy.KeepDays)
+	}
+
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+	jm.retention[app] = policy
+	return nil
+}
+
+// PurgeBatches purges the batches and slow queries of app that were done more than
+// policy.KeepDays days ago.
+//
+// The jobs are purged oldest first, in chunks. Each chunk is exported to the object
+// store as a gzip-compressed JSON Lines object in JobManagerConfig.ArchiveBucket, one
+// ArchivedBatch_t per line, and then deleted from the batches, batchrows and
+// batch_files tables in one transaction. If the transaction does not commit, the
+// archive is deleted. Once it commits, the output files of the jobs are deleted from
+// the object store and their Redis keys are dropped.
+// Concurrent purges of the same app, from any JobManager instance, skip each other's
+// chunks.
+//
+// With JobManagerConfig.PartitionedBatchRows the rows are not deleted with their batch.
+// Instead, once the batches are purged, the batchrows partitions that end before the
+// cutoff and hold no rows of remaining batches, of any app, are dropped.
+//
+// With policy.DryRun nothing is exported or deleted; the report says what would be.
+func (jm *JobManager) PurgeBatches(app string, policy RetentionPolicy_t) (PurgeReport_t, error) {
+	ctx := context.Background()
+	if policy.KeepDays <= 0 {
+		return PurgeReport_t{}, fmt.Errorf("%w: KeepDays must be positive, got %d", ErrInvalidRetentionPolicy, policy.KeepDays)
+	}
+
+	report := PurgeReport_t{
+		App:    app,
+		Cutoff: time.Now().AddDate(0, 0, -policy.KeepDays),
+		DryRun: policy.DryRun,
+	}
+	cutoff := pgtype.Timestamp{Time: report.Cutoff, Valid: true}
+
+	if policy.DryRun {
+		counts, err := jm.queries.CountBatchesToPurge(ctx, batchsqlc.CountBatchesToPurgeParams{App: app, Cutoff: cutoff})
+		if err != nil {
+			return report, fmt.Errorf("failed to count batches to purge: %w", err)
+		}
+		report.NBatches = int(counts.Nbatches)
+		report.NRows = int(counts.Nrows)
+		report.NOutputFiles = int(counts.Noutputfiles)
+		return report, nil
+	}
+
+	for {
+		n, err := jm.purgeChunk(ctx, app, cutoff, &report)
+		if err != nil {
+			return report, err
+		}
+		if n < purgeChunkNBatches {
+			break
+		}
+	}
+
+	if jm.config.PartitionedBatchRows {
+		dropped, err := jm.queries.DropBatchRowPartitions(ctx, cutoff)
+		if err != nil {
+			return report, fmt.Errorf("failed to drop batchrows partitions: %w", err)
+		}
+		report.DroppedPartitions = dropped
+	}
+	return report, nil
+}
+
+// purgeChunk purges one chunk of the batches of app done before cutoff in its own
+// transaction, adds it to report, and returns the number of batches purged. If the
+// transaction does not commit, the archive of the chunk is deleted again, so that the
+// next purge does not archive the batches a second time.
+func (jm *JobManager) purgeChunk(ctx context.Context, app string, cutoff pgtype.Timestamp, report *PurgeReport_t) (int, error) {
+	tx, err := jm.store.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	batches, nrows, archive, err := jm.archiveAndDeleteBatches(ctx, tx.Queries(), app, cutoff)
+	if err != nil || len(batches) == 0 {
+		return 0, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		jm.deleteArchive(ctx, archive)
+		return 0, fmt.Errorf("failed to commit purge: %w", err)
+	}
+
+	report.NBatches += len(batches)
+	report.NRows += nrows
+	report.NOutputFiles += jm.deletePurgedBatchData(ctx, batches)
+	report.ArchiveObjects = append(report.ArchiveObjects, archive)
+	return len(batches), nil
+}
+
+// archiveAndDeleteBatches locks a chunk of the batches of app done before cutoff,
+// exports them to an archive object and deletes them, using the given (normally
+// transaction-bound) queries. It returns the batches, their number of rows and the
+// name of the archive object; no batches if there is nothing left to purge. If the
+// batches cannot be deleted, the archive is deleted again.
+func (jm *JobManager) archiveAndDeleteBatches(ctx context.Context, q batchsqlc.Querier, app string, cutoff pgtype.Timestamp) ([]batchsqlc.Batch, int, string, error) {
+	batches, err := q.GetBatchesToPurge(ctx, batchsqlc.GetBatchesToPurgeParams{
+		App:        app,
+		Cutoff:     cutoff,
+		MaxBatches: purgeChunkNBatches,
+	})
+	if err != nil {
+		return nil, 0, "", fmt.Errorf("failed to get batches to purge: %w", err)
+	}
+	if len(batches) == 0 {
+		return nil, 0, "", nil
+	}
+
+	objectName := fmt.Sprintf("%s/%s-%s.jsonl.gz", app, time.Now().Format("20060102T150405"), uuid.NewString())
+	upload := &archiveUpload{
+		ctx:      ctx,
+		store:    jm.objStore,
+		bucket:   jm.config.ArchiveBucket,
+		obj:      objectName,
+		partSize: jm.config.OutputPartSize,
+	}
+	nrows, err := writeArchive(ctx, q, upload, batches)
+	if err == nil {
+		err = upload.Close()
+	}
+	if err != nil {
+		upload.abort()
+		return nil, 0, "", fmt.Errorf("failed to store archive of purged batches: %w", err)
+	}
+
+	if err := jm.deleteBatches(ctx, q, batches); err != nil {
+		jm.deleteArchive(ctx, objectName)
+		return nil, 0, "", err
+	}
+	return batches, nrows, objectName, nil
+}
+
+// deleteBatches deletes batches with their batch_files records and their rows.
+func (jm *JobManager) deleteBatches(ctx context.Context, q batchsqlc.Querier, batches []batchsqlc.Batch) error {
+	ids := make([]uuid.UUID, len(batches))
+	for i, batch := range batches {
+		ids[i] = batch.ID
+	}
+
+	// batch_files and the rows are deleted first, as their foreign keys do not cascade.
+	// Partitioned rows are left for their partition to be dropped.
+	if err := q.DeleteBatchFilesByBatchIDs(ctx, ids); err != nil {
+		return fmt.Errorf("failed to delete batch files: %w", err)
+	}
+	if !jm.config.PartitionedBatchRows {
+		if err := q.DeleteBatchRowsByBatchIDs(ctx, ids); err != nil {
+			return fmt.Errorf("failed to delete batch rows: %w", err)
+		}
+	}
+	if _, err := q.DeleteBatchesByIDs(ctx, ids); err != nil {
+		return fmt.Errorf("failed to delete batches: %w", err)
+	}
+	return nil
+}
+
+// deleteArchive deletes the archive of batches that were not purged after all. A
+// failure is logged: the archive is then left behind, and the batches are archived
+// again by the next purge.
+func (jm *JobManager) deleteArchive(ctx context.Context, objectName string) {
+	if err := jm.objStore.Delete(ctx, jm.config.ArchiveBucket, objectName); err != nil {
+		jm.logger.Warn().LogActivity("Failed to delete archive of batches not purged", map[string]any{
+			"objectName": objectName,
+			"error":      err.Error(),
+		})
+	}
+}
+
+// writeArchive writes the gzip-compressed JSON Lines archive of batches to w, and
+// returns the number of rows archived.
+func writeArchive(ctx context.Context, q batchsqlc.Querier, w io.Writer, batches []batchsqlc.Batch) (int, error) {
+	zw := gzip.NewWriter(w)
+	nrows := 0
+	for _, batch := range batches {
+		n, err := writeArchivedBatch(ctx, q, zw, batch)
+		if err != nil {
+			return 0, err
+		}
+		nrows += n
+	}
+	if err := zw.Close(); err != nil {
+		return 0, fmt.Errorf("failed to compress archive: %w", err)
+	}
+	return nrows, nil
+}
+
+// writeArchivedBatch writes batch to w as one ArchivedBatch_t line, reading its rows
+// archivePageNRows at a time, and returns its number of rows.
+func writeArchivedBatch(ctx context.Context, q batchsqlc.Querier, w io.Writer, batch batchsqlc.Batch) (int, error) {
+	archived := ArchivedBatch_t{
+		ID:        batch.ID.String(),
+		App:       batch.App,
+		Op:        batch.Op,
+		Context:   rawJSON(batch.Context),
+		InputFile: batch.Inputfile.String,
+		Status:    batch.Status,
+		ReqAt:     localTime(batch.Reqat.Time),
+		DoneAt:    localTime(batch.Doneat.Time),
+		NSuccess:  int(batch.Nsuccess.Int32),
+		NFailed:   int(batch.Nfailed.Int32),
+		NAborted:  int(batch.Naborted.Int32),
+		Rows:      []ArchivedBatchRow_t{},
+	}
+	if len(batch.Outputfiles) > 0 {
+		if err := json.Unmarshal(batch.Outputfiles, &archived.OutputFiles); err != nil {
+			return 0, fmt.Errorf("failed to parse output files of batch %s: %w", batch.ID, err)
+		}
+	}
+	line, err := json.Marshal(archived)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode batch %s: %w", batch.ID, err)
+	}
+	// Rows is the last field, so the line ends with its empty array: the rows are
+	// written into it page by page instead of being held all at once.
+	if _, err := w.Write(bytes.TrimSuffix(line, []byte("]}"))); err != nil {
+		return 0, err
+	}
+
+	nrows := 0
+	var afterLine int32
+	var afterRowid int64
+	for {
+		rows, err := q.GetBatchRowsPage(ctx, batchsqlc.GetBatchRowsPageParams{
+			Batch:      batch.ID,
+			AfterLine:  afterLine,
+			AfterRowid: afterRowid,
+			PageSize:   archivePageNRows,
+		})
+		if err != nil {
+			return 0, fmt.Errorf("failed to get rows of batch %s: %w", batch.ID, err)
+		}
+		for _, row := range rows {
+			encoded, err := json.Marshal(archivedRow(row))
+			if err != nil {
+				return 0, fmt.Errorf("failed to encode line %d of batch %s: %w", row.Line, batch.ID, err)
+			}
+			if nrows > 0 {
+				encoded = append([]byte(","), encoded...)
+			}
+			if _, err := w.Write(encoded); err != nil {
+				return 0, err
+			}
+			nrows++
+		}
+		if len(rows) < archivePageNRows {
+			break
+		}
+		afterLine, afterRowid = rows[len(rows)-1].Line, rows[len(rows)-1].Rowid
+	}
+
+	if _, err := w.Write([]byte("]}\n")); err != nil {
+		return 0, err
+	}
+	return nrows, nil
+}
+
+// archivedRow returns row as it is archived.
+func archivedRow(row batchsqlc.Batchrow) ArchivedBatchRow_t {
+	archived := ArchivedBatchRow_t{
+		Line:     int(row.Line),
+		Input:    rawJSON(row.Input),
+		Status:   row.Status,
+		Res:      rawJSON(row.Res),
+		BlobRows: rawJSON(row.Blobrows),
+		Messages: rawJSON(row.Messages),
+		DoneBy:   row.Doneby.String,
+	}
+	if row.Doneat.Valid {
+		archived.DoneAt = localTime(row.Doneat.Time)
+	}
+	return archived
+}
+
+// archiveUpload stores what is written to it as an archive object, uploading it in
+// parts of partSize bytes, so that at most one part is held in memory. An archive
+// smaller than a part is stored with a single Put.
+type archiveUpload struct {
+	ctx      context.Context
+	store    objstore.ObjectStore
+	bucket   string
+	obj      string
+	partSize int
+	uploadID string
+	parts    []objstore.Part
+	buf      bytes.Buffer
+}
+
+func (u *archiveUpload) Write(p []byte) (int, error) {
+	u.buf.Write(p)
+	if u.buf.Len() >= u.partSize {
+		if err := u.putPart(); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// putPart uploads the buffered bytes as the next part, starting the upload first if
+// need be.
+func (u *archiveUpload) putPart() error {
+	if u.uploadID == "" {
+		uploadID, err := u.store.NewMultipartUpload(u.ctx, u.bucket, u.obj, "application/gzip", nil)
+		if err != nil {
+			return err
+		}
+		u.uploadID = uploadID
+	}
+	part, err := u.store.PutPart(u.ctx, u.bucket, u.obj, u.uploadID, len(u.parts)+1, bytes.NewReader(u.buf.Bytes()), int64(u.buf.Len()))
+	if err != nil {
+		return err
+	}
+	u.parts = append(u.parts, part)
+	u.buf.Reset()
+	return nil
+}
+
+// Close stores the rest of the archive and completes it.
+func (u *archiveUpload) Close() error {
+	if u.uploadID == "" {
+		return u.store.Put(u.ctx, u.bucket, u.obj, bytes.NewReader(u.buf.Bytes()), int64(u.buf.Len()), "application/gzip")
+	}
+	if u.buf.Len() > 0 {
+		if err := u.putPart(); err != nil {
+			return err
+		}
+	}
+	return u.store.CompleteMultipartUpload(u.ctx, u.bucket, u.obj, u.uploadID, u.parts)
+}
+
+// abort drops the parts uploaded so far, if any.
+func (u *archiveUpload) abort() {
+	if u.uploadID != "" {
+		u.store.AbortMultipartUpload(u.ctx, u.bucket, u.obj, u.uploadID)
+	}
+}
+
+// rawJSON returns a JSONB column value as raw JSON, nil for NULL.
+func rawJSON(b []byte) json.RawMessage {
+	if len(b) == 0 {
+		return nil
+	}
+	return json.RawMessage(b)
+}
+
+// deletePurgedBatchData deletes the output files and the Redis keys of batches that
+// have been purged, and returns the number of output files deleted. Failures are
+// logged and otherwise ignored, as the batches are gone already.
+func (jm *JobManager) deletePurgedBatchData(ctx context.Context, batches []batchsqlc.Batch) int {
+	ndeleted := 0
+	for _, batch := range batches {
+		var outputFiles map[string]string
+		if len(batch.Outputfiles) > 0 {
+			json.Unmarshal(batch.Outputfiles, &outputFiles)
+		}
+		for _, objectID := range outputFiles {
+			if err := jm.objStore.Delete(ctx, jm.config.BatchOutputBucket, objectID); err != nil {
+				jm.logger.Warn().LogActivity("Failed to delete output file of purged batch", map[string]any{
+					"batchId":  batch.ID.String(),
+					"objectId": objectID,
+					"error":    err.Error(),
+				})
+				continue
+			}
+			ndeleted++
+		}
+		jm.clearBatchCache(batch.ID)
+	}
+	return ndeleted
+}
+
+// runRetention purges the jobs of the apps with a retention policy every
+// retentionInterval until ctx is cancelled, after creating the batchrows partitions
+// of the coming months if batchrows is partitioned. It returns immediately if there
+// is nothing to do.
+func (jm *JobManager) runRetention(ctx context.Context) {
+	jm.mu.RLock()
+	n := len(jm.retention)
+	jm.mu.RUnlock()
+	if n == 0 && !jm.config.PartitionedBatchRows {
+		return
+	}
+
+	ticker := time.NewTicker(retentionInterval)
+	defer ticker.Stop()
+
+	for {
+		if jm.config.PartitionedBatchRows {
+			jm.ensureBatchRowPartitions(ctx)
+		}
+		jm.applyRetentionPolicies(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// applyRetentionPolicies purges the jobs of every app with a retention policy.
+func (jm *JobManager) applyRetentionPolicies(ctx context.Context) {
+	jm.mu.RLock()
+	policies := make(map[string]RetentionPolicy_t, len(jm.retention))
+	apps := make([]string, 0, len(jm.retention))
+	for app, policy := range jm.retention {
+		policies[app] = policy
+		apps = append(apps, app)
+	}
+	jm.mu.RUnlock()
+	sort.Strings(apps)
+
+	for _, app := range apps {
+		if ctx.Err() != nil {
+			return
+		}
+		report, err := jm.PurgeBatches(app, policies[app])
+		if err != nil {
+			jm.logger.Error(err).LogActivity("Error purging batches", map[string]any{
+				"app":      app,
+				"nbatches": report.NBatches,
+			})
+			continue
+		}
+		if report.NBatches == 0 {
+			continue
+		}
+		msg := "Batches purged"
+		if report.DryRun {
+			msg = "Batches due to be purged (dry run)"
+		}
+		jm.logger.Info().LogActivity(msg, map[string]any{
+			"app":               app,
+			"cutoff":            report.Cutoff,
+			"nbatches":          report.NBatches,
+			"nrows":             report.NRows,
+			"noutputfiles":      report.NOutputFiles,
+			"archiveObjects":    report.ArchiveObjects,
+			"droppedPartitions": report.DroppedPartitions,
+		})
+	}
+}
@@ -0,0 +1,290 @@
+package jobs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/remiges-tech/alya/jobs/objstore"
+	"github.com/remiges-tech/alya/jobs/pg/batchsqlc"
+	"github.com/remiges-tech/alya/jobs/pg/batchsqlc/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newPurgeQuerier returns a mock that hands out batches and rows as the batches to
+// purge and accepts every delete.
+func newPurgeQuerier(batches []batchsqlc.Batch, rows []batchsqlc.Batchrow) *mocks.QuerierMock {
+	return &mocks.QuerierMock{
+		GetBatchesToPurgeFunc: func(ctx context.Context, arg batchsqlc.GetBatchesToPurgeParams) ([]batchsqlc.Batch, error) {
+			return batches, nil
+		},
+		GetBatchRowsPageFunc: func(ctx context.Context, arg batchsqlc.GetBatchRowsPageParams) ([]batchsqlc.Batchrow, error) {
+			var page []batchsqlc.Batchrow
+			for _, r := range rows {
+				after := r.Line > arg.AfterLine || (r.Line == arg.AfterLine && r.Rowid > arg.AfterRowid)
+				if r.Batch == arg.Batch && after && len(page) < int(arg.PageSize) {
+					page = append(page, r)
+				}
+			}
+			return page, nil
+		},
+		DeleteBatchFilesByBatchIDsFunc: func(ctx context.Context, batchIds []uuid.UUID) error {
+			return nil
+		},
+		DeleteBatchRowsByBatchIDsFunc: func(ctx context.Context, batchIds []uuid.UUID) error {
+			return nil
+		},
+		DeleteBatchesByIDsFunc: func(ctx context.Context, ids []uuid.UUID) (int64, error) {
+			return int64(len(ids)), nil
+		},
+	}
+}
+
+func purgeTestBatch(outputFiles string) batchsqlc.Batch {
+	return batchsqlc.Batch{
+		ID:          uuid.New(),
+		App:         "bank",
+		Op:          "transfer",
+		Context:     []byte(`{"branch":"north"}`),
+		Status:      batchsqlc.StatusEnumSuccess,
+		Reqat:       dbTimestamp(time.Now().AddDate(0, 0, -40)),
+		Doneat:      dbTimestamp(time.Now().AddDate(0, 0, -40)),
+		Outputfiles: []byte(outputFiles),
+		Nsuccess:    pgtype.Int4{Int32: 2, Valid: true},
+	}
+}
+
+// readArchive decodes a gzip-compressed JSON Lines archive.
+func readArchive(t *testing.T, archive []byte) []ArchivedBatch_t {
+	zr, err := gzip.NewReader(bytes.NewReader(archive))
+	require.NoError(t, err)
+	dec := json.NewDecoder(zr)
+	var batches []ArchivedBatch_t
+	for {
+		var batch ArchivedBatch_t
+		err := dec.Decode(&batch)
+		if err == io.EOF {
+			return batches
+		}
+		require.NoError(t, err)
+		batches = append(batches, batch)
+	}
+}
+
+func TestRegisterRetentionPolicy(t *testing.T) {
+	jm := newTestJobManager(&mocks.QuerierMock{}, nil)
+
+	assert.ErrorIs(t, jm.RegisterRetentionPolicy("", RetentionPolicy_t{KeepDays: 30}), ErrInvalidRetentionPolicy)
+	assert.ErrorIs(t, jm.RegisterRetentionPolicy("bank", RetentionPolicy_t{}), ErrInvalidRetentionPolicy)
+	require.NoError(t, jm.RegisterRetentionPolicy("bank", RetentionPolicy_t{KeepDays: 30}))
+	require.NoError(t, jm.RegisterRetentionPolicy("bank", RetentionPolicy_t{KeepDays: 7, DryRun: true}))
+	assert.Equal(t, RetentionPolicy_t{KeepDays: 7, DryRun: true}, jm.retention["bank"])
+}
+
+func TestPurgeBatches_DryRun(t *testing.T) {
+	q := &mocks.QuerierMock{
+		CountBatchesToPurgeFunc: func(ctx context.Context, arg batchsqlc.CountBatchesToPurgeParams) (batchsqlc.CountBatchesToPurgeRow, error) {
+			return batchsqlc.CountBatchesToPurgeRow{Nbatches: 3, Nrows: 120, Noutputfiles: 4}, nil
+		},
+	}
+	jm := newTestJobManager(q, nil)
+
+	report, err := jm.PurgeBatches("bank", RetentionPolicy_t{KeepDays: 30, DryRun: true})
+	require.NoError(t, err)
+	assert.True(t, report.DryRun)
+	assert.Equal(t, 3, report.NBatches)
+	assert.Equal(t, 120, report.NRows)
+	assert.Equal(t, 4, report.NOutputFiles)
+	assert.Empty(t, report.ArchiveObjects)
+	assert.WithinDuration(t, time.Now().AddDate(0, 0, -30), report.Cutoff, 5*time.Second)
+
+	require.Len(t, q.CountBatchesToPurgeCalls(), 1)
+	assert.Equal(t, "bank", q.CountBatchesToPurgeCalls()[0].Arg.App)
+
+	_, err = jm.PurgeBatches("bank", RetentionPolicy_t{})
+	assert.ErrorIs(t, err, ErrInvalidRetentionPolicy)
+}
+
+func TestArchiveAndDeleteBatches(t *testing.T) {
+	batch := purgeTestBatch(`{"report.csv":"obj-1"}`)
+	slowQuery := purgeTestBatch(`null`)
+	rows := []batchsqlc.Batchrow{
+		{Rowid: 1, Batch: batch.ID, Line: 1, Input: []byte(`{"amount":1}`), Status: batchsqlc.StatusEnumSuccess, Res: []byte(`{"ok":true}`), Doneat: batch.Doneat},
+		{Rowid: 2, Batch: batch.ID, Line: 2, Input: []byte(`{"amount":2}`), Status: batchsqlc.StatusEnumSuccess},
+		{Rowid: 3, Batch: slowQuery.ID, Line: 0, Input: []byte(`{}`), Status: batchsqlc.StatusEnumSuccess},
+	}
+	q := newPurgeQuerier([]batchsqlc.Batch{batch, slowQuery}, rows)
+	jm := newTestJobManager(q, nil)
+
+	var bucket, objectName, contentType string
+	var archive []byte
+	jm.objStore = &objstore.ObjectStoreMock{
+		PutFunc: func(ctx context.Context, b, obj string, reader io.Reader, size int64, ct string) error {
+			bucket, objectName, contentType = b, obj, ct
+			archive, _ = io.ReadAll(reader)
+			return nil
+		},
+	}
+	cutoff := dbTimestamp(time.Now().AddDate(0, 0, -30))
+
+	purged, nrows, name, err := jm.archiveAndDeleteBatches(context.Background(), q, "bank", cutoff)
+	require.NoError(t, err)
+	assert.Len(t, purged, 2)
+	assert.Equal(t, 3, nrows)
+	assert.Equal(t, objectName, name)
+	assert.Equal(t, ALYA_ARCHIVE_BUCKET, bucket)
+	assert.Equal(t, "application/gzip", contentType)
+	assert.Regexp(t, `^bank/\d{8}T\d{6}-[0-9a-f-]{36}\.jsonl\.gz$`, name)
+
+	assert.Equal(t, int32(purgeChunkNBatches), q.GetBatchesToPurgeCalls()[0].Arg.MaxBatches)
+	ids := []uuid.UUID{batch.ID, slowQuery.ID}
+	assert.Equal(t, ids, q.DeleteBatchFilesByBatchIDsCalls()[0].BatchIds)
+	assert.Equal(t, ids, q.DeleteBatchRowsByBatchIDsCalls()[0].BatchIds)
+	assert.Equal(t, ids, q.DeleteBatchesByIDsCalls()[0].Ids)
+
+	archived := readArchive(t, archive)
+	require.Len(t, archived, 2)
+	assert.Equal(t, batch.ID.String(), archived[0].ID)
+	assert.JSONEq(t, `{"branch":"north"}`, string(archived[0].Context))
+	assert.Equal(t, map[string]string{"report.csv": "obj-1"}, archived[0].OutputFiles)
+	assert.Equal(t, 2, archived[0].NSuccess)
+	require.Len(t, archived[0].Rows, 2)
+	assert.JSONEq(t, `{"ok":true}`, string(archived[0].Rows[0].Res))
+	assert.Nil(t, archived[0].Rows[1].Res)
+	assert.True(t, archived[0].Rows[1].DoneAt.IsZero())
+	assert.Nil(t, archived[1].OutputFiles)
+	require.Len(t, archived[1].Rows, 1)
+	assert.Equal(t, 0, archived[1].Rows[0].Line)
+}
+
+func TestArchiveAndDeleteBatches_NothingToPurge(t *testing.T) {
+	q := newPurgeQuerier(nil, nil)
+	jm := newTestJobManager(q, nil)
+	jm.objStore = &objstore.ObjectStoreMock{}
+
+	purged, _, _, err := jm.archiveAndDeleteBatches(context.Background(), q, "bank", dbTimestamp(time.Now()))
+	require.NoError(t, err)
+	assert.Empty(t, purged)
+	assert.Empty(t, q.GetBatchRowsPageCalls())
+	assert.Empty(t, q.DeleteBatchesByIDsCalls())
+}
+
+func TestArchiveAndDeleteBatches_ArchiveFails(t *testing.T) {
+	q := newPurgeQuerier([]batchsqlc.Batch{purgeTestBatch(`{}`)}, nil)
+	jm := newTestJobManager(q, nil)
+	jm.objStore = &objstore.ObjectStoreMock{
+		PutFunc: func(ctx context.Context, bucket, obj string, reader io.Reader, size int64, contentType string) error {
+			return errors.New("bucket not found")
+		},
+	}
+
+	_, _, _, err := jm.archiveAndDeleteBatches(context.Background(), q, "bank", dbTimestamp(time.Now()))
+	assert.ErrorContains(t, err, "bucket not found")
+	assert.Empty(t, q.DeleteBatchRowsByBatchIDsCalls())
+	assert.Empty(t, q.DeleteBatchesByIDsCalls())
+}
+
+func TestArchiveAndDeleteBatches_Multipart(t *testing.T) {
+	batch := purgeTestBatch(`{}`)
+	var rows []batchsqlc.Batchrow
+	for line := 1; line <= 2*archivePageNRows+10; line++ {
+		rows = append(rows, batchsqlc.Batchrow{Rowid: int64(line), Batch: batch.ID, Line: int32(line), Input: []byte(fmt.Sprintf(`{"n":"%s"}`, uuid.NewString()))})
+	}
+	q := newPurgeQuerier([]batchsqlc.Batch{batch}, rows)
+	jm := newTestJobManager(q, nil)
+	jm.config.OutputPartSize = 4096
+
+	var archive bytes.Buffer
+	var parts []objstore.Part
+	jm.objStore = &objstore.ObjectStoreMock{
+		NewMultipartUploadFunc: func(ctx context.Context, bucket, obj, contentType string, metadata map[string]string) (string, error) {
+			assert.Equal(t, "application/gzip", contentType)
+			return "upload-1", nil
+		},
+		PutPartFunc: func(ctx context.Context, bucket, obj, uploadID string, number int, reader io.Reader, size int64) (objstore.Part, error) {
+			n, _ := io.Copy(&archive, reader)
+			assert.Equal(t, size, n)
+			return objstore.Part{Number: number, Size: size}, nil
+		},
+		CompleteMultipartUploadFunc: func(ctx context.Context, bucket, obj, uploadID string, p []objstore.Part) error {
+			parts = p
+			return nil
+		},
+	}
+
+	purged, nrows, _, err := jm.archiveAndDeleteBatches(context.Background(), q, "bank", batch.Doneat)
+	require.NoError(t, err)
+	assert.Len(t, purged, 1)
+	assert.Equal(t, len(rows), nrows)
+	assert.Greater(t, len(parts), 1)
+	assert.Len(t, q.GetBatchRowsPageCalls(), 3)
+	assert.Equal(t, int32(archivePageNRows), q.GetBatchRowsPageCalls()[1].Arg.AfterLine)
+
+	archived := readArchive(t, archive.Bytes())
+	require.Len(t, archived, 1)
+	require.Len(t, archived[0].Rows, len(rows))
+	assert.Equal(t, len(rows), archived[0].Rows[len(rows)-1].Line)
+}
+
+func TestArchiveAndDeleteBatches_DeleteFails(t *testing.T) {
+	q := newPurgeQuerier([]batchsqlc.Batch{purgeTestBatch(`{}`)}, nil)
+	q.DeleteBatchesByIDsFunc = func(ctx context.Context, ids []uuid.UUID) (int64, error) {
+		return 0, errors.New("deadlock detected")
+	}
+	jm := newTestJobManager(q, nil)
+	var stored, deleted string
+	jm.objStore = &objstore.ObjectStoreMock{
+		PutFunc: func(ctx context.Context, bucket, obj string, reader io.Reader, size int64, contentType string) error {
+			stored = obj
+			return nil
+		},
+		DeleteFunc: func(ctx context.Context, bucket, obj string) error {
+			assert.Equal(t, ALYA_ARCHIVE_BUCKET, bucket)
+			deleted = obj
+			return nil
+		},
+	}
+
+	_, _, _, err := jm.archiveAndDeleteBatches(context.Background(), q, "bank", dbTimestamp(time.Now()))
+	assert.ErrorContains(t, err, "deadlock detected")
+	assert.NotEmpty(t, stored)
+	assert.Equal(t, stored, deleted)
+}
+
+func TestDeletePurgedBatchData(t *testing.T) {
+	jm, redisClient := newLimitsTestJobManager(t)
+	ctx := context.Background()
+	batch := purgeTestBatch(`{"report.csv":"obj-1","errors.csv":"obj-2"}`)
+	id := batch.ID.String()
+	require.NoError(t, redisClient.Set(ctx, BatchStatusKey(id), "success", 0).Err())
+	require.NoError(t, redisClient.Set(ctx, BatchSummaryKey(id), "{}", 0).Err())
+
+	var deleted []string
+	jm.objStore = &objstore.ObjectStoreMock{
+		DeleteFunc: func(ctx context.Context, bucket, obj string) error {
+			assert.Equal(t, jm.config.BatchOutputBucket, bucket)
+			if obj == "obj-2" {
+				return errors.New("gone already")
+			}
+			deleted = append(deleted, obj)
+			return nil
+		},
+	}
+
+	ndeleted := jm.deletePurgedBatchData(ctx, []batchsqlc.Batch{batch, purgeTestBatch(`null`)})
+	assert.Equal(t, 1, ndeleted)
+	assert.Equal(t, []string{"obj-1"}, deleted)
+
+	n, err := redisClient.Exists(ctx, BatchStatusKey(id), BatchSummaryKey(id)).Result()
+	require.NoError(t, err)
+	assert.Zero(t, n)
+}
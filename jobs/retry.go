@@ -0,0 +1,183 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/remiges-tech/alya/jobs/pg/batchsqlc"
+	"github.com/remiges-tech/alya/wscutils"
+)
+
+var (
+	// ErrTransient marks an error returned by DoBatchJob or DoSlowQuery as
+	// transient. If a retry policy is registered for the (app, op) and attempts
+	// remain, the row is put back in the queue instead of being marked failed.
+	// Use NewTransientError to wrap an error with it.
+	ErrTransient = errors.New("transient error")
+
+	ErrInvalidRetryPolicy = errors.New("invalid retry policy")
+
+	// errRowPanicked is wrapped into the error processRow returns when a
+	// processor panics, so that RetryPolicy.RetryPanics can be honoured.
+	errRowPanicked = errors.New("panic during row processing")
+)
+
+// RetryPolicy controls how a row that failed with a transient error is retried.
+//
+// MaxAttempts is the total number of attempts, including the first one.
+// The delay before attempt n+1 is InitialBackoff * Multiplier^(n-1), capped at
+// MaxBackoff, and then randomly spread by +/- Jitter (a fraction between 0 and 1).
+// If RetryPanics is set, a panic in the processor is treated as transient too.
+type RetryPolicy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+	Jitter         float64
+	RetryPanics    bool
+}
+
+// TransientError is an error that should be retried. RetryAfter, if non-zero,
+// overrides the backoff computed from the retry policy for this attempt.
+type TransientError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *TransientError) Error() string {
+	return fmt.Sprintf("%v: %v", ErrTransient, e.Err)
+}
+
+// Unwrap returns both ErrTransient and the wrapped error, so errors.Is works
+// for either.
+func (e *TransientError) Unwrap() []error {
+	return []error{ErrTransient, e.Err}
+}
+
+// NewTransientError wraps err so that the job manager retries the row
+// according to the retry policy registered for its (app, op).
+func NewTransientError(err error) error {
+	return &TransientError{Err: err}
+}
+
+// RegisterRetryPolicy sets the retry policy for rows of the given (app, op).
+// It applies to both batch jobs and slow queries. Rows of an (app, op) without
+// a retry policy fail on the first error, as before.
+// The 'op' parameter is case-insensitive.
+func (jm *JobManager) RegisterRetryPolicy(app string, op string, policy RetryPolicy) error {
+	if policy.MaxAttempts < 1 {
+		return fmt.Errorf("%w: MaxAttempts must be at least 1, got %d", ErrInvalidRetryPolicy, policy.MaxAttempts)
+	}
+	if policy.InitialBackoff < 0 || policy.MaxBackoff < 0 {
+		return fmt.Errorf("%w: backoff must not be negative", ErrInvalidRetryPolicy)
+	}
+	if policy.MaxBackoff != 0 && policy.MaxBackoff < policy.InitialBackoff {
+		return fmt.Errorf("%w: MaxBackoff %v is less than InitialBackoff %v", ErrInvalidRetryPolicy, policy.MaxBackoff, policy.InitialBackoff)
+	}
+	if policy.Multiplier == 0 {
+		policy.Multiplier = 2
+	}
+	if policy.Multiplier < 1 {
+		return fmt.Errorf("%w: Multiplier must be at least 1, got %v", ErrInvalidRetryPolicy, policy.Multiplier)
+	}
+	if policy.Jitter < 0 || policy.Jitter > 1 {
+		return fmt.Errorf("%w: Jitter must be between 0 and 1, got %v", ErrInvalidRetryPolicy, policy.Jitter)
+	}
+
+	op = strings.ToLower(op)
+	jm.retrypolicies[app+op] = policy
+	return nil
+}
+
+// backoff returns the delay before the next attempt, given the number of
+// attempts made so far.
+func (p RetryPolicy) backoff(attempts int) time.Duration {
+	delay := float64(p.InitialBackoff) * math.Pow(p.Multiplier, float64(attempts-1))
+	if p.MaxBackoff > 0 && delay > float64(p.MaxBackoff) {
+		delay = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		delay += delay * p.Jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(delay)
+}
+
+// shouldRetry reports whether a row that failed with err is to be requeued.
+// It returns the delay before the row may be picked up again.
+func (jm *JobManager) shouldRetry(row batchsqlc.FetchBlockOfRowsRow, err error) (bool, time.Duration) {
+	policy, exists := jm.retrypolicies[row.App+row.Op]
+	if !exists {
+		return false, 0
+	}
+	attempts := int(row.Attempts) + 1
+	if attempts >= policy.MaxAttempts {
+		return false, 0
+	}
+	if isConfigurationError(err) {
+		return false, 0
+	}
+	if !errors.Is(err, ErrTransient) && !(policy.RetryPanics && errors.Is(err, errRowPanicked)) {
+		return false, 0
+	}
+
+	var transientErr *TransientError
+	if errors.As(err, &transientErr) && transientErr.RetryAfter > 0 {
+		return true, transientErr.RetryAfter
+	}
+	return true, policy.backoff(attempts)
+}
+
+// retryRow puts the row back in the queue if its retry policy allows another
+// attempt. It returns true if the row was requeued; otherwise the caller
+// handles the error as a normal failure.
+func (jm *JobManager) retryRow(row batchsqlc.FetchBlockOfRowsRow, err error) bool {
+	retry, delay := jm.shouldRetry(row, err)
+	if !retry {
+		return false
+	}
+
+	messagesJSON, marshalErr := json.Marshal([]wscutils.ErrorMessage{{
+		MsgID:   MsgIDTransientError,
+		ErrCode: ErrCodeTransient,
+		Vals:    []string{err.Error()},
+	}})
+	if marshalErr != nil {
+		jm.logger.Error(marshalErr).LogActivity("Error marshalling retry message", map[string]any{
+			"rowId": row.Rowid,
+		})
+		return false
+	}
+
+	notBefore := time.Now().Add(delay)
+	requeueErr := jm.queries.RequeueBatchRowForRetry(context.Background(), batchsqlc.RequeueBatchRowForRetryParams{
+		Rowid:     row.Rowid,
+		NotBefore: pgtype.Timestamp{Time: notBefore, Valid: true},
+		Messages:  messagesJSON,
+	})
+	if requeueErr != nil {
+		jm.logger.Error(requeueErr).LogActivity("Error requeueing row for retry", map[string]any{
+			"rowId":   row.Rowid,
+			"batchId": row.Batch.String(),
+		})
+		return false
+	}
+	jm.trackRowRequeued(row.Batch)
+
+	jm.logger.Info().LogActivity("Row requeued for retry", map[string]any{
+		"rowId":     row.Rowid,
+		"batchId":   row.Batch.String(),
+		"app":       row.App,
+		"op":        row.Op,
+		"attempt":   row.Attempts + 1,
+		"notBefore": notBefore,
+		"error":     err.Error(),
+	})
+	return true
+}
@@ -0,0 +1,135 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/remiges-tech/alya/jobs/pg/batchsqlc"
+	"github.com/remiges-tech/alya/jobs/pg/batchsqlc/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterRetryPolicy_Validation(t *testing.T) {
+	jm := newTestJobManager(&mocks.QuerierMock{}, nil)
+
+	tests := []struct {
+		name   string
+		policy RetryPolicy
+	}{
+		{"zero attempts", RetryPolicy{}},
+		{"negative backoff", RetryPolicy{MaxAttempts: 3, InitialBackoff: -time.Second}},
+		{"max below initial", RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Minute, MaxBackoff: time.Second}},
+		{"multiplier below one", RetryPolicy{MaxAttempts: 3, Multiplier: 0.5}},
+		{"jitter above one", RetryPolicy{MaxAttempts: 3, Jitter: 1.5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := jm.RegisterRetryPolicy("banking", "transfer", tt.policy)
+			assert.ErrorIs(t, err, ErrInvalidRetryPolicy)
+		})
+	}
+
+	require.NoError(t, jm.RegisterRetryPolicy("banking", "Transfer", RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Second}))
+	assert.Equal(t, 2.0, jm.retrypolicies["bankingtransfer"].Multiplier)
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 10, InitialBackoff: time.Second, MaxBackoff: 10 * time.Second, Multiplier: 2}
+
+	assert.Equal(t, time.Second, p.backoff(1))
+	assert.Equal(t, 2*time.Second, p.backoff(2))
+	assert.Equal(t, 4*time.Second, p.backoff(3))
+	assert.Equal(t, 8*time.Second, p.backoff(4))
+	assert.Equal(t, 10*time.Second, p.backoff(5))
+
+	p.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		d := p.backoff(2)
+		assert.GreaterOrEqual(t, d, time.Second)
+		assert.LessOrEqual(t, d, 3*time.Second)
+	}
+}
+
+func TestTransientError(t *testing.T) {
+	cause := errors.New("connection reset")
+	err := fmt.Errorf("calling ledger: %w", NewTransientError(cause))
+
+	assert.ErrorIs(t, err, ErrTransient)
+	assert.ErrorIs(t, err, cause)
+	assert.NotErrorIs(t, cause, ErrTransient)
+}
+
+func TestShouldRetry(t *testing.T) {
+	jm := newTestJobManager(&mocks.QuerierMock{}, nil)
+	require.NoError(t, jm.RegisterRetryPolicy("banking", "transfer", RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Second}))
+	require.NoError(t, jm.RegisterRetryPolicy("banking", "report", RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Second, RetryPanics: true}))
+
+	row := batchsqlc.FetchBlockOfRowsRow{App: "banking", Op: "transfer", Batch: uuid.New(), Rowid: 1, Line: 1}
+	transient := NewTransientError(errors.New("timeout"))
+	panicked := fmt.Errorf("%w: %v", errRowPanicked, "nil map")
+
+	tests := []struct {
+		name      string
+		op        string
+		attempts  int32
+		err       error
+		wantRetry bool
+		wantDelay time.Duration
+	}{
+		{"transient first attempt", "transfer", 0, transient, true, time.Second},
+		{"transient second attempt", "transfer", 1, transient, true, 2 * time.Second},
+		{"attempts exhausted", "transfer", 2, transient, false, 0},
+		{"permanent error", "transfer", 0, errors.New("bad account"), false, 0},
+		{"configuration error", "transfer", 0, NewTransientError(ErrInitializerFailed), false, 0},
+		{"panic without RetryPanics", "transfer", 0, panicked, false, 0},
+		{"panic with RetryPanics", "report", 0, panicked, true, time.Second},
+		{"no policy", "other", 0, transient, false, 0},
+		{"retry after override", "transfer", 0, &TransientError{Err: errors.New("429"), RetryAfter: time.Minute}, true, time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := row
+			r.Op = tt.op
+			r.Attempts = tt.attempts
+			retry, delay := jm.shouldRetry(r, tt.err)
+			assert.Equal(t, tt.wantRetry, retry)
+			assert.Equal(t, tt.wantDelay, delay)
+		})
+	}
+}
+
+func TestRetryRow(t *testing.T) {
+	mockQuerier := &mocks.QuerierMock{}
+	mockQuerier.RequeueBatchRowForRetryFunc = func(ctx context.Context, arg batchsqlc.RequeueBatchRowForRetryParams) error {
+		return nil
+	}
+	jm := newTestJobManager(mockQuerier, nil)
+	require.NoError(t, jm.RegisterRetryPolicy("banking", "transfer", RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Minute}))
+
+	row := batchsqlc.FetchBlockOfRowsRow{App: "banking", Op: "transfer", Batch: uuid.New(), Rowid: 42, Line: 7}
+
+	before := time.Now()
+	assert.True(t, jm.retryRow(row, NewTransientError(errors.New("timeout"))))
+	require.Len(t, mockQuerier.RequeueBatchRowForRetryCalls(), 1)
+	call := mockQuerier.RequeueBatchRowForRetryCalls()[0].Arg
+	assert.Equal(t, int64(42), call.Rowid)
+	assert.WithinDuration(t, before.Add(time.Minute), call.NotBefore.Time, 5*time.Second)
+	assert.Contains(t, string(call.Messages), ErrCodeTransient)
+
+	// last attempt: the row is left to the normal failure handling
+	row.Attempts = 1
+	assert.False(t, jm.retryRow(row, NewTransientError(errors.New("timeout"))))
+	assert.Len(t, mockQuerier.RequeueBatchRowForRetryCalls(), 1)
+
+	// a failed requeue falls back to the normal failure handling as well
+	mockQuerier.RequeueBatchRowForRetryFunc = func(ctx context.Context, arg batchsqlc.RequeueBatchRowForRetryParams) error {
+		return errors.New("connection refused")
+	}
+	row.Attempts = 0
+	assert.False(t, jm.retryRow(row, NewTransientError(errors.New("timeout"))))
+}
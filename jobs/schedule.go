@@ -0,0 +1,261 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/remiges-tech/alya/jobs/pg/batchsqlc"
+)
+
+// scheduleInterval is how often the scheduler loop checks for due schedules.
+// Cron expressions have minute granularity, so a batch is created at most
+// this long after its fire time.
+const scheduleInterval = 30 * time.Second
+
+var (
+	ErrInvalidSchedule           = errors.New("invalid batch schedule")
+	ErrScheduleAlreadyRegistered = errors.New("batch schedule already registered with this name")
+)
+
+// BatchSchedule describes a recurring batch. Every time the cron expression
+// fires, Input is called with the fire time and the batch it returns is
+// submitted for (App, Op).
+//
+// Name identifies the schedule across all JobManager instances sharing the
+// database; it must be unique. Location is the time zone the cron expression
+// is evaluated in and defaults to time.Local. Priority is the priority of the
+// batches created, as in SubmitOptions_t.
+//
+// If Input returns an error, the fire is retried on the next scheduler pass.
+// If it returns no rows, the fire is skipped.
+type BatchSchedule struct {
+	Name     string
+	App      string
+	Op       string
+	Cron     string
+	Location *time.Location
+	Priority int
+	Input    func(fireAt time.Time) (batchctx JSONstr, batchInput []BatchInput_t, err error)
+}
+
+type registeredSchedule struct {
+	BatchSchedule
+	cron *cronSchedule
+}
+
+// RegisterBatchSchedule registers a recurring batch schedule. Schedules are
+// materialised into batches by the scheduler loop started by Run and
+// RunWithContext. When several JobManager instances register the same
+// schedule, each fire time still produces exactly one batch.
+//
+// A schedule seen for the first time starts from the current time; fire times
+// before that are not back-filled. If JobManager instances were down across
+// several fire times, only the most recent one is materialised.
+func (jm *JobManager) RegisterBatchSchedule(schedule BatchSchedule) error {
+	if schedule.Name == "" || schedule.App == "" || schedule.Op == "" {
+		return fmt.Errorf("%w: name, app and op are required", ErrInvalidSchedule)
+	}
+	if schedule.Input == nil {
+		return fmt.Errorf("%w: input function is required for schedule %s", ErrInvalidSchedule, schedule.Name)
+	}
+	cron, err := parseCron(schedule.Cron)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidSchedule, err)
+	}
+	if schedule.Location == nil {
+		schedule.Location = time.Local
+	}
+	schedule.Op = strings.ToLower(schedule.Op)
+
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+	if _, exists := jm.schedules[schedule.Name]; exists {
+		return fmt.Errorf("%w: %s", ErrScheduleAlreadyRegistered, schedule.Name)
+	}
+	jm.schedules[schedule.Name] = &registeredSchedule{BatchSchedule: schedule, cron: cron}
+	return nil
+}
+
+// runScheduler runs the scheduler loop in a background goroutine. It returns
+// immediately if no schedules are registered.
+func (jm *JobManager) runScheduler(ctx context.Context) {
+	jm.mu.RLock()
+	n := len(jm.schedules)
+	jm.mu.RUnlock()
+	if n == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(scheduleInterval)
+	defer ticker.Stop()
+
+	for {
+		jm.fireDueSchedules(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// fireDueSchedules creates the batches of all schedules that are due at now.
+func (jm *JobManager) fireDueSchedules(ctx context.Context, now time.Time) {
+	jm.mu.RLock()
+	schedules := make([]*registeredSchedule, 0, len(jm.schedules))
+	for _, s := range jm.schedules {
+		schedules = append(schedules, s)
+	}
+	jm.mu.RUnlock()
+	sort.Slice(schedules, func(i, j int) bool { return schedules[i].Name < schedules[j].Name })
+
+	for _, s := range schedules {
+		if ctx.Err() != nil {
+			return
+		}
+		batchID, err := jm.fireSchedule(ctx, s, now)
+		if err != nil {
+			jm.logger.Error(err).LogActivity("Error firing batch schedule", map[string]any{
+				"schedule": s.Name,
+				"app":      s.App,
+				"op":       s.Op,
+			})
+			continue
+		}
+		if batchID != "" {
+			jm.logger.Info().LogActivity("Batch schedule fired", map[string]any{
+				"schedule": s.Name,
+				"app":      s.App,
+				"op":       s.Op,
+				"batchId":  batchID,
+			})
+		}
+	}
+}
+
+// fireSchedule checks one schedule in its own transaction and creates its
+// batch if it is due.
+func (jm *JobManager) fireSchedule(ctx context.Context, s *registeredSchedule, now time.Time) (batchID string, err error) {
+	tx, err := jm.store.Begin(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	batchID, err = jm.fireScheduleTx(ctx, tx.Queries(), s, now)
+	if err != nil {
+		return "", err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return "", fmt.Errorf("failed to commit batch schedule: %w", err)
+	}
+	return batchID, nil
+}
+
+// fireScheduleTx does the work of fireSchedule using the transaction-bound
+// queries q. The schedule is guarded by a transaction-scoped advisory lock,
+// so when several instances check the same schedule concurrently only one of
+// them goes ahead; the others skip it until the next pass. The batch is
+// inserted and last_fireat advanced in the same transaction, so a fire time
+// never produces two batches.
+//
+// The returned batch ID is empty if the schedule is not due, is locked
+// elsewhere, or its Input returned no rows.
+func (jm *JobManager) fireScheduleTx(ctx context.Context, q batchsqlc.Querier, s *registeredSchedule, now time.Time) (batchID string, err error) {
+	locked, err := q.TryAdvisoryLockBatch(ctx, scheduleLockKey(s.Name))
+	if err != nil {
+		return "", fmt.Errorf("failed to acquire schedule lock: %w", err)
+	}
+	if !locked {
+		return "", nil
+	}
+
+	err = q.UpsertBatchSchedule(ctx, batchsqlc.UpsertBatchScheduleParams{
+		Name:       s.Name,
+		App:        s.App,
+		Op:         s.Op,
+		Cron:       s.Cron,
+		LastFireat: pgtype.Timestamp{Time: now.In(time.Local), Valid: true},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to record schedule: %w", err)
+	}
+
+	row, err := q.GetBatchScheduleForUpdate(ctx, s.Name)
+	if err != nil {
+		return "", fmt.Errorf("failed to read schedule: %w", err)
+	}
+
+	fireAt := s.latestFireTime(localTime(row.LastFireat.Time), now)
+	if fireAt.IsZero() {
+		return "", nil
+	}
+
+	batchctx, batchInput, err := s.Input(fireAt)
+	if err != nil {
+		return "", fmt.Errorf("input function of schedule %s failed for %v: %w", s.Name, fireAt, err)
+	}
+
+	var lastBatch pgtype.UUID
+	if len(batchInput) > 0 {
+		batchUUID, err := uuid.NewUUID()
+		if err != nil {
+			return "", err
+		}
+		err = insertBatch(ctx, q, batchUUID, s.App, s.Op, batchctx, batchInput, SubmitOptions_t{Priority: s.Priority})
+		if err != nil {
+			return "", fmt.Errorf("failed to insert scheduled batch: %w", err)
+		}
+		if err := jm.notifyJobsQueued(ctx, q, s.App); err != nil {
+			return "", err
+		}
+		lastBatch = pgtype.UUID{Bytes: batchUUID, Valid: true}
+		batchID = batchUUID.String()
+	} else {
+		jm.logger.Info().LogActivity("Batch schedule produced no rows, skipping fire", map[string]any{
+			"schedule": s.Name,
+			"fireAt":   fireAt,
+		})
+		lastBatch = row.LastBatch
+	}
+
+	err = q.UpdateBatchScheduleFired(ctx, batchsqlc.UpdateBatchScheduleFiredParams{
+		Name:       s.Name,
+		LastFireat: pgtype.Timestamp{Time: fireAt.In(time.Local), Valid: true},
+		LastBatch:  lastBatch,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to update schedule: %w", err)
+	}
+	return batchID, nil
+}
+
+// latestFireTime returns the most recent fire time after last and not after
+// now, or the zero time if there is none.
+func (s *registeredSchedule) latestFireTime(last, now time.Time) time.Time {
+	var fireAt time.Time
+	for t := s.cron.next(last.In(s.Location)); !t.IsZero() && !t.After(now); t = s.cron.next(t) {
+		fireAt = t
+	}
+	return fireAt
+}
+
+// scheduleLockKey returns the advisory lock key of a schedule. The prefix
+// keeps it apart from the batch ID keys used by summarizeBatch.
+func scheduleLockKey(name string) string {
+	return "schedule:" + name
+}
+
+// localTime reinterprets a timestamp read from a TIMESTAMP WITHOUT TIME ZONE
+// column, which pgx returns in UTC, as local wall clock time, the way it was
+// written.
+func localTime(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.Local)
+}
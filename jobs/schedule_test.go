@@ -0,0 +1,214 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/remiges-tech/alya/jobs/pg/batchsqlc"
+	"github.com/remiges-tech/alya/jobs/pg/batchsqlc/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// dbTimestamp returns t the way pgx reads it back from a TIMESTAMP column
+// it was written to: local wall clock time labelled as UTC.
+func dbTimestamp(t time.Time) pgtype.Timestamp {
+	l := t.In(time.Local)
+	return pgtype.Timestamp{
+		Time:  time.Date(l.Year(), l.Month(), l.Day(), l.Hour(), l.Minute(), l.Second(), l.Nanosecond(), time.UTC),
+		Valid: true,
+	}
+}
+
+// newScheduleQuerier returns a mock querier holding a single schedule row
+// last fired at lastFireat. The lock is granted if locked is true.
+func newScheduleQuerier(locked bool, lastFireat time.Time) *mocks.QuerierMock {
+	return &mocks.QuerierMock{
+		TryAdvisoryLockBatchFunc: func(ctx context.Context, key string) (bool, error) {
+			return locked, nil
+		},
+		UpsertBatchScheduleFunc: func(ctx context.Context, arg batchsqlc.UpsertBatchScheduleParams) error {
+			return nil
+		},
+		GetBatchScheduleForUpdateFunc: func(ctx context.Context, name string) (batchsqlc.GetBatchScheduleForUpdateRow, error) {
+			return batchsqlc.GetBatchScheduleForUpdateRow{
+				Name:       name,
+				App:        "banking",
+				Op:         "eodreport",
+				Cron:       "0 18 * * *",
+				LastFireat: dbTimestamp(lastFireat),
+			}, nil
+		},
+		InsertIntoBatchesFunc: func(ctx context.Context, arg batchsqlc.InsertIntoBatchesParams) (uuid.UUID, error) {
+			return arg.ID, nil
+		},
+		BulkInsertIntoBatchRowsFunc: func(ctx context.Context, arg batchsqlc.BulkInsertIntoBatchRowsParams) (int64, error) {
+			return int64(len(arg.Line)), nil
+		},
+		UpdateBatchScheduleFiredFunc: func(ctx context.Context, arg batchsqlc.UpdateBatchScheduleFiredParams) error {
+			return nil
+		},
+		NotifyJobsQueuedFunc: func(ctx context.Context, app string) (string, error) {
+			return "", nil
+		},
+	}
+}
+
+func newTestSchedule(t *testing.T, input func(fireAt time.Time) (JSONstr, []BatchInput_t, error)) *registeredSchedule {
+	cron, err := parseCron("0 18 * * *")
+	require.NoError(t, err)
+	return &registeredSchedule{
+		BatchSchedule: BatchSchedule{
+			Name:     "eod",
+			App:      "banking",
+			Op:       "eodreport",
+			Cron:     "0 18 * * *",
+			Location: time.UTC,
+			Input:    input,
+		},
+		cron: cron,
+	}
+}
+
+func scheduleInput(n int) func(fireAt time.Time) (JSONstr, []BatchInput_t, error) {
+	return func(fireAt time.Time) (JSONstr, []BatchInput_t, error) {
+		batchctx, _ := NewJSONstr(`{"date":"` + fireAt.Format("2006-01-02") + `"}`)
+		rows := make([]BatchInput_t, n)
+		for i := range rows {
+			input, _ := NewJSONstr(`{}`)
+			rows[i] = BatchInput_t{Line: i + 1, Input: input}
+		}
+		return batchctx, rows, nil
+	}
+}
+
+func TestRegisterBatchSchedule_Validation(t *testing.T) {
+	jm := newTestJobManager(&mocks.QuerierMock{}, nil)
+	input := scheduleInput(1)
+
+	tests := []struct {
+		name     string
+		schedule BatchSchedule
+	}{
+		{"missing name", BatchSchedule{App: "banking", Op: "eodreport", Cron: "@daily", Input: input}},
+		{"missing app", BatchSchedule{Name: "eod", Op: "eodreport", Cron: "@daily", Input: input}},
+		{"missing input", BatchSchedule{Name: "eod", App: "banking", Op: "eodreport", Cron: "@daily"}},
+		{"bad cron", BatchSchedule{Name: "eod", App: "banking", Op: "eodreport", Cron: "61 * * * *", Input: input}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := jm.RegisterBatchSchedule(tt.schedule)
+			assert.ErrorIs(t, err, ErrInvalidSchedule)
+		})
+	}
+
+	schedule := BatchSchedule{Name: "eod", App: "banking", Op: "EODReport", Cron: "@daily", Input: input}
+	require.NoError(t, jm.RegisterBatchSchedule(schedule))
+	assert.Equal(t, "eodreport", jm.schedules["eod"].Op)
+	assert.Equal(t, time.Local, jm.schedules["eod"].Location)
+
+	err := jm.RegisterBatchSchedule(schedule)
+	assert.ErrorIs(t, err, ErrScheduleAlreadyRegistered)
+}
+
+func TestLatestFireTime(t *testing.T) {
+	s := newTestSchedule(t, scheduleInput(1))
+	last := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	assert.True(t, s.latestFireTime(last, time.Date(2024, 3, 1, 17, 59, 0, 0, time.UTC)).IsZero())
+	assert.Equal(t, time.Date(2024, 3, 1, 18, 0, 0, 0, time.UTC),
+		s.latestFireTime(last, time.Date(2024, 3, 1, 18, 0, 20, 0, time.UTC)))
+	// missed fire times are collapsed into the most recent one
+	assert.Equal(t, time.Date(2024, 3, 4, 18, 0, 0, 0, time.UTC),
+		s.latestFireTime(last, time.Date(2024, 3, 5, 9, 0, 0, 0, time.UTC)))
+}
+
+func TestFireScheduleTx(t *testing.T) {
+	last := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	due := time.Date(2024, 3, 1, 18, 0, 20, 0, time.UTC)
+	ctx := context.Background()
+
+	t.Run("locked elsewhere", func(t *testing.T) {
+		q := newScheduleQuerier(false, last)
+		jm := newTestJobManager(q, nil)
+
+		batchID, err := jm.fireScheduleTx(ctx, q, newTestSchedule(t, scheduleInput(1)), due)
+		require.NoError(t, err)
+		assert.Empty(t, batchID)
+		assert.Empty(t, q.UpsertBatchScheduleCalls())
+		assert.Empty(t, q.InsertIntoBatchesCalls())
+	})
+
+	t.Run("not due", func(t *testing.T) {
+		q := newScheduleQuerier(true, last)
+		jm := newTestJobManager(q, nil)
+
+		batchID, err := jm.fireScheduleTx(ctx, q, newTestSchedule(t, scheduleInput(1)), time.Date(2024, 3, 1, 17, 0, 0, 0, time.UTC))
+		require.NoError(t, err)
+		assert.Empty(t, batchID)
+		assert.Len(t, q.UpsertBatchScheduleCalls(), 1)
+		assert.Empty(t, q.InsertIntoBatchesCalls())
+		assert.Empty(t, q.UpdateBatchScheduleFiredCalls())
+	})
+
+	t.Run("due", func(t *testing.T) {
+		q := newScheduleQuerier(true, last)
+		jm := newTestJobManager(q, nil)
+
+		batchID, err := jm.fireScheduleTx(ctx, q, newTestSchedule(t, scheduleInput(3)), due)
+		require.NoError(t, err)
+		require.NotEmpty(t, batchID)
+		assert.Equal(t, "schedule:eod", q.TryAdvisoryLockBatchCalls()[0].Dollar_1)
+
+		require.Len(t, q.InsertIntoBatchesCalls(), 1)
+		batch := q.InsertIntoBatchesCalls()[0].Arg
+		assert.Equal(t, batchID, batch.ID.String())
+		assert.Equal(t, "banking", batch.App)
+		assert.Equal(t, "eodreport", batch.Op)
+		assert.Equal(t, batchsqlc.StatusEnumQueued, batch.Status)
+		assert.JSONEq(t, `{"date":"2024-03-01"}`, string(batch.Context))
+		assert.False(t, batch.Runat.Valid)
+
+		require.Len(t, q.BulkInsertIntoBatchRowsCalls(), 1)
+		assert.Len(t, q.BulkInsertIntoBatchRowsCalls()[0].Arg.Line, 3)
+
+		require.Len(t, q.NotifyJobsQueuedCalls(), 1)
+		assert.Equal(t, "banking", q.NotifyJobsQueuedCalls()[0].App)
+
+		require.Len(t, q.UpdateBatchScheduleFiredCalls(), 1)
+		fired := q.UpdateBatchScheduleFiredCalls()[0].Arg
+		assert.Equal(t, "eod", fired.Name)
+		assert.True(t, fired.LastFireat.Time.Equal(time.Date(2024, 3, 1, 18, 0, 0, 0, time.UTC)))
+		assert.Equal(t, batchID, uuid.UUID(fired.LastBatch.Bytes).String())
+	})
+
+	t.Run("no rows", func(t *testing.T) {
+		q := newScheduleQuerier(true, last)
+		jm := newTestJobManager(q, nil)
+
+		batchID, err := jm.fireScheduleTx(ctx, q, newTestSchedule(t, scheduleInput(0)), due)
+		require.NoError(t, err)
+		assert.Empty(t, batchID)
+		assert.Empty(t, q.InsertIntoBatchesCalls())
+		assert.Empty(t, q.NotifyJobsQueuedCalls())
+		// the fire time is still consumed
+		assert.Len(t, q.UpdateBatchScheduleFiredCalls(), 1)
+	})
+
+	t.Run("input error", func(t *testing.T) {
+		q := newScheduleQuerier(true, last)
+		jm := newTestJobManager(q, nil)
+		input := func(fireAt time.Time) (JSONstr, []BatchInput_t, error) {
+			return JSONstr{}, nil, errors.New("ledger not closed")
+		}
+
+		_, err := jm.fireScheduleTx(ctx, q, newTestSchedule(t, input), due)
+		assert.ErrorContains(t, err, "ledger not closed")
+		assert.Empty(t, q.InsertIntoBatchesCalls())
+		assert.Empty(t, q.UpdateBatchScheduleFiredCalls())
+	})
+}
@@ -9,7 +9,6 @@ import (
 	"strings"
 	"time"
 
-	"github.com/go-redis/redis/v8"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/remiges-tech/alya/jobs/pg/batchsqlc"
@@ -26,6 +25,12 @@ var ErrProcessorAlreadyRegistered = errors.New("processor already registered for
 // Attempting to register a second processor for the same combination will result in an error.
 // The 'op' parameter is case-insensitive and will be converted to lowercase before registration.
 func (jm *JobManager) RegisterProcessorSlowQuery(app string, op string, p SlowQueryProcessor) error {
+	return jm.RegisterProcessorSlowQueryV2(app, op, slowQueryProcessorAdapter{p})
+}
+
+// RegisterProcessorSlowQueryV2 registers a context-aware processor for an operation type,
+// like RegisterProcessorSlowQuery.
+func (jm *JobManager) RegisterProcessorSlowQueryV2(app string, op string, p SlowQueryProcessorV2) error {
 	key := app + op
 	_, exists := jm.slowqueryprocessorfuncs[key]
 	if exists {
@@ -36,8 +41,15 @@ func (jm *JobManager) RegisterProcessorSlowQuery(app string, op string, p SlowQu
 }
 
 func (jm *JobManager) SlowQuerySubmit(app, op string, inputContext, input JSONstr) (reqID string, err error) {
+	return jm.SlowQuerySubmitWithOptions(app, op, inputContext, input, SubmitOptions_t{})
+}
+
+// SlowQuerySubmitWithOptions submits a slow query like SlowQuerySubmit, with the optional
+// settings in opts. opts.WaitABit and opts.DependsOn are ignored; slow queries are always
+// queued.
+func (jm *JobManager) SlowQuerySubmitWithOptions(app, op string, inputContext, input JSONstr, opts SubmitOptions_t) (reqID string, err error) {
 	// Start a database transaction
-	tx, err := jm.db.Begin(context.Background())
+	tx, err := jm.store.Begin(context.Background())
 	if err != nil {
 		return "", err
 	}
@@ -52,19 +64,38 @@ func (jm *JobManager) SlowQuerySubmit(app, op string, inputContext, input JSONst
 	}
 
 	// Create transaction-bound queries
-	txQueries := batchsqlc.New(tx)
+	txQueries := tx.Queries()
+
+	// A repeated submission returns the slow query submitted first
+	if opts.IdempotencyKey != "" {
+		existing, err := jm.claimIdempotencyKey(ctx, txQueries, app, opts.IdempotencyKey, batchId)
+		if err != nil {
+			return "", err
+		}
+		if existing != uuid.Nil {
+			return existing.String(), nil
+		}
+	}
 
 	// Convert op to lowercase before inserting into the database
 	op = strings.ToLower(op)
 
+	callbackURL, err := callbackURLParam(opts.CallbackURL)
+	if err != nil {
+		return "", err
+	}
+
 	// Use sqlc generated function to insert into batches table
 	_, err = txQueries.InsertIntoBatches(ctx, batchsqlc.InsertIntoBatchesParams{
-		ID:      batchId,
-		App:     app,
-		Op:      op,
-		Context: []byte(inputContext.String()),
-		Status:  batchsqlc.StatusEnumQueued,
-		Reqat:   pgtype.Timestamp{Time: time.Now(), Valid: true},
+		ID:          batchId,
+		App:         app,
+		Op:          op,
+		Context:     []byte(inputContext.String()),
+		Status:      batchsqlc.StatusEnumQueued,
+		Reqat:       pgtype.Timestamp{Time: time.Now(), Valid: true},
+		Runat:       pgtype.Timestamp{Time: opts.RunAt.In(time.Local), Valid: !opts.RunAt.IsZero()},
+		Priority:    int32(opts.Priority),
+		CallbackUrl: callbackURL,
 	})
 	if err != nil {
 		log.Printf("SlowQuery.Submit InsertIntoBatchesFailed: %v", err)
@@ -73,16 +104,25 @@ func (jm *JobManager) SlowQuerySubmit(app, op string, inputContext, input JSONst
 
 	// Use sqlc generated function to insert into batchrows table
 	err = txQueries.InsertIntoBatchRows(ctx, batchsqlc.InsertIntoBatchRowsParams{
-		Batch: batchId,
-		Line:  0,
-		Input: []byte(input.String()),
-		Reqat: pgtype.Timestamp{Time: time.Now(), Valid: true},
+		Batch:    batchId,
+		Line:     0,
+		Input:    []byte(input.String()),
+		Reqat:    pgtype.Timestamp{Time: time.Now(), Valid: true},
+		Priority: int32(opts.Priority),
 	})
 	if err != nil {
 		log.Printf("SlowQuery.Submit InsertIntoBatchRowsFailed: %v", err)
 		return "", err
 	}
 
+	// Wake up idle workers once the query is committed, unless it is not due yet
+	if !opts.RunAt.After(time.Now()) {
+		if err := jm.notifyJobsQueued(ctx, txQueries, app); err != nil {
+			log.Printf("SlowQuery.Submit NotifyJobsQueuedFailed: %v", err)
+			return "", err
+		}
+	}
+
 	// Commit the transaction
 	if err := tx.Commit(ctx); err != nil {
 		log.Printf("SlowQuery.Submit Txn CommitFailed: %v", err)
@@ -103,6 +143,7 @@ const (
 	BatchWait
 	BatchQueued
 	BatchInProgress
+	BatchPaused
 )
 
 // determineBatchStatus converts a batch status from the database or Redis
@@ -116,7 +157,7 @@ func getBatchStatus(status batchsqlc.StatusEnum) BatchStatus_t {
 	case batchsqlc.StatusEnumAborted:
 		return BatchAborted
 	default:
-		// This includes StatusEnumQueued, StatusEnumInprog, StatusEnumWait, or any other unexpected value.
+		// This includes StatusEnumQueued, StatusEnumInprog, StatusEnumWait, StatusEnumPaused, or any other unexpected value.
 		return BatchTryLater
 	}
 }
@@ -134,8 +175,8 @@ func (jm *JobManager) SlowQueryDone(reqID string) (status BatchStatus_t, result
 		result, _ := NewJSONstr("")
 		return BatchTryLater, result, nil, outputfiles, fmt.Errorf("invalid request ID: %v", err)
 	}
-	statusVal, err := jm.redisClient.Get(context.Background(), redisKey).Result()
-	if err == redis.Nil {
+	statusVal, err := jm.getFromRedis(context.Background(), redisKey)
+	if err == ErrStatusCacheMiss {
 		// Key does not exist in REDIS, check the database
 		batchStatus, resultData, outputfiles, err := getBatchDetails(jm, reqIDUUID)
 		if err != nil {
@@ -160,7 +201,7 @@ func (jm *JobManager) SlowQueryDone(reqID string) (status BatchStatus_t, result
 			expirySec = 100 * jm.config.BatchStatusCacheDurSec
 		}
 
-		if err := updateStatusAndOutputFilesDataInRedis(jm.redisClient, reqIDUUID, batchStatus, outputfiles, resultData.String(), expirySec); err != nil {
+		if err := updateStatusAndOutputFilesDataInRedis(jm.statusCache, reqIDUUID, batchStatus, outputfiles, resultData.String(), expirySec); err != nil {
 			jm.logger.Warn().LogActivity("Failed to update status and output files in Redis cache for slow query", map[string]any{
 				"reqId": reqIDUUID.String(),
 				"error": err.Error(),
@@ -179,13 +220,13 @@ func (jm *JobManager) SlowQueryDone(reqID string) (status BatchStatus_t, result
 		// Key exists in REDIS, determine the action based on its value
 
 		// Fetch the result and outputFiles from Redis
-		resultVal, err := jm.redisClient.Get(context.Background(), redisResultKey).Result()
+		resultVal, err := jm.getFromRedis(context.Background(), redisResultKey)
 		if err != nil {
 			result, _ := NewJSONstr("")
 			return BatchTryLater, result, nil, outputfiles, err
 		}
 
-		outputFilesVal, err := jm.redisClient.Get(context.Background(), redisOutputFilesKey).Result()
+		outputFilesVal, err := jm.getFromRedis(context.Background(), redisOutputFilesKey)
 		if err != nil {
 			result, _ := NewJSONstr("")
 			return BatchTryLater, result, nil, outputfiles, err
@@ -219,14 +260,17 @@ func (jm *JobManager) SlowQueryAbort(reqID string) (err error) {
 	}
 
 	// Start a transaction
-	tx, err := jm.db.Begin(context.Background())
+	tx, err := jm.store.Begin(context.Background())
 	if err != nil {
 		return fmt.Errorf("failed to start transaction: %v", err)
 	}
 	defer tx.Rollback(context.Background())
 
+	// Create transaction-bound queries
+	txQueries := tx.Queries()
+
 	// Perform SELECT FOR UPDATE on batches and batchrows for the given request ID
-	batch, err := jm.queries.GetBatchByID(context.Background(), reqIDUUID)
+	batch, err := txQueries.GetBatchByID(context.Background(), reqIDUUID)
 	if err != nil {
 		return fmt.Errorf("failed to get batch by ID: %v", err)
 	}
@@ -239,7 +283,7 @@ func (jm *JobManager) SlowQueryAbort(reqID string) (err error) {
 	}
 
 	// Update the batch status to aborted and set doneat timestamp
-	err = jm.queries.UpdateBatchSummary(context.Background(), batchsqlc.UpdateBatchSummaryParams{
+	err = txQueries.UpdateBatchSummary(context.Background(), batchsqlc.UpdateBatchSummaryParams{
 		ID:     reqIDUUID,
 		Status: batchsqlc.StatusEnumAborted,
 		Doneat: pgtype.Timestamp{Time: time.Now(), Valid: true},
@@ -249,7 +293,7 @@ func (jm *JobManager) SlowQueryAbort(reqID string) (err error) {
 	}
 
 	// Fetch the pending batchrows records associated with the batch ID
-	pendingRows, err := jm.queries.GetPendingBatchRows(context.Background(), reqIDUUID)
+	pendingRows, err := txQueries.GetPendingBatchRows(context.Background(), reqIDUUID)
 	if err != nil {
 		return fmt.Errorf("failed to get pending batchrows: %v", err)
 	}
@@ -261,7 +305,7 @@ func (jm *JobManager) SlowQueryAbort(reqID string) (err error) {
 	}
 
 	// Update the batchrows status to aborted for rows with status queued or inprog
-	err = jm.queries.UpdateBatchRowsStatus(context.Background(), batchsqlc.UpdateBatchRowsStatusParams{
+	err = txQueries.UpdateBatchRowsStatus(context.Background(), batchsqlc.UpdateBatchRowsStatusParams{
 		Status:  batchsqlc.StatusEnumAborted,
 		Column2: rowids,
 	})
@@ -269,6 +313,21 @@ func (jm *JobManager) SlowQueryAbort(reqID string) (err error) {
 		return fmt.Errorf("failed to update batchrows status: %v", err)
 	}
 
+	// Queue the completion webhook, if the slow query has a callback URL
+	if batch.CallbackUrl.Valid {
+		err = enqueueCompletionWebhook(context.Background(), txQueries, reqIDUUID, CompletionEvent_t{
+			Event:  WebhookEventSlowQueryDone,
+			ID:     reqID,
+			App:    batch.App,
+			Op:     batch.Op,
+			Status: batchsqlc.StatusEnumAborted,
+			DoneAt: time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
 	// Commit the transaction
 	err = tx.Commit(context.Background())
 	if err != nil {
@@ -276,13 +335,13 @@ func (jm *JobManager) SlowQueryAbort(reqID string) (err error) {
 	}
 
 	// Set the Redis batch status record to aborted with an expiry time
-	redisKey := BatchStatusKey(reqID)
-	expiry := time.Duration(jm.config.BatchStatusCacheDurSec*100) * time.Second
-	err = jm.redisClient.Set(context.Background(), redisKey, string(batchsqlc.StatusEnumAborted), expiry).Err()
+	err = updateStatusInRedis(jm.statusCache, reqIDUUID, batchsqlc.StatusEnumAborted, 100*jm.config.BatchStatusCacheDurSec)
 	if err != nil {
 		log.Printf("failed to set Redis batch status: %v", err)
 	}
 
+	// Interrupt the slow query if it is being processed
+	jm.signalAbort(reqIDUUID)
 	return nil
 }
 
@@ -0,0 +1,73 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ErrStatusCacheMiss is returned by StatusCache.Get for a key that is not cached.
+var ErrStatusCacheMiss = errors.New("status cache miss")
+
+// StatusCache caches the status, summary, result and output files of batches and slow
+// queries, so that BatchDone, BatchStatus and SlowQueryDone do not read them from the
+// database every time. NewRedisStatusCache returns the Redis cache that NewJobManager
+// uses, and NewMemoryCache one that lives in memory, for tests and single-node tools.
+type StatusCache interface {
+	// Get returns the value of key, or ErrStatusCacheMiss if it is not cached or has
+	// expired.
+	Get(ctx context.Context, key string) (string, error)
+
+	// Set caches value as the value of key for expiry, or for good if expiry is 0.
+	Set(ctx context.Context, key, value string, expiry time.Duration) error
+
+	// SetMulti caches all the given values, or none of them, for expiry.
+	SetMulti(ctx context.Context, values map[string]string, expiry time.Duration) error
+
+	// Del drops keys. Keys that are not cached are ignored.
+	Del(ctx context.Context, keys ...string) error
+}
+
+// RedisStatusCache is the StatusCache on Redis. A JobManager whose status cache is a
+// RedisStatusCache also uses its client for crash recovery, processor limits, aborts
+// and progress across instances; with any other cache it runs as without Redis.
+type RedisStatusCache struct {
+	client redis.UniversalClient
+}
+
+// NewRedisStatusCache returns the StatusCache on the Redis server or cluster of client.
+func NewRedisStatusCache(client redis.UniversalClient) *RedisStatusCache {
+	return &RedisStatusCache{client: client}
+}
+
+// Get reads key with GET.
+func (c *RedisStatusCache) Get(ctx context.Context, key string) (string, error) {
+	value, err := c.client.Get(ctx, key).Result()
+	if err == redis.Nil {
+		return "", ErrStatusCacheMiss
+	}
+	return value, err
+}
+
+// Set writes key with SET.
+func (c *RedisStatusCache) Set(ctx context.Context, key, value string, expiry time.Duration) error {
+	return c.client.Set(ctx, key, value, expiry).Err()
+}
+
+// SetMulti writes the keys in a MULTI/EXEC transaction. The keys of a batch share
+// their hash tag, so they are in the same slot of a Redis Cluster.
+func (c *RedisStatusCache) SetMulti(ctx context.Context, values map[string]string, expiry time.Duration) error {
+	pipe := c.client.TxPipeline()
+	for key, value := range values {
+		pipe.Set(ctx, key, value, expiry)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Del drops keys with DEL.
+func (c *RedisStatusCache) Del(ctx context.Context, keys ...string) error {
+	return c.client.Del(ctx, keys...).Err()
+}
@@ -0,0 +1,104 @@
+package jobs
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/remiges-tech/alya/jobs/pg/batchsqlc"
+)
+
+// Store is where a JobManager keeps its batches, their rows and the records around
+// them. NewPgStore returns the PostgreSQL store that NewJobManager uses, and
+// NewMemoryStore one that lives in memory, for tests and single-node tools.
+type Store interface {
+	// Queries returns queries that each run on their own, outside any transaction.
+	Queries() batchsqlc.Querier
+
+	// Begin starts a transaction. It must be ended with Commit or Rollback; ending
+	// it again returns pgx.ErrTxClosed.
+	Begin(ctx context.Context) (StoreTx, error)
+
+	// Listen calls notify with the app of every NotifyJobsQueued committed to the
+	// store, by any JobManager, until ctx is cancelled or the store fails. notify
+	// may also be called with an empty app, when work may have been queued that
+	// Listen did not see.
+	Listen(ctx context.Context, notify func(app string)) error
+}
+
+// StoreTx is a transaction of a Store.
+type StoreTx interface {
+	// Queries returns queries that run in the transaction.
+	Queries() batchsqlc.Querier
+	Commit(ctx context.Context) error
+	Rollback(ctx context.Context) error
+}
+
+// PgStore is the Store on a PostgreSQL database migrated with MigrateDatabase.
+type PgStore struct {
+	db      *pgxpool.Pool
+	queries *batchsqlc.Queries
+}
+
+// NewPgStore returns the Store on the database of db.
+func NewPgStore(db *pgxpool.Pool) *PgStore {
+	return &PgStore{db: db, queries: batchsqlc.New(db)}
+}
+
+// Queries returns queries on connections taken from the pool.
+func (s *PgStore) Queries() batchsqlc.Querier {
+	return s.queries
+}
+
+// Begin starts a transaction on a connection taken from the pool.
+func (s *PgStore) Begin(ctx context.Context) (StoreTx, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &pgStoreTx{tx: tx, queries: s.queries.WithTx(tx)}, nil
+}
+
+// Listen holds a dedicated connection listening on notifyChannel until ctx is
+// cancelled or the connection fails.
+func (s *PgStore) Listen(ctx context.Context, notify func(app string)) error {
+	conn, err := s.db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "LISTEN "+notifyChannel); err != nil {
+		return err
+	}
+	// Work queued while we were not listening would otherwise wait for the next poll
+	notify("")
+
+	for {
+		notification, err := conn.Conn().WaitForNotification(ctx)
+		if err != nil {
+			// The connection may still be listening; don't hand it back to the pool
+			conn.Conn().Close(context.Background())
+			return err
+		}
+		notify(notification.Payload)
+	}
+}
+
+// pgStoreTx is a transaction of a PgStore.
+type pgStoreTx struct {
+	tx      pgx.Tx
+	queries *batchsqlc.Queries
+}
+
+func (t *pgStoreTx) Queries() batchsqlc.Querier {
+	return t.queries
+}
+
+func (t *pgStoreTx) Commit(ctx context.Context) error {
+	return t.tx.Commit(ctx)
+}
+
+func (t *pgStoreTx) Rollback(ctx context.Context) error {
+	return t.tx.Rollback(ctx)
+}
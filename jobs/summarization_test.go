@@ -28,7 +28,7 @@ var summarizeBatchTests = []struct {
 	name           string
 	batchID        uuid.UUID
 	batchRows      []batchsqlc.GetBatchRowsByBatchIDSortedRow
-	processedRows  []batchsqlc.GetProcessedBatchRowsByBatchIDSortedRow
+	processedRows  []batchsqlc.GetProcessedBatchRowsPageRow
 	expectedStatus batchsqlc.StatusEnum
 	expectedCounts struct {
 		success int64
@@ -44,10 +44,10 @@ var summarizeBatchTests = []struct {
 			{Status: batchsqlc.StatusEnumSuccess, Blobrows: []byte(`{"transaction_summary.txt": "TX002,WITHDRAWAL,500.00,4500.00", "error_log.txt": ""}`)},
 			{Status: batchsqlc.StatusEnumSuccess, Blobrows: []byte(`{"transaction_summary.txt": "TX003,TRANSFER,2000.00,2500.00", "error_log.txt": ""}`)},
 		},
-		processedRows: []batchsqlc.GetProcessedBatchRowsByBatchIDSortedRow{
-			{Status: batchsqlc.StatusEnumSuccess, Blobrows: []byte(`{"transaction_summary.txt": "TX001,DEPOSIT,1000.00,5000.00", "error_log.txt": ""}`)},
-			{Status: batchsqlc.StatusEnumSuccess, Blobrows: []byte(`{"transaction_summary.txt": "TX002,WITHDRAWAL,500.00,4500.00", "error_log.txt": ""}`)},
-			{Status: batchsqlc.StatusEnumSuccess, Blobrows: []byte(`{"transaction_summary.txt": "TX003,TRANSFER,2000.00,2500.00", "error_log.txt": ""}`)},
+		processedRows: []batchsqlc.GetProcessedBatchRowsPageRow{
+			{Blobrows: []byte(`{"transaction_summary.txt": "TX001,DEPOSIT,1000.00,5000.00", "error_log.txt": ""}`)},
+			{Blobrows: []byte(`{"transaction_summary.txt": "TX002,WITHDRAWAL,500.00,4500.00", "error_log.txt": ""}`)},
+			{Blobrows: []byte(`{"transaction_summary.txt": "TX003,TRANSFER,2000.00,2500.00", "error_log.txt": ""}`)},
 		},
 		expectedStatus: batchsqlc.StatusEnumSuccess,
 		expectedCounts: struct {
@@ -69,11 +69,11 @@ var summarizeBatchTests = []struct {
 			{Status: batchsqlc.StatusEnumSuccess, Blobrows: []byte(`{"transaction_summary.txt": "TX003,TRANSFER,2000.00,2500.00", "error_log.txt": ""}`)},
 			{Status: batchsqlc.StatusEnumFailed, Blobrows: []byte(`{"transaction_summary.txt": "", "error_log.txt": "ERROR: Invalid account number for TX004"}`)},
 		},
-		processedRows: []batchsqlc.GetProcessedBatchRowsByBatchIDSortedRow{
-			{Status: batchsqlc.StatusEnumSuccess, Blobrows: []byte(`{"transaction_summary.txt": "TX001,DEPOSIT,1000.00,5000.00", "error_log.txt": ""}`)},
-			{Status: batchsqlc.StatusEnumFailed, Blobrows: []byte(`{"transaction_summary.txt": "", "error_log.txt": "ERROR: Insufficient funds for TX002"}`)},
-			{Status: batchsqlc.StatusEnumSuccess, Blobrows: []byte(`{"transaction_summary.txt": "TX003,TRANSFER,2000.00,2500.00", "error_log.txt": ""}`)},
-			{Status: batchsqlc.StatusEnumFailed, Blobrows: []byte(`{"transaction_summary.txt": "", "error_log.txt": "ERROR: Invalid account number for TX004"}`)},
+		processedRows: []batchsqlc.GetProcessedBatchRowsPageRow{
+			{Blobrows: []byte(`{"transaction_summary.txt": "TX001,DEPOSIT,1000.00,5000.00", "error_log.txt": ""}`)},
+			{Blobrows: []byte(`{"transaction_summary.txt": "", "error_log.txt": "ERROR: Insufficient funds for TX002"}`)},
+			{Blobrows: []byte(`{"transaction_summary.txt": "TX003,TRANSFER,2000.00,2500.00", "error_log.txt": ""}`)},
+			{Blobrows: []byte(`{"transaction_summary.txt": "", "error_log.txt": "ERROR: Invalid account number for TX004"}`)},
 		},
 		expectedStatus: batchsqlc.StatusEnumFailed,
 		expectedCounts: struct {
@@ -95,9 +95,9 @@ var summarizeBatchTests = []struct {
 			{Status: batchsqlc.StatusEnumSuccess, Blobrows: []byte(`{"transaction_summary.txt": "TX003,TRANSFER,2000.00,2500.00", "error_log.txt": ""}`)},
 			{Status: batchsqlc.StatusEnumAborted, Blobrows: []byte(`{"transaction_summary.txt": "", "error_log.txt": "ABORT: System maintenance, transaction TX004 aborted"}`)},
 		},
-		processedRows: []batchsqlc.GetProcessedBatchRowsByBatchIDSortedRow{
-			{Status: batchsqlc.StatusEnumSuccess, Blobrows: []byte(`{"transaction_summary.txt": "TX001,DEPOSIT,1000.00,5000.00", "error_log.txt": ""}`)},
-			{Status: batchsqlc.StatusEnumSuccess, Blobrows: []byte(`{"transaction_summary.txt": "TX003,TRANSFER,2000.00,2500.00", "error_log.txt": ""}`)},
+		processedRows: []batchsqlc.GetProcessedBatchRowsPageRow{
+			{Blobrows: []byte(`{"transaction_summary.txt": "TX001,DEPOSIT,1000.00,5000.00", "error_log.txt": ""}`)},
+			{Blobrows: []byte(`{"transaction_summary.txt": "TX003,TRANSFER,2000.00,2500.00", "error_log.txt": ""}`)},
 		},
 		expectedStatus: batchsqlc.StatusEnumAborted,
 		expectedCounts: struct {
@@ -165,7 +165,8 @@ func TestSummarizeBatch(t *testing.T) {
 			mockQuerier.GetBatchRowsByBatchIDSortedFunc = func(ctx context.Context, batch uuid.UUID) ([]batchsqlc.GetBatchRowsByBatchIDSortedRow, error) {
 				return tt.batchRows, nil
 			}
-			mockQuerier.GetProcessedBatchRowsByBatchIDSortedFunc = func(ctx context.Context, batch uuid.UUID) ([]batchsqlc.GetProcessedBatchRowsByBatchIDSortedRow, error) {
+			mockQuerier.GetProcessedBatchRowsPageFunc = func(ctx context.Context, arg batchsqlc.GetProcessedBatchRowsPageParams) ([]batchsqlc.GetProcessedBatchRowsPageRow, error) {
+				// All the rows fit in the first page
 				return tt.processedRows, nil
 			}
 			mockQuerier.UpdateBatchSummaryFunc = func(ctx context.Context, arg batchsqlc.UpdateBatchSummaryParams) error {
@@ -176,6 +177,9 @@ func TestSummarizeBatch(t *testing.T) {
 				assert.Equal(t, int32(tt.expectedCounts.aborted), arg.Naborted.Int32)
 				return nil
 			}
+			mockQuerier.GetWaitingDependentBatchesFunc = func(ctx context.Context, parent uuid.UUID) ([]uuid.UUID, error) {
+				return nil, nil
+			}
 
 			mockObjStore := &objstore.ObjectStoreMock{}
 			mockObjStore.PutFunc = func(ctx context.Context, bucket, obj string, reader io.Reader, size int64, contentType string) error {
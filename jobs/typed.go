@@ -0,0 +1,271 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/remiges-tech/alya/jobs/pg/batchsqlc"
+	"github.com/remiges-tech/alya/wscutils"
+)
+
+// TypedBatchProcessor is a BatchProcessorV2 that works on decoded values instead of
+// JSONstr. Register it with RegisterTypedBatch.
+type TypedBatchProcessor[Ctx, In, Out any] interface {
+	DoBatchJob(ctx context.Context, initBlock InitBlock, jobctx Ctx, line int, input In) (status batchsqlc.StatusEnum, result Out, messages []wscutils.ErrorMessage, blobRows map[string]string, err error)
+	MarkDone(ctx context.Context, initBlock InitBlock, jobctx Ctx, details BatchDetails_t) error
+}
+
+// TypedSlowQueryProcessor is a SlowQueryProcessorV2 that works on decoded values instead
+// of JSONstr. Register it with RegisterTypedSlowQuery.
+type TypedSlowQueryProcessor[Ctx, In, Out any] interface {
+	DoSlowQuery(ctx context.Context, initBlock InitBlock, jobctx Ctx, input In) (status batchsqlc.StatusEnum, result Out, messages []wscutils.ErrorMessage, outputFiles map[string]string, err error)
+	MarkDone(ctx context.Context, initBlock InitBlock, jobctx Ctx, details BatchDetails_t) error
+}
+
+// TypedBatchFunc is a TypedBatchProcessor made of its DoBatchJob function; its MarkDone
+// does nothing.
+type TypedBatchFunc[Ctx, In, Out any] func(ctx context.Context, initBlock InitBlock, jobctx Ctx, line int, input In) (batchsqlc.StatusEnum, Out, []wscutils.ErrorMessage, map[string]string, error)
+
+func (f TypedBatchFunc[Ctx, In, Out]) DoBatchJob(ctx context.Context, initBlock InitBlock, jobctx Ctx, line int, input In) (batchsqlc.StatusEnum, Out, []wscutils.ErrorMessage, map[string]string, error) {
+	return f(ctx, initBlock, jobctx, line, input)
+}
+
+func (f TypedBatchFunc[Ctx, In, Out]) MarkDone(ctx context.Context, initBlock InitBlock, jobctx Ctx, details BatchDetails_t) error {
+	return nil
+}
+
+// TypedSlowQueryFunc is a TypedSlowQueryProcessor made of its DoSlowQuery function; its
+// MarkDone does nothing.
+type TypedSlowQueryFunc[Ctx, In, Out any] func(ctx context.Context, initBlock InitBlock, jobctx Ctx, input In) (batchsqlc.StatusEnum, Out, []wscutils.ErrorMessage, map[string]string, error)
+
+func (f TypedSlowQueryFunc[Ctx, In, Out]) DoSlowQuery(ctx context.Context, initBlock InitBlock, jobctx Ctx, input In) (batchsqlc.StatusEnum, Out, []wscutils.ErrorMessage, map[string]string, error) {
+	return f(ctx, initBlock, jobctx, input)
+}
+
+func (f TypedSlowQueryFunc[Ctx, In, Out]) MarkDone(ctx context.Context, initBlock InitBlock, jobctx Ctx, details BatchDetails_t) error {
+	return nil
+}
+
+// TypedBatch submits batches whose context and rows have the types its processor
+// expects, so that mismatched inputs are caught at compile time.
+type TypedBatch[Ctx, In any] struct {
+	jm  *JobManager
+	app string
+	op  string
+}
+
+// TypedSlowQuery submits slow queries whose context and input have the types its
+// processor expects, and decodes their results.
+type TypedSlowQuery[Ctx, In, Out any] struct {
+	jm  *JobManager
+	app string
+	op  string
+}
+
+// RegisterTypedBatch registers a typed processor for a batch operation type, like
+// RegisterProcessorBatchV2. The context and input of each row are decoded from JSON and,
+// if they are structs, validated against their `validate` tags with
+// JobManagerConfig.Validator before the processor is called; a row that does not decode
+// or validate fails with the resulting messages. The result is encoded to JSON.
+//
+// It returns the TypedBatch to submit batches of the operation with.
+func RegisterTypedBatch[Ctx, In, Out any](jm *JobManager, app string, op string, p TypedBatchProcessor[Ctx, In, Out]) (TypedBatch[Ctx, In], error) {
+	err := jm.RegisterProcessorBatchV2(app, op, typedBatchAdapter[Ctx, In, Out]{p: p, validator: jm.validator()})
+	if err != nil {
+		return TypedBatch[Ctx, In]{}, err
+	}
+	return NewTypedBatch[Ctx, In](jm, app, op), nil
+}
+
+// RegisterTypedSlowQuery registers a typed processor for a slow query operation type,
+// like RegisterProcessorSlowQueryV2, decoding and validating as RegisterTypedBatch does.
+//
+// It returns the TypedSlowQuery to submit slow queries of the operation with.
+func RegisterTypedSlowQuery[Ctx, In, Out any](jm *JobManager, app string, op string, p TypedSlowQueryProcessor[Ctx, In, Out]) (TypedSlowQuery[Ctx, In, Out], error) {
+	err := jm.RegisterProcessorSlowQueryV2(app, op, typedSlowQueryAdapter[Ctx, In, Out]{p: p, validator: jm.validator()})
+	if err != nil {
+		return TypedSlowQuery[Ctx, In, Out]{}, err
+	}
+	return NewTypedSlowQuery[Ctx, In, Out](jm, app, op), nil
+}
+
+// NewTypedBatch returns a TypedBatch for an operation whose processor is registered
+// elsewhere, e.g. by the JobManager instances that process the batches.
+func NewTypedBatch[Ctx, In any](jm *JobManager, app string, op string) TypedBatch[Ctx, In] {
+	return TypedBatch[Ctx, In]{jm: jm, app: app, op: op}
+}
+
+// NewTypedSlowQuery returns a TypedSlowQuery for an operation whose processor is
+// registered elsewhere.
+func NewTypedSlowQuery[Ctx, In, Out any](jm *JobManager, app string, op string) TypedSlowQuery[Ctx, In, Out] {
+	return TypedSlowQuery[Ctx, In, Out]{jm: jm, app: app, op: op}
+}
+
+// Submit submits a batch with one row per input, numbered from 1, like
+// BatchSubmitWithOptions.
+func (b TypedBatch[Ctx, In]) Submit(batchctx Ctx, inputs []In, opts SubmitOptions_t) (batchID string, err error) {
+	ctxJSON, err := encodeJSONstr(batchctx)
+	if err != nil {
+		return "", fmt.Errorf("invalid batch context: %w", err)
+	}
+	batchInput, err := TypedBatchInput(inputs)
+	if err != nil {
+		return "", err
+	}
+	return b.jm.BatchSubmitWithOptions(b.app, b.op, ctxJSON, batchInput, opts)
+}
+
+// Submit submits a slow query like SlowQuerySubmitWithOptions.
+func (s TypedSlowQuery[Ctx, In, Out]) Submit(jobctx Ctx, input In, opts SubmitOptions_t) (reqID string, err error) {
+	ctxJSON, err := encodeJSONstr(jobctx)
+	if err != nil {
+		return "", fmt.Errorf("invalid slow query context: %w", err)
+	}
+	inputJSON, err := encodeJSONstr(input)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	return s.jm.SlowQuerySubmitWithOptions(s.app, s.op, ctxJSON, inputJSON, opts)
+}
+
+// Done returns the status of a slow query like SlowQueryDone, with its result decoded.
+// The result is the zero value until the slow query has succeeded.
+func (s TypedSlowQuery[Ctx, In, Out]) Done(reqID string) (status BatchStatus_t, result Out, messages []wscutils.ErrorMessage, outputFiles map[string]string, err error) {
+	status, resultJSON, messages, outputFiles, err := s.jm.SlowQueryDone(reqID)
+	if err != nil || status != BatchSuccess {
+		return status, result, messages, outputFiles, err
+	}
+	if err := json.Unmarshal([]byte(resultJSON.String()), &result); err != nil {
+		return status, result, messages, outputFiles, fmt.Errorf("failed to decode slow query result: %w", err)
+	}
+	return status, result, messages, outputFiles, nil
+}
+
+// TypedBatchInput encodes inputs as the rows of a batch, numbered from 1.
+func TypedBatchInput[In any](inputs []In) ([]BatchInput_t, error) {
+	batchInput := make([]BatchInput_t, len(inputs))
+	for i, input := range inputs {
+		inputJSON, err := encodeJSONstr(input)
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidInput, i+1, err)
+		}
+		batchInput[i] = BatchInput_t{Line: i + 1, Input: inputJSON}
+	}
+	return batchInput, nil
+}
+
+// typedBatchAdapter runs a TypedBatchProcessor as a BatchProcessorV2.
+type typedBatchAdapter[Ctx, In, Out any] struct {
+	p         TypedBatchProcessor[Ctx, In, Out]
+	validator *wscutils.Validator
+}
+
+func (a typedBatchAdapter[Ctx, In, Out]) DoBatchJob(ctx context.Context, initBlock InitBlock, jobctx JSONstr, line int, input JSONstr) (batchsqlc.StatusEnum, JSONstr, []wscutils.ErrorMessage, map[string]string, error) {
+	typedCtx, messages := decodeTyped[Ctx](jobctx, "context", a.validator)
+	typedInput, inputMessages := decodeTyped[In](input, "input", a.validator)
+	if messages = append(messages, inputMessages...); len(messages) > 0 {
+		return batchsqlc.StatusEnumFailed, JSONstr{}, messages, nil, nil
+	}
+
+	status, result, messages, blobRows, err := a.p.DoBatchJob(ctx, initBlock, typedCtx, line, typedInput)
+	if err != nil {
+		return status, JSONstr{}, messages, blobRows, err
+	}
+	resultJSON, err := encodeJSONstr(result)
+	if err != nil {
+		return batchsqlc.StatusEnumFailed, JSONstr{}, messages, blobRows, fmt.Errorf("failed to encode result: %w", err)
+	}
+	return status, resultJSON, messages, blobRows, nil
+}
+
+func (a typedBatchAdapter[Ctx, In, Out]) MarkDone(ctx context.Context, initBlock InitBlock, jobctx JSONstr, details BatchDetails_t) error {
+	var typedCtx Ctx
+	if err := json.Unmarshal([]byte(jobctx.String()), &typedCtx); err != nil {
+		return fmt.Errorf("failed to decode batch context: %w", err)
+	}
+	return a.p.MarkDone(ctx, initBlock, typedCtx, details)
+}
+
+// typedSlowQueryAdapter runs a TypedSlowQueryProcessor as a SlowQueryProcessorV2.
+type typedSlowQueryAdapter[Ctx, In, Out any] struct {
+	p         TypedSlowQueryProcessor[Ctx, In, Out]
+	validator *wscutils.Validator
+}
+
+func (a typedSlowQueryAdapter[Ctx, In, Out]) DoSlowQuery(ctx context.Context, initBlock InitBlock, jobctx JSONstr, input JSONstr) (batchsqlc.StatusEnum, JSONstr, []wscutils.ErrorMessage, map[string]string, error) {
+	typedCtx, messages := decodeTyped[Ctx](jobctx, "context", a.validator)
+	typedInput, inputMessages := decodeTyped[In](input, "input", a.validator)
+	if messages = append(messages, inputMessages...); len(messages) > 0 {
+		return batchsqlc.StatusEnumFailed, JSONstr{}, messages, nil, nil
+	}
+
+	status, result, messages, outputFiles, err := a.p.DoSlowQuery(ctx, initBlock, typedCtx, typedInput)
+	if err != nil {
+		return status, JSONstr{}, messages, outputFiles, err
+	}
+	resultJSON, err := encodeJSONstr(result)
+	if err != nil {
+		return batchsqlc.StatusEnumFailed, JSONstr{}, messages, outputFiles, fmt.Errorf("failed to encode result: %w", err)
+	}
+	return status, resultJSON, messages, outputFiles, nil
+}
+
+func (a typedSlowQueryAdapter[Ctx, In, Out]) MarkDone(ctx context.Context, initBlock InitBlock, jobctx JSONstr, details BatchDetails_t) error {
+	var typedCtx Ctx
+	if err := json.Unmarshal([]byte(jobctx.String()), &typedCtx); err != nil {
+		return fmt.Errorf("failed to decode slow query context: %w", err)
+	}
+	return a.p.MarkDone(ctx, initBlock, typedCtx, details)
+}
+
+// decodeTyped decodes js into a T and validates it if it is a struct. It returns the
+// messages to fail the row with if either fails; field names the part of the row.
+func decodeTyped[T any](js JSONstr, field string, validator *wscutils.Validator) (T, []wscutils.ErrorMessage) {
+	var v T
+	if err := json.Unmarshal([]byte(js.String()), &v); err != nil {
+		return v, []wscutils.ErrorMessage{
+			wscutils.BuildErrorMessage(MsgIDInvalidRowInput, ErrCodeInvalidInput, field, err.Error()),
+		}
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return v, nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return v, nil
+	}
+	return v, validator.Validate(rv.Interface())
+}
+
+// encodeJSONstr encodes v as a JSONstr.
+func encodeJSONstr(v any) (JSONstr, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return JSONstr{}, err
+	}
+	return NewJSONstr(string(b))
+}
+
+// defaultValidator validates the context and input of typed processors when
+// JobManagerConfig.Validator is not set.
+var defaultValidator = sync.OnceValue(func() *wscutils.Validator {
+	return wscutils.NewValidator(nil, wscutils.ValidationRule{
+		MsgID:   MsgIDInvalidRowInput,
+		ErrCode: ErrCodeInvalidInput,
+	})
+})
+
+// validator returns the validator of typed processors.
+func (jm *JobManager) validator() *wscutils.Validator {
+	if jm.config.Validator != nil {
+		return jm.config.Validator
+	}
+	return defaultValidator()
+}
@@ -0,0 +1,115 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+
+	"github.com/remiges-tech/alya/jobs/pg/batchsqlc"
+	"github.com/remiges-tech/alya/jobs/pg/batchsqlc/mocks"
+	"github.com/remiges-tech/alya/wscutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type transferCtx struct {
+	Branch string `json:"branch" validate:"required"`
+}
+
+type transferIn struct {
+	Account string `json:"account" validate:"required"`
+	Amount  int    `json:"amount" validate:"gt=0"`
+}
+
+type transferOut struct {
+	Balance int `json:"balance"`
+}
+
+func transferBatchFunc(ctx context.Context, initBlock InitBlock, jobctx transferCtx, line int, input transferIn) (batchsqlc.StatusEnum, transferOut, []wscutils.ErrorMessage, map[string]string, error) {
+	return batchsqlc.StatusEnumSuccess, transferOut{Balance: 100 - input.Amount}, nil, nil, nil
+}
+
+func mustJSONstr(t *testing.T, s string) JSONstr {
+	js, err := NewJSONstr(s)
+	require.NoError(t, err)
+	return js
+}
+
+func TestRegisterTypedBatch(t *testing.T) {
+	jm := newTestJobManager(&mocks.QuerierMock{}, nil)
+
+	batch, err := RegisterTypedBatch(jm, "bank", "Transfer", TypedBatchFunc[transferCtx, transferIn, transferOut](transferBatchFunc))
+	require.NoError(t, err)
+	assert.Equal(t, "bank", batch.app)
+	assert.Contains(t, jm.batchprocessorfuncs, "banktransfer")
+
+	_, err = RegisterTypedBatch(jm, "bank", "transfer", TypedBatchFunc[transferCtx, transferIn, transferOut](transferBatchFunc))
+	assert.ErrorIs(t, err, ErrProcessorAlreadyRegistered)
+}
+
+func TestTypedBatchAdapter(t *testing.T) {
+	adapter := typedBatchAdapter[transferCtx, transferIn, transferOut]{
+		p:         TypedBatchFunc[transferCtx, transferIn, transferOut](transferBatchFunc),
+		validator: defaultValidator(),
+	}
+	ctx := context.Background()
+	jobctx := mustJSONstr(t, `{"branch":"north"}`)
+
+	t.Run("valid row", func(t *testing.T) {
+		status, result, messages, _, err := adapter.DoBatchJob(ctx, nil, jobctx, 1, mustJSONstr(t, `{"account":"A1","amount":30}`))
+		require.NoError(t, err)
+		assert.Equal(t, batchsqlc.StatusEnumSuccess, status)
+		assert.Empty(t, messages)
+		assert.JSONEq(t, `{"balance":70}`, result.String())
+	})
+
+	t.Run("invalid input", func(t *testing.T) {
+		status, _, messages, _, err := adapter.DoBatchJob(ctx, nil, mustJSONstr(t, `{}`), 1, mustJSONstr(t, `{"account":"","amount":0}`))
+		require.NoError(t, err)
+		assert.Equal(t, batchsqlc.StatusEnumFailed, status)
+		require.Len(t, messages, 3)
+		for _, msg := range messages {
+			assert.Equal(t, ErrCodeInvalidInput, msg.ErrCode)
+		}
+		assert.Equal(t, []string{"branch", "account", "amount"}, []string{messages[0].Field, messages[1].Field, messages[2].Field})
+	})
+
+	t.Run("undecodable input", func(t *testing.T) {
+		status, _, messages, _, err := adapter.DoBatchJob(ctx, nil, jobctx, 1, mustJSONstr(t, `{"account":"A1","amount":"ten"}`))
+		require.NoError(t, err)
+		assert.Equal(t, batchsqlc.StatusEnumFailed, status)
+		require.Len(t, messages, 1)
+		assert.Equal(t, MsgIDInvalidRowInput, messages[0].MsgID)
+		assert.Equal(t, "input", messages[0].Field)
+	})
+}
+
+func TestTypedSlowQueryAdapter_NonStruct(t *testing.T) {
+	adapter := typedSlowQueryAdapter[map[string]string, []int, int]{
+		p: TypedSlowQueryFunc[map[string]string, []int, int](func(ctx context.Context, initBlock InitBlock, jobctx map[string]string, input []int) (batchsqlc.StatusEnum, int, []wscutils.ErrorMessage, map[string]string, error) {
+			sum := 0
+			for _, n := range input {
+				sum += n
+			}
+			return batchsqlc.StatusEnumSuccess, sum, nil, nil, nil
+		}),
+		validator: defaultValidator(),
+	}
+
+	status, result, _, _, err := adapter.DoSlowQuery(context.Background(), nil, mustJSONstr(t, `{}`), mustJSONstr(t, `[1,2,3]`))
+	require.NoError(t, err)
+	assert.Equal(t, batchsqlc.StatusEnumSuccess, status)
+	assert.Equal(t, "6", result.String())
+	assert.NoError(t, adapter.MarkDone(context.Background(), nil, mustJSONstr(t, `{"k":"v"}`), BatchDetails_t{}))
+}
+
+func TestTypedBatchInput(t *testing.T) {
+	rows, err := TypedBatchInput([]transferIn{{Account: "A1", Amount: 5}, {Account: "A2", Amount: 7}})
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	assert.Equal(t, 1, rows[0].Line)
+	assert.Equal(t, 2, rows[1].Line)
+	assert.JSONEq(t, `{"account":"A2","amount":7}`, rows[1].Input.String())
+
+	_, err = TypedBatchInput([]any{func() {}})
+	assert.ErrorIs(t, err, ErrInvalidInput)
+}
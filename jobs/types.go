@@ -1,7 +1,9 @@
 package jobs
 
 import (
+	"context"
 	"encoding/json"
+	"time"
 
 	"github.com/go-redis/redis/v8"
 	"github.com/google/uuid"
@@ -55,6 +57,24 @@ type BatchProcessor interface {
 	MarkDone(InitBlock InitBlock, context JSONstr, details BatchDetails_t) error
 }
 
+// SlowQueryProcessorV2 is the context-aware version of SlowQueryProcessor. The context
+// passed to DoSlowQuery is cancelled when the slow query is aborted, when the JobManager
+// shuts down and when the row timeout of its (app, op) expires; context.Cause tells which
+// (ErrJobAborted, ErrShuttingDown or ErrRowTimeout). DoSlowQuery should then return
+// promptly with an error.
+type SlowQueryProcessorV2 interface {
+	DoSlowQuery(ctx context.Context, InitBlock InitBlock, jobctx JSONstr, input JSONstr) (status batchsqlc.StatusEnum, result JSONstr, messages []wscutils.ErrorMessage, outputFiles map[string]string, err error)
+	MarkDone(ctx context.Context, InitBlock InitBlock, jobctx JSONstr, details BatchDetails_t) error
+}
+
+// BatchProcessorV2 is the context-aware version of BatchProcessor. The context passed
+// to DoBatchJob is cancelled when the batch is aborted, when the JobManager shuts down
+// and when the row timeout of its (app, op) expires, as for SlowQueryProcessorV2.
+type BatchProcessorV2 interface {
+	DoBatchJob(ctx context.Context, InitBlock InitBlock, jobctx JSONstr, line int, input JSONstr) (status batchsqlc.StatusEnum, result JSONstr, messages []wscutils.ErrorMessage, blobRows map[string]string, err error)
+	MarkDone(ctx context.Context, InitBlock InitBlock, jobctx JSONstr, details BatchDetails_t) error
+}
+
 type SlowQuery struct {
 	Db          *pgxpool.Pool
 	Queries     batchsqlc.Querier
@@ -97,17 +117,76 @@ type JobManagerConfig struct {
 	BatchStatusCacheDurSec int    // duration in seconds to cache the batch status
 	BatchOutputBucket      string // bucket name for batch files
 	PollingIntervalSec     int    // polling interval in seconds for checking jobs (default: 45)
+	Workers                int    // number of rows of a fetched block processed in parallel (default: 1)
+	DisableListenNotify    bool   // poll only, e.g. behind a connection pooler that does not support LISTEN
+	SubmitChunkNRows       int    // number of rows loaded per COPY by BatchSubmitStream (default: 1000)
+	WebhookSecret          string // key of the HMAC signature of completion webhooks; unsigned if empty
+	WebhookMaxAttempts     int    // attempts at delivering a completion webhook before giving up (default: 8)
+	ArchiveBucket          string // bucket for the archives of batches purged by retention policies (default: alya-batch-archive)
+	PartitionedBatchRows   bool   // batchrows has been partitioned by MigrateBatchRowsPartitioned
+	IdempotencyWindowHours int    // how long an idempotency key refers to the job first submitted with it (default: 24)
+	SummaryChunkNRows      int    // number of processed rows read per query when assembling output files (default: 1000)
+	OutputPartSize         int    // bytes of an output file or archive uploaded at a time, at least objstore.MinPartSize (default: 16 MiB)
+
+	// Validator validates the context and input of the rows of typed processors
+	// (default: a validator that reports every violation as MsgIDInvalidRowInput)
+	Validator *wscutils.Validator
+
+	// FetchPolicy decides how each iteration picks the rows it processes
+	// (default: FetchPolicyPriority). AppWeights gives the relative share of
+	// each app under FetchPolicyFairShare; unlisted apps have weight 1.
+	FetchPolicy FetchPolicy_t
+	AppWeights  map[string]int
+}
+
+// SubmitOptions_t holds the optional settings of a batch or slow query
+// submission. The zero value submits for immediate processing at priority 0.
+type SubmitOptions_t struct {
+	WaitABit bool      // hold the batch back in 'wait' status (batches only)
+	RunAt    time.Time // do not process before this time
+	Priority int       // rows of higher priority batches are processed first
+
+	// CallbackURL, if set, receives a CompletionEvent_t POST once the job is done
+	CallbackURL string
+
+	// DependsOn lists the batches that must be done before this one runs; the batch
+	// stays in 'wait' until then (batches only). OnParentFailure says what happens
+	// to it if one of them does not succeed (default: ParentFailureAbort).
+	DependsOn       []Dependency_t
+	OnParentFailure ParentFailurePolicy_t
+
+	// IdempotencyKey, if set, makes the submission idempotent: submitting again with
+	// the same key for the same app within JobManagerConfig.IdempotencyWindowHours
+	// returns the ID of the first batch or slow query instead of creating a new one.
+	IdempotencyKey string
 }
 
 // BatchDetails_t struct
 type BatchDetails_t struct {
+	ID              string
+	App             string
+	Op              string
+	Context         JSONstr
+	InputFile       string
+	Status          batchsqlc.StatusEnum
+	ReqAt           time.Time
+	DoneAt          time.Time
+	OutputFiles     map[string]string
+	OutputFileTypes map[string]string // content types of the output files, by logical name
+	NRows           int
+	NSuccess        int
+	NFailed         int
+	NAborted        int
+}
+
+// SlowQueryDetails_t describes one slow query as returned by SlowQueryList.
+type SlowQueryDetails_t struct {
 	ID          string
 	App         string
 	Op          string
-	Context     JSONstr
+	InputFile   string
 	Status      batchsqlc.StatusEnum
+	ReqAt       time.Time
+	DoneAt      time.Time
 	OutputFiles map[string]string
-	NSuccess    int
-	NFailed     int
-	NAborted    int
 }
@@ -0,0 +1,328 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/remiges-tech/alya/jobs/pg/batchsqlc"
+)
+
+const (
+	// Events posted to callback URLs
+	WebhookEventBatchDone     = "batch.done"
+	WebhookEventSlowQueryDone = "slowquery.done"
+
+	// Headers of webhook requests. The signature is the hex encoded HMAC-SHA256, keyed
+	// with JobManagerConfig.WebhookSecret, of the timestamp, a '.', and the body.
+	WebhookHeaderEvent     = "X-Alya-Event"
+	WebhookHeaderDelivery  = "X-Alya-Delivery"
+	WebhookHeaderTimestamp = "X-Alya-Timestamp"
+	WebhookHeaderSignature = "X-Alya-Signature"
+
+	webhookPollInterval   = 5 * time.Second
+	webhookTimeout        = 10 * time.Second
+	webhookClaimLimit     = 5
+	webhookLease          = (webhookClaimLimit + 1) * webhookTimeout // covers posting every claimed delivery in turn
+	webhookInitialBackoff = 30 * time.Second
+	webhookMaxBackoff     = time.Hour
+	webhookMaxErrorLen    = 1000
+)
+
+var ErrInvalidCallbackURL = errors.New("invalid callback URL")
+
+// CompletionEvent_t is the payload posted to the callback URL of a batch or slow query
+// once it is done, successfully or not.
+type CompletionEvent_t struct {
+	Event           string               `json:"event"` // WebhookEventBatchDone or WebhookEventSlowQueryDone
+	ID              string               `json:"id"`    // batch ID or slow query request ID
+	App             string               `json:"app"`
+	Op              string               `json:"op"`
+	Status          batchsqlc.StatusEnum `json:"status"`
+	NSuccess        int                  `json:"nsuccess"`
+	NFailed         int                  `json:"nfailed"`
+	NAborted        int                  `json:"naborted"`
+	OutputFiles     map[string]string    `json:"outputFiles,omitempty"`
+	OutputFileTypes map[string]string    `json:"outputFileTypes,omitempty"` // content types of the output files
+	DoneAt          time.Time            `json:"doneAt"`
+}
+
+// WebhookDelivery_t is an entry of the delivery log of a batch or slow query.
+// Status is "pending" while attempts remain, then "delivered" or "failed".
+type WebhookDelivery_t struct {
+	ID             int64     `json:"id"`
+	URL            string    `json:"url"`
+	Status         string    `json:"status"`
+	Attempts       int       `json:"attempts"`
+	LastHTTPStatus int       `json:"lastHttpStatus,omitempty"`
+	LastError      string    `json:"lastError,omitempty"`
+	CreatedAt      time.Time `json:"createdAt"`
+	NextAttemptAt  time.Time `json:"nextAttemptAt"`
+	DeliveredAt    time.Time `json:"deliveredAt"`
+}
+
+// SignWebhookPayload returns the signature sent in the X-Alya-Signature header of a
+// webhook request with the given timestamp and body.
+func SignWebhookPayload(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookSignature reports whether signature is a valid signature of a webhook
+// request with the given timestamp and body. Receivers should also reject requests
+// whose timestamp is too old, to guard against replays.
+func VerifyWebhookSignature(secret, timestamp, signature string, body []byte) bool {
+	return hmac.Equal([]byte(signature), []byte(SignWebhookPayload(secret, timestamp, body)))
+}
+
+// callbackURLParam validates the callback URL of a submission and converts it for the
+// batches table.
+func callbackURLParam(callbackURL string) (pgtype.Text, error) {
+	if callbackURL == "" {
+		return pgtype.Text{}, nil
+	}
+	u, err := url.Parse(callbackURL)
+	if err != nil {
+		return pgtype.Text{}, fmt.Errorf("%w: %v", ErrInvalidCallbackURL, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return pgtype.Text{}, fmt.Errorf("%w: %s: must be an absolute http or https URL", ErrInvalidCallbackURL, callbackURL)
+	}
+	return pgtype.Text{String: callbackURL, Valid: true}, nil
+}
+
+// enqueueCompletionWebhook queues the delivery of event to the callback URL of its batch,
+// if it has one. q should be bound to the transaction that completes the batch, so that
+// the delivery is queued if and only if the completion commits.
+func enqueueCompletionWebhook(ctx context.Context, q batchsqlc.Querier, batchID uuid.UUID, event CompletionEvent_t) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal completion event: %w", err)
+	}
+	err = q.InsertWebhookDelivery(ctx, batchsqlc.InsertWebhookDeliveryParams{
+		Payload:       payload,
+		NextAttemptAt: pgtype.Timestamp{Time: time.Now(), Valid: true},
+		Batch:         batchID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to queue webhook delivery: %w", err)
+	}
+	return nil
+}
+
+// WebhookDeliveries returns the delivery log of the completion webhook of a batch or
+// slow query, oldest first. It is empty if no callback URL was given at submission.
+func (jm *JobManager) WebhookDeliveries(batchID string) ([]WebhookDelivery_t, error) {
+	batchUUID, err := uuid.Parse(batchID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid batch ID: %w", err)
+	}
+	rows, err := jm.queries.GetWebhookDeliveriesByBatchID(context.Background(), batchUUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook deliveries: %w", err)
+	}
+
+	deliveries := make([]WebhookDelivery_t, 0, len(rows))
+	for _, row := range rows {
+		d := WebhookDelivery_t{
+			ID:             row.ID,
+			URL:            row.Url,
+			Status:         string(row.Status),
+			Attempts:       int(row.Attempts),
+			LastHTTPStatus: int(row.LastHttpStatus.Int32),
+			LastError:      row.LastError.String,
+			NextAttemptAt:  localTime(row.NextAttemptAt.Time),
+		}
+		if row.CreatedAt.Valid {
+			d.CreatedAt = localTime(row.CreatedAt.Time)
+		}
+		if row.DeliveredAt.Valid {
+			d.DeliveredAt = localTime(row.DeliveredAt.Time)
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, nil
+}
+
+// runWebhookDeliverer delivers queued completion webhooks until ctx is cancelled.
+// Deliveries are leased from the outbox table, so any number of JobManager instances
+// can run it; each delivery is attempted by one instance at a time.
+func (jm *JobManager) runWebhookDeliverer(ctx context.Context) {
+	ticker := time.NewTicker(webhookPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			jm.deliverWebhooks(ctx)
+		}
+	}
+}
+
+// deliverWebhooks attempts the deliveries that are due and records their outcome.
+func (jm *JobManager) deliverWebhooks(ctx context.Context) {
+	now := time.Now()
+	deliveries, err := jm.queries.ClaimWebhookDeliveries(ctx, batchsqlc.ClaimWebhookDeliveriesParams{
+		LeaseUntil: pgtype.Timestamp{Time: now.Add(webhookLease), Valid: true},
+		Now:        pgtype.Timestamp{Time: now, Valid: true},
+		Limit:      webhookClaimLimit,
+	})
+	if err != nil {
+		if ctx.Err() == nil {
+			jm.logger.Error(err).LogActivity("Error claiming webhook deliveries", nil)
+		}
+		return
+	}
+
+	for _, d := range deliveries {
+		httpStatus, err := jm.postWebhook(ctx, d)
+		if ctx.Err() != nil {
+			// Shutdown: the lease runs out and another instance retries
+			return
+		}
+		jm.recordWebhookAttempt(d, httpStatus, err)
+	}
+}
+
+// postWebhook posts the payload of a delivery to its URL. It returns the HTTP status of
+// the response, if any, and an error unless the status is 2xx.
+func (jm *JobManager) postWebhook(ctx context.Context, d batchsqlc.ClaimWebhookDeliveriesRow) (int, error) {
+	var event CompletionEvent_t
+	if err := json.Unmarshal(d.Payload, &event); err != nil {
+		return 0, fmt.Errorf("invalid payload: %w", err)
+	}
+
+	reqCtx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodPost, d.Url, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookHeaderEvent, event.Event)
+	req.Header.Set(WebhookHeaderDelivery, strconv.FormatInt(d.ID, 10))
+	req.Header.Set(WebhookHeaderTimestamp, timestamp)
+	if jm.config.WebhookSecret != "" {
+		req.Header.Set(WebhookHeaderSignature, SignWebhookPayload(jm.config.WebhookSecret, timestamp, d.Payload))
+	}
+
+	resp, err := jm.webhookClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected response status %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// recordWebhookAttempt records the outcome of a delivery attempt, unless the lease on the
+// delivery has run out and it has been claimed again. A failed delivery is retried with
+// exponential backoff until it has been attempted WebhookMaxAttempts times.
+func (jm *JobManager) recordWebhookAttempt(d batchsqlc.ClaimWebhookDeliveriesRow, httpStatus int, attemptErr error) {
+	ctx := context.Background()
+	status := pgtype.Int4{Int32: int32(httpStatus), Valid: httpStatus != 0}
+
+	if attemptErr == nil {
+		n, err := jm.queries.MarkWebhookDelivered(ctx, batchsqlc.MarkWebhookDeliveredParams{
+			HttpStatus:  status,
+			DeliveredAt: pgtype.Timestamp{Time: time.Now(), Valid: true},
+			ID:          d.ID,
+			LeasedUntil: d.NextAttemptAt,
+		})
+		if err != nil {
+			jm.logger.Error(err).LogActivity("Error recording webhook delivery", map[string]any{
+				"deliveryId": d.ID,
+				"batchId":    d.Batch.String(),
+			})
+			return
+		}
+		if n == 0 {
+			jm.logWebhookLeaseLost(d)
+			return
+		}
+		jm.logger.Info().LogActivity("Webhook delivered", map[string]any{
+			"deliveryId": d.ID,
+			"batchId":    d.Batch.String(),
+			"httpStatus": httpStatus,
+		})
+		return
+	}
+
+	attempts := int(d.Attempts) + 1
+	deliveryStatus := batchsqlc.DeliveryStatusEnumPending
+	if attempts >= jm.config.WebhookMaxAttempts {
+		deliveryStatus = batchsqlc.DeliveryStatusEnumFailed
+	}
+	errMsg := attemptErr.Error()
+	if len(errMsg) > webhookMaxErrorLen {
+		errMsg = errMsg[:webhookMaxErrorLen]
+	}
+	n, err := jm.queries.MarkWebhookAttemptFailed(ctx, batchsqlc.MarkWebhookAttemptFailedParams{
+		Status:        deliveryStatus,
+		NextAttemptAt: pgtype.Timestamp{Time: time.Now().Add(webhookBackoff(attempts)), Valid: true},
+		HttpStatus:    status,
+		LastError:     pgtype.Text{String: errMsg, Valid: true},
+		ID:            d.ID,
+		LeasedUntil:   d.NextAttemptAt,
+	})
+	if err != nil {
+		jm.logger.Error(err).LogActivity("Error recording failed webhook attempt", map[string]any{
+			"deliveryId": d.ID,
+			"batchId":    d.Batch.String(),
+		})
+		return
+	}
+	if n == 0 {
+		jm.logWebhookLeaseLost(d)
+		return
+	}
+	jm.logger.Warn().LogActivity("Webhook delivery attempt failed", map[string]any{
+		"deliveryId": d.ID,
+		"batchId":    d.Batch.String(),
+		"url":        d.Url,
+		"attempt":    attempts,
+		"status":     deliveryStatus,
+		"error":      errMsg,
+	})
+}
+
+// logWebhookLeaseLost logs an attempt whose outcome was not recorded because the lease
+// on the delivery ran out and another instance claimed it again.
+func (jm *JobManager) logWebhookLeaseLost(d batchsqlc.ClaimWebhookDeliveriesRow) {
+	jm.logger.Warn().LogActivity("Webhook delivery lease ran out, attempt not recorded", map[string]any{
+		"deliveryId": d.ID,
+		"batchId":    d.Batch.String(),
+	})
+}
+
+// webhookBackoff returns the delay before the next attempt of a delivery that has been
+// attempted the given number of times.
+func webhookBackoff(attempts int) time.Duration {
+	backoff := webhookInitialBackoff
+	for i := 1; i < attempts && backoff < webhookMaxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, webhookMaxBackoff)
+}
@@ -0,0 +1,352 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/remiges-tech/alya/jobs/objstore"
+	"github.com/remiges-tech/alya/jobs/pg/batchsqlc"
+	"github.com/remiges-tech/alya/jobs/pg/batchsqlc/mocks"
+	"github.com/remiges-tech/alya/wscutils"
+	"github.com/remiges-tech/logharbour/logharbour"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newWebhookQuerier returns a mock that hands out the deliveries once and records
+// their outcome.
+func newWebhookQuerier(deliveries ...batchsqlc.ClaimWebhookDeliveriesRow) *mocks.QuerierMock {
+	var once sync.Once
+	return &mocks.QuerierMock{
+		ClaimWebhookDeliveriesFunc: func(ctx context.Context, arg batchsqlc.ClaimWebhookDeliveriesParams) ([]batchsqlc.ClaimWebhookDeliveriesRow, error) {
+			var claimed []batchsqlc.ClaimWebhookDeliveriesRow
+			once.Do(func() { claimed = deliveries })
+			return claimed, nil
+		},
+		MarkWebhookDeliveredFunc: func(ctx context.Context, arg batchsqlc.MarkWebhookDeliveredParams) (int64, error) {
+			return 1, nil
+		},
+		MarkWebhookAttemptFailedFunc: func(ctx context.Context, arg batchsqlc.MarkWebhookAttemptFailedParams) (int64, error) {
+			return 1, nil
+		},
+	}
+}
+
+func webhookDelivery(t *testing.T, url string, attempts int32) batchsqlc.ClaimWebhookDeliveriesRow {
+	payload, err := json.Marshal(CompletionEvent_t{
+		Event:  WebhookEventBatchDone,
+		ID:     uuid.NewString(),
+		Status: batchsqlc.StatusEnumSuccess,
+	})
+	require.NoError(t, err)
+	return batchsqlc.ClaimWebhookDeliveriesRow{
+		ID:            7,
+		Batch:         uuid.New(),
+		Url:           url,
+		Payload:       payload,
+		Attempts:      attempts,
+		NextAttemptAt: pgtype.Timestamp{Time: time.Now().Add(webhookLease), Valid: true},
+	}
+}
+
+func TestWebhookSignature(t *testing.T) {
+	body := []byte(`{"event":"batch.done"}`)
+	signature := SignWebhookPayload("s3cret", "1700000000", body)
+
+	assert.Regexp(t, `^sha256=[0-9a-f]{64}$`, signature)
+	assert.True(t, VerifyWebhookSignature("s3cret", "1700000000", signature, body))
+	assert.False(t, VerifyWebhookSignature("other", "1700000000", signature, body))
+	assert.False(t, VerifyWebhookSignature("s3cret", "1700000001", signature, body))
+	assert.False(t, VerifyWebhookSignature("s3cret", "1700000000", signature, []byte(`{}`)))
+}
+
+func TestCallbackURLParam(t *testing.T) {
+	param, err := callbackURLParam("")
+	require.NoError(t, err)
+	assert.False(t, param.Valid)
+
+	param, err = callbackURLParam("https://example.com/hooks/alya?app=1")
+	require.NoError(t, err)
+	assert.True(t, param.Valid)
+	assert.Equal(t, "https://example.com/hooks/alya?app=1", param.String)
+
+	for _, bad := range []string{"example.com/hook", "ftp://example.com/hook", "https://", "http://[::1"} {
+		_, err := callbackURLParam(bad)
+		assert.ErrorIs(t, err, ErrInvalidCallbackURL, bad)
+	}
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	assert.Equal(t, 30*time.Second, webhookBackoff(1))
+	assert.Equal(t, time.Minute, webhookBackoff(2))
+	assert.Equal(t, 4*time.Minute, webhookBackoff(4))
+	assert.Equal(t, time.Hour, webhookBackoff(10))
+}
+
+func TestEnqueueCompletionWebhook(t *testing.T) {
+	q := &mocks.QuerierMock{
+		InsertWebhookDeliveryFunc: func(ctx context.Context, arg batchsqlc.InsertWebhookDeliveryParams) error {
+			return nil
+		},
+	}
+	batchID := uuid.New()
+
+	err := enqueueCompletionWebhook(context.Background(), q, batchID, CompletionEvent_t{
+		Event:    WebhookEventBatchDone,
+		ID:       batchID.String(),
+		Status:   batchsqlc.StatusEnumFailed,
+		NSuccess: 3,
+		NFailed:  1,
+	})
+	require.NoError(t, err)
+
+	calls := q.InsertWebhookDeliveryCalls()
+	require.Len(t, calls, 1)
+	assert.Equal(t, batchID, calls[0].Arg.Batch)
+	assert.True(t, calls[0].Arg.NextAttemptAt.Valid)
+	var event CompletionEvent_t
+	require.NoError(t, json.Unmarshal(calls[0].Arg.Payload, &event))
+	assert.Equal(t, batchsqlc.StatusEnumFailed, event.Status)
+	assert.Equal(t, 3, event.NSuccess)
+}
+
+func TestDeliverWebhooks(t *testing.T) {
+	t.Run("delivered and signed", func(t *testing.T) {
+		var header http.Header
+		var body []byte
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header = r.Header.Clone()
+			body, _ = io.ReadAll(r.Body)
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+
+		d := webhookDelivery(t, server.URL, 0)
+		q := newWebhookQuerier(d)
+		jm := newTestJobManager(q, nil)
+		jm.config.WebhookSecret = "s3cret"
+
+		jm.deliverWebhooks(context.Background())
+
+		require.Len(t, q.MarkWebhookDeliveredCalls(), 1)
+		assert.Empty(t, q.MarkWebhookAttemptFailedCalls())
+		delivered := q.MarkWebhookDeliveredCalls()[0].Arg
+		assert.Equal(t, d.ID, delivered.ID)
+		assert.Equal(t, d.NextAttemptAt, delivered.LeasedUntil)
+		assert.Equal(t, int32(http.StatusNoContent), delivered.HttpStatus.Int32)
+
+		assert.Equal(t, d.Payload, body)
+		assert.Equal(t, WebhookEventBatchDone, header.Get(WebhookHeaderEvent))
+		assert.Equal(t, strconv.FormatInt(d.ID, 10), header.Get(WebhookHeaderDelivery))
+		assert.True(t, VerifyWebhookSignature("s3cret", header.Get(WebhookHeaderTimestamp), header.Get(WebhookHeaderSignature), body))
+	})
+
+	t.Run("unsigned without secret", func(t *testing.T) {
+		var header http.Header
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header = r.Header.Clone()
+		}))
+		defer server.Close()
+
+		q := newWebhookQuerier(webhookDelivery(t, server.URL, 0))
+		jm := newTestJobManager(q, nil)
+
+		jm.deliverWebhooks(context.Background())
+
+		assert.Len(t, q.MarkWebhookDeliveredCalls(), 1)
+		assert.Empty(t, header.Get(WebhookHeaderSignature))
+	})
+
+	t.Run("retried after error response", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		q := newWebhookQuerier(webhookDelivery(t, server.URL, 2))
+		jm := newTestJobManager(q, nil)
+
+		jm.deliverWebhooks(context.Background())
+
+		assert.Empty(t, q.MarkWebhookDeliveredCalls())
+		require.Len(t, q.MarkWebhookAttemptFailedCalls(), 1)
+		failed := q.MarkWebhookAttemptFailedCalls()[0].Arg
+		assert.Equal(t, batchsqlc.DeliveryStatusEnumPending, failed.Status)
+		assert.Equal(t, int32(http.StatusServiceUnavailable), failed.HttpStatus.Int32)
+		assert.Contains(t, failed.LastError.String, "503")
+		assert.WithinDuration(t, time.Now().Add(webhookBackoff(3)), failed.NextAttemptAt.Time, 5*time.Second)
+	})
+
+	t.Run("failed after last attempt", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		server.Close()
+
+		q := newWebhookQuerier(webhookDelivery(t, server.URL, ALYA_WEBHOOK_MAX_ATTEMPTS-1))
+		jm := newTestJobManager(q, nil)
+
+		jm.deliverWebhooks(context.Background())
+
+		require.Len(t, q.MarkWebhookAttemptFailedCalls(), 1)
+		failed := q.MarkWebhookAttemptFailedCalls()[0].Arg
+		assert.Equal(t, batchsqlc.DeliveryStatusEnumFailed, failed.Status)
+		assert.False(t, failed.HttpStatus.Valid)
+		assert.NotEmpty(t, failed.LastError.String)
+	})
+}
+
+func TestWebhookLease(t *testing.T) {
+	ctx := context.Background()
+	jm, store, _ := newMemoryTestJobManager(t)
+	batchID := uuid.New()
+	_, err := store.Queries().InsertIntoBatches(ctx, batchsqlc.InsertIntoBatchesParams{
+		ID:          batchID,
+		App:         "bankapp",
+		Op:          "txns",
+		Context:     []byte(`{}`),
+		Status:      batchsqlc.StatusEnumSuccess,
+		Reqat:       memNow(),
+		CallbackUrl: pgtype.Text{String: "https://example.com/hook", Valid: true},
+	})
+	require.NoError(t, err)
+	event := CompletionEvent_t{Event: WebhookEventBatchDone, ID: batchID.String(), Status: batchsqlc.StatusEnumSuccess}
+	require.NoError(t, enqueueCompletionWebhook(ctx, store.Queries(), batchID, event))
+
+	claim := func(now time.Time) []batchsqlc.ClaimWebhookDeliveriesRow {
+		deliveries, err := store.Queries().ClaimWebhookDeliveries(ctx, batchsqlc.ClaimWebhookDeliveriesParams{
+			LeaseUntil: pgtype.Timestamp{Time: now.Add(webhookLease), Valid: true},
+			Now:        pgtype.Timestamp{Time: now, Valid: true},
+			Limit:      webhookClaimLimit,
+		})
+		require.NoError(t, err)
+		require.Len(t, deliveries, 1)
+		return deliveries
+	}
+	first := claim(time.Now())
+	// The lease of the first claimer runs out, and another instance claims the delivery
+	second := claim(first[0].NextAttemptAt.Time)
+
+	jm.recordWebhookAttempt(first[0], 0, errors.New("timeout"))
+	deliveries, err := jm.WebhookDeliveries(batchID.String())
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, "pending", deliveries[0].Status)
+	assert.Equal(t, 0, deliveries[0].Attempts)
+
+	jm.recordWebhookAttempt(second[0], http.StatusOK, nil)
+	deliveries, err = jm.WebhookDeliveries(batchID.String())
+	require.NoError(t, err)
+	assert.Equal(t, "delivered", deliveries[0].Status)
+	assert.Equal(t, 1, deliveries[0].Attempts)
+
+	// A late outcome of the first claimer does not overwrite the delivery either
+	jm.recordWebhookAttempt(first[0], 0, errors.New("timeout"))
+	deliveries, err = jm.WebhookDeliveries(batchID.String())
+	require.NoError(t, err)
+	assert.Equal(t, "delivered", deliveries[0].Status)
+}
+
+// webhookFailStore is a MemoryStore whose transactions fail to queue webhook deliveries.
+type webhookFailStore struct {
+	*MemoryStore
+}
+
+func (s webhookFailStore) Begin(ctx context.Context) (StoreTx, error) {
+	tx, err := s.MemoryStore.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return webhookFailTx{tx}, nil
+}
+
+type webhookFailTx struct {
+	StoreTx
+}
+
+func (tx webhookFailTx) Queries() batchsqlc.Querier {
+	return webhookFailQuerier{tx.StoreTx.Queries()}
+}
+
+type webhookFailQuerier struct {
+	batchsqlc.Querier
+}
+
+func (q webhookFailQuerier) InsertWebhookDelivery(ctx context.Context, arg batchsqlc.InsertWebhookDeliveryParams) error {
+	return errors.New("disk full")
+}
+
+// memSlowQueryProcessor succeeds with a fixed result.
+type memSlowQueryProcessor struct{}
+
+func (memSlowQueryProcessor) DoSlowQuery(ctx context.Context, initBlock InitBlock, jobctx JSONstr, input JSONstr) (batchsqlc.StatusEnum, JSONstr, []wscutils.ErrorMessage, map[string]string, error) {
+	result, err := NewJSONstr(`{"nrows":3}`)
+	return batchsqlc.StatusEnumSuccess, result, nil, nil, err
+}
+
+func (memSlowQueryProcessor) MarkDone(ctx context.Context, initBlock InitBlock, jobctx JSONstr, details BatchDetails_t) error {
+	return nil
+}
+
+func TestSlowQueryWebhookQueueFailure(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	logger := logharbour.NewLogger(&logharbour.LoggerContext{}, "test", log.Writer())
+	jm := NewJobManagerWithBackends(webhookFailStore{store}, NewMemoryCache(), objstore.NewMemObjectStore(), logger, nil)
+	require.NoError(t, jm.RegisterInitializer("memapp", &MockInitializer{}))
+	require.NoError(t, jm.RegisterProcessorSlowQueryV2("memapp", "memop", memSlowQueryProcessor{}))
+
+	reqID, err := jm.SlowQuerySubmitWithOptions("memapp", "memop", mustJSONstr(t, `{}`), mustJSONstr(t, `{}`), SubmitOptions_t{
+		CallbackURL: "https://example.com/hook",
+	})
+	require.NoError(t, err)
+	jm.RunOneIteration()
+
+	// The result is not recorded without its webhook: the slow query fails instead
+	rows, err := store.Queries().GetBatchRowsByBatchID(ctx, uuid.MustParse(reqID))
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, batchsqlc.StatusEnumFailed, rows[0].Status)
+	assert.NotContains(t, string(rows[0].Res), "nrows")
+	batch, err := store.Queries().GetBatchByID(ctx, uuid.MustParse(reqID))
+	require.NoError(t, err)
+	assert.Equal(t, batchsqlc.StatusEnumFailed, batch.Status)
+}
+
+func TestWebhookDeliveries(t *testing.T) {
+	batchID := uuid.New()
+	q := &mocks.QuerierMock{
+		GetWebhookDeliveriesByBatchIDFunc: func(ctx context.Context, batch uuid.UUID) ([]batchsqlc.WebhookDelivery, error) {
+			return []batchsqlc.WebhookDelivery{{
+				ID:            1,
+				Batch:         batch,
+				Url:           "https://example.com/hook",
+				Status:        batchsqlc.DeliveryStatusEnumDelivered,
+				Attempts:      2,
+				NextAttemptAt: dbTimestamp(time.Now()),
+				CreatedAt:     dbTimestamp(time.Now().Add(-time.Minute)),
+				DeliveredAt:   dbTimestamp(time.Now()),
+			}}, nil
+		},
+	}
+	jm := newTestJobManager(q, nil)
+
+	deliveries, err := jm.WebhookDeliveries(batchID.String())
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, "delivered", deliveries[0].Status)
+	assert.Equal(t, 2, deliveries[0].Attempts)
+	assert.WithinDuration(t, time.Now(), deliveries[0].DeliveredAt, 5*time.Second)
+
+	_, err = jm.WebhookDeliveries("not-a-uuid")
+	assert.Error(t, err)
+}